@@ -3,9 +3,9 @@ module k8s-api-access
 go 1.24.1
 
 require (
-	k8s.io/api v0.33.1
-	k8s.io/apimachinery v0.33.1
-	k8s.io/client-go v0.33.1
+	k8s.io/api v0.33.2
+	k8s.io/apimachinery v0.33.2
+	k8s.io/client-go v0.33.2
 )
 
 require (
@@ -47,3 +47,11 @@ require (
 	sigs.k8s.io/structured-merge-diff/v4 v4.6.0 // indirect
 	sigs.k8s.io/yaml v1.4.0 // indirect
 )
+
+require github.com/shamimice03/mastering-k8s-client-go/clientid v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/clientid => ../../clientid
+
+require github.com/shamimice03/mastering-k8s-client-go/testutil v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/testutil => ../../testutil
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	// k8s.io/api - Kubernetes resource definitions
 	// Contains all the Kubernetes API objects like Pod, Service, Deployment, etc.
@@ -18,6 +20,8 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
 )
 
 // getExternalClusterConfig loads kubeconfig from ~/.kube/config
@@ -39,6 +43,7 @@ func getExternalClusterConfig() (*rest.Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to build config from kubeconfig: %v", err)
 	}
+	clientid.Configure(config, "01-starter")
 
 	return config, nil
 }
@@ -62,10 +67,19 @@ func main() {
 	// Display successful connection information
 	fmt.Printf("Connected to external cluster: %s\n", config.Host)
 
-	// List all pods in the "default" namespace
-	// context.TODO() is used when context is needed but not available
-	// In production, use context.WithTimeout() or context.WithCancel()
-	podList, _ := clientset.CoreV1().Pods("default").List(context.TODO(), metav1.ListOptions{})
+	// Ctrl-C or SIGTERM cancels the context, and with it any request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// List and print all pods in the "default" namespace
+	printPods(ctx, clientset, "default")
+}
+
+// printPods lists the pods of one namespace and prints their names
+// It accepts kubernetes.Interface so tests can pass a fake clientset
+func printPods(ctx context.Context, clientset kubernetes.Interface, namespace string) {
+	// Every request takes a context; cancelling it aborts the request
+	podList, _ := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
 
 	// Iterate through the list of pods and display their names
 	// podList.Items contains an array of Pod objects
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func TestPrintPods(t *testing.T) {
+	// NewSimpleClientset pre-seeds an in-memory object tracker instead of a cluster
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"}},
+	)
+	output := testutil.CaptureOutput(t)
+
+	printPods(context.TODO(), clientset, "default")
+	testutil.WaitForOutput(t, output, "Pod Name: web\n")
+	if strings.Contains(output(), "coredns") {
+		t.Errorf("pods from other namespaces were printed:\n%s", output())
+	}
+}
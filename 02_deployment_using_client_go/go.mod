@@ -47,3 +47,7 @@ require (
 	sigs.k8s.io/structured-merge-diff/v4 v4.6.0 // indirect
 	sigs.k8s.io/yaml v1.4.0 // indirect
 )
+
+require github.com/shamimice03/mastering-k8s-client-go/clientid v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/clientid => ../clientid
@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	// k8s.io/api - Kubernetes resource definitions
 	appsv1 "k8s.io/api/apps/v1"
@@ -17,6 +20,16 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace    = flag.String("namespace", "default", "namespace to create the Deployment in")
+	replicas     = flag.Int("replicas", 3, "number of nginx replicas")
+	image        = flag.String("image", "nginx:1.21", "container image of the pods")
+	dryRun       = flag.Bool("dry-run", false, "validate the Deployment on the server without creating it")
+	generateName = flag.Bool("generate-name", false, "let the server pick a unique name, so repeated runs do not fail with AlreadyExists")
 )
 
 // getExternalClusterConfig loads kubeconfig from ~/.kube/config
@@ -31,6 +44,7 @@ func getExternalClusterConfig() (*rest.Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to build config from kubeconfig: %v", err)
 	}
+	clientid.Configure(config, "02-deployment-using-client-go")
 
 	return config, nil
 }
@@ -40,22 +54,10 @@ func int32Ptr(i int32) *int32 {
 	return &i
 }
 
-func main() {
-	// Get external cluster configuration
-	config, err := getExternalClusterConfig()
-	if err != nil {
-		panic(fmt.Errorf("failed to get external cluster config: %v", err))
-	}
-
-	// Create clientset to interact with Kubernetes API
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(fmt.Errorf("failed to create clientset: %v", err))
-	}
-
-	fmt.Printf("Connected to external cluster: %s\n", config.Host)
-
-	// Define a Deployment object
+// newDeployment defines the nginx Deployment created by this example. With
+// generateName the name is left empty and the server appends a random
+// suffix to "nginx-deployment-" on create.
+func newDeployment(namespace, image string, replicas int32, generateName bool) *appsv1.Deployment {
 	deployment := &appsv1.Deployment{
 		// TypeMeta - from apimachinery (API version and kind)
 		TypeMeta: metav1.TypeMeta{
@@ -65,11 +67,11 @@ func main() {
 		// ObjectMeta - from apimachinery (name, namespace, labels)
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "nginx-deployment",
-			Namespace: "default",
+			Namespace: namespace,
 		},
 		// Spec - from k8s.io/api/apps/v1 (Deployment-specific configuration)
 		Spec: appsv1.DeploymentSpec{
-			Replicas: int32Ptr(3),
+			Replicas: int32Ptr(replicas),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"app": "nginx-app",
@@ -86,20 +88,68 @@ func main() {
 					Containers: []corev1.Container{
 						{
 							Name:  "nginx-app",
-							Image: "nginx:1.21",
+							Image: image,
 						},
 					},
 				},
 			},
 		},
 	}
+	if generateName {
+		deployment.Name = ""
+		deployment.GenerateName = "nginx-deployment-"
+	}
+	return deployment
+}
+
+// createDeployment creates the Deployment. A dry run goes through
+// defaulting, admission and validation like a real create, and returns the
+// object the server would have stored, but nothing is persisted.
+func createDeployment(ctx context.Context, clientset kubernetes.Interface, deployment *appsv1.Deployment, dryRun bool) (*appsv1.Deployment, error) {
+	opts := metav1.CreateOptions{}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	return clientset.AppsV1().Deployments(deployment.Namespace).Create(ctx, deployment, opts)
+}
+
+func main() {
+	flag.Parse()
+	if *replicas < 0 {
+		panic(fmt.Errorf("--replicas must not be negative, got %d", *replicas))
+	}
+
+	// Get external cluster configuration
+	config, err := getExternalClusterConfig()
+	if err != nil {
+		panic(fmt.Errorf("failed to get external cluster config: %v", err))
+	}
+
+	// Create clientset to interact with Kubernetes API
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		panic(fmt.Errorf("failed to create clientset: %v", err))
+	}
+
+	fmt.Printf("Connected to external cluster: %s\n", config.Host)
+
+	// Ctrl-C or SIGTERM cancels the context, and with it the request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Define a Deployment object
+	deployment := newDeployment(*namespace, *image, int32(*replicas), *generateName)
 
 	// Create Deployment using client-go
-	res, err := clientset.AppsV1().Deployments("default").Create(context.TODO(), deployment, metav1.CreateOptions{})
+	res, err := createDeployment(ctx, clientset, deployment, *dryRun)
 
 	if err != nil {
 		panic(fmt.Errorf("failed to create deployment: %v", err))
 	}
 
+	if *dryRun {
+		fmt.Printf("Dry run: deployment %s/%s would be created (nothing was written)\n", res.Namespace, res.Name)
+		return
+	}
 	fmt.Printf("Successfully created deployment: %s\n", res.Name)
 }
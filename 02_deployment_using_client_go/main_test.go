@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNewDeploymentSelectorMatchesTemplate(t *testing.T) {
+	deployment := newDeployment("default", "nginx:1.21", 3, false)
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		t.Fatalf("invalid selector: %v", err)
+	}
+	// The API server rejects a Deployment whose selector does not match its own pods
+	if !selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+		t.Errorf("selector %s does not match template labels %v", selector, deployment.Spec.Template.Labels)
+	}
+	if got := *deployment.Spec.Replicas; got != 3 {
+		t.Errorf("replicas = %d, want 3", got)
+	}
+}
+
+func TestCreateDeployment(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx := context.TODO()
+
+	if _, err := clientset.AppsV1().Deployments("default").Create(ctx, newDeployment("default", "nginx:1.21", 3, false), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	got, err := clientset.AppsV1().Deployments("default").Get(ctx, "nginx-deployment", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if image := got.Spec.Template.Spec.Containers[0].Image; image != "nginx:1.21" {
+		t.Errorf("image = %q, want nginx:1.21", image)
+	}
+
+	// Running the example twice fails instead of overwriting the Deployment
+	_, err = clientset.AppsV1().Deployments("default").Create(ctx, newDeployment("default", "nginx:1.21", 3, false), metav1.CreateOptions{})
+	if !apierrors.IsAlreadyExists(err) {
+		t.Errorf("second create: got %v, want AlreadyExists", err)
+	}
+}
+
+// newServerClientset returns a fake clientset that handles generateName and
+// dry runs as the API server does; the fake itself stores both as they are
+func newServerClientset() *fake.Clientset {
+	clientset := fake.NewSimpleClientset()
+	generated := 0
+	clientset.PrependReactor("create", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		create := action.(k8stesting.CreateActionImpl)
+		deployment := create.GetObject().(*appsv1.Deployment).DeepCopy()
+		if deployment.Name == "" && deployment.GenerateName != "" {
+			generated++
+			deployment.Name = deployment.GenerateName + string(rune('a'+generated-1))
+		}
+		if slices.Contains(create.CreateOptions.DryRun, metav1.DryRunAll) {
+			return true, deployment, nil
+		}
+		// Let the tracker store it, and fail on a duplicate name
+		return true, deployment, clientset.Tracker().Create(action.GetResource(), deployment, deployment.Namespace)
+	})
+	return clientset
+}
+
+func TestNewDeploymentOptions(t *testing.T) {
+	deployment := newDeployment("shop", "nginx:1.27", 5, true)
+
+	if deployment.Name != "" || deployment.GenerateName != "nginx-deployment-" {
+		t.Errorf("name = %q, generateName = %q, want the server to generate the name", deployment.Name, deployment.GenerateName)
+	}
+	if deployment.Namespace != "shop" {
+		t.Errorf("namespace = %q, want shop", deployment.Namespace)
+	}
+	if got := *deployment.Spec.Replicas; got != 5 {
+		t.Errorf("replicas = %d, want 5", got)
+	}
+	if image := deployment.Spec.Template.Spec.Containers[0].Image; image != "nginx:1.27" {
+		t.Errorf("image = %q, want nginx:1.27", image)
+	}
+}
+
+func TestCreateDeploymentGenerateName(t *testing.T) {
+	clientset := newServerClientset()
+	ctx := context.TODO()
+
+	// Each run gets its own name instead of AlreadyExists
+	names := map[string]bool{}
+	for range 2 {
+		res, err := createDeployment(ctx, clientset, newDeployment("default", "nginx:1.21", 3, true), false)
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		names[res.Name] = true
+	}
+	list, err := clientset.AppsV1().Deployments("default").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || len(list.Items) != 2 {
+		t.Errorf("got names %v and %d deployments, want 2 of each", names, len(list.Items))
+	}
+}
+
+func TestCreateDeploymentDryRun(t *testing.T) {
+	clientset := newServerClientset()
+	ctx := context.TODO()
+
+	res, err := createDeployment(ctx, clientset, newDeployment("default", "nginx:1.21", 3, false), true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if res.Name != "nginx-deployment" {
+		t.Errorf("dry run returned %q, want nginx-deployment", res.Name)
+	}
+
+	var dryRun []string
+	for _, action := range clientset.Actions() {
+		if create, ok := action.(k8stesting.CreateActionImpl); ok {
+			dryRun = create.CreateOptions.DryRun
+		}
+	}
+	if !slices.Equal(dryRun, []string{metav1.DryRunAll}) {
+		t.Errorf("create sent DryRun %v, want [All]", dryRun)
+	}
+	if _, err := clientset.AppsV1().Deployments("default").Get(ctx, "nginx-deployment", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("dry run stored the deployment: get returned %v", err)
+	}
+}
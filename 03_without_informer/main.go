@@ -2,29 +2,112 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace = flag.String("namespace", "default", "namespace whose pods are polled")
+	interval  = flag.Duration("interval", 2*time.Second, "time between two polls")
+	jitter    = flag.Float64("jitter", 0.2, "random extra delay per poll, as a fraction of --interval")
 )
 
-func podStatus(clientset *kubernetes.Clientset) {
-	for {
-		pods, _ := clientset.CoreV1().Pods("default").List(context.TODO(), metav1.ListOptions{})
-		for _, pod := range pods.Items {
-			fmt.Printf("%s: %s\n", pod.Name, pod.Status.Phase)
+// printPodStatus lists the pods in namespace straight from the API server
+func printPodStatus(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		fmt.Printf("%s: %s\n", pod.Name, pod.Status.Phase)
+	}
+	fmt.Println("---")
+	return nil
+}
+
+// poller lists pods on every tick and keeps count of what that costs
+type poller struct {
+	clientset kubernetes.Interface
+	namespace string
+	usage     *apiUsage
+
+	polls    int
+	failures int
+}
+
+func (p *poller) poll(ctx context.Context) {
+	p.polls++
+	if err := printPodStatus(ctx, p.clientset, p.namespace); err != nil {
+		// A cancelled poll is the program shutting down, not a failure
+		if ctx.Err() != nil {
+			return
 		}
-		fmt.Println("---")
+		p.failures++
+		log.Printf("Failed to list pods: %v", err)
 	}
 }
 
+// run polls until ctx is cancelled. Every pass is a full LIST request, spaced
+// interval apart plus up to jitter*interval, so many pollers do not hit the
+// API server in lockstep.
+func (p *poller) run(ctx context.Context, interval time.Duration, jitter float64) {
+	wait.JitterUntilWithContext(ctx, p.poll, interval, jitter, true)
+	fmt.Println("Stopped polling")
+}
+
+// printSummary reports how much API traffic the polls generated
+func (p *poller) printSummary(elapsed time.Duration) {
+	requests, bytes := p.usage.requests.Load(), p.usage.bytes.Load()
+	fmt.Println("=== Summary ===")
+	fmt.Printf("Polls: %d in %s (%d failed)\n", p.polls, elapsed.Round(time.Second), p.failures)
+	fmt.Printf("API requests: %d\n", requests)
+	fmt.Printf("Bytes received: %s\n", humanBytes(bytes))
+	if p.polls > 0 {
+		fmt.Printf("Per poll: %.1f requests, %s\n", float64(requests)/float64(p.polls), humanBytes(bytes/int64(p.polls)))
+	}
+	fmt.Println("Every poll downloads the full pod list again. An informer lists once and")
+	fmt.Println("then only receives changes over a single watch (see 04_informer_events).")
+}
+
 func main() {
-	home, _ := os.UserHomeDir()
-	config, _ := clientcmd.BuildConfigFromFlags("", filepath.Join(home, ".kube/config"))
-	clientset, _ := kubernetes.NewForConfig(config)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, ".kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "03-without-informer")
+	// Count every request and response byte that goes through this clientset
+	usage := &apiUsage{}
+	config.Wrap(usage.wrap)
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	podStatus(clientset)
+	p := &poller{clientset: clientset, namespace: *namespace, usage: usage}
+	start := time.Now()
+	p.run(ctx, *interval, *jitter)
+	p.printSummary(time.Since(start))
 }
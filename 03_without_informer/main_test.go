@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func newPod(namespace, name string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestPrintPodStatus(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newPod("default", "web", corev1.PodRunning),
+		newPod("default", "batch", corev1.PodSucceeded),
+		newPod("kube-system", "coredns", corev1.PodRunning),
+	)
+	output := testutil.CaptureOutput(t)
+
+	if err := printPodStatus(context.TODO(), clientset, "default"); err != nil {
+		t.Fatalf("printPodStatus: %v", err)
+	}
+	testutil.WaitForOutput(t, output, "web: Running\n", "batch: Succeeded\n", "---\n")
+	if strings.Contains(output(), "coredns") {
+		t.Errorf("pods outside default were printed:\n%s", output())
+	}
+}
+
+func TestEveryPassListsFromTheAPIServer(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod("default", "web", corev1.PodRunning))
+	testutil.CaptureOutput(t)
+
+	for i := 0; i < 3; i++ {
+		if err := printPodStatus(context.TODO(), clientset, "default"); err != nil {
+			t.Fatalf("printPodStatus: %v", err)
+		}
+	}
+
+	// Without an informer nothing is cached: three passes are three LIST calls
+	lists := 0
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "pods" {
+			lists++
+		}
+	}
+	if lists != 3 {
+		t.Errorf("got %d pod LIST requests, want 3", lists)
+	}
+}
+
+func TestFailedPollsAreCounted(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewServiceUnavailable("etcd is down")
+	})
+	p := &poller{clientset: clientset, namespace: "default", usage: &apiUsage{}}
+
+	p.poll(context.TODO())
+	p.poll(context.TODO())
+	if p.polls != 2 || p.failures != 2 {
+		t.Errorf("polls = %d, failures = %d, want 2 and 2", p.polls, p.failures)
+	}
+}
+
+func TestRunPollsUntilCancelled(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod("default", "web", corev1.PodRunning))
+	output := testutil.CaptureOutput(t)
+	p := &poller{clientset: clientset, namespace: "default", usage: &apiUsage{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.run(ctx, 10*time.Millisecond, 0.5)
+		close(done)
+	}()
+	testutil.WaitForOutput(t, output, "web: Running\n---\nweb: Running\n---\n")
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("poller still running after cancel")
+	}
+	testutil.WaitForOutput(t, output, "Stopped polling\n")
+	if p.polls < 2 || p.failures != 0 {
+		t.Errorf("polls = %d, failures = %d, want at least 2 and 0", p.polls, p.failures)
+	}
+}
+
+func TestUsageCountsRequestsAndBytes(t *testing.T) {
+	body, err := json.Marshal(&corev1.PodList{
+		TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"},
+		Items:    []corev1.Pod{*newPod("default", "web", corev1.PodRunning)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	usage := &apiUsage{}
+	config := &rest.Config{Host: server.URL}
+	config.Wrap(usage.wrap)
+	clientset := kubernetes.NewForConfigOrDie(config)
+	testutil.CaptureOutput(t)
+
+	for i := 0; i < 2; i++ {
+		if err := printPodStatus(context.TODO(), clientset, "default"); err != nil {
+			t.Fatalf("printPodStatus: %v", err)
+		}
+	}
+	if got := usage.requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+	if got, want := usage.bytes.Load(), int64(2*len(body)); got != want {
+		t.Errorf("bytes = %d, want %d", got, want)
+	}
+}
+
+func TestSummaryReportsCostPerPoll(t *testing.T) {
+	usage := &apiUsage{}
+	usage.requests.Store(4)
+	usage.bytes.Store(8 << 10)
+	p := &poller{usage: usage, polls: 4, failures: 1}
+	output := testutil.CaptureOutput(t)
+
+	p.printSummary(90 * time.Second)
+	testutil.WaitForOutput(t, output,
+		"Polls: 4 in 1m30s (1 failed)\n",
+		"API requests: 4\n",
+		"Bytes received: 8.0Ki\n",
+		"Per poll: 1.0 requests, 2.0Ki\n",
+	)
+}
@@ -0,0 +1,58 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+	"github.com/shamimice03/mastering-k8s-client-go/testutil/envtest"
+)
+
+// apiPod returns a pod the API server accepts; unlike the fake clientset it validates the spec
+func apiPod(namespace, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "nginx:1.21"}},
+		},
+	}
+}
+
+func TestHandlersAgainstAPIServer(t *testing.T) {
+	_, clientset := envtest.Start(t)
+	ctx := context.TODO()
+	pods := clientset.CoreV1().Pods("default")
+
+	if _, err := pods.Create(ctx, apiPod("default", "web"), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create web: %v", err)
+	}
+	output := testutil.CaptureOutput(t)
+	podInformer := startInformer(t, clientset)
+	addPodHandlers(podInformer, startDiagnostics(t))
+	testutil.WaitForOutput(t, output, "(+) Pod added: default/web\n", "[SECOND-CONTROLLER] Also saw pod: default/web\n")
+
+	api, err := pods.Create(ctx, apiPod("default", "api"), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("create api: %v", err)
+	}
+	api.Labels = map[string]string{"app": "api"}
+	if _, err := pods.Update(ctx, api, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update api: %v", err)
+	}
+	// Without a kubelet, an unscheduled pod is removed immediately
+	if err := pods.Delete(ctx, "web", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("delete web: %v", err)
+	}
+
+	testutil.WaitForOutput(t, output,
+		"(+) Pod added: default/api\n",
+		"[SECOND-CONTROLLER] Also saw pod: default/api\n",
+		"(*) Pod updated: default/api\n",
+		"(-) Pod deleted: default/web\n",
+	)
+}
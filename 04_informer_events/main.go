@@ -5,17 +5,31 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/handlerdiag"
+	"github.com/shamimice03/mastering-k8s-client-go/tombstone"
+	"github.com/shamimice03/mastering-k8s-client-go/watcherrors"
+)
+
+var (
+	slowHandler         = flag.Duration("slow-handler", 100*time.Millisecond, "warn when a handler takes longer than this for one event")
+	maxBacklog          = flag.Int("max-backlog", 100, "warn when this many events are queued for one handler")
+	diagnosticsInterval = flag.Duration("diagnostics-interval", 30*time.Second, "how often per-handler statistics are printed (0 disables)")
 )
 
 // createClientset creates and returns a Kubernetes clientset
@@ -33,6 +47,7 @@ func createClientset() *kubernetes.Clientset {
 	if err != nil {
 		klog.Fatalf("Failed to build config: %v", err)
 	}
+	clientid.Configure(config, "04-informer-events")
 	// Create clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -42,17 +57,19 @@ func createClientset() *kubernetes.Clientset {
 }
 
 // createPodInformer creates and returns a SharedIndexInformer for pods
-func createPodInformer(clientset *kubernetes.Clientset) cache.SharedIndexInformer {
+func createPodInformer(clientset kubernetes.Interface) cache.SharedIndexInformer {
 	// Create SharedIndexInformer with ListWatch functions
 	informer := cache.NewSharedIndexInformer(
 		&cache.ListWatch{
+			// ctx is the one the informer runs with, so cancelling it also aborts an
+			// in-flight LIST or WATCH request
 			// List function - gets initial state of pods
 			ListWithContextFunc: func(ctx context.Context, options v1.ListOptions) (runtime.Object, error) {
-				return clientset.CoreV1().Pods("").List(context.TODO(), options)
+				return clientset.CoreV1().Pods("").List(ctx, options)
 			},
 			// Watch function - creates streaming connection for pod changes
 			WatchFuncWithContext: func(ctx context.Context, options v1.ListOptions) (watch.Interface, error) {
-				return clientset.CoreV1().Pods("").Watch(context.TODO(), options)
+				return clientset.CoreV1().Pods("").Watch(ctx, options)
 			},
 		},
 		&corev1.Pod{},    // Object type to watch
@@ -68,21 +85,46 @@ func main() {
 	// Create ONE pod informer instance - this will be shared among multiple handlers
 	podInformer := createPodInformer(clientset)
 
-	// Create stop channel for graceful shutdown
-	stopCh := make(chan struct{})
-	defer close(stopCh)
+	// Surface LIST and WATCH failures instead of letting the reflector retry silently
+	if err := podInformer.SetWatchErrorHandlerWithContext(watcherrors.Handle); err != nil {
+		klog.Fatalf("Failed to set watch error handler: %v", err)
+	}
+
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// The informer logs through the logger in ctx, so its lines carry this name
+	ctx = klog.NewContext(ctx, klog.LoggerWithName(klog.Background(), "pod-informer"))
+	logger := klog.FromContext(ctx)
 
 	// Start informer - creates SINGLE watch connection to API server
-	go podInformer.Run(stopCh)
+	var informers wait.Group
+	informers.StartWithContext(ctx, podInformer.RunWithContext)
 
-	// Wait for caches to sync with initial data
-	fmt.Println("Waiting for caches to sync...")
-	if !cache.WaitForCacheSync(stopCh, podInformer.HasSynced) {
-		klog.Fatal("Failed to sync caches")
+	// Wait for caches to sync with initial data; progress is logged through ctx.
+	// The wait only fails when ctx is cancelled before the cache synced.
+	if !cache.WaitForNamedCacheSyncWithContext(ctx, podInformer.HasSynced) {
+		informers.Wait()
+		return
 	}
 
+	// Register both handlers on the same informer, each measured separately
+	diag := handlerdiag.New(*slowHandler, *maxBacklog)
+	addPodHandlers(podInformer, diag)
+	diag.Run(*diagnosticsInterval, ctx.Done())
+
+	// When a pod changes, BOTH handlers get notified from the same event stream
+	// Only ONE HTTP connection is used for both handlers (efficient!)
+	<-ctx.Done()
+	logger.Info("Shutting down, waiting for the informer to stop")
+	informers.Wait()
+	logger.Info("Exited cleanly")
+}
+
+// addPodHandlers registers two independent handlers on one shared informer
+func addPodHandlers(podInformer cache.SharedIndexInformer, diag *handlerdiag.Diagnostics) {
 	// SHARED ASPECT: First handler - multiple handlers can share the same informer
-	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	podInformer.AddEventHandler(diag.Instrument("FirstController", cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			pod := obj.(*corev1.Pod)
 			fmt.Printf("(+) Pod added: %s/%s\n", pod.Namespace, pod.Name)
@@ -92,21 +134,20 @@ func main() {
 			fmt.Printf("(*) Pod updated: %s/%s\n", pod.Namespace, pod.Name)
 		},
 		DeleteFunc: func(obj interface{}) {
-			pod := obj.(*corev1.Pod)
+			pod, ok := tombstone.Unwrap[*corev1.Pod](obj)
+			if !ok {
+				return
+			}
 			fmt.Printf("(-) Pod deleted: %s/%s\n", pod.Namespace, pod.Name)
 		},
-	})
+	}))
 
 	// SHARED ASPECT: Second handler on the SAME informer instance
 	// Both handlers share the same watch connection and cache
-	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	podInformer.AddEventHandler(diag.Instrument("SecondController", cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			pod := obj.(*corev1.Pod)
 			fmt.Printf("[SECOND-CONTROLLER] Also saw pod: %s/%s\n", pod.Namespace, pod.Name)
 		},
-	})
-
-	// When a pod changes, BOTH handlers get notified from the same event stream
-	// Only ONE HTTP connection is used for both handlers (efficient!)
-	<-stopCh
+	}))
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/handlerdiag"
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func newPod(namespace, name string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+}
+
+// startInformer runs the example's informer against a fake clientset until the test ends
+func startInformer(t *testing.T, clientset kubernetes.Interface) cache.SharedIndexInformer {
+	t.Helper()
+	podInformer := createPodInformer(clientset)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	go podInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, podInformer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+	return podInformer
+}
+
+// startDiagnostics runs handler diagnostics without periodic reports until the test ends
+func startDiagnostics(t *testing.T) *handlerdiag.Diagnostics {
+	diag := handlerdiag.New(time.Second, 1000)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	diag.Run(0, stopCh)
+	return diag
+}
+
+func TestInformerCachesPodsFromAllNamespaces(t *testing.T) {
+	h := testutil.NewHarness(t,
+		newPod("default", "web"),
+		newPod("kube-system", "coredns"),
+	)
+	podInformer := startInformer(t, h.Clientset)
+
+	if got := len(podInformer.GetStore().List()); got != 2 {
+		t.Errorf("cached %d pods, want 2", got)
+	}
+	if _, exists, _ := podInformer.GetStore().GetByKey("kube-system/coredns"); !exists {
+		t.Error("kube-system/coredns missing from the cache")
+	}
+}
+
+func TestBothHandlersSeeExistingPods(t *testing.T) {
+	h := testutil.NewHarness(t,
+		newPod("default", "web"),
+		newPod("kube-system", "coredns"),
+	)
+	output := testutil.CaptureOutput(t)
+	podInformer := startInformer(t, h.Clientset)
+
+	// Handlers registered after the cache synced are replayed the cached pods as adds
+	addPodHandlers(podInformer, startDiagnostics(t))
+	testutil.WaitForOutput(t, output,
+		"(+) Pod added: default/web\n",
+		"(+) Pod added: kube-system/coredns\n",
+		"[SECOND-CONTROLLER] Also saw pod: default/web\n",
+		"[SECOND-CONTROLLER] Also saw pod: kube-system/coredns\n",
+	)
+}
+
+func TestHandlersSeeWatchEvents(t *testing.T) {
+	web := newPod("default", "web")
+	h := testutil.NewHarness(t, web)
+	output := testutil.CaptureOutput(t)
+	podInformer := startInformer(t, h.Clientset)
+	addPodHandlers(podInformer, startDiagnostics(t))
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+
+	api := newPod("default", "api")
+	h.Add(api)
+	api.Labels = map[string]string{"app": "api"}
+	h.Update(api)
+	h.Delete(web)
+
+	testutil.WaitForOutput(t, output,
+		"(+) Pod added: default/api\n",
+		"[SECOND-CONTROLLER] Also saw pod: default/api\n",
+		"(*) Pod updated: default/api\n",
+		"(-) Pod deleted: default/web\n",
+	)
+}
+
+func TestHandlersShareOneWatch(t *testing.T) {
+	h := testutil.NewHarness(t, newPod("default", "web"))
+	testutil.CaptureOutput(t)
+	podInformer := startInformer(t, h.Clientset)
+	addPodHandlers(podInformer, startDiagnostics(t))
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+
+	// Two handlers, yet the API server saw a single LIST and a single WATCH
+	verbs := map[string]int{}
+	for _, action := range h.Clientset.Actions() {
+		verbs[action.GetVerb()]++
+	}
+	if verbs["list"] != 1 || verbs["watch"] != 1 {
+		t.Errorf("got %d LIST and %d WATCH requests, want 1 each", verbs["list"], verbs["watch"])
+	}
+}
+
+func TestCancellingTheContextAbortsAnInFlightList(t *testing.T) {
+	// An API server whose LIST never answers, reporting when the client gives up
+	listStarted, listAborted := make(chan struct{}), make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(listStarted)
+		select {
+		case <-r.Context().Done():
+			close(listAborted)
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	clientset := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	podInformer := createPodInformer(clientset)
+	done := make(chan struct{})
+	go func() {
+		podInformer.RunWithContext(ctx)
+		close(done)
+	}()
+	<-listStarted
+	cancel()
+
+	for name, ch := range map[string]chan struct{}{"informer": done, "LIST request": listAborted} {
+		select {
+		case <-ch:
+		case <-time.After(testutil.Timeout):
+			t.Fatalf("%s still running after the context was cancelled", name)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+// These tests pin down what a SharedIndexInformer promises when several
+// handlers share it:
+//   - each handler sees every event, in the order the watch delivered them
+//   - handlers do not wait for each other: each has its own goroutine and its
+//     own pending buffer, so there is no ordering *between* handlers
+//   - that buffer starts at 1024 notifications and grows without bound; a slow
+//     handler costs memory, never latency for the others
+
+// linesWithPrefix returns the printed lines starting with any of the prefixes, in order
+func linesWithPrefix(output string, prefixes ...string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(line, prefix) {
+				lines = append(lines, line)
+				break
+			}
+		}
+	}
+	return lines
+}
+
+func TestEachHandlerSeesEventsInWatchOrder(t *testing.T) {
+	h := testutil.NewHarness(t, newPod("default", "seed"))
+	output := testutil.CaptureOutput(t)
+	podInformer := startInformer(t, h.Clientset)
+	addPodHandlers(podInformer, startDiagnostics(t))
+	recorder := &testutil.Recorder{}
+	podInformer.AddEventHandler(recorder)
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+
+	// Interleave the lifecycles of two pods
+	a, b := newPod("default", "a"), newPod("default", "b")
+	h.Add(a)
+	h.Add(b)
+	h.Update(a)
+	h.Delete(a)
+	h.Update(b)
+	h.Delete(b)
+	recorder.Expect(t,
+		"add default/seed",
+		"add default/a", "add default/b", "update default/a",
+		"delete default/a", "update default/b", "delete default/b",
+	)
+	testutil.WaitForOutput(t, output, "(-) Pod deleted: default/b\n", "[SECOND-CONTROLLER] Also saw pod: default/b\n")
+
+	// The first handler prints every event, in exactly the watch order
+	first := linesWithPrefix(output(), "(+)", "(*)", "(-)")
+	want := []string{
+		"(+) Pod added: default/seed",
+		"(+) Pod added: default/a",
+		"(+) Pod added: default/b",
+		"(*) Pod updated: default/a",
+		"(-) Pod deleted: default/a",
+		"(*) Pod updated: default/b",
+		"(-) Pod deleted: default/b",
+	}
+	if !slices.Equal(first, want) {
+		t.Errorf("first handler printed\n%s\nwant\n%s", strings.Join(first, "\n"), strings.Join(want, "\n"))
+	}
+
+	// The second handler only handles adds, still in watch order
+	second := linesWithPrefix(output(), "[SECOND-CONTROLLER]")
+	if !slices.Equal(second, []string{
+		"[SECOND-CONTROLLER] Also saw pod: default/seed",
+		"[SECOND-CONTROLLER] Also saw pod: default/a",
+		"[SECOND-CONTROLLER] Also saw pod: default/b",
+	}) {
+		t.Errorf("second handler printed %q", second)
+	}
+}
+
+func TestSlowHandlerDoesNotBlockTheOthers(t *testing.T) {
+	h := testutil.NewHarness(t)
+	output := testutil.CaptureOutput(t)
+	podInformer := startInformer(t, h.Clientset)
+	addPodHandlers(podInformer, startDiagnostics(t))
+	recorder := &testutil.Recorder{}
+	podInformer.AddEventHandler(recorder)
+	release := make(chan struct{})
+	slow := &testutil.Recorder{Gate: release}
+	podInformer.AddEventHandler(slow)
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+
+	// More events than the initial 1024-slot buffer of the stuck handler
+	const pods = 1500
+	var want []string
+	for i := 0; i < pods; i++ {
+		pod := newPod("default", fmt.Sprintf("pod-%04d", i))
+		want = append(want, "add "+testutil.Key(pod))
+		h.Add(pod)
+		if i%50 == 49 {
+			recorder.WaitForCount(t, i+1)
+		}
+	}
+	recorder.WaitForCount(t, pods)
+	testutil.WaitForOutput(t, output,
+		"(+) Pod added: default/pod-1499\n",
+		"[SECOND-CONTROLLER] Also saw pod: default/pod-1499\n",
+	)
+
+	// Both example handlers finished while the slow one is still on its first event
+	if got := len(slow.Events()); got != 0 {
+		t.Fatalf("slow handler completed %d events while blocked", got)
+	}
+
+	// Once unblocked it catches up on everything, in order, with nothing dropped
+	close(release)
+	slow.Expect(t, want...)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func TestDeleteMissedByTheWatchIsReported(t *testing.T) {
+	web := newPod("default", "web")
+	h := testutil.NewHarness(t, web)
+	output := testutil.CaptureOutput(t)
+	podInformer := startInformer(t, h.Clientset)
+	addPodHandlers(podInformer, startDiagnostics(t))
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+
+	// The relist delivers the delete as a tombstone, which used to panic
+	h.DeleteUnseen(web)
+	testutil.WaitForOutput(t, output, "(-) Pod deleted: default/web\n")
+}
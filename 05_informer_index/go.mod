@@ -10,8 +10,10 @@ require (
 )
 
 require (
+	github.com/blang/semver/v4 v4.0.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/emicklei/go-restful/v3 v3.11.0 // indirect
+	github.com/evanphx/json-patch/v5 v5.9.11 // indirect
 	github.com/fxamacker/cbor/v2 v2.7.0 // indirect
 	github.com/go-logr/logr v1.4.2 // indirect
 	github.com/go-openapi/jsonpointer v0.21.0 // indirect
@@ -40,10 +42,28 @@ require (
 	gopkg.in/evanphx/json-patch.v4 v4.12.0 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
+	k8s.io/apiextensions-apiserver v0.33.0 // indirect
 	k8s.io/kube-openapi v0.0.0-20250318190949-c8a335a9a2ff // indirect
 	k8s.io/utils v0.0.0-20241104100929-3ea5e8cea738 // indirect
+	sigs.k8s.io/controller-runtime v0.21.0 // indirect
 	sigs.k8s.io/json v0.0.0-20241010143419-9aa6b5e7a4b3 // indirect
 	sigs.k8s.io/randfill v1.0.0 // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.6.0 // indirect
 	sigs.k8s.io/yaml v1.4.0 // indirect
 )
+
+require github.com/shamimice03/mastering-k8s-client-go/testutil v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/testutil => ../testutil
+
+require github.com/shamimice03/mastering-k8s-client-go/clientid v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/clientid => ../clientid
+
+require github.com/shamimice03/mastering-k8s-client-go/watcherrors v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/watcherrors => ../watcherrors
+
+require github.com/shamimice03/mastering-k8s-client-go/cachetransform v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/cachetransform => ../cachetransform
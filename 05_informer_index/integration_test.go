@@ -0,0 +1,63 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+	"github.com/shamimice03/mastering-k8s-client-go/testutil/envtest"
+)
+
+func TestNodeIndexFollowsScheduling(t *testing.T) {
+	_, clientset := envtest.Start(t)
+	ctx := context.TODO()
+	pods := clientset.CoreV1().Pods("default")
+
+	pod := newPod("default", "web", "")
+	pod.Spec.Containers = []corev1.Container{{Name: "app", Image: "nginx:1.21"}}
+	if _, err := pods.Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	podInformer := createPodInformer(clientset)
+	recorder := &testutil.Recorder{}
+	podInformer.AddEventHandler(recorder)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go podInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, podInformer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+	indexer := podInformer.GetIndexer()
+	nodePods := func(node string) []string {
+		objs, _ := indexer.ByIndex("node", node)
+		return podNames(t, objs)
+	}
+	if got := nodePods(""); !slices.Equal(got, []string{"default/web"}) {
+		t.Errorf("unscheduled pods = %v", got)
+	}
+
+	// spec.nodeName is immutable; the scheduler sets it through the binding subresource
+	binding := &corev1.Binding{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Target:     corev1.ObjectReference{Kind: "Node", Name: "node-a"},
+	}
+	if err := pods.Bind(ctx, binding, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	recorder.Expect(t, "add default/web", "update default/web")
+
+	if got := nodePods("node-a"); !slices.Equal(got, []string{"default/web"}) {
+		t.Errorf("pods on node-a = %v", got)
+	}
+	if got := nodePods(""); len(got) != 0 {
+		t.Errorf("unscheduled pods after bind = %v", got)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -16,6 +18,10 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
+
+	"github.com/shamimice03/mastering-k8s-client-go/cachetransform"
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/watcherrors"
 )
 
 // createClientset creates and returns a Kubernetes clientset
@@ -33,6 +39,7 @@ func createClientset() *kubernetes.Clientset {
 	if err != nil {
 		klog.Fatalf("Failed to build config: %v", err)
 	}
+	clientid.Configure(config, "05-informer-index")
 	// Create clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -48,17 +55,19 @@ func podNodeIndexFunc(obj interface{}) ([]string, error) {
 }
 
 // createPodInformer creates and returns a SharedIndexInformer for pods
-func createPodInformer(clientset *kubernetes.Clientset) cache.SharedIndexInformer {
+func createPodInformer(clientset kubernetes.Interface) cache.SharedIndexInformer {
 	// Create SharedIndexInformer with ListWatch functions
 	informer := cache.NewSharedIndexInformer(
 		&cache.ListWatch{
+			// ctx is the one the informer runs with, so cancelling it also aborts an
+			// in-flight LIST or WATCH request
 			// List function - gets initial state of pods
 			ListWithContextFunc: func(ctx context.Context, options v1.ListOptions) (runtime.Object, error) {
-				return clientset.CoreV1().Pods("").List(context.TODO(), options)
+				return clientset.CoreV1().Pods("").List(ctx, options)
 			},
 			// Watch function - creates streaming connection for pod changes
 			WatchFuncWithContext: func(ctx context.Context, options v1.ListOptions) (watch.Interface, error) {
-				return clientset.CoreV1().Pods("").Watch(context.TODO(), options)
+				return clientset.CoreV1().Pods("").Watch(ctx, options)
 			},
 		},
 		&corev1.Pod{},  // Object type to watch
@@ -75,16 +84,27 @@ func main() {
 	clientset := createClientset()
 	// Create pod informer
 	podInformer := createPodInformer(clientset)
-	// Create stop channel for graceful shutdown
-	stopCh := make(chan struct{})
-	defer close(stopCh)
+	// Strip managedFields and last-applied annotations before pods enter the cache
+	stripper := &cachetransform.Stripper{}
+	if err := podInformer.SetTransform(stripper.Transform); err != nil {
+		klog.Fatalf("Failed to set transform: %v", err)
+	}
+	// Surface LIST and WATCH failures instead of letting the reflector retry silently
+	if err := podInformer.SetWatchErrorHandlerWithContext(watcherrors.Handle); err != nil {
+		klog.Fatalf("Failed to set watch error handler: %v", err)
+	}
+	// Stop on Ctrl-C or SIGTERM; returning from main stops the informer too
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// The informer logs through the logger in ctx, so its lines carry this name
+	ctx = klog.NewContext(ctx, klog.LoggerWithName(klog.Background(), "pod-informer"))
 	// Start informers in background
-	go podInformer.Run(stopCh)
-	// Wait for caches to sync with initial data
-	fmt.Println("Waiting for caches to sync...")
-	if !cache.WaitForCacheSync(stopCh, podInformer.HasSynced) {
+	go podInformer.RunWithContext(ctx)
+	// Wait for caches to sync with initial data; progress is logged through ctx
+	if !cache.WaitForNamedCacheSyncWithContext(ctx, podInformer.HasSynced) {
 		klog.Fatal("Failed to sync caches")
 	}
+	stripper.PrintReport(podInformer.GetStore())
 
 	// Inefficient: O(n) search through all pods without indexing
 	// Get stores and list all cached objects
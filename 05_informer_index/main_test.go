@@ -0,0 +1,138 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func newPod(namespace, name, nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+// podNames returns the sorted namespace/name keys of indexed pods
+func podNames(t *testing.T, objs []interface{}) []string {
+	t.Helper()
+	var names []string
+	for _, obj := range objs {
+		pod := obj.(*corev1.Pod)
+		names = append(names, pod.Namespace+"/"+pod.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPodNodeIndexFunc(t *testing.T) {
+	keys, err := podNodeIndexFunc(newPod("default", "web", "node-a"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(keys, []string{"node-a"}) {
+		t.Errorf("got %v, want [node-a]", keys)
+	}
+
+	// Pending pods are indexed under the empty node name until scheduled
+	keys, _ = podNodeIndexFunc(newPod("default", "pending", ""))
+	if !slices.Equal(keys, []string{""}) {
+		t.Errorf("got %v, want [\"\"]", keys)
+	}
+}
+
+func TestIndexLookups(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newPod("default", "web", "node-a"),
+		newPod("default", "api", "node-b"),
+		newPod("kube-system", "coredns", "node-a"),
+		newPod("default", "pending", ""),
+	)
+	podInformer := createPodInformer(clientset)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go podInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, podInformer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+	indexer := podInformer.GetIndexer()
+
+	tests := []struct {
+		index, value string
+		want         []string
+	}{
+		{"namespace", "default", []string{"default/api", "default/pending", "default/web"}},
+		{"namespace", "kube-system", []string{"kube-system/coredns"}},
+		{"node", "node-a", []string{"default/web", "kube-system/coredns"}},
+		{"node", "node-b", []string{"default/api"}},
+		{"node", "", []string{"default/pending"}},
+		{"node", "node-missing", nil},
+	}
+	for _, tt := range tests {
+		objs, err := indexer.ByIndex(tt.index, tt.value)
+		if err != nil {
+			t.Fatalf("ByIndex(%q, %q): %v", tt.index, tt.value, err)
+		}
+		if got := podNames(t, objs); !slices.Equal(got, tt.want) {
+			t.Errorf("ByIndex(%q, %q) = %v, want %v", tt.index, tt.value, got, tt.want)
+		}
+	}
+
+	nodes := indexer.ListIndexFuncValues("node")
+	sort.Strings(nodes)
+	if !slices.Equal(nodes, []string{"", "node-a", "node-b"}) {
+		t.Errorf("node index values = %v", nodes)
+	}
+
+	if _, err := indexer.ByIndex("zone", "a"); err == nil {
+		t.Error("querying an unregistered index should fail")
+	}
+}
+
+func TestIndexesFollowWatchEvents(t *testing.T) {
+	web := newPod("default", "web", "")
+	h := testutil.NewHarness(t, web)
+	podInformer := createPodInformer(h.Clientset)
+	recorder := &testutil.Recorder{}
+	podInformer.AddEventHandler(recorder)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go podInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, podInformer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+	indexer := podInformer.GetIndexer()
+
+	nodePods := func(node string) []string {
+		objs, _ := indexer.ByIndex("node", node)
+		return podNames(t, objs)
+	}
+
+	// Scheduling moves the pod from the "" bucket to its node
+	h.Update(newPod("default", "web", "node-a"))
+	recorder.Expect(t, "add default/web", "update default/web")
+	if got := nodePods(""); len(got) != 0 {
+		t.Errorf("unscheduled pods = %v, want none", got)
+	}
+	if got := nodePods("node-a"); !slices.Equal(got, []string{"default/web"}) {
+		t.Errorf("pods on node-a = %v", got)
+	}
+
+	// Deleting the pod removes it from every index
+	h.Delete(newPod("default", "web", "node-a"))
+	recorder.Expect(t, "add default/web", "update default/web", "delete default/web")
+	if got := nodePods("node-a"); len(got) != 0 {
+		t.Errorf("pods on node-a after delete = %v", got)
+	}
+	if objs, _ := indexer.ByIndex("namespace", "default"); len(objs) != 0 {
+		t.Errorf("default namespace still indexes %d pods", len(objs))
+	}
+}
@@ -47,3 +47,19 @@ require (
 	sigs.k8s.io/structured-merge-diff/v4 v4.6.0 // indirect
 	sigs.k8s.io/yaml v1.4.0 // indirect
 )
+
+require github.com/shamimice03/mastering-k8s-client-go/clientid v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/clientid => ../clientid
+
+require github.com/shamimice03/mastering-k8s-client-go/watcherrors v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/watcherrors => ../watcherrors
+
+require github.com/shamimice03/mastering-k8s-client-go/tombstone v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/tombstone => ../tombstone
+
+require github.com/shamimice03/mastering-k8s-client-go/testutil v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/testutil => ../testutil
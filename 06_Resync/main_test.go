@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func TestResyncRedeliversCachedPods(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", ResourceVersion: "7"},
+	})
+	output := testutil.CaptureOutput(t)
+
+	// One second is the shortest resync period an informer accepts
+	podInformer := createPodInformer(clientset, time.Second)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	go podInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, podInformer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+	addPodHandlers(podInformer)
+
+	// Nothing changed on the server, yet UpdateFunc fires with identical versions
+	testutil.WaitForOutput(t, output,
+		"(+) Pod added: default/web\n",
+		"[SECOND-CONTROLLER] Also saw pod: default/web\n",
+		"(*) Pod updated: default/web\n",
+		"Old pod version 7\nNew pod version 7\nResync event (same ResourceVersion)\n",
+	)
+	if strings.Contains(output(), "Real update event") {
+		t.Errorf("a resync was reported as a real update:\n%s", output())
+	}
+}
+
+// handlerCapture is an informer that keeps the handlers added to it, so a test
+// can hand them events the fake clientset cannot produce
+type handlerCapture struct {
+	cache.SharedIndexInformer
+	handlers []cache.ResourceEventHandler
+}
+
+func (c *handlerCapture) AddEventHandler(handler cache.ResourceEventHandler) (cache.ResourceEventHandlerRegistration, error) {
+	c.handlers = append(c.handlers, handler)
+	return nil, nil
+}
+
+func TestDeleteHandlerAcceptsTombstones(t *testing.T) {
+	output := testutil.CaptureOutput(t)
+	informer := &handlerCapture{}
+	addPodHandlers(informer)
+
+	// What an informer delivers when its watch missed the delete and a relist noticed
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	for _, handler := range informer.handlers {
+		handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "default/web", Obj: pod})
+	}
+	testutil.WaitForOutput(t, output, "(-) Pod deleted: default/web\n")
+}
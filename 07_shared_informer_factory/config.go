@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// informerConfig is the YAML file --config reads (see informers.yaml). It
+// declares the informers to run instead of the three hard-coded controllers.
+type informerConfig struct {
+	// Namespace and Resync are the defaults of every resource
+	Namespace string           `json:"namespace,omitempty"`
+	Resync    metav1.Duration  `json:"resync,omitempty"`
+	Resources []resourceConfig `json:"resources"`
+}
+
+// resourceConfig is one informer and the handlers attached to it
+type resourceConfig struct {
+	// Resource is group/version/resource, or v1/<resource> for the core group
+	Resource      string           `json:"resource"`
+	Namespace     string           `json:"namespace,omitempty"`
+	LabelSelector string           `json:"labelSelector,omitempty"`
+	FieldSelector string           `json:"fieldSelector,omitempty"`
+	Resync        *metav1.Duration `json:"resync,omitempty"`
+	Indexes       []indexConfig    `json:"indexes,omitempty"`
+	Handlers      []handlerConfig  `json:"handlers"`
+
+	// gvr is parsed from Resource by validate
+	gvr schema.GroupVersionResource
+}
+
+// indexConfig adds an index on a label or on a field of the object
+type indexConfig struct {
+	Name string `json:"name"`
+	// Label is a label key; objects without the label are not indexed
+	Label string `json:"label,omitempty"`
+	// Field is a dotted path such as spec.nodeName; an empty value is not indexed
+	Field string `json:"field,omitempty"`
+}
+
+// Built-in handler types
+const (
+	handlerLog  = "log"
+	handlerDiff = "diff"
+)
+
+// handlerConfig attaches one built-in handler
+type handlerConfig struct {
+	// Name identifies the handler in diagnostics and metrics
+	Name string `json:"name"`
+	// Type is log (print each event) or diff (print what an update changed)
+	Type string `json:"type"`
+	// Events filters the events handled: add, update, delete. Empty means all.
+	Events []string `json:"events,omitempty"`
+	// Prefix starts every line; it defaults to [<name>]
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// loadConfig reads and validates the file at path
+func loadConfig(path string) (*informerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg informerConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// validate checks the whole file up front, so a typo fails at startup and not
+// after some informers are already running
+func (cfg *informerConfig) validate() error {
+	if len(cfg.Resources) == 0 {
+		return fmt.Errorf("no resources configured")
+	}
+	for i := range cfg.Resources {
+		r := &cfg.Resources[i]
+		gvr, err := parseGVR(r.Resource)
+		if err != nil {
+			return fmt.Errorf("resources[%d]: %w", i, err)
+		}
+		r.gvr = gvr
+		if _, err := labels.Parse(r.LabelSelector); err != nil {
+			return fmt.Errorf("%s: invalid labelSelector: %w", r.Resource, err)
+		}
+		if _, err := fields.ParseSelector(r.FieldSelector); err != nil {
+			return fmt.Errorf("%s: invalid fieldSelector: %w", r.Resource, err)
+		}
+		for _, index := range r.Indexes {
+			if index.Name == "" || (index.Label == "") == (index.Field == "") {
+				return fmt.Errorf("%s: index %q needs a name and exactly one of label or field", r.Resource, index.Name)
+			}
+		}
+		if len(r.Handlers) == 0 {
+			return fmt.Errorf("%s: no handlers; an informer nobody listens to only costs memory", r.Resource)
+		}
+		for _, h := range r.Handlers {
+			if h.Name == "" {
+				return fmt.Errorf("%s: handler without a name", r.Resource)
+			}
+			if h.Type != handlerLog && h.Type != handlerDiff {
+				return fmt.Errorf("%s: handler %s has unknown type %q, want %s or %s", r.Resource, h.Name, h.Type, handlerLog, handlerDiff)
+			}
+			for _, event := range h.Events {
+				if event != "add" && event != "update" && event != "delete" {
+					return fmt.Errorf("%s: handler %s has unknown event %q", r.Resource, h.Name, event)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// namespace is the namespace the informer of r watches; --namespace applies
+// when neither the resource nor the file sets one
+func (cfg *informerConfig) namespace(r resourceConfig, flagNamespace string) string {
+	switch {
+	case r.Namespace != "":
+		return r.Namespace
+	case cfg.Namespace != "":
+		return cfg.Namespace
+	default:
+		return flagNamespace
+	}
+}
+
+// resync is the resync period of the handlers of r
+func (cfg *informerConfig) resync(r resourceConfig) time.Duration {
+	if r.Resync != nil {
+		return r.Resync.Duration
+	}
+	return cfg.Resync.Duration
+}
+
+// parseGVR parses group/version/resource, or v1/<resource> for the core group
+func parseGVR(s string) (schema.GroupVersionResource, error) {
+	parts := strings.Split(s, "/")
+	for _, part := range parts {
+		if part == "" {
+			parts = nil
+		}
+	}
+	switch len(parts) {
+	case 2:
+		return schema.GroupVersionResource{Version: parts[0], Resource: parts[1]}, nil
+	case 3:
+		return schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}, nil
+	}
+	return schema.GroupVersionResource{}, fmt.Errorf("invalid resource %q, want group/version/resource or v1/<resource>", s)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/handlerdiag"
+	"github.com/shamimice03/mastering-k8s-client-go/informermetrics"
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "informers.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestShippedConfigLoads(t *testing.T) {
+	cfg, err := loadConfig("informers.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gvrs []schema.GroupVersionResource
+	for _, r := range cfg.Resources {
+		gvrs = append(gvrs, r.gvr)
+	}
+	want := []schema.GroupVersionResource{
+		{Version: "v1", Resource: "pods"},
+		{Group: "apps", Version: "v1", Resource: "deployments"},
+		{Version: "v1", Resource: "configmaps"},
+	}
+	if !slices.Equal(gvrs, want) {
+		t.Errorf("resources = %v, want %v", gvrs, want)
+	}
+	if cfg.resync(cfg.Resources[0]) != 30*time.Second || cfg.resync(cfg.Resources[2]) != 0 {
+		t.Errorf("resync periods = %v, %v", cfg.resync(cfg.Resources[0]), cfg.resync(cfg.Resources[2]))
+	}
+	if got := cfg.namespace(cfg.Resources[0], "team-a"); got != "team-a" {
+		t.Errorf("pods namespace = %q, want --namespace", got)
+	}
+	if got := cfg.namespace(cfg.Resources[2], "team-a"); got != "kube-system" {
+		t.Errorf("configmaps namespace = %q, want its own", got)
+	}
+}
+
+func TestInvalidConfigsAreRejected(t *testing.T) {
+	for _, tc := range []struct {
+		name, config, want string
+	}{
+		{"unknown field", "resources:\n- resource: v1/pods\n  handler: []\n", `unknown field "handler"`},
+		{"no resources", "resync: 1m\n", "no resources"},
+		{"bad resource", "resources:\n- resource: pods\n  handlers: [{name: a, type: log}]\n", `invalid resource "pods"`},
+		{"bad selector", "resources:\n- resource: v1/pods\n  labelSelector: 'a in b'\n  handlers: [{name: a, type: log}]\n", "invalid labelSelector"},
+		{"no handlers", "resources:\n- resource: v1/pods\n", "no handlers"},
+		{"unknown type", "resources:\n- resource: v1/pods\n  handlers: [{name: a, type: print}]\n", `unknown type "print"`},
+		{"unknown event", "resources:\n- resource: v1/pods\n  handlers: [{name: a, type: log, events: [create]}]\n", `unknown event "create"`},
+		{"ambiguous index", "resources:\n- resource: v1/pods\n  indexes: [{name: i, label: a, field: b}]\n  handlers: [{name: a, type: log}]\n", "exactly one of label or field"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := loadConfig(writeConfig(t, tc.config))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestIndexFuncs(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Labels: map[string]string{"app": "shop"}},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+	}
+	for _, tc := range []struct {
+		index indexConfig
+		obj   *corev1.Pod
+		want  []string
+	}{
+		{indexConfig{Name: "byApp", Label: "app"}, pod, []string{"shop"}},
+		{indexConfig{Name: "byTier", Label: "tier"}, pod, nil},
+		{indexConfig{Name: "byNode", Field: "spec.nodeName"}, pod, []string{"node-1"}},
+		{indexConfig{Name: "byNode", Field: "spec.nodeName"}, &corev1.Pod{}, nil},
+	} {
+		got, err := indexFunc(tc.index)(tc.obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s(%s) = %v, want %v", tc.index.Name, tc.obj.Name, got, tc.want)
+		}
+	}
+}
+
+func TestConfiguredInformersAndHandlers(t *testing.T) {
+	web := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", Labels: map[string]string{"app": "web"}},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+	}
+	h := testutil.NewHarness(t, web,
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default", Labels: map[string]string{"app": "web"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "shop", Labels: map[string]string{"watch": "yes"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "ignored", Namespace: "shop"}},
+	)
+	cfg, err := loadConfig(writeConfig(t, `
+namespace: shop
+resources:
+- resource: v1/pods
+  indexes:
+  - {name: byNode, field: spec.nodeName}
+  handlers:
+  - {name: Pods, type: log, events: [add, delete]}
+- resource: v1/configmaps
+  labelSelector: watch=yes
+  handlers:
+  - {name: Settings, type: diff, prefix: "[diff]"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := testutil.CaptureOutput(t)
+	diag := handlerdiag.New(time.Second, 1000)
+	wired, err := wireConfig(cfg, h.Clientset, "", diag, informermetrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The label selector is a factory option, so configmaps get a factory of their own
+	if len(wired.factories) != 2 {
+		t.Fatalf("got %d factories, want 2", len(wired.factories))
+	}
+	if got := wired.groupResources["shop"]; len(got) != 2 {
+		t.Errorf("preflight resources = %v, want pods and configmaps in shop", got)
+	}
+
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		for _, factory := range wired.factories {
+			factory.Shutdown()
+		}
+	})
+	diag.Run(0, stopCh)
+	for _, factory := range wired.factories {
+		factory.Start(stopCh)
+	}
+	if !cache.WaitForCacheSync(stopCh, wired.synced...) {
+		t.Fatal("caches did not sync")
+	}
+	testutil.WaitForOutput(t, output,
+		"[Pods] Pod added: shop/web\n",
+		"[diff] ConfigMap added: shop/settings\n",
+	)
+	for _, unwanted := range []string{"default/other", "shop/ignored"} {
+		if strings.Contains(output(), unwanted) {
+			t.Errorf("%s is outside the configured scope:\n%s", unwanted, output())
+		}
+	}
+
+	informer := wired.factories[0].Core().V1().Pods().Informer()
+	if pods, err := informer.GetIndexer().ByIndex("byNode", "node-1"); err != nil || len(pods) != 1 {
+		t.Errorf("byNode index = %v, %v", pods, err)
+	}
+
+	h.WaitForWatchOf(&corev1.ConfigMap{}, 1)
+	h.WaitForWatchOf(web, 1)
+	h.Update(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "shop", Labels: map[string]string{"watch": "yes"}},
+		Data:       map[string]string{"mode": "fast"},
+	})
+	update := web.DeepCopy()
+	update.Labels["version"] = "2"
+	h.Update(update)
+	h.Delete(update)
+	testutil.WaitForOutput(t, output,
+		`[diff] ConfigMap updated: shop/settings {"data":{"mode":"fast"}}`+"\n",
+		"[Pods] Pod deleted: shop/web\n",
+	)
+	// The pod handler is not configured for updates
+	if strings.Contains(output(), "Pod updated") {
+		t.Errorf("update reached a handler configured for add and delete:\n%s", output())
+	}
+}
@@ -1,19 +1,47 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
-	appsv1 "k8s.io/api/apps/v1"
-	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/controller"
+	"github.com/shamimice03/mastering-k8s-client-go/handlerdiag"
+	"github.com/shamimice03/mastering-k8s-client-go/healthz"
+	"github.com/shamimice03/mastering-k8s-client-go/informermetrics"
+	"github.com/shamimice03/mastering-k8s-client-go/scope"
+	"github.com/shamimice03/mastering-k8s-client-go/watcherrors"
+)
+
+var (
+	slowHandler         = flag.Duration("slow-handler", 100*time.Millisecond, "warn when a handler takes longer than this for one event")
+	maxBacklog          = flag.Int("max-backlog", 100, "warn when this many events are queued for one handler")
+	diagnosticsInterval = flag.Duration("diagnostics-interval", 30*time.Second, "how often per-handler statistics are printed (0 disables)")
+	metricsAddr         = flag.String("metrics-addr", ":9102", "address serving /metrics (empty disables)")
+	healthAddr          = flag.String("health-addr", ":8081", "address serving /healthz and /readyz (empty disables)")
+	configPath          = flag.String("config", "", "YAML file declaring the informers, indexes and handlers to run (see informers.yaml); without it the built-in pod and deployment controllers run")
+	// namespace limits every informer and query to one namespace
+	namespace = scope.NamespaceFlag()
 )
 
 // createClientset creates and returns a Kubernetes clientset
@@ -31,6 +59,7 @@ func createClientSet() *kubernetes.Clientset {
 	if err != nil {
 		log.Fatalf("Failed to build config: %v", err)
 	}
+	clientid.Configure(config, "07-shared-informer-factory")
 	// Create clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -42,73 +71,291 @@ func createClientSet() *kubernetes.Clientset {
 func main() {
 	// Create client
 	clientset := createClientSet()
+	// /metrics reports the caches and handlers set up below, and the latency and
+	// result of every API request client-go makes from here on
+	metrics := informermetrics.New()
+	metrics.RegisterClientMetrics()
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Single factory for all informers
-	factory := informers.NewSharedInformerFactory(clientset, time.Second*30)
+	// Every handler is measured, so a slow one shows up before it falls far behind
+	diag := handlerdiag.New(*slowHandler, *maxBacklog)
 
-	// Setup multiple informers using same factory
-	setupPodMonitor(factory)
-	setupDeploymentMonitor(factory)
-	setupPodUpdateMonitor(factory)
+	var factories []informers.SharedInformerFactory
+	var synced []cache.InformerSynced
+	var controllers []*controller.Controller
+	if *configPath == "" {
+		var factory informers.SharedInformerFactory
+		factory, controllers = setupBuiltinControllers(ctx, clientset, diag, metrics)
+		factories = []informers.SharedInformerFactory{factory}
+		synced = []cache.InformerSynced{
+			factory.Core().V1().Pods().Informer().HasSynced,
+			factory.Apps().V1().Deployments().Informer().HasSynced,
+		}
+	} else {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		wired, err := wireConfig(cfg, clientset, *namespace, diag, metrics)
+		if err != nil {
+			log.Fatalf("Failed to set up informers from %s: %v", *configPath, err)
+		}
+		for ns, resources := range wired.groupResources {
+			if err := scope.Preflight(ctx, clientset, ns, resources...); err != nil {
+				log.Fatalf("RBAC preflight failed: %v", err)
+			}
+		}
+		factories, synced = wired.factories, wired.synced
+		fmt.Printf("Loaded %d resources from %s into %d factories\n", len(cfg.Resources), *configPath, len(factories))
+	}
 
 	// Start all informers at once
-	stopCh := make(chan struct{})
-	factory.Start(stopCh)
-	factory.WaitForCacheSync(stopCh)
-	<-stopCh
+	// The sync wait logs through the logger in ctx
+	ctx = klog.NewContext(ctx, klog.LoggerWithName(klog.Background(), "informer-manager"))
+	// SharedInformerFactory only takes a stop channel in this client-go version,
+	// so the informers themselves still log through the global klog logger
+	diag.Run(*diagnosticsInterval, ctx.Done())
+	// Reconcile counts and queue depths are served next to the cache statistics
+	metrics.AddCollector(func(w io.Writer) { controller.WriteMetrics(w, controllers...) })
+	if *metricsAddr != "" {
+		go func() {
+			if err := metrics.Serve(ctx, *metricsAddr); err != nil {
+				log.Fatalf("Metrics server failed: %v", err)
+			}
+		}()
+		fmt.Printf("Serving metrics on %s/metrics\n", *metricsAddr)
+	}
+	// Ready once every informer the factories started has synced
+	health := healthz.New()
+	for i, factory := range factories {
+		name := "informers"
+		if i > 0 {
+			name = fmt.Sprintf("informers-%d", i+1)
+		}
+		health.AddReadyCheck(name, healthz.FactorySynced(factory))
+	}
+	if *healthAddr != "" {
+		go func() {
+			if err := health.Serve(ctx, *healthAddr); err != nil {
+				log.Fatalf("Health server failed: %v", err)
+			}
+		}()
+	}
+	for _, factory := range factories {
+		factory.Start(ctx.Done())
+	}
+	// The wait only fails when ctx is cancelled, so shutdown simply follows
+	cache.WaitForNamedCacheSyncWithContext(ctx, synced...)
+	var running wait.Group
+	for _, c := range controllers {
+		// Run only fails when ctx is cancelled before the caches synced
+		running.StartWithContext(ctx, func(ctx context.Context) { _ = c.Run(ctx) })
+	}
+	<-ctx.Done()
+
+	// The controllers finish their running reconciles before the informers stop.
+	// Shutdown returns once every informer goroutine has exited
+	fmt.Println("Shutting down, waiting for controllers and informers to stop...")
+	running.Wait()
+	for _, factory := range factories {
+		factory.Shutdown()
+	}
+	fmt.Println("Exited cleanly")
+}
+
+// setupBuiltinControllers runs the three controllers below on one factory. It
+// is what runs when no --config is given.
+func setupBuiltinControllers(ctx context.Context, clientset kubernetes.Interface, diag *handlerdiag.Diagnostics, metrics *informermetrics.Registry) (informers.SharedInformerFactory, []*controller.Controller) {
+	// Fail fast if RBAC does not allow the informers to list and watch
+	if err := scope.Preflight(ctx, clientset, *namespace, schema.GroupResource{Resource: "pods"}, schema.GroupResource{Group: "apps", Resource: "deployments"}); err != nil {
+		log.Fatalf("RBAC preflight failed: %v", err)
+	}
+
+	// Single factory for all informers, scoped to --namespace when it is set
+	factory := scope.NewFactory(clientset, time.Second*30, *namespace)
+
+	// Setup multiple informers using same factory
+	controllers, err := setupMonitors(factory, diag, metrics)
+	if err != nil {
+		log.Fatalf("Failed to set up controllers: %v", err)
+	}
+	metrics.AddInformer("pods", factory.Core().V1().Pods().Informer())
+	metrics.AddInformer("deployments", factory.Apps().V1().Deployments().Informer())
+
+	// Surface LIST and WATCH failures instead of letting the reflectors retry silently
+	for _, informer := range []cache.SharedIndexInformer{
+		factory.Core().V1().Pods().Informer(),
+		factory.Apps().V1().Deployments().Informer(),
+	} {
+		if err := informer.SetWatchErrorHandlerWithContext(watcherrors.Handle); err != nil {
+			log.Fatalf("Failed to set watch error handler: %v", err)
+		}
+	}
+	return factory, controllers
+}
+
+// setupMonitors builds the three controllers on factory's shared informers
+func setupMonitors(factory informers.SharedInformerFactory, diag *handlerdiag.Diagnostics, metrics *informermetrics.Registry) ([]*controller.Controller, error) {
+	var controllers []*controller.Controller
+	for _, setup := range []func(informers.SharedInformerFactory, *handlerdiag.Diagnostics, *informermetrics.Registry) (*controller.Controller, error){
+		setupPodMonitor,
+		setupDeploymentMonitor,
+		setupPodUpdateMonitor,
+	} {
+		c, err := setup(factory, diag, metrics)
+		if err != nil {
+			return nil, err
+		}
+		controllers = append(controllers, c)
+	}
+	return controllers, nil
 }
 
 // Controller 1: Pod Monitor
-func setupPodMonitor(factory informers.SharedInformerFactory) {
+// The controller framework only hands over keys, so podMonitor remembers which
+// pods it has reported to tell a new pod from a known one
+type podMonitor struct {
+	lister corelisters.PodLister
+	seen   *seenVersions
+}
+
+func (m *podMonitor) reconcile(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	pod, err := m.lister.Pods(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		if m.seen.forget(key) {
+			fmt.Printf("[Monitor] Pod deleted: %s\n", name)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, seen := m.seen.observe(key, pod.ResourceVersion); !seen {
+		fmt.Printf("[Monitor] Pod added: %s\n", pod.Name)
+	}
+	return nil
+}
+
+func setupPodMonitor(factory informers.SharedInformerFactory, diag *handlerdiag.Diagnostics, metrics *informermetrics.Registry) (*controller.Controller, error) {
 	podInformer := factory.Core().V1().Pods()
 
-	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			pod := obj.(*corev1.Pod)
-			fmt.Printf("[Monitor] Pod added: %s\n", pod.Name)
-		},
-		DeleteFunc: func(obj interface{}) {
-			pod := obj.(*corev1.Pod)
-			fmt.Printf("[Monitor] Pod deleted: %s\n", pod.Name)
-		},
+	monitor := &podMonitor{lister: podInformer.Lister(), seen: newSeenVersions()}
+	return controller.New(podInformer.Informer(), nil, monitor.reconcile, controller.Options{
+		Name:       "PodMonitor",
+		Instrument: instrument("pods", "PodMonitor", diag, metrics),
 	})
 }
 
 // Controller 2: Deployment Manager
-// Deployment Manager by implementing cache.ResourceEventHandler
+// Deployment Manager by passing a method as the reconcile function
 // Just showing another way to implement
-type DeploymentHandler struct{}
-
-func (h *DeploymentHandler) OnAdd(obj interface{}, isInInitialList bool) {
-	deployment := obj.(*appsv1.Deployment)
-	fmt.Printf("[Manager] Deployment added: %s\n", deployment.Name)
+type DeploymentManager struct {
+	lister appslisters.DeploymentLister
+	seen   *seenVersions
 }
 
-func (h *DeploymentHandler) OnUpdate(oldObj, newObj interface{}) {
-	// Implementation
-}
-
-func (h *DeploymentHandler) OnDelete(obj interface{}) {
-	// Implementation
+func (m *DeploymentManager) Reconcile(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	deployment, err := m.lister.Deployments(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		if m.seen.forget(key) {
+			fmt.Printf("[Manager] Deployment deleted: %s\n", name)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, seen := m.seen.observe(key, deployment.ResourceVersion); !seen {
+		fmt.Printf("[Manager] Deployment added: %s\n", deployment.Name)
+	}
+	return nil
 }
 
-func setupDeploymentMonitor(factory informers.SharedInformerFactory) {
+func setupDeploymentMonitor(factory informers.SharedInformerFactory, diag *handlerdiag.Diagnostics, metrics *informermetrics.Registry) (*controller.Controller, error) {
 	deploymentInformer := factory.Apps().V1().Deployments()
 
-	handler := &DeploymentHandler{}
-	deploymentInformer.Informer().AddEventHandler(handler)
-
+	manager := &DeploymentManager{lister: deploymentInformer.Lister(), seen: newSeenVersions()}
+	return controller.New(deploymentInformer.Informer(), nil, manager.Reconcile, controller.Options{
+		Name:       "DeploymentManager",
+		Instrument: instrument("deployments", "DeploymentManager", diag, metrics),
+	})
 }
 
 // Controller 3: Pod Update Monitor (uses SAME Pod informer as Controller 1)
-func setupPodUpdateMonitor(factory informers.SharedInformerFactory) {
+// A pod counts as updated when its resourceVersion moved since the last
+// reconcile, so the initial list and resyncs print nothing
+func setupPodUpdateMonitor(factory informers.SharedInformerFactory, diag *handlerdiag.Diagnostics, metrics *informermetrics.Registry) (*controller.Controller, error) {
 	podInformer := factory.Core().V1().Pods() // Gets the SAME shared Pod informer
+	lister := podInformer.Lister()
+	versions := newSeenVersions()
 
-	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			pod := newObj.(*corev1.Pod)
+	reconcile := func(ctx context.Context, key string) error {
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			return err
+		}
+		pod, err := lister.Pods(namespace).Get(name)
+		if apierrors.IsNotFound(err) {
+			versions.forget(key)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if previous, seen := versions.observe(key, pod.ResourceVersion); seen && previous != pod.ResourceVersion {
 			fmt.Printf("[PodUpdateMonitor] Pod updated: %s\n", pod.Name)
 			// logic here
-		},
+		}
+		return nil
+	}
+	return controller.New(podInformer.Informer(), nil, reconcile, controller.Options{
+		Name:       "PodUpdateMonitor",
+		Instrument: instrument("pods", "PodUpdateMonitor", diag, metrics),
 	})
 }
+
+// instrument counts and times the events of a controller's handler, like
+// every other handler in this example
+func instrument(resource, name string, diag *handlerdiag.Diagnostics, metrics *informermetrics.Registry) func(cache.ResourceEventHandler) cache.ResourceEventHandler {
+	return func(handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+		return metrics.CountEvents(resource, name, diag.Instrument(name, handler))
+	}
+}
+
+// seenVersions remembers the last resourceVersion reconciled per key
+type seenVersions struct {
+	mu       sync.Mutex
+	versions map[string]string
+}
+
+func newSeenVersions() *seenVersions {
+	return &seenVersions{versions: make(map[string]string)}
+}
+
+// observe records version for key and returns the one recorded before, if any
+func (s *seenVersions) observe(key, version string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, seen := s.versions[key]
+	s.versions[key] = version
+	return previous, seen
+}
+
+// forget drops key and reports whether it was known
+func (s *seenVersions) forget(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, seen := s.versions[key]
+	delete(s.versions, key)
+	return seen
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/handlerdiag"
+	"github.com/shamimice03/mastering-k8s-client-go/informermetrics"
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func testPod(name string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+}
+
+// startFactory wires the three example controllers into one factory over a
+// fake clientset and runs them until the test ends
+func startFactory(t *testing.T, clientset kubernetes.Interface) informers.SharedInformerFactory {
+	t.Helper()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	diag := handlerdiag.New(time.Second, 1000)
+	metrics := informermetrics.New()
+	controllers, err := setupMonitors(factory, diag, metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var running wait.Group
+	t.Cleanup(func() {
+		cancel()
+		running.Wait()
+		factory.Shutdown()
+	})
+	diag.Run(0, ctx.Done())
+	factory.Start(ctx.Done())
+	for typ, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			t.Fatalf("%v cache did not sync", typ)
+		}
+	}
+	for _, c := range controllers {
+		running.StartWithContext(ctx, func(ctx context.Context) { _ = c.Run(ctx) })
+	}
+	return factory
+}
+
+func TestControllersSeeExistingObjects(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}},
+	)
+	output := testutil.CaptureOutput(t)
+	startFactory(t, clientset)
+
+	testutil.WaitForOutput(t, output,
+		"[Monitor] Pod added: web\n",
+		"[Manager] Deployment added: nginx\n",
+	)
+	// The initial list is delivered as adds, never as updates
+	if strings.Contains(output(), "[PodUpdateMonitor]") {
+		t.Errorf("initial list reached the update monitor:\n%s", output())
+	}
+}
+
+func TestPodEventsReachBothPodControllers(t *testing.T) {
+	web := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	h := testutil.NewHarness(t, web)
+	output := testutil.CaptureOutput(t)
+	startFactory(t, h.Clientset)
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+	testutil.WaitForOutput(t, output, "[Monitor] Pod added: web\n")
+
+	// Reconciles read the cache, so each change is awaited: a delete arriving
+	// first would leave nothing to report as updated
+	updated := web.DeepCopy()
+	updated.Spec.NodeName = "node-a"
+	h.Update(updated)
+	testutil.WaitForOutput(t, output, "[PodUpdateMonitor] Pod updated: web\n")
+	h.Delete(updated)
+	testutil.WaitForOutput(t, output, "[Monitor] Pod deleted: web\n")
+
+	// Only the update monitor reports updates, only the pod monitor adds and deletes
+	if got := strings.Count(output(), "web\n"); got != 3 {
+		t.Errorf("web reported %d times, want 3:\n%s", got, output())
+	}
+}
+
+func TestPodControllersShareOneInformer(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	testutil.CaptureOutput(t)
+	factory := startFactory(t, clientset)
+
+	// Two pod controllers registered handlers, but the factory built one informer
+	if factory.Core().V1().Pods().Informer() != factory.Core().V1().Pods().Informer() {
+		t.Error("factory returned different pod informers")
+	}
+	lists := map[string]int{}
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "list" {
+			lists[action.GetResource().Resource]++
+		}
+	}
+	if lists["pods"] != 1 || lists["deployments"] != 1 {
+		t.Errorf("LIST requests per resource = %v, want one each for pods and deployments", lists)
+	}
+}
+
+func TestDeploymentManagerReportsAddsAndDeletes(t *testing.T) {
+	output := testutil.CaptureOutput(t)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	manager := &DeploymentManager{lister: appslisters.NewDeploymentLister(indexer), seen: newSeenVersions()}
+	api := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", ResourceVersion: "1"}}
+
+	indexer.Add(api)
+	for range 2 {
+		if err := manager.Reconcile(context.TODO(), "default/api"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	indexer.Delete(api)
+	if err := manager.Reconcile(context.TODO(), "default/api"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second reconcile of the unchanged deployment prints nothing
+	want := "[Manager] Deployment added: api\n[Manager] Deployment deleted: api\n"
+	testutil.WaitForOutput(t, output, want)
+	if got := output(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+// The factory hands the Pod Monitor and the Pod Update Monitor the same
+// informer, and the Deployment Manager a second one. These tests pin down
+// what that sharing does and does not guarantee:
+//   - every handler sees the events of its informer in watch order
+//   - the controllers only queue keys and reconcile them against the cache,
+//     so a burst of events for one pod collapses into fewer reconciles
+//   - controllers never wait for each other, so there is no ordering between
+//     them, and none at all between pod and deployment events
+//   - a stuck handler buffers its backlog (1024 slots, then it grows) instead
+//     of slowing the other controllers
+
+// linesWithPrefix returns the printed lines starting with prefix, in order
+func linesWithPrefix(output, prefix string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestPodControllersCollapseEventsIntoReconciles(t *testing.T) {
+	h := testutil.NewHarness(t)
+	output := testutil.CaptureOutput(t)
+	factory := startFactory(t, h.Clientset)
+	recorder := &testutil.Recorder{}
+	factory.Core().V1().Pods().Informer().AddEventHandler(recorder)
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+
+	a := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}}
+	b := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"}}
+	h.Add(a)
+	h.Add(b)
+	h.Update(b)
+	h.Update(a)
+	h.Delete(a)
+	h.Delete(b)
+	// Handlers still get every event in watch order...
+	recorder.Expect(t,
+		"add default/a", "add default/b", "update default/b",
+		"update default/a", "delete default/a", "delete default/b",
+	)
+	// ...and the one worker takes keys first in, first out, so once c is
+	// reported every key queued before it has been reconciled
+	h.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "default"}})
+	testutil.WaitForOutput(t, output, "[Monitor] Pod added: c\n")
+
+	// A reconcile may run after later events already reached the cache, so a
+	// pod is reported added and deleted, or not at all if it was gone before
+	// its first reconcile. It is never reported deleted without being added.
+	for _, name := range []string{"a", "b"} {
+		got := linesWithPrefix(output(), "[Monitor] Pod")
+		var lines []string
+		for _, line := range got {
+			if strings.HasSuffix(line, ": "+name) {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) != 0 && !slices.Equal(lines, []string{"[Monitor] Pod added: " + name, "[Monitor] Pod deleted: " + name}) {
+			t.Errorf("Pod Monitor printed %q for %s", lines, name)
+		}
+	}
+}
+
+func TestStuckPodHandlerDoesNotDelayOtherControllers(t *testing.T) {
+	h := testutil.NewHarness(t)
+	output := testutil.CaptureOutput(t)
+	factory := startFactory(t, h.Clientset)
+	recorder := &testutil.Recorder{}
+	factory.Core().V1().Pods().Informer().AddEventHandler(recorder)
+	// A fourth controller on the shared pod informer that hangs on its first event
+	release := make(chan struct{})
+	stuck := &testutil.Recorder{Gate: release}
+	factory.Core().V1().Pods().Informer().AddEventHandler(stuck)
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+	h.WaitForWatchOf(&appsv1.Deployment{}, 1)
+
+	// Enough events to overflow the stuck handler's initial buffer
+	const pods = 1500
+	var want []string
+	for i := 0; i < pods; i++ {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%04d", i), Namespace: "default"}}
+		want = append(want, "add "+testutil.Key(pod))
+		h.Add(pod)
+		if i%50 == 49 {
+			recorder.WaitForCount(t, i+1)
+		}
+	}
+	h.Add(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}})
+
+	// The Pod Monitor shares the informer with the stuck handler, the
+	// Deployment Manager does not; both keep up
+	testutil.WaitForOutput(t, output,
+		"[Monitor] Pod added: pod-1499\n",
+		"[Manager] Deployment added: nginx\n",
+	)
+	if got := len(stuck.Events()); got != 0 {
+		t.Fatalf("stuck handler completed %d events while blocked", got)
+	}
+
+	// Released, it works through its backlog in order without losing anything
+	close(release)
+	stuck.Expect(t, want...)
+}
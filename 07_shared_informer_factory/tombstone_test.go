@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func TestDeletesMissedByTheWatchAreReported(t *testing.T) {
+	web := testPod("web")
+	nginx := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}}
+	h := testutil.NewHarness(t, web, nginx)
+	output := testutil.CaptureOutput(t)
+	startFactory(t, h.Clientset)
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+	h.WaitForWatchOf(&appsv1.Deployment{}, 1)
+
+	// Both informers relist and deliver the deletes as tombstones
+	h.DeleteUnseen(web)
+	h.DeleteUnseen(nginx)
+	testutil.WaitForOutput(t, output,
+		"[Monitor] Pod deleted: web\n",
+		"[Manager] Deployment deleted: nginx\n",
+	)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/handlerdiag"
+	"github.com/shamimice03/mastering-k8s-client-go/informermetrics"
+	"github.com/shamimice03/mastering-k8s-client-go/tombstone"
+	"github.com/shamimice03/mastering-k8s-client-go/watcherrors"
+)
+
+// configuredInformers is what wireConfig built from an informerConfig
+type configuredInformers struct {
+	// factories holds one factory per distinct namespace and selector pair,
+	// since both are options of the factory rather than of one informer
+	factories []informers.SharedInformerFactory
+	// synced has one entry per informer
+	synced []cache.InformerSynced
+	// groupResources lists, per namespace, what preflight has to check
+	groupResources map[string][]schema.GroupResource
+}
+
+// factoryScope identifies the factory a resource's informer comes from
+type factoryScope struct {
+	namespace, labelSelector, fieldSelector string
+}
+
+// wireConfig creates the informers, indexes and handlers cfg declares. Nothing
+// is started; the caller starts every factory once the handlers are attached.
+func wireConfig(cfg *informerConfig, clientset kubernetes.Interface, flagNamespace string, diag *handlerdiag.Diagnostics, metrics *informermetrics.Registry) (*configuredInformers, error) {
+	wired := &configuredInformers{groupResources: map[string][]schema.GroupResource{}}
+	factories := map[factoryScope]informers.SharedInformerFactory{}
+	seen := map[cache.SharedIndexInformer]bool{}
+	for _, r := range cfg.Resources {
+		scope := factoryScope{cfg.namespace(r, flagNamespace), r.LabelSelector, r.FieldSelector}
+		factory, ok := factories[scope]
+		if !ok {
+			factory = informers.NewSharedInformerFactoryWithOptions(clientset, cfg.Resync.Duration,
+				informers.WithNamespace(scope.namespace),
+				informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+					options.LabelSelector = scope.labelSelector
+					options.FieldSelector = scope.fieldSelector
+				}))
+			factories[scope] = factory
+			wired.factories = append(wired.factories, factory)
+		}
+		// ForResource only knows the built-in types of this client-go version
+		generic, err := factory.ForResource(r.gvr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Resource, err)
+		}
+		informer := generic.Informer()
+
+		indexers := cache.Indexers{}
+		for _, index := range r.Indexes {
+			indexers[index.Name] = indexFunc(index)
+		}
+		if len(indexers) > 0 {
+			if err := informer.AddIndexers(indexers); err != nil {
+				return nil, fmt.Errorf("%s: %w", r.Resource, err)
+			}
+		}
+
+		for _, h := range r.Handlers {
+			handler := metrics.CountEvents(r.gvr.Resource, h.Name, diag.Instrument(h.Name, newConfiguredHandler(h)))
+			if _, err := informer.AddEventHandlerWithResyncPeriod(handler, cfg.resync(r)); err != nil {
+				return nil, fmt.Errorf("%s: handler %s: %w", r.Resource, h.Name, err)
+			}
+		}
+
+		// A resource listed twice in one scope shares its informer, which
+		// only needs to be registered once
+		if !seen[informer] {
+			seen[informer] = true
+			metrics.AddInformer(r.gvr.Resource, informer)
+			if err := informer.SetWatchErrorHandlerWithContext(watcherrors.Handle); err != nil {
+				return nil, fmt.Errorf("%s: %w", r.Resource, err)
+			}
+			wired.synced = append(wired.synced, informer.HasSynced)
+		}
+		if !slices.Contains(wired.groupResources[scope.namespace], r.gvr.GroupResource()) {
+			wired.groupResources[scope.namespace] = append(wired.groupResources[scope.namespace], r.gvr.GroupResource())
+		}
+	}
+	return wired, nil
+}
+
+// indexFunc indexes objects by a label value or by the value of a field
+func indexFunc(index indexConfig) cache.IndexFunc {
+	if index.Label != "" {
+		return func(obj interface{}) ([]string, error) {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return nil, err
+			}
+			if value, ok := accessor.GetLabels()[index.Label]; ok {
+				return []string{value}, nil
+			}
+			return nil, nil
+		}
+	}
+	path := strings.Split(index.Field, ".")
+	return func(obj interface{}) ([]string, error) {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		value, found, err := unstructured.NestedFieldNoCopy(content, path...)
+		if err != nil || !found || value == nil || value == "" {
+			return nil, err
+		}
+		return []string{fmt.Sprint(value)}, nil
+	}
+}
+
+// configuredHandler is one built-in handler from the config file
+type configuredHandler struct {
+	config handlerConfig
+	prefix string
+}
+
+func newConfiguredHandler(config handlerConfig) *configuredHandler {
+	prefix := config.Prefix
+	if prefix == "" {
+		prefix = "[" + config.Name + "]"
+	}
+	return &configuredHandler{config: config, prefix: prefix}
+}
+
+// wants reports whether the handler is configured for event
+func (h *configuredHandler) wants(event string) bool {
+	return len(h.config.Events) == 0 || slices.Contains(h.config.Events, event)
+}
+
+func (h *configuredHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	if h.wants("add") {
+		fmt.Printf("%s %s added: %s\n", h.prefix, kindOf(obj), objectName(obj))
+	}
+}
+
+func (h *configuredHandler) OnUpdate(oldObj, newObj interface{}) {
+	if !h.wants("update") {
+		return
+	}
+	if h.config.Type == handlerLog {
+		fmt.Printf("%s %s updated: %s\n", h.prefix, kindOf(newObj), objectName(newObj))
+		return
+	}
+	// A resync delivers the same object twice, which is no change worth printing
+	patch, err := diffObjects(oldObj, newObj)
+	if err != nil {
+		fmt.Printf("%s %s updated: %s (diff failed: %v)\n", h.prefix, kindOf(newObj), objectName(newObj), err)
+		return
+	}
+	if patch != "" {
+		fmt.Printf("%s %s updated: %s %s\n", h.prefix, kindOf(newObj), objectName(newObj), patch)
+	}
+}
+
+func (h *configuredHandler) OnDelete(obj interface{}) {
+	if !h.wants("delete") {
+		return
+	}
+	object, ok := tombstone.Unwrap[runtime.Object](obj)
+	if !ok {
+		return
+	}
+	fmt.Printf("%s %s deleted: %s\n", h.prefix, kindOf(object), objectName(object))
+}
+
+// kindOf looks the kind up in the scheme, since objects from a typed informer
+// carry an empty TypeMeta
+func kindOf(obj interface{}) string {
+	object, ok := obj.(runtime.Object)
+	if !ok {
+		return fmt.Sprintf("%T", obj)
+	}
+	kinds, _, err := scheme.Scheme.ObjectKinds(object)
+	if err != nil || len(kinds) == 0 {
+		return fmt.Sprintf("%T", obj)
+	}
+	return kinds[0].Kind
+}
+
+// objectName is namespace/name, or just name for cluster-scoped objects
+func objectName(obj interface{}) string {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return fmt.Sprintf("%v", obj)
+	}
+	return key
+}
+
+// diffObjects returns the JSON merge patch that turns oldObj into newObj,
+// leaving out the bookkeeping the API server changes on every write. It is
+// empty when nothing else changed.
+func diffObjects(oldObj, newObj interface{}) (string, error) {
+	oldJSON, err := diffableJSON(oldObj)
+	if err != nil {
+		return "", err
+	}
+	newJSON, err := diffableJSON(newObj)
+	if err != nil {
+		return "", err
+	}
+	patch, err := jsonpatch.CreateMergePatch(oldJSON, newJSON)
+	if err != nil {
+		return "", err
+	}
+	if string(patch) == "{}" {
+		return "", nil
+	}
+	return string(patch), nil
+}
+
+func diffableJSON(obj interface{}) ([]byte, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	unstructured.RemoveNestedField(content, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(content, "metadata", "managedFields")
+	unstructured.RemoveNestedField(content, "metadata", "generation")
+	return json.Marshal(content)
+}
@@ -3,8 +3,10 @@ module shared-informer-factory
 go 1.24.1
 
 require (
+	k8s.io/api v0.33.2
 	k8s.io/apimachinery v0.33.2
 	k8s.io/client-go v0.33.2
+	k8s.io/klog/v2 v2.130.1
 )
 
 require (
@@ -38,8 +40,6 @@ require (
 	gopkg.in/evanphx/json-patch.v4 v4.12.0 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
-	k8s.io/api v0.33.2 // indirect
-	k8s.io/klog/v2 v2.130.1 // indirect
 	k8s.io/kube-openapi v0.0.0-20250318190949-c8a335a9a2ff // indirect
 	k8s.io/utils v0.0.0-20241104100929-3ea5e8cea738 // indirect
 	sigs.k8s.io/json v0.0.0-20241010143419-9aa6b5e7a4b3 // indirect
@@ -47,3 +47,27 @@ require (
 	sigs.k8s.io/structured-merge-diff/v4 v4.6.0 // indirect
 	sigs.k8s.io/yaml v1.4.0 // indirect
 )
+
+require github.com/shamimice03/mastering-k8s-client-go/informermetrics v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/informermetrics => ../informermetrics
+
+require github.com/shamimice03/mastering-k8s-client-go/httpdebug v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/httpdebug => ../httpdebug
+
+require github.com/shamimice03/mastering-k8s-client-go/clientid v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/clientid => ../clientid
+
+require github.com/shamimice03/mastering-k8s-client-go/watcherrors v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/watcherrors => ../watcherrors
+
+require github.com/shamimice03/mastering-k8s-client-go/scope v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/scope => ../scope
+
+require github.com/shamimice03/mastering-k8s-client-go/testutil v0.0.0
+
+replace github.com/shamimice03/mastering-k8s-client-go/testutil => ../testutil
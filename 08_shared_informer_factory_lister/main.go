@@ -6,16 +6,39 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/httpdebug"
+	"github.com/shamimice03/mastering-k8s-client-go/informermetrics"
+	"github.com/shamimice03/mastering-k8s-client-go/scope"
+	"github.com/shamimice03/mastering-k8s-client-go/watcherrors"
+)
+
+var (
+	metricsAddr     = flag.String("metrics-addr", "", "address serving /metrics; when set, the program keeps running after the queries until interrupted")
+	debugHTTP       = flag.Bool("debug-http", false, "log every API request with its status and latency to stderr")
+	debugHTTPBodies = flag.Bool("debug-http-bodies", false, "like --debug-http, and log the bodies too, with Secret data redacted")
+	auditReport     = flag.String("audit-report", "", "file to write, on exit, a JSON report of every verb, resource and namespace the program touched, with the RBAC rules they need")
+	// namespace limits every informer and query to one namespace
+	namespace = scope.NamespaceFlag()
 )
 
+// auditRecorder records the API requests when --audit-report is set
+var auditRecorder = httpdebug.NewRecorder()
+
 // createClientset creates and returns a Kubernetes clientset
 func createClientSet() *kubernetes.Clientset {
 	// Get home directory for kubeconfig path
@@ -31,6 +54,15 @@ func createClientSet() *kubernetes.Clientset {
 	if err != nil {
 		log.Fatalf("Failed to build config: %v", err)
 	}
+	clientid.Configure(config, "08-shared-informer-factory-lister")
+	// Show the LIST and WATCH requests behind the informers, and that the
+	// lister queries send none
+	if *debugHTTP || *debugHTTPBodies {
+		httpdebug.Configure(config, httpdebug.Options{Bodies: *debugHTTPBodies})
+	}
+	if *auditReport != "" {
+		config.Wrap(auditRecorder.Wrap)
+	}
 	// Create clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -41,33 +73,90 @@ func createClientSet() *kubernetes.Clientset {
 
 func main() {
 	clientset := createClientSet()
-
-	_, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		log.Fatalf("Failed to connect to cluster: %v", err)
+	// /metrics reports the caches set up below, and the latency and
+	// result of every API request client-go makes from here on
+	metrics := informermetrics.New()
+	metrics.RegisterClientMetrics()
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Listing namespaces is cluster-scoped, so a Role cannot do it
+	if *namespace == "" {
+		_, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Fatalf("Failed to connect to cluster: %v", err)
+		}
+		fmt.Println("Successfully connected to cluster")
+	}
+	// Fail fast if RBAC does not allow the informers to list and watch
+	if err := scope.Preflight(ctx, clientset, *namespace, schema.GroupResource{Resource: "pods"}, schema.GroupResource{Group: "apps", Resource: "deployments"}); err != nil {
+		log.Fatalf("RBAC preflight failed: %v", err)
 	}
-	fmt.Println("Successfully connected to cluster")
 
-	// Single factory for all informers
-	factory := informers.NewSharedInformerFactory(clientset, time.Second*30)
+	// Single factory for all informers, scoped to --namespace when it is set
+	factory := scope.NewFactory(clientset, time.Second*30, *namespace)
 
 	// Setup informers (this registers them with the factory)
 	setupInformers(factory)
 
-	// Start all informers at once
-	stopCh := make(chan struct{})
-	factory.Start(stopCh)
+	// Surface LIST and WATCH failures instead of letting the reflectors retry silently
+	for _, informer := range []cache.SharedIndexInformer{
+		factory.Core().V1().Pods().Informer(),
+		factory.Apps().V1().Deployments().Informer(),
+	} {
+		if err := informer.SetWatchErrorHandlerWithContext(watcherrors.Handle); err != nil {
+			log.Fatalf("Failed to set watch error handler: %v", err)
+		}
+	}
 
-	// Wait for cache sync
-	fmt.Println("Waiting for cache sync...")
-	factory.WaitForCacheSync(stopCh)
-	fmt.Println("Cache sync completed!")
+	// Report cache sizes, index cardinality and sync status
+	metrics.AddInformer("pods", factory.Core().V1().Pods().Informer())
+	metrics.AddInformer("deployments", factory.Apps().V1().Deployments().Informer())
+
+	// Start all informers at once
+	// The sync wait logs through the logger in ctx
+	ctx = klog.NewContext(ctx, klog.LoggerWithName(klog.Background(), "lister"))
+	// SharedInformerFactory only takes a stop channel in this client-go version,
+	// so the informers themselves still log through the global klog logger
+	factory.Start(ctx.Done())
+
+	// Wait for cache sync; progress is logged through ctx
+	if !cache.WaitForNamedCacheSyncWithContext(ctx,
+		factory.Core().V1().Pods().Informer().HasSynced,
+		factory.Apps().V1().Deployments().Informer().HasSynced,
+	) {
+		factory.Shutdown()
+		writeAuditReport()
+		return
+	}
 
 	// Query resources using listers
-	useListers(factory)
+	useListers(factory, *namespace)
+	serveMetricsUntilInterrupted(ctx, metrics)
 
-	// Close the channel to stop informers and exit
-	close(stopCh)
+	// Stop the informers and wait for them to exit
+	stop()
+	factory.Shutdown()
+	writeAuditReport()
+}
+
+// writeAuditReport writes the requests recorded since the start to
+// --audit-report. 23_rbac_bootstrap --rules-from turns it into a role.
+func writeAuditReport() {
+	if *auditReport == "" {
+		return
+	}
+	f, err := os.Create(*auditReport)
+	if err != nil {
+		log.Fatalf("Failed to write audit report: %v", err)
+	}
+	defer f.Close()
+	if err := auditRecorder.WriteReport(f); err != nil {
+		log.Fatalf("Failed to write audit report: %v", err)
+	}
+	report := auditRecorder.Report()
+	fmt.Printf("Audit report: %d kinds of request, %d RBAC rules, written to %s\n", len(report.Accesses), len(report.Rules), *auditReport)
 }
 
 func setupInformers(factory informers.SharedInformerFactory) {
@@ -77,18 +166,20 @@ func setupInformers(factory informers.SharedInformerFactory) {
 	factory.Apps().V1().Deployments().Informer()
 }
 
-func useListers(factory informers.SharedInformerFactory) {
+// useListers queries through namespaced listers only. With ns empty they span
+// every namespace; with ns set they never leave it, just like the informers.
+func useListers(factory informers.SharedInformerFactory, ns string) {
 	// Get listers
 	podLister := factory.Core().V1().Pods().Lister()
 	deploymentLister := factory.Apps().V1().Deployments().Lister()
 
-	// Get ALL pods (across all namespaces)
-	allPods, err := podLister.List(labels.Everything())
+	// Get ALL pods (across all namespaces, or in ns only)
+	allPods, err := podLister.Pods(ns).List(labels.Everything())
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
 	}
-	fmt.Printf("Total pods (all namespaces): %d\n", len(allPods))
+	fmt.Printf("Total pods (%s): %d\n", scope.Describe(ns), len(allPods))
 
 	// Show pods by namespace
 	namespaceCount := make(map[string]int)
@@ -100,13 +191,17 @@ func useListers(factory informers.SharedInformerFactory) {
 		fmt.Printf("  %s: %d pods\n", ns, count)
 	}
 
-	// Get pods in default namespace specifically
-	defaultPods, err := podLister.Pods("default").List(labels.Everything())
+	// Get pods in default namespace specifically (or in ns when scoped)
+	queryNamespace := "default"
+	if ns != "" {
+		queryNamespace = ns
+	}
+	defaultPods, err := podLister.Pods(queryNamespace).List(labels.Everything())
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
 	}
-	fmt.Printf("Pods in default namespace: %d\n", len(defaultPods))
+	fmt.Printf("Pods in %s namespace: %d\n", queryNamespace, len(defaultPods))
 
 	// Get specific pod by name (if any pods exist)
 	if len(allPods) > 0 {
@@ -121,7 +216,7 @@ func useListers(factory informers.SharedInformerFactory) {
 
 	// Filter by labels
 	labelSelector, _ := labels.Parse("app=nginx")
-	nginxPods, err := podLister.List(labelSelector)
+	nginxPods, err := podLister.Pods(ns).List(labelSelector)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
@@ -129,10 +224,22 @@ func useListers(factory informers.SharedInformerFactory) {
 	fmt.Printf("Nginx pods: %d\n", len(nginxPods))
 
 	// Query ALL deployments
-	allDeployments, err := deploymentLister.List(labels.Everything())
+	allDeployments, err := deploymentLister.Deployments(ns).List(labels.Everything())
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
 	}
-	fmt.Printf("Total deployments (all namespaces): %d\n", len(allDeployments))
+	fmt.Printf("Total deployments (%s): %d\n", scope.Describe(ns), len(allDeployments))
+}
+
+// serveMetricsUntilInterrupted serves /metrics on --metrics-addr until ctx is
+// cancelled. Without --metrics-addr it returns at once and the program exits.
+func serveMetricsUntilInterrupted(ctx context.Context, metrics *informermetrics.Registry) {
+	if *metricsAddr == "" {
+		return
+	}
+	fmt.Printf("Serving metrics on %s/metrics, press Ctrl-C to exit\n", *metricsAddr)
+	if err := metrics.Serve(ctx, *metricsAddr); err != nil {
+		log.Fatalf("Metrics server failed: %v", err)
+	}
 }
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/scope"
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func newPod(namespace, name string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: podLabels}}
+}
+
+func seededClientset() *fake.Clientset {
+	return fake.NewSimpleClientset(
+		newPod("default", "nginx-1", map[string]string{"app": "nginx"}),
+		newPod("default", "redis-1", map[string]string{"app": "redis"}),
+		newPod("kube-system", "coredns-1", map[string]string{"k8s-app": "kube-dns"}),
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}},
+	)
+}
+
+// startFactory registers the example's informers and waits for their caches
+func startFactory(t *testing.T, clientset *fake.Clientset) informers.SharedInformerFactory {
+	t.Helper()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	setupInformers(factory)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		factory.Shutdown()
+	})
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	return factory
+}
+
+func TestListerQueries(t *testing.T) {
+	factory := startFactory(t, seededClientset())
+	podLister := factory.Core().V1().Pods().Lister()
+
+	all, _ := podLister.List(labels.Everything())
+	if len(all) != 3 {
+		t.Errorf("all pods = %d, want 3", len(all))
+	}
+	inDefault, _ := podLister.Pods("default").List(labels.Everything())
+	if len(inDefault) != 2 {
+		t.Errorf("default pods = %d, want 2", len(inDefault))
+	}
+	nginx, _ := podLister.List(labels.SelectorFromSet(labels.Set{"app": "nginx"}))
+	if len(nginx) != 1 || nginx[0].Name != "nginx-1" {
+		t.Errorf("app=nginx pods = %v, want [nginx-1]", nginx)
+	}
+	if _, err := podLister.Pods("kube-system").Get("nginx-1"); err == nil {
+		t.Error("Get is namespaced: nginx-1 must not be found in kube-system")
+	}
+}
+
+func TestUseListers(t *testing.T) {
+	factory := startFactory(t, seededClientset())
+	output := testutil.CaptureOutput(t)
+
+	useListers(factory, "")
+	testutil.WaitForOutput(t, output,
+		"Total pods (all namespaces): 3\n",
+		"  default: 2 pods\n",
+		"  kube-system: 1 pods\n",
+		"Pods in default namespace: 2\n",
+		"Found pod: ",
+		"Nginx pods: 1\n",
+		"Total deployments (all namespaces): 1\n",
+	)
+}
+
+func TestListerOfUnstartedInformerIsEmpty(t *testing.T) {
+	clientset := seededClientset()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	// Start only launches informers that were requested before it was called,
+	// which is why setupInformers has to run first
+	factory.Start(stopCh)
+	pods, _ := factory.Core().V1().Pods().Lister().List(labels.Everything())
+	if len(pods) != 0 {
+		t.Errorf("got %d pods from an informer that was never started", len(pods))
+	}
+	if factory.Core().V1().Pods().Informer().HasSynced() {
+		t.Error("informer registered after Start should not have synced")
+	}
+}
+
+func TestUseListersInOneNamespace(t *testing.T) {
+	factory := scope.NewFactory(seededClientset(), 0, "kube-system")
+	setupInformers(factory)
+	stopCh := make(chan struct{})
+	defer func() {
+		close(stopCh)
+		factory.Shutdown()
+	}()
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	output := testutil.CaptureOutput(t)
+
+	useListers(factory, "kube-system")
+	testutil.WaitForOutput(t, output,
+		"Total pods (namespace kube-system): 1\n",
+		"Pods in kube-system namespace: 1\n",
+		"Found pod: coredns-1 in namespace: kube-system\n",
+		"Total deployments (namespace kube-system): 0\n",
+	)
+}
@@ -6,15 +6,31 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"github.com/shamimice03/mastering-k8s-client-go/cachetransform"
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/informermetrics"
+	"github.com/shamimice03/mastering-k8s-client-go/scope"
+	"github.com/shamimice03/mastering-k8s-client-go/watcherrors"
+)
+
+var (
+	metricsAddr = flag.String("metrics-addr", "", "address serving /metrics; when set, the program keeps running after the queries until interrupted")
+	// namespace limits every informer and query to one namespace
+	namespace = scope.NamespaceFlag()
 )
 
 // createClientset creates and returns a Kubernetes clientset
@@ -34,6 +50,7 @@ func createClientSet() *kubernetes.Clientset {
 	if err != nil {
 		log.Fatalf("Failed to build config: %v", err)
 	}
+	clientid.Configure(config, "09-shared-informer-factory-custom-index")
 
 	// Create clientset from the config
 	clientset, err := kubernetes.NewForConfig(config)
@@ -46,37 +63,85 @@ func createClientSet() *kubernetes.Clientset {
 func main() {
 	// Create Kubernetes client
 	clientset := createClientSet()
-
-	// Test connection to cluster by listing namespaces
-	_, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		log.Fatalf("Failed to connect to cluster: %v", err)
+	// /metrics reports the pod cache set up below, and the latency and
+	// result of every API request client-go makes from here on
+	metrics := informermetrics.New()
+	metrics.RegisterClientMetrics()
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Test connection to cluster by listing namespaces. Listing namespaces is
+	// cluster-scoped, so it is skipped when a Role is all we have.
+	if *namespace == "" {
+		_, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Fatalf("Failed to connect to cluster: %v", err)
+		}
+		fmt.Println("Successfully connected to cluster")
+	}
+	// Fail fast if RBAC does not allow the informers to list and watch
+	if err := scope.Preflight(ctx, clientset, *namespace, schema.GroupResource{Resource: "pods"}); err != nil {
+		log.Fatalf("RBAC preflight failed: %v", err)
 	}
-	fmt.Println("Successfully connected to cluster")
 
 	// Create single SharedInformerFactory with 30-second resync period
-	// This factory will manage all our informers efficiently
-	factory := informers.NewSharedInformerFactory(clientset, time.Second*30)
+	// This factory will manage all our informers efficiently; with --namespace
+	// it only watches that namespace
+	factory := scope.NewFactory(clientset, time.Second*30, *namespace)
 
 	// Setup Pod informer with custom indexes for efficient querying
 	setupInformersWithCustomIndex(factory)
 
-	// Create stop channel to control informer lifecycle
-	stopCh := make(chan struct{})
+	// Strip managedFields and last-applied annotations before pods enter the cache
+	stripper := &cachetransform.Stripper{}
+	if err := factory.Core().V1().Pods().Informer().SetTransform(stripper.Transform); err != nil {
+		log.Fatalf("Failed to set transform: %v", err)
+	}
+	// Surface LIST and WATCH failures instead of letting the reflector retry silently
+	if err := factory.Core().V1().Pods().Informer().SetWatchErrorHandlerWithContext(watcherrors.Handle); err != nil {
+		log.Fatalf("Failed to set watch error handler: %v", err)
+	}
+
+	// Report cache size, index cardinality and sync status
+	metrics.AddInformer("pods", factory.Core().V1().Pods().Informer())
+
+	// Informers run until the program is interrupted or the queries are done
+	// The sync wait logs through the logger in ctx
+	ctx = klog.NewContext(ctx, klog.LoggerWithName(klog.Background(), "custom-index"))
 
-	// Start all registered informers - they begin watching API server
-	factory.Start(stopCh)
+	// Start all registered informers - they begin watching API server.
+	// The factory only takes a stop channel in this client-go version,
+	// so the informers themselves still log through the global klog logger
+	factory.Start(ctx.Done())
 
 	// Wait for all informer caches to sync with current cluster state
-	fmt.Println("Waiting for cache sync...")
-	factory.WaitForCacheSync(stopCh)
-	fmt.Println("Cache sync completed!")
+	if !cache.WaitForNamedCacheSyncWithContext(ctx, factory.Core().V1().Pods().Informer().HasSynced) {
+		factory.Shutdown()
+		return
+	}
+	stripper.PrintReport(factory.Core().V1().Pods().Informer().GetStore())
 
 	// Perform custom indexer queries on cached data
 	queryWithCustomIndexers(factory)
+	queryByOwner(ctx, clientset, factory.Core().V1().Pods().Informer().GetIndexer())
+	serveMetricsUntilInterrupted(ctx, metrics)
+
+	// Stop the informers and wait for them to exit
+	stop()
+	factory.Shutdown()
+}
+
+// podNodeIndexFunc indexes a pod by the name of the node it is scheduled on
+func podNodeIndexFunc(obj interface{}) ([]string, error) {
+	pod := obj.(*corev1.Pod)
+	return []string{pod.Spec.NodeName}, nil
+}
 
-	// Close the channel to signal all informers to stop and exit program
-	close(stopCh)
+// podPhaseIndexFunc indexes a pod by its phase (Running, Pending, etc.)
+func podPhaseIndexFunc(obj interface{}) ([]string, error) {
+	pod := obj.(*corev1.Pod)
+	return []string{string(pod.Status.Phase)}, nil
 }
 
 // setupInformersWithCustomIndex creates Pod informer and adds custom indexes
@@ -88,15 +153,11 @@ func setupInformersWithCustomIndex(factory informers.SharedInformerFactory) {
 	podInformer.Informer().AddIndexers(
 		cache.Indexers{
 			// Index pods by the node they're running on
-			"node": func(obj interface{}) ([]string, error) {
-				pod := obj.(*corev1.Pod)
-				return []string{pod.Spec.NodeName}, nil
-			},
+			"node": podNodeIndexFunc,
 			// Index pods by their current phase (Running, Pending, etc.)
-			"phase": func(obj interface{}) ([]string, error) {
-				pod := obj.(*corev1.Pod)
-				return []string{string(pod.Status.Phase)}, nil
-			},
+			"phase": podPhaseIndexFunc,
+			// Index pods by the UID of their owners (see owners.go)
+			ownerUIDIndex: podOwnerUIDIndexFunc,
 			// Additional custom indexes can be added here
 		})
 }
@@ -138,3 +199,15 @@ func queryWithCustomIndexers(factory informers.SharedInformerFactory) {
 	}
 	fmt.Printf("Running pods: %d\n", len(runningPods))
 }
+
+// serveMetricsUntilInterrupted serves /metrics on --metrics-addr until ctx is
+// cancelled. Without --metrics-addr it returns at once and the program exits.
+func serveMetricsUntilInterrupted(ctx context.Context, metrics *informermetrics.Registry) {
+	if *metricsAddr == "" {
+		return
+	}
+	fmt.Printf("Serving metrics on %s/metrics, press Ctrl-C to exit\n", *metricsAddr)
+	if err := metrics.Serve(ctx, *metricsAddr); err != nil {
+		log.Fatalf("Metrics server failed: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func newPod(namespace, name, nodeName string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+// startFactory adds the example's indexes and waits for the pod cache
+func startFactory(t *testing.T, clientset *fake.Clientset) informers.SharedInformerFactory {
+	t.Helper()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	setupInformersWithCustomIndex(factory)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		factory.Shutdown()
+	})
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	return factory
+}
+
+func TestIndexFuncs(t *testing.T) {
+	pod := newPod("default", "web", "node-a", corev1.PodRunning)
+
+	if keys, _ := podNodeIndexFunc(pod); !slices.Equal(keys, []string{"node-a"}) {
+		t.Errorf("podNodeIndexFunc = %v, want [node-a]", keys)
+	}
+	if keys, _ := podPhaseIndexFunc(pod); !slices.Equal(keys, []string{"Running"}) {
+		t.Errorf("podPhaseIndexFunc = %v, want [Running]", keys)
+	}
+}
+
+func TestCustomIndexes(t *testing.T) {
+	factory := startFactory(t, fake.NewSimpleClientset(
+		newPod("default", "web", "node-a", corev1.PodRunning),
+		newPod("kube-system", "coredns", "node-b", corev1.PodRunning),
+		newPod("default", "migrate", "node-a", corev1.PodSucceeded),
+		newPod("default", "queued", "", corev1.PodPending),
+	))
+	indexer := factory.Core().V1().Pods().Informer().GetIndexer()
+
+	phases := indexer.ListIndexFuncValues("phase")
+	slices.Sort(phases)
+	if !slices.Equal(phases, []string{"Pending", "Running", "Succeeded"}) {
+		t.Errorf("phase index values = %v", phases)
+	}
+	running, _ := indexer.ByIndex("phase", "Running")
+	if len(running) != 2 {
+		t.Errorf("Running pods = %d, want 2", len(running))
+	}
+	onNodeA, _ := indexer.ByIndex("node", "node-a")
+	if len(onNodeA) != 2 {
+		t.Errorf("pods on node-a = %d, want 2", len(onNodeA))
+	}
+	// Pods can be found by index value and then by any other key
+	keys, _ := indexer.IndexKeys("node", "node-b")
+	if !slices.Equal(keys, []string{"kube-system/coredns"}) {
+		t.Errorf("keys on node-b = %v", keys)
+	}
+}
+
+func TestQueryWithCustomIndexers(t *testing.T) {
+	factory := startFactory(t, fake.NewSimpleClientset(
+		newPod("default", "web", "node-a", corev1.PodRunning),
+		newPod("default", "api", "node-a", corev1.PodRunning),
+		newPod("default", "migrate", "node-a", corev1.PodSucceeded),
+	))
+	output := testutil.CaptureOutput(t)
+
+	queryWithCustomIndexers(factory)
+	testutil.WaitForOutput(t, output,
+		"Available nodes: [node-a]\n",
+		"Pods on node 'node-a': 3\n",
+		"  - web (namespace: default)\n",
+		"Running pods: 2\n",
+	)
+}
+
+func TestQueryWithoutPods(t *testing.T) {
+	factory := startFactory(t, fake.NewSimpleClientset())
+	output := testutil.CaptureOutput(t)
+
+	queryWithCustomIndexers(factory)
+	testutil.WaitForOutput(t, output, "No nodes found\n", "Running pods: 0\n")
+}
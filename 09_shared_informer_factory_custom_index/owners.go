@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ownerUIDIndex indexes pods by the UID of every object in their
+// ownerReferences, such as the ReplicaSet, StatefulSet or Job that created them
+const ownerUIDIndex = "ownerUID"
+
+var replicaSet = flag.String("replicaset", "", "namespace/name of a ReplicaSet whose pods are looked up through the ownerUID index (default: the owner of the first ReplicaSet pod in the cache)")
+
+// podOwnerUIDIndexFunc indexes a pod under the UID of each of its owners. Pods
+// created directly have no owners and are not in the index.
+func podOwnerUIDIndexFunc(obj interface{}) ([]string, error) {
+	pod := obj.(*corev1.Pod)
+	uids := make([]string, 0, len(pod.OwnerReferences))
+	for _, owner := range pod.OwnerReferences {
+		uids = append(uids, string(owner.UID))
+	}
+	return uids, nil
+}
+
+// podsOwnedBy returns the cached pods that list owner in their
+// ownerReferences. The lookup goes through ownerUIDIndex, so its cost does not
+// depend on how many pods the cache holds. A controller reconciling a
+// ReplicaSet uses the same lookup to find its children.
+func podsOwnedBy(indexer cache.Indexer, owner metav1.Object) ([]*corev1.Pod, error) {
+	objs, err := indexer.ByIndex(ownerUIDIndex, string(owner.GetUID()))
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		pods = append(pods, obj.(*corev1.Pod))
+	}
+	return pods, nil
+}
+
+// findReplicaSet fetches the ReplicaSet named by --replicaset. Without the
+// flag it takes the owner of the first cached pod a ReplicaSet controls.
+// The UID is what the index is keyed by, and only the API server knows it
+// for a name, so the ReplicaSet is read with a GET rather than cached: this
+// example only watches pods.
+func findReplicaSet(ctx context.Context, clientset kubernetes.Interface, indexer cache.Indexer) (*appsv1.ReplicaSet, error) {
+	ns, name, found := strings.Cut(*replicaSet, "/")
+	if *replicaSet != "" && !found {
+		return nil, fmt.Errorf("--replicaset %q: want namespace/name", *replicaSet)
+	}
+	if *replicaSet == "" {
+		for _, obj := range indexer.List() {
+			pod := obj.(*corev1.Pod)
+			if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "ReplicaSet" {
+				ns, name = pod.Namespace, owner.Name
+				break
+			}
+		}
+		if name == "" {
+			return nil, nil
+		}
+	}
+	return clientset.AppsV1().ReplicaSets(ns).Get(ctx, name, metav1.GetOptions{})
+}
+
+// queryByOwner prints the pods of one ReplicaSet, found through ownerUIDIndex
+func queryByOwner(ctx context.Context, clientset kubernetes.Interface, indexer cache.Indexer) {
+	rs, err := findReplicaSet(ctx, clientset, indexer)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if rs == nil {
+		fmt.Println("No pods owned by a ReplicaSet found")
+		return
+	}
+	pods, err := podsOwnedBy(indexer, rs)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("Pods owned by ReplicaSet %s/%s (uid %s): %d\n", rs.Namespace, rs.Name, rs.UID, len(pods))
+	for _, pod := range pods {
+		fmt.Printf("  - %s (phase: %s)\n", pod.Name, pod.Status.Phase)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+// ownedPod returns a pod controlled by the ReplicaSet rs
+func ownedPod(name string, rs *appsv1.ReplicaSet) *corev1.Pod {
+	pod := newPod(rs.Namespace, name, "node-a", corev1.PodRunning)
+	pod.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(rs, appsv1.SchemeGroupVersion.WithKind("ReplicaSet"))}
+	return pod
+}
+
+func newReplicaSet(name string, uid types.UID) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: uid}}
+}
+
+func TestPodOwnerUIDIndexFunc(t *testing.T) {
+	pod := ownedPod("web-1", newReplicaSet("web", "uid-web"))
+	pod.OwnerReferences = append(pod.OwnerReferences, metav1.OwnerReference{Kind: "Widget", Name: "w", UID: "uid-widget"})
+	if keys, _ := podOwnerUIDIndexFunc(pod); !slices.Equal(keys, []string{"uid-web", "uid-widget"}) {
+		t.Errorf("podOwnerUIDIndexFunc = %v, want both owners", keys)
+	}
+	if keys, _ := podOwnerUIDIndexFunc(newPod("default", "bare", "", corev1.PodPending)); len(keys) != 0 {
+		t.Errorf("pod without owners indexed under %v", keys)
+	}
+}
+
+func TestPodsOwnedBy(t *testing.T) {
+	web := newReplicaSet("web", "uid-web")
+	api := newReplicaSet("api", "uid-api")
+	factory := startFactory(t, fake.NewSimpleClientset(
+		ownedPod("web-1", web),
+		ownedPod("web-2", web),
+		ownedPod("api-1", api),
+		newPod("default", "bare", "node-a", corev1.PodRunning),
+	))
+	indexer := factory.Core().V1().Pods().Informer().GetIndexer()
+
+	pods, err := podsOwnedBy(indexer, web)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"web-1", "web-2"}) {
+		t.Errorf("pods of web = %v", names)
+	}
+	// A ReplicaSet recreated under the old name has a new UID, so it does
+	// not claim the pods of its predecessor
+	if pods, _ := podsOwnedBy(indexer, newReplicaSet("web", "uid-recreated")); len(pods) != 0 {
+		t.Errorf("recreated ReplicaSet owns %d pods of its predecessor", len(pods))
+	}
+}
+
+func TestQueryByOwner(t *testing.T) {
+	web := newReplicaSet("web", "uid-web")
+	clientset := fake.NewSimpleClientset(web, ownedPod("web-1", web), ownedPod("web-2", web))
+	factory := startFactory(t, clientset)
+	indexer := factory.Core().V1().Pods().Informer().GetIndexer()
+	output := testutil.CaptureOutput(t)
+
+	// Without --replicaset the owner of a cached pod is used
+	queryByOwner(context.Background(), clientset, indexer)
+	testutil.WaitForOutput(t, output,
+		"Pods owned by ReplicaSet default/web (uid uid-web): 2\n",
+		"  - web-1 (phase: Running)\n",
+	)
+
+	*replicaSet = "default"
+	t.Cleanup(func() { *replicaSet = "" })
+	queryByOwner(context.Background(), clientset, indexer)
+	testutil.WaitForOutput(t, output, `Error: --replicaset "default": want namespace/name`)
+}
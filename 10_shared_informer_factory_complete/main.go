@@ -1,19 +1,44 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"github.com/shamimice03/mastering-k8s-client-go/cachetransform"
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/controller"
+	"github.com/shamimice03/mastering-k8s-client-go/healthz"
+	"github.com/shamimice03/mastering-k8s-client-go/informermetrics"
+	"github.com/shamimice03/mastering-k8s-client-go/scope"
+	"github.com/shamimice03/mastering-k8s-client-go/watcherrors"
+)
+
+var (
+	metricsAddr = flag.String("metrics-addr", ":9102", "address serving /metrics (empty disables)")
+	healthAddr  = flag.String("health-addr", ":8081", "address serving /healthz and /readyz (empty disables)")
+	// namespace limits every informer and query to one namespace
+	namespace = scope.NamespaceFlag()
 )
 
 // createClientset creates and returns a Kubernetes clientset
@@ -33,6 +58,7 @@ func createClientSet() *kubernetes.Clientset {
 	if err != nil {
 		log.Fatalf("Failed to build config: %v", err)
 	}
+	clientid.Configure(config, "10-shared-informer-factory-complete")
 
 	// Create clientset
 	clientset, err := kubernetes.NewForConfig(config)
@@ -46,27 +72,92 @@ func createClientSet() *kubernetes.Clientset {
 func main() {
 	// Create Kubernetes clientset
 	clientset := createClientSet()
+	// /metrics reports the pod cache and handler set up below, and the latency and
+	// result of every API request client-go makes from here on
+	metrics := informermetrics.New()
+	metrics.RegisterClientMetrics()
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Fail fast if RBAC does not allow the informers to list and watch
+	if err := scope.Preflight(ctx, clientset, *namespace, schema.GroupResource{Resource: "pods"}); err != nil {
+		log.Fatalf("RBAC preflight failed: %v", err)
+	}
 
-	// Create SharedInformerFactory with 30-second resync period
-	factory := informers.NewSharedInformerFactory(clientset, time.Second*30)
+	// Create SharedInformerFactory with 30-second resync period, scoped to
+	// --namespace when it is set
+	factory := scope.NewFactory(clientset, time.Second*30, *namespace)
 
 	// Setup custom indexers
 	setupCustomIndexers(factory)
 
-	// Setup event handlers
-	setupPodMonitor(factory)
+	// Strip managedFields and last-applied annotations before pods enter the cache
+	stripper := &cachetransform.Stripper{}
+	if err := factory.Core().V1().Pods().Informer().SetTransform(stripper.Transform); err != nil {
+		log.Fatalf("Failed to set transform: %v", err)
+	}
+	// Surface LIST and WATCH failures instead of letting the reflector retry silently
+	if err := factory.Core().V1().Pods().Informer().SetWatchErrorHandlerWithContext(watcherrors.Handle); err != nil {
+		log.Fatalf("Failed to set watch error handler: %v", err)
+	}
+
+	// Report cache size, index cardinality and sync status
+	metrics.AddInformer("pods", factory.Core().V1().Pods().Informer())
+
+	// Setup the pod controller, and serve its reconcile counts next to the
+	// cache statistics
+	monitor, err := setupPodMonitor(factory, metrics)
+	if err != nil {
+		log.Fatalf("Failed to set up pod monitor: %v", err)
+	}
+	metrics.AddCollector(func(w io.Writer) { controller.WriteMetrics(w, monitor) })
 
 	// Start and wait for sync
-	stopCh := make(chan struct{})
-	factory.Start(stopCh)
-	factory.WaitForCacheSync(stopCh)
+	// The sync wait logs through the logger in ctx
+	ctx = klog.NewContext(ctx, klog.LoggerWithName(klog.Background(), "pod-monitor"))
+	// SharedInformerFactory only takes a stop channel in this client-go version,
+	// so the informers themselves still log through the global klog logger
+	if *metricsAddr != "" {
+		go func() {
+			if err := metrics.Serve(ctx, *metricsAddr); err != nil {
+				log.Fatalf("Metrics server failed: %v", err)
+			}
+		}()
+		fmt.Printf("Serving metrics on %s/metrics\n", *metricsAddr)
+	}
+	// Ready once every informer the factory started has synced
+	health := healthz.New()
+	health.AddReadyCheck("informers", healthz.FactorySynced(factory))
+	if *healthAddr != "" {
+		go func() {
+			if err := health.Serve(ctx, *healthAddr); err != nil {
+				log.Fatalf("Health server failed: %v", err)
+			}
+		}()
+	}
+	factory.Start(ctx.Done())
+	var running wait.Group
+	// Run only fails when ctx is cancelled before the cache synced
+	running.StartWithContext(ctx, func(ctx context.Context) { _ = monitor.Run(ctx) })
+	// The wait only fails when ctx is cancelled, so skip straight to shutdown
+	if cache.WaitForNamedCacheSyncWithContext(ctx, factory.Core().V1().Pods().Informer().HasSynced) {
+		stripper.PrintReport(factory.Core().V1().Pods().Informer().GetStore())
+
+		// Query using listers and custom indexes
+		queryBylisters(factory, *namespace)
+		queryByCustomIndexes(factory)
 
-	// Query using listers and custom indexes
-	queryBylisters(factory)
-	queryByCustomIndexes(factory)
+		// Block until program termination
+		<-ctx.Done()
+	}
 
-	// Block until program termination
-	<-stopCh
+	// The monitor finishes its running reconcile before the informers stop.
+	// Shutdown returns once every informer goroutine has exited
+	fmt.Println("Shutting down, waiting for the monitor and informers to stop...")
+	running.Wait()
+	factory.Shutdown()
+	fmt.Println("Exited cleanly")
 }
 
 // setupCustomIndexers adds custom indexing functions to the pod informer
@@ -76,39 +167,80 @@ func setupCustomIndexers(factory informers.SharedInformerFactory) {
 
 	// Add custom indexer that indexes pods by node name
 	podInformer.Informer().AddIndexers(cache.Indexers{
-		"node": func(obj interface{}) ([]string, error) {
-			pod := obj.(*corev1.Pod)
-			return []string{pod.Spec.NodeName}, nil
-		},
+		"node": podNodeIndexFunc,
 	})
 }
 
-// setupPodMonitor configures event handlers for pod events
-func setupPodMonitor(factory informers.SharedInformerFactory) {
+// podNodeIndexFunc indexes a pod by the name of the node it is scheduled on
+func podNodeIndexFunc(obj interface{}) ([]string, error) {
+	pod := obj.(*corev1.Pod)
+	return []string{pod.Spec.NodeName}, nil
+}
+
+// podMonitor prints every pod it reconciles for the first time. A reconcile
+// only gets the pod's key, so the keys already printed are remembered.
+type podMonitor struct {
+	lister corelisters.PodLister
+	mu     sync.Mutex
+	seen   map[string]bool
+}
+
+func (m *podMonitor) reconcile(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	pod, err := m.lister.Pods(namespace).Get(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if apierrors.IsNotFound(err) {
+		// A pod created again under the same name is printed again
+		delete(m.seen, key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !m.seen[key] {
+		m.seen[key] = true
+		fmt.Printf("Pod added: %s\n", pod.Name)
+	}
+	return nil
+}
+
+// setupPodMonitor builds a controller that reconciles every pod the informer
+// delivers. Run starts it.
+func setupPodMonitor(factory informers.SharedInformerFactory, metrics *informermetrics.Registry) (*controller.Controller, error) {
 	// Get pod informer
 	podInformer := factory.Core().V1().Pods()
 
-	// Add event handler for pod additions
-	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			pod := obj.(*corev1.Pod)
-			fmt.Printf("Pod added: %s\n", pod.Name)
+	monitor := &podMonitor{lister: podInformer.Lister(), seen: make(map[string]bool)}
+	// Events reaching the controller are counted in informer_handler_events_total
+	return controller.New(podInformer.Informer(), nil, monitor.reconcile, controller.Options{
+		Name: "PodMonitor",
+		Instrument: func(handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+			return metrics.CountEvents("pods", "PodMonitor", handler)
 		},
 	})
 }
 
-// queryBylisters demonstrates querying using listers
-func queryBylisters(factory informers.SharedInformerFactory) {
+// queryBylisters demonstrates querying using listers. Every query goes through
+// a namespaced lister, so with ns set nothing outside ns is ever asked for.
+func queryBylisters(factory informers.SharedInformerFactory, ns string) {
 	// Get pod lister
 	podLister := factory.Core().V1().Pods().Lister()
 
-	// Query by namespace
-	defaultPods, _ := podLister.Pods("default").List(labels.Everything())
-	fmt.Printf("Default namespace pods: %d\n", len(defaultPods))
+	// Query by namespace (default, or ns when scoped)
+	queryNamespace := "default"
+	if ns != "" {
+		queryNamespace = ns
+	}
+	defaultPods, _ := podLister.Pods(queryNamespace).List(labels.Everything())
+	fmt.Printf("Pods in %s namespace: %d\n", queryNamespace, len(defaultPods))
 
 	// Query by labels
 	labelSelector, _ := labels.Parse("app=nginx")
-	nginxPods, _ := podLister.List(labelSelector)
+	nginxPods, _ := podLister.Pods(ns).List(labelSelector)
 	fmt.Printf("Nginx pods: %d\n", len(nginxPods))
 }
 
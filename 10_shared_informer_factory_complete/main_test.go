@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/informermetrics"
+	"github.com/shamimice03/mastering-k8s-client-go/scope"
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func newPod(namespace, name, nodeName string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: podLabels},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+// startFactory sets the factory up the way main does and waits for the pod cache
+func startFactory(t *testing.T, clientset *fake.Clientset) informers.SharedInformerFactory {
+	t.Helper()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	setupCustomIndexers(factory)
+	monitor, err := setupPodMonitor(factory, informermetrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var running wait.Group
+	t.Cleanup(func() {
+		cancel()
+		running.Wait()
+		factory.Shutdown()
+	})
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+	running.StartWithContext(ctx, func(ctx context.Context) { _ = monitor.Run(ctx) })
+	return factory
+}
+
+func seededClientset() *fake.Clientset {
+	return fake.NewSimpleClientset(
+		newPod("default", "nginx-1", "node-a", map[string]string{"app": "nginx"}),
+		newPod("default", "nginx-2", "node-a", map[string]string{"app": "nginx"}),
+		newPod("kube-system", "coredns", "node-a", nil),
+	)
+}
+
+func TestPodNodeIndexFunc(t *testing.T) {
+	keys, _ := podNodeIndexFunc(newPod("default", "web", "node-a", nil))
+	if !slices.Equal(keys, []string{"node-a"}) {
+		t.Errorf("got %v, want [node-a]", keys)
+	}
+}
+
+func TestPodMonitorSeesExistingPods(t *testing.T) {
+	output := testutil.CaptureOutput(t)
+	startFactory(t, seededClientset())
+
+	testutil.WaitForOutput(t, output,
+		"Pod added: nginx-1\n",
+		"Pod added: nginx-2\n",
+		"Pod added: coredns\n",
+	)
+}
+
+func TestQueries(t *testing.T) {
+	output := testutil.CaptureOutput(t)
+	factory := startFactory(t, seededClientset())
+
+	queryBylisters(factory, "")
+	queryByCustomIndexes(factory)
+	testutil.WaitForOutput(t, output,
+		"Pods in default namespace: 2\n",
+		"Nginx pods: 2\n",
+		"Nodes: [node-a]\n",
+		"Pods on node-a: 3\n",
+	)
+}
+
+func TestQueriesInOneNamespace(t *testing.T) {
+	factory := scope.NewFactory(seededClientset(), 0, "kube-system")
+	setupCustomIndexers(factory)
+	stopCh := make(chan struct{})
+	defer func() {
+		close(stopCh)
+		factory.Shutdown()
+	}()
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	output := testutil.CaptureOutput(t)
+
+	queryBylisters(factory, "kube-system")
+	queryByCustomIndexes(factory)
+	testutil.WaitForOutput(t, output,
+		"Pods in kube-system namespace: 1\n",
+		"Nginx pods: 0\n",
+		"Pods on node-a: 1\n",
+	)
+}
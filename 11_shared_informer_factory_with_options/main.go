@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -15,6 +17,8 @@ import (
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
 )
 
 // createClientset creates and returns a Kubernetes clientset
@@ -32,6 +36,7 @@ func createClientSet() *kubernetes.Clientset {
 	if err != nil {
 		log.Fatalf("Failed to build config: %v", err)
 	}
+	clientid.Configure(config, "11-shared-informer-factory-with-options")
 	// Create clientset from the config
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -43,25 +48,38 @@ func createClientSet() *kubernetes.Clientset {
 func main() {
 	// Create Kubernetes client
 	clientset := createClientSet()
+	// Ctrl-C or SIGTERM cancels the context, and with it the request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	// Test connection to cluster by listing namespaces
-	_, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	_, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		log.Fatalf("Failed to connect to cluster: %v", err)
 	}
 	fmt.Println("Successfully connected to cluster")
 
+	// Create one factory per option set
+	factories := createFactories(clientset)
+	fmt.Printf("Created %d informer factories\n", len(factories))
+}
+
+// createFactories builds a SharedInformerFactory for each way of scoping informers
+func createFactories(clientset kubernetes.Interface) map[string]informers.SharedInformerFactory {
+	factories := map[string]informers.SharedInformerFactory{}
+
 	// Create single SharedInformerFactory with 30-second resync period
-	informers.NewSharedInformerFactory(clientset, time.Second*30)
+	factories["all"] = informers.NewSharedInformerFactory(clientset, time.Second*30)
 
 	// Create factory scoped to specific namespace
-	informers.NewSharedInformerFactoryWithOptions(
+	factories["namespace"] = informers.NewSharedInformerFactoryWithOptions(
 		clientset,
 		time.Second*30,
 		informers.WithNamespace("default"),
 	)
 
 	// Create factory filtered by label selector
-	informers.NewSharedInformerFactoryWithOptions(
+	factories["label"] = informers.NewSharedInformerFactoryWithOptions(
 		clientset,
 		time.Second*30,
 		informers.WithTweakListOptions(func(lo *metav1.ListOptions) {
@@ -70,7 +88,7 @@ func main() {
 	)
 
 	// Create factory filtered by field selector
-	informers.NewSharedInformerFactoryWithOptions(
+	factories["field"] = informers.NewSharedInformerFactoryWithOptions(
 		clientset,
 		time.Second*30,
 		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
@@ -79,7 +97,7 @@ func main() {
 	)
 
 	// Create factory with multiple filters combined
-	informers.NewSharedInformerFactoryWithOptions(
+	factories["combined"] = informers.NewSharedInformerFactoryWithOptions(
 		clientset,
 		time.Second*30,
 		informers.WithNamespace("kube-system"),
@@ -90,7 +108,7 @@ func main() {
 	)
 
 	// Create factory with custom resync periods per resource type
-	informers.NewSharedInformerFactoryWithOptions(
+	factories["custom-resync"] = informers.NewSharedInformerFactoryWithOptions(
 		clientset,
 		time.Second*30,
 		informers.WithCustomResyncConfig(map[metav1.Object]time.Duration{
@@ -98,4 +116,6 @@ func main() {
 			&appsv1.Deployment{}: time.Second * 60,
 		}),
 	)
+
+	return factories
 }
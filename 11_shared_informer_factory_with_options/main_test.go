@@ -0,0 +1,112 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newPod(namespace, name string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: podLabels}}
+}
+
+func seededClientset() *fake.Clientset {
+	return fake.NewSimpleClientset(
+		newPod("default", "nginx", map[string]string{"app": "nginx"}),
+		newPod("default", "redis", map[string]string{"app": "redis"}),
+		newPod("kube-system", "coredns", map[string]string{"k8s-app": "kube-dns"}),
+		newPod("kube-system", "etcd", nil),
+	)
+}
+
+// cachedPods starts the pod informer of one factory and returns the sorted keys it caches
+func cachedPods(t *testing.T, factory informers.SharedInformerFactory) []string {
+	t.Helper()
+	lister := factory.Core().V1().Pods().Lister()
+	stopCh := make(chan struct{})
+	defer func() {
+		close(stopCh)
+		factory.Shutdown()
+	}()
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	pods, err := lister.List(labels.Everything())
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var keys []string
+	for _, pod := range pods {
+		keys = append(keys, pod.Namespace+"/"+pod.Name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestFactoryOptionsScopeTheCache(t *testing.T) {
+	tests := []struct {
+		factory string
+		want    []string
+	}{
+		{"all", []string{"default/nginx", "default/redis", "kube-system/coredns", "kube-system/etcd"}},
+		{"namespace", []string{"default/nginx", "default/redis"}},
+		{"label", []string{"default/nginx"}},
+		{"custom-resync", []string{"default/nginx", "default/redis", "kube-system/coredns", "kube-system/etcd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.factory, func(t *testing.T) {
+			factory := createFactories(seededClientset())[tt.factory]
+			if got := cachedPods(t, factory); !slices.Equal(got, tt.want) {
+				t.Errorf("cached %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFactoryOptionsReachTheListRequest(t *testing.T) {
+	// The fake clientset filters by namespace and labels but ignores field
+	// selectors, so check the LIST request the informer sends instead
+	tests := []struct {
+		factory, namespace, labels, fields string
+	}{
+		{"all", "", "", ""},
+		{"namespace", "default", "", ""},
+		{"label", "", "app=nginx", ""},
+		{"field", "", "", "status.phase=Running"},
+		{"combined", "kube-system", "k8s-app", "status.phase=Running"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.factory, func(t *testing.T) {
+			clientset := seededClientset()
+			cachedPods(t, createFactories(clientset)[tt.factory])
+
+			var list k8stesting.ListAction
+			for _, action := range clientset.Actions() {
+				if a, ok := action.(k8stesting.ListAction); ok && a.GetResource().Resource == "pods" {
+					list = a
+					break
+				}
+			}
+			if list == nil {
+				t.Fatal("no pod LIST request was sent")
+			}
+			restrictions := list.GetListRestrictions()
+			if list.GetNamespace() != tt.namespace {
+				t.Errorf("namespace = %q, want %q", list.GetNamespace(), tt.namespace)
+			}
+			if got := restrictions.Labels.String(); got != tt.labels {
+				t.Errorf("label selector = %q, want %q", got, tt.labels)
+			}
+			if got := restrictions.Fields.String(); got != tt.fields {
+				t.Errorf("field selector = %q, want %q", got, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
+	"sigs.k8s.io/yaml"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/reportsink"
+)
+
+var (
+	namespace  = flag.String("namespace", "default", "namespace to analyze (empty for all namespaces)")
+	interval   = flag.Duration("interval", 15*time.Second, "how often to poll metrics.k8s.io")
+	duration   = flag.Duration("duration", 5*time.Minute, "how long to collect usage samples")
+	bufferSize = flag.Int("samples", 40, "number of samples kept per container (ring buffer size)")
+	percentile = flag.Float64("percentile", 90, "CPU usage percentile used for the recommendation")
+	headroom   = flag.Float64("headroom", 1.15, "multiplier applied on top of observed usage")
+	patchDir   = flag.String("patch-dir", "", "write one recommended patch per Deployment into this directory")
+	sinkSpec   = flag.String("sink", "stdout", "where the capacity report goes: stdout, file://<dir> or s3://<bucket>/<prefix>")
+)
+
+// createClients creates and returns a Kubernetes clientset and a metrics clientset
+func createClients() (*kubernetes.Clientset, *metricsclientset.Clientset) {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// A zero-sized ring buffer has no slot to write the first sample into
+	if *bufferSize <= 0 {
+		log.Fatalf("--samples must be positive, not %d", *bufferSize)
+	}
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "12-workload-rightsizing")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	// Create metrics clientset (metrics.k8s.io, served by metrics-server)
+	metricsClient, err := metricsclientset.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create metrics clientset: %v", err)
+	}
+	return clientset, metricsClient
+}
+
+// sample is a single usage observation for one container
+type sample struct {
+	cpuMilli int64
+	memBytes int64
+}
+
+// ringBuffer keeps the last N samples, overwriting the oldest when full
+type ringBuffer struct {
+	samples []sample
+	next    int
+	full    bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{samples: make([]sample, size)}
+}
+
+func (r *ringBuffer) add(s sample) {
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// values returns the buffered samples (order is not preserved)
+func (r *ringBuffer) values() []sample {
+	if r.full {
+		return r.samples
+	}
+	return r.samples[:r.next]
+}
+
+// workloadRef identifies the controller that owns a pod
+type workloadRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// containerKey groups samples of the same container across all pods of a workload
+type containerKey struct {
+	Workload  workloadRef
+	Container string
+}
+
+// analyzer combines polled usage with requests/limits from the informer cache
+type analyzer struct {
+	factory informers.SharedInformerFactory
+	buffers map[containerKey]*ringBuffer
+}
+
+// resolveWorkload walks ownerReferences (Pod -> ReplicaSet -> Deployment) using listers
+func (a *analyzer) resolveWorkload(pod *corev1.Pod) workloadRef {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return workloadRef{Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name}
+	}
+	if owner.Kind == "ReplicaSet" {
+		rs, err := a.factory.Apps().V1().ReplicaSets().Lister().ReplicaSets(pod.Namespace).Get(owner.Name)
+		if err == nil {
+			if rsOwner := metav1.GetControllerOf(rs); rsOwner != nil && rsOwner.Kind == "Deployment" {
+				return workloadRef{Kind: "Deployment", Namespace: pod.Namespace, Name: rsOwner.Name}
+			}
+		}
+	}
+	return workloadRef{Kind: owner.Kind, Namespace: pod.Namespace, Name: owner.Name}
+}
+
+// collect polls PodMetrics once and appends a sample per container
+func (a *analyzer) collect(ctx context.Context, metricsClient *metricsclientset.Clientset) error {
+	podMetrics, err := metricsClient.MetricsV1beta1().PodMetricses(*namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	podLister := a.factory.Core().V1().Pods().Lister()
+	for _, pm := range podMetrics.Items {
+		// Map the metrics back to the cached pod to find its owner
+		pod, err := podLister.Pods(pm.Namespace).Get(pm.Name)
+		if err != nil {
+			continue
+		}
+		workload := a.resolveWorkload(pod)
+		for _, c := range pm.Containers {
+			key := containerKey{Workload: workload, Container: c.Name}
+			buf, ok := a.buffers[key]
+			if !ok {
+				buf = newRingBuffer(*bufferSize)
+				a.buffers[key] = buf
+			}
+			buf.add(sample{
+				cpuMilli: c.Usage.Cpu().MilliValue(),
+				memBytes: c.Usage.Memory().Value(),
+			})
+		}
+	}
+	return nil
+}
+
+// recommendation is the suggested request values for one container
+type recommendation struct {
+	Key        containerKey
+	Samples    int
+	CurrentCPU *resource.Quantity
+	CurrentMem *resource.Quantity
+	CPU        resource.Quantity
+	Memory     resource.Quantity
+}
+
+// percentileOf returns the p-th percentile of values (nearest-rank)
+func percentileOf(values []int64, p float64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// currentRequests looks up the container requests of a workload from the cache
+func (a *analyzer) currentRequests(key containerKey) (*resource.Quantity, *resource.Quantity) {
+	selector := labels.Everything()
+	pods, err := a.factory.Core().V1().Pods().Lister().Pods(key.Workload.Namespace).List(selector)
+	if err != nil {
+		return nil, nil
+	}
+	for _, pod := range pods {
+		if a.resolveWorkload(pod) != key.Workload {
+			continue
+		}
+		for _, c := range pod.Spec.Containers {
+			if c.Name != key.Container {
+				continue
+			}
+			var cpu, mem *resource.Quantity
+			if q, ok := c.Resources.Requests[corev1.ResourceCPU]; ok {
+				cpu = &q
+			}
+			if q, ok := c.Resources.Requests[corev1.ResourceMemory]; ok {
+				mem = &q
+			}
+			return cpu, mem
+		}
+	}
+	return nil, nil
+}
+
+// minMemoryMiB is the smallest memory request recommended, enough for the
+// container runtime to start the process
+const minMemoryMiB = 16
+
+// recommend computes suggestions: CPU at the configured percentile, memory at the peak
+func (a *analyzer) recommend() []recommendation {
+	var recs []recommendation
+	for key, buf := range a.buffers {
+		values := buf.values()
+		cpus := make([]int64, 0, len(values))
+		var peakMem int64
+		for _, s := range values {
+			cpus = append(cpus, s.cpuMilli)
+			if s.memBytes > peakMem {
+				peakMem = s.memBytes
+			}
+		}
+		cpu := int64(float64(percentileOf(cpus, *percentile)) * *headroom)
+		if cpu < 1 {
+			cpu = 1
+		}
+		// Round memory up to whole MiB so patches stay readable
+		mem := int64(float64(peakMem) * *headroom)
+		mem = (mem + (1 << 20) - 1) / (1 << 20)
+		// A container that never reported memory would otherwise get 0Mi
+		if mem < minMemoryMiB {
+			mem = minMemoryMiB
+		}
+
+		currentCPU, currentMem := a.currentRequests(key)
+		recs = append(recs, recommendation{
+			Key:        key,
+			Samples:    len(values),
+			CurrentCPU: currentCPU,
+			CurrentMem: currentMem,
+			CPU:        *resource.NewMilliQuantity(cpu, resource.DecimalSI),
+			Memory:     resource.MustParse(fmt.Sprintf("%dMi", mem)),
+		})
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		return fmt.Sprint(recs[i].Key) < fmt.Sprint(recs[j].Key)
+	})
+	return recs
+}
+
+func quantityString(q *resource.Quantity) string {
+	if q == nil {
+		return "<none>"
+	}
+	return q.String()
+}
+
+// writeReport shows current vs suggested requests per container
+func writeReport(w io.Writer, recs []recommendation) {
+	fmt.Fprintf(w, "%-45s %-15s %-8s %-12s %-12s %-12s %-12s\n",
+		"WORKLOAD", "CONTAINER", "SAMPLES", "CPU(req)", "CPU(rec)", "MEM(req)", "MEM(rec)")
+	for _, r := range recs {
+		workload := fmt.Sprintf("%s/%s/%s", r.Key.Workload.Namespace, r.Key.Workload.Kind, r.Key.Workload.Name)
+		fmt.Fprintf(w, "%-45s %-15s %-8d %-12s %-12s %-12s %-12s\n",
+			workload, r.Key.Container, r.Samples,
+			quantityString(r.CurrentCPU), r.CPU.String(),
+			quantityString(r.CurrentMem), r.Memory.String())
+	}
+}
+
+// deploymentPatch is the strategic-merge patch for one Deployment. kubectl
+// patch takes a single document, so each Deployment gets its own file.
+type deploymentPatch struct {
+	Workload workloadRef
+	Data     []byte
+}
+
+// fileName is where the patch goes inside --patch-dir
+func (p deploymentPatch) fileName() string {
+	return fmt.Sprintf("%s_%s.yaml", p.Workload.Namespace, p.Workload.Name)
+}
+
+// buildPatches renders one strategic-merge patch per Deployment, each usable
+// with kubectl patch deployment <name> --patch-file <file>
+func buildPatches(recs []recommendation) ([]deploymentPatch, error) {
+	containers := make(map[workloadRef][]interface{})
+	var order []workloadRef
+	for _, r := range recs {
+		if r.Key.Workload.Kind != "Deployment" {
+			continue
+		}
+		if _, ok := containers[r.Key.Workload]; !ok {
+			order = append(order, r.Key.Workload)
+		}
+		containers[r.Key.Workload] = append(containers[r.Key.Workload], map[string]interface{}{
+			"name": r.Key.Container,
+			"resources": map[string]interface{}{
+				"requests": map[string]string{
+					"cpu":    r.CPU.String(),
+					"memory": r.Memory.String(),
+				},
+			},
+		})
+	}
+
+	var patches []deploymentPatch
+	for _, workload := range order {
+		patch := map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]string{
+				"name":      workload.Name,
+				"namespace": workload.Namespace,
+			},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": containers[workload],
+					},
+				},
+			},
+		}
+		data, err := yaml.Marshal(patch)
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, deploymentPatch{Workload: workload, Data: data})
+	}
+	return patches, nil
+}
+
+func main() {
+	clientset, metricsClient := createClients()
+	// Ctrl-C or SIGTERM ends the collection window early; the report covers
+	// Pods and ReplicaSets come from the cache; only usage is polled
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, time.Minute*10,
+		informers.WithNamespace(*namespace))
+	factory.Core().V1().Pods().Informer()
+	factory.Apps().V1().ReplicaSets().Informer()
+
+	defer factory.Shutdown()
+
+	// the usage collected until then
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	factory.Start(ctx.Done())
+
+	fmt.Println("Waiting for cache sync...")
+	factory.WaitForCacheSync(ctx.Done())
+	fmt.Println("Cache sync completed!")
+
+	a := &analyzer{factory: factory, buffers: make(map[containerKey]*ringBuffer)}
+
+	// Poll metrics.k8s.io until the collection window ends
+	window, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	fmt.Printf("Collecting usage every %v for %v...\n", *interval, *duration)
+	for window.Err() == nil {
+		if err := a.collect(window, metricsClient); err != nil && window.Err() == nil {
+			log.Printf("Failed to fetch pod metrics: %v", err)
+		}
+		select {
+		case <-window.Done():
+		case <-ticker.C:
+		}
+	}
+	if ctx.Err() != nil {
+		fmt.Println("Interrupted, reporting on the usage collected so far")
+	}
+
+	recs := a.recommend()
+	sink, err := reportsink.Open(*sinkSpec)
+	if err != nil {
+		log.Fatalf("Failed to open report sink: %v", err)
+	}
+	var body bytes.Buffer
+	writeReport(&body, recs)
+	// Delivered even after Ctrl-C, so it does not use the cancelled ctx
+	err = sink.Write(context.Background(), reportsink.Report{Name: "capacity", GeneratedAt: time.Now(), Format: "txt", Body: body.Bytes()})
+	if err != nil {
+		log.Fatalf("Failed to deliver report: %v", err)
+	}
+
+	patches, err := buildPatches(recs)
+	if err != nil {
+		log.Fatalf("Failed to render patches: %v", err)
+	}
+	if *patchDir == "" {
+		for _, p := range patches {
+			fmt.Printf("\n# %s\n%s", p.fileName(), p.Data)
+		}
+		return
+	}
+	if err := os.MkdirAll(*patchDir, 0o755); err != nil {
+		log.Fatalf("Failed to create patch directory: %v", err)
+	}
+	for _, p := range patches {
+		path := filepath.Join(*patchDir, p.fileName())
+		if err := os.WriteFile(path, p.Data, 0o644); err != nil {
+			log.Fatalf("Failed to write patch file: %v", err)
+		}
+		fmt.Printf("Patch for %s/%s written to %s\n", p.Workload.Namespace, p.Workload.Name, path)
+	}
+}
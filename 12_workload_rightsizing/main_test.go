@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func controllerRef(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func newPod(name string, owners []metav1.OwnerReference, cpu string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", OwnerReferences: owners},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse(cpu),
+			}},
+		}}},
+	}
+}
+
+// startAnalyzer caches pods and ReplicaSets from a fake clientset
+func startAnalyzer(t *testing.T, objects ...runtime.Object) *analyzer {
+	t.Helper()
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(objects...), 0)
+	factory.Core().V1().Pods().Informer()
+	factory.Apps().V1().ReplicaSets().Informer()
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	return &analyzer{factory: factory, buffers: map[containerKey]*ringBuffer{}}
+}
+
+func TestResolveWorkload(t *testing.T) {
+	a := startAnalyzer(t,
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "web-5d9c", Namespace: "default", OwnerReferences: controllerRef("Deployment", "web"),
+		}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "bare-rs", Namespace: "default"}},
+	)
+
+	tests := []struct {
+		pod  *corev1.Pod
+		want workloadRef
+	}{
+		{newPod("web-5d9c-x1", controllerRef("ReplicaSet", "web-5d9c"), "100m"), workloadRef{"Deployment", "default", "web"}},
+		{newPod("bare-rs-x1", controllerRef("ReplicaSet", "bare-rs"), "100m"), workloadRef{"ReplicaSet", "default", "bare-rs"}},
+		{newPod("db-0", controllerRef("StatefulSet", "db"), "100m"), workloadRef{"StatefulSet", "default", "db"}},
+		{newPod("debug", nil, "100m"), workloadRef{"Pod", "default", "debug"}},
+		// The ReplicaSet is not cached (yet): fall back to the direct owner
+		{newPod("gone-x1", controllerRef("ReplicaSet", "gone"), "100m"), workloadRef{"ReplicaSet", "default", "gone"}},
+	}
+	for _, tt := range tests {
+		if got := a.resolveWorkload(tt.pod); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.pod.Name, got, tt.want)
+		}
+	}
+}
+
+func TestCurrentRequests(t *testing.T) {
+	a := startAnalyzer(t,
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "web-5d9c", Namespace: "default", OwnerReferences: controllerRef("Deployment", "web"),
+		}},
+		newPod("web-5d9c-x1", controllerRef("ReplicaSet", "web-5d9c"), "250m"),
+		newPod("debug", nil, "50m"),
+	)
+
+	cpu, mem := a.currentRequests(containerKey{Workload: workloadRef{"Deployment", "default", "web"}, Container: "app"})
+	if cpu == nil || cpu.String() != "250m" {
+		t.Errorf("cpu request = %v, want 250m", cpu)
+	}
+	if mem != nil {
+		t.Errorf("memory request = %v, want none", mem)
+	}
+	if cpu, _ := a.currentRequests(containerKey{Workload: workloadRef{"Deployment", "default", "api"}, Container: "app"}); cpu != nil {
+		t.Errorf("unknown workload returned %v", cpu)
+	}
+}
+
+func TestPercentileOf(t *testing.T) {
+	values := []int64{50, 10, 40, 20, 30, 60, 70, 80, 90, 100}
+	tests := map[float64]int64{0: 10, 50: 50, 90: 90, 95: 100, 100: 100}
+	for p, want := range tests {
+		if got := percentileOf(values, p); got != want {
+			t.Errorf("p%v = %d, want %d", p, got, want)
+		}
+	}
+	if got := percentileOf(nil, 90); got != 0 {
+		t.Errorf("empty input = %d, want 0", got)
+	}
+}
+
+func TestRingBufferKeepsLastSamples(t *testing.T) {
+	r := newRingBuffer(3)
+	for i := int64(1); i <= 5; i++ {
+		r.add(sample{cpuMilli: i})
+	}
+	sum := int64(0)
+	for _, s := range r.values() {
+		sum += s.cpuMilli
+	}
+	if len(r.values()) != 3 || sum != 3+4+5 {
+		t.Errorf("values = %v, want the last three samples", r.values())
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	current := resource.MustParse("100m")
+	var out bytes.Buffer
+	writeReport(&out, []recommendation{{
+		Key: containerKey{
+			Workload:  workloadRef{Namespace: "default", Kind: "Deployment", Name: "web"},
+			Container: "app",
+		},
+		Samples:    12,
+		CurrentCPU: &current,
+		CPU:        resource.MustParse("20m"),
+		Memory:     resource.MustParse("32Mi"),
+	}})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "WORKLOAD") {
+		t.Fatalf("got %q", out.String())
+	}
+	if got := strings.Fields(lines[1]); strings.Join(got, " ") != "default/Deployment/web app 12 100m 20m <none> 32Mi" {
+		t.Errorf("row = %q", got)
+	}
+}
+
+func TestRecommendFloorsMemory(t *testing.T) {
+	a := startAnalyzer(t)
+	// A container that never reported memory
+	buf := newRingBuffer(3)
+	buf.add(sample{cpuMilli: 5})
+	a.buffers[containerKey{Workload: workloadRef{"Deployment", "default", "web"}, Container: "app"}] = buf
+
+	recs := a.recommend()
+	if len(recs) != 1 || recs[0].Memory.String() != "16Mi" {
+		t.Errorf("recommendations = %+v, want a 16Mi memory floor", recs)
+	}
+}
+
+func TestBuildPatchesOnePerDeployment(t *testing.T) {
+	rec := func(kind, name, container string) recommendation {
+		return recommendation{
+			Key:    containerKey{Workload: workloadRef{kind, "default", name}, Container: container},
+			CPU:    resource.MustParse("20m"),
+			Memory: resource.MustParse("32Mi"),
+		}
+	}
+	patches, err := buildPatches([]recommendation{
+		rec("Deployment", "web", "app"),
+		rec("Deployment", "web", "sidecar"),
+		rec("StatefulSet", "db", "postgres"),
+		rec("Deployment", "api", "app"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// StatefulSets are reported but not patched
+	if len(patches) != 2 || patches[0].fileName() != "default_web.yaml" || patches[1].fileName() != "default_api.yaml" {
+		t.Fatalf("patches = %+v", patches)
+	}
+	// kubectl patch --patch-file reads a single document
+	web := string(patches[0].Data)
+	if strings.Contains(web, "---") || !strings.Contains(web, "name: app") || !strings.Contains(web, "name: sidecar") {
+		t.Errorf("web patch:\n%s", web)
+	}
+}
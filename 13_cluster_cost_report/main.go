@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/reportsink"
+)
+
+var (
+	cpuPrice    = flag.Float64("cpu-price", 0.031611, "price per vCPU-hour")
+	memoryPrice = flag.Float64("memory-price", 0.004237, "price per GiB-hour")
+	groupBy     = flag.String("group-by", "namespace", "group costs by \"namespace\" or \"label:<key>\" (e.g. label:team)")
+	format      = flag.String("format", "json", "report format: json or csv")
+	sinkSpec    = flag.String("sink", "stdout", "where reports go: stdout, file://<dir> or s3://<bucket>/<prefix>")
+	interval    = flag.Duration("interval", 0, "produce a report every interval (0 runs once)")
+)
+
+// hoursPerMonth is the average number of hours in a month used by cloud billing
+const hoursPerMonth = 730
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "13-cluster-cost-report")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// costModel prices requested resources per hour
+type costModel struct {
+	CPUPerHour    float64
+	MemoryPerHour float64 // per GiB
+}
+
+// groupCost is one row of the cost breakdown
+type groupCost struct {
+	Group       string  `json:"group"`
+	Pods        int     `json:"pods"`
+	CPUCores    float64 `json:"cpuCores"`
+	MemoryGiB   float64 `json:"memoryGiB"`
+	HourlyCost  float64 `json:"hourlyCost"`
+	MonthlyCost float64 `json:"monthlyCost"`
+}
+
+// costReport is the full breakdown produced on every run
+type costReport struct {
+	GeneratedAt time.Time   `json:"generatedAt"`
+	GroupBy     string      `json:"groupBy"`
+	Groups      []groupCost `json:"groups"`
+	TotalHourly float64     `json:"totalHourly"`
+	TotalMonth  float64     `json:"totalMonthly"`
+}
+
+// groupKey returns the group a pod belongs to according to --group-by
+func groupKey(pod *corev1.Pod, by string) string {
+	if key, ok := strings.CutPrefix(by, "label:"); ok {
+		if value, ok := pod.Labels[key]; ok {
+			return value
+		}
+		return "<unlabeled>"
+	}
+	return pod.Namespace
+}
+
+// podRequests sums CPU (cores) and memory (GiB) requests of a pod's containers
+func podRequests(pod *corev1.Pod) (float64, float64) {
+	var cpu, mem float64
+	for _, c := range pod.Spec.Containers {
+		cpu += float64(c.Resources.Requests.Cpu().MilliValue()) / 1000
+		mem += float64(c.Resources.Requests.Memory().Value()) / (1 << 30)
+	}
+	return cpu, mem
+}
+
+// buildReport applies the cost model to all cached pods that hold resources
+func buildReport(pods []*corev1.Pod, model costModel, by string) costReport {
+	groups := make(map[string]*groupCost)
+	for _, pod := range pods {
+		// Finished pods no longer reserve capacity on the node
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		key := groupKey(pod, by)
+		g, ok := groups[key]
+		if !ok {
+			g = &groupCost{Group: key}
+			groups[key] = g
+		}
+		cpu, mem := podRequests(pod)
+		g.Pods++
+		g.CPUCores += cpu
+		g.MemoryGiB += mem
+	}
+
+	report := costReport{GeneratedAt: time.Now().UTC(), GroupBy: by}
+	for _, g := range groups {
+		g.HourlyCost = g.CPUCores*model.CPUPerHour + g.MemoryGiB*model.MemoryPerHour
+		g.MonthlyCost = g.HourlyCost * hoursPerMonth
+		report.TotalHourly += g.HourlyCost
+		report.Groups = append(report.Groups, *g)
+	}
+	report.TotalMonth = report.TotalHourly * hoursPerMonth
+	// Most expensive groups first
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].MonthlyCost > report.Groups[j].MonthlyCost
+	})
+	return report
+}
+
+// writeReport renders the report as JSON or CSV
+func writeReport(w io.Writer, report costReport, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"generated_at", "group", "pods", "cpu_cores", "memory_gib", "hourly_cost", "monthly_cost"}); err != nil {
+			return err
+		}
+		ts := report.GeneratedAt.Format(time.RFC3339)
+		for _, g := range report.Groups {
+			if err := cw.Write([]string{
+				ts,
+				g.Group,
+				strconv.Itoa(g.Pods),
+				strconv.FormatFloat(g.CPUCores, 'f', 3, 64),
+				strconv.FormatFloat(g.MemoryGiB, 'f', 3, 64),
+				strconv.FormatFloat(g.HourlyCost, 'f', 4, 64),
+				strconv.FormatFloat(g.MonthlyCost, 'f', 2, 64),
+			}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+
+	// Single factory; the report only reads from the pod lister
+	factory := informers.NewSharedInformerFactory(clientset, time.Minute*10)
+	podLister := factory.Core().V1().Pods().Lister()
+	// Deferred first so it runs last, once stop has cancelled the informers
+	defer factory.Shutdown()
+
+	// Stop on Ctrl-C or SIGTERM, between reports
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	factory.Start(ctx.Done())
+
+	fmt.Fprintln(os.Stderr, "Waiting for cache sync...")
+	factory.WaitForCacheSync(ctx.Done())
+	fmt.Fprintln(os.Stderr, "Cache sync completed!")
+
+	model := costModel{CPUPerHour: *cpuPrice, MemoryPerHour: *memoryPrice}
+
+	sink, err := reportsink.Open(*sinkSpec)
+	if err != nil {
+		log.Fatalf("Failed to open report sink: %v", err)
+	}
+
+	for {
+		pods, err := podLister.List(labels.Everything())
+		if err != nil {
+			log.Fatalf("Failed to list pods: %v", err)
+		}
+		report := buildReport(pods, model, *groupBy)
+		var body bytes.Buffer
+		if err := writeReport(&body, report, *format); err != nil {
+			log.Fatalf("Failed to render report: %v", err)
+		}
+		// A sink that is briefly unavailable should not end a scheduled run
+		err = sink.Write(ctx, reportsink.Report{Name: "cost", GeneratedAt: report.GeneratedAt, Format: *format, Body: body.Bytes()})
+		if err != nil {
+			log.Printf("Failed to deliver report: %v", err)
+		}
+
+		// Run once unless a reporting interval was requested
+		if *interval == 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stderr, "Shutting down")
+			return
+		case <-time.After(*interval):
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newPod(namespace, name, team, cpu, memory string, phase corev1.PodPhase) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			}},
+		}}},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+	if team != "" {
+		pod.Labels = map[string]string{"team": team}
+	}
+	return pod
+}
+
+// cachedPods lists seeded pods through an informer, as main does
+func cachedPods(t *testing.T, pods ...*corev1.Pod) []*corev1.Pod {
+	t.Helper()
+	clientset := fake.NewSimpleClientset()
+	for _, pod := range pods {
+		clientset.Tracker().Add(pod)
+	}
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	lister := factory.Core().V1().Pods().Lister()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	cached, err := lister.List(labels.Everything())
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	return cached
+}
+
+func TestBuildReportByNamespace(t *testing.T) {
+	pods := cachedPods(t,
+		newPod("shop", "web", "checkout", "2", "4Gi", corev1.PodRunning),
+		newPod("shop", "worker", "", "1", "2Gi", corev1.PodPending),
+		newPod("blog", "web", "content", "500m", "1Gi", corev1.PodRunning),
+		// Finished pods do not reserve capacity
+		newPod("blog", "migrate", "content", "4", "8Gi", corev1.PodSucceeded),
+	)
+	report := buildReport(pods, costModel{CPUPerHour: 0.04, MemoryPerHour: 0.005}, "namespace")
+
+	if len(report.Groups) != 2 || report.Groups[0].Group != "shop" {
+		t.Fatalf("groups = %+v, want shop first", report.Groups)
+	}
+	shop := report.Groups[0]
+	if shop.Pods != 2 || shop.CPUCores != 3 || shop.MemoryGiB != 6 {
+		t.Errorf("shop = %+v", shop)
+	}
+	if want := 3*0.04 + 6*0.005; shop.HourlyCost != want {
+		t.Errorf("shop hourly cost = %v, want %v", shop.HourlyCost, want)
+	}
+	if report.TotalMonth != report.TotalHourly*hoursPerMonth {
+		t.Errorf("monthly total %v does not match hourly %v", report.TotalMonth, report.TotalHourly)
+	}
+}
+
+func TestGroupKeyByLabel(t *testing.T) {
+	labeled := newPod("shop", "web", "checkout", "1", "1Gi", corev1.PodRunning)
+	unlabeled := newPod("shop", "worker", "", "1", "1Gi", corev1.PodRunning)
+
+	if got := groupKey(labeled, "label:team"); got != "checkout" {
+		t.Errorf("labeled pod grouped as %q", got)
+	}
+	if got := groupKey(unlabeled, "label:team"); got != "<unlabeled>" {
+		t.Errorf("unlabeled pod grouped as %q", got)
+	}
+	if got := groupKey(labeled, "namespace"); got != "shop" {
+		t.Errorf("namespace grouping gave %q", got)
+	}
+}
+
+func TestWriteReportCSV(t *testing.T) {
+	report := buildReport([]*corev1.Pod{
+		newPod("shop", "web", "", "1", "1Gi", corev1.PodRunning),
+	}, costModel{CPUPerHour: 1, MemoryPerHour: 1}, "namespace")
+
+	var buf bytes.Buffer
+	if err := writeReport(&buf, report, "csv"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "generated_at,group,pods") {
+		t.Fatalf("unexpected CSV:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[1], ",shop,1,1.000,1.000,2.0000,") {
+		t.Errorf("unexpected row: %s", lines[1])
+	}
+	if err := writeReport(&buf, report, "xml"); err == nil {
+		t.Error("unknown format should fail")
+	}
+}
@@ -0,0 +1,72 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil/envtest"
+)
+
+func TestServerSideApplyAgainstAPIServer(t *testing.T) {
+	_, clientset := envtest.Start(t)
+	ctx := context.TODO()
+
+	ns := newNamespace("team-a", map[string]string{"onboarding.k8s-lab.io/enabled": "true"})
+	if _, err := clientset.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create namespace: %v", err)
+	}
+	c := startController(t, clientset)
+
+	if err := c.reconcile(ctx, "team-a"); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	quota, err := clientset.CoreV1().ResourceQuotas("team-a").Get(ctx, baselineName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("ResourceQuota not applied: %v", err)
+	}
+	managers := map[string]bool{}
+	for _, entry := range quota.ManagedFields {
+		managers[entry.Manager] = true
+	}
+	if !managers[fieldManager] {
+		t.Errorf("managedFields managers = %v, want %s", managers, fieldManager)
+	}
+
+	// A real API server skips the write when an apply changes nothing, so the
+	// resourceVersion stays put. The fake clientset cannot show this.
+	if err := c.reconcile(ctx, "team-a"); err != nil {
+		t.Fatalf("second reconcile: %v", err)
+	}
+	again, err := clientset.CoreV1().ResourceQuotas("team-a").Get(ctx, baselineName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if again.ResourceVersion != quota.ResourceVersion {
+		t.Errorf("no-op apply bumped resourceVersion %s -> %s", quota.ResourceVersion, again.ResourceVersion)
+	}
+
+	// The LimitRange is enforced by the LimitRanger admission plugin, which the
+	// fake clientset does not run: a pod without resources gets the defaults
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "nginx:1.21"}},
+		},
+	}
+	created, err := clientset.CoreV1().Pods("team-a").Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("create pod: %v", err)
+	}
+	resources := created.Spec.Containers[0].Resources
+	if got := resources.Limits.Cpu().String(); got != "500m" {
+		t.Errorf("defaulted CPU limit = %s, want 500m", got)
+	}
+	if got := resources.Requests.Memory().String(); got != "128Mi" {
+		t.Errorf("defaulted memory request = %s, want 128Mi", got)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	networkingv1ac "k8s.io/client-go/applyconfigurations/networking/v1"
+	rbacv1ac "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/healthz"
+)
+
+const (
+	// controllerName is the managed-by label value of every object the
+	// controller provisions
+	controllerName = "namespace-onboarding"
+	// managedByLabel marks every object the controller provisions
+	managedByLabel = "app.kubernetes.io/managed-by"
+	// baselineName is used for all baseline objects inside a namespace
+	baselineName = "onboarding-baseline"
+)
+
+// fieldManager identifies this controller in managedFields for server-side
+// apply. A label value cannot hold its "/", so the label uses controllerName.
+var fieldManager = clientid.FieldManager("14-namespace-onboarding-controller")
+
+var (
+	onboardSelector = flag.String("selector", "onboarding.k8s-lab.io/enabled=true", "label selector for namespaces to onboard")
+	adminGroup      = flag.String("admin-group", "team-admins", "group bound to the edit ClusterRole in onboarded namespaces")
+	workers         = flag.Int("workers", 2, "number of reconcile workers")
+	healthAddr      = flag.String("health-addr", ":8081", "address serving /healthz and /readyz (empty disables)")
+	maxQueueDepth   = flag.Int("max-queue-depth", 100, "/readyz fails while more keys than this are queued")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "14-namespace-onboarding-controller")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// OnboardingController provisions a standard baseline into labeled namespaces
+type OnboardingController struct {
+	clientset kubernetes.Interface
+	factory   informers.SharedInformerFactory // namespaces matching the selector
+	managed   informers.SharedInformerFactory // baseline objects carrying managedByLabel
+	selector  labels.Selector
+	queue     workqueue.TypedRateLimitingInterface[string]
+}
+
+func NewOnboardingController(clientset kubernetes.Interface, selector labels.Selector) *OnboardingController {
+	c := &OnboardingController{
+		clientset: clientset,
+		selector:  selector,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "namespace-onboarding"},
+		),
+	}
+
+	// Only namespaces that opted in are cached
+	c.factory = informers.NewSharedInformerFactoryWithOptions(clientset, time.Minute*5,
+		informers.WithTweakListOptions(func(lo *metav1.ListOptions) {
+			lo.LabelSelector = selector.String()
+		}))
+	// Only objects we own are cached, so the controller does not hold every policy in the cluster
+	c.managed = informers.NewSharedInformerFactoryWithOptions(clientset, time.Minute*5,
+		informers.WithTweakListOptions(func(lo *metav1.ListOptions) {
+			lo.LabelSelector = managedByLabel + "=" + controllerName
+		}))
+
+	// Namespace events enqueue the namespace itself
+	c.factory.Core().V1().Namespaces().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			ns := obj.(*corev1.Namespace)
+			c.queue.Add(ns.Name)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			ns := newObj.(*corev1.Namespace)
+			c.queue.Add(ns.Name)
+		},
+	})
+
+	// Any change (or deletion) of a baseline object is drift: re-apply its namespace
+	driftHandler := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) { c.enqueueOwnerNamespace(newObj) },
+		DeleteFunc: c.enqueueOwnerNamespace,
+	}
+	c.managed.Networking().V1().NetworkPolicies().Informer().AddEventHandler(driftHandler)
+	c.managed.Core().V1().ResourceQuotas().Informer().AddEventHandler(driftHandler)
+	c.managed.Core().V1().LimitRanges().Informer().AddEventHandler(driftHandler)
+	c.managed.Rbac().V1().RoleBindings().Informer().AddEventHandler(driftHandler)
+
+	return c
+}
+
+// enqueueOwnerNamespace enqueues the namespace of a baseline object
+func (c *OnboardingController) enqueueOwnerNamespace(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(namespace)
+}
+
+// Run starts the informers and workers and blocks until ctx is cancelled
+func (c *OnboardingController) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+
+	c.factory.Start(ctx.Done())
+	c.managed.Start(ctx.Done())
+
+	fmt.Println("Waiting for cache sync...")
+	c.factory.WaitForCacheSync(ctx.Done())
+	c.managed.WaitForCacheSync(ctx.Done())
+	fmt.Println("Cache sync completed!")
+
+	// The drain below still works through the queue after ctx is cancelled,
+	// so the workers' requests keep ctx's values but not its cancellation
+	work := context.WithoutCancel(ctx)
+	for i := 0; i < workers; i++ {
+		go wait.Until(func() { c.runWorker(work) }, time.Second, ctx.Done())
+	}
+	<-ctx.Done()
+
+	// Let the workers finish what is queued, then stop the informers
+	fmt.Println("Shutting down, draining the workqueue...")
+	c.queue.ShutDownWithDrain()
+	c.factory.Shutdown()
+	c.managed.Shutdown()
+	fmt.Println("Exited cleanly")
+}
+
+func (c *OnboardingController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *OnboardingController) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.reconcile(ctx, key); err != nil {
+		fmt.Printf("[Onboarding] Failed to reconcile %s, requeuing: %v\n", key, err)
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+// reconcile applies the full baseline to a namespace; SSA makes this idempotent
+func (c *OnboardingController) reconcile(ctx context.Context, name string) error {
+	ns, err := c.factory.Core().V1().Namespaces().Lister().Get(name)
+	if apierrors.IsNotFound(err) {
+		// Namespace was deleted or no longer matches the selector
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !ns.DeletionTimestamp.IsZero() || !c.selector.Matches(labels.Set(ns.Labels)) {
+		return nil
+	}
+
+	applyOpts := metav1.ApplyOptions{FieldManager: fieldManager, Force: true}
+	objLabels := map[string]string{managedByLabel: controllerName}
+
+	// Default-deny ingress from other namespaces, allow traffic within the namespace
+	netpol := networkingv1ac.NetworkPolicy(baselineName, name).
+		WithLabels(objLabels).
+		WithSpec(networkingv1ac.NetworkPolicySpec().
+			WithPodSelector(metav1ac.LabelSelector()).
+			WithPolicyTypes(networkingv1.PolicyTypeIngress).
+			WithIngress(networkingv1ac.NetworkPolicyIngressRule().
+				WithFrom(networkingv1ac.NetworkPolicyPeer().
+					WithPodSelector(metav1ac.LabelSelector()))))
+	if _, err := c.clientset.NetworkingV1().NetworkPolicies(name).Apply(ctx, netpol, applyOpts); err != nil {
+		return fmt.Errorf("apply NetworkPolicy: %w", err)
+	}
+
+	quota := corev1ac.ResourceQuota(baselineName, name).
+		WithLabels(objLabels).
+		WithSpec(corev1ac.ResourceQuotaSpec().
+			WithHard(corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("4"),
+				corev1.ResourceRequestsMemory: resource.MustParse("8Gi"),
+				corev1.ResourcePods:           resource.MustParse("50"),
+			}))
+	if _, err := c.clientset.CoreV1().ResourceQuotas(name).Apply(ctx, quota, applyOpts); err != nil {
+		return fmt.Errorf("apply ResourceQuota: %w", err)
+	}
+
+	limitRange := corev1ac.LimitRange(baselineName, name).
+		WithLabels(objLabels).
+		WithSpec(corev1ac.LimitRangeSpec().
+			WithLimits(corev1ac.LimitRangeItem().
+				WithType(corev1.LimitTypeContainer).
+				WithDefaultRequest(corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				}).
+				WithDefault(corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+					corev1.ResourceMemory: resource.MustParse("512Mi"),
+				})))
+	if _, err := c.clientset.CoreV1().LimitRanges(name).Apply(ctx, limitRange, applyOpts); err != nil {
+		return fmt.Errorf("apply LimitRange: %w", err)
+	}
+
+	roleBinding := rbacv1ac.RoleBinding(baselineName, name).
+		WithLabels(objLabels).
+		WithRoleRef(rbacv1ac.RoleRef().
+			WithAPIGroup(rbacv1.GroupName).
+			WithKind("ClusterRole").
+			WithName("edit")).
+		WithSubjects(rbacv1ac.Subject().
+			WithAPIGroup(rbacv1.GroupName).
+			WithKind(rbacv1.GroupKind).
+			WithName(*adminGroup))
+	if _, err := c.clientset.RbacV1().RoleBindings(name).Apply(ctx, roleBinding, applyOpts); err != nil {
+		return fmt.Errorf("apply RoleBinding: %w", err)
+	}
+
+	fmt.Printf("[Onboarding] Baseline applied to namespace %s\n", name)
+	return nil
+}
+
+func main() {
+	clientset := createClientSet()
+
+	selector, err := labels.Parse(*onboardSelector)
+	if err != nil {
+		log.Fatalf("Invalid selector %q: %v", *onboardSelector, err)
+	}
+
+	controller := NewOnboardingController(clientset, selector)
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Ready once both factories have synced, and while the queue keeps up
+	health := healthz.New()
+	health.AddReadyCheck("namespace-informers", healthz.FactorySynced(controller.factory))
+	health.AddReadyCheck("managed-informers", healthz.FactorySynced(controller.managed))
+	health.AddReadyCheck("workqueue", healthz.QueueDepth(controller.queue, *maxQueueDepth))
+	if *healthAddr != "" {
+		go func() {
+			if err := health.Serve(ctx, *healthAddr); err != nil {
+				log.Fatalf("Health server failed: %v", err)
+			}
+		}()
+	}
+
+	controller.Run(ctx, *workers)
+}
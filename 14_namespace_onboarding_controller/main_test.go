@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newNamespace(name string, nsLabels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nsLabels}}
+}
+
+// startController syncs the controller's caches without starting workers, so
+// tests drive reconcile and the queue directly
+func startController(t *testing.T, clientset kubernetes.Interface) *OnboardingController {
+	t.Helper()
+	selector, err := labels.Parse(*onboardSelector)
+	if err != nil {
+		t.Fatalf("parse selector: %v", err)
+	}
+	c := NewOnboardingController(clientset, selector)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		c.queue.ShutDown()
+	})
+	c.factory.Start(stopCh)
+	c.managed.Start(stopCh)
+	c.factory.WaitForCacheSync(stopCh)
+	c.managed.WaitForCacheSync(stopCh)
+	return c
+}
+
+func TestOnlySelectedNamespacesAreCached(t *testing.T) {
+	// NewClientset (unlike NewSimpleClientset) supports server-side apply
+	clientset := fake.NewClientset(
+		newNamespace("team-a", map[string]string{"onboarding.k8s-lab.io/enabled": "true"}),
+		newNamespace("team-b", nil),
+	)
+	c := startController(t, clientset)
+
+	namespaces, _ := c.factory.Core().V1().Namespaces().Lister().List(labels.Everything())
+	if len(namespaces) != 1 || namespaces[0].Name != "team-a" {
+		t.Errorf("cached namespaces = %v, want only team-a", namespaces)
+	}
+	// The add handler queued the opted-in namespace
+	if c.queue.Len() != 1 {
+		t.Errorf("queue length = %d, want 1", c.queue.Len())
+	}
+}
+
+func TestReconcileAppliesBaseline(t *testing.T) {
+	clientset := fake.NewClientset(
+		newNamespace("team-a", map[string]string{"onboarding.k8s-lab.io/enabled": "true"}),
+	)
+	c := startController(t, clientset)
+	ctx := context.TODO()
+
+	if err := c.reconcile(ctx, "team-a"); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	// Reconciling again is a no-op thanks to server-side apply
+	if err := c.reconcile(ctx, "team-a"); err != nil {
+		t.Fatalf("second reconcile: %v", err)
+	}
+
+	netpol, err := clientset.NetworkingV1().NetworkPolicies("team-a").Get(ctx, baselineName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("NetworkPolicy not applied: %v", err)
+	}
+	if netpol.Labels[managedByLabel] != controllerName {
+		t.Errorf("NetworkPolicy labels = %v", netpol.Labels)
+	}
+	if len(netpol.Spec.PolicyTypes) != 1 || netpol.Spec.PolicyTypes[0] != networkingv1.PolicyTypeIngress {
+		t.Errorf("policy types = %v, want [Ingress]", netpol.Spec.PolicyTypes)
+	}
+	if _, err := clientset.CoreV1().ResourceQuotas("team-a").Get(ctx, baselineName, metav1.GetOptions{}); err != nil {
+		t.Errorf("ResourceQuota not applied: %v", err)
+	}
+	if _, err := clientset.CoreV1().LimitRanges("team-a").Get(ctx, baselineName, metav1.GetOptions{}); err != nil {
+		t.Errorf("LimitRange not applied: %v", err)
+	}
+	binding, err := clientset.RbacV1().RoleBindings("team-a").Get(ctx, baselineName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("RoleBinding not applied: %v", err)
+	}
+	if binding.Subjects[0].Name != *adminGroup || binding.RoleRef.Name != "edit" {
+		t.Errorf("RoleBinding binds %s to %s", binding.Subjects[0].Name, binding.RoleRef.Name)
+	}
+}
+
+func TestReconcileSkipsUnselectedNamespace(t *testing.T) {
+	clientset := fake.NewClientset(newNamespace("team-b", nil))
+	c := startController(t, clientset)
+
+	// team-b is not in the cache, so reconcile treats it like a deleted namespace
+	if err := c.reconcile(context.TODO(), "team-b"); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "patch" {
+			t.Errorf("unexpected write: %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+}
+
+func TestEnqueueOwnerNamespace(t *testing.T) {
+	c := startController(t, fake.NewClientset())
+	quota := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: baselineName, Namespace: "team-a"}}
+
+	// Deletions may arrive as tombstones; both map to the owning namespace
+	c.enqueueOwnerNamespace(quota)
+	c.enqueueOwnerNamespace(cache.DeletedFinalStateUnknown{Key: "team-a/" + baselineName, Obj: quota})
+
+	if c.queue.Len() != 1 {
+		t.Fatalf("queue length = %d, want 1 (keys are deduplicated)", c.queue.Len())
+	}
+	if key, _ := c.queue.Get(); key != "team-a" {
+		t.Errorf("queued %q, want team-a", key)
+	}
+}
+
+func TestRunReturnsAfterStopAndShutsDownTheQueue(t *testing.T) {
+	clientset := fake.NewClientset(
+		newNamespace("team-a", map[string]string{"onboarding.k8s-lab.io/enabled": "true"}),
+	)
+	selector, _ := labels.Parse(*onboardSelector)
+	c := NewOnboardingController(clientset, selector)
+	runCtx, stop := context.WithCancel(context.Background())
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		c.Run(runCtx, 1)
+	}()
+
+	// Stop only once the worker has reconciled the queued namespace
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		_, err := clientset.CoreV1().ResourceQuotas("team-a").Get(ctx, baselineName, metav1.GetOptions{})
+		return err == nil, nil
+	})
+	if err != nil {
+		t.Fatal("team-a was never reconciled")
+	}
+	stop()
+
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after stop")
+	}
+	if !c.queue.ShuttingDown() {
+		t.Error("the workqueue was not shut down")
+	}
+}
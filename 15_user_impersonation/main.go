@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+// stringList is a repeatable string flag (--as-group a --as-group b)
+type stringList []string
+
+func (s *stringList) String() string     { return strings.Join(*s, ",") }
+func (s *stringList) Set(v string) error { *s = append(*s, v); return nil }
+
+var (
+	asUser   = flag.String("as", "jane", "username to impersonate")
+	asGroups stringList
+	asExtra  stringList
+)
+
+func init() {
+	flag.Var(&asGroups, "as-group", "group to impersonate (repeatable)")
+	flag.Var(&asExtra, "as-extra", "extra user info as key=value (repeatable)")
+}
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "15-user-impersonation")
+	return config
+}
+
+// impersonatedConfig copies the admin config and sets the identity that
+// client-go sends as Impersonate-* headers
+func impersonatedConfig(config *rest.Config, user string, groups, extras []string) (*rest.Config, error) {
+	// Copy so the original (admin) config stays untouched
+	impersonated := rest.CopyConfig(config)
+
+	extra := make(map[string][]string)
+	for _, kv := range extras {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --as-extra %q, expected key=value", kv)
+		}
+		extra[key] = append(extra[key], value)
+	}
+
+	// The authenticated user needs the "impersonate" verb on users/groups/userextras
+	impersonated.Impersonate = rest.ImpersonationConfig{
+		UserName: user,
+		Groups:   groups,
+		Extra:    extra,
+	}
+	return impersonated, nil
+}
+
+// report prints whether a request was allowed, forbidden or failed otherwise
+func report(action string, err error) {
+	switch {
+	case err == nil:
+		fmt.Printf("  [ALLOWED]   %s\n", action)
+	case apierrors.IsForbidden(err):
+		fmt.Printf("  [FORBIDDEN] %s\n              %v\n", action, err)
+	default:
+		fmt.Printf("  [ERROR]     %s: %v\n", action, err)
+	}
+}
+
+func main() {
+	config := createConfig()
+
+	impersonated, err := impersonatedConfig(config, *asUser, asGroups, asExtra)
+	if err != nil {
+		log.Fatalf("Failed to create impersonated clientset: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(impersonated)
+	if err != nil {
+		log.Fatalf("Failed to create impersonated clientset: %v", err)
+	}
+
+	// Ctrl-C or SIGTERM cancels the context, and with it any request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Ask the API server who it thinks we are after impersonation
+	review, err := clientset.AuthenticationV1().SelfSubjectReviews().Create(ctx,
+		&authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err != nil {
+		log.Fatalf("Impersonation failed (does your user have the impersonate verb?): %v", err)
+	}
+	fmt.Printf("Acting as user: %s\n", review.Status.UserInfo.Username)
+	fmt.Printf("Groups: %v\n", review.Status.UserInfo.Groups)
+	if len(review.Status.UserInfo.Extra) > 0 {
+		fmt.Printf("Extra: %v\n", review.Status.UserInfo.Extra)
+	}
+
+	fmt.Println("\nRequests made as the impersonated identity:")
+
+	// Typically allowed when the identity is bound to view/edit in default
+	_, err = clientset.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
+	report("list pods in namespace default", err)
+
+	// Typically forbidden for anyone but cluster admins
+	_, err = clientset.CoreV1().Secrets("kube-system").List(ctx, metav1.ListOptions{})
+	report("list secrets in namespace kube-system", err)
+
+	_, err = clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	report("list nodes (cluster-scoped)", err)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestImpersonatedConfig(t *testing.T) {
+	admin := &rest.Config{Host: "https://example.com", BearerToken: "admin-token"}
+	impersonated, err := impersonatedConfig(admin, "jane", []string{"dev", "qa"}, []string{"scopes=view", "scopes=edit", "team=web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := rest.ImpersonationConfig{
+		UserName: "jane",
+		Groups:   []string{"dev", "qa"},
+		Extra:    map[string][]string{"scopes": {"view", "edit"}, "team": {"web"}},
+	}
+	if !reflect.DeepEqual(impersonated.Impersonate, want) {
+		t.Errorf("Impersonate = %+v, want %+v", impersonated.Impersonate, want)
+	}
+	// The admin config is copied, not changed
+	if admin.Impersonate.UserName != "" || impersonated.BearerToken != "admin-token" {
+		t.Errorf("admin config changed or credentials lost: admin=%+v", admin.Impersonate)
+	}
+
+	if _, err := impersonatedConfig(admin, "jane", nil, []string{"no-equals"}); err == nil {
+		t.Error("expected an error for --as-extra without key=value")
+	}
+}
+
+func TestImpersonationHeadersAreSent(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind":"PodList","apiVersion":"v1","items":[]}`))
+	}))
+	defer server.Close()
+
+	impersonated, err := impersonatedConfig(&rest.Config{Host: server.URL}, "jane", []string{"dev", "qa"}, []string{"team=web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientset, err := kubernetes.NewForConfig(impersonated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clientset.CoreV1().Pods("default").List(context.TODO(), metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Get("Impersonate-User") != "jane" {
+		t.Errorf("Impersonate-User = %q, want jane", got.Get("Impersonate-User"))
+	}
+	if groups := got.Values("Impersonate-Group"); !slices.Equal(groups, []string{"dev", "qa"}) {
+		t.Errorf("Impersonate-Group = %v, want [dev qa]", groups)
+	}
+	if team := got.Get("Impersonate-Extra-Team"); team != "web" {
+		t.Errorf("Impersonate-Extra-Team = %q, want web", team)
+	}
+}
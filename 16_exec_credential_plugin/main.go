@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientauthv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	// Client flags
+	execCommand = flag.String("exec-command", "", "credential plugin to run instead of the kubeconfig user (e.g. aws, gke-gcloud-auth-plugin, or \"self\")")
+	execArgs    = flag.String("exec-args", "", "comma-separated arguments passed to --exec-command")
+	tokenFile   = flag.String("token-file", "", "token returned by the built-in plugin when --exec-command=self")
+	tokenTTL    = flag.Duration("token-ttl", 30*time.Second, "expiry of tokens issued by the built-in plugin")
+	duration    = flag.Duration("duration", 2*time.Minute, "how long to keep calling the API server")
+	callEvery   = flag.Duration("call-every", 10*time.Second, "interval between API calls")
+
+	// Plugin mode flag: the binary re-executes itself as an exec credential plugin
+	pluginMode = flag.Bool("plugin", false, "run as an exec credential plugin and print an ExecCredential")
+)
+
+// execOptions holds the --exec-command, --exec-args, --token-file and
+// --token-ttl flags
+type execOptions struct {
+	command   string
+	args      string
+	tokenFile string
+	tokenTTL  time.Duration
+}
+
+// buildConfig loads the kubeconfig and, if requested, swaps the user's
+// credentials for an exec plugin. Any kubeconfig user that already has an
+// "exec:" stanza (EKS, GKE, AKS, OIDC helpers) is used as-is.
+func buildConfig(kubeconfig string, opts execOptions) (*rest.Config, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build config: %w", err)
+	}
+	clientid.Configure(config, "16-exec-credential-plugin")
+	if opts.command == "" {
+		return config, nil
+	}
+
+	command, args := opts.command, []string{}
+	if opts.args != "" {
+		args = strings.Split(opts.args, ",")
+	}
+	// "self" uses this binary as a demo plugin so token refresh can be observed on any cluster
+	if command == "self" {
+		if opts.tokenFile == "" {
+			return nil, fmt.Errorf("--token-file is required with --exec-command=self")
+		}
+		command, err = os.Executable()
+		if err != nil {
+			return nil, err
+		}
+		args = []string{"--plugin", "--token-file", opts.tokenFile, "--token-ttl", opts.tokenTTL.String()}
+	}
+
+	// Exec credentials replace any static credentials from kubeconfig
+	config.BearerToken = ""
+	config.BearerTokenFile = ""
+	config.Username = ""
+	config.Password = ""
+	config.CertFile, config.KeyFile = "", ""
+	config.CertData, config.KeyData = nil, nil
+	config.AuthProvider = nil
+	config.ExecProvider = &clientcmdapi.ExecConfig{
+		Command:         command,
+		Args:            args,
+		APIVersion:      clientauthv1.SchemeGroupVersion.String(),
+		InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}
+	return config, nil
+}
+
+// describeAuth prints which credential mechanism the config will use
+func describeAuth(config *rest.Config) {
+	switch {
+	case config.ExecProvider != nil:
+		fmt.Printf("Authentication: exec plugin %q %v (apiVersion %s)\n",
+			config.ExecProvider.Command, config.ExecProvider.Args, config.ExecProvider.APIVersion)
+	case config.AuthProvider != nil:
+		fmt.Printf("Authentication: auth provider %q (deprecated, migrate to exec plugins)\n", config.AuthProvider.Name)
+	case config.BearerToken != "" || config.BearerTokenFile != "":
+		fmt.Println("Authentication: static bearer token")
+	case len(config.CertData) > 0 || config.CertFile != "":
+		fmt.Println("Authentication: client certificate")
+	default:
+		fmt.Println("Authentication: none/basic")
+	}
+}
+
+// runPlugin implements the exec credential protocol: read the token and print
+// an ExecCredential with a short expirationTimestamp. client-go caches the
+// credential and re-executes the plugin once it expires (or on a 401).
+func runPlugin() {
+	expiry, err := writeCredential(os.Stdout, *tokenFile, *tokenTTL, time.Now())
+	if err != nil {
+		log.Fatalf("plugin: %v", err)
+	}
+	// stdout carries the credential; stderr is passed through to the user
+	fmt.Fprintf(os.Stderr, "[plugin] issued token valid until %s\n", expiry.Format(time.TimeOnly))
+}
+
+// writeCredential writes an ExecCredential holding the token in tokenFile,
+// expiring ttl after now, and returns the expiry
+func writeCredential(w io.Writer, tokenFile string, ttl time.Duration, now time.Time) (time.Time, error) {
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read token: %w", err)
+	}
+	expiry := metav1.NewTime(now.Add(ttl))
+	cred := clientauthv1.ExecCredential{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clientauthv1.SchemeGroupVersion.String(),
+			Kind:       "ExecCredential",
+		},
+		Status: &clientauthv1.ExecCredentialStatus{
+			Token:               strings.TrimSpace(string(data)),
+			ExpirationTimestamp: &expiry,
+		},
+	}
+	if err := json.NewEncoder(w).Encode(cred); err != nil {
+		return time.Time{}, fmt.Errorf("failed to encode credential: %w", err)
+	}
+	return expiry.Time, nil
+}
+
+func main() {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+
+	if *pluginMode {
+		runPlugin()
+		return
+	}
+
+	config, err := buildConfig(*kubeconfig, execOptions{
+		command:   *execCommand,
+		args:      *execArgs,
+		tokenFile: *tokenFile,
+		tokenTTL:  *tokenTTL,
+	})
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	describeAuth(config)
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+
+	// Call the API periodically until --duration passes or Ctrl-C; with a short
+	// token TTL the plugin runs again whenever the cached credential expires
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+	for ctx.Err() == nil {
+		namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			fmt.Printf("%s API call failed: %v\n", time.Now().Format(time.TimeOnly), err)
+		} else {
+			fmt.Printf("%s API call succeeded: %d namespaces\n", time.Now().Format(time.TimeOnly), len(namespaces.Items))
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(*callEvery):
+		}
+	}
+}
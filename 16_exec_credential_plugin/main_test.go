@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	clientauthv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// writeKubeconfig writes a kubeconfig whose user has a static token and a
+// client certificate
+func writeKubeconfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	data := `apiVersion: v1
+kind: Config
+clusters:
+- name: kind
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: kind
+  context:
+    cluster: kind
+    user: admin
+current-context: kind
+users:
+- name: admin
+  user:
+    token: static-token
+    client-certificate-data: Y2VydA==
+    client-key-data: a2V5
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuildConfigKeepsKubeconfigUser(t *testing.T) {
+	config, err := buildConfig(writeKubeconfig(t), execOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ExecProvider != nil || config.BearerToken != "static-token" {
+		t.Errorf("without --exec-command the kubeconfig user should be used, got exec=%v token=%q", config.ExecProvider, config.BearerToken)
+	}
+}
+
+func TestBuildConfigSwapsCredentialsForPlugin(t *testing.T) {
+	config, err := buildConfig(writeKubeconfig(t), execOptions{command: "aws", args: "eks,get-token,--cluster-name,demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.BearerToken != "" || len(config.CertData) != 0 || len(config.KeyData) != 0 {
+		t.Errorf("static credentials left in place: token=%q cert=%q key=%q", config.BearerToken, config.CertData, config.KeyData)
+	}
+	want := &clientcmdapi.ExecConfig{
+		Command:         "aws",
+		Args:            []string{"eks", "get-token", "--cluster-name", "demo"},
+		APIVersion:      "client.authentication.k8s.io/v1",
+		InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}
+	got := config.ExecProvider
+	if got == nil || got.Command != want.Command || !slices.Equal(got.Args, want.Args) || got.APIVersion != want.APIVersion || got.InteractiveMode != want.InteractiveMode {
+		t.Errorf("ExecProvider = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildConfigSelfRunsThisBinary(t *testing.T) {
+	if _, err := buildConfig(writeKubeconfig(t), execOptions{command: "self"}); err == nil {
+		t.Error("expected an error for --exec-command=self without --token-file")
+	}
+
+	config, err := buildConfig(writeKubeconfig(t), execOptions{command: "self", tokenFile: "/tmp/token", tokenTTL: 30 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	self, _ := os.Executable()
+	wantArgs := []string{"--plugin", "--token-file", "/tmp/token", "--token-ttl", "30s"}
+	if config.ExecProvider.Command != self || !slices.Equal(config.ExecProvider.Args, wantArgs) {
+		t.Errorf("ExecProvider = %s %v, want %s %v", config.ExecProvider.Command, config.ExecProvider.Args, self, wantArgs)
+	}
+}
+
+func TestWriteCredential(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var out bytes.Buffer
+	expiry, err := writeCredential(&out, tokenFile, 30*time.Second, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !expiry.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("expiry = %v, want 30s after now", expiry)
+	}
+
+	var cred clientauthv1.ExecCredential
+	if err := json.Unmarshal(out.Bytes(), &cred); err != nil {
+		t.Fatalf("output is not an ExecCredential: %v\n%s", err, out.String())
+	}
+	if cred.APIVersion != "client.authentication.k8s.io/v1" || cred.Kind != "ExecCredential" {
+		t.Errorf("type = %s %s", cred.APIVersion, cred.Kind)
+	}
+	// The trailing newline of the file is not part of the token
+	if cred.Status == nil || cred.Status.Token != "s3cr3t" || !cred.Status.ExpirationTimestamp.Time.Equal(expiry) {
+		t.Errorf("status = %+v", cred.Status)
+	}
+
+	if _, err := writeCredential(&out, filepath.Join(t.TempDir(), "missing"), time.Second, now); err == nil {
+		t.Error("expected an error for a missing token file")
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/healthz"
+	"github.com/shamimice03/mastering-k8s-client-go/tombstone"
+)
+
+const (
+	// replicateToAnnotation on a source Secret lists target namespaces ("ns1,ns2" or "*")
+	replicateToAnnotation = "secret-sync.k8s-lab.io/replicate-to"
+	// sourceAnnotation on a copy points back at its source (namespace/name)
+	sourceAnnotation = "secret-sync.k8s-lab.io/source"
+	// hashAnnotation on a copy stores the hash of the data it was synced from
+	hashAnnotation = "secret-sync.k8s-lab.io/hash"
+	// managedLabel marks copies so they can be recognized without reading annotations
+	managedLabel = "secret-sync.k8s-lab.io/managed"
+
+	// sourceIndex indexes copies by their source key
+	sourceIndex = "source"
+)
+
+var (
+	workers       = flag.Int("workers", 2, "number of sync workers")
+	healthAddr    = flag.String("health-addr", ":8081", "address serving /healthz and /readyz (empty disables)")
+	maxQueueDepth = flag.Int("max-queue-depth", 100, "/readyz fails while more keys than this are queued")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "17-secret-syncer")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// secretSourceIndexFunc indexes replicated copies by the source they came from
+func secretSourceIndexFunc(obj interface{}) ([]string, error) {
+	secret := obj.(*corev1.Secret)
+	if source, ok := secret.Annotations[sourceAnnotation]; ok {
+		return []string{source}, nil
+	}
+	return nil, nil
+}
+
+// secretHash hashes type and data so copies are only rewritten when content changes
+func secretHash(secret *corev1.Secret) string {
+	h := sha256.New()
+	h.Write([]byte(secret.Type))
+	keys := make([]string, 0, len(secret.Data))
+	for k := range secret.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(secret.Data[k])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SecretSyncer replicates annotated Secrets into target namespaces
+type SecretSyncer struct {
+	clientset kubernetes.Interface
+	factory   informers.SharedInformerFactory
+	queue     workqueue.TypedRateLimitingInterface[string] // source secret keys
+}
+
+func NewSecretSyncer(clientset kubernetes.Interface) *SecretSyncer {
+	s := &SecretSyncer{
+		clientset: clientset,
+		factory:   informers.NewSharedInformerFactory(clientset, time.Minute*10),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "secret-syncer"},
+		),
+	}
+
+	secretInformer := s.factory.Core().V1().Secrets().Informer()
+	secretInformer.AddIndexers(cache.Indexers{sourceIndex: secretSourceIndexFunc})
+	secretInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    s.enqueueSecret,
+		UpdateFunc: func(oldObj, newObj interface{}) { s.enqueueSecret(newObj) },
+		DeleteFunc: s.enqueueSecret,
+	})
+
+	// New namespaces may be targets of "*" sources
+	s.factory.Core().V1().Namespaces().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { s.enqueueWildcardSources() },
+	})
+	return s
+}
+
+// enqueueSecret maps any secret event to the key of the source to reconcile
+func (s *SecretSyncer) enqueueSecret(obj interface{}) {
+	secret, ok := tombstone.Unwrap[*corev1.Secret](obj)
+	if !ok {
+		return
+	}
+	// A copy was touched: reconcile its source so the copy is restored or removed
+	if source, ok := secret.Annotations[sourceAnnotation]; ok {
+		s.queue.Add(source)
+		return
+	}
+	// Sources are enqueued on every change; removing the annotation also needs a
+	// sync so that the existing copies get cleaned up
+	key, err := cache.MetaNamespaceKeyFunc(secret)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	if _, ok := secret.Annotations[replicateToAnnotation]; ok {
+		s.queue.Add(key)
+		return
+	}
+	indexer := s.factory.Core().V1().Secrets().Informer().GetIndexer()
+	if copies, _ := indexer.ByIndex(sourceIndex, key); len(copies) > 0 {
+		s.queue.Add(key)
+	}
+}
+
+// enqueueWildcardSources enqueues every source replicated to all namespaces
+func (s *SecretSyncer) enqueueWildcardSources() {
+	secrets, err := s.factory.Core().V1().Secrets().Lister().List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, secret := range secrets {
+		if strings.TrimSpace(secret.Annotations[replicateToAnnotation]) == "*" {
+			s.queue.Add(secret.Namespace + "/" + secret.Name)
+		}
+	}
+}
+
+// Run starts the informers and workers and blocks until ctx is cancelled
+func (s *SecretSyncer) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+
+	s.factory.Start(ctx.Done())
+	fmt.Println("Waiting for cache sync...")
+	s.factory.WaitForCacheSync(ctx.Done())
+	fmt.Println("Cache sync completed!")
+
+	// The drain below still works through the queue after ctx is cancelled,
+	// so the workers' requests keep ctx's values but not its cancellation
+	work := context.WithoutCancel(ctx)
+	for i := 0; i < workers; i++ {
+		go wait.Until(func() { s.runWorker(work) }, time.Second, ctx.Done())
+	}
+	<-ctx.Done()
+
+	// Let the workers finish what is queued, then stop the informers
+	fmt.Println("Shutting down, draining the workqueue...")
+	s.queue.ShutDownWithDrain()
+	s.factory.Shutdown()
+	fmt.Println("Exited cleanly")
+}
+
+func (s *SecretSyncer) runWorker(ctx context.Context) {
+	for s.processNextItem(ctx) {
+	}
+}
+
+func (s *SecretSyncer) processNextItem(ctx context.Context) bool {
+	key, quit := s.queue.Get()
+	if quit {
+		return false
+	}
+	defer s.queue.Done(key)
+
+	if err := s.sync(ctx, key); err != nil {
+		fmt.Printf("[SecretSyncer] Failed to sync %s, requeuing: %v\n", key, err)
+		s.queue.AddRateLimited(key)
+		return true
+	}
+	s.queue.Forget(key)
+	return true
+}
+
+// targetNamespaces resolves the replicate-to annotation against cached namespaces
+func (s *SecretSyncer) targetNamespaces(source *corev1.Secret) (sets.Set[string], error) {
+	targets := sets.New[string]()
+	value, ok := source.Annotations[replicateToAnnotation]
+	if !ok {
+		return targets, nil
+	}
+	namespaces, err := s.factory.Core().V1().Namespaces().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	existing := sets.New[string]()
+	for _, ns := range namespaces {
+		if ns.Status.Phase != corev1.NamespaceTerminating {
+			existing.Insert(ns.Name)
+		}
+	}
+	if strings.TrimSpace(value) == "*" {
+		targets = existing
+	} else {
+		for _, ns := range strings.Split(value, ",") {
+			if ns = strings.TrimSpace(ns); ns != "" && existing.Has(ns) {
+				targets.Insert(ns)
+			}
+		}
+	}
+	// Never replicate onto the source itself
+	targets.Delete(source.Namespace)
+	return targets, nil
+}
+
+// sync makes the set of copies match the source: create/update/delete in lockstep
+func (s *SecretSyncer) sync(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	lister := s.factory.Core().V1().Secrets().Lister()
+	indexer := s.factory.Core().V1().Secrets().Informer().GetIndexer()
+
+	targets := sets.New[string]()
+	source, err := lister.Secrets(namespace).Get(name)
+	switch {
+	case apierrors.IsNotFound(err):
+		// Source deleted: every copy goes away (targets stays empty)
+	case err != nil:
+		return err
+	default:
+		if targets, err = s.targetNamespaces(source); err != nil {
+			return err
+		}
+	}
+
+	// Delete copies that are no longer wanted
+	copies, err := indexer.ByIndex(sourceIndex, key)
+	if err != nil {
+		return err
+	}
+	for _, obj := range copies {
+		replica := obj.(*corev1.Secret)
+		if targets.Has(replica.Namespace) {
+			continue
+		}
+		err := s.clientset.CoreV1().Secrets(replica.Namespace).Delete(ctx, replica.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		fmt.Printf("[SecretSyncer] Deleted copy %s/%s of %s\n", replica.Namespace, replica.Name, key)
+	}
+	if source == nil {
+		return nil
+	}
+
+	hash := secretHash(source)
+	for _, ns := range sets.List(targets) {
+		existing, err := lister.Secrets(ns).Get(source.Name)
+		switch {
+		case apierrors.IsNotFound(err):
+			if _, err := s.clientset.CoreV1().Secrets(ns).Create(ctx, s.buildCopy(source, ns, hash), metav1.CreateOptions{}); err != nil {
+				return err
+			}
+			fmt.Printf("[SecretSyncer] Created copy %s/%s of %s\n", ns, source.Name, key)
+		case err != nil:
+			return err
+		case existing.Annotations[sourceAnnotation] != key:
+			// Never overwrite a secret we do not own
+			fmt.Printf("[SecretSyncer] Skipping %s/%s: exists and is not managed by %s\n", ns, source.Name, key)
+		case secretHash(existing) != hash || existing.Type != source.Type || existing.Annotations[hashAnnotation] != hash:
+			// Hash the copy's own data too: a hand edit leaves the annotation as it was
+			if existing.Type != source.Type {
+				// Type is immutable, so recreate the copy
+				if err := s.clientset.CoreV1().Secrets(ns).Delete(ctx, existing.Name, metav1.DeleteOptions{}); err != nil {
+					return err
+				}
+				_, err = s.clientset.CoreV1().Secrets(ns).Create(ctx, s.buildCopy(source, ns, hash), metav1.CreateOptions{})
+			} else {
+				updated := s.buildCopy(source, ns, hash)
+				updated.ResourceVersion = existing.ResourceVersion
+				_, err = s.clientset.CoreV1().Secrets(ns).Update(ctx, updated, metav1.UpdateOptions{})
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("[SecretSyncer] Updated copy %s/%s of %s\n", ns, source.Name, key)
+		}
+	}
+	return nil
+}
+
+// buildCopy creates the replica object for a target namespace
+func (s *SecretSyncer) buildCopy(source *corev1.Secret, namespace, hash string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      source.Name,
+			Namespace: namespace,
+			Labels:    map[string]string{managedLabel: "true"},
+			Annotations: map[string]string{
+				sourceAnnotation: source.Namespace + "/" + source.Name,
+				hashAnnotation:   hash,
+			},
+		},
+		Type: source.Type,
+		Data: source.Data,
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+
+	syncer := NewSecretSyncer(clientset)
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Ready once the secret cache has synced, and while the queue keeps up
+	health := healthz.New()
+	health.AddReadyCheck("informers", healthz.FactorySynced(syncer.factory))
+	health.AddReadyCheck("workqueue", healthz.QueueDepth(syncer.queue, *maxQueueDepth))
+	if *healthAddr != "" {
+		go func() {
+			if err := health.Serve(ctx, *healthAddr); err != nil {
+				log.Fatalf("Health server failed: %v", err)
+			}
+		}()
+	}
+
+	syncer.Run(ctx, *workers)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newNamespace(name string, phase corev1.NamespacePhase) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.NamespaceStatus{Phase: phase},
+	}
+}
+
+func newSecret(namespace, name string, annotations map[string]string, data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{},
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+// startSyncer syncs the caches without starting workers, so tests call sync directly
+func startSyncer(t *testing.T, clientset *fake.Clientset) *SecretSyncer {
+	t.Helper()
+	s := NewSecretSyncer(clientset)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		s.queue.ShutDown()
+	})
+	s.factory.Start(stopCh)
+	s.factory.WaitForCacheSync(stopCh)
+	return s
+}
+
+func TestSecretSourceIndexFunc(t *testing.T) {
+	replica := newSecret("team-a", "registry", map[string]string{sourceAnnotation: "platform/registry"}, nil)
+	if keys, _ := secretSourceIndexFunc(replica); !slices.Equal(keys, []string{"platform/registry"}) {
+		t.Errorf("replica indexed under %v", keys)
+	}
+	// Secrets that are not copies are left out of the index entirely
+	if keys, _ := secretSourceIndexFunc(newSecret("platform", "registry", nil, nil)); len(keys) != 0 {
+		t.Errorf("source indexed under %v", keys)
+	}
+}
+
+func TestSecretHash(t *testing.T) {
+	a := newSecret("platform", "a", nil, map[string]string{"user": "admin", "pass": "s3cret"})
+	b := newSecret("team-a", "b", map[string]string{"x": "y"}, map[string]string{"pass": "s3cret", "user": "admin"})
+	if secretHash(a) != secretHash(b) {
+		t.Error("hash depends on metadata or key order")
+	}
+	b.Data["pass"] = []byte("changed")
+	if secretHash(a) == secretHash(b) {
+		t.Error("hash did not change with the data")
+	}
+	c := newSecret("platform", "a", nil, map[string]string{"user": "admin", "pass": "s3cret"})
+	c.Type = corev1.SecretTypeBasicAuth
+	if secretHash(a) == secretHash(c) {
+		t.Error("hash did not change with the type")
+	}
+}
+
+func TestTargetNamespaces(t *testing.T) {
+	s := startSyncer(t, fake.NewSimpleClientset(
+		newNamespace("platform", corev1.NamespaceActive),
+		newNamespace("team-a", corev1.NamespaceActive),
+		newNamespace("team-b", corev1.NamespaceActive),
+		newNamespace("old-team", corev1.NamespaceTerminating),
+	))
+
+	tests := []struct {
+		replicateTo string
+		want        []string
+	}{
+		{"*", []string{"team-a", "team-b"}},
+		{"team-a, missing ,platform", []string{"team-a"}},
+		{"old-team", nil},
+	}
+	for _, tt := range tests {
+		source := newSecret("platform", "registry", map[string]string{replicateToAnnotation: tt.replicateTo}, nil)
+		targets, err := s.targetNamespaces(source)
+		if err != nil {
+			t.Fatalf("targetNamespaces(%q): %v", tt.replicateTo, err)
+		}
+		if got := sets.List(targets); !slices.Equal(got, tt.want) {
+			t.Errorf("targetNamespaces(%q) = %v, want %v", tt.replicateTo, got, tt.want)
+		}
+	}
+}
+
+func TestSyncCreatesAndPrunesCopies(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newNamespace("platform", corev1.NamespaceActive),
+		newNamespace("team-a", corev1.NamespaceActive),
+		newNamespace("team-b", corev1.NamespaceActive),
+		newSecret("platform", "registry", map[string]string{replicateToAnnotation: "team-a"}, map[string]string{"token": "abc"}),
+		// Left over from when the source was also replicated to team-b
+		newSecret("team-b", "registry", map[string]string{sourceAnnotation: "platform/registry"}, map[string]string{"token": "abc"}),
+	)
+	s := startSyncer(t, clientset)
+	ctx := context.TODO()
+
+	if err := s.sync(ctx, "platform/registry"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	replica, err := clientset.CoreV1().Secrets("team-a").Get(ctx, "registry", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("copy not created: %v", err)
+	}
+	if string(replica.Data["token"]) != "abc" || replica.Annotations[sourceAnnotation] != "platform/registry" {
+		t.Errorf("unexpected copy: data=%v annotations=%v", replica.Data, replica.Annotations)
+	}
+	if replica.Labels[managedLabel] != "true" {
+		t.Errorf("copy is missing the %s label", managedLabel)
+	}
+	if _, err := clientset.CoreV1().Secrets("team-b").Get(ctx, "registry", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("stale copy in team-b: got %v, want NotFound", err)
+	}
+}
+
+func TestEnqueueSecretMapsCopiesToSource(t *testing.T) {
+	s := startSyncer(t, fake.NewSimpleClientset())
+
+	s.enqueueSecret(newSecret("team-a", "registry", map[string]string{sourceAnnotation: "platform/registry"}, nil))
+	s.enqueueSecret(newSecret("platform", "plain", nil, nil))
+
+	if s.queue.Len() != 1 {
+		t.Fatalf("queue length = %d, want 1", s.queue.Len())
+	}
+	if key, _ := s.queue.Get(); key != "platform/registry" {
+		t.Errorf("queued %q, want the source key", key)
+	}
+}
+
+func TestSyncNeverOverwritesForeignSecrets(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newNamespace("platform", corev1.NamespaceActive),
+		newNamespace("team-a", corev1.NamespaceActive),
+		newSecret("platform", "registry", map[string]string{replicateToAnnotation: "team-a"}, map[string]string{"token": "abc"}),
+		newSecret("team-a", "registry", nil, map[string]string{"token": "team-a-own"}),
+	)
+	s := startSyncer(t, clientset)
+	ctx := context.TODO()
+
+	if err := s.sync(ctx, "platform/registry"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	existing, _ := clientset.CoreV1().Secrets("team-a").Get(ctx, "registry", metav1.GetOptions{})
+	if string(existing.Data["token"]) != "team-a-own" {
+		t.Errorf("foreign secret was overwritten: %v", existing.Data)
+	}
+}
+
+func TestSyncRestoresHandEditedCopy(t *testing.T) {
+	source := newSecret("platform", "registry", map[string]string{replicateToAnnotation: "team-a"}, map[string]string{"token": "abc"})
+	// The copy still carries the hash of the source, but its data was edited by hand
+	edited := newSecret("team-a", "registry", map[string]string{
+		sourceAnnotation: "platform/registry",
+		hashAnnotation:   secretHash(source),
+	}, map[string]string{"token": "edited"})
+	clientset := fake.NewSimpleClientset(
+		newNamespace("platform", corev1.NamespaceActive),
+		newNamespace("team-a", corev1.NamespaceActive),
+		source,
+		edited,
+	)
+	s := startSyncer(t, clientset)
+	ctx := context.TODO()
+
+	if err := s.sync(ctx, "platform/registry"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	replica, _ := clientset.CoreV1().Secrets("team-a").Get(ctx, "registry", metav1.GetOptions{})
+	if string(replica.Data["token"]) != "abc" {
+		t.Errorf("copy was not restored: %v", replica.Data)
+	}
+}
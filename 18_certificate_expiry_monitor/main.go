@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/reportsink"
+)
+
+const (
+	// expiryDateIndex indexes TLS secrets by the day their certificate expires
+	expiryDateIndex = "expiryDate"
+	dateLayout      = "2006-01-02"
+)
+
+var (
+	warnDays     = flag.Int("warn-days", 30, "alert on certificates expiring within this many days")
+	criticalDays = flag.Int("critical-days", 7, "certificates expiring within this many days are critical")
+	webhookURL   = flag.String("webhook-url", "", "POST alerts as JSON to this URL (Slack-compatible)")
+	metricsAddr  = flag.String("metrics-addr", ":9102", "address serving /metrics")
+	checkEvery   = flag.Duration("check-interval", time.Hour, "how often to evaluate expiry buckets")
+	sinkSpec     = flag.String("sink", "stdout", "where reports go: stdout, file://<dir> or s3://<bucket>/<prefix>")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "18-certificate-expiry-monitor")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// leafCertificate parses the first certificate in tls.crt
+func leafCertificate(secret *corev1.Secret) (*x509.Certificate, error) {
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return nil, errors.New("tls.crt contains no PEM data")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// expiryDateIndexFunc indexes a secret by the UTC day its certificate expires.
+// The date never changes for a given certificate, so the index stays valid;
+// time-relative buckets are derived from it at query time.
+func expiryDateIndexFunc(obj interface{}) ([]string, error) {
+	secret := obj.(*corev1.Secret)
+	cert, err := leafCertificate(secret)
+	if err != nil {
+		return []string{"invalid"}, nil
+	}
+	return []string{cert.NotAfter.UTC().Format(dateLayout)}, nil
+}
+
+// bucketFor maps days remaining to an expiry bucket
+func bucketFor(days int) string {
+	switch {
+	case days < 0:
+		return "expired"
+	case days <= *criticalDays:
+		return "critical"
+	case days <= *warnDays:
+		return "warning"
+	default:
+		return "ok"
+	}
+}
+
+// certStatus describes one TLS secret
+type certStatus struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Subject   string    `json:"subject"`
+	NotAfter  time.Time `json:"notAfter"`
+	Days      int       `json:"daysRemaining"`
+	Bucket    string    `json:"bucket"`
+}
+
+// statusFor computes days remaining and bucket for one secret
+func statusFor(secret *corev1.Secret, now time.Time) certStatus {
+	status := certStatus{Namespace: secret.Namespace, Name: secret.Name, Bucket: "invalid"}
+	if cert, err := leafCertificate(secret); err == nil {
+		status.Subject = cert.Subject.CommonName
+		status.NotAfter = cert.NotAfter
+		status.Days = int(cert.NotAfter.Sub(now).Hours() / 24)
+		if cert.NotAfter.Before(now) {
+			status.Days = -1
+		}
+		status.Bucket = bucketFor(status.Days)
+	}
+	return status
+}
+
+// collectStatuses walks the expiry index (one entry per day) instead of every secret
+func collectStatuses(indexer cache.Indexer, now time.Time) []certStatus {
+	var statuses []certStatus
+	for _, day := range indexer.ListIndexFuncValues(expiryDateIndex) {
+		objs, err := indexer.ByIndex(expiryDateIndex, day)
+		if err != nil {
+			continue
+		}
+		for _, obj := range objs {
+			statuses = append(statuses, statusFor(obj.(*corev1.Secret), now))
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Namespace != statuses[j].Namespace {
+			return statuses[i].Namespace < statuses[j].Namespace
+		}
+		return statuses[i].Days < statuses[j].Days
+	})
+	return statuses
+}
+
+// writeReport lists days remaining per namespace
+func writeReport(w io.Writer, statuses []certStatus) {
+	currentNS := ""
+	for _, s := range statuses {
+		if s.Namespace != currentNS {
+			currentNS = s.Namespace
+			fmt.Fprintf(w, "\nNamespace: %s\n", currentNS)
+		}
+		if s.Bucket == "invalid" {
+			fmt.Fprintf(w, "  %-40s %-10s unparsable certificate\n", s.Name, s.Bucket)
+			continue
+		}
+		fmt.Fprintf(w, "  %-40s %-10s %4d days  (CN=%s, expires %s)\n",
+			s.Name, s.Bucket, s.Days, s.Subject, s.NotAfter.Format(dateLayout))
+	}
+}
+
+// alerter posts webhook alerts at most once per secret per day. The informer
+// handlers and the periodic check only queue secret keys; one worker checks
+// them and posts, so a slow webhook never holds up the informer.
+type alerter struct {
+	indexer cache.Indexer
+	client  *http.Client
+	queue   workqueue.TypedInterface[string]
+
+	mu   sync.Mutex
+	sent map[string]string // namespace/name -> date of last alert
+}
+
+func newAlerter(indexer cache.Indexer) *alerter {
+	return &alerter{
+		indexer: indexer,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		queue:   workqueue.NewTyped[string](),
+		sent:    make(map[string]string),
+	}
+}
+
+// enqueue queues a secret to be checked. A secret already waiting is
+// checked once, however often it changed meanwhile.
+func (a *alerter) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	a.queue.Add(key)
+}
+
+// run checks queued secrets against the cache until the queue is shut down
+func (a *alerter) run(ctx context.Context) {
+	for {
+		key, quit := a.queue.Get()
+		if quit {
+			return
+		}
+		// A secret deleted while queued needs no alert
+		if obj, exists, err := a.indexer.GetByKey(key); err == nil && exists {
+			now := time.Now()
+			a.notify(ctx, []certStatus{statusFor(obj.(*corev1.Secret), now)}, now)
+		}
+		a.queue.Done(key)
+	}
+}
+
+func (a *alerter) notify(ctx context.Context, statuses []certStatus, now time.Time) {
+	today := now.Format(dateLayout)
+	for _, s := range statuses {
+		if s.Bucket == "ok" {
+			continue
+		}
+		key := s.Namespace + "/" + s.Name
+		a.mu.Lock()
+		already := a.sent[key] == today
+		a.mu.Unlock()
+		if already {
+			continue
+		}
+
+		text := fmt.Sprintf(":warning: TLS secret %s is %s: %d days remaining (CN=%s)", key, s.Bucket, s.Days, s.Subject)
+		if s.Bucket == "invalid" {
+			text = fmt.Sprintf(":warning: TLS secret %s holds an unparsable certificate", key)
+		}
+		fmt.Println("[Alert]", text)
+		if *webhookURL != "" {
+			if err := a.post(ctx, text); err != nil {
+				fmt.Printf("[Alert] Webhook failed: %v\n", err)
+				continue
+			}
+		}
+		// Only a delivered alert counts, a failed one is retried next run
+		a.mu.Lock()
+		a.sent[key] = today
+		a.mu.Unlock()
+	}
+}
+
+// post sends a Slack-compatible {"text": ...} payload to the webhook
+func (a *alerter) post(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+// serveMetrics exposes days remaining per certificate in Prometheus text
+// format until ctx is cancelled
+func serveMetrics(ctx context.Context, indexer cache.Indexer) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintln(w, "# HELP tls_certificate_expiry_days Days until the certificate in a TLS secret expires.")
+		fmt.Fprintln(w, "# TYPE tls_certificate_expiry_days gauge")
+		buckets := map[string]int{}
+		for _, s := range collectStatuses(indexer, time.Now()) {
+			buckets[s.Bucket]++
+			if s.Bucket == "invalid" {
+				continue
+			}
+			fmt.Fprintf(w, "tls_certificate_expiry_days{namespace=%q,secret=%q} %d\n", s.Namespace, s.Name, s.Days)
+		}
+		fmt.Fprintln(w, "# HELP tls_certificates_by_bucket Number of TLS secrets per expiry bucket.")
+		fmt.Fprintln(w, "# TYPE tls_certificates_by_bucket gauge")
+		for _, bucket := range []string{"expired", "critical", "warning", "ok", "invalid"} {
+			fmt.Fprintf(w, "tls_certificates_by_bucket{bucket=%q} %d\n", bucket, buckets[bucket])
+		}
+	})
+	server := &http.Server{Addr: *metricsAddr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Metrics server failed: %v", err)
+		}
+	}()
+}
+
+func main() {
+	clientset := createClientSet()
+	// "report" prints the table once; anything else runs the monitor
+	reportOnly := flag.Arg(0) == "report"
+
+	// Only cache TLS secrets: the field selector is evaluated by the API server
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, time.Hour,
+		informers.WithTweakListOptions(func(lo *metav1.ListOptions) {
+			lo.FieldSelector = "type=" + string(corev1.SecretTypeTLS)
+		}))
+	secretInformer := factory.Core().V1().Secrets().Informer()
+	secretInformer.AddIndexers(cache.Indexers{expiryDateIndex: expiryDateIndexFunc})
+
+	defer factory.Shutdown()
+
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	factory.Start(ctx.Done())
+
+	fmt.Println("Waiting for cache sync...")
+	factory.WaitForCacheSync(ctx.Done())
+	fmt.Println("Cache sync completed!")
+
+	sink, err := reportsink.Open(*sinkSpec)
+	if err != nil {
+		log.Fatalf("Failed to open report sink: %v", err)
+	}
+
+	indexer := secretInformer.GetIndexer()
+	if reportOnly {
+		now := time.Now()
+		if err := sink.Write(ctx, renderReport("security", collectStatuses(indexer, now), now)); err != nil {
+			log.Fatalf("Failed to deliver report: %v", err)
+		}
+		return
+	}
+
+	serveMetrics(ctx, indexer)
+	fmt.Printf("Serving metrics on %s/metrics\n", *metricsAddr)
+
+	// New or renewed certificates are checked as soon as they arrive
+	a := newAlerter(indexer)
+	secretInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    a.enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) { a.enqueue(newObj) },
+	})
+	var alerts wait.Group
+	alerts.StartWithContext(ctx, a.run)
+
+	// Scheduled reports run alongside the alerting, from the same cache
+	runner, err := scheduleReports(indexer, sink, schedules)
+	if err != nil {
+		log.Fatalf("Failed to schedule reports: %v", err)
+	}
+	var scheduler wait.Group
+	scheduler.StartWithContext(ctx, runner.Run)
+
+	// Buckets move as time passes even without events, so re-evaluate periodically
+	ticker := time.NewTicker(*checkEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// A report being delivered is abandoned with its context
+			// So is an alert being posted; queued ones are dropped
+			fmt.Println("Shutting down, waiting for the scheduler and informers to stop...")
+			a.queue.ShutDown()
+			alerts.Wait()
+			scheduler.Wait()
+			return
+		case <-ticker.C:
+			for _, key := range indexer.ListKeys() {
+				a.queue.Add(key)
+			}
+		}
+	}
+}
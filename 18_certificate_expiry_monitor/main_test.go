@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// now is fixed so buckets do not depend on when the tests run
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// tlsSecret returns a kubernetes.io/tls secret holding a self-signed certificate
+func tlsSecret(t *testing.T, namespace, name string, notAfter time.Time) *corev1.Secret {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name + ".example.com"},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		},
+	}
+}
+
+func TestExpiryDateIndexFunc(t *testing.T) {
+	secret := tlsSecret(t, "default", "web", time.Date(2025, 7, 4, 23, 0, 0, 0, time.UTC))
+	if keys, _ := expiryDateIndexFunc(secret); !slices.Equal(keys, []string{"2025-07-04"}) {
+		t.Errorf("got %v, want [2025-07-04]", keys)
+	}
+
+	// Broken certificates are kept in the index so they show up in reports
+	broken := &corev1.Secret{Data: map[string][]byte{corev1.TLSCertKey: []byte("not pem")}}
+	if keys, _ := expiryDateIndexFunc(broken); !slices.Equal(keys, []string{"invalid"}) {
+		t.Errorf("got %v, want [invalid]", keys)
+	}
+}
+
+func TestBucketFor(t *testing.T) {
+	tests := map[int]string{-1: "expired", 0: "critical", 7: "critical", 8: "warning", 30: "warning", 31: "ok"}
+	for days, want := range tests {
+		if got := bucketFor(days); got != want {
+			t.Errorf("bucketFor(%d) = %q, want %q", days, got, want)
+		}
+	}
+}
+
+func TestCollectStatusesFromIndex(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		tlsSecret(t, "default", "expired", now.Add(-24*time.Hour)),
+		tlsSecret(t, "default", "soon", now.Add(3*24*time.Hour+time.Hour)),
+		tlsSecret(t, "shop", "fine", now.Add(200*24*time.Hour)),
+		tlsSecret(t, "shop", "month", now.Add(20*24*time.Hour+time.Hour)),
+	)
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	secretInformer := factory.Core().V1().Secrets().Informer()
+	secretInformer.AddIndexers(cache.Indexers{expiryDateIndex: expiryDateIndexFunc})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	var got []string
+	for _, s := range collectStatuses(secretInformer.GetIndexer(), now) {
+		got = append(got, s.Namespace+"/"+s.Name+"="+s.Bucket)
+	}
+	// Sorted by namespace, then by days remaining
+	want := []string{"default/expired=expired", "default/soon=critical", "shop/month=warning", "shop/fine=ok"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestAlerterNotifiesOncePerDay(t *testing.T) {
+	a := newAlerter(nil)
+	statuses := []certStatus{
+		{Namespace: "default", Name: "soon", Bucket: "critical", Days: 3},
+		{Namespace: "default", Name: "fine", Bucket: "ok", Days: 200},
+	}
+
+	a.notify(t.Context(), statuses, now)
+	if len(a.sent) != 1 || a.sent["default/soon"] != "2025-06-01" {
+		t.Fatalf("sent = %v, want only default/soon", a.sent)
+	}
+	a.notify(t.Context(), statuses, now.Add(time.Hour))
+	a.notify(t.Context(), statuses, now.Add(24*time.Hour))
+	if a.sent["default/soon"] != "2025-06-02" {
+		t.Errorf("alert was not repeated the next day: %v", a.sent)
+	}
+}
+
+func TestAlerterRetriesFailedWebhook(t *testing.T) {
+	var texts []string
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		texts = append(texts, payload["text"])
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	defer func(url string) { *webhookURL = url }(*webhookURL)
+	*webhookURL = server.URL
+
+	a := newAlerter(nil)
+	statuses := []certStatus{{Namespace: "default", Name: "broken", Bucket: "invalid"}}
+
+	a.notify(t.Context(), statuses, now)
+	if len(a.sent) != 0 {
+		t.Fatalf("failed post was recorded as sent: %v", a.sent)
+	}
+	fail = false
+	a.notify(t.Context(), statuses, now.Add(time.Hour))
+	a.notify(t.Context(), statuses, now.Add(2*time.Hour))
+	if len(texts) != 2 || a.sent["default/broken"] != "2025-06-01" {
+		t.Fatalf("posted %q, sent = %v, want one retry and then nothing", texts, a.sent)
+	}
+	if !strings.Contains(texts[1], "unparsable certificate") || strings.Contains(texts[1], "days remaining") {
+		t.Errorf("invalid certificate alert = %q", texts[1])
+	}
+}
+
+func TestAlerterWorkerChecksQueuedSecrets(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(tlsSecret(t, "default", "soon", time.Now().Add(3*24*time.Hour)))
+	indexer.Add(tlsSecret(t, "default", "fine", time.Now().Add(200*24*time.Hour)))
+	a := newAlerter(indexer)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.run(t.Context())
+	}()
+
+	// Queued as the handlers would; gone is no longer in the cache
+	for _, name := range []string{"soon", "fine", "gone"} {
+		a.enqueue(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}})
+	}
+	a.queue.ShutDownWithDrain()
+	<-done
+	if len(a.sent) != 1 || a.sent["default/soon"] == "" {
+		t.Errorf("sent = %v, want only default/soon", a.sent)
+	}
+}
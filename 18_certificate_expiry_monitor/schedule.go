@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/reportsink"
+)
+
+// reportFilters are the reports the monitor can produce, by name. Each one
+// selects the certificates it lists.
+var reportFilters = map[string]func(certStatus) bool{
+	// security lists every TLS secret
+	"security": func(certStatus) bool { return true },
+	// expiring lists only certificates that need attention
+	"expiring": func(s certStatus) bool { return s.Bucket != "ok" },
+}
+
+// scheduleFlag collects repeated --schedule <report>=<cron> flags
+type scheduleFlag map[string]string
+
+func (f scheduleFlag) String() string {
+	var pairs []string
+	for name, spec := range f {
+		pairs = append(pairs, name+"="+spec)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func (f scheduleFlag) Set(value string) error {
+	name, spec, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("want <report>=<cron expression>, got %q", value)
+	}
+	if _, known := reportFilters[name]; !known {
+		return fmt.Errorf("unknown report %q (want security or expiring)", name)
+	}
+	if _, err := reportsink.ParseSchedule(spec); err != nil {
+		return err
+	}
+	f[name] = spec
+	return nil
+}
+
+var schedules = scheduleFlag{}
+
+func init() {
+	flag.Var(schedules, "schedule", `run a report on a cron schedule and deliver it to --sink, e.g. "expiring=0 8 * * mon-fri" (repeatable; reports: security, expiring)`)
+}
+
+// renderReport renders the expiry table for the certificates the named report selects
+func renderReport(name string, statuses []certStatus, now time.Time) reportsink.Report {
+	var selected []certStatus
+	for _, s := range statuses {
+		if reportFilters[name](s) {
+			selected = append(selected, s)
+		}
+	}
+	var body bytes.Buffer
+	if len(selected) == 0 {
+		fmt.Fprintln(&body, "No certificates to report")
+	}
+	writeReport(&body, selected)
+	return reportsink.Report{Name: name, GeneratedAt: now, Format: "txt", Body: body.Bytes()}
+}
+
+// scheduleReports returns a runner producing every --schedule report from the
+// expiry index. Scheduled runs read the cache, never the API server.
+func scheduleReports(indexer cache.Indexer, sink reportsink.ReportSink, schedules scheduleFlag) (*reportsink.Runner, error) {
+	runner := reportsink.NewRunner(sink)
+	for name, spec := range schedules {
+		err := runner.Add(name, spec, func(ctx context.Context, now time.Time) (reportsink.Report, error) {
+			return renderReport(name, collectStatuses(indexer, now), now), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return runner, nil
+}
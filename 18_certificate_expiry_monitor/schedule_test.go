@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shamimice03/mastering-k8s-client-go/reportsink"
+)
+
+func TestScheduleFlag(t *testing.T) {
+	f := scheduleFlag{}
+	for _, value := range []string{"expiring=0 8 * * mon-fri", "security=@weekly"} {
+		if err := f.Set(value); err != nil {
+			t.Errorf("Set(%q): %v", value, err)
+		}
+	}
+	if got := f.String(); got != "expiring=0 8 * * mon-fri, security=@weekly" {
+		t.Errorf("String() = %q", got)
+	}
+	for _, value := range []string{"0 8 * * *", "inventory=@daily", "security=every day"} {
+		if err := f.Set(value); err == nil {
+			t.Errorf("Set(%q) succeeded", value)
+		}
+	}
+}
+
+func TestRenderReports(t *testing.T) {
+	statuses := []certStatus{
+		statusFor(tlsSecret(t, "default", "soon", now.Add(3*24*time.Hour+time.Hour)), now),
+		statusFor(tlsSecret(t, "shop", "fine", now.Add(200*24*time.Hour)), now),
+		{Namespace: "shop", Name: "broken", Bucket: "invalid"},
+	}
+
+	security := renderReport("security", statuses, now)
+	if security.Key() != "security/security-20250601T120000Z.txt" {
+		t.Errorf("key = %s", security.Key())
+	}
+	for _, line := range []string{"Namespace: default\n", "soon.example.com", "fine.example.com", "unparsable certificate\n"} {
+		if !strings.Contains(string(security.Body), line) {
+			t.Errorf("security report is missing %q:\n%s", line, security.Body)
+		}
+	}
+
+	// Only what needs attention: the healthy certificate is left out
+	expiring := string(renderReport("expiring", statuses, now).Body)
+	if strings.Contains(expiring, "fine") || !strings.Contains(expiring, "soon") || !strings.Contains(expiring, "broken") {
+		t.Errorf("expiring report:\n%s", expiring)
+	}
+
+	empty := string(renderReport("expiring", statuses[1:2], now).Body)
+	if empty != "No certificates to report\n" {
+		t.Errorf("empty expiring report = %q", empty)
+	}
+}
+
+func TestScheduledReportIsStored(t *testing.T) {
+	dir := t.TempDir()
+	if err := (reportsink.File{Dir: dir}).Write(context.TODO(), renderReport("security", nil, now)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "security", "security-20250601T120000Z.txt")); err != nil {
+		t.Errorf("report not stored: %v", err)
+	}
+
+	if _, err := scheduleReports(nil, reportsink.File{Dir: dir}, scheduleFlag{"expiring": "not cron"}); err == nil {
+		t.Error("scheduleReports accepted an invalid schedule")
+	}
+}
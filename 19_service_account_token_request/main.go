@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace      = flag.String("namespace", "default", "namespace of the ServiceAccount")
+	serviceAccount = flag.String("service-account", "token-demo", "ServiceAccount to request a token for (created if missing)")
+	audience       = flag.String("audience", "", "token audience (empty uses the API server's default audience)")
+	expiration     = flag.Duration("expiration", 10*time.Minute, "requested token lifetime (the API server enforces a minimum of 10m)")
+)
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "19-service-account-token-request")
+	return config
+}
+
+// ensureServiceAccount creates the ServiceAccount if it does not exist yet
+func ensureServiceAccount(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error {
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	_, err := clientset.CoreV1().ServiceAccounts(namespace).Create(ctx, sa, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// requestToken asks for a token bound to the ServiceAccount. TokenRequest is
+// a subresource: POST .../serviceaccounts/<name>/token
+func requestToken(ctx context.Context, clientset kubernetes.Interface, namespace, name, audience string, expiration time.Duration) (*authenticationv1.TokenRequest, error) {
+	expirationSeconds := int64(expiration.Seconds())
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}
+	if audience != "" {
+		tokenRequest.Spec.Audiences = []string{audience}
+	}
+	return clientset.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, name, tokenRequest, metav1.CreateOptions{})
+}
+
+// decodeClaims decodes the JWT payload (no signature verification, display only)
+func decodeClaims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token has %d parts, want 3", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// printClaims prints the JWT payload, or nothing if the token is not a JWT
+func printClaims(token string) {
+	claims, err := decodeClaims(token)
+	if err != nil {
+		return
+	}
+	pretty, _ := json.MarshalIndent(claims, "  ", "  ")
+	fmt.Printf("Token claims:\n  %s\n", pretty)
+}
+
+func main() {
+	config := createConfig()
+
+	// Admin clientset from kubeconfig - used only to request the token
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+
+	// Ctrl-C or SIGTERM cancels the context, and with it any request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := ensureServiceAccount(ctx, clientset, *namespace, *serviceAccount); err != nil {
+		log.Fatalf("Failed to ensure ServiceAccount: %v", err)
+	}
+
+	result, err := requestToken(ctx, clientset, *namespace, *serviceAccount, *audience, *expiration)
+	if err != nil {
+		log.Fatalf("Failed to request token: %v", err)
+	}
+	fmt.Printf("Issued token for %s/%s, expires at %s\n", *namespace, *serviceAccount, result.Status.ExpirationTimestamp.Format(time.RFC3339))
+	printClaims(result.Status.Token)
+
+	// Second config: same server and CA, but none of the kubeconfig credentials
+	saConfig := rest.AnonymousClientConfig(config)
+	saConfig.BearerToken = result.Status.Token
+	saClientset, err := kubernetes.NewForConfig(saConfig)
+	if err != nil {
+		log.Fatalf("Failed to create ServiceAccount clientset: %v", err)
+	}
+
+	// Confirm the API server now sees us as the ServiceAccount
+	review, err := saClientset.AuthenticationV1().SelfSubjectReviews().Create(ctx,
+		&authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err != nil {
+		// A token for a custom audience is rejected by the API server itself
+		log.Fatalf("Authentication with the issued token failed: %v", err)
+	}
+	fmt.Printf("Authenticated as: %s\n", review.Status.UserInfo.Username)
+	fmt.Printf("Groups: %v\n", review.Status.UserInfo.Groups)
+
+	// Without RBAC bindings the ServiceAccount can do almost nothing
+	_, err = saClientset.CoreV1().Pods(*namespace).List(ctx, metav1.ListOptions{})
+	switch {
+	case err == nil:
+		fmt.Printf("List pods in %s: allowed\n", *namespace)
+	case apierrors.IsForbidden(err):
+		fmt.Printf("List pods in %s: forbidden (bind a Role to the ServiceAccount to allow it)\n", *namespace)
+	default:
+		fmt.Printf("List pods in %s: %v\n", *namespace, err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"slices"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// jwt builds an unsigned token carrying payload, enough for decodeClaims
+func jwt(payload string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"RS256"}`)) + "." + encode([]byte(payload)) + ".signature"
+}
+
+func TestEnsureServiceAccountIsIdempotent(t *testing.T) {
+	clientset := fake.NewClientset()
+	for i := 0; i < 2; i++ {
+		if err := ensureServiceAccount(context.TODO(), clientset, "default", "token-demo"); err != nil {
+			t.Fatalf("call %d: %v", i+1, err)
+		}
+	}
+	if _, err := clientset.CoreV1().ServiceAccounts("default").Get(context.TODO(), "token-demo", metav1.GetOptions{}); err != nil {
+		t.Errorf("ServiceAccount not created: %v", err)
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	clientset := fake.NewClientset(&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "token-demo"}})
+	expires := metav1.NewTime(time.Date(2025, 6, 1, 12, 10, 0, 0, time.UTC))
+
+	// The tracker has no token subresource, so the reactor plays the API
+	// server: it checks the request and returns a token for it
+	var got *authenticationv1.TokenRequest
+	clientset.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		create := action.(k8stesting.CreateActionImpl)
+		if create.Name != "token-demo" || create.Namespace != "default" {
+			t.Errorf("token requested for %s/%s", create.Namespace, create.Name)
+		}
+		got = create.GetObject().(*authenticationv1.TokenRequest).DeepCopy()
+		got.Status = authenticationv1.TokenRequestStatus{
+			Token:               jwt(`{"sub":"system:serviceaccount:default:token-demo","aud":["vault"]}`),
+			ExpirationTimestamp: expires,
+		}
+		return true, got, nil
+	})
+
+	result, err := requestToken(context.TODO(), clientset, "default", "token-demo", "vault", 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Fatal("no TokenRequest reached the API")
+	}
+	if got.Spec.ExpirationSeconds == nil || *got.Spec.ExpirationSeconds != 600 {
+		t.Errorf("expirationSeconds = %v, want 600", got.Spec.ExpirationSeconds)
+	}
+	if !slices.Equal(got.Spec.Audiences, []string{"vault"}) {
+		t.Errorf("audiences = %v, want [vault]", got.Spec.Audiences)
+	}
+	if !result.Status.ExpirationTimestamp.Equal(&expires) {
+		t.Errorf("expiration = %v, want %v", result.Status.ExpirationTimestamp, expires)
+	}
+
+	claims, err := decodeClaims(result.Status.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "system:serviceaccount:default:token-demo" {
+		t.Errorf("sub = %v", claims["sub"])
+	}
+}
+
+func TestRequestTokenWithoutAudience(t *testing.T) {
+	clientset := fake.NewClientset()
+	var audiences []string
+	clientset.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		request := action.(k8stesting.CreateActionImpl).GetObject().(*authenticationv1.TokenRequest)
+		audiences = request.Spec.Audiences
+		return true, request, nil
+	})
+	if _, err := requestToken(context.TODO(), clientset, "default", "token-demo", "", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// No audiences means the API server's own audience
+	if audiences != nil {
+		t.Errorf("audiences = %v, want none", audiences)
+	}
+}
+
+func TestDecodeClaimsRejectsNonJWT(t *testing.T) {
+	for _, token := range []string{"opaque-token", "a.!!!.c", jwt("not json")} {
+		if _, err := decodeClaims(token); err == nil {
+			t.Errorf("decodeClaims(%q) succeeded", token)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	userName    = flag.String("user", "csr-demo-user", "common name (username) of the client certificate")
+	group       = flag.String("group", "csr-demo", "organization (group) of the client certificate")
+	validity    = flag.Duration("validity", time.Hour, "requested certificate lifetime")
+	autoApprove = flag.Bool("auto-approve", false, "approve the CSR with the kubeconfig credentials instead of waiting for an admin")
+	timeout     = flag.Duration("timeout", 5*time.Minute, "how long to wait for approval and issuance")
+	outDir      = flag.String("out-dir", "", "write the key and certificate as <user>.key/<user>.crt into this directory")
+)
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "20-certificate-signing-request")
+	return config
+}
+
+// generateKeyAndCSR creates an ECDSA key and a PEM encoded x509 CSR.
+// The API server maps CN to the username and O to groups.
+func generateKeyAndCSR(user, group string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: user, Organization: []string{group}},
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+	return keyPEM, csrPEM, nil
+}
+
+// waitForCertificate uses an informer scoped to the single CSR and returns the
+// issued certificate once the signer fills status.certificate
+func waitForCertificate(ctx context.Context, clientset kubernetes.Interface, name string, timeout time.Duration) ([]byte, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithTweakListOptions(func(lo *metav1.ListOptions) {
+			lo.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	csrInformer := factory.Certificates().V1().CertificateSigningRequests().Informer()
+
+	issued := make(chan []byte, 1)
+	denied := make(chan string, 1)
+	check := func(obj interface{}) {
+		csr := obj.(*certificatesv1.CertificateSigningRequest)
+		for _, c := range csr.Status.Conditions {
+			switch c.Type {
+			case certificatesv1.CertificateApproved:
+				fmt.Printf("CSR %s approved: %s\n", csr.Name, c.Message)
+			case certificatesv1.CertificateDenied, certificatesv1.CertificateFailed:
+				select {
+				case denied <- fmt.Sprintf("%s: %s", c.Type, c.Message):
+				default:
+				}
+			}
+		}
+		if len(csr.Status.Certificate) > 0 {
+			select {
+			case issued <- csr.Status.Certificate:
+			default:
+			}
+		}
+	}
+	csrInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    check,
+		UpdateFunc: func(oldObj, newObj interface{}) { check(newObj) },
+	})
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer factory.Shutdown()
+	defer cancel()
+	factory.Start(ctx.Done())
+
+	select {
+	case cert := <-issued:
+		return cert, nil
+	case reason := <-denied:
+		return nil, fmt.Errorf("CSR was not issued: %s", reason)
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("timed out waiting for CSR %s (approve with: kubectl certificate approve %s)", name, name)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("interrupted while waiting for CSR %s", name)
+	}
+}
+
+func main() {
+	config := createConfig()
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	// Ctrl-C or SIGTERM cancels the context, and with it any request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Step 1: key and CSR never leave this process except the public CSR
+	keyPEM, csrPEM, err := generateKeyAndCSR(*userName, *group)
+	if err != nil {
+		log.Fatalf("Failed to generate key/CSR: %v", err)
+	}
+
+	// Step 2: submit a certificates.k8s.io CSR for a client certificate
+	expirationSeconds := int32(validity.Seconds())
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: *userName + "-"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:           csrPEM,
+			SignerName:        certificatesv1.KubeAPIServerClientSignerName,
+			ExpirationSeconds: &expirationSeconds,
+			Usages:            []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageClientAuth},
+		},
+	}
+	created, err := clientset.CertificatesV1().CertificateSigningRequests().Create(ctx, csr, metav1.CreateOptions{})
+	if err != nil {
+		log.Fatalf("Failed to create CSR: %v", err)
+	}
+	fmt.Printf("Submitted CSR %s (signer %s)\n", created.Name, created.Spec.SignerName)
+	defer func() {
+		// CSRs are garbage collected eventually; remove ours right away
+		_ = clientset.CertificatesV1().CertificateSigningRequests().Delete(ctx, created.Name, metav1.DeleteOptions{})
+	}()
+
+	// Step 3: approval is normally a human/admin decision (kubectl certificate approve)
+	if *autoApprove {
+		created.Status.Conditions = append(created.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+			Type:    certificatesv1.CertificateApproved,
+			Status:  corev1.ConditionTrue,
+			Reason:  "AutoApproved",
+			Message: "approved by the csr example",
+		})
+		if _, err := clientset.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, created.Name, created, metav1.UpdateOptions{}); err != nil {
+			log.Fatalf("Failed to approve CSR: %v", err)
+		}
+	} else {
+		fmt.Printf("Waiting for approval: kubectl certificate approve %s\n", created.Name)
+	}
+
+	// Step 4: wait for the issued certificate via an informer
+	certPEM, err := waitForCertificate(ctx, clientset, created.Name, *timeout)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if block, _ := pem.Decode(certPEM); block != nil {
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			fmt.Printf("Issued certificate: subject=%s, expires %s\n", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+		}
+	}
+
+	if *outDir != "" {
+		if err := os.WriteFile(filepath.Join(*outDir, *userName+".key"), keyPEM, 0o600); err != nil {
+			log.Fatalf("Failed to write key: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(*outDir, *userName+".crt"), certPEM, 0o644); err != nil {
+			log.Fatalf("Failed to write certificate: %v", err)
+		}
+	}
+
+	// Step 5: build a rest.Config that authenticates with mTLS using the new cert
+	certConfig := rest.AnonymousClientConfig(config)
+	certConfig.CertData = certPEM
+	certConfig.KeyData = keyPEM
+	certClientset, err := kubernetes.NewForConfig(certConfig)
+	if err != nil {
+		log.Fatalf("Failed to create certificate clientset: %v", err)
+	}
+
+	review, err := certClientset.AuthenticationV1().SelfSubjectReviews().Create(ctx,
+		&authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err != nil {
+		log.Fatalf("Authentication with the issued certificate failed: %v", err)
+	}
+	fmt.Printf("Authenticated via client certificate as: %s %v\n",
+		review.Status.UserInfo.Username, review.Status.UserInfo.Groups)
+}
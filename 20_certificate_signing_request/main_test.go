@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func csrWithStatus(name string, status certificatesv1.CertificateSigningRequestStatus) *certificatesv1.CertificateSigningRequest {
+	return &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     status,
+	}
+}
+
+func TestGenerateKeyAndCSR(t *testing.T) {
+	keyPEM, csrPEM, err := generateKeyAndCSR("jane", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block, _ := pem.Decode(keyPEM); block == nil || block.Type != "EC PRIVATE KEY" {
+		t.Fatalf("key is not an EC PRIVATE KEY PEM block")
+	}
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		t.Fatalf("CSR is not a CERTIFICATE REQUEST PEM block")
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := request.CheckSignature(); err != nil {
+		t.Errorf("CSR signature: %v", err)
+	}
+	// CN becomes the username and O the groups
+	if request.Subject.CommonName != "jane" || !slices.Equal(request.Subject.Organization, []string{"dev"}) {
+		t.Errorf("subject = %v, want CN=jane,O=dev", request.Subject)
+	}
+}
+
+func TestWaitForCertificateIssued(t *testing.T) {
+	clientset := fake.NewClientset(csrWithStatus("jane-abcde", certificatesv1.CertificateSigningRequestStatus{}))
+	ctx := context.TODO()
+
+	go func() {
+		// The signer fills status.certificate after approval
+		time.Sleep(100 * time.Millisecond)
+		csr := csrWithStatus("jane-abcde", certificatesv1.CertificateSigningRequestStatus{
+			Conditions: []certificatesv1.CertificateSigningRequestCondition{
+				{Type: certificatesv1.CertificateApproved, Status: corev1.ConditionTrue, Message: "approved"},
+			},
+			Certificate: []byte("issued-cert"),
+		})
+		if _, err := clientset.CertificatesV1().CertificateSigningRequests().UpdateStatus(ctx, csr, metav1.UpdateOptions{}); err != nil {
+			t.Errorf("UpdateStatus: %v", err)
+		}
+	}()
+
+	cert, err := waitForCertificate(ctx, clientset, "jane-abcde", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cert) != "issued-cert" {
+		t.Errorf("certificate = %q, want issued-cert", cert)
+	}
+}
+
+func TestWaitForCertificateDenied(t *testing.T) {
+	clientset := fake.NewClientset(csrWithStatus("jane-abcde", certificatesv1.CertificateSigningRequestStatus{
+		Conditions: []certificatesv1.CertificateSigningRequestCondition{
+			{Type: certificatesv1.CertificateDenied, Status: corev1.ConditionTrue, Message: "not today"},
+		},
+	}))
+
+	_, err := waitForCertificate(context.TODO(), clientset, "jane-abcde", 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "not today") {
+		t.Errorf("err = %v, want the denial message", err)
+	}
+}
+
+func TestWaitForCertificateTimesOut(t *testing.T) {
+	clientset := fake.NewClientset(csrWithStatus("jane-abcde", certificatesv1.CertificateSigningRequestStatus{}))
+
+	_, err := waitForCertificate(context.TODO(), clientset, "jane-abcde", 100*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "kubectl certificate approve jane-abcde") {
+		t.Errorf("err = %v, want a timeout with the approve hint", err)
+	}
+}
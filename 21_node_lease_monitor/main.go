@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+// nodeLeaseNamespace holds one Lease per node, renewed by the kubelet
+const nodeLeaseNamespace = "kube-node-lease"
+
+var (
+	staleAfter = flag.Duration("stale-after", 20*time.Second, "flag a node when its lease has not been renewed for this long")
+	checkEvery = flag.Duration("check-interval", 2*time.Second, "how often lease ages are evaluated")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "21-node-lease-monitor")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// nodeReady returns the status of the node's Ready condition
+func nodeReady(node *corev1.Node) corev1.ConditionStatus {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status
+		}
+	}
+	return corev1.ConditionUnknown
+}
+
+// leaseAge returns how long ago the kubelet last renewed the lease
+func leaseAge(lease *coordinationv1.Lease, now time.Time) time.Duration {
+	if lease.Spec.RenewTime == nil {
+		return time.Duration(1<<63 - 1)
+	}
+	return now.Sub(lease.Spec.RenewTime.Time)
+}
+
+// staleTracker remembers when each node's lease went stale so the lead time
+// over the Ready condition can be reported
+type staleTracker struct {
+	mu         sync.Mutex
+	staleSince map[string]time.Time
+}
+
+func main() {
+	clientset := createClientSet()
+	// Leases live in their own namespace; scope that factory to it
+	leaseFactory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(nodeLeaseNamespace))
+	leaseLister := leaseFactory.Coordination().V1().Leases().Lister()
+	leaseFactory.Coordination().V1().Leases().Informer()
+
+	// Node informer is the "node-condition watcher" we compare against
+	nodeFactory := informers.NewSharedInformerFactory(clientset, 0)
+	nodeLister := nodeFactory.Core().V1().Nodes().Lister()
+
+	tracker := &staleTracker{staleSince: make(map[string]time.Time)}
+
+	nodeFactory.Core().V1().Nodes().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, newNode := oldObj.(*corev1.Node), newObj.(*corev1.Node)
+			before, after := nodeReady(oldNode), nodeReady(newNode)
+			if before == after {
+				return
+			}
+			msg := fmt.Sprintf("[NodeCondition] Node %s Ready: %s -> %s", newNode.Name, before, after)
+			tracker.mu.Lock()
+			if since, ok := tracker.staleSince[newNode.Name]; ok && after != corev1.ConditionTrue {
+				msg += fmt.Sprintf(" (lease monitor detected it %v earlier)", time.Since(since).Round(time.Second))
+			}
+			tracker.mu.Unlock()
+			fmt.Println(msg)
+		},
+	})
+
+	defer nodeFactory.Shutdown()
+	defer leaseFactory.Shutdown()
+
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	leaseFactory.Start(ctx.Done())
+	nodeFactory.Start(ctx.Done())
+
+	fmt.Println("Waiting for cache sync...")
+	leaseFactory.WaitForCacheSync(ctx.Done())
+	nodeFactory.WaitForCacheSync(ctx.Done())
+	fmt.Println("Cache sync completed!")
+
+	// Lease updates arrive every ~10s; the absence of an update is what matters,
+	// so staleness is evaluated on a timer rather than in event handlers
+	ticker := time.NewTicker(*checkEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Shutting down, waiting for informers to stop...")
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		nodes, err := nodeLister.List(labels.Everything())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		for _, node := range nodes {
+			lease, err := leaseLister.Leases(nodeLeaseNamespace).Get(node.Name)
+			if err != nil {
+				continue
+			}
+			age := leaseAge(lease, now)
+
+			tracker.mu.Lock()
+			since, wasStale := tracker.staleSince[node.Name]
+			switch {
+			case age > *staleAfter && !wasStale:
+				tracker.staleSince[node.Name] = now
+				fmt.Printf("[LeaseMonitor] Node %s heartbeat STALE: last renew %v ago (Ready=%s)\n",
+					node.Name, age.Round(time.Second), nodeReady(node))
+			case age <= *staleAfter && wasStale:
+				delete(tracker.staleSince, node.Name)
+				fmt.Printf("[LeaseMonitor] Node %s heartbeat recovered after %v\n",
+					node.Name, now.Sub(since).Round(time.Second))
+			}
+			tracker.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeReady(t *testing.T) {
+	node := &corev1.Node{Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+		{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+		{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+	}}}
+	if got := nodeReady(node); got != corev1.ConditionTrue {
+		t.Errorf("nodeReady = %s, want True", got)
+	}
+	if got := nodeReady(&corev1.Node{}); got != corev1.ConditionUnknown {
+		t.Errorf("node without conditions = %s, want Unknown", got)
+	}
+}
+
+func TestLeaseAgeFromLister(t *testing.T) {
+	now := time.Now()
+	renewed := metav1.NewMicroTime(now.Add(-45 * time.Second))
+	clientset := fake.NewSimpleClientset(
+		&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-a", Namespace: nodeLeaseNamespace},
+			Spec:       coordinationv1.LeaseSpec{RenewTime: &renewed},
+		},
+		&coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: "node-b", Namespace: nodeLeaseNamespace}},
+		// Leases outside kube-node-lease are not node heartbeats
+		&coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Namespace: "default"}},
+	)
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(nodeLeaseNamespace))
+	lister := factory.Coordination().V1().Leases().Lister()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	lease, err := lister.Leases(nodeLeaseNamespace).Get("node-a")
+	if err != nil {
+		t.Fatalf("get lease: %v", err)
+	}
+	if age := leaseAge(lease, now); age != 45*time.Second {
+		t.Errorf("age = %v, want 45s", age)
+	}
+	// A lease that was never renewed counts as infinitely stale
+	never, _ := lister.Leases(nodeLeaseNamespace).Get("node-b")
+	if age := leaseAge(never, now); age < 24*time.Hour {
+		t.Errorf("age of unrenewed lease = %v", age)
+	}
+	if _, err := lister.Leases("default").Get("node-a"); err == nil {
+		t.Error("namespaced factory cached a lease from another namespace")
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/reportsink"
+)
+
+// maxKubeletSkew is how many minor versions a kubelet may lag behind
+// kube-apiserver (3 since Kubernetes 1.28, 2 before that)
+var maxKubeletSkew = flag.Int("max-skew", 3, "supported number of minor versions a kubelet may be older than the API server")
+
+var sinkSpec = flag.String("sink", "stdout", "where the report goes: stdout, file://<dir> or s3://<bucket>/<prefix>")
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "22-kubelet-version-skew")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// skewResult classifies a kubelet against the API server version
+type skewResult struct {
+	Message       string
+	Supported     bool
+	BlocksUpgrade bool
+}
+
+func skewStatus(apiServer, kubelet *version.Version) skewResult {
+	if kubelet.Major() != apiServer.Major() {
+		return skewResult{Message: "UNSUPPORTED (major version mismatch)"}
+	}
+	skew := int(apiServer.Minor()) - int(kubelet.Minor())
+	switch {
+	case skew < 0:
+		return skewResult{Message: "UNSUPPORTED (kubelet newer than API server)"}
+	case skew > *maxKubeletSkew:
+		return skewResult{Message: fmt.Sprintf("UNSUPPORTED (%d minor versions behind)", skew)}
+	case skew == *maxKubeletSkew:
+		// Still supported today, but upgrading the control plane by one minor breaks it
+		return skewResult{Message: fmt.Sprintf("OK, blocks next upgrade (%d behind)", skew), Supported: true, BlocksUpgrade: true}
+	case skew > 0:
+		return skewResult{Message: fmt.Sprintf("OK (%d behind)", skew), Supported: true}
+	default:
+		return skewResult{Message: "OK (same minor)", Supported: true}
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+	// API server version comes from discovery (/version)
+	serverInfo, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		log.Fatalf("Failed to get server version: %v", err)
+	}
+	apiServerVersion, err := version.ParseGeneric(serverInfo.GitVersion)
+	if err != nil {
+		log.Fatalf("Failed to parse server version %q: %v", serverInfo.GitVersion, err)
+	}
+
+	// Node data is read purely from the node lister
+	factory := informers.NewSharedInformerFactory(clientset, time.Minute*10)
+	nodeLister := factory.Core().V1().Nodes().Lister()
+
+	defer factory.Shutdown()
+
+	// Ctrl-C or SIGTERM cancels the context, and with it any request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	factory.Start(ctx.Done())
+
+	fmt.Println("Waiting for cache sync...")
+	factory.WaitForCacheSync(ctx.Done())
+	fmt.Println("Cache sync completed!")
+
+	nodes, err := nodeLister.List(labels.Everything())
+	if err != nil {
+		log.Fatalf("Failed to list nodes: %v", err)
+	}
+
+	sink, err := reportsink.Open(*sinkSpec)
+	if err != nil {
+		log.Fatalf("Failed to open report sink: %v", err)
+	}
+	var report bytes.Buffer
+	writeReport(&report, serverInfo.GitVersion, apiServerVersion, nodes)
+	err = sink.Write(ctx, reportsink.Report{Name: "drift", GeneratedAt: time.Now(), Format: "txt", Body: report.Bytes()})
+	if err != nil {
+		log.Fatalf("Failed to deliver report: %v", err)
+	}
+}
+
+// writeReport groups nodes by kubelet version and judges each group's skew
+// against the API server. It is the "drift" report.
+func writeReport(w io.Writer, serverVersion string, apiServerVersion *version.Version, nodes []*corev1.Node) {
+	// Group nodes by kubelet version
+	byVersion := make(map[string][]string)
+	for _, node := range nodes {
+		v := node.Status.NodeInfo.KubeletVersion
+		byVersion[v] = append(byVersion[v], node.Name)
+	}
+	versions := make([]string, 0, len(byVersion))
+	for v := range byVersion {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		vi, erri := version.ParseGeneric(versions[i])
+		vj, errj := version.ParseGeneric(versions[j])
+		if erri != nil || errj != nil {
+			return versions[i] < versions[j]
+		}
+		return vi.LessThan(vj)
+	})
+
+	fmt.Fprintf(w, "\nAPI server version: %s\n", serverVersion)
+	fmt.Fprintf(w, "Next minor upgrade target: v%d.%d\n\n", apiServerVersion.Major(), apiServerVersion.Minor()+1)
+
+	unsupported, blocking := 0, 0
+	for _, v := range versions {
+		result := skewResult{Message: "UNSUPPORTED (unparsable version)"}
+		if kubeletVersion, err := version.ParseGeneric(v); err == nil {
+			result = skewStatus(apiServerVersion, kubeletVersion)
+		}
+		fmt.Fprintf(w, "Kubelet %s: %d node(s) - %s\n", v, len(byVersion[v]), result.Message)
+		for _, name := range byVersion[v] {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+		switch {
+		case !result.Supported:
+			unsupported += len(byVersion[v])
+		case result.BlocksUpgrade:
+			blocking += len(byVersion[v])
+		}
+	}
+
+	fmt.Fprintln(w)
+	switch {
+	case unsupported > 0:
+		fmt.Fprintf(w, "NOT READY: %d node(s) run an unsupported kubelet version\n", unsupported)
+	case blocking > 0:
+		fmt.Fprintf(w, "NOT READY: upgrade %d node(s) before upgrading the control plane\n", blocking)
+	default:
+		fmt.Fprintln(w, "READY: all kubelets stay within the supported skew after a one-minor control plane upgrade")
+	}
+}
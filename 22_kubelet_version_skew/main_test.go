@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestSkewStatus(t *testing.T) {
+	apiServer := version.MustParseGeneric("v1.33.2")
+	tests := []struct {
+		kubelet              string
+		supported, blocksUpg bool
+	}{
+		{"v1.33.0", true, false},
+		{"v1.31.4", true, false},
+		{"v1.30.1", true, true},
+		{"v1.29.9", false, false},
+		{"v1.34.0", false, false},
+		{"v2.33.0", false, false},
+	}
+	for _, tt := range tests {
+		got := skewStatus(apiServer, version.MustParseGeneric(tt.kubelet))
+		if got.Supported != tt.supported || got.BlocksUpgrade != tt.blocksUpg {
+			t.Errorf("kubelet %s: got %+v", tt.kubelet, got)
+		}
+	}
+}
+
+func node(name, kubeletVersion string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: kubeletVersion}},
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	var out bytes.Buffer
+	writeReport(&out, "v1.33.2", version.MustParseGeneric("v1.33.2"), []*corev1.Node{
+		node("worker-1", "v1.33.1"),
+		node("worker-2", "v1.30.4"),
+	})
+	report := out.String()
+
+	for _, line := range []string{
+		"API server version: v1.33.2\n",
+		"Next minor upgrade target: v1.34\n",
+		"Kubelet v1.30.4: 1 node(s) - ",
+		"  - worker-2\n",
+		"NOT READY: upgrade 1 node(s) before upgrading the control plane\n",
+	} {
+		if !strings.Contains(report, line) {
+			t.Errorf("report is missing %q:\n%s", line, report)
+		}
+	}
+	// Oldest kubelets first
+	if strings.Index(report, "v1.30.4") > strings.Index(report, "v1.33.1") {
+		t.Errorf("versions not sorted:\n%s", report)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/httpdebug"
+	"github.com/shamimice03/mastering-k8s-client-go/scope"
+)
+
+var (
+	namespace  = flag.String("namespace", "default", "namespace of the ServiceAccount (and Role in --namespaced mode)")
+	name       = flag.String("name", "informer-reader", "name used for the ServiceAccount, role and binding")
+	namespaced = flag.Bool("namespaced", false, "create a Role/RoleBinding instead of a ClusterRole/ClusterRoleBinding")
+	rulesFrom  = flag.String("rules-from", "", "audit report written by an example's --audit-report; its rules replace the informer rules")
+)
+
+// informerRules are the permissions the informer and lister examples need:
+// list+watch to fill the cache, get for direct reads
+var informerRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{""},
+		Resources: []string{"pods"},
+		Verbs:     []string{"get", "list", "watch"},
+	},
+	{
+		APIGroups: []string{"apps"},
+		Resources: []string{"deployments", "replicasets"},
+		Verbs:     []string{"get", "list", "watch"},
+	},
+}
+
+// informerChecks are the accesses the informer rules must allow
+var informerChecks = []authorizationv1.ResourceAttributes{
+	{Verb: "list", Resource: "pods"},
+	{Verb: "watch", Resource: "pods"},
+	{Verb: "get", Resource: "pods"},
+	{Verb: "watch", Group: "apps", Resource: "deployments"},
+}
+
+// loadRules reads the rules of an audit report from httpdebug. A Role only
+// grants access inside its namespace, so a report with cluster-wide
+// requests, or with requests to another namespace, needs the cluster scope.
+func loadRules(path, namespace string, namespaced bool) ([]rbacv1.PolicyRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report httpdebug.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("read audit report %s: %w", path, err)
+	}
+	if len(report.Rules) == 0 {
+		return nil, fmt.Errorf("audit report %s has no rules: the example sent no request that needs one", path)
+	}
+	if namespaced {
+		if report.ClusterScoped {
+			return nil, fmt.Errorf("audit report %s has cluster-wide requests, which a Role cannot grant; run without --namespaced", path)
+		}
+		for _, ns := range report.Namespaces {
+			if ns != namespace {
+				return nil, fmt.Errorf("audit report %s has requests in namespace %s, which a Role in %s cannot grant", path, ns, namespace)
+			}
+		}
+	}
+	return report.Rules, nil
+}
+
+// checksFor returns one access per resource and verb the rules grant.
+// Non-resource URLs are left out: a review of them is not scoped to a
+// namespace, and the examples only touch them for discovery.
+func checksFor(rules []rbacv1.PolicyRule) []authorizationv1.ResourceAttributes {
+	var checks []authorizationv1.ResourceAttributes
+	for _, rule := range rules {
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				resource, subresource, _ := strings.Cut(resource, "/")
+				for _, verb := range rule.Verbs {
+					checks = append(checks, authorizationv1.ResourceAttributes{Verb: verb, Group: group, Resource: resource, Subresource: subresource})
+				}
+			}
+		}
+	}
+	return checks
+}
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "23-rbac-bootstrap")
+	return config
+}
+
+// bootstrap creates (or updates) the ServiceAccount, and a role with rules
+// and its binding
+func bootstrap(ctx context.Context, clientset kubernetes.Interface, rules []rbacv1.PolicyRule) error {
+	// ServiceAccount - nothing to update if it already exists
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: *name, Namespace: *namespace}}
+	if _, err := clientset.CoreV1().ServiceAccounts(*namespace).Create(ctx, sa, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create ServiceAccount: %w", err)
+	}
+	fmt.Printf("ServiceAccount %s/%s ready\n", *namespace, *name)
+
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: *name, Namespace: *namespace}}
+
+	if *namespaced {
+		role := &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: *name, Namespace: *namespace},
+			Rules:      rules,
+		}
+		_, err := clientset.RbacV1().Roles(*namespace).Create(ctx, role, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			_, err = clientset.RbacV1().Roles(*namespace).Update(ctx, role, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return fmt.Errorf("create Role: %w", err)
+		}
+		fmt.Printf("Role %s/%s ready\n", *namespace, *name)
+
+		binding := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: *name, Namespace: *namespace},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: *name},
+			Subjects:   subjects,
+		}
+		_, err = clientset.RbacV1().RoleBindings(*namespace).Create(ctx, binding, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			_, err = clientset.RbacV1().RoleBindings(*namespace).Update(ctx, binding, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return fmt.Errorf("create RoleBinding: %w", err)
+		}
+		fmt.Printf("RoleBinding %s/%s ready\n", *namespace, *name)
+		return nil
+	}
+
+	// Informers in the examples watch all namespaces, which needs cluster-scoped RBAC
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: *name},
+		Rules:      rules,
+	}
+	_, err := clientset.RbacV1().ClusterRoles().Create(ctx, clusterRole, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = clientset.RbacV1().ClusterRoles().Update(ctx, clusterRole, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("create ClusterRole: %w", err)
+	}
+	fmt.Printf("ClusterRole %s ready\n", *name)
+
+	clusterBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: *name},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: *name},
+		Subjects:   subjects,
+	}
+	_, err = clientset.RbacV1().ClusterRoleBindings().Create(ctx, clusterBinding, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = clientset.RbacV1().ClusterRoleBindings().Update(ctx, clusterBinding, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("create ClusterRoleBinding: %w", err)
+	}
+	fmt.Printf("ClusterRoleBinding %s ready\n", *name)
+	return nil
+}
+
+// serviceAccountClientset authenticates as the ServiceAccount using a short-lived token
+func serviceAccountClientset(ctx context.Context, config *rest.Config, clientset kubernetes.Interface) (*kubernetes.Clientset, error) {
+	expiration := int64(600)
+	token, err := clientset.CoreV1().ServiceAccounts(*namespace).CreateToken(ctx, *name,
+		&authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expiration}},
+		metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	saConfig := rest.AnonymousClientConfig(config)
+	saConfig.BearerToken = token.Status.Token
+	return kubernetes.NewForConfig(saConfig)
+}
+
+// verifyAccess asks the API server, as the ServiceAccount, what it may do:
+// allowed must be, a delete and a secrets list must not
+func verifyAccess(ctx context.Context, saClientset kubernetes.Interface, allowed []authorizationv1.ResourceAttributes) {
+	ns := "" // all namespaces
+	if *namespaced {
+		ns = *namespace
+	}
+	checks := append(slices.Clone(allowed),
+		// Must be denied: the examples never write
+		authorizationv1.ResourceAttributes{Verb: "delete", Resource: "pods"},
+		authorizationv1.ResourceAttributes{Verb: "list", Resource: "secrets"},
+	)
+	for i := range checks {
+		checks[i].Namespace = ns
+	}
+	if *namespaced {
+		// Must be denied too: a Role does not reach past its namespace, which is
+		// why the examples need --namespace to run under it
+		checks = append(checks, authorizationv1.ResourceAttributes{Verb: "list", Resource: "pods"})
+	}
+
+	fmt.Printf("\nSelfSubjectAccessReview as system:serviceaccount:%s:%s\n", *namespace, *name)
+	for _, attrs := range checks {
+		allowed, err := scope.Allowed(ctx, saClientset, attrs)
+		if err != nil {
+			fmt.Printf("  %-8s %-25s error: %v\n", attrs.Verb, attrs.Resource, err)
+			continue
+		}
+		verdict := "DENIED"
+		if allowed {
+			verdict = "ALLOWED"
+		}
+		resource := attrs.Resource
+		if attrs.Subresource != "" {
+			resource += "/" + attrs.Subresource
+		}
+		if attrs.Group != "" {
+			resource += "." + attrs.Group
+		}
+		fmt.Printf("  %-7s %-6s %-18s in %-20s\n", verdict, attrs.Verb, resource, scope.Describe(attrs.Namespace))
+	}
+}
+
+func main() {
+	config := createConfig()
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	// Ctrl-C or SIGTERM cancels the context, and with it any request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	rules, checks := informerRules, informerChecks
+	if *rulesFrom != "" {
+		if rules, err = loadRules(*rulesFrom, *namespace, *namespaced); err != nil {
+			log.Fatalf("Failed to load rules: %v", err)
+		}
+		checks = checksFor(rules)
+		fmt.Printf("Rules from %s: %d\n", *rulesFrom, len(rules))
+	}
+
+	if err := bootstrap(ctx, clientset, rules); err != nil {
+		log.Fatalf("RBAC bootstrap failed: %v", err)
+	}
+
+	saClientset, err := serviceAccountClientset(ctx, config, clientset)
+	if err != nil {
+		log.Fatalf("Failed to authenticate as ServiceAccount: %v", err)
+	}
+	verifyAccess(ctx, saClientset, checks)
+}
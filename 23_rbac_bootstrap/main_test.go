@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBootstrapClusterScope(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx := context.TODO()
+
+	// Running twice must converge instead of failing on AlreadyExists
+	for i := 0; i < 2; i++ {
+		if err := bootstrap(ctx, clientset, informerRules); err != nil {
+			t.Fatalf("bootstrap run %d: %v", i+1, err)
+		}
+	}
+
+	if _, err := clientset.CoreV1().ServiceAccounts(*namespace).Get(ctx, *name, metav1.GetOptions{}); err != nil {
+		t.Errorf("ServiceAccount missing: %v", err)
+	}
+	role, err := clientset.RbacV1().ClusterRoles().Get(ctx, *name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("ClusterRole missing: %v", err)
+	}
+	for _, rule := range role.Rules {
+		for _, verb := range rule.Verbs {
+			if verb != "get" && verb != "list" && verb != "watch" {
+				t.Errorf("informer role grants %q on %v", verb, rule.Resources)
+			}
+		}
+	}
+	binding, err := clientset.RbacV1().ClusterRoleBindings().Get(ctx, *name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("ClusterRoleBinding missing: %v", err)
+	}
+	subject := binding.Subjects[0]
+	if subject.Kind != rbacv1.ServiceAccountKind || subject.Name != *name || subject.Namespace != *namespace {
+		t.Errorf("binding subject = %+v", subject)
+	}
+}
+
+func TestBootstrapNamespaced(t *testing.T) {
+	*namespaced = true
+	t.Cleanup(func() { *namespaced = false })
+	clientset := fake.NewSimpleClientset()
+	ctx := context.TODO()
+
+	if err := bootstrap(ctx, clientset, informerRules); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	if _, err := clientset.RbacV1().Roles(*namespace).Get(ctx, *name, metav1.GetOptions{}); err != nil {
+		t.Errorf("Role missing: %v", err)
+	}
+	if _, err := clientset.RbacV1().RoleBindings(*namespace).Get(ctx, *name, metav1.GetOptions{}); err != nil {
+		t.Errorf("RoleBinding missing: %v", err)
+	}
+	// Nothing cluster-scoped is created in namespaced mode
+	roles, _ := clientset.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+	if len(roles.Items) != 0 {
+		t.Errorf("created %d ClusterRoles in namespaced mode", len(roles.Items))
+	}
+}
+
+// writeReport writes an audit report in the format of httpdebug
+func writeReport(t *testing.T, report string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, []byte(report), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRules(t *testing.T) {
+	namespacedReport := writeReport(t, `{
+		"accesses": [{"verb": "list", "apiGroup": "", "resource": "pods", "namespace": "shop", "count": 1}],
+		"clusterScoped": false,
+		"namespaces": ["shop"],
+		"rules": [
+			{"apiGroups": [""], "resources": ["pods", "pods/log"], "verbs": ["get", "list"]}
+		]
+	}`)
+	clusterReport := writeReport(t, `{
+		"clusterScoped": true,
+		"namespaces": [],
+		"rules": [{"apiGroups": [""], "resources": ["namespaces"], "verbs": ["list"]}]
+	}`)
+
+	rules, err := loadRules(namespacedReport, "shop", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods", "pods/log"}, Verbs: []string{"get", "list"}}}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules = %+v, want %+v", rules, want)
+	}
+	if _, err := loadRules(clusterReport, "shop", false); err != nil {
+		t.Errorf("cluster report with a ClusterRole: %v", err)
+	}
+
+	for _, tt := range []struct {
+		path, namespace string
+		wantErr         string
+	}{
+		{clusterReport, "shop", "cluster-wide requests"},
+		{namespacedReport, "default", "namespace shop"},
+		{writeReport(t, `{"rules": []}`), "shop", "no rules"},
+	} {
+		if _, err := loadRules(tt.path, tt.namespace, true); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("loadRules(%s) = %v, want an error about %s", tt.namespace, err, tt.wantErr)
+		}
+	}
+}
+
+func TestChecksFor(t *testing.T) {
+	got := checksFor([]rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods", "pods/log"}, Verbs: []string{"get"}},
+		{NonResourceURLs: []string{"/metrics"}, Verbs: []string{"get"}},
+	})
+	want := []authorizationv1.ResourceAttributes{
+		{Verb: "get", Resource: "pods"},
+		{Verb: "get", Resource: "pods", Subresource: "log"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	pageSize      = flag.Int64("page-size", 500, "items per LIST page (limit/continue)")
+	warnCount     = flag.Int("warn-count", 1000, "highlight collections with more objects than this")
+	perNamespace  = flag.Int("top-namespaces", 3, "namespaces shown per resource")
+	measureSizes  = flag.Bool("sizes", true, "estimate the serialized size of every collection")
+	skipResources = flag.String("skip", "", "comma-separated resources to skip (e.g. events,leases)")
+)
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "24-api-resource-census")
+	return config
+}
+
+// census is the result for one GroupVersionResource
+type census struct {
+	GVR          schema.GroupVersionResource
+	Namespaced   bool
+	Total        int
+	Bytes        int
+	Pages        int
+	PerNamespace map[string]int
+	Notes        []string
+}
+
+// servedTwice maps resources the API server also serves under a second group
+// to the copy that is counted. events.k8s.io events are the core events, so
+// listing both would count every event twice.
+var servedTwice = map[schema.GroupResource]schema.GroupResource{
+	{Group: "events.k8s.io", Resource: "events"}: {Resource: "events"},
+}
+
+// listableResources returns the preferred version of every resource that supports LIST
+func listableResources(dc discovery.DiscoveryInterface) ([]metav1.APIResource, []schema.GroupVersion, error) {
+	lists, err := dc.ServerPreferredResources()
+	if err != nil && len(lists) == 0 {
+		// Partial discovery failures (e.g. a broken aggregated API) still return usable lists
+		return nil, nil, err
+	}
+	var resources []metav1.APIResource
+	var gvs []schema.GroupVersion
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			// Skip subresources such as pods/log
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			canList := false
+			for _, verb := range r.Verbs {
+				if verb == "list" {
+					canList = true
+				}
+			}
+			if canList {
+				resources = append(resources, r)
+				gvs = append(gvs, gv)
+			}
+		}
+	}
+
+	listed := make(map[schema.GroupResource]bool)
+	for i, r := range resources {
+		listed[gvs[i].WithResource(r.Name).GroupResource()] = true
+	}
+	var kept []metav1.APIResource
+	var keptGVs []schema.GroupVersion
+	for i, r := range resources {
+		if counted, ok := servedTwice[gvs[i].WithResource(r.Name).GroupResource()]; ok && listed[counted] {
+			continue
+		}
+		kept = append(kept, r)
+		keptGVs = append(keptGVs, gvs[i])
+	}
+	return kept, keptGVs, nil
+}
+
+// count lists a resource page by page and tallies objects per namespace
+func count(ctx context.Context, client dynamic.Interface, gvr schema.GroupVersionResource, namespaced bool) (*census, error) {
+	c := &census{GVR: gvr, Namespaced: namespaced, PerNamespace: make(map[string]int)}
+	zeroReplicaSets := 0
+
+	opts := metav1.ListOptions{Limit: *pageSize}
+	for {
+		list, err := client.Resource(gvr).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		c.Pages++
+		for i := range list.Items {
+			item := &list.Items[i]
+			c.Total++
+			c.PerNamespace[item.GetNamespace()]++
+			if *measureSizes {
+				if data, err := item.MarshalJSON(); err == nil {
+					c.Bytes += len(data)
+				}
+			}
+			// Old ReplicaSets kept by revisionHistoryLimit are scaled to zero
+			if gvr.Resource == "replicasets" {
+				if replicas, found, _ := unstructured.NestedInt64(item.Object, "spec", "replicas"); found && replicas == 0 {
+					zeroReplicaSets++
+				}
+			}
+		}
+		// An empty continue token means this was the last page
+		if list.GetContinue() == "" {
+			break
+		}
+		opts.Continue = list.GetContinue()
+	}
+
+	if c.Total > *warnCount {
+		c.Notes = append(c.Notes, fmt.Sprintf("LARGE: more than %d objects", *warnCount))
+	}
+	if zeroReplicaSets > 0 {
+		c.Notes = append(c.Notes, fmt.Sprintf("%d replicasets scaled to 0 (revision history, consider lowering revisionHistoryLimit)", zeroReplicaSets))
+	}
+	if gvr.Resource == "events" && c.Total > *warnCount/2 {
+		c.Notes = append(c.Notes, "event churn: every informer on events caches all of these")
+	}
+	return c, nil
+}
+
+// humanBytes formats a byte count
+func humanBytes(b int) string {
+	switch {
+	case b >= 1<<20:
+		return fmt.Sprintf("%.1fMi", float64(b)/(1<<20))
+	case b >= 1<<10:
+		return fmt.Sprintf("%.1fKi", float64(b)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", b)
+	}
+}
+
+func main() {
+	config := createConfig()
+
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create discovery client: %v", err)
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create dynamic client: %v", err)
+	}
+
+	resources, gvs, err := listableResources(dc)
+	if err != nil {
+		log.Fatalf("Discovery failed: %v", err)
+	}
+	skip := make(map[string]bool)
+	for _, r := range strings.Split(*skipResources, ",") {
+		skip[strings.TrimSpace(r)] = true
+	}
+	fmt.Printf("Discovered %d listable resources\n", len(resources))
+
+	// Ctrl-C or SIGTERM cancels the context, and with it any request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	var results []*census
+	for i, r := range resources {
+		if skip[r.Name] {
+			continue
+		}
+		gvr := gvs[i].WithResource(r.Name)
+		c, err := count(ctx, client, gvr, r.Namespaced)
+		if ctx.Err() != nil {
+			// Every remaining LIST would fail the same way
+			break
+		}
+		if err != nil {
+			// Forbidden resources are common for non-admin users
+			fmt.Printf("  skipped %s: %v\n", gvr.String(), err)
+			continue
+		}
+		results = append(results, c)
+	}
+	if ctx.Err() != nil {
+		fmt.Println("Interrupted, no report for a partial census")
+		return
+	}
+
+	// Largest collections first
+	sort.Slice(results, func(i, j int) bool { return results[i].Total > results[j].Total })
+
+	fmt.Printf("\n%-50s %8s %6s %10s\n", "RESOURCE", "COUNT", "PAGES", "SIZE")
+	totalObjects, totalBytes := 0, 0
+	for _, c := range results {
+		if c.Total == 0 {
+			continue
+		}
+		totalObjects += c.Total
+		totalBytes += c.Bytes
+		name := c.GVR.Resource
+		if c.GVR.Group != "" {
+			name += "." + c.GVR.Group
+		}
+		name += "/" + c.GVR.Version
+		fmt.Printf("%-50s %8d %6d %10s\n", name, c.Total, c.Pages, humanBytes(c.Bytes))
+
+		if c.Namespaced {
+			type nsCount struct {
+				ns    string
+				count int
+			}
+			var top []nsCount
+			for ns, n := range c.PerNamespace {
+				top = append(top, nsCount{ns, n})
+			}
+			sort.Slice(top, func(i, j int) bool { return top[i].count > top[j].count })
+			for k := 0; k < len(top) && k < *perNamespace; k++ {
+				fmt.Printf("    %-46s %8d\n", top[k].ns, top[k].count)
+			}
+		}
+		for _, note := range c.Notes {
+			fmt.Printf("    ! %s\n", note)
+		}
+	}
+	fmt.Printf("\nTotal: %d objects, ~%s serialized\n", totalObjects, humanBytes(totalBytes))
+}
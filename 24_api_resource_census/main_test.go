@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var (
+	podsGVR        = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	replicaSetsGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+)
+
+// preferredDiscovery serves the fake's resources as the preferred ones, which
+// FakeDiscovery itself leaves unimplemented
+type preferredDiscovery struct {
+	*discoveryfake.FakeDiscovery
+	err error
+}
+
+func (d preferredDiscovery) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	return d.Resources, d.err
+}
+
+func newDiscovery(err error, lists ...*metav1.APIResourceList) discovery.DiscoveryInterface {
+	return preferredDiscovery{FakeDiscovery: &discoveryfake.FakeDiscovery{Fake: &k8stesting.Fake{Resources: lists}}, err: err}
+}
+
+var coreAndApps = []*metav1.APIResourceList{
+	{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "pods", Namespaced: true, Verbs: []string{"create", "get", "list", "watch"}},
+			{Name: "pods/log", Namespaced: true, Verbs: []string{"get"}},
+			{Name: "bindings", Namespaced: true, Verbs: []string{"create"}},
+		},
+	},
+	{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "replicasets", Namespaced: true, Verbs: []string{"get", "list"}},
+		},
+	},
+}
+
+// listedGVRs pairs every resource with its group version, as main does
+func listedGVRs(resources []metav1.APIResource, gvs []schema.GroupVersion) []schema.GroupVersionResource {
+	var gvrs []schema.GroupVersionResource
+	for i, r := range resources {
+		gvrs = append(gvrs, gvs[i].WithResource(r.Name))
+	}
+	return gvrs
+}
+
+func TestListableResources(t *testing.T) {
+	resources, gvs, err := listableResources(newDiscovery(nil, coreAndApps...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Subresources and resources without list are left out
+	want := []schema.GroupVersionResource{podsGVR, replicaSetsGVR}
+	if got := listedGVRs(resources, gvs); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestListableResourcesWithPartialDiscovery(t *testing.T) {
+	// A broken aggregated API fails its group, the others are still counted
+	failed := &discovery.ErrGroupDiscoveryFailed{Groups: map[schema.GroupVersion]error{
+		{Group: "metrics.k8s.io", Version: "v1beta1"}: errors.New("service unavailable"),
+	}}
+	resources, gvs, err := listableResources(newDiscovery(failed, coreAndApps...))
+	if err != nil {
+		t.Fatalf("partial discovery failed the census: %v", err)
+	}
+	if len(listedGVRs(resources, gvs)) != 2 {
+		t.Errorf("got %v", listedGVRs(resources, gvs))
+	}
+
+	if _, _, err := listableResources(newDiscovery(errors.New("connection refused"))); err == nil {
+		t.Error("expected an error when discovery returned nothing")
+	}
+}
+
+func newObject(gvr schema.GroupVersionResource, kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(gvr.GroupVersion().String())
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func newDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		podsGVR:        "PodList",
+		replicaSetsGVR: "ReplicaSetList",
+	}, objects...)
+}
+
+func TestCountTalliesNamespaces(t *testing.T) {
+	client := newDynamicClient(
+		newObject(podsGVR, "Pod", "default", "web"),
+		newObject(podsGVR, "Pod", "default", "api"),
+		newObject(podsGVR, "Pod", "kube-system", "coredns"),
+	)
+	c, err := count(context.TODO(), client, podsGVR, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Total != 3 || c.Pages != 1 {
+		t.Errorf("total %d in %d pages, want 3 in 1", c.Total, c.Pages)
+	}
+	if c.PerNamespace["default"] != 2 || c.PerNamespace["kube-system"] != 1 {
+		t.Errorf("per namespace = %v", c.PerNamespace)
+	}
+	if c.Bytes == 0 {
+		t.Error("serialized size not measured")
+	}
+	if len(c.Notes) != 0 {
+		t.Errorf("unexpected notes: %v", c.Notes)
+	}
+}
+
+func TestCountFollowsContinueTokens(t *testing.T) {
+	client := newDynamicClient()
+	// The tracker ignores limit and the fake drops continue from the action,
+	// so the reactor pages by request: the first page carries a token
+	requests := 0
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		requests++
+		list := &unstructured.UnstructuredList{}
+		list.SetAPIVersion("v1")
+		list.SetKind("PodList")
+		list.Items = []unstructured.Unstructured{*newObject(podsGVR, "Pod", "default", fmt.Sprintf("pod-%d", requests))}
+		if requests == 1 {
+			list.SetContinue("page-2")
+		}
+		return true, list, nil
+	})
+
+	c, err := count(context.TODO(), client, podsGVR, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The last page, without a token, ends the listing
+	if c.Total != 2 || c.Pages != 2 || requests != 2 {
+		t.Errorf("total %d in %d pages from %d requests, want 2 in 2 from 2", c.Total, c.Pages, requests)
+	}
+}
+
+func TestCountNotesScaledDownReplicaSets(t *testing.T) {
+	old := newObject(replicaSetsGVR, "ReplicaSet", "default", "web-1")
+	unstructured.SetNestedField(old.Object, int64(0), "spec", "replicas")
+	current := newObject(replicaSetsGVR, "ReplicaSet", "default", "web-2")
+	unstructured.SetNestedField(current.Object, int64(3), "spec", "replicas")
+
+	c, err := count(context.TODO(), newDynamicClient(old, current), replicaSetsGVR, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Notes) != 1 || !strings.HasPrefix(c.Notes[0], "1 replicasets scaled to 0") {
+		t.Errorf("notes = %v", c.Notes)
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	for b, want := range map[int]string{512: "512B", 2048: "2.0Ki", 3 << 20: "3.0Mi"} {
+		if got := humanBytes(b); got != want {
+			t.Errorf("humanBytes(%d) = %s, want %s", b, got, want)
+		}
+	}
+}
+
+func TestListableResourcesCountsEventsOnce(t *testing.T) {
+	lists := []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "events", Namespaced: true, Verbs: []string{"list"}}}},
+		{GroupVersion: "events.k8s.io/v1", APIResources: []metav1.APIResource{{Name: "events", Namespaced: true, Verbs: []string{"list"}}}},
+	}
+	resources, gvs, err := listableResources(newDiscovery(nil, lists...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []schema.GroupVersionResource{{Version: "v1", Resource: "events"}}
+	if got := listedGVRs(resources, gvs); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Without core events the events.k8s.io copy is the one counted
+	resources, gvs, _ = listableResources(newDiscovery(nil, lists[1]))
+	if got := listedGVRs(resources, gvs); len(got) != 1 || got[0].Group != "events.k8s.io" {
+		t.Errorf("got %v", got)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// webhookSignerName is the custom signer used in --cert-source=csr mode.
+// Custom signers are not handled by kube-controller-manager; this process
+// plays the signer role itself.
+const webhookSignerName = "k8s-lab.io/webhook-serving"
+
+// servingCerts holds a serving certificate and the CA that signed it.
+// The CA PEM is what goes into the webhook configurations' caBundle.
+type servingCerts struct {
+	CAPEM   []byte
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// tlsCertificate converts the PEM pair into a tls.Certificate for the server
+func (c *servingCerts) tlsCertificate() (tls.Certificate, error) {
+	return tls.X509KeyPair(c.CertPEM, c.KeyPEM)
+}
+
+// certSource issues serving certificates for the given hosts
+type certSource interface {
+	issue(ctx context.Context, hosts []string, validity time.Duration) (*servingCerts, error)
+}
+
+// certAuthority is a CA key pair able to sign certificates
+type certAuthority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newCertAuthority creates a self-signed CA
+func newCertAuthority(name string, validity time.Duration) (*certAuthority, error) {
+	now := time.Now()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(now.UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &certAuthority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}, nil
+}
+
+// sign issues a serving certificate for the public key and hosts
+func (ca *certAuthority) sign(pub interface{}, hosts []string, validity time.Duration) ([]byte, error) {
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano()),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	// The API server verifies the SAN against the service DNS name or URL host
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, pub, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// newServingKey generates the serving key pair
+func newServingKey() (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// selfSignedSource creates a brand new CA for every issued certificate, so
+// every rotation also rotates the caBundle
+type selfSignedSource struct{}
+
+func (selfSignedSource) issue(_ context.Context, hosts []string, validity time.Duration) (*servingCerts, error) {
+	ca, err := newCertAuthority("k8s-lab-webhook-ca", validity)
+	if err != nil {
+		return nil, err
+	}
+	key, keyPEM, err := newServingKey()
+	if err != nil {
+		return nil, err
+	}
+	certPEM, err := ca.sign(&key.PublicKey, hosts, validity)
+	if err != nil {
+		return nil, err
+	}
+	return &servingCerts{CAPEM: ca.pem, CertPEM: certPEM, KeyPEM: keyPEM}, nil
+}
+
+// csrSource requests serving certificates through the certificates.k8s.io API.
+// It submits and approves the CSR, then acts as the custom signer: it signs
+// approved CSRs for webhookSignerName with its CA and writes status.certificate.
+type csrSource struct {
+	clientset kubernetes.Interface
+	signer    *certAuthority
+}
+
+func newCSRSource(clientset kubernetes.Interface) (*csrSource, error) {
+	// The signer CA outlives the serving certificates it issues
+	signer, err := newCertAuthority("k8s-lab-webhook-signer", 10*365*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	return &csrSource{clientset: clientset, signer: signer}, nil
+}
+
+func (s *csrSource) issue(ctx context.Context, hosts []string, validity time.Duration) (*servingCerts, error) {
+	key, keyPEM, err := newServingKey()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.CertificateRequest{Subject: pkix.Name{CommonName: hosts[0]}}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, err
+	}
+
+	csrClient := s.clientset.CertificatesV1().CertificateSigningRequests()
+	expirationSeconds := int32(validity.Seconds())
+	csr, err := csrClient.Create(ctx, &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "k8s-lab-webhook-"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:           pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}),
+			SignerName:        webhookSignerName,
+			ExpirationSeconds: &expirationSeconds,
+			Usages:            []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageServerAuth},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("create CSR: %w", err)
+	}
+	defer csrClient.Delete(ctx, csr.Name, metav1.DeleteOptions{})
+
+	// Approve (requires the "approve" verb on signers/k8s-lab.io/webhook-serving)
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:    certificatesv1.CertificateApproved,
+		Status:  corev1.ConditionTrue,
+		Reason:  "WebhookSelfApproved",
+		Message: "serving certificate for the k8s-lab webhook",
+	})
+	if csr, err = csrClient.UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("approve CSR: %w", err)
+	}
+
+	// Sign (requires the "sign" verb on the signer) and publish the certificate
+	block, _ := pem.Decode(csr.Spec.Request)
+	parsed, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, err := s.signer.sign(parsed.PublicKey, hosts, validity)
+	if err != nil {
+		return nil, err
+	}
+	csr.Status.Certificate = certPEM
+	if _, err := csrClient.UpdateStatus(ctx, csr, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("publish certificate: %w", err)
+	}
+
+	// Read the issued certificate back from the API like any CSR client would
+	var issued []byte
+	err = wait.PollUntilContextTimeout(ctx, time.Second, 30*time.Second, true, func(ctx context.Context) (bool, error) {
+		current, err := csrClient.Get(ctx, csr.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		issued = current.Status.Certificate
+		return len(issued) > 0, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("wait for certificate: %w", err)
+	}
+	return &servingCerts{CAPEM: s.signer.pem, CertPEM: issued, KeyPEM: keyPEM}, nil
+}
+
+// certRotator serves the current certificate and replaces it before it expires.
+// During a rotation the new CA is added to the caBundle first, then the serving
+// certificate is swapped, so the API server trusts both old and new certificates.
+type certRotator struct {
+	clientset kubernetes.Interface
+	source    certSource
+	hosts     []string
+	validity  time.Duration
+
+	mu       sync.RWMutex
+	current  *tls.Certificate
+	notAfter time.Time
+	bundle   [][]byte // CA PEMs, newest first
+}
+
+// GetCertificate is plugged into tls.Config so rotations need no restart
+func (r *certRotator) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current, nil
+}
+
+// caBundle returns the concatenated CA PEMs trusted for the webhook
+func (r *certRotator) caBundle() []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return bytes.Join(r.bundle, nil)
+}
+
+// rotate issues a new certificate, publishes the CA bundle, then swaps certificates
+func (r *certRotator) rotate(ctx context.Context, publish bool) error {
+	certs, err := r.source.issue(ctx, r.hosts, r.validity)
+	if err != nil {
+		return err
+	}
+	tlsCert, err := certs.tlsCertificate()
+	if err != nil {
+		return err
+	}
+	leaf, err := x509.ParseCertificate(tlsCert.Certificate[0])
+	if err != nil {
+		return err
+	}
+
+	// Keep the previous CA for an overlap window
+	r.mu.Lock()
+	if len(r.bundle) == 0 || !bytes.Equal(r.bundle[0], certs.CAPEM) {
+		r.bundle = append([][]byte{certs.CAPEM}, r.bundle...)
+		if len(r.bundle) > 2 {
+			r.bundle = r.bundle[:2]
+		}
+	}
+	r.mu.Unlock()
+
+	if publish {
+		if err := patchCABundle(ctx, r.clientset, r.caBundle()); err != nil {
+			return fmt.Errorf("patch caBundle: %w", err)
+		}
+	}
+
+	r.mu.Lock()
+	r.current = &tlsCert
+	r.notAfter = leaf.NotAfter
+	r.mu.Unlock()
+	fmt.Printf("[CertRotator] Serving certificate valid until %s\n", leaf.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+// run rotates the certificate when less than rotateBefore of its lifetime remains
+func (r *certRotator) run(ctx context.Context, rotateBefore time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		r.mu.RLock()
+		remaining := time.Until(r.notAfter)
+		r.mu.RUnlock()
+		if remaining > rotateBefore {
+			return
+		}
+		fmt.Printf("[CertRotator] Certificate expires in %v, rotating\n", remaining.Round(time.Second))
+		if err := r.rotate(ctx, true); err != nil {
+			fmt.Printf("[CertRotator] Rotation failed, will retry: %v\n", err)
+		}
+	}, 10*time.Second)
+}
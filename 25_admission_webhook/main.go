@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+// webhookConfigName is the name of both registered webhook configurations
+const webhookConfigName = "k8s-lab-pod-policy"
+
+var (
+	listenAddr       = flag.String("listen", ":8443", "HTTPS listen address")
+	serviceName      = flag.String("service-name", "k8s-lab-webhook", "Service fronting the webhook when running in-cluster")
+	serviceNamespace = flag.String("service-namespace", "k8s-lab", "namespace of that Service")
+	servicePort      = flag.Int("service-port", 443, "port of that Service")
+	webhookURL       = flag.String("url", "", "external base URL reachable by the API server (e.g. https://192.168.1.10:8443); overrides the Service")
+	failurePolicy    = flag.String("failure-policy", "Ignore", "webhook failurePolicy: Ignore or Fail")
+	certSourceName   = flag.String("cert-source", "self-signed", "serving certificate source: self-signed or csr")
+	certValidity     = flag.Duration("cert-validity", 24*time.Hour, "validity of each serving certificate")
+	rotateBefore     = flag.Duration("rotate-before", 8*time.Hour, "rotate the serving certificate when less than this remains")
+	sidecarImage     = flag.String("sidecar-image", "busybox:1.36", "image of the injected sidecar")
+	keepConfig       = flag.Bool("keep-config", false, "do not delete the webhook configurations on shutdown")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "25-admission-webhook")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// servingHosts returns the names the serving certificate must be valid for
+func servingHosts() ([]string, error) {
+	if *webhookURL != "" {
+		u, err := url.Parse(*webhookURL)
+		if err != nil {
+			return nil, err
+		}
+		return []string{u.Hostname()}, nil
+	}
+	svc := *serviceName + "." + *serviceNamespace + ".svc"
+	return []string{svc, svc + ".cluster.local"}, nil
+}
+
+func main() {
+	clientset := createClientSet()
+	// Ctrl-C or SIGTERM stops the rotator and starts the shutdown below
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Step 1: choose where serving certificates come from
+	var source certSource
+	switch *certSourceName {
+	case "self-signed":
+		source = selfSignedSource{}
+	case "csr":
+		csr, err := newCSRSource(clientset)
+		if err != nil {
+			log.Fatalf("Failed to create CSR signer: %v", err)
+		}
+		source = csr
+	default:
+		log.Fatalf("Unknown --cert-source %q", *certSourceName)
+	}
+
+	hosts, err := servingHosts()
+	if err != nil {
+		log.Fatalf("Invalid webhook URL: %v", err)
+	}
+	rotator := &certRotator{clientset: clientset, source: source, hosts: hosts, validity: *certValidity}
+	// The configurations do not exist yet, so the first CA bundle is not patched in
+	if err := rotator.rotate(ctx, false); err != nil {
+		log.Fatalf("Failed to issue serving certificate: %v", err)
+	}
+	fmt.Printf("Issued serving certificate for %v via %s\n", hosts, *certSourceName)
+
+	// Step 2: start the HTTPS server before registering, so the API server
+	// never calls a webhook that is not listening yet
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate", serveAdmission(validatePod))
+	mux.HandleFunc("/mutate", serveAdmission(mutatePod))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	server := &http.Server{
+		Addr:    *listenAddr,
+		Handler: mux,
+		// GetCertificate picks up rotated certificates without a restart
+		TLSConfig: &tls.Config{GetCertificate: rotator.GetCertificate, MinVersion: tls.VersionTLS12},
+	}
+	go func() {
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Webhook server failed: %v", err)
+		}
+	}()
+	fmt.Printf("Webhook server listening on %s\n", *listenAddr)
+
+	// Step 3: register both configurations with the current CA bundle
+	if err := registerMutatingWebhook(ctx, clientset, rotator.caBundle()); err != nil {
+		log.Fatalf("Failed to register mutating webhook: %v", err)
+	}
+	if err := registerValidatingWebhook(ctx, clientset, rotator.caBundle()); err != nil {
+		log.Fatalf("Failed to register validating webhook: %v", err)
+	}
+	fmt.Printf("Registered Mutating/ValidatingWebhookConfiguration %s (failurePolicy=%s)\n", webhookConfigName, *failurePolicy)
+
+	// Step 4: keep the serving certificate fresh
+	go rotator.run(ctx, *rotateBefore)
+
+	// Remove the configurations on exit, otherwise the API server keeps calling a dead endpoint
+	<-ctx.Done()
+	fmt.Println("Shutting down...")
+	if !*keepConfig {
+		if err := unregisterWebhooks(context.Background(), clientset); err != nil {
+			fmt.Printf("Failed to remove webhook configurations: %v\n", err)
+		} else {
+			fmt.Printf("Removed webhook configurations %s\n", webhookConfigName)
+		}
+	}
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	server.Shutdown(shutdownCtx)
+	fmt.Println("Exited cleanly")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// injectedLabel is added to every pod that passes through the mutating webhook
+	injectedLabel = "k8s-lab.io/webhook-injected"
+	// injectSidecarAnnotation opts a pod into the sidecar injection
+	injectSidecarAnnotation = "k8s-lab.io/inject-sidecar"
+	sidecarName             = "k8s-lab-sidecar"
+)
+
+// jsonPatchOp is one RFC 6902 operation
+type jsonPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// escapeJSONPointer escapes "~" and "/" in a JSON pointer segment (RFC 6901)
+func escapeJSONPointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// sidecarContainer is the container injected into opted-in pods
+func sidecarContainer() corev1.Container {
+	return corev1.Container{
+		Name:  sidecarName,
+		Image: *sidecarImage,
+		Args:  []string{"sh", "-c", "while true; do sleep 3600; done"},
+	}
+}
+
+// mutatePod labels every pod and injects a sidecar into pods that ask for it
+func mutatePod(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Kind.Kind != "Pod" {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	var pod corev1.Pod
+	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result:  &metav1.Status{Code: http.StatusBadRequest, Message: err.Error()},
+		}
+	}
+
+	var patch []jsonPatchOp
+
+	// "add" on /metadata/labels/<key> fails when the labels map is missing
+	if pod.Labels == nil {
+		patch = append(patch, jsonPatchOp{Op: "add", Path: "/metadata/labels", Value: map[string]string{}})
+	}
+	patch = append(patch, jsonPatchOp{
+		Op:    "add",
+		Path:  "/metadata/labels/" + escapeJSONPointer(injectedLabel),
+		Value: "true",
+	})
+
+	// Reinvocation or UPDATE must not inject the sidecar twice
+	alreadyInjected := false
+	for _, c := range pod.Spec.Containers {
+		if c.Name == sidecarName {
+			alreadyInjected = true
+		}
+	}
+	if pod.Annotations[injectSidecarAnnotation] == "true" && !alreadyInjected {
+		// "/-" appends to the end of the containers array
+		patch = append(patch, jsonPatchOp{Op: "add", Path: "/spec/containers/-", Value: sidecarContainer()})
+	}
+
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result:  &metav1.Status{Code: http.StatusInternalServerError, Message: err.Error()},
+		}
+	}
+
+	name := pod.Name
+	if name == "" {
+		name = pod.GenerateName + "<generated>"
+	}
+	fmt.Printf("[Webhook] MUTATE %s/%s: %s\n", req.Namespace, name, patchBytes)
+
+	patchType := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patchBytes,
+		PatchType: &patchType,
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// mutate runs mutatePod and applies the returned JSON patch to the pod
+func mutate(t *testing.T, pod *corev1.Pod) *corev1.Pod {
+	t.Helper()
+	req := podRequest(t, pod)
+	resp := mutatePod(req)
+	if !resp.Allowed {
+		t.Fatalf("pod was denied: %v", resp.Result)
+	}
+	patch, err := jsonpatch.DecodePatch(resp.Patch)
+	if err != nil {
+		t.Fatalf("invalid patch %s: %v", resp.Patch, err)
+	}
+	patched, err := patch.Apply(req.Object.Raw)
+	if err != nil {
+		t.Fatalf("apply patch %s: %v", resp.Patch, err)
+	}
+	out := &corev1.Pod{}
+	if err := json.Unmarshal(patched, out); err != nil {
+		t.Fatalf("decode patched pod: %v", err)
+	}
+	return out
+}
+
+func TestMutatePodAddsLabel(t *testing.T) {
+	// No labels map at all: the patch has to create it first
+	pod := mutate(t, &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx:1.27"}}}})
+	if pod.Labels[injectedLabel] != "true" {
+		t.Errorf("labels = %v", pod.Labels)
+	}
+	if len(pod.Spec.Containers) != 1 {
+		t.Errorf("sidecar injected without the annotation")
+	}
+}
+
+func TestMutatePodInjectsSidecarOnce(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      map[string]string{"app": "web"},
+			Annotations: map[string]string{injectSidecarAnnotation: "true"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx:1.27"}}},
+	}
+
+	once := mutate(t, pod)
+	if len(once.Spec.Containers) != 2 || once.Spec.Containers[1].Name != sidecarName {
+		t.Fatalf("containers = %v", once.Spec.Containers)
+	}
+	if once.Labels["app"] != "web" {
+		t.Errorf("existing labels were replaced: %v", once.Labels)
+	}
+	// Reinvocation sees the sidecar and leaves the containers alone
+	if twice := mutate(t, once); len(twice.Spec.Containers) != 2 {
+		t.Errorf("sidecar injected twice: %v", twice.Spec.Containers)
+	}
+}
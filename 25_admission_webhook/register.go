@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// webhookClientConfig points the API server at the webhook, either through an
+// in-cluster Service or a URL reachable from the API server
+func webhookClientConfig(path string, caBundle []byte) admissionregistrationv1.WebhookClientConfig {
+	cfg := admissionregistrationv1.WebhookClientConfig{CABundle: caBundle}
+	if *webhookURL != "" {
+		url := *webhookURL + path
+		cfg.URL = &url
+		return cfg
+	}
+	port := int32(*servicePort)
+	cfg.Service = &admissionregistrationv1.ServiceReference{
+		Namespace: *serviceNamespace,
+		Name:      *serviceName,
+		Path:      &path,
+		Port:      &port,
+	}
+	return cfg
+}
+
+// excludeSystemNamespaces keeps the webhook away from kube-system so a broken
+// webhook cannot block the control plane add-ons
+func excludeSystemNamespaces() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      "kubernetes.io/metadata.name",
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   []string{"kube-system", *serviceNamespace},
+		}},
+	}
+}
+
+// podRules matches pod CREATE and UPDATE requests
+func podRules() []admissionregistrationv1.RuleWithOperations {
+	return []admissionregistrationv1.RuleWithOperations{{
+		Operations: []admissionregistrationv1.OperationType{
+			admissionregistrationv1.Create,
+			admissionregistrationv1.Update,
+		},
+		Rule: admissionregistrationv1.Rule{
+			APIGroups:   []string{""},
+			APIVersions: []string{"v1"},
+			Resources:   []string{"pods"},
+		},
+	}}
+}
+
+// registerValidatingWebhook creates or updates the ValidatingWebhookConfiguration
+// with the CA bundle that signed the serving certificate
+func registerValidatingWebhook(ctx context.Context, clientset kubernetes.Interface, caBundle []byte) error {
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	failurePolicy := admissionregistrationv1.FailurePolicyType(*failurePolicy)
+	timeout := int32(5)
+
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: webhookConfigName},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name:                    "pod-image-policy.k8s-lab.io",
+			ClientConfig:            webhookClientConfig("/validate", caBundle),
+			AdmissionReviewVersions: []string{"v1"},
+			SideEffects:             &sideEffects,
+			FailurePolicy:           &failurePolicy,
+			TimeoutSeconds:          &timeout,
+			NamespaceSelector:       excludeSystemNamespaces(),
+			Rules:                   podRules(),
+		}},
+	}
+
+	client := clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	existing, err := client.Get(ctx, webhookConfigName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, config, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	config.ResourceVersion = existing.ResourceVersion
+	_, err = client.Update(ctx, config, metav1.UpdateOptions{})
+	return err
+}
+
+// registerMutatingWebhook creates or updates the MutatingWebhookConfiguration
+func registerMutatingWebhook(ctx context.Context, clientset kubernetes.Interface, caBundle []byte) error {
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	failurePolicy := admissionregistrationv1.FailurePolicyType(*failurePolicy)
+	// Let other mutating webhooks run again after ours has injected the sidecar
+	reinvocation := admissionregistrationv1.IfNeededReinvocationPolicy
+	timeout := int32(5)
+
+	config := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: webhookConfigName},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{{
+			Name:                    "pod-injector.k8s-lab.io",
+			ClientConfig:            webhookClientConfig("/mutate", caBundle),
+			AdmissionReviewVersions: []string{"v1"},
+			SideEffects:             &sideEffects,
+			FailurePolicy:           &failurePolicy,
+			ReinvocationPolicy:      &reinvocation,
+			TimeoutSeconds:          &timeout,
+			NamespaceSelector:       excludeSystemNamespaces(),
+			Rules:                   podRules(),
+		}},
+	}
+
+	client := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations()
+	existing, err := client.Get(ctx, webhookConfigName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, config, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	config.ResourceVersion = existing.ResourceVersion
+	_, err = client.Update(ctx, config, metav1.UpdateOptions{})
+	return err
+}
+
+// patchCABundle replaces the caBundle of every webhook in both configurations,
+// retrying on conflicts with concurrent writers
+func patchCABundle(ctx context.Context, clientset kubernetes.Interface, caBundle []byte) error {
+	validating := clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		config, err := validating.Get(ctx, webhookConfigName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		for i := range config.Webhooks {
+			config.Webhooks[i].ClientConfig.CABundle = caBundle
+		}
+		_, err = validating.Update(ctx, config, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	mutating := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		config, err := mutating.Get(ctx, webhookConfigName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		for i := range config.Webhooks {
+			config.Webhooks[i].ClientConfig.CABundle = caBundle
+		}
+		_, err = mutating.Update(ctx, config, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// unregisterWebhooks removes both configurations on shutdown
+func unregisterWebhooks(ctx context.Context, clientset kubernetes.Interface) error {
+	err := clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().Delete(ctx, webhookConfigName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	err = clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Delete(ctx, webhookConfigName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
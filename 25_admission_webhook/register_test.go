@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRegisterAndRotateCABundle(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx := context.TODO()
+
+	if err := registerValidatingWebhook(ctx, clientset, []byte("ca-1")); err != nil {
+		t.Fatalf("register validating: %v", err)
+	}
+	if err := registerMutatingWebhook(ctx, clientset, []byte("ca-1")); err != nil {
+		t.Fatalf("register mutating: %v", err)
+	}
+	// Registering again on restart updates instead of failing
+	if err := registerValidatingWebhook(ctx, clientset, []byte("ca-1")); err != nil {
+		t.Fatalf("re-register validating: %v", err)
+	}
+
+	if err := patchCABundle(ctx, clientset, []byte("ca-2")); err != nil {
+		t.Fatalf("patch CA bundle: %v", err)
+	}
+	validating, err := clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, webhookConfigName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get validating: %v", err)
+	}
+	mutating, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, webhookConfigName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get mutating: %v", err)
+	}
+	for _, w := range validating.Webhooks {
+		if !bytes.Equal(w.ClientConfig.CABundle, []byte("ca-2")) {
+			t.Errorf("validating webhook %s still has CA %q", w.Name, w.ClientConfig.CABundle)
+		}
+	}
+	for _, w := range mutating.Webhooks {
+		if !bytes.Equal(w.ClientConfig.CABundle, []byte("ca-2")) {
+			t.Errorf("mutating webhook %s still has CA %q", w.Name, w.ClientConfig.CABundle)
+		}
+	}
+
+	if err := unregisterWebhooks(ctx, clientset); err != nil {
+		t.Fatalf("unregister: %v", err)
+	}
+	// Unregistering twice is harmless
+	if err := unregisterWebhooks(ctx, clientset); err != nil {
+		t.Fatalf("second unregister: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// admitFunc decides on a single AdmissionRequest
+type admitFunc func(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+// serveAdmission decodes the AdmissionReview, calls admit and writes the
+// review back with the response UID matching the request UID
+func serveAdmission(admit admitFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "expected application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var review admissionv1.AdmissionReview
+		if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, fmt.Sprintf("invalid AdmissionReview: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		response := admit(review.Request)
+		response.UID = review.Request.UID
+		review.Response = response
+		review.Request = nil // not needed in the reply
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			fmt.Printf("[Webhook] Failed to write response: %v\n", err)
+		}
+	}
+}
+
+// imageUsesLatest reports images without a tag, or tagged :latest, that are not pinned by digest
+func imageUsesLatest(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	// The tag is after the last ":" following the last "/" (registry ports contain ":")
+	name := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(name, ":")
+	return i == -1 || name[i+1:] == "latest"
+}
+
+// validatePod denies pods that use :latest (or untagged) images
+func validatePod(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Kind.Kind != "Pod" {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	var pod corev1.Pod
+	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result:  &metav1.Status{Code: http.StatusBadRequest, Message: err.Error()},
+		}
+	}
+
+	var violations []string
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, c := range containers {
+		if imageUsesLatest(c.Image) {
+			violations = append(violations, fmt.Sprintf("container %q uses image %q", c.Name, c.Image))
+		}
+	}
+
+	name := pod.Name
+	if name == "" {
+		name = pod.GenerateName + "<generated>"
+	}
+	if len(violations) > 0 {
+		fmt.Printf("[Webhook] DENY %s/%s: %s\n", req.Namespace, name, strings.Join(violations, "; "))
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Code:    http.StatusForbidden,
+				Reason:  metav1.StatusReasonForbidden,
+				Message: "images must be pinned to a tag other than latest: " + strings.Join(violations, "; "),
+			},
+		}
+	}
+	fmt.Printf("[Webhook] ALLOW %s/%s\n", req.Namespace, name)
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// podRequest wraps a pod in an AdmissionRequest as the API server sends it
+func podRequest(t *testing.T, pod *corev1.Pod) *admissionv1.AdmissionRequest {
+	t.Helper()
+	raw, err := json.Marshal(pod)
+	if err != nil {
+		t.Fatalf("marshal pod: %v", err)
+	}
+	return &admissionv1.AdmissionRequest{
+		UID:       types.UID("req-1"),
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Namespace: "default",
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
+
+func TestImageUsesLatest(t *testing.T) {
+	tests := map[string]bool{
+		"nginx":                        true,
+		"nginx:latest":                 true,
+		"nginx:1.27":                   false,
+		"registry.local:5000/app":      true,
+		"registry.local:5000/app:v2":   false,
+		"nginx:latest@sha256:abcdef01": false,
+	}
+	for image, want := range tests {
+		if got := imageUsesLatest(image); got != want {
+			t.Errorf("imageUsesLatest(%q) = %v, want %v", image, got, want)
+		}
+	}
+}
+
+func TestValidatePod(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+		Containers:     []corev1.Container{{Name: "app", Image: "nginx:1.27"}},
+	}}
+	if resp := validatePod(podRequest(t, pod)); resp.Allowed {
+		t.Error("untagged init container image was allowed")
+	}
+
+	pod.Spec.InitContainers[0].Image = "busybox:1.36"
+	if resp := validatePod(podRequest(t, pod)); !resp.Allowed {
+		t.Errorf("pinned images were denied: %v", resp.Result)
+	}
+}
+
+func TestServeAdmissionEchoesUID(t *testing.T) {
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  podRequest(t, &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}}}),
+	}
+	body, _ := json.Marshal(review)
+	req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	serveAdmission(validatePod)(rec, req)
+
+	var got admissionv1.AdmissionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Response == nil || got.Response.UID != "req-1" || got.Response.Allowed {
+		t.Errorf("response = %+v, want a denial for req-1", got.Response)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+const (
+	// ownerUIDIndex indexes ReplicaSets by the UID of their controlling Deployment
+	ownerUIDIndex = "ownerUID"
+	// revisionAnnotation is set by the Deployment controller on every ReplicaSet
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+var (
+	namespace = flag.String("namespace", "", "namespace to prune (empty for all namespaces)")
+	keep      = flag.Int("keep", -1, "old ReplicaSets to keep per Deployment (-1 uses each Deployment's revisionHistoryLimit)")
+	mode      = flag.String("mode", "report", "report (list only), dry-run (server-side dry-run deletes) or delete")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "26-replicaset-pruner")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// ownerUIDIndexFunc indexes a ReplicaSet by its controller's UID
+func ownerUIDIndexFunc(obj interface{}) ([]string, error) {
+	rs, ok := obj.(*appsv1.ReplicaSet)
+	if !ok {
+		return nil, nil
+	}
+	if owner := metav1.GetControllerOfNoCopy(rs); owner != nil {
+		return []string{string(owner.UID)}, nil
+	}
+	return nil, nil
+}
+
+// revision parses the revision annotation (0 if missing)
+func revision(rs *appsv1.ReplicaSet) int64 {
+	v, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// isIdle reports ReplicaSets that are scaled to zero and have no pods left
+func isIdle(rs *appsv1.ReplicaSet) bool {
+	return rs.Spec.Replicas != nil && *rs.Spec.Replicas == 0 &&
+		rs.Status.Replicas == 0 && rs.DeletionTimestamp == nil
+}
+
+// pruneCandidates returns idle ReplicaSets of a Deployment beyond the retention count
+func pruneCandidates(owned []interface{}, retention int) []*appsv1.ReplicaSet {
+	var idle []*appsv1.ReplicaSet
+	var newest int64
+	for _, obj := range owned {
+		rs := obj.(*appsv1.ReplicaSet)
+		if r := revision(rs); r > newest {
+			newest = r
+		}
+		if isIdle(rs) {
+			idle = append(idle, rs)
+		}
+	}
+	// Never touch the current revision, even if the Deployment is scaled to zero
+	filtered := idle[:0]
+	for _, rs := range idle {
+		if revision(rs) != newest {
+			filtered = append(filtered, rs)
+		}
+	}
+	idle = filtered
+
+	// Newest revisions first: those are the ones worth keeping for rollbacks
+	sort.Slice(idle, func(i, j int) bool { return revision(idle[i]) > revision(idle[j]) })
+	if len(idle) <= retention {
+		return nil
+	}
+	return idle[retention:]
+}
+
+func main() {
+	clientset := createClientSet()
+	if *mode != "report" && *mode != "dry-run" && *mode != "delete" {
+		log.Fatalf("Unknown --mode %q", *mode)
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, time.Minute*10,
+		informers.WithNamespace(*namespace))
+	deploymentLister := factory.Apps().V1().Deployments().Lister()
+	rsInformer := factory.Apps().V1().ReplicaSets().Informer()
+	// The ownership index turns "ReplicaSets of this Deployment" into an O(1) lookup
+	rsInformer.AddIndexers(cache.Indexers{ownerUIDIndex: ownerUIDIndexFunc})
+
+	defer factory.Shutdown()
+
+	// Ctrl-C or SIGTERM cancels the context, and with it any request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	factory.Start(ctx.Done())
+
+	fmt.Println("Waiting for cache sync...")
+	factory.WaitForCacheSync(ctx.Done())
+	fmt.Println("Cache sync completed!")
+
+	deployments, err := deploymentLister.List(labels.Everything())
+	if err != nil {
+		log.Fatalf("Failed to list deployments: %v", err)
+	}
+	sort.Slice(deployments, func(i, j int) bool {
+		return deployments[i].Namespace+"/"+deployments[i].Name < deployments[j].Namespace+"/"+deployments[j].Name
+	})
+
+	deleteOpts := metav1.DeleteOptions{}
+	if *mode == "dry-run" {
+		// The API server runs admission and validation but persists nothing
+		deleteOpts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	total, pruned := 0, 0
+	for _, deployment := range deployments {
+		// Interrupted: stop between deletes and report what was done
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted, not pruning the remaining Deployments")
+			break
+		}
+		owned, err := rsInformer.GetIndexer().ByIndex(ownerUIDIndex, string(deployment.UID))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+
+		retention := *keep
+		if retention < 0 {
+			retention = 10 // API default for revisionHistoryLimit
+			if deployment.Spec.RevisionHistoryLimit != nil {
+				retention = int(*deployment.Spec.RevisionHistoryLimit)
+			}
+		}
+
+		candidates := pruneCandidates(owned, retention)
+		if len(candidates) == 0 {
+			continue
+		}
+		fmt.Printf("Deployment %s/%s: %d ReplicaSets, keeping %d old revisions, %d to prune\n",
+			deployment.Namespace, deployment.Name, len(owned), retention, len(candidates))
+
+		for _, rs := range candidates {
+			total++
+			age := time.Since(rs.CreationTimestamp.Time).Round(time.Hour)
+			if *mode == "report" {
+				fmt.Printf("  - %s (revision %d, age %v)\n", rs.Name, revision(rs), age)
+				continue
+			}
+			// Preconditions guard against deleting a ReplicaSet that changed since it was cached
+			uid, rv := rs.UID, rs.ResourceVersion
+			opts := deleteOpts
+			opts.Preconditions = &metav1.Preconditions{UID: &uid, ResourceVersion: &rv}
+			err := clientset.AppsV1().ReplicaSets(rs.Namespace).Delete(ctx, rs.Name, opts)
+			switch {
+			case err == nil:
+				pruned++
+				fmt.Printf("  - %s (revision %d, age %v): deleted (%s)\n", rs.Name, revision(rs), age, *mode)
+			case apierrors.IsConflict(err) || apierrors.IsNotFound(err):
+				fmt.Printf("  - %s: skipped, changed since cached\n", rs.Name)
+			default:
+				fmt.Printf("  - %s: delete failed: %v\n", rs.Name, err)
+			}
+		}
+	}
+
+	switch *mode {
+	case "report":
+		fmt.Printf("\n%d ReplicaSets can be pruned (run with --mode dry-run or --mode delete)\n", total)
+	default:
+		fmt.Printf("\nPruned %d of %d ReplicaSets (mode %s)\n", pruned, total, *mode)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+// newReplicaSet returns a ReplicaSet controlled by the Deployment with ownerUID
+func newReplicaSet(name string, ownerUID types.UID, rev int, replicas int32) *appsv1.ReplicaSet {
+	controller := true
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: map[string]string{revisionAnnotation: fmt.Sprint(rev)},
+		},
+		Spec:   appsv1.ReplicaSetSpec{Replicas: int32Ptr(replicas)},
+		Status: appsv1.ReplicaSetStatus{Replicas: replicas},
+	}
+	if ownerUID != "" {
+		rs.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "apps/v1", Kind: "Deployment", Name: "web", UID: ownerUID, Controller: &controller,
+		}}
+	}
+	return rs
+}
+
+func names(replicaSets []*appsv1.ReplicaSet) []string {
+	var out []string
+	for _, rs := range replicaSets {
+		out = append(out, rs.Name)
+	}
+	return out
+}
+
+func TestOwnerUIDIndex(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newReplicaSet("web-1", "uid-web", 1, 0),
+		newReplicaSet("web-2", "uid-web", 2, 3),
+		newReplicaSet("api-1", "uid-api", 1, 2),
+		newReplicaSet("orphan", "", 1, 1),
+	)
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	rsInformer := factory.Apps().V1().ReplicaSets().Informer()
+	rsInformer.AddIndexers(cache.Indexers{ownerUIDIndex: ownerUIDIndexFunc})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	keys, _ := rsInformer.GetIndexer().IndexKeys(ownerUIDIndex, "uid-web")
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"default/web-1", "default/web-2"}) {
+		t.Errorf("ReplicaSets of uid-web = %v", keys)
+	}
+	// ReplicaSets without a controller are not indexed at all
+	if values := rsInformer.GetIndexer().ListIndexFuncValues(ownerUIDIndex); len(values) != 2 {
+		t.Errorf("index values = %v, want two owners", values)
+	}
+}
+
+func TestPruneCandidates(t *testing.T) {
+	owned := []interface{}{
+		newReplicaSet("web-1", "uid-web", 1, 0),
+		newReplicaSet("web-2", "uid-web", 2, 0),
+		newReplicaSet("web-3", "uid-web", 3, 0),
+		newReplicaSet("web-4", "uid-web", 4, 2), // still running pods
+		newReplicaSet("web-5", "uid-web", 5, 0), // current revision, scaled to zero
+	}
+
+	tests := []struct {
+		retention int
+		want      []string
+	}{
+		{0, []string{"web-3", "web-2", "web-1"}},
+		{1, []string{"web-2", "web-1"}},
+		{3, nil},
+	}
+	for _, tt := range tests {
+		if got := names(pruneCandidates(owned, tt.retention)); !slices.Equal(got, tt.want) {
+			t.Errorf("retention %d: got %v, want %v", tt.retention, got, tt.want)
+		}
+	}
+}
+
+func TestIsIdle(t *testing.T) {
+	scaledDown := newReplicaSet("web-1", "uid-web", 1, 0)
+	if !isIdle(scaledDown) {
+		t.Error("scaled-down ReplicaSet should be idle")
+	}
+	// Scaled to zero but pods are still terminating
+	draining := newReplicaSet("web-2", "uid-web", 2, 0)
+	draining.Status.Replicas = 1
+	if isIdle(draining) {
+		t.Error("ReplicaSet with pods left should not be idle")
+	}
+}
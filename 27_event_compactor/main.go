@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespaces   = flag.String("namespaces", "default", "comma-separated namespaces to compact")
+	maxAge       = flag.Duration("max-age", time.Hour, "delete events last seen longer ago than this (0 disables)")
+	maxPerObject = flag.Int("max-per-object", 20, "keep at most this many events per involved object (0 disables)")
+	interval     = flag.Duration("interval", time.Minute, "how often to sweep")
+	deleteQPS    = flag.Float64("delete-qps", 5, "maximum delete requests per second")
+	deleteBurst  = flag.Int("delete-burst", 10, "delete request burst")
+	dryRun       = flag.Bool("dry-run", false, "only print what would be deleted")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "27-event-compactor")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// lastSeen returns the most recent timestamp recorded on an event
+func lastSeen(event *corev1.Event) time.Time {
+	t := event.CreationTimestamp.Time
+	if event.EventTime.After(t) {
+		t = event.EventTime.Time
+	}
+	if event.LastTimestamp.After(t) {
+		t = event.LastTimestamp.Time
+	}
+	if event.Series != nil && event.Series.LastObservedTime.After(t) {
+		t = event.Series.LastObservedTime.Time
+	}
+	return t
+}
+
+// EventCompactor deletes expired and excess events in a fixed set of namespaces
+type EventCompactor struct {
+	clientset kubernetes.Interface
+	listers   map[string]listersv1.EventLister
+	// limiter throttles deletes so a large backlog does not flood the API server
+	limiter flowcontrol.RateLimiter
+	// maxAge and maxPerObject are the --max-age and --max-per-object limits,
+	// zero disables either
+	maxAge       time.Duration
+	maxPerObject int
+	dryRun       bool
+}
+
+// expired selects events to delete: everything older than c.maxAge, then the
+// oldest events of any involved object above c.maxPerObject
+func (c *EventCompactor) expired(events []*corev1.Event, now time.Time) map[*corev1.Event]string {
+	victims := map[*corev1.Event]string{}
+	byObject := map[types.UID][]*corev1.Event{}
+	for _, event := range events {
+		if c.maxAge > 0 && now.Sub(lastSeen(event)) > c.maxAge {
+			victims[event] = "age"
+			continue
+		}
+		byObject[event.InvolvedObject.UID] = append(byObject[event.InvolvedObject.UID], event)
+	}
+	if c.maxPerObject <= 0 {
+		return victims
+	}
+	for _, objectEvents := range byObject {
+		if len(objectEvents) <= c.maxPerObject {
+			continue
+		}
+		// Newest first, keep the head
+		sort.Slice(objectEvents, func(i, j int) bool {
+			return lastSeen(objectEvents[i]).After(lastSeen(objectEvents[j]))
+		})
+		for _, event := range objectEvents[c.maxPerObject:] {
+			victims[event] = "count"
+		}
+	}
+	return victims
+}
+
+// sweep compacts every watched namespace once
+func (c *EventCompactor) sweep(ctx context.Context) {
+	for ns, lister := range c.listers {
+		events, err := lister.List(labels.Everything())
+		if err != nil {
+			fmt.Printf("[Compactor] Error listing events in %s: %v\n", ns, err)
+			continue
+		}
+		victims := c.expired(events, time.Now())
+		if len(victims) == 0 {
+			continue
+		}
+		fmt.Printf("[Compactor] %s: %d events cached, %d to delete\n", ns, len(events), len(victims))
+
+		deleted := 0
+		for event, reason := range victims {
+			if c.dryRun {
+				fmt.Printf("  - %s (%s %s, %s)\n", event.Name, event.InvolvedObject.Kind, event.InvolvedObject.Name, reason)
+				continue
+			}
+			if err := c.limiter.Wait(ctx); err != nil {
+				return
+			}
+			// The UID precondition avoids deleting a recreated event with the same name
+			uid := event.UID
+			err := c.clientset.CoreV1().Events(ns).Delete(ctx, event.Name, metav1.DeleteOptions{
+				Preconditions: &metav1.Preconditions{UID: &uid},
+			})
+			switch {
+			case err == nil:
+				deleted++
+			case apierrors.IsNotFound(err) || apierrors.IsConflict(err):
+				// Already gone, or recreated under the same name; someone
+				// else's delete is not counted as ours
+			default:
+				fmt.Printf("[Compactor] Failed to delete %s/%s: %v\n", ns, event.Name, err)
+			}
+		}
+		if !c.dryRun {
+			fmt.Printf("[Compactor] %s: deleted %d events\n", ns, deleted)
+		}
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// One namespaced factory per namespace keeps events from the rest of the
+	// cluster out of the cache entirely
+	compactor := &EventCompactor{
+		clientset:    clientset,
+		listers:      map[string]listersv1.EventLister{},
+		limiter:      flowcontrol.NewTokenBucketRateLimiter(float32(*deleteQPS), *deleteBurst),
+		maxAge:       *maxAge,
+		maxPerObject: *maxPerObject,
+		dryRun:       *dryRun,
+	}
+	var factories []informers.SharedInformerFactory
+	for _, ns := range strings.Split(*namespaces, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" {
+			continue
+		}
+		factory := informers.NewSharedInformerFactoryWithOptions(clientset, time.Minute*10, informers.WithNamespace(ns))
+		compactor.listers[ns] = factory.Core().V1().Events().Lister()
+		factories = append(factories, factory)
+	}
+
+	for _, factory := range factories {
+		factory.Start(ctx.Done())
+	}
+
+	fmt.Println("Waiting for cache sync...")
+	for _, factory := range factories {
+		factory.WaitForCacheSync(ctx.Done())
+	}
+	fmt.Println("Cache sync completed!")
+	fmt.Printf("Compacting events in %s (max-age=%v, max-per-object=%d, %v qps)\n", *namespaces, *maxAge, *maxPerObject, *deleteQPS)
+
+	var sweeper wait.Group
+	sweeper.StartWithContext(ctx, func(ctx context.Context) {
+		wait.UntilWithContext(ctx, compactor.sweep, *interval)
+	})
+
+	<-ctx.Done()
+	// A sweep in progress stops at its next rate-limited delete
+	fmt.Println("Shutting down, waiting for the current sweep to stop...")
+	sweeper.Wait()
+	for _, factory := range factories {
+		factory.Shutdown()
+	}
+	fmt.Println("Exited cleanly")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newEvent(name string, object types.UID, seen time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: metav1.NewTime(seen)},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: string(object), UID: object},
+	}
+}
+
+func TestLastSeen(t *testing.T) {
+	event := newEvent("web.1", "pod-web", now.Add(-time.Hour))
+	event.LastTimestamp = metav1.NewTime(now.Add(-time.Minute))
+	if got := lastSeen(event); !got.Equal(now.Add(-time.Minute)) {
+		t.Errorf("lastSeen = %v, want lastTimestamp", got)
+	}
+	// events.k8s.io style series take precedence when newer
+	event.Series = &corev1.EventSeries{LastObservedTime: metav1.NewMicroTime(now)}
+	if got := lastSeen(event); !got.Equal(now) {
+		t.Errorf("lastSeen = %v, want series lastObservedTime", got)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	old := newEvent("web.old", "pod-web", now.Add(-2*time.Hour))
+	var recent []*corev1.Event
+	for i := 0; i < 4; i++ {
+		recent = append(recent, newEvent(fmt.Sprintf("web.%d", i), "pod-web", now.Add(-time.Duration(i)*time.Minute)))
+	}
+	other := newEvent("api.0", "pod-api", now)
+
+	victims := (&EventCompactor{maxAge: time.Hour, maxPerObject: 2}).expired(append([]*corev1.Event{old, other}, recent...), now)
+
+	want := map[*corev1.Event]string{old: "age", recent[2]: "count", recent[3]: "count"}
+	if len(victims) != len(want) {
+		t.Fatalf("got %d victims, want %d", len(victims), len(want))
+	}
+	for event, reason := range want {
+		if victims[event] != reason {
+			t.Errorf("%s: reason %q, want %q", event.Name, victims[event], reason)
+		}
+	}
+}
+
+// newTestCompactor returns a compactor over a synced event cache of clientset
+func newTestCompactor(t *testing.T, clientset *fake.Clientset) *EventCompactor {
+	t.Helper()
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace("default"))
+	compactor := &EventCompactor{
+		clientset: clientset,
+		listers:   map[string]listersv1.EventLister{"default": factory.Core().V1().Events().Lister()},
+		limiter:   flowcontrol.NewFakeAlwaysRateLimiter(),
+		maxAge:    time.Hour,
+	}
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	return compactor
+}
+
+func TestSweepDeletesFromCache(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newEvent("web.old", "pod-web", time.Now().Add(-3*time.Hour)),
+		newEvent("web.new", "pod-web", time.Now()),
+	)
+	compactor := newTestCompactor(t, clientset)
+
+	compactor.sweep(context.TODO())
+
+	events, _ := clientset.CoreV1().Events("default").List(context.TODO(), metav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].Name != "web.new" {
+		t.Errorf("events left = %v, want only web.new", events.Items)
+	}
+}
+
+func TestSweepCountsOnlyItsOwnDeletes(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		newEvent("web.gone", "pod-web", time.Now().Add(-3*time.Hour)),
+		newEvent("web.recreated", "pod-web", time.Now().Add(-3*time.Hour)),
+		newEvent("web.old", "pod-web", time.Now().Add(-3*time.Hour)),
+	)
+	compactor := newTestCompactor(t, clientset)
+	clientset.PrependReactor("delete", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		switch action.(k8stesting.DeleteAction).GetName() {
+		case "web.gone":
+			return true, nil, apierrors.NewNotFound(corev1.Resource("events"), "web.gone")
+		case "web.recreated":
+			return true, nil, apierrors.NewConflict(corev1.Resource("events"), "web.recreated", errors.New("UID precondition failed"))
+		}
+		return false, nil, nil
+	})
+	output := testutil.CaptureOutput(t)
+
+	compactor.sweep(context.TODO())
+
+	testutil.WaitForOutput(t, output, "[Compactor] default: 3 events cached, 3 to delete\n", "[Compactor] default: deleted 1 events\n")
+	if strings.Contains(output(), "Failed to delete") {
+		t.Errorf("NotFound and Conflict reported as failures:\n%s", output())
+	}
+}
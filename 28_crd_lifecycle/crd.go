@@ -0,0 +1,93 @@
+package main
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	crdGroup   = "k8s-lab.io"
+	crdVersion = "v1alpha1"
+	crdKind    = "Website"
+	crdPlural  = "websites"
+	// crdName must be <plural>.<group>
+	crdName = crdPlural + "." + crdGroup
+)
+
+// websiteGVR is what the dynamic client needs to address Website objects
+var websiteGVR = schema.GroupVersionResource{Group: crdGroup, Version: crdVersion, Resource: crdPlural}
+
+func float64Ptr(f float64) *float64 { return &f }
+
+// websiteCRD describes a namespaced Website resource with a structural schema,
+// a status subresource and printer columns for kubectl get
+func websiteCRD() *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: crdName},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: crdGroup,
+			Scope: apiextensionsv1.NamespaceScoped,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind:       crdKind,
+				ListKind:   crdKind + "List",
+				Plural:     crdPlural,
+				Singular:   "website",
+				ShortNames: []string{"ws"},
+			},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:    crdVersion,
+				Served:  true,
+				Storage: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"spec": {
+								Type:     "object",
+								Required: []string{"url"},
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{
+									"url": {
+										Type:    "string",
+										Pattern: `^https?://`,
+									},
+									"replicas": {
+										Type:    "integer",
+										Minimum: float64Ptr(1),
+										Maximum: float64Ptr(10),
+										Default: &apiextensionsv1.JSON{Raw: []byte("1")},
+									},
+								},
+							},
+							"status": {
+								Type: "object",
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{
+									"readyReplicas": {Type: "integer"},
+								},
+							},
+						},
+					},
+				},
+				// With the status subresource, writes to / ignore .status and vice versa
+				Subresources: &apiextensionsv1.CustomResourceSubresources{
+					Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+				},
+				AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+					{Name: "URL", Type: "string", JSONPath: ".spec.url"},
+					{Name: "Replicas", Type: "integer", JSONPath: ".spec.replicas"},
+					{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+				},
+			}},
+		},
+	}
+}
+
+// crdCondition reports whether the CRD has the given condition set to True
+func crdCondition(crd *apiextensionsv1.CustomResourceDefinition, condType apiextensionsv1.CustomResourceDefinitionConditionType) bool {
+	for _, cond := range crd.Status.Conditions {
+		if cond.Type == condType {
+			return cond.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestCRDCondition(t *testing.T) {
+	crd := websiteCRD()
+	if crdCondition(crd, apiextensionsv1.Established) {
+		t.Error("a new CRD cannot be Established")
+	}
+	crd.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{
+		{Type: apiextensionsv1.NamesAccepted, Status: apiextensionsv1.ConditionTrue},
+		{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionFalse},
+	}
+	if !crdCondition(crd, apiextensionsv1.NamesAccepted) || crdCondition(crd, apiextensionsv1.Established) {
+		t.Errorf("conditions misread: %+v", crd.Status.Conditions)
+	}
+}
+
+func TestCreateCRD(t *testing.T) {
+	client := apiextensionsfake.NewSimpleClientset()
+	ctx := context.TODO()
+
+	if _, err := client.ApiextensionsV1().CustomResourceDefinitions().Create(ctx, websiteCRD(), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	crd, err := client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crdName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	// The name of a CRD must be <plural>.<group>
+	if crd.Name != crd.Spec.Names.Plural+"."+crd.Spec.Group {
+		t.Errorf("name %q does not match %s.%s", crd.Name, crd.Spec.Names.Plural, crd.Spec.Group)
+	}
+	storage := 0
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			storage++
+		}
+	}
+	if storage != 1 {
+		t.Errorf("%d storage versions, want exactly one", storage)
+	}
+}
+
+func TestDynamicWebsites(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: crdGroup, Version: crdVersion, Resource: crdPlural}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: crdKind + "List"})
+	ctx := context.TODO()
+
+	if _, err := client.Resource(gvr).Namespace("default").Create(ctx, newWebsite("blog", "https://blog.example.com", 2), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	list, err := client.Resource(gvr).Namespace("default").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.Items) != 1 {
+		t.Fatalf("listed %d Websites, want 1", len(list.Items))
+	}
+	replicas, _, _ := unstructured.NestedInt64(list.Items[0].Object, "spec", "replicas")
+	if replicas != 2 {
+		t.Errorf("replicas = %d, want 2", replicas)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace = flag.String("namespace", "default", "namespace for the Website instances")
+	keep      = flag.Bool("keep", false, "keep the CRD and instances instead of cleaning up")
+)
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "28-crd-lifecycle")
+	return config
+}
+
+// newWebsite builds an unstructured Website instance
+func newWebsite(name, url string, replicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": crdGroup + "/" + crdVersion,
+		"kind":       crdKind,
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"url":      url,
+			"replicas": replicas,
+		},
+	}}
+}
+
+func main() {
+	config := createConfig()
+	// Ctrl-C or SIGTERM cancels the context, and with it any request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// The apiextensions clientset manages CRDs; the dynamic client manages instances
+	extClient, err := apiextensionsclientset.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create apiextensions clientset: %v", err)
+	}
+	dynClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create dynamic client: %v", err)
+	}
+	crds := extClient.ApiextensionsV1().CustomResourceDefinitions()
+
+	// Step 1: create the CRD (or reuse it from a previous --keep run)
+	_, err = crds.Create(ctx, websiteCRD(), metav1.CreateOptions{})
+	switch {
+	case err == nil:
+		fmt.Printf("Created CustomResourceDefinition %s\n", crdName)
+	case apierrors.IsAlreadyExists(err):
+		fmt.Printf("CustomResourceDefinition %s already exists\n", crdName)
+	default:
+		log.Fatalf("Failed to create CRD: %v", err)
+	}
+
+	// Step 2: wait until the names are accepted and the API is served.
+	// Creating instances before Established returns 404s.
+	start := time.Now()
+	err = wait.PollUntilContextTimeout(ctx, 500*time.Millisecond, time.Minute, true, func(ctx context.Context) (bool, error) {
+		crd, err := crds.Get(ctx, crdName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range crd.Status.Conditions {
+			if cond.Type == apiextensionsv1.NamesAccepted && cond.Status == apiextensionsv1.ConditionFalse {
+				return false, fmt.Errorf("names not accepted: %s", cond.Message)
+			}
+		}
+		return crdCondition(crd, apiextensionsv1.Established), nil
+	})
+	if err != nil {
+		log.Fatalf("CRD did not become established: %v", err)
+	}
+	fmt.Printf("CRD established after %v\n", time.Since(start).Round(time.Millisecond))
+
+	// Step 3: create instances with the dynamic client
+	websites := dynClient.Resource(websiteGVR).Namespace(*namespace)
+	for _, w := range []*unstructured.Unstructured{
+		newWebsite("blog", "https://blog.example.com", 2),
+		newWebsite("docs", "https://docs.example.com", 1),
+	} {
+		created, err := websites.Create(ctx, w, metav1.CreateOptions{})
+		if err != nil {
+			log.Fatalf("Failed to create Website %s: %v", w.GetName(), err)
+		}
+		fmt.Printf("Created Website %s/%s\n", created.GetNamespace(), created.GetName())
+	}
+
+	// The OpenAPI schema is enforced by the API server
+	_, err = websites.Create(ctx, newWebsite("invalid", "ftp://example.com", 50), metav1.CreateOptions{})
+	if apierrors.IsInvalid(err) {
+		fmt.Printf("Invalid Website rejected by schema: %v\n", err)
+	} else if err == nil {
+		fmt.Println("Warning: invalid Website was accepted")
+	}
+
+	// Step 4: read them back
+	list, err := websites.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Fatalf("Failed to list Websites: %v", err)
+	}
+	fmt.Printf("\n%-10s %-30s %s\n", "NAME", "URL", "REPLICAS")
+	for _, item := range list.Items {
+		url, _, _ := unstructured.NestedString(item.Object, "spec", "url")
+		replicas, _, _ := unstructured.NestedInt64(item.Object, "spec", "replicas")
+		fmt.Printf("%-10s %-30s %d\n", item.GetName(), url, replicas)
+	}
+	fmt.Println()
+
+	if *keep {
+		fmt.Println("Keeping CRD and instances (--keep)")
+		return
+	}
+
+	// Step 5: clean up. Deleting the CRD also deletes every instance, but doing
+	// it explicitly first shows the normal per-object lifecycle.
+	for _, item := range list.Items {
+		if err := websites.Delete(ctx, item.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			fmt.Printf("Failed to delete Website %s: %v\n", item.GetName(), err)
+			continue
+		}
+		fmt.Printf("Deleted Website %s/%s\n", item.GetNamespace(), item.GetName())
+	}
+	if err := crds.Delete(ctx, crdName, metav1.DeleteOptions{}); err != nil {
+		log.Fatalf("Failed to delete CRD: %v", err)
+	}
+	// The CRD lingers while the API server removes remaining instances (customresourcecleanup finalizer)
+	err = wait.PollUntilContextTimeout(ctx, 500*time.Millisecond, time.Minute, true, func(ctx context.Context) (bool, error) {
+		_, err := crds.Get(ctx, crdName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		log.Fatalf("CRD was not removed: %v", err)
+	}
+	fmt.Printf("Deleted CustomResourceDefinition %s\n", crdName)
+}
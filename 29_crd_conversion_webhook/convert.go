@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// pathAnnotation preserves the URL path of a v1alpha1 object, which v1beta1 has
+// no field for. Conversions must round-trip without loss because the API server
+// may convert an object many times between storage and clients.
+const pathAnnotation = "k8s-lab.io/v1alpha1-path"
+
+// v1alpha1 spec: {url: "https://blog.example.com/home", replicas: 2}
+// v1beta1 spec:  {host: "blog.example.com", tls: true, replicas: 2}
+
+// alphaToBeta splits spec.url into spec.host and spec.tls
+func alphaToBeta(obj *unstructured.Unstructured) error {
+	rawURL, _, _ := unstructured.NestedString(obj.Object, "spec", "url")
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("spec.url: %w", err)
+	}
+	unstructured.RemoveNestedField(obj.Object, "spec", "url")
+	if err := unstructured.SetNestedField(obj.Object, u.Host, "spec", "host"); err != nil {
+		return err
+	}
+	if err := unstructured.SetNestedField(obj.Object, u.Scheme == "https", "spec", "tls"); err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if u.Path != "" {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[pathAnnotation] = u.Path
+	} else {
+		delete(annotations, pathAnnotation)
+	}
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// betaToAlpha joins spec.host, spec.tls and the preserved path back into spec.url
+func betaToAlpha(obj *unstructured.Unstructured) error {
+	host, _, _ := unstructured.NestedString(obj.Object, "spec", "host")
+	tls, _, _ := unstructured.NestedBool(obj.Object, "spec", "tls")
+	u := url.URL{Scheme: "http", Host: host}
+	if tls {
+		u.Scheme = "https"
+	}
+	annotations := obj.GetAnnotations()
+	u.Path = annotations[pathAnnotation]
+	delete(annotations, pathAnnotation)
+	obj.SetAnnotations(annotations)
+
+	unstructured.RemoveNestedField(obj.Object, "spec", "host")
+	unstructured.RemoveNestedField(obj.Object, "spec", "tls")
+	return unstructured.SetNestedField(obj.Object, u.String(), "spec", "url")
+}
+
+// convertObject converts one object to desiredAPIVersion. Only the
+// apiVersion, spec and annotations may change; metadata such as name, UID and
+// labels must be left untouched or the API server rejects the response.
+func convertObject(raw []byte, desiredAPIVersion string) (runtime.RawExtension, error) {
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(raw); err != nil {
+		return runtime.RawExtension{}, err
+	}
+	from := obj.GetAPIVersion()
+	alpha, beta := crdGroup+"/v1alpha1", crdGroup+"/v1beta1"
+
+	var err error
+	switch {
+	case from == desiredAPIVersion:
+		// Nothing to do
+	case from == alpha && desiredAPIVersion == beta:
+		err = alphaToBeta(obj)
+	case from == beta && desiredAPIVersion == alpha:
+		err = betaToAlpha(obj)
+	default:
+		err = fmt.Errorf("unsupported conversion %s -> %s", from, desiredAPIVersion)
+	}
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	obj.SetAPIVersion(desiredAPIVersion)
+	fmt.Printf("[Convert] %s %s: %s -> %s\n", obj.GetKind(), obj.GetName(), from, desiredAPIVersion)
+
+	out, err := obj.MarshalJSON()
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	return runtime.RawExtension{Raw: out}, nil
+}
+
+// serveConversion handles a ConversionReview. A single failure fails the whole
+// request; the API server never accepts a partially converted list.
+func serveConversion(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var review apiextensionsv1.ConversionReview
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, fmt.Sprintf("invalid ConversionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	response := &apiextensionsv1.ConversionResponse{
+		UID:    review.Request.UID,
+		Result: metav1.Status{Status: metav1.StatusSuccess},
+	}
+	for _, in := range review.Request.Objects {
+		out, err := convertObject(in.Raw, review.Request.DesiredAPIVersion)
+		if err != nil {
+			response.ConvertedObjects = nil
+			response.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			break
+		}
+		response.ConvertedObjects = append(response.ConvertedObjects, out)
+	}
+	review.Response = response
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		fmt.Printf("[Convert] Failed to write response: %v\n", err)
+	}
+}
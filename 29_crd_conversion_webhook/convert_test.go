@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func alphaSite(url string) []byte {
+	raw, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": crdGroup + "/v1alpha1",
+		"kind":       "Site",
+		"metadata":   map[string]interface{}{"name": "blog", "namespace": "default", "labels": map[string]interface{}{"team": "web"}},
+		"spec":       map[string]interface{}{"url": url, "replicas": int64(2)},
+	})
+	return raw
+}
+
+func decode(t *testing.T, raw runtime.RawExtension) *unstructured.Unstructured {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(raw.Raw); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return obj
+}
+
+func TestConvertObjectRoundTrip(t *testing.T) {
+	for _, url := range []string{"https://blog.example.com/home", "http://blog.example.com"} {
+		beta, err := convertObject(alphaSite(url), crdGroup+"/v1beta1")
+		if err != nil {
+			t.Fatalf("%s to v1beta1: %v", url, err)
+		}
+		obj := decode(t, beta)
+		host, _, _ := unstructured.NestedString(obj.Object, "spec", "host")
+		if host != "blog.example.com" {
+			t.Errorf("%s: host = %q", url, host)
+		}
+		// Metadata other than annotations must survive untouched
+		if obj.GetLabels()["team"] != "web" || obj.GetName() != "blog" {
+			t.Errorf("%s: metadata changed: %v", url, obj.GetLabels())
+		}
+
+		alpha, err := convertObject(beta.Raw, crdGroup+"/v1alpha1")
+		if err != nil {
+			t.Fatalf("%s back to v1alpha1: %v", url, err)
+		}
+		obj = decode(t, alpha)
+		if got, _, _ := unstructured.NestedString(obj.Object, "spec", "url"); got != url {
+			t.Errorf("round trip changed url: %q -> %q", url, got)
+		}
+		if _, ok := obj.GetAnnotations()[pathAnnotation]; ok {
+			t.Errorf("%s: path annotation leaked into v1alpha1", url)
+		}
+	}
+}
+
+func TestServeConversionFailsWholeRequest(t *testing.T) {
+	review := apiextensionsv1.ConversionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1", Kind: "ConversionReview"},
+		Request: &apiextensionsv1.ConversionRequest{
+			UID:               "req-1",
+			DesiredAPIVersion: crdGroup + "/v2",
+			Objects:           []runtime.RawExtension{{Raw: alphaSite("https://blog.example.com")}},
+		},
+	}
+	body, _ := json.Marshal(review)
+	rec := httptest.NewRecorder()
+	serveConversion(rec, httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(body)))
+
+	var got apiextensionsv1.ConversionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Response == nil || got.Response.UID != "req-1" {
+		t.Fatalf("response = %+v", got.Response)
+	}
+	if got.Response.Result.Status != metav1.StatusFailure || len(got.Response.ConvertedObjects) != 0 {
+		t.Errorf("unsupported version: status %q with %d objects", got.Response.Result.Status, len(got.Response.ConvertedObjects))
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	crdGroup  = "k8s-lab.io"
+	crdKind   = "Site"
+	crdPlural = "sites"
+	crdName   = crdPlural + "." + crdGroup
+)
+
+// siteGVR returns the resource for one served version
+func siteGVR(version string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: crdGroup, Version: version, Resource: crdPlural}
+}
+
+// replicasSchema is shared by both versions
+var replicasSchema = apiextensionsv1.JSONSchemaProps{Type: "integer", Minimum: func() *float64 { f := 1.0; return &f }()}
+
+// siteCRD serves v1alpha1 and v1beta1 and stores v1beta1. Every read or write
+// in the other version goes through the conversion webhook.
+func siteCRD(clientConfig *apiextensionsv1.WebhookClientConfig) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: crdName},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: crdGroup,
+			Scope: apiextensionsv1.NamespaceScoped,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind:     crdKind,
+				ListKind: crdKind + "List",
+				Plural:   crdPlural,
+				Singular: "site",
+			},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    "v1alpha1",
+					Served:  true,
+					Storage: false,
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"spec": {
+									Type:     "object",
+									Required: []string{"url"},
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{
+										"url":      {Type: "string"},
+										"replicas": replicasSchema,
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					Name:    "v1beta1",
+					Served:  true,
+					Storage: true,
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"spec": {
+									Type:     "object",
+									Required: []string{"host"},
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{
+										"host":     {Type: "string"},
+										"tls":      {Type: "boolean"},
+										"replicas": replicasSchema,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			Conversion: &apiextensionsv1.CustomResourceConversion{
+				Strategy: apiextensionsv1.WebhookConverter,
+				Webhook: &apiextensionsv1.WebhookConversion{
+					ClientConfig:             clientConfig,
+					ConversionReviewVersions: []string{"v1"},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	listenAddr       = flag.String("listen", ":8443", "HTTPS listen address")
+	serviceName      = flag.String("service-name", "k8s-lab-conversion", "Service fronting the webhook when running in-cluster")
+	serviceNamespace = flag.String("service-namespace", "k8s-lab", "namespace of that Service")
+	servicePort      = flag.Int("service-port", 443, "port of that Service")
+	webhookURL       = flag.String("url", "", "external base URL reachable by the API server (e.g. https://192.168.1.10:8443); overrides the Service")
+	namespace        = flag.String("namespace", "default", "namespace for the Site instances")
+	keep             = flag.Bool("keep", false, "keep the CRD, instances and webhook running")
+)
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "29-crd-conversion-webhook")
+	return config
+}
+
+// webhookClientConfig points the API server at /convert, either through an
+// in-cluster Service or a URL reachable from the API server
+func webhookClientConfig() (*apiextensionsv1.WebhookClientConfig, []string, error) {
+	cfg := &apiextensionsv1.WebhookClientConfig{}
+	if *webhookURL != "" {
+		u, err := url.Parse(*webhookURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		full := *webhookURL + "/convert"
+		cfg.URL = &full
+		return cfg, []string{u.Hostname()}, nil
+	}
+	path := "/convert"
+	port := int32(*servicePort)
+	cfg.Service = &apiextensionsv1.ServiceReference{
+		Namespace: *serviceNamespace,
+		Name:      *serviceName,
+		Path:      &path,
+		Port:      &port,
+	}
+	svc := *serviceName + "." + *serviceNamespace + ".svc"
+	return cfg, []string{svc, svc + ".cluster.local"}, nil
+}
+
+// watchVersion prints every event a client watching in the given version receives
+func watchVersion(ctx context.Context, client dynamic.Interface, version string) {
+	w, err := client.Resource(siteGVR(version)).Namespace(*namespace).Watch(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Printf("[Watch %s] Failed to watch: %v\n", version, err)
+		return
+	}
+	defer w.Stop()
+	for event := range w.ResultChan() {
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+		fmt.Printf("[Watch %s] %s %s apiVersion=%s spec=%v\n", version, event.Type, obj.GetName(), obj.GetAPIVersion(), spec)
+	}
+}
+
+func main() {
+	config := createConfig()
+	// Ctrl-C or SIGTERM stops the version watches and ends --keep
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	extClient, err := apiextensionsclientset.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create apiextensions clientset: %v", err)
+	}
+	dynClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create dynamic client: %v", err)
+	}
+
+	// Step 1: serve /convert over HTTPS
+	clientConfig, hosts, err := webhookClientConfig()
+	if err != nil {
+		log.Fatalf("Invalid webhook URL: %v", err)
+	}
+	cert, caPEM, err := selfSignedServingCert(hosts, 24*time.Hour)
+	if err != nil {
+		log.Fatalf("Failed to create serving certificate: %v", err)
+	}
+	clientConfig.CABundle = caPEM
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/convert", serveConversion)
+	server := &http.Server{
+		Addr:      *listenAddr,
+		Handler:   mux,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+	}
+	go func() {
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Conversion webhook failed: %v", err)
+		}
+	}()
+	fmt.Printf("Conversion webhook listening on %s for %v\n", *listenAddr, hosts)
+
+	// Step 2: create the multi-version CRD and wait until it is established
+	crds := extClient.ApiextensionsV1().CustomResourceDefinitions()
+	if _, err := crds.Create(ctx, siteCRD(clientConfig), metav1.CreateOptions{}); err != nil {
+		log.Fatalf("Failed to create CRD: %v", err)
+	}
+	err = wait.PollUntilContextTimeout(ctx, 500*time.Millisecond, time.Minute, true, func(ctx context.Context) (bool, error) {
+		crd, err := crds.Get(ctx, crdName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range crd.Status.Conditions {
+			if cond.Type == apiextensionsv1.Established {
+				return cond.Status == apiextensionsv1.ConditionTrue, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		log.Fatalf("CRD did not become established: %v", err)
+	}
+	fmt.Printf("Created CRD %s (served: v1alpha1, v1beta1; storage: v1beta1)\n\n", crdName)
+
+	// Step 3: one watcher per version; each receives objects in its own version
+	go watchVersion(ctx, dynClient, "v1alpha1")
+	go watchVersion(ctx, dynClient, "v1beta1")
+	time.Sleep(time.Second)
+
+	// Step 4: write in v1alpha1, read and update in v1beta1
+	alpha := dynClient.Resource(siteGVR("v1alpha1")).Namespace(*namespace)
+	beta := dynClient.Resource(siteGVR("v1beta1")).Namespace(*namespace)
+
+	site := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": crdGroup + "/v1alpha1",
+		"kind":       crdKind,
+		"metadata":   map[string]interface{}{"name": "blog"},
+		"spec":       map[string]interface{}{"url": "https://blog.example.com/home", "replicas": int64(2)},
+	}}
+	if _, err := alpha.Create(ctx, site, metav1.CreateOptions{}); err != nil {
+		log.Fatalf("Failed to create Site: %v", err)
+	}
+	time.Sleep(time.Second)
+
+	stored, err := beta.Get(ctx, "blog", metav1.GetOptions{})
+	if err != nil {
+		log.Fatalf("Failed to get Site as v1beta1: %v", err)
+	}
+	if err := unstructured.SetNestedField(stored.Object, "www.example.com", "spec", "host"); err != nil {
+		log.Fatalf("Failed to edit Site: %v", err)
+	}
+	if _, err := beta.Update(ctx, stored, metav1.UpdateOptions{}); err != nil {
+		log.Fatalf("Failed to update Site as v1beta1: %v", err)
+	}
+	time.Sleep(time.Second)
+
+	back, err := alpha.Get(ctx, "blog", metav1.GetOptions{})
+	if err != nil {
+		log.Fatalf("Failed to get Site as v1alpha1: %v", err)
+	}
+	siteURL, _, _ := unstructured.NestedString(back.Object, "spec", "url")
+	fmt.Printf("\nRead back as v1alpha1: spec.url=%s (path survived the round trip)\n\n", siteURL)
+
+	if *keep {
+		fmt.Println("Keeping CRD and webhook running (--keep), press Ctrl+C to exit")
+		<-ctx.Done()
+		return
+	}
+
+	// Step 5: clean up while the webhook is still up, deletes of non-storage
+	// versions need conversion too
+	if err := alpha.Delete(ctx, "blog", metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		fmt.Printf("Failed to delete Site: %v\n", err)
+	}
+	time.Sleep(time.Second)
+	if err := crds.Delete(ctx, crdName, metav1.DeleteOptions{}); err != nil {
+		fmt.Printf("Failed to delete CRD: %v\n", err)
+	} else {
+		fmt.Printf("Deleted CRD %s\n", crdName)
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	server.Shutdown(shutdownCtx)
+}
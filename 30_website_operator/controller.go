@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+const (
+	// controllerName identifies the operator in events and in the managed-by
+	// label of its children
+	controllerName = "website-operator"
+	// managedByLabel marks every child object, and scopes the child informers
+	managedByLabel = "app.kubernetes.io/managed-by"
+	// websiteLabel selects the pods of one Website
+	websiteLabel = "k8s-lab.io/website"
+	// finalizerName blocks deletion until the operator has cleaned up
+	finalizerName = "k8s-lab.io/website-cleanup"
+)
+
+// fieldManager identifies the operator in managedFields
+var fieldManager = clientid.FieldManager("30-website-operator")
+
+// WebsiteController reconciles Website objects into a Deployment, a Service and an optional Ingress
+type WebsiteController struct {
+	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
+	// gvr is the Website version this controller watches and writes. During a
+	// migration old and new controllers can run side by side on different versions.
+	gvr schema.GroupVersionResource
+	// shard limits reconciles to the Websites this replica owns
+	shard shard
+
+	// websites caches Website objects as unstructured
+	websites dynamicinformer.DynamicSharedInformerFactory
+	// children caches only objects carrying managedByLabel
+	children informers.SharedInformerFactory
+
+	recorder record.EventRecorder
+	queue    workqueue.TypedRateLimitingInterface[string]
+}
+
+func NewWebsiteController(clientset kubernetes.Interface, dynamicClient dynamic.Interface, version string, shard shard) *WebsiteController {
+	c := &WebsiteController{
+		clientset: clientset,
+		dynamic:   dynamicClient,
+		gvr:       websiteGVR(version),
+		shard:     shard,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "website-operator"},
+		),
+	}
+
+	c.websites = dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, time.Minute*10)
+	c.children = informers.NewSharedInformerFactoryWithOptions(clientset, time.Minute*10,
+		informers.WithTweakListOptions(func(lo *metav1.ListOptions) {
+			lo.LabelSelector = managedByLabel + "=" + controllerName
+		}))
+
+	// Events show up in kubectl describe website <name>
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	c.recorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerName})
+
+	// Every replica caches all Websites (a UID hash cannot be expressed as a
+	// server-side selector), but only enqueues the ones in its shard
+	c.websites.ForResource(c.gvr).Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: c.ownsObject,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.enqueue,
+			UpdateFunc: func(oldObj, newObj interface{}) { c.enqueue(newObj) },
+			DeleteFunc: c.enqueue,
+		},
+	})
+
+	// Changes to children (including manual edits and deletions) re-reconcile the owner
+	childHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueOwner,
+		UpdateFunc: func(oldObj, newObj interface{}) { c.enqueueOwner(newObj) },
+		DeleteFunc: c.enqueueOwner,
+	}
+	c.children.Apps().V1().Deployments().Informer().AddEventHandler(childHandler)
+	c.children.Core().V1().Services().Informer().AddEventHandler(childHandler)
+	c.children.Networking().V1().Ingresses().Informer().AddEventHandler(childHandler)
+
+	return c
+}
+
+// enqueue adds the namespace/name key of a Website
+func (c *WebsiteController) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// ownsObject reports whether a Website belongs to this replica's shard
+func (c *WebsiteController) ownsObject(obj interface{}) bool {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	website, ok := obj.(metav1.Object)
+	return ok && c.shard.owns(website.GetUID())
+}
+
+// enqueueOwner maps a child object to the Website that controls it
+func (c *WebsiteController) enqueueOwner(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	child, ok := obj.(metav1.Object)
+	if !ok {
+		return
+	}
+	owner := metav1.GetControllerOf(child)
+	if owner == nil || owner.Kind != crdKind {
+		return
+	}
+	// Owner references may name either version, depending on which controller wrote them
+	if gv, err := schema.ParseGroupVersion(owner.APIVersion); err != nil || gv.Group != crdGroup {
+		return
+	}
+	// The owner's UID is in the reference, so children are sharded without a lookup
+	if !c.shard.owns(owner.UID) {
+		return
+	}
+	c.queue.Add(child.GetNamespace() + "/" + owner.Name)
+}
+
+// Run starts the informers and workers and blocks until ctx is cancelled
+func (c *WebsiteController) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+
+	c.websites.Start(ctx.Done())
+	c.children.Start(ctx.Done())
+
+	fmt.Println("Waiting for cache sync...")
+	c.websites.WaitForCacheSync(ctx.Done())
+	c.children.WaitForCacheSync(ctx.Done())
+	fmt.Println("Cache sync completed!")
+
+	// The drain below still works through the queue after ctx is cancelled,
+	// so the workers' requests keep ctx's values but not its cancellation
+	work := context.WithoutCancel(ctx)
+	for i := 0; i < workers; i++ {
+		go wait.Until(func() { c.runWorker(work) }, time.Second, ctx.Done())
+	}
+	<-ctx.Done()
+
+	// Let the workers finish what is queued, then stop the informers
+	fmt.Println("Shutting down, draining the workqueue...")
+	c.queue.ShutDownWithDrain()
+	c.websites.Shutdown()
+	c.children.Shutdown()
+	fmt.Println("Exited cleanly")
+}
+
+func (c *WebsiteController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *WebsiteController) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.reconcile(ctx, key); err != nil {
+		fmt.Printf("[Website] Failed to reconcile %s, requeuing: %v\n", key, err)
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// newTestController builds a controller over fake clients without starting it
+func newTestController(t *testing.T, mine shard, objects ...runtime.Object) *WebsiteController {
+	t.Helper()
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			websiteGVR(v1alpha1): crdKind + "List",
+			websiteGVR(v1beta1):  crdKind + "List",
+		})
+	c := NewWebsiteController(fake.NewSimpleClientset(objects...), dynamicClient, v1beta1, mine)
+	t.Cleanup(c.queue.ShutDown)
+	return c
+}
+
+// child returns a Deployment controlled by the named Website
+func child(name string, ownerUID types.UID, apiVersion string) *appsv1.Deployment {
+	controller := true
+	return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: "default",
+		Labels:    map[string]string{managedByLabel: controllerName, websiteLabel: name},
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: apiVersion, Kind: crdKind, Name: name, UID: ownerUID, Controller: &controller,
+		}},
+	}}
+}
+
+func TestEnqueueOwner(t *testing.T) {
+	c := newTestController(t, shard{index: 0, count: 1})
+
+	c.enqueueOwner(child("blog", "uid-blog", crdGroup+"/"+v1beta1))
+	// References written by a v1alpha1 controller map to the same Website
+	c.enqueueOwner(child("shop", "uid-shop", crdGroup+"/"+v1alpha1))
+	// Tombstones from missed deletions are unwrapped
+	c.enqueueOwner(cache.DeletedFinalStateUnknown{Key: "default/docs", Obj: child("docs", "uid-docs", crdGroup+"/"+v1beta1)})
+	// Objects owned by something else are ignored
+	c.enqueueOwner(child("other", "uid-other", "example.com/v1"))
+
+	got := map[string]bool{}
+	for c.queue.Len() > 0 {
+		key, _ := c.queue.Get()
+		got[key] = true
+		c.queue.Done(key)
+	}
+	for _, want := range []string{"default/blog", "default/shop", "default/docs"} {
+		if !got[want] {
+			t.Errorf("%s was not enqueued", want)
+		}
+	}
+	if len(got) != 3 {
+		t.Errorf("queued %v, want exactly three Websites", got)
+	}
+}
+
+func TestEnqueueOwnerRespectsShard(t *testing.T) {
+	owner := types.UID("uid-blog")
+	mine := shard{index: shardFor(owner, 3), count: 3}
+	other := shard{index: (mine.index + 1) % 3, count: 3}
+
+	owning := newTestController(t, mine)
+	owning.enqueueOwner(child("blog", owner, crdGroup+"/"+v1beta1))
+	if owning.queue.Len() != 1 {
+		t.Errorf("owning shard queued %d keys, want 1", owning.queue.Len())
+	}
+
+	notOwning := newTestController(t, other)
+	notOwning.enqueueOwner(child("blog", owner, crdGroup+"/"+v1beta1))
+	if notOwning.queue.Len() != 0 {
+		t.Errorf("other shard queued %d keys, want 0", notOwning.queue.Len())
+	}
+}
+
+func TestChildInformerEnqueuesOwners(t *testing.T) {
+	unmanaged := child("legacy", "uid-legacy", crdGroup+"/"+v1beta1)
+	unmanaged.Labels = nil
+	c := newTestController(t, shard{index: 0, count: 1},
+		child("blog", "uid-blog", crdGroup+"/"+v1beta1),
+		unmanaged,
+	)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c.children.Start(stopCh)
+	c.children.WaitForCacheSync(stopCh)
+
+	// Only children carrying the managed-by label reach the cache and the handler
+	deployments, _ := c.children.Apps().V1().Deployments().Lister().List(labels.Everything())
+	if len(deployments) != 1 {
+		t.Errorf("cached %d Deployments, want 1", len(deployments))
+	}
+	if c.queue.Len() != 1 {
+		t.Fatalf("queue length = %d, want 1", c.queue.Len())
+	}
+	if key, _ := c.queue.Get(); key != "default/blog" {
+		t.Errorf("queued %q, want default/blog", key)
+	}
+}
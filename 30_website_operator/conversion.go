@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ingressClassAnnotation keeps spec.ingress.className when a v1beta1 object is
+// read as v1alpha1, which has no such field. Without it, a v1alpha1 client
+// doing read-modify-write would silently drop the class.
+const ingressClassAnnotation = "k8s-lab.io/v1beta1-ingress-class"
+
+// v1alpha1 spec: {image, replicas, port, host}
+// v1beta1 spec:  {image, replicas, port, ingress: {host, className}}
+
+// alphaToBeta moves spec.host into spec.ingress
+func alphaToBeta(obj *unstructured.Unstructured) error {
+	host, _, _ := unstructured.NestedString(obj.Object, "spec", "host")
+	unstructured.RemoveNestedField(obj.Object, "spec", "host")
+
+	annotations := obj.GetAnnotations()
+	className := annotations[ingressClassAnnotation]
+	delete(annotations, ingressClassAnnotation)
+	obj.SetAnnotations(annotations)
+
+	if host == "" {
+		return nil
+	}
+	ingress := map[string]interface{}{"host": host}
+	if className != "" {
+		ingress["className"] = className
+	}
+	return unstructured.SetNestedMap(obj.Object, ingress, "spec", "ingress")
+}
+
+// betaToAlpha flattens spec.ingress back into spec.host
+func betaToAlpha(obj *unstructured.Unstructured) error {
+	host, _, _ := unstructured.NestedString(obj.Object, "spec", "ingress", "host")
+	className, _, _ := unstructured.NestedString(obj.Object, "spec", "ingress", "className")
+	unstructured.RemoveNestedField(obj.Object, "spec", "ingress")
+
+	if className != "" {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[ingressClassAnnotation] = className
+		obj.SetAnnotations(annotations)
+	}
+	if host == "" {
+		return nil
+	}
+	return unstructured.SetNestedField(obj.Object, host, "spec", "host")
+}
+
+// convertWebsite converts obj in place to desiredAPIVersion. Only apiVersion,
+// spec and annotations change; the API server rejects conversions that touch
+// other metadata.
+func convertWebsite(obj *unstructured.Unstructured, desiredAPIVersion string) error {
+	from := obj.GetAPIVersion()
+	alpha := websiteGVR(v1alpha1).GroupVersion().String()
+	beta := websiteGVR(v1beta1).GroupVersion().String()
+
+	var err error
+	switch {
+	case from == desiredAPIVersion:
+		return nil
+	case from == alpha && desiredAPIVersion == beta:
+		err = alphaToBeta(obj)
+	case from == beta && desiredAPIVersion == alpha:
+		err = betaToAlpha(obj)
+	default:
+		err = fmt.Errorf("unsupported conversion %s -> %s", from, desiredAPIVersion)
+	}
+	if err != nil {
+		return err
+	}
+	obj.SetAPIVersion(desiredAPIVersion)
+	return nil
+}
+
+// serveConversion handles a ConversionReview from the API server. A single
+// failure fails the whole request.
+func serveConversion(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var review apiextensionsv1.ConversionReview
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, fmt.Sprintf("invalid ConversionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	response := &apiextensionsv1.ConversionResponse{
+		UID:    review.Request.UID,
+		Result: metav1.Status{Status: metav1.StatusSuccess},
+	}
+	for _, in := range review.Request.Objects {
+		obj := &unstructured.Unstructured{}
+		err := obj.UnmarshalJSON(in.Raw)
+		if err == nil {
+			err = convertWebsite(obj, review.Request.DesiredAPIVersion)
+		}
+		var out []byte
+		if err == nil {
+			out, err = obj.MarshalJSON()
+		}
+		if err != nil {
+			response.ConvertedObjects = nil
+			response.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			break
+		}
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: out})
+	}
+	review.Response = response
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		fmt.Printf("[Conversion] Failed to write response: %v\n", err)
+	}
+}
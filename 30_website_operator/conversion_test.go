@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newUnstructuredWebsite(version string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": crdGroup + "/" + version,
+		"kind":       crdKind,
+		"metadata":   map[string]interface{}{"name": "blog", "namespace": "default"},
+		"spec":       spec,
+	}}
+}
+
+func TestConvertAlphaToBeta(t *testing.T) {
+	obj := newUnstructuredWebsite(v1alpha1, map[string]interface{}{"image": "nginx", "host": "blog.example.com"})
+
+	if err := convertWebsite(obj, crdGroup+"/"+v1beta1); err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	if obj.GetAPIVersion() != crdGroup+"/"+v1beta1 {
+		t.Errorf("apiVersion = %s", obj.GetAPIVersion())
+	}
+	host, _, _ := unstructured.NestedString(obj.Object, "spec", "ingress", "host")
+	if host != "blog.example.com" {
+		t.Errorf("spec.ingress.host = %q", host)
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "host"); found {
+		t.Error("spec.host was not removed")
+	}
+}
+
+func TestConvertRoundTripKeepsIngressClass(t *testing.T) {
+	obj := newUnstructuredWebsite(v1beta1, map[string]interface{}{
+		"image":   "nginx",
+		"ingress": map[string]interface{}{"host": "blog.example.com", "className": "nginx"},
+	})
+
+	if err := convertWebsite(obj, crdGroup+"/"+v1alpha1); err != nil {
+		t.Fatalf("convert to v1alpha1: %v", err)
+	}
+	// v1alpha1 has no className field, so it rides along in an annotation
+	if obj.GetAnnotations()[ingressClassAnnotation] != "nginx" {
+		t.Errorf("annotations = %v", obj.GetAnnotations())
+	}
+	if err := convertWebsite(obj, crdGroup+"/"+v1beta1); err != nil {
+		t.Fatalf("convert back to v1beta1: %v", err)
+	}
+	className, _, _ := unstructured.NestedString(obj.Object, "spec", "ingress", "className")
+	if className != "nginx" {
+		t.Errorf("className lost in round trip: %q", className)
+	}
+	if _, ok := obj.GetAnnotations()[ingressClassAnnotation]; ok {
+		t.Error("annotation leaked into v1beta1")
+	}
+}
+
+func TestConvertUnsupportedVersion(t *testing.T) {
+	obj := newUnstructuredWebsite(v1beta1, map[string]interface{}{"image": "nginx"})
+	if err := convertWebsite(obj, crdGroup+"/v2"); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
+
+func TestWebsiteFromUnstructuredAcceptsBothVersions(t *testing.T) {
+	for _, obj := range []*unstructured.Unstructured{
+		newUnstructuredWebsite(v1alpha1, map[string]interface{}{"image": "nginx", "host": "blog.example.com"}),
+		newUnstructuredWebsite(v1beta1, map[string]interface{}{"image": "nginx", "ingress": map[string]interface{}{"host": "blog.example.com"}}),
+	} {
+		website, err := websiteFromUnstructured(obj)
+		if err != nil {
+			t.Fatalf("%s: %v", obj.GetAPIVersion(), err)
+		}
+		if website.host() != "blog.example.com" {
+			t.Errorf("%s: host = %q", obj.GetAPIVersion(), website.host())
+		}
+	}
+}
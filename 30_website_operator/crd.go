@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func int64Ptr(i int64) *int64       { return &i }
+func float64Ptr(f float64) *float64 { return &f }
+
+// conditionSchema matches metav1.Condition
+var conditionSchema = apiextensionsv1.JSONSchemaProps{
+	Type:     "object",
+	Required: []string{"type", "status", "lastTransitionTime", "reason", "message"},
+	Properties: map[string]apiextensionsv1.JSONSchemaProps{
+		"type":               {Type: "string"},
+		"status":             {Type: "string", Enum: []apiextensionsv1.JSON{{Raw: []byte(`"True"`)}, {Raw: []byte(`"False"`)}, {Raw: []byte(`"Unknown"`)}}},
+		"observedGeneration": {Type: "integer", Format: "int64"},
+		"lastTransitionTime": {Type: "string", Format: "date-time"},
+		"reason":             {Type: "string"},
+		"message":            {Type: "string"},
+	},
+}
+
+// specSchema returns the spec schema of one version
+func specSchema(version string) apiextensionsv1.JSONSchemaProps {
+	spec := apiextensionsv1.JSONSchemaProps{
+		Type:     "object",
+		Required: []string{"image"},
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"image":    {Type: "string", MinLength: int64Ptr(1)},
+			"replicas": {Type: "integer", Format: "int32", Minimum: float64Ptr(0), Default: &apiextensionsv1.JSON{Raw: []byte("1")}},
+			"port":     {Type: "integer", Format: "int32", Minimum: float64Ptr(1), Maximum: float64Ptr(65535), Default: &apiextensionsv1.JSON{Raw: []byte("80")}},
+		},
+	}
+	switch version {
+	case v1alpha1:
+		spec.Properties["host"] = apiextensionsv1.JSONSchemaProps{Type: "string"}
+	case v1beta1:
+		spec.Properties["ingress"] = apiextensionsv1.JSONSchemaProps{
+			Type:     "object",
+			Required: []string{"host"},
+			Properties: map[string]apiextensionsv1.JSONSchemaProps{
+				"host":      {Type: "string", MinLength: int64Ptr(1)},
+				"className": {Type: "string"},
+			},
+		}
+	}
+	return spec
+}
+
+// websiteVersion describes one served version; v1beta1 is the storage version
+func websiteVersion(version string) apiextensionsv1.CustomResourceDefinitionVersion {
+	listMap := "map"
+	hostPath := ".spec.host"
+	if version == v1beta1 {
+		hostPath = ".spec.ingress.host"
+	}
+	return apiextensionsv1.CustomResourceDefinitionVersion{
+		Name:    version,
+		Served:  true,
+		Storage: version == v1beta1,
+		Schema: &apiextensionsv1.CustomResourceValidation{
+			OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"spec": specSchema(version),
+					"status": {
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"observedGeneration": {Type: "integer", Format: "int64"},
+							"readyReplicas":      {Type: "integer", Format: "int32"},
+							"url":                {Type: "string"},
+							"conditions": {
+								Type:         "array",
+								XListType:    &listMap,
+								XListMapKeys: []string{"type"},
+								Items:        &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &conditionSchema},
+							},
+						},
+					},
+				},
+			},
+		},
+		Subresources: &apiextensionsv1.CustomResourceSubresources{
+			Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+		},
+		AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+			{Name: "Image", Type: "string", JSONPath: ".spec.image"},
+			{Name: "Host", Type: "string", JSONPath: hostPath},
+			{Name: "Ready", Type: "integer", JSONPath: ".status.readyReplicas"},
+			{Name: "Available", Type: "string", JSONPath: `.status.conditions[?(@.type=="Available")].status`},
+			{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+		},
+	}
+}
+
+// websiteCRD describes the Website resource reconciled by the operator. Both
+// versions are served; the API server calls the conversion webhook whenever a
+// client asks for a version other than the one an object is stored in.
+func websiteCRD(conversion *apiextensionsv1.WebhookClientConfig) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: crdName},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: crdGroup,
+			Scope: apiextensionsv1.NamespaceScoped,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind:       crdKind,
+				ListKind:   crdKind + "List",
+				Plural:     crdPlural,
+				Singular:   "website",
+				ShortNames: []string{"ws"},
+			},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				websiteVersion(v1alpha1),
+				websiteVersion(v1beta1),
+			},
+			Conversion: &apiextensionsv1.CustomResourceConversion{
+				Strategy: apiextensionsv1.WebhookConverter,
+				Webhook: &apiextensionsv1.WebhookConversion{
+					ClientConfig:             conversion,
+					ConversionReviewVersions: []string{"v1"},
+				},
+			},
+		},
+	}
+}
+
+// ensureCRD creates or updates the Website CRD and waits until it is established
+func ensureCRD(ctx context.Context, client apiextensionsclientset.Interface, conversion *apiextensionsv1.WebhookClientConfig) error {
+	crds := client.ApiextensionsV1().CustomResourceDefinitions()
+	desired := websiteCRD(conversion)
+
+	existing, err := crds.Get(ctx, crdName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		if _, err := crds.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("create CRD: %w", err)
+		}
+	case err != nil:
+		return err
+	default:
+		desired.ResourceVersion = existing.ResourceVersion
+		if _, err := crds.Update(ctx, desired, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("update CRD: %w", err)
+		}
+	}
+
+	return wait.PollUntilContextTimeout(ctx, 500*time.Millisecond, time.Minute, true, func(ctx context.Context) (bool, error) {
+		crd, err := crds.Get(ctx, crdName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range crd.Status.Conditions {
+			if cond.Type == apiextensionsv1.Established {
+				return cond.Status == apiextensionsv1.ConditionTrue, nil
+			}
+		}
+		return false, nil
+	})
+}
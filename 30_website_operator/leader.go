@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// leaseName is the Lease that elects the replica running singleton tasks
+const leaseName = "website-operator-leader"
+
+// singletonTasks run on exactly one replica at a time, no matter how many
+// shards there are: installing the CRD (so replicas do not race on its
+// caBundle) and reporting how Websites are spread over the shards
+func (c *WebsiteController) singletonTasks(ctx context.Context, extClient apiextensionsclientset.Interface, conversion *apiextensionsv1.WebhookClientConfig, shardCount int) {
+	if *installCRD {
+		if err := ensureCRD(ctx, extClient, conversion); err != nil {
+			fmt.Printf("[Leader] Failed to install CRD: %v\n", err)
+		} else {
+			fmt.Printf("[Leader] CRD %s established (served: %s, %s; storage: %s)\n", crdName, v1alpha1, v1beta1, v1beta1)
+		}
+	}
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		objs, err := c.websites.ForResource(c.gvr).Lister().List(labels.Everything())
+		if err != nil {
+			return
+		}
+		perShard := map[int]int{}
+		for _, obj := range objs {
+			perShard[shardFor(obj.(*unstructured.Unstructured).GetUID(), shardCount)]++
+		}
+		var shards []int
+		for s := range perShard {
+			shards = append(shards, s)
+		}
+		sort.Ints(shards)
+		line := ""
+		for _, s := range shards {
+			line += fmt.Sprintf(" shard %d=%d", s, perShard[s])
+		}
+		fmt.Printf("[Leader] %d Websites over %d shards:%s\n", len(objs), shardCount, line)
+	}, time.Minute)
+}
+
+// runLeaderElection blocks until ctx is cancelled, running singletonTasks
+// whenever this replica holds the Lease
+func runLeaderElection(ctx context.Context, clientset kubernetes.Interface, namespace string, leading func(ctx context.Context)) {
+	hostname, _ := os.Hostname()
+	identity := hostname + "-" + rand.String(5)
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: leaseName, Namespace: namespace},
+		Client:     clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		ReleaseOnCancel: true,
+		Name:            leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				fmt.Printf("[Leader] %s acquired %s/%s\n", identity, namespace, leaseName)
+				leading(ctx)
+			},
+			OnStoppedLeading: func() {
+				fmt.Printf("[Leader] %s stopped leading\n", identity)
+			},
+			OnNewLeader: func(current string) {
+				if current != identity {
+					fmt.Printf("[Leader] Current leader: %s\n", current)
+				}
+			},
+		},
+	})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/healthz"
+)
+
+var (
+	workers      = flag.Int("workers", 2, "number of reconcile workers")
+	installCRD   = flag.Bool("install-crd", true, "create or update the Website CRD on startup")
+	ingressClass = flag.String("ingress-class", "", "default ingressClassName for generated Ingresses (empty uses the cluster default)")
+	watchVersion = flag.String("watch-version", v1beta1, "Website version the controller watches and writes: v1alpha1 or v1beta1")
+	runMigration = flag.Bool("migrate-storage", false, "rewrite all Websites in the storage version and drop v1alpha1 from storedVersions, then exit")
+
+	shardCount        = flag.Int("shard-count", 1, "number of operator replicas sharing the Websites")
+	shardIndex        = flag.Int("shard-index", -1, "shard of this replica (-1 derives it from a StatefulSet-style hostname ordinal)")
+	operatorNamespace = flag.String("operator-namespace", "default", "namespace of the leader election Lease and the webhook certificate Secret")
+
+	webhookListen    = flag.String("webhook-listen", ":9443", "HTTPS listen address of the conversion webhook")
+	webhookURL       = flag.String("webhook-url", "", "external base URL of the conversion webhook reachable by the API server; overrides the Service")
+	serviceName      = flag.String("service-name", "website-operator", "Service fronting the conversion webhook when running in-cluster")
+	serviceNamespace = flag.String("service-namespace", "k8s-lab", "namespace of that Service")
+	servicePort      = flag.Int("service-port", 443, "port of that Service")
+
+	healthAddr    = flag.String("health-addr", ":8081", "address serving /healthz and /readyz (empty disables)")
+	maxQueueDepth = flag.Int("max-queue-depth", 100, "/readyz fails while more keys than this are queued")
+)
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "30-website-operator")
+	return config
+}
+
+// conversionClientConfig points the API server at /convert, either through an
+// in-cluster Service or a URL reachable from the API server
+func conversionClientConfig() (*apiextensionsv1.WebhookClientConfig, []string, error) {
+	cfg := &apiextensionsv1.WebhookClientConfig{}
+	if *webhookURL != "" {
+		u, err := url.Parse(*webhookURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		full := *webhookURL + "/convert"
+		cfg.URL = &full
+		return cfg, []string{u.Hostname()}, nil
+	}
+	path := "/convert"
+	port := int32(*servicePort)
+	cfg.Service = &apiextensionsv1.ServiceReference{
+		Namespace: *serviceNamespace,
+		Name:      *serviceName,
+		Path:      &path,
+		Port:      &port,
+	}
+	svc := *serviceName + "." + *serviceNamespace + ".svc"
+	return cfg, []string{svc, svc + ".cluster.local"}, nil
+}
+
+func main() {
+	config := createConfig()
+	ctx := context.Background()
+
+	if *watchVersion != v1alpha1 && *watchVersion != v1beta1 {
+		log.Fatalf("Unknown --watch-version %q", *watchVersion)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create dynamic client: %v", err)
+	}
+	extClient, err := apiextensionsclientset.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create apiextensions clientset: %v", err)
+	}
+
+	// The conversion webhook must be up before anything reads a Website in a
+	// version other than the one it is stored in, so it starts first. All
+	// replicas share one certificate, stored in a Secret.
+	conversion, hosts, err := conversionClientConfig()
+	if err != nil {
+		log.Fatalf("Invalid webhook URL: %v", err)
+	}
+	cert, caPEM, err := loadOrCreateServingCert(ctx, clientset, *operatorNamespace, hosts)
+	if err != nil {
+		log.Fatalf("Failed to load serving certificate: %v", err)
+	}
+	conversion.CABundle = caPEM
+	mux := http.NewServeMux()
+	mux.HandleFunc("/convert", serveConversion)
+	server := &http.Server{
+		Addr:      *webhookListen,
+		Handler:   mux,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+	}
+	go func() {
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Conversion webhook failed: %v", err)
+		}
+	}()
+	fmt.Printf("Conversion webhook listening on %s for %v\n", *webhookListen, hosts)
+
+	if *runMigration {
+		if *installCRD {
+			if err := ensureCRD(ctx, extClient, conversion); err != nil {
+				log.Fatalf("Failed to install CRD: %v", err)
+			}
+		}
+		if err := migrateStorage(ctx, extClient, dynamicClient); err != nil {
+			log.Fatalf("Storage migration failed: %v", err)
+		}
+		return
+	}
+
+	mine := shard{index: *shardIndex, count: *shardCount}
+	if mine.index < 0 {
+		mine.index = shardIndexFromHostname()
+	}
+	if mine.count < 1 || mine.index >= mine.count {
+		log.Fatalf("Invalid shard %d of %d", mine.index, mine.count)
+	}
+	controller := NewWebsiteController(clientset, dynamicClient, *watchVersion, mine)
+	fmt.Printf("Watching Websites as %s, shard %s\n", controller.gvr.GroupVersion(), mine)
+
+	// Stop on Ctrl-C, or on SIGTERM when the pod is deleted
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Ready once the Website and child caches have synced, and while the
+	// queue keeps up. Every replica is ready, not only the leader.
+	health := healthz.New()
+	health.AddReadyCheck("website-informers", healthz.FactorySynced(controller.websites))
+	health.AddReadyCheck("child-informers", healthz.FactorySynced(controller.children))
+	health.AddReadyCheck("workqueue", healthz.QueueDepth(controller.queue, *maxQueueDepth))
+	if *healthAddr != "" {
+		go func() {
+			if err := health.Serve(ctx, *healthAddr); err != nil {
+				log.Fatalf("Health server failed: %v", err)
+			}
+		}()
+	}
+
+	// Every replica reconciles its shard; only the leader runs singleton tasks
+	go runLeaderElection(ctx, clientset, *operatorNamespace, func(ctx context.Context) {
+		controller.singletonTasks(ctx, extClient, conversion, mine.count)
+	})
+	controller.Run(ctx, *workers)
+}
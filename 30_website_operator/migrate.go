@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// migrateStorage rewrites every Website in the storage version (v1beta1), then
+// drops v1alpha1 from the CRD's status.storedVersions. Objects are only
+// re-encoded when they are written, so without this step etcd keeps v1alpha1
+// objects forever and v1alpha1 can never be removed from the CRD.
+func migrateStorage(ctx context.Context, extClient apiextensionsclientset.Interface, dynamicClient dynamic.Interface) error {
+	crds := extClient.ApiextensionsV1().CustomResourceDefinitions()
+	crd, err := crds.Get(ctx, crdName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[Migration] storedVersions before: %v\n", crd.Status.StoredVersions)
+	if len(crd.Status.StoredVersions) == 1 && crd.Status.StoredVersions[0] == v1beta1 {
+		fmt.Println("[Migration] Nothing to migrate")
+		return nil
+	}
+
+	// A no-op update is enough: the API server decodes the stored object,
+	// converts it and encodes it again in the storage version
+	websites := dynamicClient.Resource(websiteGVR(v1beta1))
+	migrated := 0
+	opts := metav1.ListOptions{Limit: 100}
+	for {
+		list, err := websites.List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, item := range list.Items {
+			ns, name := item.GetNamespace(), item.GetName()
+			err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				current, err := websites.Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				_, err = websites.Namespace(ns).Update(ctx, current, metav1.UpdateOptions{FieldManager: fieldManager})
+				return err
+			})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("rewrite %s/%s: %w", ns, name, err)
+			}
+			migrated++
+		}
+		if list.GetContinue() == "" {
+			break
+		}
+		opts.Continue = list.GetContinue()
+	}
+	fmt.Printf("[Migration] Rewrote %d Websites as %s\n", migrated, v1beta1)
+
+	// Only now is it true that nothing in etcd is stored as v1alpha1
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		crd, err := crds.Get(ctx, crdName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		crd.Status.StoredVersions = []string{v1beta1}
+		if _, err := crds.UpdateStatus(ctx, crd, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		fmt.Printf("[Migration] storedVersions after: %v\n", crd.Status.StoredVersions)
+		return nil
+	})
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	appsv1ac "k8s.io/client-go/applyconfigurations/apps/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	networkingv1ac "k8s.io/client-go/applyconfigurations/networking/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// conditionAvailable is True when every desired replica is available
+	conditionAvailable = "Available"
+	// conditionReconciled is True when all children match the spec
+	conditionReconciled = "Reconciled"
+)
+
+// reconcile drives one Website towards its spec. Children are applied with
+// server-side apply, so this is idempotent and safe to run on every event.
+func (c *WebsiteController) reconcile(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	obj, err := c.websites.ForResource(c.gvr).Lister().ByNamespace(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		// Already gone: owner references let the garbage collector remove the children
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Never mutate the cached object
+	u := obj.(*unstructured.Unstructured).DeepCopy()
+	website, err := websiteFromUnstructured(u)
+	if err != nil {
+		return err
+	}
+	client := c.dynamic.Resource(c.gvr).Namespace(namespace)
+
+	// Deletion: run cleanup, then release the finalizer
+	if !website.DeletionTimestamp.IsZero() {
+		if !hasFinalizer(u) {
+			return nil
+		}
+		if err := c.finalize(ctx, website); err != nil {
+			return err
+		}
+		removeFinalizer(u)
+		_, err := client.Update(ctx, u, metav1.UpdateOptions{FieldManager: fieldManager})
+		if err == nil {
+			fmt.Printf("[Website] %s finalized\n", key)
+		}
+		return err
+	}
+
+	// Add the finalizer before creating anything that needs cleaning up.
+	// The update triggers another event, which continues the reconcile.
+	if !hasFinalizer(u) {
+		u.SetFinalizers(append(u.GetFinalizers(), finalizerName))
+		_, err := client.Update(ctx, u, metav1.UpdateOptions{FieldManager: fieldManager})
+		return err
+	}
+
+	// Work on a copy so the change check below compares against what was read
+	status := website.Status
+	status.Conditions = append([]metav1.Condition(nil), website.Status.Conditions...)
+	status.ObservedGeneration = website.Generation
+
+	deployment, applyErr := c.applyChildren(ctx, website)
+	if applyErr != nil {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               conditionReconciled,
+			Status:             metav1.ConditionFalse,
+			Reason:             "ApplyFailed",
+			Message:            applyErr.Error(),
+			ObservedGeneration: website.Generation,
+		})
+		c.recorder.Event(u, corev1.EventTypeWarning, "ApplyFailed", applyErr.Error())
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               conditionReconciled,
+			Status:             metav1.ConditionTrue,
+			Reason:             "ChildrenApplied",
+			Message:            "Deployment, Service and Ingress match the spec",
+			ObservedGeneration: website.Generation,
+		})
+		status.ReadyReplicas = deployment.Status.ReadyReplicas
+		desired := *deployment.Spec.Replicas
+		available := metav1.Condition{
+			Type:               conditionAvailable,
+			Status:             metav1.ConditionFalse,
+			Reason:             "ReplicasUnavailable",
+			Message:            fmt.Sprintf("%d of %d replicas available", deployment.Status.AvailableReplicas, desired),
+			ObservedGeneration: website.Generation,
+		}
+		if deployment.Status.AvailableReplicas >= desired && deployment.Status.ObservedGeneration >= deployment.Generation {
+			available.Status = metav1.ConditionTrue
+			available.Reason = "MinimumReplicasAvailable"
+		}
+		meta.SetStatusCondition(&status.Conditions, available)
+	}
+	status.URL = websiteURL(website)
+
+	// Only write status when it changed; SetStatusCondition keeps
+	// lastTransitionTime stable, so an unchanged status compares equal
+	if !reflect.DeepEqual(status, website.Status) {
+		if err := setStatus(u, &status); err != nil {
+			return err
+		}
+		if _, err := client.UpdateStatus(ctx, u, metav1.UpdateOptions{FieldManager: fieldManager}); err != nil {
+			return fmt.Errorf("update status: %w", err)
+		}
+		fmt.Printf("[Website] %s: ready %d, %s\n", key, status.ReadyReplicas, conditionSummary(status.Conditions))
+	}
+	return applyErr
+}
+
+// applyChildren server-side applies the Deployment, Service and Ingress of a Website
+func (c *WebsiteController) applyChildren(ctx context.Context, website *Website) (*appsv1.Deployment, error) {
+	applyOpts := metav1.ApplyOptions{FieldManager: fieldManager, Force: true}
+	ns, name := website.Namespace, website.Name
+	selector := map[string]string{websiteLabel: name}
+	objLabels := map[string]string{websiteLabel: name, managedByLabel: controllerName}
+
+	// The controller reference makes the Website the owner: the garbage collector
+	// deletes children with it, and enqueueOwner maps child events back to it
+	owner := metav1ac.OwnerReference().
+		WithAPIVersion(c.gvr.GroupVersion().String()).
+		WithKind(crdKind).
+		WithName(name).
+		WithUID(website.UID).
+		WithController(true).
+		WithBlockOwnerDeletion(true)
+
+	replicas := int32(1)
+	if website.Spec.Replicas != nil {
+		replicas = *website.Spec.Replicas
+	}
+	port := website.Spec.Port
+	if port == 0 {
+		port = 80
+	}
+
+	deployment := appsv1ac.Deployment(name, ns).
+		WithLabels(objLabels).
+		WithOwnerReferences(owner).
+		WithSpec(appsv1ac.DeploymentSpec().
+			WithReplicas(replicas).
+			WithSelector(metav1ac.LabelSelector().WithMatchLabels(selector)).
+			WithTemplate(corev1ac.PodTemplateSpec().
+				WithLabels(selector).
+				WithSpec(corev1ac.PodSpec().
+					WithContainers(corev1ac.Container().
+						WithName("web").
+						WithImage(website.Spec.Image).
+						WithPorts(corev1ac.ContainerPort().WithName("http").WithContainerPort(port)).
+						WithReadinessProbe(corev1ac.Probe().
+							WithTCPSocket(corev1ac.TCPSocketAction().WithPort(intstr.FromString("http"))))))))
+	applied, err := c.clientset.AppsV1().Deployments(ns).Apply(ctx, deployment, applyOpts)
+	if err != nil {
+		return nil, fmt.Errorf("apply Deployment: %w", err)
+	}
+
+	service := corev1ac.Service(name, ns).
+		WithLabels(objLabels).
+		WithOwnerReferences(owner).
+		WithSpec(corev1ac.ServiceSpec().
+			WithSelector(selector).
+			WithPorts(corev1ac.ServicePort().
+				WithName("http").
+				WithPort(80).
+				WithTargetPort(intstr.FromString("http"))))
+	if _, err := c.clientset.CoreV1().Services(ns).Apply(ctx, service, applyOpts); err != nil {
+		return nil, fmt.Errorf("apply Service: %w", err)
+	}
+
+	// The Ingress is optional: remove it when spec.host is cleared
+	if website.host() == "" {
+		// Check the cache first so steady state costs no API calls
+		if _, err := c.children.Networking().V1().Ingresses().Lister().Ingresses(ns).Get(name); apierrors.IsNotFound(err) {
+			return applied, nil
+		}
+		err := c.clientset.NetworkingV1().Ingresses(ns).Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("delete Ingress: %w", err)
+		}
+		return applied, nil
+	}
+	pathType := networkingv1.PathTypePrefix
+	ingressSpec := networkingv1ac.IngressSpec().
+		WithRules(networkingv1ac.IngressRule().
+			WithHost(website.host()).
+			WithHTTP(networkingv1ac.HTTPIngressRuleValue().
+				WithPaths(networkingv1ac.HTTPIngressPath().
+					WithPath("/").
+					WithPathType(pathType).
+					WithBackend(networkingv1ac.IngressBackend().
+						WithService(networkingv1ac.IngressServiceBackend().
+							WithName(name).
+							WithPort(networkingv1ac.ServiceBackendPort().WithName("http")))))))
+	// spec.ingress.className (v1beta1 only) wins over the operator-wide default
+	className := website.Spec.Ingress.ClassName
+	if className == "" {
+		className = *ingressClass
+	}
+	if className != "" {
+		ingressSpec = ingressSpec.WithIngressClassName(className)
+	}
+	ingress := networkingv1ac.Ingress(name, ns).
+		WithLabels(objLabels).
+		WithOwnerReferences(owner).
+		WithSpec(ingressSpec)
+	if _, err := c.clientset.NetworkingV1().Ingresses(ns).Apply(ctx, ingress, applyOpts); err != nil {
+		return nil, fmt.Errorf("apply Ingress: %w", err)
+	}
+	return applied, nil
+}
+
+// finalize runs before the Website disappears. Owner references already take
+// care of the children; the Ingress is removed first so the host stops routing
+// to pods that are about to terminate.
+func (c *WebsiteController) finalize(ctx context.Context, website *Website) error {
+	err := c.clientset.NetworkingV1().Ingresses(website.Namespace).Delete(ctx, website.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete Ingress: %w", err)
+	}
+	fmt.Printf("[Website] %s/%s: released host %q\n", website.Namespace, website.Name, website.host())
+	return nil
+}
+
+// websiteURL is where the Website is reachable
+func websiteURL(website *Website) string {
+	if website.host() != "" {
+		return "http://" + website.host()
+	}
+	return fmt.Sprintf("http://%s.%s.svc", website.Name, website.Namespace)
+}
+
+func hasFinalizer(u *unstructured.Unstructured) bool {
+	for _, f := range u.GetFinalizers() {
+		if f == finalizerName {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFinalizer(u *unstructured.Unstructured) {
+	var kept []string
+	for _, f := range u.GetFinalizers() {
+		if f != finalizerName {
+			kept = append(kept, f)
+		}
+	}
+	u.SetFinalizers(kept)
+}
+
+// conditionSummary renders conditions as Type=Status pairs
+func conditionSummary(conditions []metav1.Condition) string {
+	s := ""
+	for i, cond := range conditions {
+		if i > 0 {
+			s += " "
+		}
+		s += cond.Type + "=" + string(cond.Status)
+	}
+	return s
+}
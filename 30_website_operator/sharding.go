@@ -0,0 +1,75 @@
+package main
+
+import (
+	"hash/fnv"
+	"os"
+	"regexp"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// shard identifies the slice of Websites one replica reconciles
+type shard struct {
+	index int
+	count int
+}
+
+// shardFor picks the owning shard of a UID with rendezvous (highest random
+// weight) hashing: every shard scores the UID and the highest score wins.
+// Changing the shard count only moves the UIDs whose winner was added or
+// removed, unlike uid % count which reshuffles almost everything.
+func shardFor(uid types.UID, count int) int {
+	best, bestScore := 0, uint64(0)
+	for i := 0; i < count; i++ {
+		h := fnv.New64a()
+		h.Write([]byte(uid))
+		h.Write([]byte{byte(i), byte(i >> 8)})
+		if score := mix64(h.Sum64()); i == 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// mix64 is the murmur3 finalizer. FNV barely mixes its last input bytes, so
+// without it the scores of UIDs that differ only at the end (and of the shard
+// number appended last) stay correlated and some shards win far more often.
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// owns reports whether this replica reconciles the object with the given UID
+func (s shard) owns(uid types.UID) bool {
+	if s.count <= 1 {
+		return true
+	}
+	return shardFor(uid, s.count) == s.index
+}
+
+func (s shard) String() string {
+	return strconv.Itoa(s.index) + "/" + strconv.Itoa(s.count)
+}
+
+// ordinalSuffix matches the ordinal of a StatefulSet pod name such as website-operator-2
+var ordinalSuffix = regexp.MustCompile(`-(\d+)$`)
+
+// shardIndexFromHostname derives the shard index from the StatefulSet ordinal,
+// so replicas need no per-pod configuration
+func shardIndexFromHostname() int {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return 0
+	}
+	m := ordinalSuffix.FindStringSubmatch(hostname)
+	if m == nil {
+		return 0
+	}
+	i, _ := strconv.Atoi(m[1])
+	return i
+}
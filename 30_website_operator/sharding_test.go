@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func uids(n int) []types.UID {
+	var out []types.UID
+	for i := 0; i < n; i++ {
+		out = append(out, types.UID(fmt.Sprintf("00000000-0000-0000-0000-%012d", i)))
+	}
+	return out
+}
+
+func TestShardForSpreadsUIDs(t *testing.T) {
+	perShard := map[int]int{}
+	for _, uid := range uids(3000) {
+		perShard[shardFor(uid, 3)]++
+	}
+	for i := 0; i < 3; i++ {
+		if perShard[i] < 800 {
+			t.Errorf("shard %d got %d of 3000 UIDs", i, perShard[i])
+		}
+	}
+}
+
+func TestShardForMovesOnlyToNewShard(t *testing.T) {
+	moved := 0
+	for _, uid := range uids(1000) {
+		before, after := shardFor(uid, 3), shardFor(uid, 4)
+		if before == after {
+			continue
+		}
+		// Rendezvous hashing: adding a shard only takes UIDs, it never reshuffles the others
+		if after != 3 {
+			t.Fatalf("%s moved from shard %d to %d", uid, before, after)
+		}
+		moved++
+	}
+	if moved == 0 || moved > 400 {
+		t.Errorf("%d of 1000 UIDs moved to the new shard, want about a quarter", moved)
+	}
+}
+
+func TestShardOwns(t *testing.T) {
+	uid := uids(1)[0]
+	if !(shard{index: 0, count: 1}).owns(uid) {
+		t.Error("a single shard must own everything")
+	}
+	owners := 0
+	for i := 0; i < 4; i++ {
+		if (shard{index: i, count: 4}).owns(uid) {
+			owners++
+		}
+	}
+	if owners != 1 {
+		t.Errorf("%d shards own %s, want exactly one", owners, uid)
+	}
+}
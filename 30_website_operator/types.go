@@ -0,0 +1,97 @@
+package main
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	crdGroup  = "k8s-lab.io"
+	crdKind   = "Website"
+	crdPlural = "websites"
+	crdName   = crdPlural + "." + crdGroup
+
+	// v1alpha1 is the original version: spec.host
+	v1alpha1 = "v1alpha1"
+	// v1beta1 moves the host into spec.ingress and adds spec.ingress.className.
+	// It is the storage version and the hub the controller works with.
+	v1beta1 = "v1beta1"
+)
+
+// websiteGVR addresses Website objects in the given version through the dynamic
+// client and informer
+func websiteGVR(version string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: crdGroup, Version: version, Resource: crdPlural}
+}
+
+// Website is the typed view of the custom resource, shaped like v1beta1. There
+// is no generated clientset: objects travel as unstructured and are converted
+// at the edges, which keeps the example free of code generation.
+type Website struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WebsiteSpec   `json:"spec"`
+	Status WebsiteStatus `json:"status,omitempty"`
+}
+
+// WebsiteSpec is the desired state: a container image served behind a Service
+// and, when Ingress is set, an Ingress
+type WebsiteSpec struct {
+	Image    string          `json:"image"`
+	Replicas *int32          `json:"replicas,omitempty"`
+	Port     int32           `json:"port,omitempty"`
+	Ingress  *WebsiteIngress `json:"ingress,omitempty"`
+}
+
+// WebsiteIngress exposes the Website under a host name
+type WebsiteIngress struct {
+	Host      string `json:"host"`
+	ClassName string `json:"className,omitempty"`
+}
+
+// WebsiteStatus is written through the status subresource only. It is
+// identical in both versions.
+type WebsiteStatus struct {
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	ReadyReplicas      int32              `json:"readyReplicas,omitempty"`
+	URL                string             `json:"url,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// host returns the Ingress host, empty when the Website has no Ingress
+func (w *Website) host() string {
+	if w.Spec.Ingress == nil {
+		return ""
+	}
+	return w.Spec.Ingress.Host
+}
+
+// websiteFromUnstructured converts an informer object of either version into
+// a Website. v1alpha1 objects go through the same conversion the webhook uses,
+// so the controller behaves identically whichever version it watches.
+func websiteFromUnstructured(u *unstructured.Unstructured) (*Website, error) {
+	if u.GetAPIVersion() == websiteGVR(v1alpha1).GroupVersion().String() {
+		u = u.DeepCopy()
+		if err := convertWebsite(u, websiteGVR(v1beta1).GroupVersion().String()); err != nil {
+			return nil, err
+		}
+	}
+	website := &Website{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, website); err != nil {
+		return nil, err
+	}
+	return website, nil
+}
+
+// setStatus replaces .status of an unstructured Website
+func setStatus(u *unstructured.Unstructured, status *WebsiteStatus) error {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return err
+	}
+	u.Object["status"] = obj
+	return nil
+}
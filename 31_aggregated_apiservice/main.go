@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	aggregatorclientset "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+// serviceName fronts the extension apiserver; APIServices only accept a Service reference
+const serviceName = "k8s-lab-aggregated"
+
+var (
+	port             = flag.Int("port", 8443, "HTTPS port of the extension apiserver")
+	advertiseAddress = flag.String("advertise-address", "", "IP of this machine reachable from the API server (required)")
+	namespace        = flag.String("namespace", "default", "namespace of the Service that fronts the extension apiserver")
+	keep             = flag.Bool("keep", false, "keep serving and keep the APIService registered until Ctrl+C")
+)
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "31-aggregated-apiservice")
+	return config
+}
+
+// registerService creates a selectorless Service with manual Endpoints pointing
+// at this process, so the aggregator can reach a server running outside the cluster
+func registerService(ctx context.Context, clientset kubernetes.Interface) error {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: serviceName, Namespace: *namespace},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "https", Port: 443, TargetPort: intstr.FromInt32(int32(*port))}},
+		},
+	}
+	if _, err := clientset.CoreV1().Services(*namespace).Create(ctx, service, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create Service: %w", err)
+	}
+
+	// Endpoints of a selectorless Service are mirrored into an EndpointSlice by the control plane
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: serviceName, Namespace: *namespace},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: *advertiseAddress}},
+			Ports:     []corev1.EndpointPort{{Name: "https", Port: int32(*port)}},
+		}},
+	}
+	existing, err := clientset.CoreV1().Endpoints(*namespace).Get(ctx, serviceName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = clientset.CoreV1().Endpoints(*namespace).Create(ctx, endpoints, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	endpoints.ResourceVersion = existing.ResourceVersion
+	_, err = clientset.CoreV1().Endpoints(*namespace).Update(ctx, endpoints, metav1.UpdateOptions{})
+	return err
+}
+
+// registerAPIService creates or updates the APIService that routes the group/version to the Service
+func registerAPIService(ctx context.Context, client aggregatorclientset.Interface, caBundle []byte) (string, error) {
+	servicePort := int32(443)
+	apiService := &apiregistrationv1.APIService{
+		// The name must be <version>.<group>
+		ObjectMeta: metav1.ObjectMeta{Name: apiVersion + "." + apiGroup},
+		Spec: apiregistrationv1.APIServiceSpec{
+			Service:              &apiregistrationv1.ServiceReference{Namespace: *namespace, Name: serviceName, Port: &servicePort},
+			Group:                apiGroup,
+			Version:              apiVersion,
+			CABundle:             caBundle,
+			GroupPriorityMinimum: 1000,
+			VersionPriority:      15,
+		},
+	}
+	apiServices := client.ApiregistrationV1().APIServices()
+	existing, err := apiServices.Get(ctx, apiService.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = apiServices.Create(ctx, apiService, metav1.CreateOptions{})
+		return apiService.Name, err
+	}
+	if err != nil {
+		return "", err
+	}
+	apiService.ResourceVersion = existing.ResourceVersion
+	_, err = apiServices.Update(ctx, apiService, metav1.UpdateOptions{})
+	return apiService.Name, err
+}
+
+// cleanup removes the APIService first, so the API server stops routing to us
+func cleanup(ctx context.Context, clientset kubernetes.Interface, aggregator aggregatorclientset.Interface, name string) {
+	if err := aggregator.ApiregistrationV1().APIServices().Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		fmt.Printf("Failed to delete APIService: %v\n", err)
+	}
+	if err := clientset.CoreV1().Services(*namespace).Delete(ctx, serviceName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		fmt.Printf("Failed to delete Service: %v\n", err)
+	}
+	fmt.Printf("Removed APIService %s and Service %s/%s\n", name, *namespace, serviceName)
+}
+
+func main() {
+	config := createConfig()
+	if *advertiseAddress == "" {
+		log.Fatalf("--advertise-address is required")
+	}
+	// Ctrl-C or SIGTERM ends --keep; the deferred cleanup still runs
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	aggregator, err := aggregatorclientset.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create kube-aggregator clientset: %v", err)
+	}
+
+	// Step 1: serve the extension API over HTTPS with a certificate for the Service name
+	svcHost := serviceName + "." + *namespace + ".svc"
+	cert, caPEM, err := selfSignedServingCert([]string{svcHost}, 24*time.Hour)
+	if err != nil {
+		log.Fatalf("Failed to create serving certificate: %v", err)
+	}
+	ext := &extensionServer{started: time.Now()}
+	server := &http.Server{
+		Addr:      fmt.Sprintf(":%d", *port),
+		Handler:   logRequests(ext.routes()),
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+	}
+	go func() {
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Extension apiserver failed: %v", err)
+		}
+	}()
+	fmt.Printf("Extension apiserver listening on :%d (%s)\n", *port, groupVersion)
+
+	// Step 2: make it reachable through a Service and register the APIService
+	if err := registerService(ctx, clientset); err != nil {
+		log.Fatalf("Failed to register Service: %v", err)
+	}
+	name, err := registerAPIService(ctx, aggregator, caPEM)
+	if err != nil {
+		log.Fatalf("Failed to register APIService: %v", err)
+	}
+	defer cleanup(context.Background(), clientset, aggregator, name)
+	fmt.Printf("Registered APIService %s -> service %s/%s\n", name, *namespace, serviceName)
+
+	// Step 3: wait for the aggregator to mark the APIService Available
+	err = wait.PollUntilContextTimeout(ctx, time.Second, time.Minute, true, func(ctx context.Context) (bool, error) {
+		apiService, err := aggregator.ApiregistrationV1().APIServices().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range apiService.Status.Conditions {
+			if cond.Type == apiregistrationv1.Available {
+				if cond.Status != apiregistrationv1.ConditionTrue {
+					fmt.Printf("APIService not available yet: %s: %s\n", cond.Reason, cond.Message)
+				}
+				return cond.Status == apiregistrationv1.ConditionTrue, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		fmt.Printf("APIService did not become available: %v\n", err)
+		return
+	}
+	fmt.Println("APIService is Available")
+
+	// Step 4: the group now shows up in discovery next to the built-in APIs
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create discovery client: %v", err)
+	}
+	resources, err := dc.ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		fmt.Printf("Discovery failed: %v\n", err)
+		return
+	}
+	fmt.Printf("\nDiscovery for %s:\n", groupVersion)
+	for _, r := range resources.APIResources {
+		fmt.Printf("  %s (kind %s, namespaced=%v, verbs=%v)\n", r.Name, r.Kind, r.Namespaced, r.Verbs)
+	}
+
+	// Step 5: consume it with the dynamic client, exactly like a CRD
+	dynClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create dynamic client: %v", err)
+	}
+	gvr := schema.GroupVersionResource{Group: apiGroup, Version: apiVersion, Resource: apiResource}
+	list, err := dynClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Printf("List failed: %v\n", err)
+		return
+	}
+	fmt.Printf("\nkubectl get %s.%s:\n", apiResource, apiGroup)
+	for _, item := range list.Items {
+		fmt.Printf("  %s: hostname=%v uptime=%v go=%v requestUser=%v\n", item.GetName(),
+			item.Object["hostname"], item.Object["uptime"], item.Object["goVersion"], item.Object["requestUser"])
+	}
+	if _, err := dynClient.Resource(gvr).Get(ctx, "missing", metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		fmt.Printf("  GET missing: %v\n", err)
+	}
+	fmt.Println()
+
+	if *keep {
+		fmt.Printf("Serving until Ctrl+C, try: kubectl get %s\n", apiResource)
+		<-ctx.Done()
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	apiGroup    = "lab.k8s-lab.io"
+	apiVersion  = "v1alpha1"
+	apiResource = "serverinfos"
+	apiKind     = "ServerInfo"
+)
+
+// groupVersion is the apiVersion of every object served here
+var groupVersion = apiGroup + "/" + apiVersion
+
+// ServerInfo describes the extension apiserver process itself. It is computed
+// on every request, there is no storage behind it.
+type ServerInfo struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Hostname  string `json:"hostname"`
+	GoVersion string `json:"goVersion"`
+	Uptime    string `json:"uptime"`
+	// RequestUser is the user the aggregator forwarded in X-Remote-User
+	RequestUser string `json:"requestUser,omitempty"`
+}
+
+// ServerInfoList is returned for LIST requests
+type ServerInfoList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServerInfo `json:"items"`
+}
+
+// extensionServer is a minimal aggregated API: discovery for one group/version
+// and a read-only, cluster-scoped resource. A real extension apiserver would use
+// k8s.io/apiserver and verify the aggregator's client certificate (requestheader
+// authentication) before trusting X-Remote-User.
+type extensionServer struct {
+	started time.Time
+}
+
+func (s *extensionServer) serverInfo(r *http.Request) ServerInfo {
+	hostname, _ := os.Hostname()
+	return ServerInfo{
+		TypeMeta: metav1.TypeMeta{APIVersion: groupVersion, Kind: apiKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "self",
+			CreationTimestamp: metav1.NewTime(s.started),
+			ResourceVersion:   "1",
+		},
+		Hostname:    hostname,
+		GoVersion:   runtime.Version(),
+		Uptime:      time.Since(s.started).Round(time.Second).String(),
+		RequestUser: r.Header.Get("X-Remote-User"),
+	}
+}
+
+// routes registers discovery and resource endpoints
+func (s *extensionServer) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// Group discovery, used by the aggregator and `kubectl api-versions`
+	mux.HandleFunc("/apis/"+apiGroup, func(w http.ResponseWriter, r *http.Request) {
+		gv := metav1.GroupVersionForDiscovery{GroupVersion: groupVersion, Version: apiVersion}
+		writeJSON(w, http.StatusOK, &metav1.APIGroup{
+			TypeMeta:         metav1.TypeMeta{Kind: "APIGroup", APIVersion: "v1"},
+			Name:             apiGroup,
+			Versions:         []metav1.GroupVersionForDiscovery{gv},
+			PreferredVersion: gv,
+		})
+	})
+
+	// Resource discovery, used by discovery clients and RESTMappers
+	mux.HandleFunc("/apis/"+groupVersion, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &metav1.APIResourceList{
+			TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: groupVersion,
+			APIResources: []metav1.APIResource{{
+				Name:       apiResource,
+				Kind:       apiKind,
+				Namespaced: false,
+				Verbs:      metav1.Verbs{"get", "list"},
+			}},
+		})
+	})
+
+	// The resource itself: LIST on the collection, GET on /serverinfos/self
+	prefix := "/apis/" + groupVersion + "/" + apiResource
+	mux.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &ServerInfoList{
+			TypeMeta: metav1.TypeMeta{APIVersion: groupVersion, Kind: apiKind + "List"},
+			ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+			Items:    []ServerInfo{s.serverInfo(r)},
+		})
+	})
+	mux.HandleFunc(prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, prefix+"/")
+		if name != "self" {
+			writeJSON(w, http.StatusNotFound, &metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Reason:   metav1.StatusReasonNotFound,
+				Code:     http.StatusNotFound,
+				Message:  fmt.Sprintf("%s.%s %q not found", apiResource, apiGroup, name),
+			})
+			return
+		}
+		info := s.serverInfo(r)
+		writeJSON(w, http.StatusOK, &info)
+	})
+
+	// The aggregator probes the service before marking the APIService Available
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	return mux
+}
+
+// logRequests prints every request forwarded by the aggregator
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("[Extension] %s %s (user=%q)\n", r.Method, r.URL.Path, r.Header.Get("X-Remote-User"))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		fmt.Printf("[Extension] Failed to write response: %v\n", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// startServer serves the extension API over plain HTTP; the aggregator and TLS
+// are not needed to check what client-go makes of the responses
+func startServer(t *testing.T) *rest.Config {
+	t.Helper()
+	srv := httptest.NewServer((&extensionServer{started: time.Now()}).routes())
+	t.Cleanup(srv.Close)
+	return &rest.Config{Host: srv.URL}
+}
+
+func TestDiscovery(t *testing.T) {
+	dc, err := discovery.NewDiscoveryClientForConfig(startServer(t))
+	if err != nil {
+		t.Fatalf("discovery client: %v", err)
+	}
+	resources, err := dc.ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		t.Fatalf("resources for %s: %v", groupVersion, err)
+	}
+	if len(resources.APIResources) != 1 {
+		t.Fatalf("resources = %v", resources.APIResources)
+	}
+	r := resources.APIResources[0]
+	if r.Name != apiResource || r.Kind != apiKind || r.Namespaced {
+		t.Errorf("resource = %+v", r)
+	}
+}
+
+func TestDynamicClientGetAndList(t *testing.T) {
+	client, err := dynamic.NewForConfig(startServer(t))
+	if err != nil {
+		t.Fatalf("dynamic client: %v", err)
+	}
+	resource := client.Resource(schema.GroupVersionResource{Group: apiGroup, Version: apiVersion, Resource: apiResource})
+	ctx := context.TODO()
+
+	self, err := resource.Get(ctx, "self", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get self: %v", err)
+	}
+	if self.GetKind() != apiKind || self.GetAPIVersion() != groupVersion {
+		t.Errorf("got %s %s", self.GetAPIVersion(), self.GetKind())
+	}
+
+	list, err := resource.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].GetName() != "self" {
+		t.Errorf("list items = %v", list.Items)
+	}
+
+	// The Status body is turned into a typed API error by client-go
+	_, err = resource.Get(ctx, "other", metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("get other: got %v, want NotFound", err)
+	}
+}
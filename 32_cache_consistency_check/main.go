@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	interval   = flag.Duration("interval", 30*time.Second, "how often a sample is verified")
+	sampleSize = flag.Int("sample", 10, "number of objects compared per round, from each side")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "32-cache-consistency-check")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// verifierStats counts the outcome of every comparison since startup
+type verifierStats struct {
+	Checked int
+	// Match: same resourceVersion and same content
+	Match int
+	// Lagging: the API has a newer resourceVersion; normal while a watch event is in flight
+	Lagging int
+	// ContentMismatch: same resourceVersion but different content, usually a
+	// handler that mutated an object it got from the cache
+	ContentMismatch int
+	// MissingFromCache: the API has the object, the cache does not
+	MissingFromCache int
+	// MissingFromAPI: the cache still holds an object the API has deleted
+	MissingFromAPI int
+	Errors         int
+}
+
+// Diverged is the number of comparisons that a healthy cache never produces
+func (s verifierStats) Diverged() int {
+	return s.ContentMismatch + s.MissingFromCache + s.MissingFromAPI
+}
+
+func (s verifierStats) String() string {
+	return fmt.Sprintf("checked=%d match=%d lagging=%d content_mismatch=%d missing_from_cache=%d missing_from_api=%d errors=%d",
+		s.Checked, s.Match, s.Lagging, s.ContentMismatch, s.MissingFromCache, s.MissingFromAPI, s.Errors)
+}
+
+// verifier compares the pod informer cache with the API server
+type verifier struct {
+	clientset kubernetes.Interface
+	indexer   cache.Indexer
+	sample    int
+	rand      *rand.Rand
+	stats     verifierStats
+}
+
+// contentHash hashes the serialized pod. Objects served by the cache and by
+// the API server both come without TypeMeta, so they serialize identically.
+func contentHash(pod *corev1.Pod) string {
+	data, err := json.Marshal(pod)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// compare records the outcome for one object; either side may be nil
+func (v *verifier) compare(key string, cached, live *corev1.Pod) {
+	v.stats.Checked++
+	switch {
+	case cached == nil:
+		v.stats.MissingFromCache++
+		fmt.Printf("[Diverged] %s exists in the API (rv %s) but not in the cache\n", key, live.ResourceVersion)
+	case live == nil:
+		v.stats.MissingFromAPI++
+		fmt.Printf("[Diverged] %s was deleted from the API but is still cached (rv %s)\n", key, cached.ResourceVersion)
+	case cached.ResourceVersion != live.ResourceVersion:
+		v.stats.Lagging++
+		fmt.Printf("[Lagging] %s cached rv %s, API rv %s\n", key, cached.ResourceVersion, live.ResourceVersion)
+	case contentHash(cached) != contentHash(live):
+		v.stats.ContentMismatch++
+		fmt.Printf("[Diverged] %s rv %s: cache hash %s, API hash %s\n", key, live.ResourceVersion, contentHash(cached), contentHash(live))
+	default:
+		v.stats.Match++
+	}
+}
+
+// cached returns the pod stored under key, or nil
+func (v *verifier) cached(key string) *corev1.Pod {
+	obj, exists, err := v.indexer.GetByKey(key)
+	if err != nil || !exists {
+		return nil
+	}
+	return obj.(*corev1.Pod)
+}
+
+// verifyFromCache picks random cached keys and GETs each one from the API.
+// Only objects the cache knows about can be found stale this way.
+func (v *verifier) verifyFromCache(ctx context.Context) {
+	keys := v.indexer.ListKeys()
+	for _, i := range v.rand.Perm(len(keys))[:min(v.sample, len(keys))] {
+		key := keys[i]
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			v.stats.Errors++
+			continue
+		}
+		// A GET without resourceVersion is a quorum read, bypassing the watch cache
+		live, err := v.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			live = nil
+		} else if err != nil {
+			v.stats.Errors++
+			fmt.Printf("Error getting %s: %v\n", key, err)
+			continue
+		}
+		v.compare(key, v.cached(key), live)
+	}
+}
+
+// verifyFromAPI lists one page of pods from the API and looks each one up in
+// the cache, catching objects the cache never received
+func (v *verifier) verifyFromAPI(ctx context.Context) {
+	pods, err := v.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{Limit: int64(v.sample)})
+	if err != nil {
+		v.stats.Errors++
+		fmt.Printf("Error listing pods: %v\n", err)
+		return
+	}
+	for i := range pods.Items {
+		live := &pods.Items[i]
+		key, _ := cache.MetaNamespaceKeyFunc(live)
+		v.compare(key, v.cached(key), live)
+	}
+}
+
+// verify runs one round and returns the cumulative stats
+func (v *verifier) verify(ctx context.Context) verifierStats {
+	v.verifyFromCache(ctx)
+	v.verifyFromAPI(ctx)
+	return v.stats
+}
+
+func main() {
+	clientset := createClientSet()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	podInformer := factory.Core().V1().Pods().Informer()
+
+	defer factory.Shutdown()
+
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	factory.Start(ctx.Done())
+
+	fmt.Println("Waiting for cache sync...")
+	factory.WaitForCacheSync(ctx.Done())
+	fmt.Println("Cache sync completed!")
+
+	v := &verifier{
+		clientset: clientset,
+		indexer:   podInformer.GetIndexer(),
+		sample:    *sampleSize,
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Printf("Shutting down, final stats: %s diverged=%d\n", v.stats, v.stats.Diverged())
+			return
+		case <-ticker.C:
+		}
+		stats := v.verify(ctx)
+		fmt.Printf("[Verifier] %s diverged=%d\n", stats, stats.Diverged())
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newPod(namespace, name, rv string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, ResourceVersion: rv},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+	}
+}
+
+// newTestVerifier syncs a pod informer over a fake clientset seeded with pods
+func newTestVerifier(t *testing.T, pods ...*corev1.Pod) *verifier {
+	t.Helper()
+	var objects []runtime.Object
+	for _, pod := range pods {
+		objects = append(objects, pod)
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	podInformer := factory.Core().V1().Pods().Informer()
+
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		factory.Shutdown()
+	})
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	return &verifier{
+		clientset: clientset,
+		indexer:   podInformer.GetIndexer(),
+		sample:    len(pods) + 1,
+		rand:      rand.New(rand.NewSource(1)),
+	}
+}
+
+func TestHealthyCacheMatches(t *testing.T) {
+	v := newTestVerifier(t, newPod("default", "web", "10"), newPod("kube-system", "dns", "11"))
+
+	stats := v.verify(context.TODO())
+	// Each pod is checked once from the cache side and once from the API side
+	if stats.Checked != 4 || stats.Match != 4 || stats.Diverged() != 0 {
+		t.Errorf("stats = %s", stats)
+	}
+}
+
+func TestDivergenceIsReported(t *testing.T) {
+	v := newTestVerifier(t,
+		newPod("default", "mutated", "10"),
+		newPod("default", "lost", "11"),
+		newPod("default", "behind", "12"),
+	)
+
+	// A handler that mutates a cached object without copying it first
+	mutated := v.cached("default/mutated")
+	mutated.Spec.NodeName = "node-2"
+	// A delete event the cache never received
+	v.indexer.Add(newPod("default", "ghost", "9"))
+	// An add event the cache never received
+	v.indexer.Delete(v.cached("default/lost"))
+	// An update still in flight
+	behind := newPod("default", "behind", "5")
+	v.indexer.Update(behind)
+
+	stats := v.verify(context.TODO())
+	if stats.ContentMismatch != 2 {
+		t.Errorf("content mismatches = %d, want 2 (once from each side)", stats.ContentMismatch)
+	}
+	if stats.MissingFromAPI != 1 {
+		t.Errorf("missing from API = %d, want 1", stats.MissingFromAPI)
+	}
+	if stats.MissingFromCache != 1 {
+		t.Errorf("missing from cache = %d, want 1", stats.MissingFromCache)
+	}
+	if stats.Lagging != 2 {
+		t.Errorf("lagging = %d, want 2 (once from each side)", stats.Lagging)
+	}
+	if stats.Diverged() != 4 {
+		t.Errorf("diverged = %d, want 4", stats.Diverged())
+	}
+}
+
+func TestSampleIsBounded(t *testing.T) {
+	v := newTestVerifier(t, newPod("default", "a", "1"), newPod("default", "b", "2"), newPod("default", "c", "3"))
+	v.sample = 2
+
+	v.verifyFromCache(context.TODO())
+	if v.stats.Checked != 2 {
+		t.Errorf("checked %d cached objects, want a sample of 2", v.stats.Checked)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "33-cache-shell")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+func main() {
+	clientset := createClientSet()
+	// Ctrl-C or SIGTERM leaves the shell like "exit" does, even mid-sync
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	s := newShell(clientset, os.Stdout, ctx.Done())
+
+	fmt.Println("Informer cache shell. Type \"help\" for commands.")
+	// The prompt blocks on stdin, so it runs on its own goroutine
+	done := make(chan error, 1)
+	go func() { done <- s.run(os.Stdin) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Fatalf("Failed to read input: %v", err)
+		}
+	case <-ctx.Done():
+		fmt.Println()
+	}
+
+	stop()
+	s.factory.Shutdown()
+	fmt.Println("Exited cleanly")
+}
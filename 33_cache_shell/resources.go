@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resource is a type the shell can query, and how its objects are printed
+type resource struct {
+	// name is the plural used in commands; aliases work too
+	name       string
+	aliases    []string
+	gvr        schema.GroupVersionResource
+	namespaced bool
+	// headers and columns are printed between NAME and AGE
+	headers []string
+	columns func(obj interface{}) []string
+	// indexers are added to the informer before it starts, next to the
+	// namespace index every factory informer already has
+	indexers cache.Indexers
+}
+
+var resources = []*resource{
+	{
+		name:       "pods",
+		aliases:    []string{"pod", "po"},
+		gvr:        corev1.SchemeGroupVersion.WithResource("pods"),
+		namespaced: true,
+		headers:    []string{"STATUS", "NODE"},
+		columns: func(obj interface{}) []string {
+			pod := obj.(*corev1.Pod)
+			return []string{string(pod.Status.Phase), pod.Spec.NodeName}
+		},
+		indexers: cache.Indexers{"node": podNodeIndexFunc},
+	},
+	{
+		name:       "deployments",
+		aliases:    []string{"deployment", "deploy"},
+		gvr:        appsv1.SchemeGroupVersion.WithResource("deployments"),
+		namespaced: true,
+		headers:    []string{"READY"},
+		columns: func(obj interface{}) []string {
+			deployment := obj.(*appsv1.Deployment)
+			desired := int32(1)
+			if deployment.Spec.Replicas != nil {
+				desired = *deployment.Spec.Replicas
+			}
+			return []string{fmt.Sprintf("%d/%d", deployment.Status.ReadyReplicas, desired)}
+		},
+	},
+	{
+		name:       "services",
+		aliases:    []string{"service", "svc"},
+		gvr:        corev1.SchemeGroupVersion.WithResource("services"),
+		namespaced: true,
+		headers:    []string{"TYPE", "CLUSTER-IP"},
+		columns: func(obj interface{}) []string {
+			service := obj.(*corev1.Service)
+			return []string{string(service.Spec.Type), service.Spec.ClusterIP}
+		},
+	},
+	{
+		name:       "configmaps",
+		aliases:    []string{"configmap", "cm"},
+		gvr:        corev1.SchemeGroupVersion.WithResource("configmaps"),
+		namespaced: true,
+		headers:    []string{"DATA"},
+		columns: func(obj interface{}) []string {
+			configMap := obj.(*corev1.ConfigMap)
+			return []string{strconv.Itoa(len(configMap.Data) + len(configMap.BinaryData))}
+		},
+	},
+	{
+		name:    "nodes",
+		aliases: []string{"node", "no"},
+		gvr:     corev1.SchemeGroupVersion.WithResource("nodes"),
+		headers: []string{"STATUS"},
+		columns: func(obj interface{}) []string {
+			node := obj.(*corev1.Node)
+			for _, condition := range node.Status.Conditions {
+				if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+					return []string{"Ready"}
+				}
+			}
+			return []string{"NotReady"}
+		},
+	},
+	{
+		name:    "namespaces",
+		aliases: []string{"namespace", "ns"},
+		gvr:     corev1.SchemeGroupVersion.WithResource("namespaces"),
+		headers: []string{"STATUS"},
+		columns: func(obj interface{}) []string {
+			return []string{string(obj.(*corev1.Namespace).Status.Phase)}
+		},
+	},
+}
+
+// podNodeIndexFunc indexes a pod by the name of the node it is scheduled on
+func podNodeIndexFunc(obj interface{}) ([]string, error) {
+	pod := obj.(*corev1.Pod)
+	return []string{pod.Spec.NodeName}, nil
+}
+
+// lookupResource finds a resource by its name or one of its aliases
+func lookupResource(name string) (*resource, error) {
+	for _, r := range resources {
+		if r.name == name {
+			return r, nil
+		}
+		for _, alias := range r.aliases {
+			if alias == name {
+				return r, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown resource %q, see \"resources\"", name)
+}
+
+// printTable prints objs sorted by key, kubectl style
+func printTable(w io.Writer, r *resource, objs []interface{}, now time.Time) {
+	if len(objs) == 0 {
+		fmt.Fprintf(w, "No %s found.\n", r.name)
+		return
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		ki, _ := cache.MetaNamespaceKeyFunc(objs[i])
+		kj, _ := cache.MetaNamespaceKeyFunc(objs[j])
+		return ki < kj
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	var headers []string
+	if r.namespaced {
+		headers = append(headers, "NAMESPACE")
+	}
+	headers = append(headers, "NAME")
+	headers = append(headers, r.headers...)
+	fmt.Fprintln(tw, strings.Join(append(headers, "AGE"), "\t"))
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			continue
+		}
+		var row []string
+		if r.namespaced {
+			row = append(row, accessor.GetNamespace())
+		}
+		row = append(row, accessor.GetName())
+		row = append(row, r.columns(obj)...)
+		age := "<unknown>"
+		if created := accessor.GetCreationTimestamp(); !created.IsZero() {
+			age = duration.HumanDuration(now.Sub(created.Time))
+		}
+		fmt.Fprintln(tw, strings.Join(append(row, age), "\t"))
+	}
+	tw.Flush()
+}
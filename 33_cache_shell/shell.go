@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+)
+
+const prompt = "cache> "
+
+const helpText = `Commands:
+  resources                         list the resources, and which are synced or watched
+  get <resource> [-n ns] [-l sel]   list cached objects through the lister
+  show <resource> <key>             print one cached object as YAML (key is ns/name or name)
+  index <resource>                  list the indexes of the informer
+  index <resource> <index>          list the values of an index, with object counts
+  index <resource> <index> <value>  list the objects stored under a value
+  watch <resource>                  print events for the resource as they arrive
+  unwatch <resource>                stop printing events for the resource
+  help                              show this text
+  exit                              leave the shell
+`
+
+// errExit ends the shell loop
+var errExit = errors.New("exit")
+
+// shell answers queries from informer caches. An informer is created and
+// synced the first time its resource is used, then kept for the session, so
+// every later query is served from memory.
+type shell struct {
+	factory informers.SharedInformerFactory
+	stopCh  <-chan struct{}
+	now     func() time.Time
+
+	// mu serializes output; watch handlers print from informer goroutines
+	mu  sync.Mutex
+	out io.Writer
+
+	// synced and watches are only touched by the command loop
+	synced  map[string]bool
+	watches map[string]cache.ResourceEventHandlerRegistration
+}
+
+func newShell(clientset kubernetes.Interface, out io.Writer, stopCh <-chan struct{}) *shell {
+	return &shell{
+		factory: informers.NewSharedInformerFactory(clientset, 0),
+		stopCh:  stopCh,
+		now:     time.Now,
+		out:     out,
+		synced:  make(map[string]bool),
+		watches: make(map[string]cache.ResourceEventHandlerRegistration),
+	}
+}
+
+func (s *shell) printf(format string, args ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.out, format, args...)
+}
+
+// write prints the output of fn in one piece, never interleaved with events
+func (s *shell) write(fn func(w io.Writer)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.out)
+}
+
+// run reads commands from in until "exit" or the end of the input
+func (s *shell) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		s.printf(prompt)
+		if !scanner.Scan() {
+			s.printf("\n")
+			return scanner.Err()
+		}
+		err := s.exec(scanner.Text())
+		if err == errExit {
+			return nil
+		}
+		if err != nil {
+			s.printf("Error: %v\n", err)
+		}
+	}
+}
+
+// exec runs one command line
+func (s *shell) exec(line string) error {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return nil
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "help":
+		s.printf(helpText)
+		return nil
+	case "exit", "quit":
+		return errExit
+	case "resources":
+		s.listResources()
+		return nil
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("%s needs a resource, see \"help\"", command)
+	}
+	r, err := lookupResource(args[0])
+	if err != nil {
+		return err
+	}
+	args = args[1:]
+	switch command {
+	case "get":
+		return s.get(r, args)
+	case "show":
+		return s.show(r, args)
+	case "index":
+		return s.index(r, args)
+	case "watch":
+		return s.watch(r)
+	case "unwatch":
+		return s.unwatch(r)
+	}
+	return fmt.Errorf("unknown command %q, see \"help\"", command)
+}
+
+// informer returns the synced informer of r, starting it on first use
+func (s *shell) informer(r *resource) (cache.SharedIndexInformer, error) {
+	generic, err := s.factory.ForResource(r.gvr)
+	if err != nil {
+		return nil, err
+	}
+	informer := generic.Informer()
+	if s.synced[r.name] {
+		return informer, nil
+	}
+	// Indexers can only be added before the informer starts
+	if r.indexers != nil {
+		if err := informer.AddIndexers(r.indexers); err != nil {
+			return nil, err
+		}
+	}
+	s.printf("Syncing %s...\n", r.name)
+	s.factory.Start(s.stopCh)
+	if !cache.WaitForCacheSync(s.stopCh, informer.HasSynced) {
+		return nil, fmt.Errorf("%s cache did not sync", r.name)
+	}
+	s.synced[r.name] = true
+	return informer, nil
+}
+
+func (s *shell) listResources() {
+	s.write(func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tALIASES\tSYNCED\tWATCHING")
+		for _, r := range resources {
+			_, watching := s.watches[r.name]
+			fmt.Fprintf(tw, "%s\t%s\t%t\t%t\n", r.name, strings.Join(r.aliases, ","), s.synced[r.name], watching)
+		}
+		tw.Flush()
+	})
+}
+
+// get lists objects through the lister, like a controller would
+func (s *shell) get(r *resource, args []string) error {
+	flags := flag.NewFlagSet("get", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	namespace := flags.String("n", "", "namespace (all namespaces when empty)")
+	selector := flags.String("l", "", "label selector")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("get: %v", err)
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("get: unexpected argument %q", flags.Arg(0))
+	}
+	parsed, err := labels.Parse(*selector)
+	if err != nil {
+		return fmt.Errorf("get: %v", err)
+	}
+	if _, err := s.informer(r); err != nil {
+		return err
+	}
+	lister := s.lister(r)
+
+	var found []runtime.Object
+	if *namespace != "" && r.namespaced {
+		found, err = lister.ByNamespace(*namespace).List(parsed)
+	} else {
+		found, err = lister.List(parsed)
+	}
+	if err != nil {
+		return err
+	}
+	objs := make([]interface{}, len(found))
+	for i, obj := range found {
+		objs[i] = obj
+	}
+	s.write(func(w io.Writer) { printTable(w, r, objs, s.now()) })
+	return nil
+}
+
+// lister returns the generic lister of r's informer
+func (s *shell) lister(r *resource) cache.GenericLister {
+	generic, _ := s.factory.ForResource(r.gvr)
+	return generic.Lister()
+}
+
+// show prints one object straight from the cache, without managedFields
+func (s *shell) show(r *resource, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("show needs one key, e.g. \"show pods default/web\"")
+	}
+	informer, err := s.informer(r)
+	if err != nil {
+		return err
+	}
+	obj, exists, err := informer.GetIndexer().GetByKey(args[0])
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s %q is not in the cache", r.name, args[0])
+	}
+	// Never modify an object the cache hands out; strip a copy
+	copied := obj.(runtime.Object).DeepCopyObject()
+	if accessor, err := meta.Accessor(copied); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	data, err := yaml.Marshal(copied)
+	if err != nil {
+		return err
+	}
+	s.printf("%s", data)
+	return nil
+}
+
+// index queries the informer's indexer directly
+func (s *shell) index(r *resource, args []string) error {
+	informer, err := s.informer(r)
+	if err != nil {
+		return err
+	}
+	indexer := informer.GetIndexer()
+	switch len(args) {
+	case 0:
+		var names []string
+		for name := range indexer.GetIndexers() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		s.printf("Indexes of %s: %s\n", r.name, strings.Join(names, ", "))
+		return nil
+	case 1:
+		if _, ok := indexer.GetIndexers()[args[0]]; !ok {
+			return fmt.Errorf("%s has no index %q", r.name, args[0])
+		}
+		values := indexer.ListIndexFuncValues(args[0])
+		sort.Strings(values)
+		s.write(func(w io.Writer) {
+			tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(tw, "VALUE\tOBJECTS")
+			for _, value := range values {
+				keys, _ := indexer.IndexKeys(args[0], value)
+				if value == "" {
+					value = `""`
+				}
+				fmt.Fprintf(tw, "%s\t%d\n", value, len(keys))
+			}
+			tw.Flush()
+		})
+		return nil
+	case 2:
+		objs, err := indexer.ByIndex(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		s.write(func(w io.Writer) { printTable(w, r, objs, s.now()) })
+		return nil
+	}
+	return fmt.Errorf("index takes at most an index name and a value")
+}
+
+// watch adds an event handler that prints every change of r until unwatch
+// removes it. The informer replays its cache to a new handler as adds; those
+// are skipped, so only live events are printed.
+func (s *shell) watch(r *resource) error {
+	if _, ok := s.watches[r.name]; ok {
+		return fmt.Errorf("already watching %s", r.name)
+	}
+	informer, err := s.informer(r)
+	if err != nil {
+		return err
+	}
+	event := func(kind string, obj interface{}) {
+		key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		s.printf("[watch %s] %s %s\n", r.name, kind, key)
+	}
+	registration, err := informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if !isInInitialList {
+				event("ADDED", obj)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) { event("MODIFIED", newObj) },
+		DeleteFunc: func(obj interface{}) { event("DELETED", obj) },
+	})
+	if err != nil {
+		return err
+	}
+	s.watches[r.name] = registration
+	s.printf("Watching %s; \"unwatch %s\" to stop\n", r.name, r.name)
+	return nil
+}
+
+// unwatch removes the handler added by watch; the informer keeps running
+func (s *shell) unwatch(r *resource) error {
+	registration, ok := s.watches[r.name]
+	if !ok {
+		return fmt.Errorf("not watching %s", r.name)
+	}
+	informer, err := s.informer(r)
+	if err != nil {
+		return err
+	}
+	if err := informer.RemoveEventHandler(registration); err != nil {
+		return err
+	}
+	delete(s.watches, r.name)
+	s.printf("Stopped watching %s\n", r.name)
+	return nil
+}
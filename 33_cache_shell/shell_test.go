@@ -0,0 +1,159 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+var created = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testPod(namespace, name, node string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: namespace, Labels: labels,
+			CreationTimestamp: metav1.NewTime(created),
+			ManagedFields:     []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		},
+		Spec:   corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+// startShell returns a shell over h whose output is captured, and a function
+// running one command line that fails the test on error
+func startShell(t *testing.T, h *testutil.Harness) (*shell, func() string, func(string)) {
+	t.Helper()
+	output := testutil.CaptureOutput(t)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	s := newShell(h.Clientset, os.Stdout, stopCh)
+	s.now = func() time.Time { return created.Add(90 * time.Minute) }
+	exec := func(line string) {
+		t.Helper()
+		if err := s.exec(line); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+	}
+	return s, output, exec
+}
+
+func TestGetFiltersByNamespaceAndLabels(t *testing.T) {
+	h := testutil.NewHarness(t,
+		testPod("default", "web", "node-a", map[string]string{"app": "web"}),
+		testPod("default", "api", "node-b", map[string]string{"app": "api"}),
+		testPod("kube-system", "coredns", "node-a", nil),
+	)
+	_, output, exec := startShell(t, h)
+
+	exec("get po -n default -l app=web")
+	testutil.WaitForOutput(t, output,
+		"Syncing pods...\n",
+		"NAMESPACE   NAME   STATUS    NODE     AGE\ndefault     web    Running   node-a   90m\n",
+	)
+	if strings.Contains(output(), "api") || strings.Contains(output(), "coredns") {
+		t.Errorf("get returned pods outside the query:\n%s", output())
+	}
+
+	// The informer synced once; later queries are served from the cache
+	exec("get pods")
+	testutil.WaitForOutput(t, output, "kube-system   coredns")
+	if n := strings.Count(output(), "Syncing pods"); n != 1 {
+		t.Errorf("pods synced %d times, want once", n)
+	}
+}
+
+func TestIndexQueries(t *testing.T) {
+	h := testutil.NewHarness(t,
+		testPod("default", "web", "node-a", nil),
+		testPod("kube-system", "coredns", "node-a", nil),
+		testPod("default", "pending", "", nil),
+	)
+	_, output, exec := startShell(t, h)
+
+	exec("index pods")
+	testutil.WaitForOutput(t, output, "Indexes of pods: namespace, node\n")
+	exec("index pods node")
+	testutil.WaitForOutput(t, output, "VALUE    OBJECTS\n\"\"       1\nnode-a   2\n")
+	exec("index pods node node-a")
+	testutil.WaitForOutput(t, output, "default       web       Running", "kube-system   coredns   Running")
+}
+
+func TestShowPrintsYAMLWithoutManagedFields(t *testing.T) {
+	h := testutil.NewHarness(t, testPod("default", "web", "node-a", nil))
+	s, output, exec := startShell(t, h)
+
+	exec("show pods default/web")
+	testutil.WaitForOutput(t, output, "  name: web\n", "  nodeName: node-a\n")
+	if strings.Contains(output(), "managedFields") {
+		t.Errorf("show printed managedFields:\n%s", output())
+	}
+	// The cached object itself is untouched
+	pods, _ := lookupResource("pods")
+	informer, _ := s.informer(pods)
+	obj, _, _ := informer.GetIndexer().GetByKey("default/web")
+	if len(obj.(*corev1.Pod).ManagedFields) == 0 {
+		t.Error("show stripped managedFields from the cached pod")
+	}
+
+	if err := s.exec("show pods default/missing"); err == nil || !strings.Contains(err.Error(), "not in the cache") {
+		t.Errorf("show of a missing pod returned %v", err)
+	}
+}
+
+func TestWatchTogglesLiveEvents(t *testing.T) {
+	h := testutil.NewHarness(t, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}})
+	s, output, exec := startShell(t, h)
+
+	exec("watch deploy")
+	deployments, _ := lookupResource("deployments")
+	informer, _ := s.informer(deployments)
+	recorder := &testutil.Recorder{}
+	informer.AddEventHandler(recorder)
+	h.WaitForWatchOf(&appsv1.Deployment{}, 1)
+	h.Add(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"}})
+	h.Delete(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}})
+	testutil.WaitForOutput(t, output,
+		"[watch deployments] ADDED default/api\n",
+		"[watch deployments] DELETED default/nginx\n",
+	)
+	// Objects already cached are not replayed as events
+	if strings.Contains(output(), "ADDED default/nginx") {
+		t.Errorf("the initial list was printed as events:\n%s", output())
+	}
+
+	exec("unwatch deployments")
+	h.Add(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}})
+	// The informer keeps running without the handler
+	recorder.Expect(t, "add default/nginx", "add default/api", "delete default/nginx", "add default/db")
+	if strings.Contains(output(), "ADDED default/db") {
+		t.Errorf("events printed after unwatch:\n%s", output())
+	}
+}
+
+func TestRunReportsErrorsAndExits(t *testing.T) {
+	h := testutil.NewHarness(t)
+	s, output, _ := startShell(t, h)
+
+	input := "help\nget widgets\nfrobnicate pods\nget\nexit\nget pods\n"
+	if err := s.run(strings.NewReader(input)); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	testutil.WaitForOutput(t, output,
+		"cache> Commands:\n",
+		"Error: unknown resource \"widgets\", see \"resources\"\n",
+		"Error: unknown command \"frobnicate\", see \"help\"\n",
+		"Error: get needs a resource, see \"help\"\n",
+	)
+	// Nothing after exit runs
+	if strings.Contains(output(), "Syncing") {
+		t.Errorf("a command after exit ran:\n%s", output())
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// poller is the 03_without_informer approach: LIST every ConfigMap in the
+// namespace on every tick and look for the probe in the result
+type poller struct {
+	clientset kubernetes.Interface
+	namespace string
+	interval  time.Duration
+	jitter    float64
+	detector  *detector
+}
+
+func (p *poller) run(ctx context.Context) {
+	wait.JitterUntilWithContext(ctx, func(ctx context.Context) {
+		cms, err := p.clientset.CoreV1().ConfigMaps(p.namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[Poller] Failed to list configmaps: %v", err)
+			}
+			return
+		}
+		for i := range cms.Items {
+			p.detector.observe(&cms.Items[i])
+		}
+	}, p.interval, p.jitter, true)
+}
+
+// newConfigMapInformer is the informer approach: one LIST, then a WATCH that
+// delivers every change to the ConfigMaps in the namespace
+func newConfigMapInformer(clientset kubernetes.Interface, namespace string, d *detector) (cache.SharedIndexInformer, error) {
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+				return clientset.CoreV1().ConfigMaps(namespace).List(ctx, options)
+			},
+			WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+				return clientset.CoreV1().ConfigMaps(namespace).Watch(ctx, options)
+			},
+		},
+		&corev1.ConfigMap{},
+		0, // No resync: it would only replay the cache, not reach the API server
+		cache.Indexers{},
+	)
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			d.observe(obj.(*corev1.ConfigMap))
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			d.observe(newObj.(*corev1.ConfigMap))
+		},
+	})
+	return informer, err
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace      = flag.String("namespace", "default", "namespace both contenders watch, and where the probe ConfigMap is created")
+	duration       = flag.Duration("duration", 5*time.Minute, "how long to keep changing the probe")
+	changeInterval = flag.Duration("change-interval", 10*time.Second, "time between two changes to the probe")
+	interval       = flag.Duration("interval", 2*time.Second, "poller: time between two LIST requests")
+	jitter         = flag.Float64("jitter", 0.2, "poller: random extra delay per poll, as a fraction of --interval")
+)
+
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "34-polling-vs-informer")
+	return config
+}
+
+// countedClientset returns a clientset whose requests and response bytes are
+// added to usage. Each contender gets its own, so their traffic is not mixed.
+func countedClientset(config *rest.Config, usage *apiUsage) *kubernetes.Clientset {
+	config = rest.CopyConfig(config)
+	config.Wrap(usage.wrap)
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+func main() {
+	config := createConfig()
+	pollerUsage, informerUsage := &apiUsage{}, &apiUsage{}
+	pollerClient := countedClientset(config, pollerUsage)
+	informerClient := countedClientset(config, informerUsage)
+	// The probe writes are the same for both contenders, so they are not counted
+	probeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+
+	// Stop on Ctrl-C or SIGTERM; the results so far are still reported
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	p := newProbe(probeClient.CoreV1().ConfigMaps(*namespace))
+	if err := p.create(ctx); err != nil {
+		log.Fatalf("Failed to create probe ConfigMap: %v", err)
+	}
+	defer func() {
+		// ctx may be cancelled already, the cleanup must still go through
+		if err := p.delete(context.Background()); err != nil {
+			log.Printf("Failed to delete probe ConfigMap: %v", err)
+		}
+	}()
+
+	pollerSeen, informerSeen := newDetector(), newDetector()
+	polling := &poller{clientset: pollerClient, namespace: *namespace, interval: *interval, jitter: *jitter, detector: pollerSeen}
+	informer, err := newConfigMapInformer(informerClient, *namespace, informerSeen)
+	if err != nil {
+		log.Fatalf("Failed to add event handler: %v", err)
+	}
+
+	contendersCtx, stopContenders := context.WithCancel(ctx)
+	defer stopContenders()
+	var contenders wait.Group
+	start := time.Now()
+	contenders.StartWithContext(contendersCtx, polling.run)
+	contenders.StartWithContext(contendersCtx, informer.RunWithContext)
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		log.Fatal("Failed to sync informer cache")
+	}
+
+	log.Printf("Changing %s/%s every %s for %s", *namespace, probeName, *changeInterval, *duration)
+	changesCtx, stopChanges := context.WithTimeout(ctx, *duration)
+	defer stopChanges()
+	wait.UntilWithContext(changesCtx, func(ctx context.Context) {
+		if err := p.bump(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to change probe ConfigMap: %v", err)
+		}
+	}, *changeInterval)
+
+	// Give the poller one more full interval to notice the last change
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Duration(float64(*interval) * (1 + *jitter))):
+	}
+	stopContenders()
+	contenders.Wait()
+	elapsed := time.Since(start)
+
+	writes := p.writes()
+	printComparison(os.Stdout, elapsed,
+		summarize(fmt.Sprintf("POLLER (%s)", *interval), pollerUsage, pollerSeen, writes),
+		summarize("INFORMER", informerUsage, informerSeen, writes),
+	)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func probeAt(generation string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:        probeName,
+		Annotations: map[string]string{generationAnnotation: generation},
+	}}
+}
+
+func TestDetectorKeepsTheFirstSighting(t *testing.T) {
+	d := newDetector()
+	d.observe(probeAt("1"))
+	first := d.seen[1]
+	time.Sleep(time.Millisecond)
+	d.observe(probeAt("1"))
+	if d.seen[1] != first {
+		t.Error("a later sighting replaced the first one")
+	}
+
+	// Other ConfigMaps and unnumbered probes are ignored
+	other := probeAt("2")
+	other.Name = "kube-root-ca.crt"
+	d.observe(other)
+	d.observe(probeAt("not-a-number"))
+	if len(d.seen) != 1 {
+		t.Errorf("seen = %v, want generation 1 only", d.seen)
+	}
+}
+
+func TestSummarizeCountsMissedChangesAndLatencies(t *testing.T) {
+	start := time.Now()
+	writes := map[int]time.Time{
+		1: start,
+		2: start.Add(10 * time.Second),
+		3: start.Add(20 * time.Second),
+		4: start.Add(30 * time.Second),
+	}
+	d := newDetector()
+	d.seen[1] = start.Add(100 * time.Millisecond)
+	d.seen[3] = start.Add(22 * time.Second)
+	d.seen[4] = start.Add(30*time.Second - time.Millisecond) // event beat the Update response
+
+	usage := &apiUsage{}
+	usage.requests.Store(7)
+	r := summarize("POLLER", usage, d, writes)
+	if r.requests != 7 || r.changes != 4 || len(r.latencies) != 3 {
+		t.Fatalf("got %d requests, %d/%d changes seen, want 7 and 3/4", r.requests, len(r.latencies), r.changes)
+	}
+	if got := r.percentile(50); got != 100*time.Millisecond {
+		t.Errorf("p50 = %s, want 100ms", got)
+	}
+	if got := r.percentile(100); got != 2*time.Second {
+		t.Errorf("max = %s, want 2s", got)
+	}
+	if r.latencies[0] != 0 {
+		t.Errorf("negative latency was not clamped: %s", r.latencies[0])
+	}
+}
+
+func TestBothContendersDetectProbeChanges(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := newProbe(clientset.CoreV1().ConfigMaps("default"))
+	if err := p.create(ctx); err != nil {
+		t.Fatalf("create probe: %v", err)
+	}
+	pollerSeen, informerSeen := newDetector(), newDetector()
+	polling := &poller{clientset: clientset, namespace: "default", interval: 10 * time.Millisecond, jitter: 0.5, detector: pollerSeen}
+	informer, err := newConfigMapInformer(clientset, "default", informerSeen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contenders wait.Group
+	defer contenders.Wait()
+	defer cancel()
+	contenders.StartWithContext(ctx, polling.run)
+	contenders.StartWithContext(ctx, informer.RunWithContext)
+
+	for generation := 1; generation <= 3; generation++ {
+		if err := p.bump(ctx); err != nil {
+			t.Fatalf("bump: %v", err)
+		}
+		err := wait.PollUntilContextTimeout(ctx, time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+			return pollerSeen.sawGeneration(generation) && informerSeen.sawGeneration(generation), nil
+		})
+		if err != nil {
+			t.Fatalf("generation %d: poller saw it %t, informer saw it %t", generation,
+				pollerSeen.sawGeneration(generation), informerSeen.sawGeneration(generation))
+		}
+	}
+	if writes := p.writes(); len(writes) != 3 {
+		t.Errorf("recorded %d writes, want 3", len(writes))
+	}
+}
+
+func TestComparisonTable(t *testing.T) {
+	poller := result{name: "POLLER (2s)", requests: 150, bytes: 3 << 20, changes: 30,
+		latencies: []time.Duration{time.Second, 1500 * time.Millisecond}}
+	informer := result{name: "INFORMER", requests: 3, bytes: 12 << 10, changes: 30,
+		latencies: []time.Duration{12 * time.Millisecond, 40 * time.Millisecond}}
+	var out bytes.Buffer
+	printComparison(&out, 5*time.Minute, poller, informer)
+
+	for _, want := range []string{
+		"=== Polling vs informer: 5m0s ===",
+		"METRIC            POLLER (2s)   INFORMER",
+		"API requests      150           3",
+		"Requests/minute   30.0          0.6",
+		"Bytes received    3.0Mi         12.0Ki",
+		"Changes seen      2/30          2/30",
+		"Latency max       1.5s          40ms",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+}
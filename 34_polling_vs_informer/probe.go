@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// probeName is the ConfigMap the benchmark changes at a known time
+	probeName = "polling-vs-informer-probe"
+	// generationAnnotation numbers the changes, so a sighting maps back to a write
+	generationAnnotation = "k8s-lab.io/probe-generation"
+)
+
+// probe changes the probe ConfigMap and remembers when each change was sent
+type probe struct {
+	client corev1client.ConfigMapInterface
+
+	mu         sync.Mutex
+	generation int
+	written    map[int]time.Time
+}
+
+func newProbe(client corev1client.ConfigMapInterface) *probe {
+	return &probe{client: client, written: map[int]time.Time{}}
+}
+
+// create creates the probe at generation 0, which is not counted as a change
+func (p *probe) create(ctx context.Context) error {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:        probeName,
+		Annotations: map[string]string{generationAnnotation: "0"},
+	}}
+	_, err := p.client.Create(ctx, cm, metav1.CreateOptions{})
+	return err
+}
+
+// bump writes the next generation. The clock starts before the request is
+// sent, so both contenders are charged the same write round trip.
+func (p *probe) bump(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cm, err := p.client.Get(ctx, probeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	generation := p.generation + 1
+	cm.Annotations[generationAnnotation] = strconv.Itoa(generation)
+	sent := time.Now()
+	if _, err := p.client.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	p.generation = generation
+	p.written[generation] = sent
+	return nil
+}
+
+// writes returns the send time of every change made so far
+func (p *probe) writes() map[int]time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	writes := make(map[int]time.Time, len(p.written))
+	for generation, sent := range p.written {
+		writes[generation] = sent
+	}
+	return writes
+}
+
+func (p *probe) delete(ctx context.Context) error {
+	return p.client.Delete(ctx, probeName, metav1.DeleteOptions{})
+}
+
+// detector records when one contender first saw each probe generation
+type detector struct {
+	mu   sync.Mutex
+	seen map[int]time.Time
+}
+
+func newDetector() *detector {
+	return &detector{seen: map[int]time.Time{}}
+}
+
+// observe notes a sighting of cm if it is the probe at a generation not seen before
+func (d *detector) observe(cm *corev1.ConfigMap) {
+	if cm.Name != probeName {
+		return
+	}
+	generation, err := strconv.Atoi(cm.Annotations[generationAnnotation])
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.seen[generation]; !ok {
+		d.seen[generation] = now
+	}
+}
+
+// sawGeneration reports whether the detector has seen the given generation
+func (d *detector) sawGeneration(generation int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.seen[generation]
+	return ok
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// result is what one contender cost and how quickly it noticed the changes
+type result struct {
+	name      string
+	requests  int64
+	bytes     int64
+	changes   int
+	latencies []time.Duration // sorted, one per change that was seen
+}
+
+// summarize matches every write to the contender's first sighting of it. A
+// change that was overwritten before the contender looked counts as missed.
+func summarize(name string, usage *apiUsage, d *detector, writes map[int]time.Time) result {
+	r := result{name: name, requests: usage.requests.Load(), bytes: usage.bytes.Load(), changes: len(writes)}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for generation, sent := range writes {
+		seen, ok := d.seen[generation]
+		if !ok {
+			continue
+		}
+		// A watch event can race the Update response, never the request
+		r.latencies = append(r.latencies, max(seen.Sub(sent), 0))
+	}
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	return r
+}
+
+// percentile returns the p-th percentile (0-100) of the detection latencies
+func (r result) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.latencies))*p/100+0.5) - 1
+	return r.latencies[min(max(i, 0), len(r.latencies)-1)]
+}
+
+// formatLatency rounds a latency to a readable precision, "-" if nothing was seen
+func (r result) formatLatency(d time.Duration) string {
+	switch {
+	case len(r.latencies) == 0:
+		return "-"
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	default:
+		return d.Round(time.Millisecond).String()
+	}
+}
+
+// printComparison prints the contenders side by side
+func printComparison(w io.Writer, elapsed time.Duration, results ...result) {
+	fmt.Fprintf(w, "\n=== Polling vs informer: %s ===\n", elapsed.Round(time.Second))
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	row := func(metric string, cell func(r result) string) {
+		fmt.Fprint(tw, metric)
+		for _, r := range results {
+			fmt.Fprintf(tw, "\t%s", cell(r))
+		}
+		fmt.Fprintln(tw)
+	}
+	row("METRIC", func(r result) string { return r.name })
+	row("API requests", func(r result) string { return fmt.Sprint(r.requests) })
+	row("Requests/minute", func(r result) string {
+		return fmt.Sprintf("%.1f", float64(r.requests)/elapsed.Minutes())
+	})
+	row("Bytes received", func(r result) string { return humanBytes(r.bytes) })
+	row("Changes seen", func(r result) string { return fmt.Sprintf("%d/%d", len(r.latencies), r.changes) })
+	row("Latency p50", func(r result) string { return r.formatLatency(r.percentile(50)) })
+	row("Latency p95", func(r result) string { return r.formatLatency(r.percentile(95)) })
+	row("Latency max", func(r result) string { return r.formatLatency(r.percentile(100)) })
+	tw.Flush()
+}
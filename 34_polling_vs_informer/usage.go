@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// apiUsage counts the requests a clientset sends and the response bytes it
+// reads. Install it with rest.Config.Wrap(usage.wrap).
+type apiUsage struct {
+	requests atomic.Int64
+	bytes    atomic.Int64
+}
+
+func (u *apiUsage) wrap(rt http.RoundTripper) http.RoundTripper {
+	return &countingTransport{next: rt, usage: u}
+}
+
+// countingTransport counts every round trip, and the body bytes as they are read
+type countingTransport struct {
+	next  http.RoundTripper
+	usage *apiUsage
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.usage.requests.Add(1)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, bytes: &t.usage.bytes}
+	return resp, nil
+}
+
+type countingBody struct {
+	io.ReadCloser
+	bytes *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes.Add(int64(n))
+	return n, err
+}
+
+// humanBytes formats a byte count
+func humanBytes(b int64) string {
+	switch {
+	case b >= 1<<20:
+		return fmt.Sprintf("%.1fMi", float64(b)/(1<<20))
+	case b >= 1<<10:
+		return fmt.Sprintf("%.1fKi", float64(b)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", b)
+	}
+}
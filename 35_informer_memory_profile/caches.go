@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// The three ways this program can cache pods, from largest to smallest
+const (
+	// modeFull caches complete Pod objects, like 04-10 without a transform
+	modeFull = "full"
+	// modeTransformed drops managedFields and the last-applied annotation
+	// before caching, like 09_shared_informer_factory_custom_index
+	modeTransformed = "transformed"
+	// modeMetadata caches only ObjectMeta, as PartialObjectMetadata: the
+	// metadata client asks the API server to send nothing else
+	modeMetadata = "metadata"
+)
+
+var modes = []string{modeFull, modeTransformed, modeMetadata}
+
+// resyncPeriod is 0: a resync only replays the cache and would not change its size
+const resyncPeriod = 0
+
+// newPodInformer returns a pod informer that caches pods the way mode says
+func newPodInformer(mode string, clientset kubernetes.Interface, metadataClient metadata.Interface, namespace string) (cache.SharedIndexInformer, error) {
+	switch mode {
+	case modeFull:
+		factory := informers.NewSharedInformerFactoryWithOptions(clientset, resyncPeriod, informers.WithNamespace(namespace))
+		return factory.Core().V1().Pods().Informer(), nil
+	case modeTransformed:
+		factory := informers.NewSharedInformerFactoryWithOptions(clientset, resyncPeriod,
+			informers.WithNamespace(namespace), informers.WithTransform(stripForCache))
+		return factory.Core().V1().Pods().Informer(), nil
+	case modeMetadata:
+		factory := metadatainformer.NewFilteredSharedInformerFactory(metadataClient, resyncPeriod, namespace, nil)
+		return factory.ForResource(corev1.SchemeGroupVersion.WithResource("pods")).Informer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mode %q, want one of %v", mode, modes)
+	}
+}
+
+// stripForCache is a cache.TransformFunc dropping the metadata nobody in this
+// program reads. It modifies the object in place, which client-go allows
+// since v1.27.
+func stripForCache(obj interface{}) (interface{}, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		// Tombstones pass through untouched
+		return obj, nil
+	}
+	accessor.SetManagedFields(nil)
+	if annotations := accessor.GetAnnotations(); annotations != nil {
+		delete(annotations, corev1.LastAppliedConfigAnnotation)
+	}
+	return obj, nil
+}
+
+// waitForSync runs informer until ctx is cancelled and returns how long the
+// initial LIST took to land in the cache
+func waitForSync(ctx context.Context, informer cache.SharedIndexInformer) (time.Duration, bool) {
+	start := time.Now()
+	go informer.RunWithContext(ctx)
+	synced := cache.WaitForCacheSync(ctx.Done(), informer.HasSynced)
+	return time.Since(start), synced
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	mode        = flag.String("mode", modeFull, "how pods are cached: full, transformed or metadata")
+	namespace   = flag.String("namespace", "", "only cache the pods of this namespace")
+	pprofAddr   = flag.String("pprof-addr", "", "serve net/http/pprof on this address, e.g. localhost:6060, and keep running until interrupted")
+	heapProfile = flag.String("heap-profile", "", "write a heap profile to this file once the cache has synced")
+)
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "35-informer-memory-profile")
+	return config
+}
+
+// printReport shows what the synced cache costs in heap
+func printReport(mode string, objects int, took time.Duration, cacheBytes uint64) {
+	fmt.Printf("\n=== Pod cache (mode: %s) ===\n", mode)
+	fmt.Printf("Pods cached: %d (synced in %s)\n", objects, took.Round(time.Millisecond))
+	fmt.Printf("Heap in use by the cache: %s\n", mib(cacheBytes))
+	if objects > 0 {
+		fmt.Printf("Per pod: %.1f KiB\n", float64(cacheBytes)/float64(objects)/1024)
+	}
+}
+
+func main() {
+	config := createConfig()
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	metadataClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create metadata client: %v", err)
+	}
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *pprofAddr != "" {
+		servePprof(*pprofAddr)
+		fmt.Printf("Serving pprof on http://%s/debug/pprof/\n", *pprofAddr)
+	}
+
+	// Measure the heap before the informer exists, so the difference after the
+	// sync is what the cache (and the informer machinery) holds
+	baseline := heapInUse()
+	informer, err := newPodInformer(*mode, clientset, metadataClient, *namespace)
+	if err != nil {
+		log.Fatal(err)
+	}
+	took, synced := waitForSync(ctx, informer)
+	if !synced {
+		return
+	}
+	objects := len(informer.GetStore().ListKeys())
+	var cacheBytes uint64
+	if after := heapInUse(); after > baseline {
+		cacheBytes = after - baseline
+	}
+	printReport(*mode, objects, took, cacheBytes)
+
+	if *heapProfile != "" {
+		if err := writeHeapProfile(*heapProfile); err != nil {
+			log.Fatalf("Failed to write heap profile: %v", err)
+		}
+		fmt.Printf("Heap profile written to %s\n", *heapProfile)
+	}
+	if *pprofAddr != "" {
+		fmt.Println("Press Ctrl-C to exit")
+		<-ctx.Done()
+	}
+	// The cache must stay reachable until everything above was measured
+	runtime.KeepAlive(informer)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func bloatedPod(name string) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:          name,
+			Namespace:     "default",
+			Labels:        map[string]string{"app": "web"},
+			Annotations:   map[string]string{corev1.LastAppliedConfigAnnotation: `{"kind":"Pod"}`, "team": "web"},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply}},
+		},
+		Spec: corev1.PodSpec{NodeName: "node-a", Containers: []corev1.Container{{Name: "web", Image: "nginx"}}},
+	}
+}
+
+// cachedPod syncs an informer in the given mode and returns the cached default/web
+func cachedPod(t *testing.T, mode string) interface{} {
+	t.Helper()
+	pod := bloatedPod("web")
+	clientset := fake.NewSimpleClientset(pod)
+	partial := &metav1.PartialObjectMetadata{TypeMeta: pod.TypeMeta, ObjectMeta: *pod.ObjectMeta.DeepCopy()}
+	scheme := metadatafake.NewTestScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	metav1.AddMetaToScheme(scheme)
+	metadataClient := metadatafake.NewSimpleMetadataClient(scheme, partial)
+
+	informer, err := newPodInformer(mode, clientset, metadataClient, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, synced := waitForSync(ctx, informer); !synced {
+		t.Fatal("cache did not sync")
+	}
+	obj, exists, err := informer.GetStore().GetByKey("default/web")
+	if err != nil || !exists {
+		t.Fatalf("default/web not cached: %v", err)
+	}
+	return obj
+}
+
+func TestFullModeCachesWholePods(t *testing.T) {
+	pod, ok := cachedPod(t, modeFull).(*corev1.Pod)
+	if !ok {
+		t.Fatal("cached object is not a *corev1.Pod")
+	}
+	if pod.Spec.NodeName != "node-a" || len(pod.ManagedFields) != 1 {
+		t.Errorf("full mode lost data: node %q, %d managedFields", pod.Spec.NodeName, len(pod.ManagedFields))
+	}
+}
+
+func TestTransformedModeStripsUnreadMetadata(t *testing.T) {
+	pod, ok := cachedPod(t, modeTransformed).(*corev1.Pod)
+	if !ok {
+		t.Fatal("cached object is not a *corev1.Pod")
+	}
+	if len(pod.ManagedFields) != 0 {
+		t.Error("managedFields were cached")
+	}
+	if _, ok := pod.Annotations[corev1.LastAppliedConfigAnnotation]; ok {
+		t.Error("last-applied annotation was cached")
+	}
+	if pod.Annotations["team"] != "web" || pod.Spec.NodeName != "node-a" {
+		t.Error("the transform dropped more than it should")
+	}
+}
+
+func TestMetadataModeCachesOnlyObjectMeta(t *testing.T) {
+	obj := cachedPod(t, modeMetadata)
+	partial, ok := obj.(*metav1.PartialObjectMetadata)
+	if !ok {
+		t.Fatalf("cached %T, want *metav1.PartialObjectMetadata", obj)
+	}
+	if partial.Labels["app"] != "web" {
+		t.Errorf("labels = %v", partial.Labels)
+	}
+}
+
+func TestUnknownModeIsRejected(t *testing.T) {
+	if _, err := newPodInformer("compressed", fake.NewSimpleClientset(), nil, ""); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func TestWriteHeapProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heap.pprof")
+	if err := writeHeapProfile(path); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		t.Errorf("heap profile missing or empty: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// refreshInterval caps the redraw rate. A rollout can send hundreds of pod
+// events a second; the screen only needs to keep up with a human.
+const refreshInterval = 250 * time.Millisecond
+
+// tickMsg asks the dashboard to redraw if anything changed since the last tick
+type tickMsg struct{}
+
+func tick() tea.Cmd {
+	return tea.Tick(refreshInterval, func(time.Time) tea.Msg { return tickMsg{} })
+}
+
+// dashboard is the bubbletea model drawing pod tables from the informer
+// cache. Event handlers only mark the screen stale; the tables are always
+// rebuilt from the lister, so the screen shows the cache and never a partial
+// copy of it.
+type dashboard struct {
+	lister corelisters.PodLister
+	now    func() time.Time
+
+	// changed holds at most one pending redraw, however many events arrive
+	changed chan struct{}
+	events  atomic.Int64
+
+	// Only touched by the bubbletea program
+	view   int
+	height int
+	screen string
+}
+
+func newDashboard(lister corelisters.PodLister) *dashboard {
+	return &dashboard{
+		lister:  lister,
+		now:     time.Now,
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// markChanged asks for a redraw without ever blocking the informer
+func (d *dashboard) markChanged() {
+	d.events.Add(1)
+	select {
+	case d.changed <- struct{}{}:
+	default:
+	}
+}
+
+// handler is registered on the pod informer
+func (d *dashboard) handler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { d.markChanged() },
+		UpdateFunc: func(oldObj, newObj interface{}) { d.markChanged() },
+		DeleteFunc: func(obj interface{}) { d.markChanged() },
+	}
+}
+
+// Init draws the first frame and starts the refresh ticks
+func (d *dashboard) Init() tea.Cmd {
+	d.render()
+	return tick()
+}
+
+// Update handles keys, terminal resizes and ticks. The frame is only rebuilt
+// when one of them changed something; View returns the last one.
+func (d *dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if !d.handleKey(msg.String()) {
+			return d, tea.Quit
+		}
+		d.render()
+	case tea.WindowSizeMsg:
+		d.height = msg.Height
+		d.render()
+	case tickMsg:
+		select {
+		case <-d.changed:
+			d.render()
+		default:
+		}
+		return d, tick()
+	}
+	return d, nil
+}
+
+// View returns the frame bubbletea puts on the screen
+func (d *dashboard) View() string {
+	return d.screen
+}
+
+// handleKey switches views and reports false when the dashboard should quit
+func (d *dashboard) handleKey(key string) bool {
+	switch key {
+	case "q", "ctrl+c":
+		return false
+	case "tab":
+		d.view = (d.view + 1) % len(groupings)
+	default:
+		if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= len(groupings) {
+			d.view = n - 1
+		}
+	}
+	return true
+}
+
+// render rebuilds the frame of the current view from the lister
+func (d *dashboard) render() {
+	var buf strings.Builder
+	d.frame(&buf)
+	d.screen = buf.String()
+}
+
+// frame writes the tables of the current view
+func (d *dashboard) frame(w io.Writer) {
+	g := groupings[d.view]
+	pods, err := d.lister.List(labels.Everything())
+	if err != nil {
+		fmt.Fprintf(w, "Failed to list pods: %v\n", err)
+		return
+	}
+	rows, total := summarize(pods, g)
+
+	fmt.Fprintf(w, "Pods by %s  %s  %d events\n\n", g.name, d.now().Format("15:04:05"), d.events.Load())
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tPODS\tRUNNING\tPENDING\tSUCCEEDED\tFAILED\tUNKNOWN\tRESTARTS\n", strings.ToUpper(g.name))
+	shown := rows
+	// Header, blank line, table header, total and footer take 5 lines
+	if d.height > 0 && len(rows) > d.height-6 {
+		shown = rows[:max(d.height-6, 0)]
+	}
+	for _, r := range shown {
+		printRow(tw, r)
+	}
+	if hidden := len(rows) - len(shown); hidden > 0 {
+		fmt.Fprintf(tw, "... %d more\t\t\t\t\t\t\t\n", hidden)
+	}
+	printRow(tw, total)
+	tw.Flush()
+
+	var keys []string
+	for i, g := range groupings {
+		keys = append(keys, fmt.Sprintf("%d %s", i+1, g.name))
+	}
+	fmt.Fprintf(w, "\n[%s]  tab next view  q quit\n", strings.Join(keys, "] ["))
+}
+
+func printRow(w io.Writer, r row) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n",
+		r.name, r.pods, r.running, r.pending, r.succeeded, r.failed, r.unknown, r.restarts)
+}
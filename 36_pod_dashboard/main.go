@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var namespace = flag.String("namespace", "", "only show the pods of this namespace (default all namespaces)")
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "36-pod-dashboard")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(*namespace))
+	podInformer := factory.Core().V1().Pods()
+	d := newDashboard(podInformer.Lister())
+	if _, err := podInformer.Informer().AddEventHandler(d.handler()); err != nil {
+		log.Fatalf("Failed to add event handler: %v", err)
+	}
+	defer factory.Shutdown()
+	factory.Start(ctx.Done())
+
+	fmt.Println("Syncing pods...")
+	if !cache.WaitForCacheSync(ctx.Done(), podInformer.Informer().HasSynced) {
+		return
+	}
+
+	// The alternate screen is left and the terminal restored when the
+	// program ends, whether by q, Ctrl-C or SIGTERM cancelling ctx
+	opts := []tea.ProgramOption{tea.WithAltScreen(), tea.WithContext(ctx)}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		// Without a terminal, e.g. when piped, there are no keys to read and
+		// the dashboard redraws until interrupted
+		opts = append(opts, tea.WithInput(nil))
+	}
+	if _, err := tea.NewProgram(d, opts...).Run(); err != nil && ctx.Err() == nil {
+		log.Fatalf("Dashboard failed: %v", err)
+	}
+}
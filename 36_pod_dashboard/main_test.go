@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newPod(namespace, name, node string, phase corev1.PodPhase, restarts int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{
+			Phase:             phase,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: restarts}},
+		},
+	}
+}
+
+var testPods = []*corev1.Pod{
+	newPod("web", "web-1", "node-a", corev1.PodRunning, 0),
+	newPod("web", "web-2", "node-b", corev1.PodRunning, 2),
+	newPod("web", "web-3", "", corev1.PodPending, 0),
+	newPod("batch", "job-1", "node-a", corev1.PodFailed, 5),
+}
+
+func TestSummarizeByNamespace(t *testing.T) {
+	rows, total := summarize(testPods, groupings[0])
+	want := []row{
+		{name: "web", pods: 3, running: 2, pending: 1, restarts: 2},
+		{name: "batch", pods: 1, failed: 1, restarts: 5},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %+v", rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+	if total.pods != 4 || total.restarts != 7 {
+		t.Errorf("total = %+v", total)
+	}
+}
+
+func TestSummarizeByNodeShowsUnscheduledPods(t *testing.T) {
+	rows, _ := summarize(testPods, groupings[1])
+	var names []string
+	for _, r := range rows {
+		names = append(names, r.name)
+	}
+	if got := strings.Join(names, ","); got != "node-a,<unscheduled>,node-b" {
+		t.Errorf("nodes = %s", got)
+	}
+}
+
+func TestSummarizeByPhase(t *testing.T) {
+	rows, _ := summarize(testPods, groupings[2])
+	if rows[0].name != "Running" || rows[0].pods != 2 || rows[0].running != 2 {
+		t.Errorf("first row = %+v", rows[0])
+	}
+}
+
+// newTestDashboard syncs a pod informer over a fake clientset
+func newTestDashboard(t *testing.T) (*dashboard, *fake.Clientset) {
+	t.Helper()
+	var objects []runtime.Object
+	for _, pod := range testPods {
+		objects = append(objects, pod)
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	podInformer := factory.Core().V1().Pods()
+	d := newDashboard(podInformer.Lister())
+	d.now = func() time.Time { return time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC) }
+	if _, err := podInformer.Informer().AddEventHandler(d.handler()); err != nil {
+		t.Fatal(err)
+	}
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		factory.Shutdown()
+	})
+	factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, podInformer.Informer().HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+	return d, clientset
+}
+
+func TestEventsCoalesceIntoOneRedraw(t *testing.T) {
+	d, _ := newTestDashboard(t)
+	// The initial list delivered one add per pod, but only one redraw is pending
+	if got := d.events.Load(); got != int64(len(testPods)) {
+		t.Errorf("events = %d, want %d", got, len(testPods))
+	}
+	<-d.changed
+	select {
+	case <-d.changed:
+		t.Error("more than one redraw pending")
+	default:
+	}
+}
+
+func TestFrameShowsTheSelectedView(t *testing.T) {
+	d, _ := newTestDashboard(t)
+	d.handleKey("2")
+	var buf bytes.Buffer
+	d.frame(&buf)
+	frame := buf.String()
+	for _, want := range []string{"Pods by node", "NODE", "<unscheduled>", "TOTAL", "[1 namespace] [2 node] [3 phase]"} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("frame lacks %q:\n%s", want, frame)
+		}
+	}
+}
+
+func TestFrameTruncatesToTheTerminalHeight(t *testing.T) {
+	d, _ := newTestDashboard(t)
+	d.handleKey("2")
+	d.height = 8
+	var buf bytes.Buffer
+	d.frame(&buf)
+	if !strings.Contains(buf.String(), "... 1 more") {
+		t.Errorf("frame not truncated:\n%s", buf.String())
+	}
+}
+
+// key returns the message bubbletea sends for a key press
+func key(k string) tea.KeyMsg {
+	switch k {
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	case "ctrl+c":
+		return tea.KeyMsg{Type: tea.KeyCtrlC}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+func TestUpdateSwitchesViewsAndQuits(t *testing.T) {
+	d, _ := newTestDashboard(t)
+	d.Init()
+	for _, k := range []string{"tab", "3"} {
+		if _, cmd := d.Update(key(k)); cmd != nil {
+			t.Errorf("%s returned a command", k)
+		}
+	}
+	if d.view != 2 || !strings.Contains(d.View(), "Pods by phase") {
+		t.Errorf("view = %d, screen:\n%s", d.view, d.View())
+	}
+	for _, k := range []string{"q", "ctrl+c"} {
+		if _, cmd := d.Update(key(k)); cmd == nil || cmd() != tea.Quit() {
+			t.Errorf("%s does not quit", k)
+		}
+	}
+}
+
+func TestUpdateFitsTheWindow(t *testing.T) {
+	d, _ := newTestDashboard(t)
+	d.handleKey("2")
+	d.Update(tea.WindowSizeMsg{Width: 80, Height: 8})
+	if !strings.Contains(d.View(), "... 1 more") {
+		t.Errorf("frame not truncated:\n%s", d.View())
+	}
+}
+
+func TestTickRedrawsAfterAnEvent(t *testing.T) {
+	d, clientset := newTestDashboard(t)
+	d.Init()
+	<-d.changed // drop the redraw the initial list asked for
+
+	pod := newPod("web", "web-4", "node-b", corev1.PodRunning, 0)
+	if _, err := clientset.CoreV1().Pods("web").Create(t.Context(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// The frame is only rebuilt on the next tick
+	for deadline := time.Now().Add(5 * time.Second); len(d.changed) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if regexp.MustCompile(`web +4 +3 `).MatchString(d.View()) {
+		t.Fatal("frame changed before the tick")
+	}
+	if _, cmd := d.Update(tickMsg{}); cmd == nil {
+		t.Error("tick did not schedule the next one")
+	}
+	if !regexp.MustCompile(`web +4 +3 `).MatchString(d.View()) {
+		t.Errorf("frame does not show the new pod:\n%s", d.View())
+	}
+}
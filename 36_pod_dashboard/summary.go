@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// grouping is one way of slicing the pods into table rows
+type grouping struct {
+	name string
+	key  func(pod *corev1.Pod) string
+}
+
+// groupings are the dashboard views, in the order the number keys select them
+var groupings = []grouping{
+	{name: "namespace", key: func(pod *corev1.Pod) string { return pod.Namespace }},
+	{name: "node", key: func(pod *corev1.Pod) string {
+		if pod.Spec.NodeName == "" {
+			return "<unscheduled>"
+		}
+		return pod.Spec.NodeName
+	}},
+	{name: "phase", key: func(pod *corev1.Pod) string {
+		if pod.Status.Phase == "" {
+			return string(corev1.PodUnknown)
+		}
+		return string(pod.Status.Phase)
+	}},
+}
+
+// row counts the pods of one group by phase
+type row struct {
+	name      string
+	pods      int
+	running   int
+	pending   int
+	succeeded int
+	failed    int
+	unknown   int
+	restarts  int32
+}
+
+func (r *row) add(pod *corev1.Pod) {
+	r.pods++
+	switch pod.Status.Phase {
+	case corev1.PodRunning:
+		r.running++
+	case corev1.PodPending:
+		r.pending++
+	case corev1.PodSucceeded:
+		r.succeeded++
+	case corev1.PodFailed:
+		r.failed++
+	default:
+		r.unknown++
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		r.restarts += status.RestartCount
+	}
+}
+
+// summarize groups pods into rows, largest group first, plus a total row
+func summarize(pods []*corev1.Pod, g grouping) (rows []row, total row) {
+	byName := make(map[string]*row)
+	total.name = "TOTAL"
+	for _, pod := range pods {
+		name := g.key(pod)
+		r, ok := byName[name]
+		if !ok {
+			r = &row{name: name}
+			byName[name] = r
+		}
+		r.add(pod)
+		total.add(pod)
+	}
+	rows = make([]row, 0, len(byName))
+	for _, r := range byName {
+		rows = append(rows, *r)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].pods != rows[j].pods {
+			return rows[i].pods > rows[j].pods
+		}
+		return rows[i].name < rows[j].name
+	})
+	return rows, total
+}
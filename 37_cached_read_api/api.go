@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Index names on the pod informer
+const (
+	nodeIndex  = "node"
+	phaseIndex = "phase"
+)
+
+// podNodeIndexFunc indexes a pod by the name of the node it is scheduled on
+func podNodeIndexFunc(obj interface{}) ([]string, error) {
+	pod := obj.(*corev1.Pod)
+	return []string{pod.Spec.NodeName}, nil
+}
+
+// podPhaseIndexFunc indexes a pod by its phase (Running, Pending, etc.)
+func podPhaseIndexFunc(obj interface{}) ([]string, error) {
+	pod := obj.(*corev1.Pod)
+	return []string{string(pod.Status.Phase)}, nil
+}
+
+// podSummary is the JSON form of a pod. Clients get the fields they ask
+// about, not the whole object with its managedFields.
+type podSummary struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Node      string `json:"node,omitempty"`
+	Phase     string `json:"phase"`
+	PodIP     string `json:"podIP,omitempty"`
+	Restarts  int32  `json:"restarts"`
+}
+
+// nodeSummary is the JSON form of a node, with the number of pods on it
+type nodeSummary struct {
+	Name          string `json:"name"`
+	Ready         bool   `json:"ready"`
+	Unschedulable bool   `json:"unschedulable"`
+	Pods          int    `json:"pods"`
+}
+
+type listResponse[T any] struct {
+	Count int `json:"count"`
+	Items []T `json:"items"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// api answers every request from the informer caches. No handler calls the
+// API server, so a busy client costs the cluster nothing.
+type api struct {
+	pods   cache.Indexer
+	nodes  corelisters.NodeLister
+	synced cache.InformerSynced
+}
+
+func newAPI(pods cache.Indexer, nodes corelisters.NodeLister, synced cache.InformerSynced) *api {
+	return &api{pods: pods, nodes: nodes, synced: synced}
+}
+
+func (a *api) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pods", a.listPods)
+	mux.HandleFunc("GET /nodes", a.listNodes)
+	return a.requireSync(mux)
+}
+
+// requireSync refuses requests until the caches are complete. A half-filled
+// cache would answer with a silently short list.
+func (a *api) requireSync(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.synced() {
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "informer caches not synced yet"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listPods serves GET /pods?namespace=&node=&phase=. The most selective index
+// fetches the candidates, and the other parameters filter them. The index
+// used is reported in the X-Index-Used header.
+func (a *api) listPods(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	for param := range query {
+		if param != "namespace" && param != nodeIndex && param != phaseIndex {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("unknown query parameter %q", param)})
+			return
+		}
+	}
+	namespace, node, phase := query.Get("namespace"), query.Get(nodeIndex), query.Get(phaseIndex)
+
+	var objs []interface{}
+	var err error
+	switch {
+	case node != "":
+		w.Header().Set("X-Index-Used", nodeIndex)
+		objs, err = a.pods.ByIndex(nodeIndex, node)
+	case namespace != "":
+		w.Header().Set("X-Index-Used", cache.NamespaceIndex)
+		objs, err = a.pods.ByIndex(cache.NamespaceIndex, namespace)
+	case phase != "":
+		w.Header().Set("X-Index-Used", phaseIndex)
+		objs, err = a.pods.ByIndex(phaseIndex, phase)
+	default:
+		w.Header().Set("X-Index-Used", "none")
+		objs = a.pods.List()
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+
+	items := make([]podSummary, 0, len(objs))
+	for _, obj := range objs {
+		pod := obj.(*corev1.Pod)
+		if (namespace != "" && pod.Namespace != namespace) ||
+			(node != "" && pod.Spec.NodeName != node) ||
+			(phase != "" && string(pod.Status.Phase) != phase) {
+			continue
+		}
+		items = append(items, summarizePod(pod))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Namespace != items[j].Namespace {
+			return items[i].Namespace < items[j].Namespace
+		}
+		return items[i].Name < items[j].Name
+	})
+	writeJSON(w, http.StatusOK, listResponse[podSummary]{Count: len(items), Items: items})
+}
+
+// listNodes serves GET /nodes. The pod count of each node is the size of
+// its bucket in the node index.
+func (a *api) listNodes(w http.ResponseWriter, r *http.Request) {
+	nodes, err := a.nodes.List(labels.Everything())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+	items := make([]nodeSummary, 0, len(nodes))
+	for _, node := range nodes {
+		pods, err := a.pods.IndexKeys(nodeIndex, node.Name)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+			return
+		}
+		items = append(items, nodeSummary{
+			Name:          node.Name,
+			Ready:         nodeReady(node),
+			Unschedulable: node.Spec.Unschedulable,
+			Pods:          len(pods),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	writeJSON(w, http.StatusOK, listResponse[nodeSummary]{Count: len(items), Items: items})
+}
+
+func summarizePod(pod *corev1.Pod) podSummary {
+	s := podSummary{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		Node:      pod.Spec.NodeName,
+		Phase:     string(pod.Status.Phase),
+		PodIP:     pod.Status.PodIP,
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		s.Restarts += status.RestartCount
+	}
+	return s
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/healthz"
+)
+
+var addr = flag.String("addr", ":8080", "address the API, /healthz and /readyz are served on")
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "37-cached-read-api")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// setupAPI creates the pod and node informers, with the pod indexes the
+// queries need, and returns the API served from them
+func setupAPI(factory informers.SharedInformerFactory) (*api, error) {
+	podInformer := factory.Core().V1().Pods().Informer()
+	// Indexes must be added before the informer starts
+	err := podInformer.AddIndexers(cache.Indexers{
+		nodeIndex:  podNodeIndexFunc,
+		phaseIndex: podPhaseIndexFunc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	nodeInformer := factory.Core().V1().Nodes()
+	synced := func() bool {
+		return podInformer.HasSynced() && nodeInformer.Informer().HasSynced()
+	}
+	return newAPI(podInformer.GetIndexer(), nodeInformer.Lister(), synced), nil
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	a, err := setupAPI(factory)
+	if err != nil {
+		log.Fatalf("Failed to set up the API: %v", err)
+	}
+	defer factory.Shutdown()
+	factory.Start(ctx.Done())
+
+	health := healthz.New()
+	health.AddReadyCheck("informers", healthz.InformersSynced(a.synced))
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", health.Handler())
+	mux.Handle("/readyz", health.Handler())
+	mux.Handle("/", a.handler())
+
+	server := &http.Server{Addr: *addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	log.Printf("Serving the cached read API on %s", *addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("API server failed: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newPod(namespace, name, node string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func newNode(name string, ready corev1.ConditionStatus) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: ready},
+		}},
+	}
+}
+
+// newTestServer serves the API over synced informers of a fake clientset
+func newTestServer(t *testing.T, objects ...runtime.Object) *httptest.Server {
+	t.Helper()
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(objects...), 0)
+	a, err := setupAPI(factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		factory.Shutdown()
+	})
+	factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, a.synced) {
+		t.Fatal("caches did not sync")
+	}
+	server := httptest.NewServer(a.handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, server *httptest.Server, path string, out interface{}) *http.Response {
+	t.Helper()
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func podNames(list listResponse[podSummary]) []string {
+	var names []string
+	for _, pod := range list.Items {
+		names = append(names, pod.Namespace+"/"+pod.Name)
+	}
+	return names
+}
+
+var testObjects = []runtime.Object{
+	newPod("web", "web-1", "node-a", corev1.PodRunning),
+	newPod("web", "web-2", "node-b", corev1.PodRunning),
+	newPod("web", "web-3", "", corev1.PodPending),
+	newPod("batch", "job-1", "node-a", corev1.PodSucceeded),
+	newNode("node-a", corev1.ConditionTrue),
+	newNode("node-b", corev1.ConditionFalse),
+}
+
+func TestListPodsUsesTheMostSelectiveIndex(t *testing.T) {
+	server := newTestServer(t, testObjects...)
+	tests := []struct {
+		path  string
+		index string
+		want  []string
+	}{
+		{"/pods", "none", []string{"batch/job-1", "web/web-1", "web/web-2", "web/web-3"}},
+		{"/pods?node=node-a", nodeIndex, []string{"batch/job-1", "web/web-1"}},
+		{"/pods?phase=Running", phaseIndex, []string{"web/web-1", "web/web-2"}},
+		{"/pods?namespace=web&phase=Pending", cache.NamespaceIndex, []string{"web/web-3"}},
+		{"/pods?node=node-a&phase=Running", nodeIndex, []string{"web/web-1"}},
+		{"/pods?node=node-c", nodeIndex, nil},
+	}
+	for _, tt := range tests {
+		var list listResponse[podSummary]
+		resp := get(t, server, tt.path, &list)
+		if got := resp.Header.Get("X-Index-Used"); got != tt.index {
+			t.Errorf("%s used index %q, want %q", tt.path, got, tt.index)
+		}
+		got := podNames(list)
+		if len(got) != len(tt.want) || list.Count != len(tt.want) {
+			t.Errorf("%s = %v (count %d), want %v", tt.path, got, list.Count, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s = %v, want %v", tt.path, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestListPodsRejectsUnknownParameters(t *testing.T) {
+	server := newTestServer(t, testObjects...)
+	var body errorResponse
+	resp := get(t, server, "/pods?nodeName=node-a", &body)
+	if resp.StatusCode != http.StatusBadRequest || body.Error == "" {
+		t.Errorf("status %d, error %q", resp.StatusCode, body.Error)
+	}
+}
+
+func TestListNodesCountsPodsFromTheIndex(t *testing.T) {
+	server := newTestServer(t, testObjects...)
+	var list listResponse[nodeSummary]
+	get(t, server, "/nodes", &list)
+	want := []nodeSummary{
+		{Name: "node-a", Ready: true, Pods: 2},
+		{Name: "node-b", Ready: false, Pods: 1},
+	}
+	if list.Count != len(want) {
+		t.Fatalf("nodes = %+v", list.Items)
+	}
+	for i := range want {
+		if list.Items[i] != want[i] {
+			t.Errorf("node %d = %+v, want %+v", i, list.Items[i], want[i])
+		}
+	}
+}
+
+func TestRequestsWaitForSync(t *testing.T) {
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	a, err := setupAPI(factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The factory is never started, so the caches never sync
+	recorder := httptest.NewRecorder()
+	a.handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/pods", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", recorder.Code)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	dbPath    = flag.String("db", "cluster.db", "SQLite database file the caches are mirrored into")
+	namespace = flag.String("namespace", "", "only mirror this namespace (default all namespaces)")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "38-sqlite-mirror")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// setupMirror registers the mirror's handlers on the pod and deployment
+// informers and returns their HasSynced functions
+func setupMirror(factory informers.SharedInformerFactory, m *mirror) ([]cache.InformerSynced, error) {
+	podInformer := factory.Core().V1().Pods().Informer()
+	if _, err := podInformer.AddEventHandler(m.podHandler()); err != nil {
+		return nil, err
+	}
+	deploymentInformer := factory.Apps().V1().Deployments().Informer()
+	if _, err := deploymentInformer.AddEventHandler(m.deploymentHandler()); err != nil {
+		return nil, err
+	}
+	return []cache.InformerSynced{podInformer.HasSynced, deploymentInformer.HasSynced}, nil
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := openSQLite(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *dbPath, err)
+	}
+	m, err := newMirror(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(*namespace))
+	synced, err := setupMirror(factory, m)
+	if err != nil {
+		log.Fatalf("Failed to add event handlers: %v", err)
+	}
+	factory.Start(ctx.Done())
+	if cache.WaitForCacheSync(ctx.Done(), synced...) {
+		fmt.Printf("Mirrored the caches into %s (%d writes). Query it with:\n", *dbPath, m.writes.Load())
+		fmt.Printf("  sqlite3 %s \"SELECT node, count(*) FROM pods GROUP BY node\"\n", *dbPath)
+	}
+
+	<-ctx.Done()
+	// Handlers may still be writing; the database is closed only after the
+	// informers have stopped calling them
+	factory.Shutdown()
+	if err := db.Close(); err != nil {
+		log.Printf("Failed to close the database: %v", err)
+	}
+	fmt.Printf("Stopped after %d writes (%d failed)\n", m.writes.Load(), m.failures.Load())
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// openTestDB opens a database in a temporary directory unless path is given
+func openTestDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	if path == "" {
+		path = filepath.Join(t.TempDir(), "cluster.db")
+	}
+	db, err := openSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// query returns the rows as the sqlite3 shell prints them: columns separated
+// by | and rows by newlines
+func query(t *testing.T, db *sql.DB, sql string) string {
+	t.Helper()
+	rows, err := db.Query(sql)
+	if err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			t.Fatal(err)
+		}
+		fields := make([]string, len(values))
+		for i, v := range values {
+			if v != nil {
+				fields[i] = fmt.Sprint(v)
+			}
+		}
+		lines = append(lines, strings.Join(fields, "|"))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// eventually retries query until it returns want; handlers write asynchronously
+func eventually(t *testing.T, db *sql.DB, sql, want string) {
+	t.Helper()
+	var got string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if got = query(t, db, sql); got == want {
+			return
+		}
+	}
+	t.Errorf("%s = %q, want %q", sql, got, want)
+}
+
+func newPod(namespace, name, node string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID("uid-" + name)},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{
+			Phase:             phase,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: 2}},
+		},
+	}
+}
+
+func TestValuesAreParameters(t *testing.T) {
+	db := openTestDB(t, "")
+	m, err := newMirror(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Quotes and SQL in names reach the table as they are
+	pod := newPod("web", "it's'); DROP TABLE pods; --", "node-a", corev1.PodRunning)
+	m.write(upsertPod(pod))
+	if got := query(t, db, "SELECT name FROM pods;"); got != pod.Name {
+		t.Errorf("name = %q, want %q", got, pod.Name)
+	}
+	m.write(deleteRow("pods", pod))
+	if got := query(t, db, "SELECT count(*) FROM pods;"); got != "0" || m.failures.Load() != 0 {
+		t.Errorf("%s rows left, %d writes failed", got, m.failures.Load())
+	}
+}
+
+func TestFailedWritesAreCounted(t *testing.T) {
+	db := openTestDB(t, "")
+	m, err := newMirror(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.write(statement{query: "INSERT INTO pods (namespace) VALUES (?);", args: []any{"web"}})
+	if m.failures.Load() != 1 || m.writes.Load() != 0 {
+		t.Errorf("%d writes, %d failures, want the NOT NULL violation counted", m.writes.Load(), m.failures.Load())
+	}
+}
+
+func TestMirrorFollowsTheCache(t *testing.T) {
+	db := openTestDB(t, "")
+	m, err := newMirror(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas := int32(3)
+	clientset := fake.NewSimpleClientset(
+		newPod("web", "web-1", "node-a", corev1.PodRunning),
+		newPod("web", "web-2", "node-b", corev1.PodPending),
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "web"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+		},
+	)
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	synced, err := setupMirror(factory, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopCh := make(chan struct{})
+	defer factory.Shutdown()
+	defer close(stopCh)
+	factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, synced...) {
+		t.Fatal("caches did not sync")
+	}
+
+	eventually(t, db, "SELECT name, node, phase, restarts FROM pods ORDER BY name;",
+		"web-1|node-a|Running|2\nweb-2|node-b|Pending|2")
+	eventually(t, db, "SELECT name, replicas, ready_replicas FROM deployments;", "web|3|1")
+	// The object column holds the JSON for ad-hoc queries
+	eventually(t, db, "SELECT json_extract(object, '$.spec.nodeName') FROM pods WHERE name = 'web-1';", "node-a")
+
+	ctx := context.Background()
+	updated := newPod("web", "web-2", "node-b", corev1.PodRunning)
+	if _, err := clientset.CoreV1().Pods("web").Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := clientset.CoreV1().Pods("web").Delete(ctx, "web-1", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, db, "SELECT name, phase FROM pods;", "web-2|Running")
+	if m.failures.Load() != 0 {
+		t.Errorf("%d writes failed", m.failures.Load())
+	}
+}
+
+func TestTombstonesDeleteTheirRow(t *testing.T) {
+	tombstone := cache.DeletedFinalStateUnknown{Key: "web/it's-gone", Obj: nil}
+	got := deleteRow("pods", tombstone)
+	if got.query != "DELETE FROM pods WHERE namespace = ? AND name = ?;" || !slices.Equal(got.args, []any{"web", "it's-gone"}) {
+		t.Errorf("deleteRow = %+v", got)
+	}
+}
+
+func TestNewMirrorDropsRowsFromAnEarlierRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluster.db")
+	db := openTestDB(t, path)
+	m, err := newMirror(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.write(upsertPod(newPod("web", "deleted-while-offline", "node-a", corev1.PodRunning)))
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db = openTestDB(t, path)
+	if _, err := newMirror(db); err != nil {
+		t.Fatal(err)
+	}
+	if got := query(t, db, "SELECT count(*) FROM pods;"); got != "0" {
+		t.Errorf("%s rows left from the earlier run", got)
+	}
+}
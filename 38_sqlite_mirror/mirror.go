@@ -0,0 +1,172 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// schema is created on start. Every table keeps a few columns for easy
+// queries and the whole object as JSON for json_extract.
+const schema = `
+CREATE TABLE IF NOT EXISTS pods (
+	namespace        TEXT NOT NULL,
+	name             TEXT NOT NULL,
+	uid              TEXT NOT NULL,
+	node             TEXT,
+	phase            TEXT,
+	restarts         INTEGER NOT NULL,
+	created          TEXT NOT NULL,
+	resource_version TEXT NOT NULL,
+	object           TEXT,
+	PRIMARY KEY (namespace, name)
+);
+CREATE TABLE IF NOT EXISTS deployments (
+	namespace          TEXT NOT NULL,
+	name               TEXT NOT NULL,
+	uid                TEXT NOT NULL,
+	replicas           INTEGER NOT NULL,
+	ready_replicas     INTEGER NOT NULL,
+	updated_replicas   INTEGER NOT NULL,
+	available_replicas INTEGER NOT NULL,
+	created            TEXT NOT NULL,
+	resource_version   TEXT NOT NULL,
+	object             TEXT,
+	PRIMARY KEY (namespace, name)
+);
+`
+
+// mirror keeps the pods and deployments tables equal to the informer caches.
+// Its handlers write every add, update and delete through to the database.
+type mirror struct {
+	db *sql.DB
+
+	writes   atomic.Int64
+	failures atomic.Int64
+}
+
+// newMirror creates the tables and empties them. Objects deleted while the
+// program was not running would otherwise stay in the database forever; the
+// initial list fills the tables again.
+func newMirror(db *sql.DB) (*mirror, error) {
+	if _, err := db.Exec(schema + "DELETE FROM pods;\nDELETE FROM deployments;"); err != nil {
+		return nil, fmt.Errorf("failed to create the schema: %w", err)
+	}
+	return &mirror{db: db}, nil
+}
+
+// write runs one statement and logs a failure; a failed write is repaired by
+// the next event for the same object
+func (m *mirror) write(s statement) {
+	if s.query == "" {
+		return
+	}
+	if _, err := m.db.Exec(s.query, s.args...); err != nil {
+		m.failures.Add(1)
+		log.Printf("Failed to write to the database: %v", err)
+		return
+	}
+	m.writes.Add(1)
+}
+
+func (m *mirror) podHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { m.write(upsertPod(obj.(*corev1.Pod))) },
+		UpdateFunc: func(oldObj, newObj interface{}) { m.write(upsertPod(newObj.(*corev1.Pod))) },
+		DeleteFunc: func(obj interface{}) { m.write(deleteRow("pods", obj)) },
+	}
+}
+
+func (m *mirror) deploymentHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { m.write(upsertDeployment(obj.(*appsv1.Deployment))) },
+		UpdateFunc: func(oldObj, newObj interface{}) { m.write(upsertDeployment(newObj.(*appsv1.Deployment))) },
+		DeleteFunc: func(obj interface{}) { m.write(deleteRow("deployments", obj)) },
+	}
+}
+
+// upsert builds an INSERT that updates the row when the key already exists
+func upsert(table string, columns []string, values []any) statement {
+	var updates []string
+	for _, column := range columns[2:] { // namespace and name are the key
+		updates = append(updates, fmt.Sprintf("%s = excluded.%s", column, column))
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return statement{
+		query: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)\nON CONFLICT (namespace, name) DO UPDATE SET %s;",
+			table, strings.Join(columns, ", "), placeholders, strings.Join(updates, ", ")),
+		args: values,
+	}
+}
+
+func upsertPod(pod *corev1.Pod) statement {
+	var restarts int32
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+	return upsert("pods",
+		[]string{"namespace", "name", "uid", "node", "phase", "restarts", "created", "resource_version", "object"},
+		[]any{
+			pod.Namespace, pod.Name, string(pod.UID),
+			pod.Spec.NodeName, string(pod.Status.Phase), restarts,
+			pod.CreationTimestamp.UTC().Format("2006-01-02T15:04:05Z"), pod.ResourceVersion,
+			objectJSON(pod),
+		})
+}
+
+func upsertDeployment(deployment *appsv1.Deployment) statement {
+	var replicas int32 = 1
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return upsert("deployments",
+		[]string{"namespace", "name", "uid", "replicas", "ready_replicas", "updated_replicas", "available_replicas", "created", "resource_version", "object"},
+		[]any{
+			deployment.Namespace, deployment.Name, string(deployment.UID),
+			replicas, deployment.Status.ReadyReplicas,
+			deployment.Status.UpdatedReplicas, deployment.Status.AvailableReplicas,
+			deployment.CreationTimestamp.UTC().Format("2006-01-02T15:04:05Z"), deployment.ResourceVersion,
+			objectJSON(deployment),
+		})
+}
+
+// deleteRow removes the row of a deleted object. obj may be a tombstone when
+// the delete was only noticed on a relist; its key still names the row.
+func deleteRow(table string, obj interface{}) statement {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return statement{}
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return statement{}
+	}
+	return statement{
+		query: fmt.Sprintf("DELETE FROM %s WHERE namespace = ? AND name = ?;", table),
+		args:  []any{namespace, name},
+	}
+}
+
+// objectJSON stores obj as JSON, without managedFields, which nobody queries
+// and which are often larger than the rest of the object. It is NULL when obj
+// cannot be encoded.
+func objectJSON(obj interface{ DeepCopyObject() runtime.Object }) any {
+	copied := obj.DeepCopyObject()
+	if accessor, err := meta.Accessor(copied); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	data, err := json.Marshal(copied)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
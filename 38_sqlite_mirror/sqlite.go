@@ -0,0 +1,38 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	// Pure-Go SQLite, so the module builds without cgo or a sqlite3 binary
+	_ "modernc.org/sqlite"
+)
+
+// openSQLite opens the database at path, creating the file if needed
+func openSQLite(path string) (*sql.DB, error) {
+	// Pragmas in the DSN are applied to every connection the pool opens.
+	// WAL lets readers such as the sqlite3 shell query while the mirror
+	// writes, and the busy timeout waits for them instead of failing.
+	dsn := fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)",
+		url.PathEscape(path))
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite has a single writer; one connection serializes the handlers'
+	// writes in Go instead of in SQLite's lock
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// statement is one SQL statement with its parameters. Values are never
+// spliced into the SQL text, so names and JSON need no quoting.
+type statement struct {
+	query string
+	args  []any
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Retry delays while the bus is unreachable
+const (
+	minRetryDelay = 500 * time.Millisecond
+	maxRetryDelay = 30 * time.Second
+)
+
+// bridge turns informer notifications into bus messages. Handlers only append
+// to the spool; run delivers the spool in order, one message at a time.
+type bridge struct {
+	spool     *spool
+	publisher publisher
+	prefix    string
+	now       func() time.Time
+
+	delivered atomic.Int64
+	failures  atomic.Int64
+}
+
+func newBridge(s *spool, p publisher, prefix string) *bridge {
+	return &bridge{spool: s, publisher: p, prefix: prefix, now: time.Now}
+}
+
+// handler returns the event handler for the informer of gvr
+func (b *bridge) handler(gvr schema.GroupVersionResource) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			b.enqueue(eventAdded, gvr, nil, obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old := oldObj.(*unstructured.Unstructured)
+			current := newObj.(*unstructured.Unstructured)
+			// Resyncs deliver updates without a change; they are not events
+			if old.GetResourceVersion() == current.GetResourceVersion() {
+				return
+			}
+			b.enqueue(eventUpdated, gvr, old, current)
+		},
+		DeleteFunc: func(obj interface{}) {
+			// A delete noticed on a relist carries the last state the cache had
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			b.enqueue(eventDeleted, gvr, nil, obj)
+		},
+	}
+}
+
+func (b *bridge) enqueue(eventType string, gvr schema.GroupVersionResource, old *unstructured.Unstructured, obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		log.Printf("Skipping %s event of unexpected type %T", eventType, obj)
+		return
+	}
+	e, err := newEvent(eventType, gvr, old, u, b.now())
+	if err != nil {
+		log.Printf("Failed to build %s event for %s: %v", eventType, objectKey(u), err)
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("Failed to encode %s event for %s: %v", eventType, e.Key, err)
+		return
+	}
+	// The handler returns only once the message is on disk; a crash after
+	// this point cannot lose it
+	if err := b.spool.append(record{Subject: subject(b.prefix, gvr, eventType), ID: e.ID, Event: data}); err != nil {
+		log.Printf("Failed to buffer %s event for %s: %v", eventType, e.Key, err)
+	}
+}
+
+// run delivers buffered messages until ctx is cancelled. A message is removed
+// from the spool only after the bus acknowledged it, so a crash between the
+// two publishes it again: delivery is at-least-once, and consumers use the
+// event ID to drop duplicates.
+func (b *bridge) run(ctx context.Context) {
+	delay := minRetryDelay
+	for {
+		name, r, ok, err := b.spool.peek()
+		if err != nil {
+			log.Printf("Dropping unreadable buffered message %s: %v", name, err)
+			b.spool.remove(name)
+			continue
+		}
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-b.spool.notify:
+			}
+			continue
+		}
+
+		if err := b.publisher.publish(ctx, r.Subject, r.ID, r.Event); err != nil {
+			b.failures.Add(1)
+			log.Printf("Failed to publish to %s, %d messages buffered, retrying in %s: %v", r.Subject, b.spool.len(), delay, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, maxRetryDelay)
+			continue
+		}
+		delay = minRetryDelay
+		if err := b.spool.remove(name); err != nil {
+			log.Printf("Failed to remove delivered message %s: %v", name, err)
+		}
+		b.delivered.Add(1)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Event types, as they appear in the message and its subject
+const (
+	eventAdded   = "added"
+	eventUpdated = "updated"
+	eventDeleted = "deleted"
+)
+
+// event is the message published for every informer notification
+type event struct {
+	// ID is the same every time this change is published, so consumers and
+	// JetStream can drop the duplicates at-least-once delivery produces
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	Group           string `json:"group"`
+	Version         string `json:"version"`
+	Kind            string `json:"kind"`
+	Resource        string `json:"resource"`
+	Key             string `json:"key"`
+	UID             string `json:"uid"`
+	ResourceVersion string `json:"resourceVersion"`
+	Time            string `json:"time"`
+	// Diff is a JSON merge patch (RFC 7386) from the old to the new object,
+	// only set on updates
+	Diff json.RawMessage `json:"diff,omitempty"`
+	// Object is the new object, or the last known state of a deleted one
+	Object json.RawMessage `json:"object"`
+}
+
+// newEvent builds the message for one notification. oldObj is only set on
+// updates. managedFields are left out of the object and the diff; they change
+// on every write and would drown the real change.
+func newEvent(eventType string, gvr schema.GroupVersionResource, oldObj, obj *unstructured.Unstructured, now time.Time) (*event, error) {
+	current, err := marshalWithoutManagedFields(obj)
+	if err != nil {
+		return nil, err
+	}
+	e := &event{
+		ID:              fmt.Sprintf("%s-%s-%s", obj.GetUID(), obj.GetResourceVersion(), eventType),
+		Type:            eventType,
+		Group:           gvr.Group,
+		Version:         gvr.Version,
+		Kind:            obj.GetKind(),
+		Resource:        gvr.Resource,
+		Key:             objectKey(obj),
+		UID:             string(obj.GetUID()),
+		ResourceVersion: obj.GetResourceVersion(),
+		Time:            now.UTC().Format(time.RFC3339),
+		Object:          current,
+	}
+	if oldObj != nil {
+		old, err := marshalWithoutManagedFields(oldObj)
+		if err != nil {
+			return nil, err
+		}
+		if e.Diff, err = jsonpatch.CreateMergePatch(old, current); err != nil {
+			return nil, fmt.Errorf("failed to diff %s: %w", e.Key, err)
+		}
+	}
+	return e, nil
+}
+
+func marshalWithoutManagedFields(obj *unstructured.Unstructured) ([]byte, error) {
+	copied := obj.DeepCopy()
+	unstructured.RemoveNestedField(copied.Object, "metadata", "managedFields")
+	return json.Marshal(copied.Object)
+}
+
+func objectKey(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// subject returns the bus subject of an event, <prefix>.<group>.<resource>.<type>,
+// e.g. k8s.apps.deployments.updated. Subject tokens cannot contain dots, so the
+// dots of a group turn into underscores, and the core group is called "core".
+func subject(prefix string, gvr schema.GroupVersionResource, eventType string) string {
+	group := gvr.Group
+	if group == "" {
+		group = "core"
+	}
+	return strings.Join([]string{prefix, strings.ReplaceAll(group, ".", "_"), gvr.Resource, eventType}, ".")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	natsURL   = flag.String("nats-url", nats.DefaultURL, "NATS server URL, or a comma-separated list of cluster URLs")
+	prefix    = flag.String("subject-prefix", "k8s", "first token of every subject")
+	spoolDir  = flag.String("spool-dir", "event-bridge-spool", "directory buffering messages until the bus acknowledges them")
+	resources = flag.String("resources", "apps/v1/deployments,v1/configmaps", "comma-separated group/version/resource list to bridge; the core group is written as v1/<resource>")
+	namespace = flag.String("namespace", "", "only bridge this namespace (default all namespaces)")
+)
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "39-event-bridge")
+	return config
+}
+
+// parseResources parses the --resources list
+func parseResources(list string) ([]schema.GroupVersionResource, error) {
+	var gvrs []schema.GroupVersionResource
+	for _, item := range strings.Split(list, ",") {
+		parts := strings.Split(strings.TrimSpace(item), "/")
+		switch {
+		case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+			gvrs = append(gvrs, schema.GroupVersionResource{Version: parts[0], Resource: parts[1]})
+		case len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "":
+			gvrs = append(gvrs, schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]})
+		default:
+			return nil, fmt.Errorf("invalid resource %q, want group/version/resource or v1/resource", item)
+		}
+	}
+	return gvrs, nil
+}
+
+// setupBridge registers the bridge's handler on a dynamic informer per resource
+func setupBridge(factory dynamicinformer.DynamicSharedInformerFactory, b *bridge, gvrs []schema.GroupVersionResource) ([]cache.InformerSynced, error) {
+	var synced []cache.InformerSynced
+	for _, gvr := range gvrs {
+		informer := factory.ForResource(gvr).Informer()
+		if _, err := informer.AddEventHandler(b.handler(gvr)); err != nil {
+			return nil, err
+		}
+		synced = append(synced, informer.HasSynced)
+	}
+	return synced, nil
+}
+
+func main() {
+	config := createConfig()
+	gvrs, err := parseResources(*resources)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create dynamic client: %v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	s, err := openSpool(*spoolDir)
+	if err != nil {
+		log.Fatalf("Failed to open spool %s: %v", *spoolDir, err)
+	}
+	if n := s.len(); n > 0 {
+		log.Printf("Resuming with %d messages buffered by an earlier run", n)
+	}
+	js := newJetStream(*natsURL, 10*time.Second)
+	b := newBridge(s, js, *prefix)
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, 0, *namespace, nil)
+	synced, err := setupBridge(factory, b, gvrs)
+	if err != nil {
+		log.Fatalf("Failed to add event handlers: %v", err)
+	}
+	factory.Start(ctx.Done())
+	done := make(chan struct{})
+	go func() {
+		b.run(ctx)
+		close(done)
+	}()
+	if cache.WaitForCacheSync(ctx.Done(), synced...) {
+		log.Printf("Bridging %s to %s under %s.>", *resources, *natsURL, *prefix)
+	}
+
+	<-ctx.Done()
+	factory.Shutdown()
+	<-done
+	js.close()
+	fmt.Printf("Delivered %d messages, %d still buffered in %s\n", b.delivered.Load(), s.len(), *spoolDir)
+}
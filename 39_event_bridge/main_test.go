@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+var configMaps = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+func newConfigMap(name, rv string, data map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"namespace":       "default",
+			"name":            name,
+			"uid":             "uid-" + name,
+			"resourceVersion": rv,
+			"managedFields":   []interface{}{map[string]interface{}{"manager": "kubectl"}},
+		},
+		"data": data,
+	}}
+}
+
+func TestNewEventDiffsWithoutManagedFields(t *testing.T) {
+	old := newConfigMap("app", "1", map[string]interface{}{"mode": "blue"})
+	current := newConfigMap("app", "2", map[string]interface{}{"mode": "green"})
+	e, err := newEvent(eventUpdated, configMaps, old, current, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != "uid-app-2-updated" || e.Key != "default/app" || e.Kind != "ConfigMap" || e.Time != "2025-01-01T00:00:00Z" {
+		t.Errorf("event = %+v", e)
+	}
+	if got, want := string(e.Diff), `{"data":{"mode":"green"},"metadata":{"resourceVersion":"2"}}`; got != want {
+		t.Errorf("diff = %s, want %s", got, want)
+	}
+	if strings.Contains(string(e.Object), "managedFields") {
+		t.Error("object still has managedFields")
+	}
+}
+
+func TestSubject(t *testing.T) {
+	tests := []struct {
+		gvr  schema.GroupVersionResource
+		want string
+	}{
+		{configMaps, "k8s.core.configmaps.added"},
+		{schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}, "k8s.networking_k8s_io.ingresses.added"},
+	}
+	for _, tt := range tests {
+		if got := subject("k8s", tt.gvr, eventAdded); got != tt.want {
+			t.Errorf("subject(%v) = %s, want %s", tt.gvr, got, tt.want)
+		}
+	}
+}
+
+func TestParseResources(t *testing.T) {
+	gvrs, err := parseResources("apps/v1/deployments, v1/configmaps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []schema.GroupVersionResource{{Group: "apps", Version: "v1", Resource: "deployments"}, configMaps}
+	if len(gvrs) != 2 || gvrs[0] != want[0] || gvrs[1] != want[1] {
+		t.Errorf("gvrs = %v", gvrs)
+	}
+	if _, err := parseResources("deployments"); err == nil {
+		t.Error("a bare resource was accepted")
+	}
+}
+
+func TestSpoolSurvivesARestart(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.append(record{Subject: "k8s.test", ID: strconv.Itoa(i), Event: json.RawMessage(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	name, _, _, _ := s.peek()
+	if err := s.remove(name); err != nil {
+		t.Fatal(err)
+	}
+	// A write that was cut off by a crash
+	os.WriteFile(filepath.Join(dir, "00000000000000000003.json.tmp"), []byte("{"), 0o644)
+
+	s, err = openSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.len() != 2 {
+		t.Fatalf("len = %d, want 2", s.len())
+	}
+	if _, r, _, _ := s.peek(); r.ID != "1" {
+		t.Errorf("oldest message = %q, want 1", r.ID)
+	}
+	if err := s.append(record{ID: "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "00000000000000000003.json")); err != nil {
+		t.Errorf("sequence did not continue after the restart: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "00000000000000000003.json.tmp")); !os.IsNotExist(err) {
+		t.Error("the partial write was not cleaned up")
+	}
+}
+
+// runJetStream starts an in-process NATS server with JetStream and a K8S
+// stream storing k8s.>, as the README creates with the nats CLI
+func runJetStream(t *testing.T) *natsserver.Server {
+	t.Helper()
+	server, err := natsserver.NewServer(&natsserver.Options{
+		Host:      "127.0.0.1",
+		Port:      natsserver.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Start()
+	t.Cleanup(server.Shutdown)
+	if !server.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+
+	conn, err := nats.Connect(server.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = js.CreateStream(context.Background(), jetstream.StreamConfig{
+		Name:       "K8S",
+		Subjects:   []string{"k8s.>"},
+		Duplicates: 2 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// streamMessages returns the messages the K8S stream stored
+func streamMessages(t *testing.T, server *natsserver.Server) []*jetstream.RawStreamMsg {
+	t.Helper()
+	conn, err := nats.Connect(server.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	stream, err := js.Stream(ctx, "K8S")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []*jetstream.RawStreamMsg
+	for seq := info.State.FirstSeq; seq <= info.State.LastSeq && info.State.Msgs > 0; seq++ {
+		msg, err := stream.GetMsg(ctx, seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestJetStreamPublishWaitsForTheAck(t *testing.T) {
+	server := runJetStream(t)
+	js := newJetStream(server.ClientURL(), time.Second)
+	defer js.close()
+	// The second publish repeats the first, as after a crash before the
+	// spool file was removed
+	for range 2 {
+		if err := js.publish(context.Background(), "k8s.core.configmaps.added", "id-1", []byte(`{"a":1}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	msgs := streamMessages(t, server)
+	if len(msgs) != 1 {
+		t.Fatalf("stream stored %d messages, want the duplicate dropped", len(msgs))
+	}
+	if msgs[0].Header.Get(jetstream.MsgIDHeader) != "id-1" || string(msgs[0].Data) != `{"a":1}` {
+		t.Errorf("headers %v, payload %q", msgs[0].Header, msgs[0].Data)
+	}
+}
+
+func TestJetStreamPublishWithoutAStream(t *testing.T) {
+	server := runJetStream(t)
+	js := newJetStream(server.ClientURL(), time.Second)
+	defer js.close()
+	err := js.publish(context.Background(), "audit.core.configmaps.added", "id", []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "no JetStream stream") {
+		t.Errorf("err = %v, want the missing stream reported", err)
+	}
+}
+
+func TestJetStreamConnectsOnceTheBrokerIsUp(t *testing.T) {
+	js := newJetStream("nats://127.0.0.1:1", 200*time.Millisecond)
+	if err := js.publish(context.Background(), "k8s.test", "id", []byte("{}")); err == nil {
+		t.Fatal("publish to a closed port succeeded")
+	}
+	server := runJetStream(t)
+	js.url = server.ClientURL()
+	if err := js.publish(context.Background(), "k8s.test", "id", []byte("{}")); err != nil {
+		t.Errorf("publish after the broker came back: %v", err)
+	}
+	js.close()
+}
+
+// flakyPublisher fails the first failures publishes, then records messages
+type flakyPublisher struct {
+	mu       sync.Mutex
+	failures int
+	subjects []string
+	events   []event
+}
+
+func (p *flakyPublisher) publish(ctx context.Context, subject, id string, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	var e event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return err
+	}
+	p.subjects = append(p.subjects, subject)
+	p.events = append(p.events, e)
+	return nil
+}
+
+func (p *flakyPublisher) delivered() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.subjects...)
+}
+
+func TestBridgeDeliversInOrderAfterAnOutage(t *testing.T) {
+	s, err := openSpool(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	publisher := &flakyPublisher{failures: 1}
+	b := newBridge(s, publisher, "k8s")
+
+	initial := newConfigMap("app", "1", map[string]interface{}{"mode": "blue"})
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMaps: "ConfigMapList"}, initial)
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	synced, err := setupBridge(factory, b, []schema.GroupVersionResource{configMaps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer factory.Shutdown()
+	defer cancel()
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		t.Fatal("cache did not sync")
+	}
+	done := make(chan struct{})
+	go func() {
+		b.run(ctx)
+		close(done)
+	}()
+
+	resource := client.Resource(configMaps).Namespace("default")
+	updated := newConfigMap("app", "2", map[string]interface{}{"mode": "green"})
+	if _, err := resource.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := resource.Delete(ctx, "app", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"k8s.core.configmaps.added", "k8s.core.configmaps.updated", "k8s.core.configmaps.deleted"}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(publisher.delivered()) < len(want) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if got := publisher.delivered(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+	if string(publisher.events[1].Diff) != `{"data":{"mode":"green"},"metadata":{"resourceVersion":"2"}}` {
+		t.Errorf("update diff = %s", publisher.events[1].Diff)
+	}
+	if b.failures.Load() != 1 || s.len() != 0 {
+		t.Errorf("failures = %d, buffered = %d", b.failures.Load(), s.len())
+	}
+}
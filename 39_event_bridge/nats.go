@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// publisher sends one message and returns once the bus has stored it
+type publisher interface {
+	publish(ctx context.Context, subject, id string, data []byte) error
+}
+
+// jetStream publishes to NATS JetStream. Core NATS forgets a message nobody
+// is listening for; a JetStream stream stores it and acknowledges the
+// publish, which is what makes delivery at-least-once. The stream itself, e.g.
+//
+//	nats stream add K8S --subjects 'k8s.>' --dupe-window 2m
+//
+// is created by the operator of the bus, not by this program.
+type jetStream struct {
+	url     string
+	timeout time.Duration
+
+	// The connection is opened on the first publish; once it is up, the
+	// client reconnects by itself
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+func newJetStream(url string, timeout time.Duration) *jetStream {
+	return &jetStream{url: url, timeout: timeout}
+}
+
+// publish sends data with a Nats-Msg-Id header and waits for the stream's
+// acknowledgement. JetStream drops a message whose ID it has already stored
+// within the stream's duplicate window.
+func (j *jetStream) publish(ctx context.Context, subject, id string, data []byte) error {
+	if j.js == nil {
+		if err := j.connect(); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, j.timeout)
+	defer cancel()
+	_, err := j.js.Publish(ctx, subject, data, jetstream.WithMsgID(id))
+	// The server answers at once with "no responders" when no stream
+	// listens on the subject, instead of letting the publish time out
+	if errors.Is(err, jetstream.ErrNoStreamResponse) {
+		return errors.New("no JetStream stream stores this subject")
+	}
+	return err
+}
+
+// connect opens the connection. A failed first connect is not retried by the
+// client; the next publish tries again.
+func (j *jetStream) connect() error {
+	conn, err := nats.Connect(j.url,
+		nats.Name("event-bridge"),
+		nats.Timeout(j.timeout),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	j.conn, j.js = conn, js
+	return nil
+}
+
+func (j *jetStream) close() {
+	if j.conn != nil {
+		j.conn.Close()
+		j.conn, j.js = nil, nil
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// record is one buffered message
+type record struct {
+	Subject string          `json:"subject"`
+	ID      string          `json:"id"`
+	Event   json.RawMessage `json:"event"`
+}
+
+// spool is the local buffer between the event handlers and the bus. Every
+// message is a file named by its sequence number, written and synced before
+// the handler returns, so a broker outage or a restart loses nothing. A file is
+// removed only after the broker acknowledged the message.
+type spool struct {
+	dir string
+
+	mu      sync.Mutex
+	next    uint64
+	pending []string // file names, oldest first
+
+	// notify wakes the sender when a message is appended
+	notify chan struct{}
+}
+
+// openSpool opens the buffer in dir, picking up messages left by an earlier run
+func openSpool(dir string) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &spool{dir: dir, notify: make(chan struct{}, 1)}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			// A write that never finished; its handler never returned
+			os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		s.pending = append(s.pending, name)
+		if seq >= s.next {
+			s.next = seq + 1
+		}
+	}
+	// Zero-padded names sort in sequence order
+	sort.Strings(s.pending)
+	return s, nil
+}
+
+// append stores a message durably
+func (s *spool) append(r record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := fmt.Sprintf("%020d.json", s.next)
+	if err := writeFileSync(filepath.Join(s.dir, name), data); err != nil {
+		return err
+	}
+	s.next++
+	s.pending = append(s.pending, name)
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// peek returns the oldest message without removing it
+func (s *spool) peek() (string, record, bool, error) {
+	s.mu.Lock()
+	if len(s.pending) == 0 {
+		s.mu.Unlock()
+		return "", record{}, false, nil
+	}
+	name := s.pending[0]
+	s.mu.Unlock()
+
+	var r record
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err == nil {
+		err = json.Unmarshal(data, &r)
+	}
+	return name, r, true, err
+}
+
+// remove drops a delivered message. It leaves the queue even if the file
+// cannot be deleted; the file is then only delivered again after a restart.
+func (s *spool) remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) > 0 && s.pending[0] == name {
+		s.pending = s.pending[1:]
+	}
+	if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// len returns the number of undelivered messages
+func (s *spool) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// writeFileSync writes data to path atomically: a crash leaves either the
+// whole file or a .tmp file, never half a message
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// AlertController watches pod status transitions and sends an alert when a
+// container starts crash looping, cannot pull its image or is OOM killed
+type AlertController struct {
+	factory  informers.SharedInformerFactory
+	queue    workqueue.TypedRateLimitingInterface[alert]
+	notifier notifier
+	limiter  *podLimiter
+	now      func() time.Time
+}
+
+func NewAlertController(clientset kubernetes.Interface, namespace string, n notifier, cooldown time.Duration) *AlertController {
+	c := &AlertController{
+		factory: informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace)),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[alert](),
+			workqueue.TypedRateLimitingQueueConfig[alert]{Name: "pod-failure-alerts"},
+		),
+		notifier: n,
+		limiter:  newPodLimiter(cooldown),
+		now:      time.Now,
+	}
+
+	c.factory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			// Pods that were already failing when the controller started are
+			// not news; restarting the controller must not repeat every alert
+			if isInInitialList {
+				return
+			}
+			c.enqueue(detectFailures(nil, obj.(*corev1.Pod)))
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.enqueue(detectFailures(oldObj.(*corev1.Pod), newObj.(*corev1.Pod)))
+		},
+		DeleteFunc: func(obj interface{}) {
+			if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+				c.limiter.forget(key)
+			}
+		},
+	})
+	return c
+}
+
+func (c *AlertController) enqueue(alerts []alert) {
+	for _, a := range alerts {
+		c.queue.Add(a)
+	}
+}
+
+// Run starts the informers and workers and blocks until ctx is cancelled
+func (c *AlertController) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+
+	c.factory.Start(ctx.Done())
+
+	fmt.Println("Waiting for cache sync...")
+	c.factory.WaitForCacheSync(ctx.Done())
+	fmt.Println("Cache sync completed!")
+
+	// The drain below still works through the queue after ctx is cancelled,
+	// so the workers' requests keep ctx's values but not its cancellation
+	work := context.WithoutCancel(ctx)
+	for i := 0; i < workers; i++ {
+		go wait.Until(func() { c.runWorker(work) }, time.Second, ctx.Done())
+	}
+	<-ctx.Done()
+
+	// Send what is queued, then stop the informers
+	fmt.Println("Shutting down, draining the workqueue...")
+	c.queue.ShutDownWithDrain()
+	c.factory.Shutdown()
+	fmt.Println("Exited cleanly")
+}
+
+func (c *AlertController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *AlertController) processNextItem(ctx context.Context) bool {
+	a, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(a)
+
+	if err := c.send(ctx, a); err != nil {
+		fmt.Printf("[Alert] Failed to send %s alert for %s, requeuing: %v\n", a.Reason, a.podKey(), err)
+		c.queue.AddRateLimited(a)
+		return true
+	}
+	c.queue.Forget(a)
+	return true
+}
+
+// send delivers an alert unless its pod already alerted within the cooldown
+func (c *AlertController) send(ctx context.Context, a alert) error {
+	allowed, held := c.limiter.allow(a.podKey(), c.now())
+	if !allowed {
+		return nil
+	}
+	if err := c.notifier.notify(ctx, formatAlert(a, held)); err != nil {
+		c.limiter.undo(a.podKey(), held)
+		return err
+	}
+	return nil
+}
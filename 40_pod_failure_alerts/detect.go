@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Failure reasons that raise an alert
+const (
+	reasonCrashLoop = "CrashLoopBackOff"
+	reasonImagePull = "ImagePullBackOff"
+	reasonOOMKilled = "OOMKilled"
+)
+
+// alert is one container entering a failure state. It is the workqueue item,
+// so it only holds comparable fields; the same failure queued twice is sent once.
+type alert struct {
+	Namespace string
+	Pod       string
+	Node      string
+	Container string
+	Reason    string
+	Message   string
+	Restarts  int32
+}
+
+func (a alert) podKey() string {
+	return a.Namespace + "/" + a.Pod
+}
+
+// containerFailure returns the failure reason of a container status, if any.
+// CrashLoopBackOff and ImagePullBackOff are waiting reasons. An OOM kill shows
+// as the reason of the current or, once the container restarted, the last
+// termination.
+func containerFailure(status corev1.ContainerStatus) (reason, message string) {
+	if waiting := status.State.Waiting; waiting != nil {
+		switch waiting.Reason {
+		case reasonCrashLoop, reasonImagePull:
+			return waiting.Reason, waiting.Message
+		}
+	}
+	for _, terminated := range []*corev1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+		if terminated != nil && terminated.Reason == reasonOOMKilled {
+			return reasonOOMKilled, fmt.Sprintf("exit code %d at %s", terminated.ExitCode, terminated.FinishedAt.UTC().Format("15:04:05"))
+		}
+	}
+	return "", ""
+}
+
+// oomKilledAt identifies one OOM kill, so a new kill of a container that was
+// already OOMKilled before is still a transition
+func oomKilledAt(status corev1.ContainerStatus) string {
+	for _, terminated := range []*corev1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+		if terminated != nil && terminated.Reason == reasonOOMKilled {
+			return terminated.FinishedAt.String()
+		}
+	}
+	return ""
+}
+
+// detectFailures returns the containers of pod that entered a failure state
+// since old. old is nil for a pod seen for the first time.
+func detectFailures(old, pod *corev1.Pod) []alert {
+	previous := map[string]corev1.ContainerStatus{}
+	if old != nil {
+		for _, status := range allContainerStatuses(old) {
+			previous[status.Name] = status
+		}
+	}
+	var alerts []alert
+	for _, status := range allContainerStatuses(pod) {
+		reason, message := containerFailure(status)
+		if reason == "" {
+			continue
+		}
+		if before, ok := previous[status.Name]; ok {
+			oldReason, _ := containerFailure(before)
+			if oldReason == reason && (reason != reasonOOMKilled || oomKilledAt(before) == oomKilledAt(status)) {
+				// Still in the state that was already alerted on
+				continue
+			}
+		}
+		alerts = append(alerts, alert{
+			Namespace: pod.Namespace,
+			Pod:       pod.Name,
+			Node:      pod.Spec.NodeName,
+			Container: status.Name,
+			Reason:    reason,
+			Message:   message,
+			Restarts:  status.RestartCount,
+		})
+	}
+	return alerts
+}
+
+// allContainerStatuses includes init containers, which crash loop too
+func allContainerStatuses(pod *corev1.Pod) []corev1.ContainerStatus {
+	statuses := make([]corev1.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	return append(statuses, pod.Status.ContainerStatuses...)
+}
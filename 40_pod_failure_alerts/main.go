@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/healthz"
+)
+
+var (
+	webhookURL    = flag.String("webhook-url", "", "POST alerts as JSON to this URL (Slack-compatible); empty prints them")
+	namespace     = flag.String("namespace", "", "only watch pods in this namespace (default all namespaces)")
+	cooldown      = flag.Duration("cooldown", 10*time.Minute, "send at most one alert per pod within this period")
+	workers       = flag.Int("workers", 2, "number of workers sending alerts")
+	healthAddr    = flag.String("health-addr", ":8081", "address serving /healthz and /readyz (empty disables)")
+	maxQueueDepth = flag.Int("max-queue-depth", 100, "/readyz fails while more alerts than this are queued")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "40-pod-failure-alerts")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+func main() {
+	clientset := createClientSet()
+
+	var n notifier = stdoutNotifier{}
+	if *webhookURL != "" {
+		n = &webhookNotifier{url: *webhookURL, client: &http.Client{Timeout: 10 * time.Second}}
+	}
+	controller := NewAlertController(clientset, *namespace, n, *cooldown)
+	// Stop on Ctrl-C, or on SIGTERM from kubectl delete or the kubelet
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Ready once the pod cache has synced, and while alerts keep flowing
+	health := healthz.New()
+	health.AddReadyCheck("pod-informer", healthz.FactorySynced(controller.factory))
+	health.AddReadyCheck("workqueue", healthz.QueueDepth(controller.queue, *maxQueueDepth))
+	if *healthAddr != "" {
+		go func() {
+			if err := health.Serve(ctx, *healthAddr); err != nil {
+				log.Fatalf("Health server failed: %v", err)
+			}
+		}()
+	}
+
+	controller.Run(ctx, *workers)
+}
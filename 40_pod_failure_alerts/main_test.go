@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func newPod(statuses ...corev1.ContainerStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "api-0"},
+		Spec:       corev1.PodSpec{NodeName: "node-a"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: statuses},
+	}
+}
+
+func running(name string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}
+}
+
+func waiting(name, reason string, restarts int32) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:         name,
+		RestartCount: restarts,
+		State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: "back-off restarting failed container"}},
+	}
+}
+
+func oomKilled(name string, at time.Time) corev1.ContainerStatus {
+	status := running(name)
+	status.RestartCount = 1
+	status.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{
+		Reason: reasonOOMKilled, ExitCode: 137, FinishedAt: metav1.NewTime(at),
+	}
+	return status
+}
+
+func reasons(alerts []alert) string {
+	var out []string
+	for _, a := range alerts {
+		out = append(out, a.Container+":"+a.Reason)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestDetectFailuresOnlyReportsTransitions(t *testing.T) {
+	kill := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	initFailing := newPod(running("app"))
+	initFailing.Status.InitContainerStatuses = []corev1.ContainerStatus{waiting("migrate", reasonCrashLoop, 3)}
+
+	tests := []struct {
+		name     string
+		old, pod *corev1.Pod
+		want     string
+	}{
+		{"starts crash looping", newPod(running("app")), newPod(waiting("app", reasonCrashLoop, 4)), "app:CrashLoopBackOff"},
+		{"still crash looping", newPod(waiting("app", reasonCrashLoop, 4)), newPod(waiting("app", reasonCrashLoop, 5)), ""},
+		{"image pull after crash loop", newPod(waiting("app", reasonCrashLoop, 4)), newPod(waiting("app", reasonImagePull, 4)), "app:ImagePullBackOff"},
+		{"ErrImagePull is not yet an alert", newPod(running("app")), newPod(waiting("app", "ErrImagePull", 0)), ""},
+		{"OOM kill", newPod(running("app")), newPod(oomKilled("app", kill)), "app:OOMKilled"},
+		{"same OOM kill", newPod(oomKilled("app", kill)), newPod(oomKilled("app", kill)), ""},
+		{"second OOM kill", newPod(oomKilled("app", kill)), newPod(oomKilled("app", kill.Add(time.Minute))), "app:OOMKilled"},
+		{"new pod already failing", nil, newPod(running("app"), waiting("sidecar", reasonImagePull, 0)), "sidecar:ImagePullBackOff"},
+		{"init container", newPod(running("app")), initFailing, "migrate:CrashLoopBackOff"},
+	}
+	for _, tt := range tests {
+		if got := reasons(detectFailures(tt.old, tt.pod)); got != tt.want {
+			t.Errorf("%s: alerts = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPodLimiter(t *testing.T) {
+	l := newPodLimiter(10 * time.Minute)
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	if ok, _ := l.allow("web/api-0", now); !ok {
+		t.Fatal("first alert suppressed")
+	}
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("web/api-0", now.Add(time.Minute)); ok {
+			t.Fatal("alert within the cooldown allowed")
+		}
+	}
+	if ok, _ := l.allow("web/api-1", now.Add(time.Minute)); !ok {
+		t.Error("another pod was suppressed")
+	}
+	ok, held := l.allow("web/api-0", now.Add(11*time.Minute))
+	if !ok || held != 3 {
+		t.Errorf("after the cooldown: allowed %v, held %d, want true, 3", ok, held)
+	}
+	// A failed delivery hands the slot back
+	l.undo("web/api-0", held)
+	if ok, held := l.allow("web/api-0", now.Add(12*time.Minute)); !ok || held != 3 {
+		t.Errorf("retry: allowed %v, held %d, want true, 3", ok, held)
+	}
+}
+
+func TestFormatAlert(t *testing.T) {
+	a := detectFailures(nil, newPod(waiting("app", reasonCrashLoop, 4)))[0]
+	want := ":rotating_light: *CrashLoopBackOff*: pod `web/api-0`, container `app` on `node-a` (restarts: 4)\n" +
+		"> back-off restarting failed container\n_2 more alerts for this pod were suppressed_"
+	if got := formatAlert(a, 2); got != want {
+		t.Errorf("formatAlert =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got map[string]string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+		w.Write([]byte("invalid_payload"))
+	}))
+	defer server.Close()
+	n := &webhookNotifier{url: server.URL, client: server.Client()}
+
+	if err := n.notify(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if got["text"] != "hello" {
+		t.Errorf("payload = %v", got)
+	}
+	status = http.StatusBadRequest
+	if err := n.notify(context.Background(), "hello"); err == nil || !strings.Contains(err.Error(), "invalid_payload") {
+		t.Errorf("err = %v, want the webhook's explanation", err)
+	}
+}
+
+// recordingNotifier fails the first failures calls, then records the texts
+type recordingNotifier struct {
+	mu       sync.Mutex
+	failures int
+	sent     []string
+}
+
+func (n *recordingNotifier) notify(ctx context.Context, text string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.failures > 0 {
+		n.failures--
+		return errors.New("webhook unavailable")
+	}
+	n.sent = append(n.sent, text)
+	return nil
+}
+
+func TestControllerAlertsOnTransitionsOnly(t *testing.T) {
+	failing := newPod(waiting("app", reasonCrashLoop, 7))
+	failing.Name = "already-failing"
+	healthy := newPod(running("app"), running("sidecar"))
+	h := testutil.NewHarness(t, failing, healthy)
+	n := &recordingNotifier{}
+	c := NewAlertController(h.Clientset, "", n, 10*time.Minute)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		c.queue.ShutDown()
+		c.factory.Shutdown()
+	})
+	c.factory.Start(stopCh)
+	c.factory.WaitForCacheSync(stopCh)
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+	if c.queue.Len() != 0 {
+		t.Fatalf("the initial list queued %d alerts", c.queue.Len())
+	}
+
+	// Both containers fail: two alerts are queued, one is sent
+	h.Update(newPod(waiting("app", reasonCrashLoop, 1), oomKilled("sidecar", time.Now())))
+	deadline := time.Now().Add(testutil.Timeout)
+	for c.queue.Len() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for c.queue.Len() > 0 {
+		c.processNextItem(context.Background())
+	}
+	if len(n.sent) != 1 || !strings.Contains(n.sent[0], "web/api-0") {
+		t.Errorf("sent %q, want one alert for web/api-0", n.sent)
+	}
+}
+
+func TestControllerRetriesFailedDeliveries(t *testing.T) {
+	n := &recordingNotifier{failures: 1}
+	c := NewAlertController(testutil.NewHarness(t).Clientset, "", n, 10*time.Minute)
+	defer c.queue.ShutDown()
+	a := detectFailures(nil, newPod(waiting("app", reasonImagePull, 0)))[0]
+	c.enqueue([]alert{a})
+
+	c.processNextItem(context.Background())
+	if len(n.sent) != 0 || c.queue.NumRequeues(a) != 1 {
+		t.Fatalf("failed delivery was not requeued")
+	}
+	// The rate limited retry comes back after a few milliseconds
+	c.processNextItem(context.Background())
+	if len(n.sent) != 1 || !strings.Contains(n.sent[0], "ImagePullBackOff") {
+		t.Errorf("sent %q after the retry", n.sent)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// notifier delivers one alert
+type notifier interface {
+	notify(ctx context.Context, text string) error
+}
+
+// webhookNotifier posts {"text": ...} to a Slack incoming webhook. Mattermost,
+// Rocket.Chat and most chat tools accept the same payload.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *webhookNotifier) notify(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		// Slack explains a rejected payload in the body, e.g. "invalid_payload"
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// stdoutNotifier prints alerts when no webhook is configured
+type stdoutNotifier struct{}
+
+func (stdoutNotifier) notify(ctx context.Context, text string) error {
+	fmt.Println("[Alert]", text)
+	return nil
+}
+
+// podLimiter allows one alert per pod per cooldown. A crash looping pod with
+// several containers, or a pod flapping between failure states, sends one
+// message instead of a storm; the alerts held back are counted in the next one.
+type podLimiter struct {
+	cooldown time.Duration
+
+	mu         sync.Mutex
+	lastSent   map[string]time.Time
+	suppressed map[string]int
+}
+
+func newPodLimiter(cooldown time.Duration) *podLimiter {
+	return &podLimiter{cooldown: cooldown, lastSent: map[string]time.Time{}, suppressed: map[string]int{}}
+}
+
+// allow reports whether an alert for podKey may be sent now, and how many were
+// held back since the last one
+func (l *podLimiter) allow(podKey string, now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if last, ok := l.lastSent[podKey]; ok && now.Sub(last) < l.cooldown {
+		l.suppressed[podKey]++
+		return false, 0
+	}
+	held := l.suppressed[podKey]
+	l.lastSent[podKey] = now
+	delete(l.suppressed, podKey)
+	return true, held
+}
+
+// undo takes back an allow whose alert could not be delivered, so the retry
+// is not suppressed by the limiter
+func (l *podLimiter) undo(podKey string, held int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.lastSent, podKey)
+	l.suppressed[podKey] += held
+}
+
+// forget drops the state of a deleted pod
+func (l *podLimiter) forget(podKey string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.lastSent, podKey)
+	delete(l.suppressed, podKey)
+}
+
+var reasonEmoji = map[string]string{
+	reasonCrashLoop: ":rotating_light:",
+	reasonImagePull: ":package:",
+	reasonOOMKilled: ":boom:",
+}
+
+// formatAlert renders an alert as Slack mrkdwn
+func formatAlert(a alert, held int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s *%s*: pod `%s`, container `%s`", reasonEmoji[a.Reason], a.Reason, a.podKey(), a.Container)
+	if a.Node != "" {
+		fmt.Fprintf(&b, " on `%s`", a.Node)
+	}
+	fmt.Fprintf(&b, " (restarts: %d)", a.Restarts)
+	if a.Message != "" {
+		fmt.Fprintf(&b, "\n> %s", a.Message)
+	}
+	if held > 0 {
+		fmt.Fprintf(&b, "\n_%d more alerts for this pod were suppressed_", held)
+	}
+	return b.String()
+}
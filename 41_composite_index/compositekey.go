@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// keySeparator joins the parts of a composite key. Each part is path-escaped
+// first, so a part that contains the separator itself still parses back.
+const keySeparator = "/"
+
+// compositeKey builds an index key out of several fields, e.g.
+// compositeKey("default", "Running") is "default/Running". Index functions
+// and queries must build keys with the same helper, or lookups silently miss.
+func compositeKey(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = url.PathEscape(part)
+	}
+	return strings.Join(escaped, keySeparator)
+}
+
+// parseCompositeKey splits a key built by compositeKey into n parts
+func parseCompositeKey(key string, n int) ([]string, error) {
+	parts := strings.Split(key, keySeparator)
+	if len(parts) != n {
+		return nil, fmt.Errorf("composite key %q has %d parts, want %d", key, len(parts), n)
+	}
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil {
+			return nil, fmt.Errorf("composite key %q: %w", key, err)
+		}
+		parts[i] = unescaped
+	}
+	return parts, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// namespacePhaseIndex keys pods by compositeKey(namespace, phase)
+	namespacePhaseIndex = "namespace/phase"
+	// nodePhaseIndex keys pods by compositeKey(node, phase); pods that are not
+	// scheduled yet have an empty node
+	nodePhaseIndex = "node/phase"
+)
+
+// phases is the lifecycle order of the table columns
+var phases = []corev1.PodPhase{corev1.PodPending, corev1.PodRunning, corev1.PodSucceeded, corev1.PodFailed, corev1.PodUnknown}
+
+// indexers returns both composite indexes. Each answers a two-dimensional
+// question, such as "which pods in default are Pending", with one lookup
+// instead of intersecting a namespace index with a phase index.
+func indexers() cache.Indexers {
+	return cache.Indexers{
+		namespacePhaseIndex: func(obj interface{}) ([]string, error) {
+			pod := obj.(*corev1.Pod)
+			return []string{compositeKey(pod.Namespace, string(pod.Status.Phase))}, nil
+		},
+		nodePhaseIndex: func(obj interface{}) ([]string, error) {
+			pod := obj.(*corev1.Pod)
+			return []string{compositeKey(pod.Spec.NodeName, string(pod.Status.Phase))}, nil
+		},
+	}
+}
+
+// printTable prints how many pods each value of the first dimension of index
+// has in each phase. It reads only the index: ListIndexFuncValues returns the
+// composite keys in use, and IndexKeys how many pods share one.
+func printTable(w io.Writer, indexer cache.Indexer, index, dimension string) error {
+	counts := map[string]map[string]int{}
+	for _, key := range indexer.ListIndexFuncValues(index) {
+		parts, err := parseCompositeKey(key, 2)
+		if err != nil {
+			return err
+		}
+		keys, err := indexer.IndexKeys(index, key)
+		if err != nil {
+			return err
+		}
+		row := parts[0]
+		if counts[row] == nil {
+			counts[row] = map[string]int{}
+		}
+		counts[row][parts[1]] = len(keys)
+	}
+	rows := make([]string, 0, len(counts))
+	for row := range counts {
+		rows = append(rows, row)
+	}
+	slices.Sort(rows)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{strings.ToUpper(dimension)}
+	for _, phase := range phases {
+		header = append(header, strings.ToUpper(string(phase)))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		name := row
+		if name == "" {
+			name = "<unscheduled>"
+		}
+		cells := []string{name}
+		for _, phase := range phases {
+			cells = append(cells, fmt.Sprint(counts[row][string(phase)]))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// query answers --query: index=key, where key is the composite key with its
+// parts separated by /, e.g. namespace/phase=default/Pending
+func query(w io.Writer, indexer cache.Indexer, q string) error {
+	index, key, ok := strings.Cut(q, "=")
+	if !ok {
+		return fmt.Errorf("--query %q: want index=key, e.g. %s=default/Pending", q, namespacePhaseIndex)
+	}
+	if _, known := indexer.GetIndexers()[index]; !known {
+		return fmt.Errorf("--query %q: unknown index %q, want %s or %s", q, index, namespacePhaseIndex, nodePhaseIndex)
+	}
+	// Rebuild the key so that the parts are escaped exactly as the index
+	// function escaped them
+	parts := strings.Split(key, keySeparator)
+	if len(parts) != 2 {
+		return fmt.Errorf("--query %q: key %q needs 2 parts", q, key)
+	}
+	objs, err := indexer.ByIndex(index, compositeKey(parts...))
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		pod := obj.(*corev1.Pod)
+		names = append(names, pod.Namespace+"/"+pod.Name)
+	}
+	slices.Sort(names)
+	fmt.Fprintf(w, "%s = %s: %d pods\n", index, key, len(names))
+	for _, name := range names {
+		fmt.Fprintf(w, "  - %s\n", name)
+	}
+	return nil
+}
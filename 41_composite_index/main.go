@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var queryFlag = flag.String("query", "", "look up one composite key, e.g. namespace/phase=default/Pending or node/phase=node-1/Running")
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "41-composite-index")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	podInformer := factory.Core().V1().Pods().Informer()
+	// Indexes must be added before the informer starts
+	if err := podInformer.AddIndexers(indexers()); err != nil {
+		log.Fatalf("Failed to add indexes: %v", err)
+	}
+	defer factory.Shutdown()
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), podInformer.HasSynced) {
+		return
+	}
+	indexer := podInformer.GetIndexer()
+
+	fmt.Printf("=== Pods per namespace and phase (index %s) ===\n", namespacePhaseIndex)
+	if err := printTable(os.Stdout, indexer, namespacePhaseIndex, "namespace"); err != nil {
+		log.Fatalf("Failed to read index: %v", err)
+	}
+	fmt.Printf("\n=== Pods per node and phase (index %s) ===\n", nodePhaseIndex)
+	if err := printTable(os.Stdout, indexer, nodePhaseIndex, "node"); err != nil {
+		log.Fatalf("Failed to read index: %v", err)
+	}
+	if *queryFlag != "" {
+		fmt.Println()
+		if err := query(os.Stdout, indexer, *queryFlag); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
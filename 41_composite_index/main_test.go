@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestCompositeKeyRoundTrip(t *testing.T) {
+	for _, parts := range [][]string{
+		{"default", "Running"},
+		{"", "Pending"},
+		{"a/b", "c%d"},
+	} {
+		key := compositeKey(parts...)
+		got, err := parseCompositeKey(key, len(parts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, parts) {
+			t.Errorf("parse(%q) = %q, want %q", key, got, parts)
+		}
+	}
+	if key := compositeKey("default", "Running"); key != "default/Running" {
+		t.Errorf("key = %q, want default/Running", key)
+	}
+	// A separator inside a part must not create a third part
+	if _, err := parseCompositeKey(compositeKey("a/b", "c"), 2); err != nil {
+		t.Errorf("escaped separator: %v", err)
+	}
+	if _, err := parseCompositeKey("a/b/c", 2); err == nil {
+		t.Error("three parts parsed as two")
+	}
+}
+
+func pod(namespace, name, node string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+// newIndexer returns an indexer with the example's indexes holding pods
+func newIndexer(t *testing.T, pods ...*corev1.Pod) cache.Indexer {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, indexers())
+	for _, p := range pods {
+		if err := indexer.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return indexer
+}
+
+func testIndexer(t *testing.T) cache.Indexer {
+	return newIndexer(t,
+		pod("default", "web-1", "node-a", corev1.PodRunning),
+		pod("default", "web-2", "node-b", corev1.PodRunning),
+		pod("default", "queued", "", corev1.PodPending),
+		pod("kube-system", "coredns", "node-a", corev1.PodRunning),
+		pod("kube-system", "migrate", "node-b", corev1.PodSucceeded),
+	)
+}
+
+func TestCompositeIndexLookups(t *testing.T) {
+	indexer := testIndexer(t)
+	for _, tc := range []struct {
+		index string
+		parts []string
+		want  []string
+	}{
+		{namespacePhaseIndex, []string{"default", "Running"}, []string{"default/web-1", "default/web-2"}},
+		{namespacePhaseIndex, []string{"kube-system", "Pending"}, nil},
+		{nodePhaseIndex, []string{"node-a", "Running"}, []string{"default/web-1", "kube-system/coredns"}},
+		{nodePhaseIndex, []string{"", "Pending"}, []string{"default/queued"}},
+	} {
+		keys, err := indexer.IndexKeys(tc.index, compositeKey(tc.parts...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(keys)
+		if !slices.Equal(keys, tc.want) {
+			t.Errorf("%s %q = %v, want %v", tc.index, tc.parts, keys, tc.want)
+		}
+	}
+
+	// The index follows updates: the key moves with the phase
+	if err := indexer.Update(pod("default", "queued", "node-a", corev1.PodRunning)); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := indexer.IndexKeys(nodePhaseIndex, compositeKey("", "Pending")); len(keys) != 0 {
+		t.Errorf("scheduled pod still indexed as unscheduled: %v", keys)
+	}
+}
+
+func TestPrintTable(t *testing.T) {
+	var out bytes.Buffer
+	if err := printTable(&out, testIndexer(t), nodePhaseIndex, "node"); err != nil {
+		t.Fatal(err)
+	}
+	want := `NODE           PENDING  RUNNING  SUCCEEDED  FAILED  UNKNOWN
+<unscheduled>  1        0        0          0       0
+node-a         0        2        0          0       0
+node-b         0        1        1          0       0
+`
+	if out.String() != want {
+		t.Errorf("table:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	indexer := testIndexer(t)
+	var out bytes.Buffer
+	if err := query(&out, indexer, "namespace/phase=default/Running"); err != nil {
+		t.Fatal(err)
+	}
+	want := "namespace/phase = default/Running: 2 pods\n  - default/web-1\n  - default/web-2\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	for q, wantErr := range map[string]string{
+		"default/Running":             "want index=key",
+		"phase=Running":               `unknown index "phase"`,
+		"namespace/phase=default":     "needs 2 parts",
+		"node/phase=node-a/Running/x": "needs 2 parts",
+	} {
+		if err := query(&out, indexer, q); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("query(%q) err = %v, want %q", q, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resourceTotals is CPU in millicores and memory in bytes
+type resourceTotals struct {
+	cpuRequests, cpuLimits       int64
+	memoryRequests, memoryLimits int64
+}
+
+func (t *resourceTotals) add(other resourceTotals) {
+	t.cpuRequests += other.cpuRequests
+	t.cpuLimits += other.cpuLimits
+	t.memoryRequests += other.memoryRequests
+	t.memoryLimits += other.memoryLimits
+}
+
+func (t *resourceTotals) subtract(other resourceTotals) {
+	t.cpuRequests -= other.cpuRequests
+	t.cpuLimits -= other.cpuLimits
+	t.memoryRequests -= other.memoryRequests
+	t.memoryLimits -= other.memoryLimits
+}
+
+// podTotals is what a pod reserves on its node, computed the way the
+// scheduler and kubectl describe node do. Init containers run one at a time
+// before the app containers, so they count only where the largest of them
+// exceeds the app containers. Sidecars (init containers with restartPolicy
+// Always) keep running and add up. The pod overhead of its RuntimeClass
+// comes on top.
+func podTotals(pod *corev1.Pod) resourceTotals {
+	cpu := func(list corev1.ResourceList) int64 { return list.Cpu().MilliValue() }
+	memory := func(list corev1.ResourceList) int64 { return list.Memory().Value() }
+	requests := func(c corev1.Container) corev1.ResourceList { return c.Resources.Requests }
+	limits := func(c corev1.Container) corev1.ResourceList { return c.Resources.Limits }
+	return resourceTotals{
+		cpuRequests:    podResource(pod, requests, cpu),
+		cpuLimits:      podResource(pod, limits, cpu),
+		memoryRequests: podResource(pod, requests, memory),
+		memoryLimits:   podResource(pod, limits, memory),
+	}
+}
+
+func podResource(pod *corev1.Pod, list func(corev1.Container) corev1.ResourceList, value func(corev1.ResourceList) int64) int64 {
+	var app, sidecars, init int64
+	for _, c := range pod.Spec.Containers {
+		app += value(list(c))
+	}
+	for _, c := range pod.Spec.InitContainers {
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			sidecars += value(list(c))
+			init = max(init, sidecars)
+			continue
+		}
+		init = max(init, sidecars+value(list(c)))
+	}
+	return max(app+sidecars, init) + value(pod.Spec.Overhead)
+}
+
+// podContribution is what one pod added to the totals of its node
+type podContribution struct {
+	node   string
+	totals resourceTotals
+}
+
+// nodeAggregator keeps the summed requests and limits of every node up to
+// date from pod events. Each event subtracts what the pod contributed before
+// and adds what it contributes now, so an event costs the same however many
+// pods the node runs, and nothing is ever rescanned.
+type nodeAggregator struct {
+	mu            sync.Mutex
+	nodes         map[string]resourceTotals
+	contributions map[string]podContribution
+}
+
+func newNodeAggregator() *nodeAggregator {
+	return &nodeAggregator{
+		nodes:         map[string]resourceTotals{},
+		contributions: map[string]podContribution{},
+	}
+}
+
+func (a *nodeAggregator) OnAdd(obj interface{}, isInInitialList bool) {
+	a.set(obj.(*corev1.Pod))
+}
+
+func (a *nodeAggregator) OnUpdate(oldObj, newObj interface{}) {
+	a.set(newObj.(*corev1.Pod))
+}
+
+func (a *nodeAggregator) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		a.remove(tombstone.Key)
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	a.remove(key)
+}
+
+// set replaces the contribution of pod. Pods that are not scheduled, or have
+// finished, reserve nothing.
+func (a *nodeAggregator) set(pod *corev1.Pod) {
+	key, err := cache.MetaNamespaceKeyFunc(pod)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.removeLocked(key)
+	if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return
+	}
+	contribution := podContribution{node: pod.Spec.NodeName, totals: podTotals(pod)}
+	totals := a.nodes[contribution.node]
+	totals.add(contribution.totals)
+	a.nodes[contribution.node] = totals
+	a.contributions[key] = contribution
+}
+
+func (a *nodeAggregator) remove(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.removeLocked(key)
+}
+
+func (a *nodeAggregator) removeLocked(key string) {
+	contribution, ok := a.contributions[key]
+	if !ok {
+		return
+	}
+	delete(a.contributions, key)
+	totals := a.nodes[contribution.node]
+	totals.subtract(contribution.totals)
+	if totals == (resourceTotals{}) {
+		delete(a.nodes, contribution.node)
+		return
+	}
+	a.nodes[contribution.node] = totals
+}
+
+// totals returns the current sums for one node
+func (a *nodeAggregator) totals(node string) resourceTotals {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.nodes[node]
+}
+
+// printUtilization prints each node's requests and limits as a share of its
+// allocatable capacity. Limits above 100% mean the node is overcommitted:
+// its pods may together use more than it has.
+func printUtilization(w io.Writer, nodes corelisters.NodeLister, aggregator *nodeAggregator) error {
+	nodeList, err := nodes.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(nodeList, func(a, b *corev1.Node) int { return strings.Compare(a.Name, b.Name) })
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tCPU REQUESTS\tCPU LIMITS\tMEMORY REQUESTS\tMEMORY LIMITS")
+	for _, node := range nodeList {
+		totals := aggregator.totals(node.Name)
+		allocatable := node.Status.Allocatable
+		cpu, memory := allocatable.Cpu().MilliValue(), allocatable.Memory().Value()
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", node.Name,
+			withPercent(formatCPU(resource.NewMilliQuantity(totals.cpuRequests, resource.DecimalSI)), totals.cpuRequests, cpu),
+			withPercent(formatCPU(resource.NewMilliQuantity(totals.cpuLimits, resource.DecimalSI)), totals.cpuLimits, cpu),
+			withPercent(formatMemory(resource.NewQuantity(totals.memoryRequests, resource.BinarySI)), totals.memoryRequests, memory),
+			withPercent(formatMemory(resource.NewQuantity(totals.memoryLimits, resource.BinarySI)), totals.memoryLimits, memory),
+		)
+	}
+	return tw.Flush()
+}
+
+// withPercent appends used as a percentage of allocatable
+func withPercent(formatted string, used, allocatable int64) string {
+	if allocatable == 0 {
+		return formatted
+	}
+	return fmt.Sprintf("%s (%d%%)", formatted, used*100/allocatable)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// withResources gives pod one container per requirement
+func withResources(pod *corev1.Pod, requirements ...corev1.ResourceRequirements) *corev1.Pod {
+	for _, r := range requirements {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Resources: r})
+	}
+	return pod
+}
+
+func requirements(cpuRequest, memoryRequest, cpuLimit, memoryLimit string) corev1.ResourceRequirements {
+	r := corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}
+	set := func(list corev1.ResourceList, name corev1.ResourceName, value string) {
+		if value != "" {
+			list[name] = resource.MustParse(value)
+		}
+	}
+	set(r.Requests, corev1.ResourceCPU, cpuRequest)
+	set(r.Requests, corev1.ResourceMemory, memoryRequest)
+	set(r.Limits, corev1.ResourceCPU, cpuLimit)
+	set(r.Limits, corev1.ResourceMemory, memoryLimit)
+	return r
+}
+
+func TestPodTotals(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	p := withResources(pod("default", "web", "node-a"),
+		requirements("100m", "128Mi", "200m", ""),
+		requirements("250m", "64Mi", "", "256Mi"),
+	)
+	if got, want := podTotals(p), (resourceTotals{cpuRequests: 350, cpuLimits: 200, memoryRequests: 192 << 20, memoryLimits: 256 << 20}); got != want {
+		t.Errorf("app containers: %+v, want %+v", got, want)
+	}
+
+	// A large init container dominates the app containers it runs before
+	p.Spec.InitContainers = []corev1.Container{{Resources: requirements("1", "", "", "")}}
+	if got := podTotals(p).cpuRequests; got != 1000 {
+		t.Errorf("with init container: cpu requests %d, want 1000", got)
+	}
+	// A sidecar keeps running next to the app containers and every later init container
+	p.Spec.InitContainers = []corev1.Container{
+		{Resources: requirements("50m", "", "", ""), RestartPolicy: &always},
+		{Resources: requirements("300m", "", "", "")},
+	}
+	if got := podTotals(p).cpuRequests; got != 400 {
+		t.Errorf("with sidecar: cpu requests %d, want 350+50", got)
+	}
+	p.Spec.Overhead = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")}
+	if got := podTotals(p).cpuRequests; got != 410 {
+		t.Errorf("with overhead: cpu requests %d, want 410", got)
+	}
+}
+
+func TestAggregatorIsIncremental(t *testing.T) {
+	a := newNodeAggregator()
+	web := withResources(pod("default", "web", "node-a"), requirements("100m", "1Gi", "", ""))
+	api := withResources(pod("default", "api", "node-a"), requirements("200m", "", "", ""))
+	a.OnAdd(web, true)
+	a.OnAdd(api, true)
+	if got := a.totals("node-a").cpuRequests; got != 300 {
+		t.Fatalf("after adds: %dm, want 300m", got)
+	}
+
+	// Updating a pod replaces its contribution instead of adding to it
+	a.OnUpdate(web, web)
+	if got := a.totals("node-a").cpuRequests; got != 300 {
+		t.Errorf("after resync: %dm, want 300m", got)
+	}
+	// A pod that finished no longer reserves anything
+	done := api.DeepCopy()
+	done.Status.Phase = corev1.PodSucceeded
+	a.OnUpdate(api, done)
+	if got := a.totals("node-a").cpuRequests; got != 100 {
+		t.Errorf("after api finished: %dm, want 100m", got)
+	}
+	// A delete the watch missed arrives as a tombstone
+	a.OnDelete(cache.DeletedFinalStateUnknown{Key: "default/web", Obj: web})
+	if got := a.totals("node-a"); got != (resourceTotals{}) {
+		t.Errorf("after deletes: %+v, want zero", got)
+	}
+	if len(a.nodes) != 0 || len(a.contributions) != 0 {
+		t.Errorf("state left behind: %v %v", a.nodes, a.contributions)
+	}
+
+	// Unscheduled pods count once they are bound
+	queued := withResources(pod("default", "queued", ""), requirements("500m", "", "", ""))
+	a.OnAdd(queued, false)
+	bound := queued.DeepCopy()
+	bound.Spec.NodeName = "node-b"
+	a.OnUpdate(queued, bound)
+	if got := a.totals("node-b").cpuRequests; got != 500 {
+		t.Errorf("bound pod: %dm, want 500m", got)
+	}
+}
+
+func TestPrintUtilization(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		node("node-a", "4", "8Gi"),
+		node("node-b", "2", "4Gi"),
+		withResources(pod("default", "web", "node-a"), requirements("1", "2Gi", "2", "4Gi")),
+		withResources(pod("default", "big", "node-a"), requirements("1", "", "4", "8Gi")),
+	)
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	aggregator := newNodeAggregator()
+	registration, err := factory.Core().V1().Pods().Informer().AddEventHandler(aggregator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeLister := factory.Core().V1().Nodes().Lister()
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		factory.Shutdown()
+	})
+	factory.Start(stopCh)
+	// The registration has synced once the aggregator has seen the initial list
+	cache.WaitForCacheSync(stopCh, registration.HasSynced, factory.Core().V1().Nodes().Informer().HasSynced)
+
+	var out bytes.Buffer
+	if err := printUtilization(&out, nodeLister, aggregator); err != nil {
+		t.Fatal(err)
+	}
+	want := `NODE    CPU REQUESTS  CPU LIMITS    MEMORY REQUESTS  MEMORY LIMITS
+node-a  2000m (50%)   6000m (150%)  2.0Gi (25%)      12.0Gi (150%)
+node-b  0m (0%)       0m (0%)       0.0Gi (0%)       0.0Gi (0%)
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// nodeIndex indexes pods by spec.nodeName. Pods that are not scheduled yet
+// are indexed under "".
+const nodeIndex = "node"
+
+func podNodeIndexFunc(obj interface{}) ([]string, error) {
+	pod := obj.(*corev1.Pod)
+	return []string{pod.Spec.NodeName}, nil
+}
+
+// nodeView is one node and the pods placed on it. node is nil for pods that
+// are not scheduled, or whose node is not in the node cache (yet).
+type nodeView struct {
+	name string
+	node *corev1.Node
+	pods []*corev1.Pod
+}
+
+// joinNodes correlates the two caches: every node from the node lister, with
+// its pods looked up in the pod indexer through nodeIndex. Pods on a node
+// missing from the node cache are found by comparing the index values with
+// the node names, without scanning every pod.
+func joinNodes(nodes corelisters.NodeLister, pods cache.Indexer) ([]nodeView, error) {
+	nodeList, err := nodes.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(nodeList, func(a, b *corev1.Node) int { return strings.Compare(a.Name, b.Name) })
+
+	known := map[string]bool{}
+	var views []nodeView
+	for _, node := range nodeList {
+		known[node.Name] = true
+		podsOnNode, err := podsByNode(pods, node.Name)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, nodeView{name: node.Name, node: node, pods: podsOnNode})
+	}
+	// Index values without a node: unscheduled pods, and pods on a node the
+	// node informer has not seen or has already deleted
+	var orphans []string
+	for _, name := range pods.ListIndexFuncValues(nodeIndex) {
+		if !known[name] {
+			orphans = append(orphans, name)
+		}
+	}
+	slices.Sort(orphans)
+	for _, name := range orphans {
+		podsOnNode, err := podsByNode(pods, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(podsOnNode) > 0 {
+			views = append(views, nodeView{name: name, pods: podsOnNode})
+		}
+	}
+	return views, nil
+}
+
+// podsByNode returns the pods on one node, sorted by namespace/name
+func podsByNode(pods cache.Indexer, name string) ([]*corev1.Pod, error) {
+	objs, err := pods.ByIndex(nodeIndex, name)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*corev1.Pod))
+	}
+	slices.SortFunc(result, func(a, b *corev1.Pod) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+	return result, nil
+}
+
+// printNodes prints each node's allocatable capacity followed by its pods
+func printNodes(w io.Writer, views []nodeView) {
+	for _, view := range views {
+		switch {
+		case view.node != nil:
+			allocatable := view.node.Status.Allocatable
+			fmt.Fprintf(w, "%s  cpu %s  memory %s  pods %d/%s\n", view.name,
+				formatCPU(allocatable.Cpu()), formatMemory(allocatable.Memory()),
+				len(view.pods), allocatable.Pods())
+		case view.name == "":
+			fmt.Fprintf(w, "<unscheduled>  pods %d\n", len(view.pods))
+		default:
+			fmt.Fprintf(w, "%s  (not in the node cache)  pods %d\n", view.name, len(view.pods))
+		}
+		for _, pod := range view.pods {
+			fmt.Fprintf(w, "  - %s/%s (%s)\n", pod.Namespace, pod.Name, pod.Status.Phase)
+		}
+	}
+}
+
+// formatCPU prints CPU in millicores, the unit requests are usually written in
+func formatCPU(q *resource.Quantity) string {
+	return fmt.Sprintf("%dm", q.MilliValue())
+}
+
+// formatMemory prints memory in GiB; allocatable is reported in Ki, which is
+// hard to read
+func formatMemory(q *resource.Quantity) string {
+	return fmt.Sprintf("%.1fGi", float64(q.Value())/(1<<30))
+}
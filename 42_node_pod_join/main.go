@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var interval = flag.Duration("interval", 0, "keep running and print the utilization table this often (0 prints once and exits)")
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "42-node-pod-join")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Both informers come from one factory, so they share its lifecycle and
+	// one Start and WaitForCacheSync covers the two of them
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	podInformer := factory.Core().V1().Pods().Informer()
+	// Indexes must be added before the informer starts
+	if err := podInformer.AddIndexers(cache.Indexers{nodeIndex: podNodeIndexFunc}); err != nil {
+		log.Fatalf("Failed to add node index: %v", err)
+	}
+	// The aggregator keeps per-node sums up to date from pod events, starting
+	// with the adds of the initial list
+	aggregator := newNodeAggregator()
+	registration, err := podInformer.AddEventHandler(aggregator)
+	if err != nil {
+		log.Fatalf("Failed to add aggregator: %v", err)
+	}
+	nodeLister := factory.Core().V1().Nodes().Lister()
+	defer factory.Shutdown()
+	factory.Start(ctx.Done())
+	for typ, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			log.Printf("%v cache did not sync", typ)
+			return
+		}
+	}
+	// The informer has synced when its cache holds the initial list; the
+	// aggregator may still be working through the adds until its registration
+	// has synced too
+	if !cache.WaitForCacheSync(ctx.Done(), registration.HasSynced) {
+		return
+	}
+
+	views, err := joinNodes(nodeLister, podInformer.GetIndexer())
+	if err != nil {
+		log.Fatalf("Failed to join nodes and pods: %v", err)
+	}
+	printNodes(os.Stdout, views)
+	fmt.Println()
+	if err := printUtilization(os.Stdout, nodeLister, aggregator); err != nil {
+		log.Fatalf("Failed to list nodes: %v", err)
+	}
+	if *interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fmt.Printf("\n%s\n", time.Now().Format(time.TimeOnly))
+			if err := printUtilization(os.Stdout, nodeLister, aggregator); err != nil {
+				log.Printf("Failed to list nodes: %v", err)
+			}
+		}
+	}
+}
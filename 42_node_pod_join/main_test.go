@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func node(name, cpu, memory string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+			corev1.ResourcePods:   resource.MustParse("110"),
+		}},
+	}
+}
+
+func pod(namespace, name, nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func TestJoinNodesAndPods(t *testing.T) {
+	queued := pod("default", "queued", "")
+	queued.Status.Phase = corev1.PodPending
+	clientset := fake.NewSimpleClientset(
+		node("node-b", "2", "4Gi"),
+		node("node-a", "3920m", "8029384Ki"),
+		pod("default", "web", "node-a"),
+		pod("kube-system", "coredns", "node-a"),
+		queued,
+		pod("default", "stale", "node-gone"),
+	)
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	podInformer := factory.Core().V1().Pods().Informer()
+	if err := podInformer.AddIndexers(cache.Indexers{nodeIndex: podNodeIndexFunc}); err != nil {
+		t.Fatal(err)
+	}
+	nodeLister := factory.Core().V1().Nodes().Lister()
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		factory.Shutdown()
+	})
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	views, err := joinNodes(nodeLister, podInformer.GetIndexer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	printNodes(&out, views)
+	want := `node-a  cpu 3920m  memory 7.7Gi  pods 2/110
+  - default/web (Running)
+  - kube-system/coredns (Running)
+node-b  cpu 2000m  memory 4.0Gi  pods 0/110
+<unscheduled>  pods 1
+  - default/queued (Pending)
+node-gone  (not in the node cache)  pods 1
+  - default/stale (Running)
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
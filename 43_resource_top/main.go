@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	mode      = flag.String("mode", "pods", "pods (like kubectl top pod) or nodes (like kubectl top node)")
+	namespace = flag.String("namespace", "", "only show pods in this namespace (empty for all namespaces)")
+	sortBy    = flag.String("sort-by", "cpu", "cpu or memory")
+	watch     = flag.Duration("watch", 0, "print the table again this often (0 prints once and exits)")
+)
+
+// createClients creates and returns a Kubernetes clientset and a metrics clientset
+func createClients() (*kubernetes.Clientset, *metricsclientset.Clientset) {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "43-resource-top")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	// Create metrics clientset (metrics.k8s.io, served by metrics-server)
+	metricsClient, err := metricsclientset.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create metrics clientset: %v", err)
+	}
+	return clientset, metricsClient
+}
+
+func main() {
+	clientset, metricsClient := createClients()
+	if *sortBy != "cpu" && *sortBy != "memory" {
+		log.Fatalf("--sort-by must be cpu or memory, not %q", *sortBy)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Pods and nodes come from the cache, and only the informer the mode needs
+	// is created. Nodes are cluster scoped, so --namespace does not limit them.
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(*namespace))
+	var printTable func(ctx context.Context) error
+	switch *mode {
+	case "pods":
+		pods := factory.Core().V1().Pods().Lister()
+		printTable = func(ctx context.Context) error {
+			rows, err := topPods(ctx, metricsClient, pods, *namespace)
+			if err != nil {
+				return err
+			}
+			sortPods(rows, *sortBy)
+			return printPods(os.Stdout, rows)
+		}
+	case "nodes":
+		nodes := factory.Core().V1().Nodes().Lister()
+		printTable = func(ctx context.Context) error {
+			rows, err := topNodes(ctx, metricsClient, nodes)
+			if err != nil {
+				return err
+			}
+			sortNodes(rows, *sortBy)
+			return printNodes(os.Stdout, rows)
+		}
+	default:
+		log.Fatalf("--mode must be pods or nodes, not %q", *mode)
+	}
+	defer factory.Shutdown()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+
+	// metrics.k8s.io has no watch, so usage is listed again for every table
+	for {
+		if err := printTable(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Fatal(err)
+		}
+		if *watch == 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*watch):
+			fmt.Println()
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func resources(cpu, memory string) corev1.ResourceList {
+	return corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(memory)}
+}
+
+func pod(name string, phase corev1.PodPhase, requests corev1.ResourceList) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName:   "node-a",
+			Containers: []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{Requests: requests}}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func podMetrics(name string, containers ...corev1.ResourceList) metricsv1beta1.PodMetrics {
+	m := metricsv1beta1.PodMetrics{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	for _, usage := range containers {
+		m.Containers = append(m.Containers, metricsv1beta1.ContainerMetrics{Usage: usage})
+	}
+	return m
+}
+
+// metricsClient answers LISTs with the given metrics. The fake tracker
+// cannot map PodMetrics to the "pods" resource of metrics.k8s.io, so the
+// lists are served by reactors.
+func metricsClient(pods []metricsv1beta1.PodMetrics, nodes []metricsv1beta1.NodeMetrics) *metricsfake.Clientset {
+	client := metricsfake.NewSimpleClientset()
+	client.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &metricsv1beta1.PodMetricsList{Items: pods}, nil
+	})
+	client.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &metricsv1beta1.NodeMetricsList{Items: nodes}, nil
+	})
+	return client
+}
+
+// startFactory returns a synced factory over objects
+func startFactory(t *testing.T, objects ...runtime.Object) informers.SharedInformerFactory {
+	t.Helper()
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(objects...), 0)
+	factory.Core().V1().Pods().Informer()
+	factory.Core().V1().Nodes().Informer()
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		factory.Shutdown()
+	})
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	return factory
+}
+
+func TestTopPods(t *testing.T) {
+	factory := startFactory(t,
+		pod("web", corev1.PodRunning, resources("100m", "128Mi")),
+		pod("api", corev1.PodRunning, resources("500m", "256Mi")),
+		pod("starting", corev1.PodRunning, nil),
+		pod("done", corev1.PodSucceeded, nil),
+	)
+	client := metricsClient([]metricsv1beta1.PodMetrics{
+		// Two containers: the usage of the pod is their sum
+		podMetrics("web", resources("120m", "100Mi"), resources("30m", "28Mi")),
+		podMetrics("api", resources("50m", "300Mi")),
+		// Already deleted, and no longer in the cache
+		podMetrics("gone", resources("1", "1Gi")),
+	}, nil)
+
+	rows, err := topPods(context.Background(), client, factory.Core().V1().Pods().Lister(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortPods(rows, "cpu")
+	var out bytes.Buffer
+	if err := printPods(&out, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := `NAMESPACE  NAME      NODE    CPU(cores)  CPU%REQ  MEMORY(bytes)  MEMORY%REQ
+default    web       node-a  150m        150%     128Mi          100%
+default    api       node-a  50m         10%      300Mi          117%
+default    starting  node-a  <unknown>   -        <unknown>      -
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	sortPods(rows, "memory")
+	if rows[0].name != "api" {
+		t.Errorf("sorted by memory: %s first, want api", rows[0].name)
+	}
+}
+
+func TestTopNodes(t *testing.T) {
+	node := func(name string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.NodeStatus{Allocatable: resources("4", "8Gi")},
+		}
+	}
+	factory := startFactory(t, node("node-a"), node("node-b"), node("node-new"))
+	client := metricsClient(nil, []metricsv1beta1.NodeMetrics{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}, Usage: resources("1", "2Gi")},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}, Usage: resources("3", "1Gi")},
+	})
+
+	rows, err := topNodes(context.Background(), client, factory.Core().V1().Nodes().Lister())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortNodes(rows, "cpu")
+	var out bytes.Buffer
+	if err := printNodes(&out, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := `NAME      CPU(cores)  CPU%       MEMORY(bytes)  MEMORY%
+node-b    3000m       75%        1024Mi         12%
+node-a    1000m       25%        2048Mi         25%
+node-new  <unknown>   <unknown>  <unknown>      <unknown>
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// usage is what metrics-server measured for one pod or node
+type usage struct {
+	cpuMilli, memoryBytes int64
+}
+
+// podRow is one line of the pod table: usage from metrics.k8s.io next to
+// what the cached pod requests
+type podRow struct {
+	namespace, name, node     string
+	usage                     *usage
+	cpuRequest, memoryRequest int64
+}
+
+// nodeRow is one line of the node table: usage next to allocatable
+type nodeRow struct {
+	name                              string
+	usage                             *usage
+	cpuAllocatable, memoryAllocatable int64
+}
+
+// topPods lists PodMetrics once and joins them with the cached pods. The
+// cache decides which pods are shown: a pod without metrics yet, because
+// metrics-server has not scraped it since it started, is shown without
+// usage, and metrics of a pod already gone from the cache are dropped.
+func topPods(ctx context.Context, metricsClient metricsclientset.Interface, pods corelisters.PodLister, ns string) ([]podRow, error) {
+	list, err := metricsClient.MetricsV1beta1().PodMetricses(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing pod metrics (is metrics-server installed?): %w", err)
+	}
+	measured := make(map[string]*usage, len(list.Items))
+	for _, m := range list.Items {
+		measured[m.Namespace+"/"+m.Name] = podUsage(m)
+	}
+
+	cached, err := pods.Pods(ns).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var rows []podRow
+	for _, pod := range cached {
+		// Only running pods are measured
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		cpu, memory := podRequests(pod)
+		rows = append(rows, podRow{
+			namespace: pod.Namespace, name: pod.Name, node: pod.Spec.NodeName,
+			usage:      measured[pod.Namespace+"/"+pod.Name],
+			cpuRequest: cpu, memoryRequest: memory,
+		})
+	}
+	return rows, nil
+}
+
+// podUsage sums the usage of a pod's containers
+func podUsage(m metricsv1beta1.PodMetrics) *usage {
+	u := &usage{}
+	for _, c := range m.Containers {
+		u.cpuMilli += c.Usage.Cpu().MilliValue()
+		u.memoryBytes += c.Usage.Memory().Value()
+	}
+	return u
+}
+
+// podRequests sums the CPU (millicores) and memory (bytes) requests of a
+// pod's containers
+func podRequests(pod *corev1.Pod) (int64, int64) {
+	var cpu, memory int64
+	for _, c := range pod.Spec.Containers {
+		cpu += c.Resources.Requests.Cpu().MilliValue()
+		memory += c.Resources.Requests.Memory().Value()
+	}
+	return cpu, memory
+}
+
+// topNodes lists NodeMetrics once and joins them with the cached nodes
+func topNodes(ctx context.Context, metricsClient metricsclientset.Interface, nodes corelisters.NodeLister) ([]nodeRow, error) {
+	list, err := metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing node metrics (is metrics-server installed?): %w", err)
+	}
+	measured := make(map[string]*usage, len(list.Items))
+	for _, m := range list.Items {
+		measured[m.Name] = &usage{
+			cpuMilli:    m.Usage.Cpu().MilliValue(),
+			memoryBytes: m.Usage.Memory().Value(),
+		}
+	}
+
+	cached, err := nodes.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]nodeRow, 0, len(cached))
+	for _, node := range cached {
+		rows = append(rows, nodeRow{
+			name:              node.Name,
+			usage:             measured[node.Name],
+			cpuAllocatable:    node.Status.Allocatable.Cpu().MilliValue(),
+			memoryAllocatable: node.Status.Allocatable.Memory().Value(),
+		})
+	}
+	return rows, nil
+}
+
+// sortPods orders rows by --sort-by, the heaviest first, and by name when
+// usage is equal or missing
+func sortPods(rows []podRow, by string) {
+	slices.SortStableFunc(rows, func(a, b podRow) int {
+		if c := compareUsage(a.usage, b.usage, by); c != 0 {
+			return c
+		}
+		return strings.Compare(a.namespace+"/"+a.name, b.namespace+"/"+b.name)
+	})
+}
+
+func sortNodes(rows []nodeRow, by string) {
+	slices.SortStableFunc(rows, func(a, b nodeRow) int {
+		if c := compareUsage(a.usage, b.usage, by); c != 0 {
+			return c
+		}
+		return strings.Compare(a.name, b.name)
+	})
+}
+
+// compareUsage sorts descending, with rows lacking metrics last
+func compareUsage(a, b *usage, by string) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	case by == "memory":
+		return -cmpInt64(a.memoryBytes, b.memoryBytes)
+	default:
+		return -cmpInt64(a.cpuMilli, b.cpuMilli)
+	}
+}
+
+func cmpInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// printPods prints the pod table. CPU% and MEMORY% are usage as a share of
+// the pod's requests; above 100% the pod uses more than it reserved.
+func printPods(w io.Writer, rows []podRow) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tNAME\tNODE\tCPU(cores)\tCPU%REQ\tMEMORY(bytes)\tMEMORY%REQ")
+	for _, row := range rows {
+		if row.usage == nil {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t<unknown>\t-\t<unknown>\t-\n", row.namespace, row.name, row.node)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", row.namespace, row.name, row.node,
+			formatCPU(row.usage.cpuMilli), percent(row.usage.cpuMilli, row.cpuRequest),
+			formatMemory(row.usage.memoryBytes), percent(row.usage.memoryBytes, row.memoryRequest))
+	}
+	return tw.Flush()
+}
+
+// printNodes prints the node table, with usage as a share of allocatable
+// like kubectl top node
+func printNodes(w io.Writer, rows []nodeRow) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCPU(cores)\tCPU%\tMEMORY(bytes)\tMEMORY%")
+	for _, row := range rows {
+		if row.usage == nil {
+			fmt.Fprintf(tw, "%s\t<unknown>\t<unknown>\t<unknown>\t<unknown>\n", row.name)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", row.name,
+			formatCPU(row.usage.cpuMilli), percent(row.usage.cpuMilli, row.cpuAllocatable),
+			formatMemory(row.usage.memoryBytes), percent(row.usage.memoryBytes, row.memoryAllocatable))
+	}
+	return tw.Flush()
+}
+
+// formatCPU prints millicores the way kubectl top does
+func formatCPU(milli int64) string {
+	return fmt.Sprintf("%dm", milli)
+}
+
+// formatMemory prints memory in Mi, the unit kubectl top uses
+func formatMemory(bytes int64) string {
+	return fmt.Sprintf("%dMi", bytes/(1<<20))
+}
+
+// percent is used as a share of total, or - when there is no total, such as
+// for a pod without requests
+func percent(used, total int64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", used*100/total)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	custommetrics "k8s.io/metrics/pkg/client/custom_metrics"
+	externalmetrics "k8s.io/metrics/pkg/client/external_metrics"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace      = flag.String("namespace", "default", "namespace of the deployment")
+	deploymentName = flag.String("deployment", "", "deployment to evaluate (required)")
+	metricName     = flag.String("metric", "", "metric name, e.g. http_requests_per_second (required)")
+	source         = flag.String("source", sourcePods, "pods (average over the pods), object (a metric of the Deployment) or external")
+	targetFlag     = flag.String("target", "", "target value: per pod for pods and external, in total for object, e.g. 100 or 500m (required)")
+	metricSelector = flag.String("metric-selector", "", "label selector narrowing the metric's series, e.g. queue=orders")
+	minReplicas    = flag.Int("min-replicas", 1, "never scale below this")
+	maxReplicas    = flag.Int("max-replicas", 10, "never scale above this")
+	tolerance      = flag.Float64("tolerance", 0.1, "keep the replica count while usage/target is within this of 1.0")
+	apply          = flag.Bool("apply", false, "scale the deployment instead of only printing the decision")
+	interval       = flag.Duration("interval", 0, "evaluate again this often, like the HPA's 15s sync period (0 evaluates once)")
+)
+
+// createClients creates the Kubernetes clientset and the clients of the
+// custom and external metrics APIs
+func createClients() (*kubernetes.Clientset, *metricReader) {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "44-custom-metrics-scaler")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	// custom.metrics.k8s.io addresses metrics by resource, so the client
+	// needs a RESTMapper to turn a kind such as Deployment into one. It also
+	// asks discovery which version of the API the adapter serves.
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+	custom := custommetrics.NewForConfig(config, mapper, custommetrics.NewAvailableAPIsGetter(clientset.Discovery()))
+	external, err := externalmetrics.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create external metrics client: %v", err)
+	}
+	return clientset, &metricReader{custom: custom, external: external}
+}
+
+func main() {
+	clientset, reader := createClients()
+	if *deploymentName == "" || *metricName == "" || *targetFlag == "" {
+		log.Fatal("--deployment, --metric and --target are required")
+	}
+	if *source != sourcePods && *source != sourceObject && *source != sourceExternal {
+		log.Fatalf("--source must be %s, %s or %s, not %q", sourcePods, sourceObject, sourceExternal, *source)
+	}
+	target, err := resource.ParseQuantity(*targetFlag)
+	if err != nil || target.Sign() <= 0 {
+		log.Fatalf("--target must be a positive quantity, not %q", *targetFlag)
+	}
+	selector, err := labels.Parse(*metricSelector)
+	if err != nil {
+		log.Fatalf("Invalid --metric-selector: %v", err)
+	}
+	if *minReplicas < 1 || *maxReplicas < *minReplicas {
+		log.Fatalf("Need 1 <= --min-replicas <= --max-replicas, got %d and %d", *minReplicas, *maxReplicas)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	s := &scaler{
+		clientset:      clientset,
+		reader:         reader,
+		namespace:      *namespace,
+		deployment:     *deploymentName,
+		source:         *source,
+		metric:         *metricName,
+		metricSelector: selector,
+		target:         &target,
+		policy:         scalePolicy{minReplicas: int32(*minReplicas), maxReplicas: int32(*maxReplicas), tolerance: *tolerance},
+		apply:          *apply,
+		out:            os.Stdout,
+	}
+	for {
+		if _, err := s.evaluate(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// With --interval a failed evaluation is retried, as the HPA does
+			if *interval == 0 {
+				log.Fatal(err)
+			}
+			log.Printf("Evaluation failed: %v", err)
+		}
+		if *interval == 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*interval):
+		}
+	}
+}
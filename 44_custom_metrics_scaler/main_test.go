@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	custommetricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+	externalmetricsv1beta1 "k8s.io/metrics/pkg/apis/external_metrics/v1beta1"
+	customfake "k8s.io/metrics/pkg/client/custom_metrics/fake"
+	externalfake "k8s.io/metrics/pkg/client/external_metrics/fake"
+)
+
+func quantity(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func TestDecide(t *testing.T) {
+	policy := scalePolicy{minReplicas: 1, maxReplicas: 10, tolerance: 0.1}
+	for _, tc := range []struct {
+		name, source  string
+		value, target string
+		current       int32
+		desired       int32
+		limited       bool
+	}{
+		{"scale up", sourcePods, "150", "100", 4, 6, false},
+		{"scale down rounds up", sourcePods, "30", "100", 5, 2, false},
+		{"within tolerance", sourcePods, "108", "100", 4, 4, false},
+		{"capped at max", sourcePods, "1", "100m", 4, 10, true},
+		{"floored at min", sourceObject, "0", "100", 3, 1, true},
+		// 90 messages, 10 per replica: 9 replicas whatever runs now
+		{"external total", sourceExternal, "90", "10", 3, 9, false},
+		{"scaled to zero by hand", sourcePods, "500", "100", 0, 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := decide(tc.source, reading{value: quantity(tc.value)}, quantity(tc.target), tc.current, policy)
+			if d.desired != tc.desired || d.limited != tc.limited {
+				t.Errorf("desired %d limited %v, want %d %v (ratio %.2f)", d.desired, d.limited, tc.desired, tc.limited, d.ratio)
+			}
+		})
+	}
+}
+
+func deployment(replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+	}
+}
+
+// scaleReactors serves the scale subresource, which the fake object tracker
+// does not know, and records the replicas written
+func scaleReactors(clientset *fake.Clientset, replicas int32, written *int32) {
+	clientset.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		return true, &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: replicas},
+		}, nil
+	})
+	clientset.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		scale := action.(k8stesting.UpdateAction).GetObject().(*autoscalingv1.Scale)
+		*written = scale.Spec.Replicas
+		return true, scale, nil
+	})
+}
+
+func TestEvaluatePodsMetric(t *testing.T) {
+	clientset := fake.NewSimpleClientset(deployment(2))
+	var written int32
+	scaleReactors(clientset, 2, &written)
+	custom := &customfake.FakeCustomMetricsClient{}
+	var podSelector string
+	custom.AddReactor("get", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(customfake.GetForAction)
+		podSelector = get.GetLabelSelector().String()
+		if get.GetName() != "*" || get.GetMetricName() != "http_requests_per_second" {
+			t.Errorf("unexpected request for %s %s", get.GetName(), get.GetMetricName())
+		}
+		return true, &custommetricsv1beta2.MetricValueList{Items: []custommetricsv1beta2.MetricValue{
+			{Value: resource.MustParse("300")},
+			{Value: resource.MustParse("100")},
+		}}, nil
+	})
+	var out bytes.Buffer
+	s := &scaler{
+		clientset: clientset, reader: &metricReader{custom: custom},
+		namespace: "default", deployment: "web",
+		source: sourcePods, metric: "http_requests_per_second", metricSelector: labels.Everything(),
+		target: quantity("100"), policy: scalePolicy{minReplicas: 1, maxReplicas: 10, tolerance: 0.1},
+		apply: true, out: &out,
+	}
+	d, err := s.evaluate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The average is 200 against a target of 100: double the replicas
+	if d.desired != 4 || written != 4 {
+		t.Errorf("desired %d, written %d, want 4", d.desired, written)
+	}
+	// The pods are found through the deployment's selector
+	if podSelector != "app=web" {
+		t.Errorf("pod selector = %q, want app=web", podSelector)
+	}
+	for _, line := range []string{
+		"default/web: pods metric http_requests_per_second = 200 (2 series), target 100, ratio 2.00\n",
+		"  scaled from 2 to 4 replicas\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output missing %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestEvaluateExternalMetricWithoutApply(t *testing.T) {
+	clientset := fake.NewSimpleClientset(deployment(3))
+	external := &externalfake.FakeExternalMetricsClient{}
+	var metricSelector string
+	external.AddReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		metricSelector = action.(k8stesting.ListAction).GetListRestrictions().Labels.String()
+		return true, &externalmetricsv1beta1.ExternalMetricValueList{Items: []externalmetricsv1beta1.ExternalMetricValue{
+			{Value: resource.MustParse("25")},
+			{Value: resource.MustParse("20")},
+		}}, nil
+	})
+	selector, _ := labels.Parse("queue=orders")
+	var out bytes.Buffer
+	s := &scaler{
+		clientset: clientset, reader: &metricReader{external: external},
+		namespace: "default", deployment: "web",
+		source: sourceExternal, metric: "queue_messages_ready", metricSelector: selector,
+		target: quantity("30"), policy: scalePolicy{minReplicas: 1, maxReplicas: 10, tolerance: 0.1},
+		out: &out,
+	}
+	d, err := s.evaluate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 45 messages at 30 per replica need 2 replicas
+	if d.desired != 2 {
+		t.Errorf("desired %d, want 2", d.desired)
+	}
+	if metricSelector != "queue=orders" {
+		t.Errorf("metric selector = %q", metricSelector)
+	}
+	if want := "  would scale from 3 to 2 replicas; pass --apply to scale\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, out.String())
+	}
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "update" {
+			t.Errorf("scaled without --apply: %v", action)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	custommetrics "k8s.io/metrics/pkg/client/custom_metrics"
+	externalmetrics "k8s.io/metrics/pkg/client/external_metrics"
+)
+
+// Metric sources, named like the metric types of an HPA
+const (
+	// sourcePods averages a custom metric over the deployment's pods and
+	// compares the average with the target (an HPA Pods metric)
+	sourcePods = "pods"
+	// sourceObject reads one custom metric describing the Deployment itself
+	// and compares it with the target (an HPA Object metric with a Value target)
+	sourceObject = "object"
+	// sourceExternal sums an external metric, such as a queue length, and
+	// divides it by the target per replica (an HPA External metric with an
+	// AverageValue target)
+	sourceExternal = "external"
+)
+
+var (
+	podGroupKind        = schema.GroupKind{Kind: "Pod"}
+	deploymentGroupKind = schema.GroupKind{Group: "apps", Kind: "Deployment"}
+)
+
+// metricReader fetches one metric through custom.metrics.k8s.io or
+// external.metrics.k8s.io
+type metricReader struct {
+	custom   custommetrics.CustomMetricsClient
+	external externalmetrics.ExternalMetricsClient
+}
+
+// reading is one metric fetched for a deployment
+type reading struct {
+	// value is the average per pod for sourcePods, the object's value for
+	// sourceObject, and the sum over all series for sourceExternal
+	value *resource.Quantity
+	// series is how many values were combined: pods for sourcePods, metric
+	// series for sourceExternal, and 1 for sourceObject
+	series int
+}
+
+// read fetches the metric for deployment. metricSelector narrows the series
+// of the metric by their labels; it is not the pod selector.
+func (r *metricReader) read(source, metric string, metricSelector labels.Selector, deployment *appsv1.Deployment) (reading, error) {
+	switch source {
+	case sourcePods:
+		selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			return reading{}, err
+		}
+		values, err := r.custom.NamespacedMetrics(deployment.Namespace).GetForObjects(podGroupKind, selector, metric, metricSelector)
+		if err != nil {
+			return reading{}, err
+		}
+		if len(values.Items) == 0 {
+			return reading{}, fmt.Errorf("no pod of %s has metric %s", deployment.Name, metric)
+		}
+		var total int64
+		for _, v := range values.Items {
+			total += v.Value.MilliValue()
+		}
+		average := resource.NewMilliQuantity(total/int64(len(values.Items)), resource.DecimalSI)
+		return reading{value: average, series: len(values.Items)}, nil
+	case sourceObject:
+		value, err := r.custom.NamespacedMetrics(deployment.Namespace).GetForObject(deploymentGroupKind, deployment.Name, metric, metricSelector)
+		if err != nil {
+			return reading{}, err
+		}
+		return reading{value: &value.Value, series: 1}, nil
+	case sourceExternal:
+		values, err := r.external.NamespacedMetrics(deployment.Namespace).List(metric, metricSelector)
+		if err != nil {
+			return reading{}, err
+		}
+		if len(values.Items) == 0 {
+			return reading{}, fmt.Errorf("external metric %s has no series", metric)
+		}
+		var total int64
+		for _, v := range values.Items {
+			total += v.Value.MilliValue()
+		}
+		return reading{value: resource.NewMilliQuantity(total, resource.DecimalSI), series: len(values.Items)}, nil
+	}
+	return reading{}, fmt.Errorf("unknown metric source %q", source)
+}
+
+// scalePolicy bounds a scaling decision like the fields of an HPA spec
+type scalePolicy struct {
+	minReplicas, maxReplicas int32
+	// tolerance is how far the usage ratio may be from 1.0 before the
+	// replica count changes; the HPA controller defaults to 0.1
+	tolerance float64
+}
+
+// decision is what the HPA algorithm makes of one reading
+type decision struct {
+	ratio            float64
+	current, desired int32
+	// limited is set when minReplicas or maxReplicas changed desired
+	limited bool
+}
+
+// decide computes the desired replica count the way the HPA controller
+// does: desired = ceil(current * value / target). External metrics with an
+// average target have no current value per replica, so their ratio is
+// total / (target * current). Ratios within the tolerance of 1.0 keep the
+// current count, so small fluctuations do not make the deployment flap.
+func decide(source string, r reading, target *resource.Quantity, current int32, policy scalePolicy) decision {
+	ratio := float64(r.value.MilliValue()) / float64(target.MilliValue())
+	if source == sourceExternal {
+		ratio /= float64(max(current, 1))
+	}
+	d := decision{ratio: ratio, current: current, desired: current}
+	// Like the HPA, leave a deployment scaled to zero by hand alone
+	if current == 0 {
+		return d
+	}
+	if math.Abs(ratio-1) > policy.tolerance {
+		d.desired = int32(math.Ceil(ratio * float64(current)))
+	}
+	bounded := min(max(d.desired, policy.minReplicas), policy.maxReplicas)
+	d.limited = bounded != d.desired
+	d.desired = bounded
+	return d
+}
+
+// scaler evaluates one deployment against one metric target
+type scaler struct {
+	clientset      kubernetes.Interface
+	reader         *metricReader
+	namespace      string
+	deployment     string
+	source, metric string
+	metricSelector labels.Selector
+	target         *resource.Quantity
+	policy         scalePolicy
+	// apply scales the deployment through its scale subresource; otherwise
+	// the decision is only printed
+	apply bool
+	out   io.Writer
+}
+
+// evaluate reads the deployment and the metric, prints the decision and,
+// with apply, carries it out
+func (s *scaler) evaluate(ctx context.Context) (decision, error) {
+	deployment, err := s.clientset.AppsV1().Deployments(s.namespace).Get(ctx, s.deployment, metav1.GetOptions{})
+	if err != nil {
+		return decision{}, err
+	}
+	r, err := s.reader.read(s.source, s.metric, s.metricSelector, deployment)
+	if err != nil {
+		return decision{}, fmt.Errorf("reading %s metric %s: %w", s.source, s.metric, err)
+	}
+	current := int32(1)
+	if deployment.Spec.Replicas != nil {
+		current = *deployment.Spec.Replicas
+	}
+	d := decide(s.source, r, s.target, current, s.policy)
+
+	fmt.Fprintf(s.out, "%s/%s: %s metric %s = %s (%d series), target %s, ratio %.2f\n",
+		s.namespace, s.deployment, s.source, s.metric, r.value, r.series, s.target, d.ratio)
+	switch {
+	case d.current == 0:
+		fmt.Fprintln(s.out, "  scaled to zero, autoscaling is off")
+		return d, nil
+	case d.desired == d.current && !d.limited:
+		fmt.Fprintf(s.out, "  keep %d replicas (within tolerance %.2f)\n", d.current, s.policy.tolerance)
+		return d, nil
+	case d.desired == d.current:
+		fmt.Fprintf(s.out, "  keep %d replicas (limited by min %d, max %d)\n", d.current, s.policy.minReplicas, s.policy.maxReplicas)
+		return d, nil
+	}
+	limited := ""
+	if d.limited {
+		limited = fmt.Sprintf(" (limited by min %d, max %d)", s.policy.minReplicas, s.policy.maxReplicas)
+	}
+	if !s.apply {
+		fmt.Fprintf(s.out, "  would scale from %d to %d replicas%s; pass --apply to scale\n", d.current, d.desired, limited)
+		return d, nil
+	}
+	// The scale subresource only touches spec.replicas, and its
+	// resourceVersion makes the update fail if someone else scaled meanwhile
+	scale, err := s.clientset.AppsV1().Deployments(s.namespace).GetScale(ctx, s.deployment, metav1.GetOptions{})
+	if err != nil {
+		return d, err
+	}
+	scale.Spec.Replicas = d.desired
+	if _, err := s.clientset.AppsV1().Deployments(s.namespace).UpdateScale(ctx, s.deployment, scale, metav1.UpdateOptions{}); err != nil {
+		return d, err
+	}
+	fmt.Fprintf(s.out, "  scaled from %d to %d replicas%s\n", d.current, d.desired, limited)
+	return d, nil
+}
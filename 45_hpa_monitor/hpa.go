@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// newHPA returns an autoscaling/v2 HPA that keeps the average CPU usage of
+// the deployment's pods at cpuUtilization percent of their CPU requests
+func newHPA(namespace, name, deployment string, minReplicas, maxReplicas, cpuUtilization int32) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       deployment,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: maxReplicas,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{
+					Name: corev1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{
+						Type:               autoscalingv2.UtilizationMetricType,
+						AverageUtilization: &cpuUtilization,
+					},
+				},
+			}},
+		},
+	}
+}
+
+// ensureHPA creates hpa, or replaces the spec of an existing HPA of the same
+// name. The status is left to the HPA controller.
+func ensureHPA(ctx context.Context, clientset kubernetes.Interface, hpa *autoscalingv2.HorizontalPodAutoscaler) (string, error) {
+	client := clientset.AutoscalingV2().HorizontalPodAutoscalers(hpa.Namespace)
+	existing, err := client.Get(ctx, hpa.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := client.Create(ctx, hpa, metav1.CreateOptions{}); err != nil {
+			return "", err
+		}
+		return "created", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	existing.Spec = hpa.Spec
+	if _, err := client.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return "", err
+	}
+	return "updated", nil
+}
+
+// containersWithoutCPURequest lists the containers of deployment that do
+// not request CPU. The HPA computes utilization as usage / request, so with
+// any of them it cannot compute a value: ScalingActive turns False with
+// reason FailedGetResourceMetric, and the deployment is never scaled. The
+// nginx deployment from 02_deployment_using_client_go requests nothing.
+func containersWithoutCPURequest(deployment *appsv1.Deployment) []string {
+	var names []string
+	for _, c := range deployment.Spec.Template.Spec.Containers {
+		if _, ok := c.Resources.Requests[corev1.ResourceCPU]; !ok {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// checkTarget fetches the deployment the HPA scales and warns about what
+// will keep it from working
+func checkTarget(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error {
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("deployment %s/%s not found; create it with 02_deployment_using_client_go", namespace, name)
+	}
+	if err != nil {
+		return err
+	}
+	if missing := containersWithoutCPURequest(deployment); len(missing) > 0 {
+		fmt.Printf("WARNING: containers %v of %s request no CPU, so the HPA cannot compute CPU utilization.\n", missing, name)
+		fmt.Printf("  Fix it with: kubectl -n %s set resources deployment %s --requests=cpu=100m\n", namespace, name)
+	}
+	return nil
+}
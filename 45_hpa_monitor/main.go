@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace      = flag.String("namespace", "default", "namespace of the deployment and the HPA")
+	deploymentName = flag.String("deployment", "nginx-deployment", "deployment to scale (02_deployment_using_client_go creates nginx-deployment)")
+	hpaName        = flag.String("name", "nginx-deployment", "name of the HPA")
+	minReplicas    = flag.Int("min", 1, "minimum replicas")
+	maxReplicas    = flag.Int("max", 5, "maximum replicas")
+	cpuUtilization = flag.Int("cpu-utilization", 50, "target average CPU utilization, in percent of the CPU requests")
+	cleanup        = flag.Bool("cleanup", false, "delete the HPA on exit")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "45-hpa-monitor")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// startMonitor registers the HPA, deployment and event handlers and starts
+// the informers. The events come from a factory of their own, because only
+// that factory's list options select the HPA's events.
+func startMonitor(clientset kubernetes.Interface, ns, hpa, deployment string, stopCh <-chan struct{}) []informers.SharedInformerFactory {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(ns))
+	factory.Autoscaling().V2().HorizontalPodAutoscalers().Informer().AddEventHandler(named(hpa, hpaHandler()))
+	factory.Apps().V1().Deployments().Informer().AddEventHandler(named(deployment, deploymentHandler()))
+
+	eventFactory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(ns),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.Set{
+				"involvedObject.kind": "HorizontalPodAutoscaler",
+				"involvedObject.name": hpa,
+			}.AsSelector().String()
+		}))
+	eventFactory.Core().V1().Events().Informer().AddEventHandler(eventHandler())
+
+	factories := []informers.SharedInformerFactory{factory, eventFactory}
+	for _, f := range factories {
+		f.Start(stopCh)
+	}
+	return factories
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := checkTarget(ctx, clientset, *namespace, *deploymentName); err != nil {
+		log.Fatal(err)
+	}
+	hpa := newHPA(*namespace, *hpaName, *deploymentName, int32(*minReplicas), int32(*maxReplicas), int32(*cpuUtilization))
+	result, err := ensureHPA(ctx, clientset, hpa)
+	if err != nil {
+		log.Fatalf("Failed to create HPA: %v", err)
+	}
+	fmt.Printf("HPA %s/%s %s: %d-%d replicas of %s at %d%% CPU\n", *namespace, *hpaName, result,
+		*minReplicas, *maxReplicas, *deploymentName, *cpuUtilization)
+
+	factories := startMonitor(clientset, *namespace, *hpaName, *deploymentName, ctx.Done())
+	for _, f := range factories {
+		f.WaitForCacheSync(ctx.Done())
+	}
+	fmt.Println("Watching the HPA, its events and the deployment; press Ctrl-C to exit")
+	<-ctx.Done()
+	for _, f := range factories {
+		f.Shutdown()
+	}
+
+	if *cleanup {
+		// ctx is cancelled by now
+		err := clientset.AutoscalingV2().HorizontalPodAutoscalers(*namespace).Delete(context.Background(), *hpaName, metav1.DeleteOptions{})
+		if err != nil {
+			log.Fatalf("Failed to delete HPA: %v", err)
+		}
+		fmt.Printf("Deleted HPA %s/%s\n", *namespace, *hpaName)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+// nginx is the deployment 02_deployment_using_client_go creates
+func nginx(replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx-deployment", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "nginx-app", Image: "nginx:1.21"}},
+			}},
+		},
+	}
+}
+
+func TestEnsureHPA(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx := context.Background()
+	if result, err := ensureHPA(ctx, clientset, newHPA("default", "nginx-deployment", "nginx-deployment", 1, 5, 50)); err != nil || result != "created" {
+		t.Fatalf("first ensure: %s, %v", result, err)
+	}
+	if result, err := ensureHPA(ctx, clientset, newHPA("default", "nginx-deployment", "nginx-deployment", 2, 8, 70)); err != nil || result != "updated" {
+		t.Fatalf("second ensure: %s, %v", result, err)
+	}
+	hpa, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("default").Get(ctx, "nginx-deployment", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *hpa.Spec.MinReplicas != 2 || hpa.Spec.MaxReplicas != 8 || *hpa.Spec.Metrics[0].Resource.Target.AverageUtilization != 70 {
+		t.Errorf("spec not replaced: %+v", hpa.Spec)
+	}
+	if ref := hpa.Spec.ScaleTargetRef; ref.Kind != "Deployment" || ref.Name != "nginx-deployment" || ref.APIVersion != "apps/v1" {
+		t.Errorf("scale target = %+v", ref)
+	}
+}
+
+func TestContainersWithoutCPURequest(t *testing.T) {
+	d := nginx(3)
+	d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, corev1.Container{
+		Name:      "sidecar",
+		Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")}},
+	})
+	if got := containersWithoutCPURequest(d); !slices.Equal(got, []string{"nginx-app"}) {
+		t.Errorf("missing requests = %v, want [nginx-app]", got)
+	}
+}
+
+func condition(typ autoscalingv2.HorizontalPodAutoscalerConditionType, status corev1.ConditionStatus, reason, message string) autoscalingv2.HorizontalPodAutoscalerCondition {
+	return autoscalingv2.HorizontalPodAutoscalerCondition{Type: typ, Status: status, Reason: reason, Message: message}
+}
+
+func TestConditionChanges(t *testing.T) {
+	old := []autoscalingv2.HorizontalPodAutoscalerCondition{
+		condition(autoscalingv2.AbleToScale, corev1.ConditionTrue, "SucceededGetScale", "the HPA controller was able to get the target's current scale"),
+		condition(autoscalingv2.ScalingActive, corev1.ConditionFalse, "FailedGetResourceMetric", "missing request for cpu"),
+	}
+	updated := []autoscalingv2.HorizontalPodAutoscalerCondition{
+		// Only the message changed
+		condition(autoscalingv2.AbleToScale, corev1.ConditionTrue, "SucceededGetScale", "recommended size matches current size"),
+		condition(autoscalingv2.ScalingActive, corev1.ConditionTrue, "ValidMetricFound", "computed from cpu resource utilization"),
+		condition(autoscalingv2.ScalingLimited, corev1.ConditionFalse, "DesiredWithinRange", "the desired count is within the acceptable range"),
+	}
+	want := []string{
+		"ScalingActive=False (FailedGetResourceMetric) -> ScalingActive=True (ValidMetricFound): computed from cpu resource utilization",
+		"ScalingLimited=False (DesiredWithinRange): the desired count is within the acceptable range",
+	}
+	if got := conditionChanges(old, updated); !slices.Equal(got, want) {
+		t.Errorf("changes:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestMonitorFollowsScaling(t *testing.T) {
+	hpa := newHPA("default", "nginx-deployment", "nginx-deployment", 1, 5, 50)
+	h := testutil.NewHarness(t, nginx(3), hpa)
+	output := testutil.CaptureOutput(t)
+	stopCh := make(chan struct{})
+	factories := startMonitor(h.Clientset, "default", "nginx-deployment", "nginx-deployment", stopCh)
+	t.Cleanup(func() {
+		close(stopCh)
+		for _, f := range factories {
+			f.Shutdown()
+		}
+	})
+	for _, f := range factories {
+		f.WaitForCacheSync(stopCh)
+	}
+	testutil.WaitForOutput(t, output,
+		"[HPA] nginx-deployment: replicas 0, desired 0, cpu unknown of target 50%\n",
+		"[Deployment] nginx-deployment: replicas 3, ready 0\n",
+	)
+	h.WaitForWatchOf(hpa, 1)
+	h.WaitForWatchOf(nginx(3), 1)
+	h.WaitForWatchOf(&corev1.Event{}, 1)
+
+	// The HPA controller measures 120% against 50% and raises the count
+	utilization := int32(120)
+	scaled := hpa.DeepCopy()
+	scaled.Status = autoscalingv2.HorizontalPodAutoscalerStatus{
+		CurrentReplicas: 3,
+		DesiredReplicas: 5,
+		CurrentMetrics: []autoscalingv2.MetricStatus{{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricStatus{
+				Name:    corev1.ResourceCPU,
+				Current: autoscalingv2.MetricValueStatus{AverageUtilization: &utilization},
+			},
+		}},
+		Conditions: []autoscalingv2.HorizontalPodAutoscalerCondition{
+			condition(autoscalingv2.ScalingLimited, corev1.ConditionTrue, "TooManyReplicas", "the desired replica count is more than the maximum replica count"),
+		},
+	}
+	h.Update(scaled)
+	h.Add(&corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "nginx-deployment.1", Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "HorizontalPodAutoscaler", Name: "nginx-deployment"},
+		Type:           corev1.EventTypeNormal,
+		Reason:         "SuccessfulRescale",
+		Message:        "New size: 5; reason: cpu resource utilization (percentage of request) above target",
+		Count:          1,
+	})
+	// Another deployment in the namespace is ignored. It is added before the
+	// update, so it has been handled once the update shows up.
+	other := nginx(1)
+	other.Name = "other"
+	h.Add(other)
+	h.Update(nginx(5))
+
+	testutil.WaitForOutput(t, output,
+		"[HPA] nginx-deployment: replicas 3, desired 5, cpu 120% of target 50%\n",
+		"[HPA] nginx-deployment: condition ScalingLimited=True (TooManyReplicas): the desired replica count is more than the maximum replica count\n",
+		"[Event] Normal SuccessfulRescale: New size: 5; reason: cpu resource utilization (percentage of request) above target\n",
+		"[Deployment] nginx-deployment: spec.replicas 3 -> 5\n",
+	)
+	if got := output(); strings.Contains(got, "[Deployment] other") {
+		t.Errorf("unrelated deployment reported:\n%s", got)
+	}
+}
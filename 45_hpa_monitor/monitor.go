@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/tombstone"
+)
+
+// hpaHandler prints what the HPA controller writes into the HPA's status:
+// the replica counts, the measured utilization and the conditions
+func hpaHandler() cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			hpa := obj.(*autoscalingv2.HorizontalPodAutoscaler)
+			fmt.Printf("[HPA] %s: %s\n", hpa.Name, describeStatus(hpa))
+			for _, c := range hpa.Status.Conditions {
+				fmt.Printf("[HPA] %s: condition %s\n", hpa.Name, describeCondition(c))
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old := oldObj.(*autoscalingv2.HorizontalPodAutoscaler)
+			hpa := newObj.(*autoscalingv2.HorizontalPodAutoscaler)
+			if describeStatus(old) != describeStatus(hpa) {
+				fmt.Printf("[HPA] %s: %s\n", hpa.Name, describeStatus(hpa))
+			}
+			for _, change := range conditionChanges(old.Status.Conditions, hpa.Status.Conditions) {
+				fmt.Printf("[HPA] %s: condition %s\n", hpa.Name, change)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if hpa, ok := tombstone.Unwrap[*autoscalingv2.HorizontalPodAutoscaler](obj); ok {
+				fmt.Printf("[HPA] %s: deleted, the deployment keeps its current replicas\n", hpa.Name)
+			}
+		},
+	}
+}
+
+// describeStatus summarizes the replica counts and the CPU utilization
+func describeStatus(hpa *autoscalingv2.HorizontalPodAutoscaler) string {
+	utilization := "unknown"
+	for _, m := range hpa.Status.CurrentMetrics {
+		if m.Type == autoscalingv2.ResourceMetricSourceType && m.Resource != nil &&
+			m.Resource.Name == corev1.ResourceCPU && m.Resource.Current.AverageUtilization != nil {
+			utilization = fmt.Sprintf("%d%%", *m.Resource.Current.AverageUtilization)
+		}
+	}
+	target := "?"
+	for _, m := range hpa.Spec.Metrics {
+		if m.Resource != nil && m.Resource.Name == corev1.ResourceCPU && m.Resource.Target.AverageUtilization != nil {
+			target = fmt.Sprintf("%d%%", *m.Resource.Target.AverageUtilization)
+		}
+	}
+	return fmt.Sprintf("replicas %d, desired %d, cpu %s of target %s",
+		hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas, utilization, target)
+}
+
+func describeCondition(c autoscalingv2.HorizontalPodAutoscalerCondition) string {
+	return fmt.Sprintf("%s=%s (%s): %s", c.Type, c.Status, c.Reason, c.Message)
+}
+
+// conditionChanges describes the conditions that were added, or whose
+// status or reason changed. The message alone changes on every sync, since
+// it often contains the measured value, so it is not compared.
+func conditionChanges(old, updated []autoscalingv2.HorizontalPodAutoscalerCondition) []string {
+	previous := make(map[autoscalingv2.HorizontalPodAutoscalerConditionType]autoscalingv2.HorizontalPodAutoscalerCondition, len(old))
+	for _, c := range old {
+		previous[c.Type] = c
+	}
+	var changes []string
+	for _, c := range updated {
+		before, ok := previous[c.Type]
+		switch {
+		case !ok:
+			changes = append(changes, describeCondition(c))
+		case before.Status != c.Status || before.Reason != c.Reason:
+			changes = append(changes, fmt.Sprintf("%s=%s (%s) -> %s", c.Type, before.Status, before.Reason, describeCondition(c)))
+		}
+	}
+	return changes
+}
+
+// eventHandler prints the events the HPA controller records for the HPA,
+// such as SuccessfulRescale or FailedGetResourceMetric. A repeated event is
+// an update of the same Event with a higher count.
+func eventHandler() cache.ResourceEventHandlerFuncs {
+	printEvent := func(event *corev1.Event) {
+		count := ""
+		if event.Count > 1 {
+			count = fmt.Sprintf(" (x%d)", event.Count)
+		}
+		fmt.Printf("[Event] %s %s%s: %s\n", event.Type, event.Reason, count, strings.TrimSpace(event.Message))
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { printEvent(obj.(*corev1.Event)) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if oldObj.(*corev1.Event).Count != newObj.(*corev1.Event).Count {
+				printEvent(newObj.(*corev1.Event))
+			}
+		},
+	}
+}
+
+// deploymentHandler shows the other side: the HPA controller changes
+// spec.replicas through the scale subresource, and the deployment
+// controller then rolls the new count out
+func deploymentHandler() cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			d := obj.(*appsv1.Deployment)
+			fmt.Printf("[Deployment] %s: replicas %d, ready %d\n", d.Name, replicas(d), d.Status.ReadyReplicas)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old, d := oldObj.(*appsv1.Deployment), newObj.(*appsv1.Deployment)
+			if replicas(old) != replicas(d) {
+				fmt.Printf("[Deployment] %s: spec.replicas %d -> %d\n", d.Name, replicas(old), replicas(d))
+			}
+			if old.Status.ReadyReplicas != d.Status.ReadyReplicas {
+				fmt.Printf("[Deployment] %s: ready %d/%d\n", d.Name, d.Status.ReadyReplicas, replicas(d))
+			}
+		},
+	}
+}
+
+func replicas(d *appsv1.Deployment) int32 {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+// named only passes events for the object called name to handler
+func named(name string, handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			if deleted, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = deleted.Obj
+			}
+			accessor, err := meta.Accessor(obj)
+			return err == nil && accessor.GetName() == name
+		},
+		Handler: handler,
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// jobNameLabel is set by the Job controller on every pod of a Job
+const jobNameLabel = batchv1.JobNameLabel
+
+// newJob returns a Job running command in a shell, once, without retries
+// unless backoffLimit allows them. Finished Jobs and their pods are removed
+// ttlSeconds after they finish.
+func newJob(namespace, generateName, image, command string, backoffLimit, ttlSeconds int32) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: generateName, Namespace: namespace},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttlSeconds,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					// A Job's pods must not restart in place; failed pods are
+					// replaced by the Job controller, up to backoffLimit
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "main",
+						Image:   image,
+						Command: []string{"sh", "-c", command},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// finishedCondition returns the Complete or Failed condition of job, or nil
+// while it is still running. Newer Job controllers first add SuccessCriteriaMet
+// or FailureTarget, and only add Complete or Failed once every pod has
+// terminated, so the pods' logs are final by then.
+func finishedCondition(job *batchv1.Job) *batchv1.JobCondition {
+	for i, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return &job.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// waitForJob uses an informer scoped to the single Job and returns it once it
+// has completed or failed. Pod counts are printed as they change.
+func waitForJob(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*batchv1.Job, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(lo *metav1.ListOptions) {
+			lo.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	jobInformer := factory.Batch().V1().Jobs().Informer()
+
+	finished := make(chan *batchv1.Job, 1)
+	lastStatus := ""
+	check := func(obj interface{}) {
+		job := obj.(*batchv1.Job)
+		status := fmt.Sprintf("active %d, succeeded %d, failed %d", job.Status.Active, job.Status.Succeeded, job.Status.Failed)
+		// Handlers of one informer are called one at a time, so lastStatus
+		// needs no lock
+		if status != lastStatus {
+			fmt.Printf("Job %s: %s\n", job.Name, status)
+			lastStatus = status
+		}
+		if finishedCondition(job) != nil {
+			select {
+			case finished <- job:
+			default:
+			}
+		}
+	}
+	jobInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    check,
+		UpdateFunc: func(oldObj, newObj interface{}) { check(newObj) },
+		DeleteFunc: func(obj interface{}) {
+			fmt.Printf("Job %s was deleted before it finished\n", name)
+		},
+	})
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer factory.Shutdown()
+	defer cancel()
+	factory.Start(ctx.Done())
+
+	select {
+	case job := <-finished:
+		return job, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("stopped waiting for job %s: %w", name, context.Cause(ctx))
+	}
+}
+
+// printPodLogs writes the logs of every container of every pod of the Job to
+// w. A Job that retried has one pod per attempt, and each attempt's logs are
+// printed.
+func printPodLogs(ctx context.Context, clientset kubernetes.Interface, w io.Writer, namespace, name string) error {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: jobNameLabel + "=" + name,
+	})
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) == 0 {
+		fmt.Fprintf(w, "No pods left for job %s\n", name)
+		return nil
+	}
+	for _, pod := range pods.Items {
+		for _, c := range pod.Spec.Containers {
+			fmt.Fprintf(w, "----- %s/%s (%s) -----\n", pod.Name, c.Name, describeTermination(pod, c.Name))
+			stream, err := clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: c.Name}).Stream(ctx)
+			if err != nil {
+				fmt.Fprintf(w, "cannot read logs: %v\n", err)
+				continue
+			}
+			_, err = io.Copy(w, stream)
+			stream.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// describeTermination says how a container ended, e.g. "exit code 1, Error"
+func describeTermination(pod corev1.Pod, container string) string {
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.Name != container {
+			continue
+		}
+		if t := s.State.Terminated; t != nil {
+			return fmt.Sprintf("exit code %d, %s", t.ExitCode, t.Reason)
+		}
+		if w := s.State.Waiting; w != nil {
+			return "waiting: " + w.Reason
+		}
+	}
+	return string(pod.Status.Phase)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace    = flag.String("namespace", "default", "namespace the Job runs in")
+	generateName = flag.String("generate-name", "job-runner-", "prefix of the Job name; the API server appends a random suffix")
+	image        = flag.String("image", "busybox:1.36", "container image")
+	command      = flag.String("command", "echo started; sleep 5; echo done", "shell command the container runs")
+	backoffLimit = flag.Int("backoff-limit", 0, "retries before the Job is marked Failed")
+	ttl          = flag.Int("ttl", 300, "seconds after finishing until the Job and its pods are deleted")
+	timeout      = flag.Duration("timeout", 5*time.Minute, "give up waiting after this long (the Job keeps running)")
+	logs         = flag.String("logs", "failure", "print the pod logs on failure, always or never")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "46-job-runner")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// runJob creates the Job, waits for it to finish and prints the logs
+// according to --logs. It returns whether the Job completed.
+func runJob(ctx context.Context, clientset kubernetes.Interface, job *batchv1.Job) (bool, error) {
+	created, err := clientset.BatchV1().Jobs(job.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("creating job: %w", err)
+	}
+	fmt.Printf("Created job %s/%s\n", created.Namespace, created.Name)
+
+	waitCtx, cancel := context.WithTimeoutCause(ctx, *timeout, fmt.Errorf("timed out after %v", *timeout))
+	defer cancel()
+	finished, err := waitForJob(waitCtx, clientset, created.Namespace, created.Name)
+	if err != nil {
+		return false, err
+	}
+	condition := finishedCondition(finished)
+	succeeded := condition.Type == batchv1.JobComplete
+	if succeeded {
+		elapsed := ""
+		if finished.Status.StartTime != nil && finished.Status.CompletionTime != nil {
+			elapsed = " in " + finished.Status.CompletionTime.Sub(finished.Status.StartTime.Time).String()
+		}
+		fmt.Printf("Job %s completed%s\n", finished.Name, elapsed)
+	} else {
+		fmt.Printf("Job %s failed: %s: %s\n", finished.Name, condition.Reason, condition.Message)
+	}
+
+	if *logs == "always" || (*logs == "failure" && !succeeded) {
+		if err := printPodLogs(ctx, clientset, os.Stdout, finished.Namespace, finished.Name); err != nil {
+			return succeeded, fmt.Errorf("reading logs: %w", err)
+		}
+	}
+	return succeeded, nil
+}
+
+func main() {
+	clientset := createClientSet()
+	if *logs != "failure" && *logs != "always" && *logs != "never" {
+		log.Fatalf("--logs must be failure, always or never, not %q", *logs)
+	}
+	// Ctrl-C or SIGTERM stops the waiting; the Job itself keeps running
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	job := newJob(*namespace, *generateName, *image, *command, int32(*backoffLimit), int32(*ttl))
+	succeeded, err := runJob(ctx, clientset, job)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !succeeded {
+		os.Exit(1)
+	}
+}
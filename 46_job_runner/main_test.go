@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+// newHarness returns a harness whose API server names Jobs from their
+// generateName, which the fake object tracker does not do
+func newHarness(t *testing.T, objects ...runtime.Object) *testutil.Harness {
+	h := testutil.NewHarness(t, objects...)
+	h.Clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		job.Name = job.GenerateName + "x7k2p"
+		return false, nil, nil
+	})
+	return h
+}
+
+func finished(job *batchv1.Job, typ batchv1.JobConditionType, reason, message string) *batchv1.Job {
+	job = job.DeepCopy()
+	job.Name = job.GenerateName + "x7k2p"
+	job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{
+		Type: typ, Status: corev1.ConditionTrue, Reason: reason, Message: message,
+	})
+	return job
+}
+
+func failedPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "job-runner-x7k2p-4xz9q", Namespace: "default",
+			Labels: map[string]string{jobNameLabel: "job-runner-x7k2p"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "main"}}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "main",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 3, Reason: "Error"}},
+			}},
+		},
+	}
+}
+
+func TestNewJob(t *testing.T) {
+	job := newJob("default", "job-runner-", "busybox:1.36", "exit 3", 2, 60)
+	if job.Spec.Template.Spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("restart policy = %s, want Never", job.Spec.Template.Spec.RestartPolicy)
+	}
+	if *job.Spec.BackoffLimit != 2 || *job.Spec.TTLSecondsAfterFinished != 60 {
+		t.Errorf("backoffLimit %d, ttl %d", *job.Spec.BackoffLimit, *job.Spec.TTLSecondsAfterFinished)
+	}
+	if cmd := job.Spec.Template.Spec.Containers[0].Command; len(cmd) != 3 || cmd[2] != "exit 3" {
+		t.Errorf("command = %q", cmd)
+	}
+}
+
+func TestFinishedCondition(t *testing.T) {
+	job := newJob("default", "job-runner-", "busybox", "true", 0, 60)
+	// The early condition of newer Job controllers is not the end yet
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailureTarget, Status: corev1.ConditionTrue}}
+	if c := finishedCondition(job); c != nil {
+		t.Errorf("FailureTarget treated as finished: %v", c)
+	}
+	job = finished(job, batchv1.JobFailed, "BackoffLimitExceeded", "Job has reached the specified backoff limit")
+	if c := finishedCondition(job); c == nil || c.Type != batchv1.JobFailed {
+		t.Errorf("finished condition = %v, want Failed", c)
+	}
+}
+
+// run starts runJob and returns a function waiting for whether the job succeeded
+func run(t *testing.T, h *testutil.Harness, job *batchv1.Job, output func() string) func() bool {
+	type result struct {
+		succeeded bool
+		err       error
+	}
+	done := make(chan result, 1)
+	go func() {
+		succeeded, err := runJob(context.Background(), h.Clientset, job)
+		done <- result{succeeded, err}
+	}()
+	return func() bool {
+		t.Helper()
+		select {
+		case r := <-done:
+			if r.err != nil {
+				t.Fatalf("runJob: %v", r.err)
+			}
+			return r.succeeded
+		case <-time.After(testutil.Timeout):
+			t.Fatalf("runJob did not return:\n%s", output())
+		}
+		return false
+	}
+}
+
+func TestRunJobCompletes(t *testing.T) {
+	h := newHarness(t)
+	output := testutil.CaptureOutput(t)
+	job := newJob("default", "job-runner-", "busybox", "true", 0, 60)
+	wait := run(t, h, job, output)
+
+	h.WaitForWatchOf(&batchv1.Job{}, 1)
+	running := finished(job, batchv1.JobComplete, "", "")
+	running.Status.Conditions = nil
+	running.Status.Active = 1
+	h.Update(running)
+	complete := finished(job, batchv1.JobComplete, "CompletionsReached", "Reached expected number of succeeded pods")
+	complete.Status.Succeeded = 1
+	start := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	end := metav1.NewTime(start.Add(6 * time.Second))
+	complete.Status.StartTime, complete.Status.CompletionTime = &start, &end
+	h.Update(complete)
+
+	if !wait() {
+		t.Fatalf("job reported as failed:\n%s", output())
+	}
+	testutil.WaitForOutput(t, output,
+		"Created job default/job-runner-x7k2p\n",
+		"Job job-runner-x7k2p: active 1, succeeded 0, failed 0\n",
+		"Job job-runner-x7k2p completed in 6s\n",
+	)
+}
+
+func TestRunJobFailsWithLogs(t *testing.T) {
+	h := newHarness(t, failedPod())
+	output := testutil.CaptureOutput(t)
+	job := newJob("default", "job-runner-", "busybox", "exit 3", 0, 60)
+	wait := run(t, h, job, output)
+
+	h.WaitForWatchOf(&batchv1.Job{}, 1)
+	failed := finished(job, batchv1.JobFailed, "BackoffLimitExceeded", "Job has reached the specified backoff limit")
+	failed.Status.Failed = 1
+	h.Update(failed)
+
+	if wait() {
+		t.Fatalf("failed job reported as complete:\n%s", output())
+	}
+	// The fake clientset answers every log request with "fake logs"
+	testutil.WaitForOutput(t, output,
+		"Job job-runner-x7k2p failed: BackoffLimitExceeded: Job has reached the specified backoff limit\n",
+		"----- job-runner-x7k2p-4xz9q/main (exit code 3, Error) -----\nfake logs",
+	)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+// fieldManager names this example as the writer of the CronJobs and Jobs
+// it creates, and of the fields its suspend and resume patches set
+var fieldManager = clientid.FieldManager("47-cronjob-manager")
+
+// ownerUIDIndex indexes Jobs by the UID of their controlling owner, which is
+// the CronJob for every Job a CronJob spawned
+const ownerUIDIndex = "ownerUID"
+
+// instantiateAnnotation marks a Job created by hand from a CronJob, as
+// kubectl create job --from=cronjob/<name> does
+const instantiateAnnotation = "cronjob.kubernetes.io/instantiate"
+
+// newCronJob returns a CronJob that runs command in a shell on schedule.
+// Runs never overlap, and the last three successful and failed Jobs are kept.
+func newCronJob(namespace, name, schedule, image, command string) *batchv1.CronJob {
+	successful, failed := int32(3), int32(3)
+	backoffLimit := int32(0)
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   schedule,
+			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &successful,
+			FailedJobsHistoryLimit:     &failed,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							RestartPolicy: corev1.RestartPolicyNever,
+							Containers: []corev1.Container{{
+								Name:    "main",
+								Image:   image,
+								Command: []string{"sh", "-c", command},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// setSuspend pauses or resumes the schedule with a merge patch of
+// spec.suspend. Jobs that are already running are not affected.
+func setSuspend(ctx context.Context, clientset kubernetes.Interface, namespace, name string, suspend bool) (*batchv1.CronJob, error) {
+	patch := fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend)
+	return clientset.BatchV1().CronJobs(namespace).Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{FieldManager: fieldManager})
+}
+
+// jobFromCronJob builds a Job from the CronJob's jobTemplate, like kubectl
+// create job --from=cronjob/<name>. The controller reference makes the Job
+// part of the CronJob's history and deletes it together with the CronJob.
+func jobFromCronJob(cronJob *batchv1.CronJob) *batchv1.Job {
+	annotations := map[string]string{instantiateAnnotation: "manual"}
+	for k, v := range cronJob.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+	labels := make(map[string]string, len(cronJob.Spec.JobTemplate.Labels))
+	for k, v := range cronJob.Spec.JobTemplate.Labels {
+		labels[k] = v
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName:    cronJob.Name + "-manual-",
+			Namespace:       cronJob.Namespace,
+			Labels:          labels,
+			Annotations:     annotations,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind("CronJob"))},
+		},
+		Spec: *cronJob.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+}
+
+// trigger starts one run of the CronJob now, regardless of its schedule and
+// of spec.suspend
+func trigger(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*batchv1.Job, error) {
+	cronJob, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return clientset.BatchV1().Jobs(namespace).Create(ctx, jobFromCronJob(cronJob), metav1.CreateOptions{FieldManager: fieldManager})
+}
+
+// jobOwnerUIDIndexFunc indexes a Job under the UID of its controller. Jobs
+// created directly have none and are not in the index.
+func jobOwnerUIDIndexFunc(obj interface{}) ([]string, error) {
+	job := obj.(*batchv1.Job)
+	if owner := metav1.GetControllerOf(job); owner != nil {
+		return []string{string(owner.UID)}, nil
+	}
+	return nil, nil
+}
+
+// jobsOf returns the cached Jobs the CronJob controls, oldest first. The
+// lookup goes through ownerUIDIndex rather than a label selector: the labels
+// of the jobTemplate can be changed or shared with other Jobs, the controller
+// reference cannot.
+func jobsOf(indexer cache.Indexer, cronJob *batchv1.CronJob) ([]*batchv1.Job, error) {
+	objs, err := indexer.ByIndex(ownerUIDIndex, string(cronJob.UID))
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*batchv1.Job, 0, len(objs))
+	for _, obj := range objs {
+		jobs = append(jobs, obj.(*batchv1.Job))
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreationTimestamp.Equal(&jobs[j].CreationTimestamp) {
+			return jobs[i].CreationTimestamp.Before(&jobs[j].CreationTimestamp)
+		}
+		return jobs[i].Name < jobs[j].Name
+	})
+	return jobs, nil
+}
+
+// jobStatus summarizes a Job as Complete, Failed or Running
+func jobStatus(job *batchv1.Job) string {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return string(c.Type)
+		}
+	}
+	return "Running"
+}
+
+// jobOrigin says whether the schedule or a manual trigger created the Job
+func jobOrigin(job *batchv1.Job) string {
+	if job.Annotations[instantiateAnnotation] == "manual" {
+		return "manual"
+	}
+	return "scheduled"
+}
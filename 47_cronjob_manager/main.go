@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace = flag.String("namespace", "default", "namespace of the CronJob")
+	name      = flag.String("name", "hello-cron", "name of the CronJob")
+	schedule  = flag.String("schedule", "*/5 * * * *", "cron schedule used by create")
+	image     = flag.String("image", "busybox:1.36", "container image used by create")
+	command   = flag.String("command", "date; echo hello from the cronjob", "shell command used by create")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "47-cronjob-manager")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// listJobs prints the CronJob and the Jobs it spawned. Both are read from
+// informer caches, and the Jobs are found through ownerUIDIndex.
+func listJobs(ctx context.Context, clientset kubernetes.Interface, w io.Writer, namespace, name string) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	cronJobLister := factory.Batch().V1().CronJobs().Lister()
+	jobInformer := factory.Batch().V1().Jobs().Informer()
+	if err := jobInformer.AddIndexers(cache.Indexers{ownerUIDIndex: jobOwnerUIDIndexFunc}); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer factory.Shutdown()
+	defer cancel()
+	factory.Start(ctx.Done())
+	for typ, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("cache for %v did not sync", typ)
+		}
+	}
+
+	cronJob, err := cronJobLister.CronJobs(namespace).Get(name)
+	if err != nil {
+		return err
+	}
+	jobs, err := jobsOf(jobInformer.GetIndexer(), cronJob)
+	if err != nil {
+		return err
+	}
+	printCronJob(w, cronJob, jobs, time.Now())
+	return nil
+}
+
+// printCronJob writes the schedule state of the CronJob and a table of its Jobs
+func printCronJob(w io.Writer, cronJob *batchv1.CronJob, jobs []*batchv1.Job, now time.Time) {
+	suspended := cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+	lastSchedule := "never"
+	if t := cronJob.Status.LastScheduleTime; t != nil {
+		lastSchedule = duration.HumanDuration(now.Sub(t.Time)) + " ago"
+	}
+	fmt.Fprintf(w, "CronJob %s/%s: schedule %q, suspended %t, last schedule %s, active %d\n",
+		cronJob.Namespace, cronJob.Name, cronJob.Spec.Schedule, suspended, lastSchedule, len(cronJob.Status.Active))
+	if len(jobs) == 0 {
+		fmt.Fprintln(w, "No jobs")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "JOB\tORIGIN\tSTATUS\tSUCCEEDED\tFAILED\tAGE")
+	for _, job := range jobs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\n", job.Name, jobOrigin(job), jobStatus(job),
+			job.Status.Succeeded, job.Status.Failed, duration.HumanDuration(now.Sub(job.CreationTimestamp.Time)))
+	}
+	tw.Flush()
+}
+
+// run performs one action against the CronJob named by --name
+func run(ctx context.Context, clientset kubernetes.Interface, action string) error {
+	cronJobs := clientset.BatchV1().CronJobs(*namespace)
+	switch action {
+	case "create":
+		cronJob, err := cronJobs.Create(ctx, newCronJob(*namespace, *name, *schedule, *image, *command), metav1.CreateOptions{FieldManager: fieldManager})
+		if apierrors.IsAlreadyExists(err) {
+			fmt.Printf("CronJob %s/%s already exists\n", *namespace, *name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created CronJob %s/%s with schedule %q\n", cronJob.Namespace, cronJob.Name, cronJob.Spec.Schedule)
+	case "suspend", "resume":
+		if _, err := setSuspend(ctx, clientset, *namespace, *name, action == "suspend"); err != nil {
+			return err
+		}
+		fmt.Printf("CronJob %s/%s %sd; running jobs are not affected\n", *namespace, *name, action)
+	case "trigger":
+		job, err := trigger(ctx, clientset, *namespace, *name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created job %s/%s from CronJob %s\n", job.Namespace, job.Name, *name)
+	case "jobs":
+		return listJobs(ctx, clientset, os.Stdout, *namespace, *name)
+	case "delete":
+		// Background propagation lets the garbage collector delete the Jobs
+		// and their pods after the CronJob is gone
+		propagation := metav1.DeletePropagationBackground
+		if err := cronJobs.Delete(ctx, *name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted CronJob %s/%s and its jobs\n", *namespace, *name)
+	default:
+		return fmt.Errorf("unknown action %q (want create, suspend, resume, trigger, jobs or delete)", action)
+	}
+	return nil
+}
+
+func main() {
+	clientset := createClientSet()
+	// Ctrl-C or SIGTERM cancels the request in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	action := flag.Arg(0)
+	if action == "" {
+		action = "jobs"
+	}
+	if err := run(ctx, clientset, action); err != nil {
+		log.Fatalf("%s %s/%s: %v", action, *namespace, *name, err)
+	}
+}
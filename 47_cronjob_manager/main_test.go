@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func cronJob() *batchv1.CronJob {
+	c := newCronJob("default", "hello-cron", "*/5 * * * *", "busybox:1.36", "date")
+	c.UID = types.UID("cron-uid")
+	return c
+}
+
+func ownedJob(name string, owner *batchv1.CronJob, created time.Time) *batchv1.Job {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name: name, Namespace: "default",
+		CreationTimestamp: metav1.NewTime(created),
+	}}
+	if owner != nil {
+		job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, batchv1.SchemeGroupVersion.WithKind("CronJob"))}
+	}
+	return job
+}
+
+func TestJobFromCronJob(t *testing.T) {
+	c := cronJob()
+	job := jobFromCronJob(c)
+	owner := metav1.GetControllerOf(job)
+	if owner == nil || owner.UID != c.UID || owner.Kind != "CronJob" {
+		t.Fatalf("controller = %+v, want the CronJob", owner)
+	}
+	if job.Annotations[instantiateAnnotation] != "manual" || job.Labels["app"] != "hello-cron" {
+		t.Errorf("annotations %v, labels %v", job.Annotations, job.Labels)
+	}
+	if job.GenerateName != "hello-cron-manual-" {
+		t.Errorf("generateName = %q", job.GenerateName)
+	}
+	// The Job gets its own copy of the template
+	job.Spec.Template.Spec.Containers[0].Image = "changed"
+	if c.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image != "busybox:1.36" {
+		t.Error("jobFromCronJob shares the CronJob's template")
+	}
+}
+
+func TestJobsOf(t *testing.T) {
+	c := cronJob()
+	other := cronJob()
+	other.UID = "other-uid"
+	now := time.Now()
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{ownerUIDIndex: jobOwnerUIDIndexFunc})
+	for _, job := range []*batchv1.Job{
+		ownedJob("hello-cron-29000010", c, now.Add(-time.Minute)),
+		ownedJob("hello-cron-29000005", c, now.Add(-6*time.Minute)),
+		ownedJob("other-29000010", other, now),
+		ownedJob("standalone", nil, now),
+	} {
+		if err := indexer.Add(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs, err := jobsOf(indexer, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, job := range jobs {
+		names = append(names, job.Name)
+	}
+	if got := strings.Join(names, ","); got != "hello-cron-29000005,hello-cron-29000010" {
+		t.Errorf("jobs = %s", got)
+	}
+}
+
+func TestSuspendResume(t *testing.T) {
+	clientset := fake.NewSimpleClientset(cronJob())
+	ctx := context.Background()
+	for _, suspend := range []bool{true, false} {
+		got, err := setSuspend(ctx, clientset, "default", "hello-cron", suspend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Spec.Suspend == nil || *got.Spec.Suspend != suspend {
+			t.Errorf("suspend = %v, want %t", got.Spec.Suspend, suspend)
+		}
+		if got.Spec.Schedule != "*/5 * * * *" {
+			t.Errorf("patch changed the schedule to %q", got.Spec.Schedule)
+		}
+	}
+}
+
+func TestTriggerAndList(t *testing.T) {
+	c := cronJob()
+	clientset := fake.NewSimpleClientset(c)
+	// The fake object tracker does not apply generateName
+	clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job); job.Name == "" {
+			job.Name = job.GenerateName + "x7k2p"
+			job.CreationTimestamp = metav1.Now()
+		}
+		return false, nil, nil
+	})
+	scheduled := ownedJob("hello-cron-29000005", c, time.Now().Add(-5*time.Minute))
+	scheduled.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	scheduled.Status.Succeeded = 1
+	if _, err := clientset.BatchV1().Jobs("default").Create(context.Background(), scheduled, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, err := trigger(ctx, clientset, "default", "hello-cron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Name != "hello-cron-manual-x7k2p" {
+		t.Errorf("job name = %q", job.Name)
+	}
+
+	var out bytes.Buffer
+	if err := listJobs(ctx, clientset, &out, "default", "hello-cron"); err != nil {
+		t.Fatal(err)
+	}
+	// Compare with the table's padding collapsed to single spaces
+	var lines []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	got := strings.Join(lines, "\n")
+	for _, want := range []string{
+		`CronJob default/hello-cron: schedule "*/5 * * * *", suspended false, last schedule never, active 0`,
+		"hello-cron-29000005 scheduled Complete 1 0 5m",
+		"hello-cron-manual-x7k2p manual Running 0 0",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output misses %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunUnknownAction(t *testing.T) {
+	err := run(context.Background(), fake.NewSimpleClientset(), "pause")
+	if err == nil || !strings.Contains(err.Error(), `unknown action "pause"`) {
+		t.Errorf("err = %v", err)
+	}
+}
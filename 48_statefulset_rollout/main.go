@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace = flag.String("namespace", "default", "namespace of the StatefulSet")
+	name      = flag.String("name", "web", "name of the StatefulSet")
+	create    = flag.Bool("create", false, "create the StatefulSet and its headless Service first")
+	replicas  = flag.Int("replicas", 3, "replicas of the StatefulSet created by --create")
+	image     = flag.String("image", "", "set the image of the first container, which starts a rolling update")
+	partition = flag.Int("partition", -1, "set spec.updateStrategy.rollingUpdate.partition (-1 leaves it unchanged)")
+	follow    = flag.Bool("follow", false, "keep watching after the rollout reached its target")
+	timeout   = flag.Duration("timeout", 10*time.Minute, "give up waiting for the rollout after this long")
+)
+
+// initialImage is the image of a StatefulSet created by --create without --image
+const initialImage = "nginx:1.27"
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "48-statefulset-rollout")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// newStatefulSet returns a StatefulSet of nginx pods and the headless Service
+// that gives each pod a stable DNS name
+func newStatefulSet(namespace, name, image string, replicas int32) (*appsv1.StatefulSet, *corev1.Service) {
+	labels := map[string]string{"app": name}
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: name,
+			Selector:    &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "nginx",
+						Image: image,
+						Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 80}},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/", Port: intstr.FromString("http")}},
+						},
+					}},
+				},
+			},
+		},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  labels,
+			Ports:     []corev1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}
+	return sts, svc
+}
+
+// createStatefulSet creates the headless Service and the StatefulSet,
+// leaving either alone if it exists already
+func createStatefulSet(ctx context.Context, clientset kubernetes.Interface, sts *appsv1.StatefulSet, svc *corev1.Service) error {
+	if _, err := clientset.CoreV1().Services(svc.Namespace).Create(ctx, svc, metav1.CreateOptions{FieldManager: fieldManager}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("creating service: %w", err)
+	}
+	_, err := clientset.AppsV1().StatefulSets(sts.Namespace).Create(ctx, sts, metav1.CreateOptions{FieldManager: fieldManager})
+	if apierrors.IsAlreadyExists(err) {
+		fmt.Printf("StatefulSet %s/%s already exists\n", sts.Namespace, sts.Name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("creating statefulset: %w", err)
+	}
+	fmt.Printf("Created StatefulSet %s/%s with %d replicas of %s\n", sts.Namespace, sts.Name, *sts.Spec.Replicas, sts.Spec.Template.Spec.Containers[0].Image)
+	return nil
+}
+
+// ownedPods returns the cached pods the StatefulSet controls
+func ownedPods(podLister corelisters.PodLister, sts *appsv1.StatefulSet) ([]*corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(sts.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := podLister.Pods(sts.Namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+	owned := pods[:0]
+	for _, pod := range pods {
+		if owner := metav1.GetControllerOf(pod); owner != nil && owner.UID == sts.UID {
+			owned = append(owned, pod)
+		}
+	}
+	return owned, nil
+}
+
+// report renders the progress of the StatefulSet from the caches
+func report(stsLister appslisters.StatefulSetLister, podLister corelisters.PodLister, namespace, name string) (string, bool, error) {
+	sts, err := stsLister.StatefulSets(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return fmt.Sprintf("StatefulSet %s/%s does not exist\n", namespace, name), false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	pods, err := ownedPods(podLister, sts)
+	if err != nil {
+		return "", false, err
+	}
+	statuses := replicaProgress(sts, pods)
+	done, state := rolloutState(sts, statuses)
+	var out bytes.Buffer
+	printProgress(&out, sts, statuses, state)
+	return out.String(), done, nil
+}
+
+// monitor prints the progress whenever the StatefulSet or one of its pods
+// changes, and returns once the rollout reached its target. Handlers only
+// signal that something changed; the report is computed from the listers, so
+// it always reflects both caches.
+func monitor(ctx context.Context, clientset kubernetes.Interface, namespace, name string, follow bool) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	stsLister := factory.Apps().V1().StatefulSets().Lister()
+	podLister := factory.Core().V1().Pods().Lister()
+
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { notify() },
+		DeleteFunc: func(obj interface{}) { notify() },
+	}
+	factory.Apps().V1().StatefulSets().Informer().AddEventHandler(handler)
+	factory.Core().V1().Pods().Informer().AddEventHandler(handler)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer factory.Shutdown()
+	defer cancel()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+
+	last := ""
+	for {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+		out, done, err := report(stsLister, podLister, namespace, name)
+		if err != nil {
+			return err
+		}
+		if out != last {
+			fmt.Print(out)
+			last = out
+		}
+		if done && !follow {
+			return nil
+		}
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+	// Ctrl-C or SIGTERM stops the monitor; the rollout itself continues
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	newImage := *image
+	if *create {
+		initial := *image
+		if initial == "" {
+			initial = initialImage
+		}
+		sts, svc := newStatefulSet(*namespace, *name, initial, int32(*replicas))
+		if err := createStatefulSet(ctx, clientset, sts, svc); err != nil {
+			log.Fatal(err)
+		}
+		// The image went into the new StatefulSet; it is no update
+		newImage = ""
+	}
+
+	var newPartition *int32
+	if *partition >= 0 {
+		p := int32(*partition)
+		newPartition = &p
+	}
+	sts, err := startUpdate(ctx, clientset, *namespace, *name, newImage, newPartition)
+	if err != nil {
+		log.Fatalf("Failed to update StatefulSet: %v", err)
+	}
+	if sts != nil {
+		fmt.Printf("Patched StatefulSet %s/%s: %s (generation %d)\n", sts.Namespace, sts.Name, describeUpdate(newImage, newPartition), sts.Generation)
+	}
+
+	waitCtx, cancel := context.WithTimeoutCause(ctx, *timeout, fmt.Errorf("rollout not finished after %v", *timeout))
+	defer cancel()
+	if err := monitor(waitCtx, clientset, *namespace, *name, *follow); err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+// web is a StatefulSet halfway through an update from web-1 to web-2
+func web(partition int32) *appsv1.StatefulSet {
+	sts, _ := newStatefulSet("default", "web", "nginx:1.28", 3)
+	sts.UID = "web-uid"
+	sts.Generation = 2
+	sts.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+		Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+	}
+	sts.Status = appsv1.StatefulSetStatus{
+		ObservedGeneration: 2,
+		CurrentRevision:    "web-1",
+		UpdateRevision:     "web-2",
+	}
+	return sts
+}
+
+func pod(sts *appsv1.StatefulSet, name, revision string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: "default",
+			Labels:          map[string]string{"app": "web", appsv1.StatefulSetRevisionLabel: revision},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(sts, appsv1.SchemeGroupVersion.WithKind("StatefulSet"))},
+		},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+	}
+}
+
+func TestRolloutState(t *testing.T) {
+	tests := []struct {
+		name      string
+		partition int32
+		observed  int64
+		pods      []replicaStatus
+		wantDone  bool
+		wantState string
+	}{
+		{
+			name: "spec not observed yet", partition: 0, observed: 1,
+			pods:      []replicaStatus{{0, "web-0", "web-1", true}, {1, "web-1", "web-1", true}, {2, "web-2", "web-1", true}},
+			wantState: "waiting for the controller to observe generation 2",
+		},
+		{
+			name: "highest ordinal first", partition: 0, observed: 2,
+			pods:      []replicaStatus{{0, "web-0", "web-1", true}, {1, "web-1", "web-1", true}, {2, "web-2", "web-2", false}},
+			wantState: "waiting for web-2 to be ready",
+		},
+		{
+			name: "next ordinal", partition: 0, observed: 2,
+			pods:      []replicaStatus{{0, "web-0", "web-1", true}, {1, "web-1", "", false}, {2, "web-2", "web-2", true}},
+			wantState: "waiting for web-1 to be replaced",
+		},
+		{
+			name: "partition reached", partition: 2, observed: 2,
+			pods:      []replicaStatus{{0, "web-0", "web-1", true}, {1, "web-1", "web-1", true}, {2, "web-2", "web-2", true}},
+			wantDone:  true,
+			wantState: "partition 2 reached: ordinals 2-2 run web-2, 0-1 stay on web-1; lower --partition to continue",
+		},
+		{
+			name: "below the partition a pod must still be ready", partition: 2, observed: 2,
+			pods:      []replicaStatus{{0, "web-0", "web-1", false}, {1, "web-1", "web-1", true}, {2, "web-2", "web-2", true}},
+			wantState: "waiting for web-0 to be ready",
+		},
+		{
+			name: "complete", partition: 0, observed: 2,
+			pods:      []replicaStatus{{0, "web-0", "web-2", true}, {1, "web-1", "web-2", true}, {2, "web-2", "web-2", true}},
+			wantDone:  true,
+			wantState: "rollout complete: 3 replicas run web-2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sts := web(tt.partition)
+			sts.Status.ObservedGeneration = tt.observed
+			done, state := rolloutState(sts, tt.pods)
+			if done != tt.wantDone || state != tt.wantState {
+				t.Errorf("got %t %q, want %t %q", done, state, tt.wantDone, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestStartUpdate(t *testing.T) {
+	sts, _ := newStatefulSet("default", "web", "nginx:1.27", 3)
+	sts.Spec.Template.Spec.Containers = append(sts.Spec.Template.Spec.Containers, corev1.Container{Name: "sidecar", Image: "busybox"})
+	clientset := fake.NewSimpleClientset(sts)
+	partition := int32(2)
+
+	got, err := startUpdate(context.Background(), clientset, "default", "web", "nginx:1.28", &partition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers := got.Spec.Template.Spec.Containers
+	if len(containers) != 2 || containers[0].Image != "nginx:1.28" || containers[1].Image != "busybox" {
+		t.Errorf("containers after patch = %+v", containers)
+	}
+	if partitionOf(got) != 2 || got.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		t.Errorf("update strategy = %+v", got.Spec.UpdateStrategy)
+	}
+
+	if got, err := startUpdate(context.Background(), clientset, "default", "web", "", nil); got != nil || err != nil {
+		t.Errorf("empty update = %v, %v; want no patch", got, err)
+	}
+}
+
+func TestMonitorPartitionedRollout(t *testing.T) {
+	sts := web(1)
+	h := testutil.NewHarness(t, sts,
+		pod(sts, "web-0", "web-1", true),
+		pod(sts, "web-1", "web-1", true),
+		pod(sts, "web-2", "web-2", true),
+	)
+	output := testutil.CaptureOutput(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testutil.Timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- monitor(ctx, h.Clientset, "default", "web", false) }()
+
+	testutil.WaitForOutput(t, output, "waiting for web-1 to be replaced")
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+
+	// The controller deletes web-1 and creates it again from the update revision
+	h.Update(pod(sts, "web-1", "web-2", false))
+	testutil.WaitForOutput(t, output, "waiting for web-1 to be ready")
+	h.Update(pod(sts, "web-1", "web-2", true))
+
+	if err := <-done; err != nil {
+		t.Fatalf("monitor: %v", err)
+	}
+	testutil.WaitForOutput(t, output,
+		"partition 1 reached: ordinals 1-2 run web-2, 0-0 stay on web-1",
+		"  1        web-1  web-2     yes      true",
+	)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+// fieldManager owns the partition the rollout patches, so managedFields
+// shows which tool last moved it
+var fieldManager = clientid.FieldManager("48-statefulset-rollout")
+
+// replicaStatus is the rollout state of one ordinal of a StatefulSet
+type replicaStatus struct {
+	Ordinal  int32
+	Pod      string
+	Revision string // empty while the pod does not exist
+	Ready    bool
+}
+
+// replicaProgress returns one entry per ordinal from 0 to spec.replicas-1.
+// A StatefulSet's pods are named <statefulset>-<ordinal> and carry the name
+// of the ControllerRevision they were created from in the
+// controller-revision-hash label.
+func replicaProgress(sts *appsv1.StatefulSet, pods []*corev1.Pod) []replicaStatus {
+	byName := make(map[string]*corev1.Pod, len(pods))
+	for _, pod := range pods {
+		byName[pod.Name] = pod
+	}
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+	statuses := make([]replicaStatus, 0, replicas)
+	for ordinal := int32(0); ordinal < replicas; ordinal++ {
+		s := replicaStatus{Ordinal: ordinal, Pod: fmt.Sprintf("%s-%d", sts.Name, ordinal)}
+		if pod, ok := byName[s.Pod]; ok && pod.DeletionTimestamp == nil {
+			s.Revision = pod.Labels[appsv1.StatefulSetRevisionLabel]
+			s.Ready = podReady(pod)
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// podReady reports whether the pod's Ready condition is True
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// partitionOf returns the ordinal from which a rolling update replaces pods.
+// Pods below it keep the current revision.
+func partitionOf(sts *appsv1.StatefulSet) int32 {
+	if ru := sts.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil {
+		return *ru.Partition
+	}
+	return 0
+}
+
+// rolloutState says whether the update reached its target, and what it is
+// waiting for otherwise. The controller replaces pods from the highest
+// ordinal down to the partition, one at a time, and waits for each to be
+// Ready before the next.
+func rolloutState(sts *appsv1.StatefulSet, statuses []replicaStatus) (bool, string) {
+	if sts.Status.ObservedGeneration < sts.Generation {
+		return false, fmt.Sprintf("waiting for the controller to observe generation %d", sts.Generation)
+	}
+	onDelete := sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType
+	partition := partitionOf(sts)
+	if onDelete {
+		partition = 0
+	}
+	for i := len(statuses) - 1; i >= 0; i-- {
+		s := statuses[i]
+		switch {
+		case s.Ordinal >= partition && s.Revision != sts.Status.UpdateRevision:
+			if onDelete {
+				return false, fmt.Sprintf("OnDelete: %s is updated when it is deleted", s.Pod)
+			}
+			return false, fmt.Sprintf("waiting for %s to be replaced", s.Pod)
+		case s.Revision == "":
+			return false, fmt.Sprintf("waiting for %s to be created", s.Pod)
+		case !s.Ready:
+			return false, fmt.Sprintf("waiting for %s to be ready", s.Pod)
+		}
+	}
+	if partition > 0 && int(partition) < len(statuses) {
+		return true, fmt.Sprintf("partition %d reached: ordinals %d-%d run %s, 0-%d stay on %s; lower --partition to continue",
+			partition, partition, len(statuses)-1, sts.Status.UpdateRevision, partition-1, sts.Status.CurrentRevision)
+	}
+	return true, fmt.Sprintf("rollout complete: %d replicas run %s", len(statuses), sts.Status.UpdateRevision)
+}
+
+// printProgress writes the revisions of the StatefulSet and a row per ordinal
+func printProgress(w io.Writer, sts *appsv1.StatefulSet, statuses []replicaStatus, state string) {
+	fmt.Fprintf(w, "StatefulSet %s/%s: currentRevision %s (%d), updateRevision %s (%d), partition %d, ready %d/%d\n",
+		sts.Namespace, sts.Name, sts.Status.CurrentRevision, sts.Status.CurrentReplicas,
+		sts.Status.UpdateRevision, sts.Status.UpdatedReplicas, partitionOf(sts), sts.Status.ReadyReplicas, len(statuses))
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ORDINAL\tPOD\tREVISION\tUPDATED\tREADY")
+	for _, s := range statuses {
+		revision := s.Revision
+		if revision == "" {
+			revision = "<none>"
+		}
+		updated := "no"
+		if s.Revision != "" && s.Revision == sts.Status.UpdateRevision {
+			updated = "yes"
+		}
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%s\t%t\n", s.Ordinal, s.Pod, revision, updated, s.Ready)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "  %s\n", state)
+}
+
+// updatePatch returns a strategic merge patch that sets the image of
+// container and the partition. Either may be left out. Sending both in one
+// patch matters: with two, the controller could start replacing pods below
+// the new partition before the second patch arrives.
+func updatePatch(container, image string, partition *int32) ([]byte, error) {
+	spec := map[string]interface{}{}
+	if image != "" {
+		// Containers are merged by name, so the other containers stay as they are
+		spec["template"] = map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []map[string]interface{}{{"name": container, "image": image}},
+			},
+		}
+	}
+	if partition != nil {
+		spec["updateStrategy"] = map[string]interface{}{
+			"type":          appsv1.RollingUpdateStatefulSetStrategyType,
+			"rollingUpdate": map[string]interface{}{"partition": *partition},
+		}
+	}
+	if len(spec) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(map[string]interface{}{"spec": spec})
+}
+
+// startUpdate patches the image of the first container and the partition of
+// the StatefulSet. It returns nil when there is nothing to change.
+func startUpdate(ctx context.Context, clientset kubernetes.Interface, namespace, name, image string, partition *int32) (*appsv1.StatefulSet, error) {
+	statefulSets := clientset.AppsV1().StatefulSets(namespace)
+	container := ""
+	if image != "" {
+		sts, err := statefulSets.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		container = sts.Spec.Template.Spec.Containers[0].Name
+	}
+	patch, err := updatePatch(container, image, partition)
+	if err != nil || patch == nil {
+		return nil, err
+	}
+	return statefulSets.Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager})
+}
+
+// describeUpdate says what startUpdate changed, e.g. "image nginx:1.28, partition 2"
+func describeUpdate(image string, partition *int32) string {
+	var parts []string
+	if image != "" {
+		parts = append(parts, "image "+image)
+	}
+	if partition != nil {
+		parts = append(parts, fmt.Sprintf("partition %d", *partition))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace    = flag.String("namespace", "", "namespace of the DaemonSets (empty for all namespaces)")
+	name         = flag.String("name", "", "track only the DaemonSet with this name")
+	problemsOnly = flag.Bool("problems-only", false, "leave out nodes whose pod is updated and ready")
+	once         = flag.Bool("once", false, "print the report once after the caches synced and exit")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "49-daemonset-tracker")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// tracker reads DaemonSets, their ControllerRevisions, pods and nodes from
+// one informer factory
+type tracker struct {
+	factory informers.SharedInformerFactory
+	pods    cache.Indexer
+}
+
+// newTracker registers the four informers and the node index on the pods.
+// Every event calls notify.
+func newTracker(clientset kubernetes.Interface, namespace string, notify func()) (*tracker, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	podInformer := factory.Core().V1().Pods().Informer()
+	if err := podInformer.AddIndexers(cache.Indexers{nodeIndex: podNodeIndexFunc}); err != nil {
+		return nil, err
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { notify() },
+		DeleteFunc: func(obj interface{}) { notify() },
+	}
+	for _, informer := range []cache.SharedIndexInformer{
+		factory.Apps().V1().DaemonSets().Informer(),
+		factory.Apps().V1().ControllerRevisions().Informer(),
+		factory.Core().V1().Nodes().Informer(),
+		podInformer,
+	} {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return nil, err
+		}
+	}
+	return &tracker{factory: factory, pods: podInformer.GetIndexer()}, nil
+}
+
+// report renders every tracked DaemonSet from the caches
+func (t *tracker) report(namespace, name string, problemsOnly bool) (string, error) {
+	daemonSets, err := t.factory.Apps().V1().DaemonSets().Lister().DaemonSets(namespace).List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	nodes, err := t.factory.Core().V1().Nodes().Lister().List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	slices.SortFunc(daemonSets, func(a, b *appsv1.DaemonSet) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+	slices.SortFunc(nodes, func(a, b *corev1.Node) int { return strings.Compare(a.Name, b.Name) })
+
+	var out bytes.Buffer
+	for _, ds := range daemonSets {
+		if name != "" && ds.Name != name {
+			continue
+		}
+		hash, err := currentHash(t.factory.Apps().V1().ControllerRevisions().Lister(), ds)
+		if err != nil {
+			return "", err
+		}
+		states, err := trackNodes(ds, nodes, t.pods, hash)
+		if err != nil {
+			return "", err
+		}
+		printDaemonSet(&out, ds, states, problemsOnly)
+	}
+	if out.Len() == 0 {
+		fmt.Fprintln(&out, "No DaemonSets")
+	}
+	return out.String(), nil
+}
+
+// run prints the report whenever a DaemonSet, revision, pod or node changes,
+// as long as the report itself changed. Handlers only signal a change; the
+// report is computed from the listers, so it always reflects all caches.
+func run(ctx context.Context, clientset kubernetes.Interface, namespace, name string, problemsOnly, once bool) error {
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	t, err := newTracker(clientset, namespace, notify)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer t.factory.Shutdown()
+	defer cancel()
+	t.factory.Start(ctx.Done())
+	t.factory.WaitForCacheSync(ctx.Done())
+	// The first report does not wait for an event
+	notify()
+
+	last := ""
+	for {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+		out, err := t.report(namespace, name, problemsOnly)
+		if err != nil {
+			return err
+		}
+		if out != last {
+			fmt.Print(out)
+			last = out
+		}
+		if once {
+			return nil
+		}
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, clientset, *namespace, *name, *problemsOnly, *once); err != nil {
+		log.Fatal(err)
+	}
+}
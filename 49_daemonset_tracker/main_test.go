@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func agent() *appsv1.DaemonSet {
+	labels := map[string]string{"app": "agent"}
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "kube-system", UID: "agent-uid", Generation: 2},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{NodeSelector: map[string]string{"kubernetes.io/os": "linux"}},
+			},
+		},
+		Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, CurrentNumberScheduled: 2, UpdatedNumberScheduled: 1, ObservedGeneration: 2},
+	}
+}
+
+func node(name string, taints ...corev1.Taint) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kubernetes.io/os": "linux"}},
+		Spec:       corev1.NodeSpec{Taints: taints},
+	}
+}
+
+func revision(ds *appsv1.DaemonSet, hash string, number int64) *appsv1.ControllerRevision {
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ds.Name + "-" + hash, Namespace: ds.Namespace, Labels: ds.Spec.Template.Labels,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(ds, appsv1.SchemeGroupVersion.WithKind("DaemonSet"))},
+		},
+		Revision: number,
+	}
+}
+
+func daemonPod(ds *appsv1.DaemonSet, name, nodeName, hash string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: ds.Namespace,
+			Labels:          map[string]string{"app": "agent", appsv1.DefaultDaemonSetUniqueLabelKey: hash},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(ds, appsv1.SchemeGroupVersion.WithKind("DaemonSet"))},
+		},
+		Spec:   corev1.PodSpec{NodeName: nodeName},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+	}
+}
+
+func TestShouldRun(t *testing.T) {
+	controlPlane := corev1.Taint{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule}
+	tolerating := agent()
+	tolerating.Spec.Template.Spec.Tolerations = []corev1.Toleration{{Key: controlPlane.Key, Operator: corev1.TolerationOpExists}}
+	pinned := agent()
+	pinned.Spec.Template.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+			MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"gpu"}}},
+		}}},
+	}}
+	gpu := node("gpu-1")
+	gpu.Labels["pool"] = "gpu"
+	windows := node("win-1")
+	windows.Labels["kubernetes.io/os"] = "windows"
+
+	tests := []struct {
+		name string
+		ds   *appsv1.DaemonSet
+		node *corev1.Node
+		want bool
+	}{
+		{"plain node", agent(), node("node-a"), true},
+		{"nodeSelector mismatch", agent(), windows, false},
+		{"untolerated taint", agent(), node("cp-1", controlPlane), false},
+		{"tolerated taint", tolerating, node("cp-1", controlPlane), true},
+		{"PreferNoSchedule is ignored", agent(), node("node-b", corev1.Taint{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule}), true},
+		{"taint the controller tolerates", agent(), node("node-c", corev1.Taint{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule}), true},
+		{"affinity match", pinned, gpu, true},
+		{"affinity mismatch", pinned, node("node-a"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldRun(tt.ds, tt.node); got != tt.want {
+				t.Errorf("shouldRun = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetNode(t *testing.T) {
+	ds := agent()
+	pod := daemonPod(ds, "agent-p9x2z", "", "7c9d", false)
+	pod.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+			MatchFields: []corev1.NodeSelectorRequirement{{Key: metav1.ObjectNameField, Operator: corev1.NodeSelectorOpIn, Values: []string{"node-c"}}},
+		}}},
+	}}
+	if got := targetNode(pod); got != "node-c" {
+		t.Errorf("targetNode of a pending pod = %q, want node-c", got)
+	}
+	if got := targetNode(daemonPod(ds, "agent-abcde", "node-a", "7c9d", true)); got != "node-a" {
+		t.Errorf("targetNode of a bound pod = %q, want node-a", got)
+	}
+}
+
+func TestRunReportsPerNode(t *testing.T) {
+	ds := agent()
+	h := testutil.NewHarness(t, ds,
+		revision(ds, "5b8f", 1), revision(ds, "7c9d", 2),
+		node("node-a"), node("node-b"), node("node-c"),
+		node("cp-1", corev1.Taint{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule}),
+		daemonPod(ds, "agent-abcde", "node-a", "7c9d", true),
+		daemonPod(ds, "agent-fghij", "node-b", "5b8f", true),
+	)
+	output := testutil.CaptureOutput(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, h.Clientset, "", "", false, false) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("run: %v", err)
+		}
+	}()
+
+	testutil.WaitForOutput(t, output,
+		"DaemonSet kube-system/agent: desired 3, current 2, updated 1, available 0, ready 0, misscheduled 0 (generation 2, observed 2)",
+		"  node-a  agent-abcde  7c9d      ready\n",
+		"  node-b  agent-fghij  5b8f      outdated !\n",
+		"  node-c  -            -         missing !\n",
+	)
+	if got := output(); strings.Contains(got, "cp-1") {
+		t.Errorf("the tainted control plane node is reported:\n%s", got)
+	}
+
+	// The controller replaced the outdated pod; the new one is not ready yet
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+	h.Delete(daemonPod(ds, "agent-fghij", "node-b", "5b8f", true))
+	h.Add(daemonPod(ds, "agent-klmno", "node-b", "7c9d", false))
+	testutil.WaitForOutput(t, output, "  node-b  agent-klmno  7c9d      not ready !\n")
+}
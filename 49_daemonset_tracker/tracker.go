@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// nodeIndex indexes pods by spec.nodeName, as in 05_informer_index. Pods
+// that are not scheduled yet are indexed under "".
+const nodeIndex = "node"
+
+func podNodeIndexFunc(obj interface{}) ([]string, error) {
+	pod := obj.(*corev1.Pod)
+	return []string{pod.Spec.NodeName}, nil
+}
+
+// Per-node states of a DaemonSet
+const (
+	stateReady        = "ready"
+	stateNotReady     = "not ready"
+	stateOutdated     = "outdated"
+	statePending      = "pending"
+	stateMissing      = "missing"
+	stateMisscheduled = "misscheduled"
+)
+
+// daemonTolerations are added to every DaemonSet pod by the DaemonSet
+// controller, so these taints never keep a daemon off a node
+var daemonTolerations = []corev1.Toleration{
+	{Key: corev1.TaintNodeNotReady, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	{Key: corev1.TaintNodeDiskPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodeMemoryPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodePIDPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	{Key: corev1.TaintNodeUnschedulable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+}
+
+// shouldRun reports whether the DaemonSet wants a pod on node. It checks the
+// nodeSelector, the required node affinity and the NoSchedule and NoExecute
+// taints, which is what decides placement for almost every DaemonSet.
+// Resource fit and host ports are left to the scheduler.
+func shouldRun(ds *appsv1.DaemonSet, node *corev1.Node) bool {
+	spec := ds.Spec.Template.Spec
+	if !labels.SelectorFromSet(spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+		return false
+	}
+	if a := spec.Affinity; a != nil && a.NodeAffinity != nil && a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		if !matchesNodeSelector(a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution, node) {
+			return false
+		}
+	}
+	tolerations := append(slices.Clone(spec.Tolerations), daemonTolerations...)
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		if !slices.ContainsFunc(tolerations, func(t corev1.Toleration) bool { return t.ToleratesTaint(taint) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesNodeSelector reports whether node matches any of the terms. Label
+// expressions are converted to label selector requirements; metadata.name is
+// the only field a term can select on.
+func matchesNodeSelector(ns *corev1.NodeSelector, node *corev1.Node) bool {
+	for _, term := range ns.NodeSelectorTerms {
+		if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+			continue
+		}
+		if termMatches(term, node) {
+			return true
+		}
+	}
+	return false
+}
+
+func termMatches(term corev1.NodeSelectorTerm, node *corev1.Node) bool {
+	for _, expr := range term.MatchExpressions {
+		r, err := labels.NewRequirement(expr.Key, nodeSelectorOperator(expr.Operator), expr.Values)
+		if err != nil || !r.Matches(labels.Set(node.Labels)) {
+			return false
+		}
+	}
+	for _, expr := range term.MatchFields {
+		r, err := labels.NewRequirement(expr.Key, nodeSelectorOperator(expr.Operator), expr.Values)
+		if err != nil || expr.Key != metav1.ObjectNameField || !r.Matches(labels.Set{metav1.ObjectNameField: node.Name}) {
+			return false
+		}
+	}
+	return true
+}
+
+func nodeSelectorOperator(op corev1.NodeSelectorOperator) selection.Operator {
+	switch op {
+	case corev1.NodeSelectorOpIn:
+		return selection.In
+	case corev1.NodeSelectorOpNotIn:
+		return selection.NotIn
+	case corev1.NodeSelectorOpExists:
+		return selection.Exists
+	case corev1.NodeSelectorOpDoesNotExist:
+		return selection.DoesNotExist
+	case corev1.NodeSelectorOpGt:
+		return selection.GreaterThan
+	case corev1.NodeSelectorOpLt:
+		return selection.LessThan
+	}
+	return selection.Operator(op)
+}
+
+// targetNode returns the node a DaemonSet pod is meant for. The controller
+// pins every pod to its node with a metadata.name node affinity term, which
+// is how a pod the scheduler has not bound yet is attributed to a node.
+func targetNode(pod *corev1.Pod) string {
+	if pod.Spec.NodeName != "" {
+		return pod.Spec.NodeName
+	}
+	a := pod.Spec.Affinity
+	if a == nil || a.NodeAffinity == nil || a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+	for _, term := range a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, f := range term.MatchFields {
+			if f.Key == metav1.ObjectNameField && f.Operator == corev1.NodeSelectorOpIn && len(f.Values) == 1 {
+				return f.Values[0]
+			}
+		}
+	}
+	return ""
+}
+
+// currentHash returns the hash of the DaemonSet's current template: the
+// suffix of its newest ControllerRevision. Updated pods carry the same value
+// in their controller-revision-hash label.
+func currentHash(revisions appslisters.ControllerRevisionLister, ds *appsv1.DaemonSet) (string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(ds.Spec.Selector)
+	if err != nil {
+		return "", err
+	}
+	list, err := revisions.ControllerRevisions(ds.Namespace).List(selector)
+	if err != nil {
+		return "", err
+	}
+	var newest *appsv1.ControllerRevision
+	for _, r := range list {
+		if !metav1.IsControlledBy(r, ds) {
+			continue
+		}
+		if newest == nil || r.Revision > newest.Revision {
+			newest = r
+		}
+	}
+	if newest == nil {
+		return "", nil
+	}
+	return strings.TrimPrefix(newest.Name, ds.Name+"-"), nil
+}
+
+// nodeState is the DaemonSet's pod on one node, or its absence
+type nodeState struct {
+	Node     string
+	Pod      string
+	Revision string
+	State    string
+}
+
+// trackNodes returns one entry per node that should run the DaemonSet or
+// runs one of its pods. The pods of a node are looked up through nodeIndex;
+// pods the scheduler has not bound yet are under "" and are attributed to
+// the node their affinity names.
+func trackNodes(ds *appsv1.DaemonSet, nodes []*corev1.Node, pods cache.Indexer, hash string) ([]nodeState, error) {
+	pending := map[string]*corev1.Pod{}
+	unscheduled, err := pods.ByIndex(nodeIndex, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range unscheduled {
+		pod := obj.(*corev1.Pod)
+		if metav1.IsControlledBy(pod, ds) {
+			pending[targetNode(pod)] = pod
+		}
+	}
+
+	var states []nodeState
+	for _, node := range nodes {
+		objs, err := pods.ByIndex(nodeIndex, node.Name)
+		if err != nil {
+			return nil, err
+		}
+		var pod *corev1.Pod
+		for _, obj := range objs {
+			if p := obj.(*corev1.Pod); metav1.IsControlledBy(p, ds) && p.DeletionTimestamp == nil {
+				pod = p
+				break
+			}
+		}
+		if pod == nil {
+			pod = pending[node.Name]
+		}
+		wanted := shouldRun(ds, node)
+		if pod == nil && !wanted {
+			continue
+		}
+		s := nodeState{Node: node.Name}
+		if pod == nil {
+			s.State = stateMissing
+			states = append(states, s)
+			continue
+		}
+		s.Pod = pod.Name
+		s.Revision = pod.Labels[appsv1.DefaultDaemonSetUniqueLabelKey]
+		switch {
+		case !wanted:
+			s.State = stateMisscheduled
+		case pod.Spec.NodeName == "":
+			s.State = statePending
+		case hash != "" && s.Revision != hash:
+			s.State = stateOutdated
+		case !podReady(pod):
+			s.State = stateNotReady
+		default:
+			s.State = stateReady
+		}
+		states = append(states, s)
+	}
+	slices.SortFunc(states, func(a, b nodeState) int { return strings.Compare(a.Node, b.Node) })
+	return states, nil
+}
+
+// podReady reports whether the pod's Ready condition is True
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// printDaemonSet writes the status counts of the DaemonSet and its nodes.
+// With problemsOnly, nodes in the ready state are left out.
+func printDaemonSet(w io.Writer, ds *appsv1.DaemonSet, states []nodeState, problemsOnly bool) {
+	st := ds.Status
+	fmt.Fprintf(w, "DaemonSet %s/%s: desired %d, current %d, updated %d, available %d, ready %d, misscheduled %d (generation %d, observed %d)\n",
+		ds.Namespace, ds.Name, st.DesiredNumberScheduled, st.CurrentNumberScheduled, st.UpdatedNumberScheduled,
+		st.NumberAvailable, st.NumberReady, st.NumberMisscheduled, ds.Generation, st.ObservedGeneration)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "  NODE\tPOD\tREVISION\tSTATE")
+	for _, s := range states {
+		if problemsOnly && s.State == stateReady {
+			continue
+		}
+		flag := ""
+		if s.State != stateReady {
+			flag = " !"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s%s\n", s.Node, dash(s.Pod), dash(s.Revision), s.State, flag)
+	}
+	tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/tombstone"
+)
+
+// newPVC returns a claim for size of storage with one access mode. An empty
+// storageClass leaves spec.storageClassName unset, so the default class
+// provisions the volume.
+func newPVC(namespace, name, size, storageClass string, accessMode corev1.PersistentVolumeAccessMode) (*corev1.PersistentVolumeClaim, error) {
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return nil, fmt.Errorf("size %q: %w", size, err)
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: quantity},
+			},
+		},
+	}
+	if storageClass != "" {
+		pvc.Spec.StorageClassName = &storageClass
+	}
+	return pvc, nil
+}
+
+// describePVC summarizes the phase, the volume and what was asked for
+func describePVC(pvc *corev1.PersistentVolumeClaim) string {
+	class := "<default>"
+	if pvc.Spec.StorageClassName != nil {
+		class = *pvc.Spec.StorageClassName
+	}
+	parts := []string{string(pvc.Status.Phase)}
+	if pvc.Spec.VolumeName != "" {
+		parts = append(parts, "volume "+pvc.Spec.VolumeName)
+	}
+	request := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	parts = append(parts, "class "+class, "requested "+request.String())
+	if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+		parts = append(parts, "capacity "+capacity.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describePV summarizes the phase and the claim a volume is bound to
+func describePV(pv *corev1.PersistentVolume) string {
+	parts := []string{string(pv.Status.Phase)}
+	if ref := pv.Spec.ClaimRef; ref != nil {
+		parts = append(parts, "claim "+ref.Namespace+"/"+ref.Name)
+	}
+	parts = append(parts, "class "+pv.Spec.StorageClassName, "reclaim "+string(pv.Spec.PersistentVolumeReclaimPolicy))
+	return strings.Join(parts, ", ")
+}
+
+// pvcHandler prints every claim when it appears, and its phase changes. When
+// a claim becomes Bound, the time it spent Pending is printed as well.
+func pvcHandler(now func() time.Time) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pvc := obj.(*corev1.PersistentVolumeClaim)
+			fmt.Printf("[PVC] %s/%s: %s\n", pvc.Namespace, pvc.Name, describePVC(pvc))
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old := oldObj.(*corev1.PersistentVolumeClaim)
+			pvc := newObj.(*corev1.PersistentVolumeClaim)
+			if old.Status.Phase == pvc.Status.Phase {
+				return
+			}
+			after := ""
+			if old.Status.Phase == corev1.ClaimPending && pvc.Status.Phase == corev1.ClaimBound {
+				after = " after " + duration.HumanDuration(now().Sub(pvc.CreationTimestamp.Time))
+			}
+			fmt.Printf("[PVC] %s/%s: %s -> %s%s\n", pvc.Namespace, pvc.Name, old.Status.Phase, describePVC(pvc), after)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if pvc, ok := tombstone.Unwrap[*corev1.PersistentVolumeClaim](obj); ok {
+				fmt.Printf("[PVC] %s/%s: deleted\n", pvc.Namespace, pvc.Name)
+			}
+		},
+	}
+}
+
+// pvHandler prints every volume when it appears, and its phase changes. A
+// dynamically provisioned volume appears already bound to its claim.
+func pvHandler() cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pv := obj.(*corev1.PersistentVolume)
+			fmt.Printf("[PV] %s: %s\n", pv.Name, describePV(pv))
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old := oldObj.(*corev1.PersistentVolume)
+			pv := newObj.(*corev1.PersistentVolume)
+			if old.Status.Phase != pv.Status.Phase {
+				fmt.Printf("[PV] %s: %s -> %s\n", pv.Name, old.Status.Phase, describePV(pv))
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if pv, ok := tombstone.Unwrap[*corev1.PersistentVolume](obj); ok {
+				fmt.Printf("[PV] %s: deleted\n", pv.Name)
+			}
+		},
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace    = flag.String("namespace", "default", "namespace of the claims to watch")
+	name         = flag.String("name", "data-claim", "name of the claim to create")
+	create       = flag.Bool("create", true, "create the claim before watching")
+	size         = flag.String("size", "1Gi", "storage requested by the claim")
+	storageClass = flag.String("storage-class", "", "storage class of the claim (empty for the default class)")
+	accessMode   = flag.String("access-mode", string(corev1.ReadWriteOnce), "access mode of the claim")
+	stuckAfter   = flag.Duration("stuck-after", time.Minute, "report claims that have been Pending for longer than this")
+	cleanup      = flag.Bool("cleanup", false, "delete the claim on exit")
+)
+
+// checkInterval is how often Pending claims are compared with --stuck-after
+const checkInterval = 5 * time.Second
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "50-pvc-binding")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// startWatch registers the claim and volume handlers, and the caches the
+// stuck detector reads, then starts the informers. The events come from a
+// factory of their own, because only that factory's list options select the
+// events about claims.
+func startWatch(clientset kubernetes.Interface, namespace string, stuckAfter time.Duration, stopCh <-chan struct{}) (*stuckDetector, []informers.SharedInformerFactory, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	factory.Core().V1().PersistentVolumeClaims().Informer().AddEventHandler(pvcHandler(time.Now))
+	factory.Core().V1().PersistentVolumes().Informer().AddEventHandler(pvHandler())
+
+	eventFactory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("involvedObject.kind", "PersistentVolumeClaim").String()
+		}))
+	eventInformer := eventFactory.Core().V1().Events().Informer()
+	if err := eventInformer.AddIndexers(cache.Indexers{involvedUIDIndex: eventInvolvedUIDIndexFunc}); err != nil {
+		return nil, nil, err
+	}
+
+	detector := &stuckDetector{
+		pvcs:       factory.Core().V1().PersistentVolumeClaims().Lister(),
+		classes:    factory.Storage().V1().StorageClasses().Lister(),
+		events:     eventInformer.GetIndexer(),
+		namespace:  namespace,
+		stuckAfter: stuckAfter,
+		reported:   make(map[types.UID]bool),
+	}
+	factories := []informers.SharedInformerFactory{factory, eventFactory}
+	for _, f := range factories {
+		f.Start(stopCh)
+	}
+	return detector, factories, nil
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *create {
+		pvc, err := newPVC(*namespace, *name, *size, *storageClass, corev1.PersistentVolumeAccessMode(*accessMode))
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, err = clientset.CoreV1().PersistentVolumeClaims(*namespace).Create(ctx, pvc, metav1.CreateOptions{})
+		switch {
+		case apierrors.IsAlreadyExists(err):
+			fmt.Printf("PVC %s/%s already exists\n", *namespace, *name)
+		case err != nil:
+			log.Fatalf("Failed to create PVC: %v", err)
+		default:
+			fmt.Printf("Created PVC %s/%s\n", *namespace, *name)
+		}
+	}
+
+	detector, factories, err := startWatch(clientset, *namespace, *stuckAfter, ctx.Done())
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range factories {
+		f.WaitForCacheSync(ctx.Done())
+	}
+	fmt.Printf("Watching claims in %s and all volumes; claims Pending for more than %v are reported\n", *namespace, *stuckAfter)
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for done := false; !done; {
+		select {
+		case now := <-ticker.C:
+			if err := detector.check(os.Stdout, now); err != nil {
+				log.Printf("Stuck check failed: %v", err)
+			}
+		case <-ctx.Done():
+			done = true
+		}
+	}
+	for _, f := range factories {
+		f.Shutdown()
+	}
+
+	if *create && *cleanup {
+		// ctx is cancelled by now
+		if err := clientset.CoreV1().PersistentVolumeClaims(*namespace).Delete(context.Background(), *name, metav1.DeleteOptions{}); err != nil {
+			log.Fatalf("Failed to delete PVC: %v", err)
+		}
+		fmt.Printf("Deleted PVC %s/%s\n", *namespace, *name)
+	}
+}
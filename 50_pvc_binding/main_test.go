@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+var created = time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+func claim(class string, phase corev1.PersistentVolumeClaimPhase) *corev1.PersistentVolumeClaim {
+	pvc, err := newPVC("default", "data-claim", "1Gi", class, corev1.ReadWriteOnce)
+	if err != nil {
+		panic(err)
+	}
+	pvc.UID = "claim-uid"
+	pvc.CreationTimestamp = metav1.NewTime(created)
+	pvc.Status.Phase = phase
+	return pvc
+}
+
+func bound(pvc *corev1.PersistentVolumeClaim, volume string) *corev1.PersistentVolumeClaim {
+	pvc = pvc.DeepCopy()
+	pvc.Spec.VolumeName = volume
+	pvc.Status.Phase = corev1.ClaimBound
+	pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")}
+	return pvc
+}
+
+func newStorageClass(name string, mode storagev1.VolumeBindingMode, isDefault bool) *storagev1.StorageClass {
+	sc := &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: name},
+		Provisioner:       "ebs.csi.aws.com",
+		VolumeBindingMode: &mode,
+	}
+	if isDefault {
+		sc.Annotations = map[string]string{defaultClassAnnotation: "true"}
+	}
+	return sc
+}
+
+func claimEvent(name, typ, reason, message string, count int32, at time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{
+			Kind: "PersistentVolumeClaim", Namespace: "default", Name: "data-claim", UID: "claim-uid",
+		},
+		Type: typ, Reason: reason, Message: message, Count: count,
+		LastTimestamp: metav1.NewTime(at),
+	}
+}
+
+// newDetector builds a stuck detector over plain indexers
+func newDetector(t *testing.T, objects ...interface{}) *stuckDetector {
+	pvcs := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	classes := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	events := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{involvedUIDIndex: eventInvolvedUIDIndexFunc})
+	for _, obj := range objects {
+		var err error
+		switch obj.(type) {
+		case *corev1.PersistentVolumeClaim:
+			err = pvcs.Add(obj)
+		case *storagev1.StorageClass:
+			err = classes.Add(obj)
+		case *corev1.Event:
+			err = events.Add(obj)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &stuckDetector{
+		pvcs:       corelisters.NewPersistentVolumeClaimLister(pvcs),
+		classes:    storagelisters.NewStorageClassLister(classes),
+		events:     events,
+		namespace:  "default",
+		stuckAfter: time.Minute,
+		reported:   make(map[types.UID]bool),
+	}
+}
+
+func TestNewPVC(t *testing.T) {
+	pvc, err := newPVC("default", "data-claim", "5Gi", "", corev1.ReadWriteOnce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pvc.Spec.StorageClassName != nil {
+		t.Errorf("storageClassName = %q, want unset for the default class", *pvc.Spec.StorageClassName)
+	}
+	if got := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; got.String() != "5Gi" {
+		t.Errorf("request = %s", got.String())
+	}
+	if _, err := newPVC("default", "data-claim", "lots", "", corev1.ReadWriteOnce); err == nil {
+		t.Error("invalid size accepted")
+	}
+}
+
+func TestBindingLifecycle(t *testing.T) {
+	pending := claim("standard", corev1.ClaimPending)
+	h := testutil.NewHarness(t, pending)
+	output := testutil.CaptureOutput(t)
+
+	stopCh := make(chan struct{})
+	_, factories, err := startWatch(h.Clientset, "default", time.Minute, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Shutdown waits for the informers, which stop when stopCh is closed
+	defer func() {
+		close(stopCh)
+		for _, f := range factories {
+			f.Shutdown()
+		}
+	}()
+	for _, f := range factories {
+		f.WaitForCacheSync(stopCh)
+	}
+	testutil.WaitForOutput(t, output, "[PVC] default/data-claim: Pending, class standard, requested 1Gi\n")
+
+	h.WaitForWatchOf(&corev1.PersistentVolume{}, 1)
+	h.WaitForWatchOf(pending, 1)
+	h.Add(&corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-3f1c"},
+		Spec: corev1.PersistentVolumeSpec{
+			ClaimRef:                      &corev1.ObjectReference{Namespace: "default", Name: "data-claim"},
+			StorageClassName:              "standard",
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+		},
+		Status: corev1.PersistentVolumeStatus{Phase: corev1.VolumeBound},
+	})
+	h.Update(bound(pending, "pvc-3f1c"))
+	testutil.WaitForOutput(t, output,
+		"[PV] pvc-3f1c: Bound, claim default/data-claim, class standard, reclaim Delete\n",
+		"[PVC] default/data-claim: Pending -> Bound, volume pvc-3f1c, class standard, requested 1Gi, capacity 1Gi after ",
+	)
+}
+
+func TestStuckDetector(t *testing.T) {
+	d := newDetector(t,
+		claim("", corev1.ClaimPending),
+		newStorageClass("gp3", storagev1.VolumeBindingWaitForFirstConsumer, true),
+		claimEvent("e2", corev1.EventTypeNormal, "WaitForPodScheduled", "waiting for pod web-0 to be scheduled", 1, created.Add(20*time.Second)),
+		claimEvent("e1", corev1.EventTypeNormal, "WaitForFirstConsumer", "waiting for first consumer to be created before binding", 4, created.Add(time.Second)),
+	)
+
+	var out bytes.Buffer
+	if err := d.check(&out, created.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("reported before --stuck-after:\n%s", out.String())
+	}
+
+	if err := d.check(&out, created.Add(90*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	want := "[Stuck] default/data-claim: Pending for 90s; storage class gp3 binds on WaitForFirstConsumer: the volume is provisioned once a pod using the claim is scheduled\n" +
+		"  Normal WaitForFirstConsumer (x4): waiting for first consumer to be created before binding\n" +
+		"  Normal WaitForPodScheduled: waiting for pod web-0 to be scheduled\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	// Reported once, not on every check
+	out.Reset()
+	if err := d.check(&out, created.Add(2*time.Minute)); err != nil || out.Len() != 0 {
+		t.Errorf("second report: %v\n%s", err, out.String())
+	}
+}
+
+func TestStuckHint(t *testing.T) {
+	tests := []struct {
+		name    string
+		class   *string
+		classes []interface{}
+		want    string
+	}{
+		{"no default class", nil, nil, "no storage class given and the cluster has no default class"},
+		{"static binding", ptr(""), nil, `storageClassName is ""`},
+		{"missing class", ptr("fast"), nil, "storage class fast does not exist"},
+		{"immediate class", ptr("fast"), []interface{}{newStorageClass("fast", storagev1.VolumeBindingImmediate, false)}, "storage class fast (provisioner ebs.csi.aws.com)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pvc := claim("", corev1.ClaimPending)
+			pvc.Spec.StorageClassName = tt.class
+			d := newDetector(t, tt.classes...)
+			if got := d.hint(pvc); !strings.Contains(got, tt.want) {
+				t.Errorf("hint = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string { return &s }
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// involvedUIDIndex indexes events by the UID of the object they are about
+const involvedUIDIndex = "involvedUID"
+
+func eventInvolvedUIDIndexFunc(obj interface{}) ([]string, error) {
+	event := obj.(*corev1.Event)
+	return []string{string(event.InvolvedObject.UID)}, nil
+}
+
+// defaultClassAnnotation marks the StorageClass used by claims without
+// spec.storageClassName
+const defaultClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// stuckDetector reports claims that have been Pending for longer than
+// stuckAfter, once per claim, with the events recorded for them
+type stuckDetector struct {
+	pvcs       corelisters.PersistentVolumeClaimLister
+	classes    storagelisters.StorageClassLister
+	events     cache.Indexer
+	namespace  string
+	stuckAfter time.Duration
+
+	// reported is only used by check, which runs on one goroutine
+	reported map[types.UID]bool
+}
+
+// check prints every claim that became stuck since the last call. A claim
+// that leaves Pending and comes back is reported again.
+func (d *stuckDetector) check(w io.Writer, now time.Time) error {
+	pvcs, err := d.pvcs.PersistentVolumeClaims(d.namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(pvcs, func(a, b *corev1.PersistentVolumeClaim) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+	pending := make(map[types.UID]bool)
+	for _, pvc := range pvcs {
+		if pvc.Status.Phase != corev1.ClaimPending {
+			continue
+		}
+		pending[pvc.UID] = true
+		age := now.Sub(pvc.CreationTimestamp.Time)
+		if age < d.stuckAfter || d.reported[pvc.UID] {
+			continue
+		}
+		d.reported[pvc.UID] = true
+		fmt.Fprintf(w, "[Stuck] %s/%s: Pending for %s; %s\n", pvc.Namespace, pvc.Name, duration.HumanDuration(age), d.hint(pvc))
+		events, err := d.events.ByIndex(involvedUIDIndex, string(pvc.UID))
+		if err != nil {
+			return err
+		}
+		printEvents(w, events)
+	}
+	for uid := range d.reported {
+		if !pending[uid] {
+			delete(d.reported, uid)
+		}
+	}
+	return nil
+}
+
+// hint explains a Pending claim from its storage class, which is where most
+// claims get stuck: a class that does not exist, none at all, or one that
+// waits for a pod
+func (d *stuckDetector) hint(pvc *corev1.PersistentVolumeClaim) string {
+	var class *storagev1.StorageClass
+	if pvc.Spec.StorageClassName == nil {
+		classes, err := d.classes.List(labels.Everything())
+		if err != nil {
+			return "storage classes unknown: " + err.Error()
+		}
+		for _, c := range classes {
+			if c.Annotations[defaultClassAnnotation] == "true" {
+				class = c
+			}
+		}
+		if class == nil {
+			return "no storage class given and the cluster has no default class; it waits for a matching PV"
+		}
+	} else {
+		name := *pvc.Spec.StorageClassName
+		if name == "" {
+			return "storageClassName is \"\", so nothing is provisioned; it waits for a matching PV"
+		}
+		var err error
+		if class, err = d.classes.Get(name); err != nil {
+			return fmt.Sprintf("storage class %s does not exist", name)
+		}
+	}
+	if class.VolumeBindingMode != nil && *class.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+		return fmt.Sprintf("storage class %s binds on WaitForFirstConsumer: the volume is provisioned once a pod using the claim is scheduled", class.Name)
+	}
+	return fmt.Sprintf("storage class %s (provisioner %s)", class.Name, class.Provisioner)
+}
+
+// printEvents writes events oldest first, one line each
+func printEvents(w io.Writer, objs []interface{}) {
+	if len(objs) == 0 {
+		fmt.Fprintln(w, "  no events")
+		return
+	}
+	events := make([]*corev1.Event, 0, len(objs))
+	for _, obj := range objs {
+		events = append(events, obj.(*corev1.Event))
+	}
+	slices.SortFunc(events, func(a, b *corev1.Event) int { return eventTime(a).Compare(eventTime(b)) })
+	for _, e := range events {
+		count := ""
+		if e.Count > 1 {
+			count = fmt.Sprintf(" (x%d)", e.Count)
+		}
+		fmt.Fprintf(w, "  %s %s%s: %s\n", e.Type, e.Reason, count, e.Message)
+	}
+}
+
+// eventTime is when the event last occurred. Events written through the
+// events.k8s.io API only set eventTime.
+func eventTime(e *corev1.Event) time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+	return e.EventTime.Time
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+)
+
+// defaultClassAnnotation marks the StorageClass used by claims without
+// spec.storageClassName
+const defaultClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// noProvisioner is the provisioner of classes for statically created local
+// volumes; nothing runs for it on any node
+const noProvisioner = "kubernetes.io/no-provisioner"
+
+// inventory is the storage setup of the cluster, joined from the three caches
+type inventory struct {
+	classes []classInfo
+	drivers []driverInfo
+	nodes   []nodeInfo
+}
+
+// classInfo is one StorageClass and where its provisioner runs
+type classInfo struct {
+	class *storagev1.StorageClass
+	// nodes is the number of nodes whose CSINode lists the provisioner;
+	// -1 for provisioners that are not CSI drivers
+	nodes int
+}
+
+// driverInfo is one CSI driver. A driver does not need a CSIDriver object:
+// it only tunes how Kubernetes calls the driver. Drivers that are registered
+// on nodes without one are listed with object nil.
+type driverInfo struct {
+	name   string
+	object *storagev1.CSIDriver
+	nodes  []string
+}
+
+// nodeInfo is the CSI drivers the kubelet of one node has registered
+type nodeInfo struct {
+	name    string
+	drivers []storagev1.CSINodeDriver
+}
+
+// buildInventory joins StorageClasses, CSIDrivers and CSINodes by driver
+// name. A CSINode has the name of its node and is created by the kubelet.
+func buildInventory(classes []*storagev1.StorageClass, drivers []*storagev1.CSIDriver, csiNodes []*storagev1.CSINode) inventory {
+	nodesByDriver := map[string][]string{}
+	var inv inventory
+	for _, csiNode := range csiNodes {
+		node := nodeInfo{name: csiNode.Name, drivers: slices.Clone(csiNode.Spec.Drivers)}
+		slices.SortFunc(node.drivers, func(a, b storagev1.CSINodeDriver) int { return strings.Compare(a.Name, b.Name) })
+		for _, d := range node.drivers {
+			nodesByDriver[d.Name] = append(nodesByDriver[d.Name], csiNode.Name)
+		}
+		inv.nodes = append(inv.nodes, node)
+	}
+	slices.SortFunc(inv.nodes, func(a, b nodeInfo) int { return strings.Compare(a.name, b.name) })
+
+	known := map[string]bool{}
+	for _, d := range drivers {
+		known[d.Name] = true
+		inv.drivers = append(inv.drivers, driverInfo{name: d.Name, object: d, nodes: nodesByDriver[d.Name]})
+	}
+	for name, nodes := range nodesByDriver {
+		if !known[name] {
+			inv.drivers = append(inv.drivers, driverInfo{name: name, nodes: nodes})
+		}
+	}
+	slices.SortFunc(inv.drivers, func(a, b driverInfo) int { return strings.Compare(a.name, b.name) })
+	for i := range inv.drivers {
+		slices.Sort(inv.drivers[i].nodes)
+	}
+
+	for _, c := range classes {
+		info := classInfo{class: c, nodes: len(nodesByDriver[c.Provisioner])}
+		if isInTree(c.Provisioner) {
+			info.nodes = -1
+		}
+		inv.classes = append(inv.classes, info)
+	}
+	slices.SortFunc(inv.classes, func(a, b classInfo) int { return strings.Compare(a.class.Name, b.class.Name) })
+	return inv
+}
+
+// isInTree reports whether a provisioner is built into Kubernetes rather
+// than a CSI driver. In-tree names have the kubernetes.io/ prefix.
+func isInTree(provisioner string) bool {
+	return strings.HasPrefix(provisioner, "kubernetes.io/")
+}
+
+// warnings lists classes whose provisioner no node has registered. Volumes
+// of such a class may still be provisioned, but no pod can mount them.
+func (inv inventory) warnings() []string {
+	var warnings []string
+	for _, c := range inv.classes {
+		if c.nodes == 0 {
+			warnings = append(warnings, fmt.Sprintf("no node has registered driver %s, so volumes of class %s cannot be mounted", c.class.Provisioner, c.class.Name))
+		}
+	}
+	return warnings
+}
+
+// print writes the classes, drivers and nodes as three tables
+func (inv inventory) print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "STORAGECLASS\tPROVISIONER\tRECLAIM\tBINDING\tEXPANSION\tNODES")
+	for _, c := range inv.classes {
+		name := c.class.Name
+		if c.class.Annotations[defaultClassAnnotation] == "true" {
+			name += " (default)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", name, c.class.Provisioner, reclaimPolicy(c.class),
+			bindingMode(c.class), yesNo(c.class.AllowVolumeExpansion), classNodes(c, len(inv.nodes)))
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CSIDRIVER\tATTACH\tPODINFO\tCAPACITY\tFSGROUP\tMODES\tNODES")
+	for _, d := range inv.drivers {
+		if d.object == nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t-\t%d (no CSIDriver object)\n", d.name, len(d.nodes))
+			continue
+		}
+		spec := d.object.Spec
+		// An unset attachRequired means attach is required
+		attach := spec.AttachRequired == nil || *spec.AttachRequired
+		fsGroup := storagev1.ReadWriteOnceWithFSTypeFSGroupPolicy
+		if spec.FSGroupPolicy != nil {
+			fsGroup = *spec.FSGroupPolicy
+		}
+		modes := []string{string(storagev1.VolumeLifecyclePersistent)}
+		if len(spec.VolumeLifecycleModes) > 0 {
+			modes = modes[:0]
+			for _, m := range spec.VolumeLifecycleModes {
+				modes = append(modes, string(m))
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%s\t%s\t%s\t%d\n", d.name, attach, yesNo(spec.PodInfoOnMount),
+			yesNo(spec.StorageCapacity), fsGroup, strings.Join(modes, ","), len(d.nodes))
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tDRIVERS")
+	for _, n := range inv.nodes {
+		drivers := make([]string, 0, len(n.drivers))
+		for _, d := range n.drivers {
+			if d.Allocatable != nil && d.Allocatable.Count != nil {
+				drivers = append(drivers, fmt.Sprintf("%s (max %d volumes)", d.Name, *d.Allocatable.Count))
+			} else {
+				drivers = append(drivers, d.Name)
+			}
+		}
+		if len(drivers) == 0 {
+			drivers = append(drivers, "<none>")
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", n.name, strings.Join(drivers, ", "))
+	}
+	tw.Flush()
+
+	for _, warning := range inv.warnings() {
+		fmt.Fprintf(w, "WARNING: %s\n", warning)
+	}
+}
+
+// classNodes says on how many nodes the provisioner of a class is registered
+func classNodes(c classInfo, total int) string {
+	switch {
+	case c.class.Provisioner == noProvisioner:
+		return "static"
+	case c.nodes < 0:
+		return "in-tree"
+	}
+	return fmt.Sprintf("%d/%d", c.nodes, total)
+}
+
+func reclaimPolicy(c *storagev1.StorageClass) string {
+	if c.ReclaimPolicy == nil {
+		return string(corev1.PersistentVolumeReclaimDelete)
+	}
+	return string(*c.ReclaimPolicy)
+}
+
+func bindingMode(c *storagev1.StorageClass) string {
+	if c.VolumeBindingMode == nil {
+		return string(storagev1.VolumeBindingImmediate)
+	}
+	return string(*c.VolumeBindingMode)
+}
+
+func yesNo(b *bool) string {
+	if b != nil && *b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var watch = flag.Bool("watch", false, "print the report again whenever a class, driver or node registration changes")
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "51-storage-discovery")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// report renders the inventory from the three listers
+func report(factory informers.SharedInformerFactory) (string, error) {
+	storage := factory.Storage().V1()
+	classes, err := storage.StorageClasses().Lister().List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	drivers, err := storage.CSIDrivers().Lister().List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	csiNodes, err := storage.CSINodes().Lister().List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	buildInventory(classes, drivers, csiNodes).print(&out)
+	return out.String(), nil
+}
+
+// run prints the inventory once the caches synced. With watch it prints it
+// again whenever it changes, until ctx is cancelled. All three resources are
+// cluster-scoped, and small enough to cache in full.
+func run(ctx context.Context, clientset kubernetes.Interface, watch bool) error {
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { notify() },
+		DeleteFunc: func(obj interface{}) { notify() },
+	}
+	for _, informer := range []cache.SharedIndexInformer{
+		factory.Storage().V1().StorageClasses().Informer(),
+		factory.Storage().V1().CSIDrivers().Informer(),
+		factory.Storage().V1().CSINodes().Informer(),
+	} {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer factory.Shutdown()
+	defer cancel()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+	// The first report does not wait for an event
+	notify()
+
+	last := ""
+	for {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+		out, err := report(factory)
+		if err != nil {
+			return err
+		}
+		if out != last {
+			if last != "" {
+				fmt.Println("---")
+			}
+			fmt.Print(out)
+			last = out
+		}
+		if !watch {
+			return nil
+		}
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, clientset, *watch); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func class(name, provisioner string, expansion bool) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: name},
+		Provisioner:          provisioner,
+		AllowVolumeExpansion: &expansion,
+	}
+}
+
+func csiNode(name string, drivers ...string) *storagev1.CSINode {
+	n := &storagev1.CSINode{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	for _, d := range drivers {
+		n.Spec.Drivers = append(n.Spec.Drivers, storagev1.CSINodeDriver{Name: d, NodeID: name})
+	}
+	return n
+}
+
+// cluster has an EBS driver on two of three nodes, an NFS driver without a
+// CSIDriver object, and a class whose driver is not installed
+func cluster() []*storagev1.StorageClass {
+	gp3 := class("gp3", "ebs.csi.aws.com", true)
+	gp3.Annotations = map[string]string{defaultClassAnnotation: "true"}
+	wait := storagev1.VolumeBindingWaitForFirstConsumer
+	gp3.VolumeBindingMode = &wait
+	retain := corev1.PersistentVolumeReclaimRetain
+	nfs := class("nfs", "nfs.csi.k8s.io", false)
+	nfs.ReclaimPolicy = &retain
+	return []*storagev1.StorageClass{
+		nfs, gp3,
+		class("local", noProvisioner, false),
+		class("legacy", "kubernetes.io/aws-ebs", false),
+		class("fast", "pd.csi.storage.gke.io", true),
+	}
+}
+
+func ebsDriver() *storagev1.CSIDriver {
+	attach, capacity := true, false
+	policy := storagev1.FileFSGroupPolicy
+	return &storagev1.CSIDriver{
+		ObjectMeta: metav1.ObjectMeta{Name: "ebs.csi.aws.com"},
+		Spec: storagev1.CSIDriverSpec{
+			AttachRequired:  &attach,
+			StorageCapacity: &capacity,
+			FSGroupPolicy:   &policy,
+		},
+	}
+}
+
+func TestBuildInventory(t *testing.T) {
+	count := int32(25)
+	nodeB := csiNode("node-b", "nfs.csi.k8s.io", "ebs.csi.aws.com")
+	nodeB.Spec.Drivers[1].Allocatable = &storagev1.VolumeNodeResources{Count: &count}
+	inv := buildInventory(cluster(), []*storagev1.CSIDriver{ebsDriver()},
+		[]*storagev1.CSINode{csiNode("node-c"), nodeB, csiNode("node-a", "ebs.csi.aws.com")})
+
+	var out bytes.Buffer
+	inv.print(&out)
+	// Compare with the table's padding collapsed to single spaces
+	var lines []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	got := strings.Join(lines, "\n")
+	want := `STORAGECLASS PROVISIONER RECLAIM BINDING EXPANSION NODES
+fast pd.csi.storage.gke.io Delete Immediate yes 0/3
+gp3 (default) ebs.csi.aws.com Delete WaitForFirstConsumer yes 2/3
+legacy kubernetes.io/aws-ebs Delete Immediate no in-tree
+local kubernetes.io/no-provisioner Delete Immediate no static
+nfs nfs.csi.k8s.io Retain Immediate no 1/3
+
+CSIDRIVER ATTACH PODINFO CAPACITY FSGROUP MODES NODES
+ebs.csi.aws.com true no no File Persistent 2
+nfs.csi.k8s.io - - - - - 1 (no CSIDriver object)
+
+NODE DRIVERS
+node-a ebs.csi.aws.com
+node-b ebs.csi.aws.com (max 25 volumes), nfs.csi.k8s.io
+node-c <none>
+WARNING: no node has registered driver pd.csi.storage.gke.io, so volumes of class fast cannot be mounted
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	output := testutil.CaptureOutput(t)
+	clientset := fake.NewSimpleClientset(class("gp3", "ebs.csi.aws.com", true), ebsDriver(), csiNode("node-a", "ebs.csi.aws.com"))
+	if err := run(context.Background(), clientset, false); err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForOutput(t, output, "gp3           ebs.csi.aws.com  Delete   Immediate  yes        1/1\n")
+}
+
+func TestRunWatchesNodeRegistrations(t *testing.T) {
+	h := testutil.NewHarness(t, class("gp3", "ebs.csi.aws.com", true), csiNode("node-a"))
+	output := testutil.CaptureOutput(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, h.Clientset, true) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("run: %v", err)
+		}
+	}()
+	testutil.WaitForOutput(t, output, "WARNING: no node has registered driver ebs.csi.aws.com")
+
+	// The driver's node plugin starts and the kubelet registers it
+	h.WaitForWatchOf(&storagev1.CSINode{}, 1)
+	h.Update(csiNode("node-a", "ebs.csi.aws.com"))
+	testutil.WaitForOutput(t, output, "---\n", "node-a  ebs.csi.aws.com\n")
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace     = flag.String("namespace", "", "namespace of the Ingresses (empty for all namespaces)")
+	warnDays      = flag.Int("warn-days", 30, "warn about certificates expiring within this many days")
+	checkInterval = flag.Duration("check-interval", time.Hour, "how often every certificate is evaluated again, as expiry approaches")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "52-ingress-tls")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// checker keeps the last status of every TLS entry and prints the entries
+// whose status changed. Ingress and secret handlers run on different
+// goroutines, so last is guarded by mu.
+type checker struct {
+	ingresses cache.Indexer
+	secrets   corelisters.SecretLister
+	warnDays  int
+	now       func() time.Time
+	out       io.Writer
+
+	mu   sync.Mutex
+	last map[string]map[string]tlsStatus // ingress key -> entry key -> status
+}
+
+// checkIngress evaluates one Ingress by its namespace/name key. Entries that
+// are new or changed state are printed; so are entries that went away.
+func (c *checker) checkIngress(key string) {
+	obj, exists, err := c.ingresses.GetByKey(key)
+	if err != nil {
+		return
+	}
+	current := map[string]tlsStatus{}
+	if exists {
+		for _, s := range evaluate(obj.(*networkingv1.Ingress), c.secrets, c.now(), c.warnDays) {
+			current[s.key()] = s
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.last[key]
+	for _, k := range sortedKeys(current) {
+		s := current[k]
+		if old, ok := previous[k]; !ok || old.State != s.State {
+			fmt.Fprintf(c.out, "[TLS] %s\n", s)
+		}
+	}
+	for _, k := range sortedKeys(previous) {
+		if _, ok := current[k]; !ok {
+			fmt.Fprintf(c.out, "[TLS] %s secret %s: removed\n", previous[k].Ingress, previous[k].Secret)
+		}
+	}
+	if len(current) == 0 {
+		delete(c.last, key)
+	} else {
+		c.last[key] = current
+	}
+}
+
+// checkAll evaluates every cached Ingress
+func (c *checker) checkAll() {
+	keys := c.ingresses.ListKeys()
+	slices.Sort(keys)
+	for _, key := range keys {
+		c.checkIngress(key)
+	}
+}
+
+// ingressHandler evaluates an Ingress whenever it changes
+func (c *checker) ingressHandler() cache.ResourceEventHandlerFuncs {
+	check := func(obj interface{}) {
+		if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+			c.checkIngress(key)
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    check,
+		UpdateFunc: func(oldObj, newObj interface{}) { check(newObj) },
+		DeleteFunc: check,
+	}
+}
+
+// secretHandler evaluates the Ingresses that reference a changed secret,
+// found through ingressSecretIndex. A renewed certificate, or a secret that
+// is created after its Ingress, shows up right away.
+func (c *checker) secretHandler() cache.ResourceEventHandlerFuncs {
+	check := func(obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil {
+			return
+		}
+		ingresses, err := c.ingresses.ByIndex(ingressSecretIndex, key)
+		if err != nil {
+			return
+		}
+		for _, ing := range ingresses {
+			if ingKey, err := cache.MetaNamespaceKeyFunc(ing); err == nil {
+				c.checkIngress(ingKey)
+			}
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    check,
+		UpdateFunc: func(oldObj, newObj interface{}) { check(newObj) },
+		DeleteFunc: check,
+	}
+}
+
+// startChecker wires the Ingress and secret informers of one shared factory
+// to a checker and starts them. The secret informer only keeps tls.crt.
+func startChecker(clientset kubernetes.Interface, namespace string, warnDays int, out io.Writer, stopCh <-chan struct{}) (*checker, informers.SharedInformerFactory, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	ingressInformer := factory.Networking().V1().Ingresses().Informer()
+	if err := ingressInformer.AddIndexers(cache.Indexers{ingressSecretIndex: ingressSecretIndexFunc}); err != nil {
+		return nil, nil, err
+	}
+	secretInformer := factory.Core().V1().Secrets().Informer()
+	if err := secretInformer.SetTransform(stripPrivateKey); err != nil {
+		return nil, nil, err
+	}
+
+	c := &checker{
+		ingresses: ingressInformer.GetIndexer(),
+		secrets:   factory.Core().V1().Secrets().Lister(),
+		warnDays:  warnDays,
+		now:       time.Now,
+		out:       out,
+		last:      make(map[string]map[string]tlsStatus),
+	}
+	// Secrets are synced first, so that the first evaluation of each
+	// Ingress already finds its secrets
+	factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, secretInformer.HasSynced) {
+		return nil, nil, fmt.Errorf("secret cache did not sync")
+	}
+	if _, err := ingressInformer.AddEventHandler(c.ingressHandler()); err != nil {
+		return nil, nil, err
+	}
+	if _, err := secretInformer.AddEventHandler(c.secretHandler()); err != nil {
+		return nil, nil, err
+	}
+	return c, factory, nil
+}
+
+func sortedKeys(m map[string]tlsStatus) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	c, factory, err := startChecker(clientset, *namespace, *warnDays, os.Stdout, ctx.Done())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer factory.Shutdown()
+	factory.WaitForCacheSync(ctx.Done())
+	fmt.Printf("Watching Ingress TLS certificates; warning %d days before expiry\n", *warnDays)
+
+	// Certificates approach expiry without any event, so every entry is
+	// evaluated again on a timer; only entries that changed state print
+	ticker := time.NewTicker(*checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.checkAll()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
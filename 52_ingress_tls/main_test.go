@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+// now is fixed so the day counts do not depend on when the tests run
+var now = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// tlsSecret returns a kubernetes.io/tls secret holding a self-signed
+// certificate for hosts, and a private key
+func tlsSecret(t *testing.T, name string, notAfter time.Time, hosts ...string) *corev1.Secret {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		DNSNames:     hosts,
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		},
+	}
+}
+
+func ingress(name string, tls ...networkingv1.IngressTLS) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Spec:       networkingv1.IngressSpec{TLS: tls},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	secrets := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, s := range []*corev1.Secret{
+		tlsSecret(t, "web-tls", now.Add(90*24*time.Hour), "shop.example.com", "*.shop.example.com"),
+		tlsSecret(t, "api-tls", now.Add(10*24*time.Hour), "api.example.com"),
+		tlsSecret(t, "old-tls", now.Add(-time.Hour), "old.example.com"),
+		{ObjectMeta: metav1.ObjectMeta{Name: "broken-tls", Namespace: "shop"}, Data: map[string][]byte{corev1.TLSCertKey: []byte("not pem")}},
+	} {
+		if err := secrets.Add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ing := ingress("web",
+		networkingv1.IngressTLS{Hosts: []string{"shop.example.com", "www.shop.example.com"}, SecretName: "web-tls"},
+		networkingv1.IngressTLS{Hosts: []string{"api.example.com"}, SecretName: "api-tls"},
+		networkingv1.IngressTLS{Hosts: []string{"admin.example.com"}, SecretName: "web-tls"},
+		networkingv1.IngressTLS{Hosts: []string{"old.example.com"}, SecretName: "old-tls"},
+		networkingv1.IngressTLS{Hosts: []string{"x.example.com"}, SecretName: "broken-tls"},
+		networkingv1.IngressTLS{Hosts: []string{"y.example.com"}, SecretName: "gone-tls"},
+		networkingv1.IngressTLS{Hosts: []string{"z.example.com"}},
+	)
+
+	want := []string{
+		"shop/web secret web-tls hosts shop.example.com,www.shop.example.com: ok (expires 2027-01-13, 90 days)",
+		"shop/web secret api-tls hosts api.example.com: warning (expires 2026-10-25, 10 days)",
+		"shop/web secret web-tls hosts admin.example.com: mismatch (certificate does not cover admin.example.com; expires 2027-01-13, 90 days)",
+		"shop/web secret old-tls hosts old.example.com: expired (expired 2026-10-15)",
+		"shop/web secret broken-tls hosts x.example.com: invalid (tls.crt contains no PEM data)",
+		"shop/web secret gone-tls hosts y.example.com: missing (secret does not exist)",
+		"shop/web secret <none> hosts z.example.com: default (no secretName, the ingress controller serves its default certificate)",
+	}
+	got := evaluate(ing, corelisters.NewSecretLister(secrets), now, 30)
+	if len(got) != len(want) {
+		t.Fatalf("got %d statuses, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("entry %d:\n got %s\nwant %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStripPrivateKey(t *testing.T) {
+	secret := tlsSecret(t, "web-tls", now, "shop.example.com")
+	obj, err := stripPrivateKey(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := obj.(*corev1.Secret).Data
+	if _, ok := data[corev1.TLSPrivateKeyKey]; ok || len(data) != 1 || data[corev1.TLSCertKey] == nil {
+		t.Errorf("cached data keys = %v, want only tls.crt", data)
+	}
+}
+
+func TestRenewalIsFoundThroughTheSecretIndex(t *testing.T) {
+	soon := time.Now().Add(5 * 24 * time.Hour)
+	h := testutil.NewHarness(t,
+		tlsSecret(t, "web-tls", soon, "shop.example.com"),
+		ingress("web", networkingv1.IngressTLS{Hosts: []string{"shop.example.com"}, SecretName: "web-tls"}),
+		ingress("blog", networkingv1.IngressTLS{Hosts: []string{"blog.example.com"}, SecretName: "blog-tls"}),
+	)
+	output := testutil.CaptureOutput(t)
+
+	stopCh := make(chan struct{})
+	_, factory, err := startChecker(h.Clientset, "shop", 30, os.Stdout, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		close(stopCh)
+		factory.Shutdown()
+	}()
+	testutil.WaitForOutput(t, output,
+		"[TLS] shop/web secret web-tls hosts shop.example.com: warning (expires "+soon.UTC().Format(time.DateOnly)+", 4 days)",
+		"[TLS] shop/blog secret blog-tls hosts blog.example.com: missing (secret does not exist)",
+	)
+
+	// cert-manager renews the certificate, and the blog secret is created
+	h.WaitForWatchOf(&corev1.Secret{}, 1)
+	renewed := time.Now().Add(90 * 24 * time.Hour)
+	h.Update(tlsSecret(t, "web-tls", renewed, "shop.example.com"))
+	h.Add(tlsSecret(t, "blog-tls", renewed, "blog.example.com"))
+	testutil.WaitForOutput(t, output,
+		"[TLS] shop/web secret web-tls hosts shop.example.com: ok (expires "+renewed.UTC().Format(time.DateOnly)+", 89 days)",
+		"[TLS] shop/blog secret blog-tls hosts blog.example.com: ok",
+	)
+
+	h.WaitForWatchOf(&networkingv1.Ingress{}, 1)
+	h.Delete(ingress("blog", networkingv1.IngressTLS{Hosts: []string{"blog.example.com"}, SecretName: "blog-tls"}))
+	testutil.WaitForOutput(t, output, "[TLS] shop/blog secret blog-tls: removed")
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// ingressSecretIndex indexes Ingresses by namespace/name of every TLS secret
+// they reference. A changed secret finds its Ingresses through it.
+const ingressSecretIndex = "tlsSecret"
+
+func ingressSecretIndexFunc(obj interface{}) ([]string, error) {
+	ing := obj.(*networkingv1.Ingress)
+	var keys []string
+	for _, tls := range ing.Spec.TLS {
+		if tls.SecretName != "" {
+			keys = append(keys, ing.Namespace+"/"+tls.SecretName)
+		}
+	}
+	return keys, nil
+}
+
+// stripPrivateKey is the transform of the secret informer. Only the
+// certificate is needed, so tls.key and all other data never enter the
+// cache, and a secret costs a few kilobytes at most.
+func stripPrivateKey(obj interface{}) (interface{}, error) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return obj, nil
+	}
+	secret.ManagedFields = nil
+	if cert, ok := secret.Data[corev1.TLSCertKey]; ok {
+		secret.Data = map[string][]byte{corev1.TLSCertKey: cert}
+	} else {
+		secret.Data = nil
+	}
+	secret.StringData = nil
+	return secret, nil
+}
+
+// TLS entry states, from worst to best
+const (
+	stateMissing  = "missing"
+	stateInvalid  = "invalid"
+	stateExpired  = "expired"
+	stateMismatch = "mismatch"
+	stateWarning  = "warning"
+	stateOK       = "ok"
+	stateDefault  = "default"
+)
+
+// tlsStatus is the certificate of one TLS entry of an Ingress
+type tlsStatus struct {
+	Ingress  string // namespace/name
+	Secret   string
+	Hosts    []string
+	NotAfter time.Time
+	Days     int
+	State    string
+	Detail   string
+}
+
+// key identifies the TLS entry across evaluations
+func (s tlsStatus) key() string {
+	return s.Ingress + "|" + s.Secret + "|" + strings.Join(s.Hosts, ",")
+}
+
+// String is the line printed for the entry
+func (s tlsStatus) String() string {
+	secret := s.Secret
+	if secret == "" {
+		secret = "<none>"
+	}
+	return fmt.Sprintf("%s secret %s hosts %s: %s (%s)", s.Ingress, secret, strings.Join(s.Hosts, ","), s.State, s.Detail)
+}
+
+// leafCertificate parses the first certificate in tls.crt
+func leafCertificate(secret *corev1.Secret) (*x509.Certificate, error) {
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return nil, errors.New("tls.crt contains no PEM data")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// evaluate resolves the TLS secrets of the Ingress through the secret
+// lister and checks each certificate: that it parses, when it expires, and
+// that it covers the hosts the entry lists
+func evaluate(ing *networkingv1.Ingress, secrets corelisters.SecretLister, now time.Time, warnDays int) []tlsStatus {
+	statuses := make([]tlsStatus, 0, len(ing.Spec.TLS))
+	for _, tls := range ing.Spec.TLS {
+		s := tlsStatus{Ingress: ing.Namespace + "/" + ing.Name, Secret: tls.SecretName, Hosts: tls.Hosts}
+		statuses = append(statuses, s)
+		st := &statuses[len(statuses)-1]
+		if tls.SecretName == "" {
+			st.State, st.Detail = stateDefault, "no secretName, the ingress controller serves its default certificate"
+			continue
+		}
+		secret, err := secrets.Secrets(ing.Namespace).Get(tls.SecretName)
+		if apierrors.IsNotFound(err) {
+			st.State, st.Detail = stateMissing, "secret does not exist"
+			continue
+		}
+		if err != nil {
+			st.State, st.Detail = stateInvalid, err.Error()
+			continue
+		}
+		cert, err := leafCertificate(secret)
+		if err != nil {
+			st.State, st.Detail = stateInvalid, err.Error()
+			continue
+		}
+		st.NotAfter = cert.NotAfter
+		st.Days = int(cert.NotAfter.Sub(now).Hours() / 24)
+		expiry := fmt.Sprintf("expires %s, %d days", cert.NotAfter.UTC().Format(time.DateOnly), st.Days)
+		uncovered := slices.DeleteFunc(slices.Clone(tls.Hosts), func(host string) bool { return cert.VerifyHostname(host) == nil })
+		switch {
+		case !now.Before(cert.NotAfter):
+			st.State, st.Detail = stateExpired, "expired "+cert.NotAfter.UTC().Format(time.DateOnly)
+		case len(uncovered) > 0:
+			st.State, st.Detail = stateMismatch, fmt.Sprintf("certificate does not cover %s; %s", strings.Join(uncovered, ","), expiry)
+		case st.Days < warnDays:
+			st.State, st.Detail = stateWarning, expiry
+		default:
+			st.State, st.Detail = stateOK, expiry
+		}
+	}
+	return statuses
+}
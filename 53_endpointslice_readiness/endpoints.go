@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// endpointRow is one address of the Service, with its conditions and the pod
+// it points at
+type endpointRow struct {
+	Address     string
+	Pod         string
+	Node        string
+	Ready       bool
+	Serving     bool
+	Terminating bool
+	PodPhase    string // empty when the pod is not in the cache
+	PodReady    string
+	Note        string
+}
+
+// conditions applies the defaults of the API: nil ready and serving mean
+// true, nil terminating means false
+func conditions(c discoveryv1.EndpointConditions) (ready, serving, terminating bool) {
+	ready, serving = true, true
+	if c.Ready != nil {
+		ready = *c.Ready
+	}
+	if c.Serving != nil {
+		serving = *c.Serving
+	}
+	if c.Terminating != nil {
+		terminating = *c.Terminating
+	}
+	return ready, serving, terminating
+}
+
+// podReadyCondition returns the status of the pod's Ready condition, and its
+// reason when it is not True
+func podReadyCondition(pod *corev1.Pod) (string, string) {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return string(c.Status), c.Reason
+		}
+	}
+	return string(corev1.ConditionUnknown), ""
+}
+
+// buildRows lists every address of the slices, and looks the pod of each up
+// in the pod lister. An address that appears in two slices, as it can while
+// the controller moves endpoints between slices, is listed once.
+func buildRows(endpointSlices []*discoveryv1.EndpointSlice, pods corelisters.PodLister) ([]endpointRow, error) {
+	endpointSlices = slices.Clone(endpointSlices)
+	slices.SortFunc(endpointSlices, func(a, b *discoveryv1.EndpointSlice) int { return strings.Compare(a.Name, b.Name) })
+
+	var rows []endpointRow
+	seen := map[string]bool{}
+	for _, slice := range endpointSlices {
+		for _, ep := range slice.Endpoints {
+			if len(ep.Addresses) == 0 || seen[ep.Addresses[0]] {
+				continue
+			}
+			seen[ep.Addresses[0]] = true
+			row := endpointRow{Address: ep.Addresses[0]}
+			row.Ready, row.Serving, row.Terminating = conditions(ep.Conditions)
+			if ep.NodeName != nil {
+				row.Node = *ep.NodeName
+			}
+
+			var pod *corev1.Pod
+			if ref := ep.TargetRef; ref != nil && ref.Kind == "Pod" {
+				row.Pod = ref.Name
+				ns := ref.Namespace
+				if ns == "" {
+					ns = slice.Namespace
+				}
+				p, err := pods.Pods(ns).Get(ref.Name)
+				if err != nil && !apierrors.IsNotFound(err) {
+					return nil, err
+				}
+				pod = p
+			}
+			row.Note = note(&row, pod)
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b endpointRow) int {
+		return strings.Compare(a.Pod+"/"+a.Address, b.Pod+"/"+b.Address)
+	})
+	return rows, nil
+}
+
+// note fills the pod columns of the row and explains whether the address
+// gets traffic. The conditions come from the EndpointSlice controller, which
+// derives them from the pod; the pod columns show that source.
+func note(row *endpointRow, pod *corev1.Pod) string {
+	if pod != nil {
+		row.PodPhase = string(pod.Status.Phase)
+		row.PodReady, _ = podReadyCondition(pod)
+	}
+	switch {
+	case row.Ready && pod != nil && row.PodReady != string(corev1.ConditionTrue):
+		return "gets traffic although the pod is not ready (publishNotReadyAddresses)"
+	case row.Ready:
+		return "gets traffic"
+	case row.Terminating && row.Serving:
+		return "draining: terminating, gets traffic only when no endpoint is ready"
+	case row.Terminating:
+		return "terminating, no traffic"
+	case pod == nil && row.Pod != "":
+		return "no traffic: pod is not in the cache"
+	case pod == nil:
+		return "no traffic"
+	case pod.Status.Phase != corev1.PodRunning:
+		return fmt.Sprintf("no traffic: pod is %s", pod.Status.Phase)
+	}
+	if _, reason := podReadyCondition(pod); reason != "" {
+		return "no traffic: pod not ready (" + reason + ")"
+	}
+	return "no traffic: pod not ready"
+}
+
+// servicePorts renders the ports of the first slice that has any; all slices
+// of a Service carry the same ports unless the Service is being changed
+func servicePorts(endpointSlices []*discoveryv1.EndpointSlice) string {
+	for _, slice := range endpointSlices {
+		var ports []string
+		for _, p := range slice.Ports {
+			port := ""
+			if p.Name != nil && *p.Name != "" {
+				port = *p.Name + " "
+			}
+			if p.Port != nil {
+				port += fmt.Sprint(*p.Port)
+			}
+			if p.Protocol != nil {
+				port += "/" + string(*p.Protocol)
+			}
+			ports = append(ports, port)
+		}
+		if len(ports) > 0 {
+			return strings.Join(ports, ", ")
+		}
+	}
+	return "<none>"
+}
+
+// printService writes the summary line of the Service and a row per address
+func printService(w io.Writer, svc *corev1.Service, endpointSlices []*discoveryv1.EndpointSlice, rows []endpointRow) {
+	ready, terminating := 0, 0
+	for _, r := range rows {
+		if r.Ready {
+			ready++
+		}
+		if r.Terminating {
+			terminating++
+		}
+	}
+	fmt.Fprintf(w, "Service %s/%s: %d/%d endpoints ready, %d terminating, %d slices, ports %s",
+		svc.Namespace, svc.Name, ready, len(rows), terminating, len(endpointSlices), servicePorts(endpointSlices))
+	if svc.Spec.PublishNotReadyAddresses {
+		fmt.Fprint(w, " (publishNotReadyAddresses)")
+	}
+	fmt.Fprintln(w)
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "  no endpoints")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ADDRESS\tPOD\tNODE\tREADY\tSERVING\tTERMINATING\tPOD PHASE\tPOD READY\tNOTE")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%t\t%t\t%t\t%s\t%s\t%s\n",
+			r.Address, dash(r.Pod), dash(r.Node), r.Ready, r.Serving, r.Terminating, dash(r.PodPhase), dash(r.PodReady), r.Note)
+	}
+	tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
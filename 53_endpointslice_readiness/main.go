@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace = flag.String("namespace", "default", "namespace of the Service")
+	service   = flag.String("service", "", "name of the Service whose endpoints are tracked")
+	once      = flag.Bool("once", false, "print the report once after the caches synced and exit")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "53-endpointslice-readiness")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// report renders the Service's endpoints from the listers. The slices of a
+// Service carry the kubernetes.io/service-name label, so they are selected
+// from the namespace's slices by label.
+func report(factory informers.SharedInformerFactory, namespace, service string) (string, error) {
+	svc, err := factory.Core().V1().Services().Lister().Services(namespace).Get(service)
+	if apierrors.IsNotFound(err) {
+		return fmt.Sprintf("Service %s/%s: not found\n", namespace, service), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	selector := labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: service})
+	endpointSlices, err := factory.Discovery().V1().EndpointSlices().Lister().EndpointSlices(namespace).List(selector)
+	if err != nil {
+		return "", err
+	}
+	rows, err := buildRows(endpointSlices, factory.Core().V1().Pods().Lister())
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	printService(&out, svc, endpointSlices, rows)
+	return out.String(), nil
+}
+
+// run prints the report whenever the Service, one of the namespace's
+// EndpointSlices or one of its pods changes, as long as the report itself
+// changed. A pod's readiness reaches the slice through the EndpointSlice
+// controller, so a report usually follows the pod event and then the slice
+// event.
+func run(ctx context.Context, clientset kubernetes.Interface, namespace, service string, once bool) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { notify() },
+		DeleteFunc: func(obj interface{}) { notify() },
+	}
+	for _, informer := range []cache.SharedIndexInformer{
+		factory.Core().V1().Services().Informer(),
+		factory.Discovery().V1().EndpointSlices().Informer(),
+		factory.Core().V1().Pods().Informer(),
+	} {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer factory.Shutdown()
+	defer cancel()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+	// The first report does not wait for an event
+	notify()
+
+	last := ""
+	for {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+		out, err := report(factory, namespace, service)
+		if err != nil {
+			return err
+		}
+		if out != last {
+			if last != "" {
+				fmt.Println("---")
+			}
+			fmt.Print(out)
+			last = out
+		}
+		if once {
+			return nil
+		}
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+	if *service == "" {
+		log.Fatalf("--service is required")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, clientset, *namespace, *service, *once); err != nil {
+		log.Fatal(err)
+	}
+}
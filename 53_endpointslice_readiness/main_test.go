@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func webService() *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+	}
+}
+
+func pod(name string, phase corev1.PodPhase, ready corev1.ConditionStatus, reason string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{"app": "web"}},
+		Status: corev1.PodStatus{
+			Phase:      phase,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready, Reason: reason}},
+		},
+	}
+}
+
+// endpoint returns the endpoint of a pod as the EndpointSlice controller
+// writes it
+func endpoint(address, podName, nodeName string, ready, serving, terminating bool) discoveryv1.Endpoint {
+	return discoveryv1.Endpoint{
+		Addresses:  []string{address},
+		NodeName:   ptr.To(nodeName),
+		TargetRef:  &corev1.ObjectReference{Kind: "Pod", Namespace: "shop", Name: podName},
+		Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(ready), Serving: ptr.To(serving), Terminating: ptr.To(terminating)},
+	}
+}
+
+func slice(name string, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta:  metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{discoveryv1.LabelServiceName: "web"}},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints:   endpoints,
+		Ports:       []discoveryv1.EndpointPort{{Name: ptr.To("http"), Port: ptr.To(int32(8080)), Protocol: ptr.To(corev1.ProtocolTCP)}},
+	}
+}
+
+func TestBuildRows(t *testing.T) {
+	pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, p := range []*corev1.Pod{
+		pod("web-a", corev1.PodRunning, corev1.ConditionTrue, ""),
+		pod("web-b", corev1.PodRunning, corev1.ConditionFalse, "ContainersNotReady"),
+		pod("web-c", corev1.PodRunning, corev1.ConditionTrue, ""),
+		pod("web-d", corev1.PodRunning, corev1.ConditionFalse, "ContainersNotReady"),
+		pod("web-e", corev1.PodPending, corev1.ConditionFalse, ""),
+	} {
+		if err := pods.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	endpointSlices := []*discoveryv1.EndpointSlice{
+		slice("web-y",
+			endpoint("10.0.1.5", "web-e", "node-2", false, false, false),
+			// moved to web-x; listed once
+			endpoint("10.0.0.4", "web-a", "node-1", true, true, false),
+			endpoint("10.0.1.9", "web-gone", "node-2", false, false, false),
+		),
+		slice("web-x",
+			endpoint("10.0.0.4", "web-a", "node-1", true, true, false),
+			endpoint("10.0.0.7", "web-b", "node-1", false, false, false),
+			endpoint("10.0.1.2", "web-c", "node-2", false, true, true),
+			endpoint("10.0.1.3", "web-d", "node-2", false, false, true),
+			// conditions left out are defaulted
+			discoveryv1.Endpoint{Addresses: []string{"192.168.0.10"}},
+		),
+	}
+	rows, err := buildRows(endpointSlices, corelisters.NewPodLister(pods))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	printService(&out, webService(), endpointSlices, rows)
+
+	want := `Service shop/web: 2/7 endpoints ready, 2 terminating, 2 slices, ports http 8080/TCP
+ADDRESS POD NODE READY SERVING TERMINATING POD PHASE POD READY NOTE
+192.168.0.10 - - true true false - - gets traffic
+10.0.0.4 web-a node-1 true true false Running True gets traffic
+10.0.0.7 web-b node-1 false false false Running False no traffic: pod not ready (ContainersNotReady)
+10.0.1.2 web-c node-2 false true true Running True draining: terminating, gets traffic only when no endpoint is ready
+10.0.1.3 web-d node-2 false false true Running False terminating, no traffic
+10.0.1.5 web-e node-2 false false false Pending False no traffic: pod is Pending
+10.0.1.9 web-gone node-2 false false false - - no traffic: pod is not in the cache
+`
+	if got := testutil.Collapse(out.String()); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPublishNotReadyAddresses(t *testing.T) {
+	pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	if err := pods.Add(pod("web-a", corev1.PodRunning, corev1.ConditionFalse, "ContainersNotReady")); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := buildRows([]*discoveryv1.EndpointSlice{slice("web-x", endpoint("10.0.0.4", "web-a", "node-1", true, false, false))},
+		corelisters.NewPodLister(pods))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "gets traffic although the pod is not ready (publishNotReadyAddresses)"; rows[0].Note != want {
+		t.Errorf("note = %q, want %q", rows[0].Note, want)
+	}
+}
+
+func TestRunFollowsTermination(t *testing.T) {
+	h := testutil.NewHarness(t,
+		webService(),
+		pod("web-a", corev1.PodRunning, corev1.ConditionTrue, ""),
+		slice("web-x", endpoint("10.0.0.4", "web-a", "node-1", true, true, false)),
+	)
+	output := testutil.CaptureOutput(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, h.Clientset, "shop", "web", false) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("run: %v", err)
+		}
+	}()
+	testutil.WaitForOutput(t, output, "Service shop/web: 1/1 endpoints ready, 0 terminating")
+
+	// The pod is deleted; the controller marks its endpoint terminating while
+	// it still passes its readiness probe
+	h.WaitForWatchOf(&discoveryv1.EndpointSlice{}, 1)
+	h.Update(slice("web-x", endpoint("10.0.0.4", "web-a", "node-1", false, true, true)))
+	testutil.WaitForOutput(t, output, "---\n", "Service shop/web: 0/1 endpoints ready, 1 terminating", "draining: terminating")
+
+	h.Update(slice("web-x"))
+	testutil.WaitForOutput(t, output, "Service shop/web: 0/0 endpoints ready, 0 terminating, 1 slices, ports http 8080/TCP\n  no endpoints\n")
+}
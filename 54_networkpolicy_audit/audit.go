@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// podCoverage lists the policies that isolate a pod, per direction
+type podCoverage struct {
+	Pod         string
+	Ingress     []string
+	Egress      []string
+	HostNetwork bool
+}
+
+// unprotected is true when no policy applies to the pod at all
+func (c podCoverage) unprotected() bool {
+	return c.HostNetwork || len(c.Ingress) == 0 && len(c.Egress) == 0
+}
+
+// namespaceAudit is the coverage of every running pod of a namespace
+type namespaceAudit struct {
+	Namespace string
+	Policies  int
+	Pods      []podCoverage
+	Unused    []string // policies that select no pod
+}
+
+// policyTypes returns the directions a policy isolates. When policyTypes is
+// not set, a policy always isolates ingress, and egress only if it has
+// egress rules.
+func policyTypes(np *networkingv1.NetworkPolicy) (ingress, egress bool) {
+	if len(np.Spec.PolicyTypes) == 0 {
+		return true, len(np.Spec.Egress) > 0
+	}
+	for _, t := range np.Spec.PolicyTypes {
+		switch t {
+		case networkingv1.PolicyTypeIngress:
+			ingress = true
+		case networkingv1.PolicyTypeEgress:
+			egress = true
+		}
+	}
+	return ingress, egress
+}
+
+// auditNamespace matches the pod selector of every policy against the pods of
+// the namespace. A policy only selects pods of its own namespace; an empty
+// selector selects all of them. Pods that finished are left out, they have
+// no traffic to protect.
+func auditNamespace(namespace string, policies []*networkingv1.NetworkPolicy, pods []*corev1.Pod) (namespaceAudit, error) {
+	a := namespaceAudit{Namespace: namespace, Policies: len(policies)}
+	var running []*corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			running = append(running, pod)
+		}
+	}
+	slices.SortFunc(running, func(x, y *corev1.Pod) int { return strings.Compare(x.Name, y.Name) })
+	policies = slices.Clone(policies)
+	slices.SortFunc(policies, func(x, y *networkingv1.NetworkPolicy) int { return strings.Compare(x.Name, y.Name) })
+
+	a.Pods = make([]podCoverage, len(running))
+	for i, pod := range running {
+		a.Pods[i] = podCoverage{Pod: pod.Name, HostNetwork: pod.Spec.HostNetwork}
+	}
+	for _, np := range policies {
+		selector, err := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+		if err != nil {
+			return a, fmt.Errorf("policy %s/%s: %w", namespace, np.Name, err)
+		}
+		ingress, egress := policyTypes(np)
+		selected := false
+		for i, pod := range running {
+			if !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			selected = true
+			if ingress {
+				a.Pods[i].Ingress = append(a.Pods[i].Ingress, np.Name)
+			}
+			if egress {
+				a.Pods[i].Egress = append(a.Pods[i].Egress, np.Name)
+			}
+		}
+		if !selected {
+			a.Unused = append(a.Unused, np.Name)
+		}
+	}
+	return a, nil
+}
+
+// printAudit writes a summary line per namespace, a row per pod, and the
+// policies that select nothing. With unprotectedOnly, only pods that no
+// policy applies to are listed.
+func printAudit(w io.Writer, audits []namespaceAudit, unprotectedOnly bool) {
+	for _, a := range audits {
+		unprotected, openIngress, openEgress := 0, 0, 0
+		for _, c := range a.Pods {
+			if c.unprotected() {
+				unprotected++
+			}
+			if c.HostNetwork || len(c.Ingress) == 0 {
+				openIngress++
+			}
+			if c.HostNetwork || len(c.Egress) == 0 {
+				openEgress++
+			}
+		}
+		if unprotectedOnly && unprotected == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "Namespace %s: %d policies, %d pods, %d unprotected (%d open for ingress, %d open for egress)\n",
+			a.Namespace, a.Policies, len(a.Pods), unprotected, openIngress, openEgress)
+		if len(a.Pods) > 0 {
+			tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(tw, "  POD\tINGRESS\tEGRESS")
+			for _, c := range a.Pods {
+				if unprotectedOnly && !c.unprotected() {
+					continue
+				}
+				switch {
+				case c.HostNetwork:
+					fmt.Fprintf(tw, "  %s\thost network\thost network !\n", c.Pod)
+				case c.unprotected():
+					fmt.Fprintf(tw, "  %s\topen\topen !\n", c.Pod)
+				default:
+					fmt.Fprintf(tw, "  %s\t%s\t%s\n", c.Pod, policyList(c.Ingress), policyList(c.Egress))
+				}
+			}
+			tw.Flush()
+		}
+		for _, name := range a.Unused {
+			fmt.Fprintf(w, "  policy %s selects no pods\n", name)
+		}
+	}
+}
+
+func policyList(names []string) string {
+	if len(names) == 0 {
+		return "open"
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace       = flag.String("namespace", "", "audit only this namespace (empty for all namespaces)")
+	unprotectedOnly = flag.Bool("unprotected-only", false, "list only pods that no NetworkPolicy applies to")
+	watch           = flag.Bool("watch", false, "print the report again whenever a namespace, pod or policy changes")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "54-networkpolicy-audit")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// report audits every namespace from the namespace lister, so namespaces
+// without pods or policies are listed too. Pods and policies are read per
+// namespace from their listers.
+func report(factory informers.SharedInformerFactory, namespace string, unprotectedOnly bool) (string, error) {
+	namespaces, err := factory.Core().V1().Namespaces().Lister().List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	slices.SortFunc(namespaces, func(a, b *corev1.Namespace) int { return strings.Compare(a.Name, b.Name) })
+
+	var audits []namespaceAudit
+	for _, ns := range namespaces {
+		if namespace != "" && ns.Name != namespace {
+			continue
+		}
+		policies, err := factory.Networking().V1().NetworkPolicies().Lister().NetworkPolicies(ns.Name).List(labels.Everything())
+		if err != nil {
+			return "", err
+		}
+		pods, err := factory.Core().V1().Pods().Lister().Pods(ns.Name).List(labels.Everything())
+		if err != nil {
+			return "", err
+		}
+		a, err := auditNamespace(ns.Name, policies, pods)
+		if err != nil {
+			return "", err
+		}
+		audits = append(audits, a)
+	}
+	var out bytes.Buffer
+	printAudit(&out, audits, unprotectedOnly)
+	if out.Len() == 0 {
+		fmt.Fprintln(&out, "No unprotected pods")
+	}
+	return out.String(), nil
+}
+
+// run prints the report once the caches synced. With watch it prints it
+// again whenever it changes, until ctx is cancelled.
+func run(ctx context.Context, clientset kubernetes.Interface, namespace string, unprotectedOnly, watch bool) error {
+	// Namespaces are cluster-scoped and ignore the namespace option
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { notify() },
+		DeleteFunc: func(obj interface{}) { notify() },
+	}
+	for _, informer := range []cache.SharedIndexInformer{
+		factory.Core().V1().Namespaces().Informer(),
+		factory.Core().V1().Pods().Informer(),
+		factory.Networking().V1().NetworkPolicies().Informer(),
+	} {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer factory.Shutdown()
+	defer cancel()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+	// The first report does not wait for an event
+	notify()
+
+	last := ""
+	for {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+		out, err := report(factory, namespace, unprotectedOnly)
+		if err != nil {
+			return err
+		}
+		if out != last {
+			if last != "" {
+				fmt.Println("---")
+			}
+			fmt.Print(out)
+			last = out
+		}
+		if !watch {
+			return nil
+		}
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, clientset, *namespace, *unprotectedOnly, *watch); err != nil {
+		log.Fatal(err)
+	}
+}
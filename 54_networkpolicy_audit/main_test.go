@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func namespaceObj(name string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func pod(namespace, name, app string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": app}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func policy(namespace, name string, matchLabels map[string]string, types ...networkingv1.PolicyType) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: matchLabels},
+			PolicyTypes: types,
+		},
+	}
+}
+
+func TestPolicyTypes(t *testing.T) {
+	withEgressRules := policy("shop", "p", nil)
+	withEgressRules.Spec.Egress = []networkingv1.NetworkPolicyEgressRule{{}}
+	tests := []struct {
+		name            string
+		np              *networkingv1.NetworkPolicy
+		ingress, egress bool
+	}{
+		{"defaults to ingress", policy("shop", "p", nil), true, false},
+		{"egress rules add egress", withEgressRules, true, true},
+		{"egress only", policy("shop", "p", nil, networkingv1.PolicyTypeEgress), false, true},
+		{"both", policy("shop", "p", nil, networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress, egress := policyTypes(tt.np)
+			if ingress != tt.ingress || egress != tt.egress {
+				t.Errorf("policyTypes = %v, %v, want %v, %v", ingress, egress, tt.ingress, tt.egress)
+			}
+		})
+	}
+}
+
+func TestAuditNamespace(t *testing.T) {
+	agent := pod("shop", "agent-x", "agent")
+	agent.Spec.HostNetwork = true
+	done := pod("shop", "migrate-1", "migrate")
+	done.Status.Phase = corev1.PodSucceeded
+	policies := []*networkingv1.NetworkPolicy{
+		policy("shop", "web-egress", map[string]string{"app": "web"}, networkingv1.PolicyTypeEgress),
+		policy("shop", "allow-web", map[string]string{"app": "web"}),
+		policy("shop", "allow-db", map[string]string{"app": "db"}, networkingv1.PolicyTypeIngress),
+		policy("shop", "allow-cache", map[string]string{"app": "cache"}),
+	}
+	a, err := auditNamespace("shop", policies, []*corev1.Pod{
+		pod("shop", "web-2", "web"), pod("shop", "web-1", "web"), pod("shop", "db-0", "db"),
+		pod("shop", "worker-1", "worker"), agent, done,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	printAudit(&out, []namespaceAudit{a}, false)
+	want := `Namespace shop: 4 policies, 5 pods, 2 unprotected (2 open for ingress, 3 open for egress)
+POD INGRESS EGRESS
+agent-x host network host network !
+db-0 allow-db open
+web-1 allow-web web-egress
+web-2 allow-web web-egress
+worker-1 open open !
+policy allow-cache selects no pods
+`
+	if got := testutil.Collapse(out.String()); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	output := testutil.CaptureOutput(t)
+	clientset := fake.NewSimpleClientset(
+		namespaceObj("shop"), namespaceObj("dev"),
+		pod("shop", "web-1", "web"), pod("dev", "scratch", "scratch"),
+		policy("shop", "default-deny", nil, networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress),
+	)
+	if err := run(context.Background(), clientset, "", true, false); err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForOutput(t, output, "Namespace dev: 0 policies, 1 pods, 1 unprotected (1 open for ingress, 1 open for egress)\n")
+	if strings.Contains(output(), "Namespace shop") {
+		t.Errorf("--unprotected-only listed namespace shop, whose pods are all covered:\n%s", output())
+	}
+}
+
+func TestRunWatchesPolicies(t *testing.T) {
+	h := testutil.NewHarness(t, namespaceObj("shop"), pod("shop", "web-1", "web"))
+	output := testutil.CaptureOutput(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, h.Clientset, "shop", false, true) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("run: %v", err)
+		}
+	}()
+	testutil.WaitForOutput(t, output, "Namespace shop: 0 policies, 1 pods, 1 unprotected")
+
+	h.WaitForWatchOf(&networkingv1.NetworkPolicy{}, 1)
+	h.Add(policy("shop", "default-deny", nil, networkingv1.PolicyTypeIngress))
+	testutil.WaitForOutput(t, output, "---\n", "Namespace shop: 1 policies, 1 pods, 0 unprotected (0 open for ingress, 1 open for egress)")
+}
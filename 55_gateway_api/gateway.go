@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// gatewayGroup is the API group of the Gateway API. It is installed as CRDs,
+// so the standard clientset has no typed client for it.
+const gatewayGroup = "gateway.networking.k8s.io"
+
+var (
+	gatewayKind   = schema.GroupKind{Group: gatewayGroup, Kind: "Gateway"}
+	httpRouteKind = schema.GroupKind{Group: gatewayGroup, Kind: "HTTPRoute"}
+)
+
+// resolve asks the RESTMapper for the resource of a kind, in the version the
+// API server prefers. Clusters run v1 or, with older CRDs, v1beta1; the
+// mapper finds out through discovery instead of the version being hardcoded.
+func resolve(mapper meta.RESTMapper, gk schema.GroupKind) (schema.GroupVersionResource, error) {
+	mapping, err := mapper.RESTMapping(gk)
+	if meta.IsNoMatchError(err) {
+		return schema.GroupVersionResource{}, fmt.Errorf("%s is not served; are the Gateway API CRDs installed? %w", gk.String(), err)
+	}
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	return mapping.Resource, nil
+}
+
+// conditionStatus returns "<type>=<status>" for a condition in a list of
+// conditions, or "<type>=Unknown" when it is not there
+func conditionStatus(conditions []interface{}, conditionType string) string {
+	for _, c := range conditions {
+		m, ok := c.(map[string]interface{})
+		if !ok || m["type"] != conditionType {
+			continue
+		}
+		if status, ok := m["status"].(string); ok {
+			return conditionType + "=" + status
+		}
+	}
+	return conditionType + "=Unknown"
+}
+
+// maps returns the maps found in a slice field, skipping anything else
+func maps(obj map[string]interface{}, fields ...string) []map[string]interface{} {
+	items, _, _ := unstructured.NestedSlice(obj, fields...)
+	var out []map[string]interface{}
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// describeGateway renders the listeners of a Gateway and what its
+// controller reports in the status: addresses, Programmed, and how many
+// routes are attached to the listeners
+func describeGateway(gw *unstructured.Unstructured) string {
+	className, _, _ := unstructured.NestedString(gw.Object, "spec", "gatewayClassName")
+	var listeners []string
+	for _, l := range maps(gw.Object, "spec", "listeners") {
+		name, _, _ := unstructured.NestedString(l, "name")
+		port, _, _ := unstructured.NestedInt64(l, "port")
+		protocol, _, _ := unstructured.NestedString(l, "protocol")
+		listener := fmt.Sprintf("%s %d/%s", name, port, protocol)
+		if hostname, _, _ := unstructured.NestedString(l, "hostname"); hostname != "" {
+			listener += " " + hostname
+		}
+		listeners = append(listeners, listener)
+	}
+	var addresses []string
+	for _, a := range maps(gw.Object, "status", "addresses") {
+		if value, _, _ := unstructured.NestedString(a, "value"); value != "" {
+			addresses = append(addresses, value)
+		}
+	}
+	attached := int64(0)
+	for _, l := range maps(gw.Object, "status", "listeners") {
+		n, _, _ := unstructured.NestedInt64(l, "attachedRoutes")
+		attached += n
+	}
+	conditions, _, _ := unstructured.NestedSlice(gw.Object, "status", "conditions")
+	return fmt.Sprintf("%s/%s class %s: listeners %s; addresses %s; %s, %d routes attached",
+		gw.GetNamespace(), gw.GetName(), className, orNone(listeners), orNone(addresses),
+		conditionStatus(conditions, "Programmed"), attached)
+}
+
+// parentKey renders a parentRef as namespace/name[/section]. A parentRef
+// without a namespace refers to the route's own namespace.
+func parentKey(ref map[string]interface{}, routeNamespace string) string {
+	namespace, _, _ := unstructured.NestedString(ref, "namespace")
+	if namespace == "" {
+		namespace = routeNamespace
+	}
+	name, _, _ := unstructured.NestedString(ref, "name")
+	key := namespace + "/" + name
+	if section, _, _ := unstructured.NestedString(ref, "sectionName"); section != "" {
+		key += "/" + section
+	}
+	return key
+}
+
+// describeRoute renders the hostnames, parents and backends of an HTTPRoute.
+// Every parent shows the conditions the Gateway's controller wrote for it in
+// status.parents: Accepted, and ResolvedRefs, which is False when a backend
+// does not exist.
+func describeRoute(route *unstructured.Unstructured) string {
+	hostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+
+	parentStatus := map[string][]interface{}{}
+	for _, p := range maps(route.Object, "status", "parents") {
+		ref, _, _ := unstructured.NestedMap(p, "parentRef")
+		conditions, _, _ := unstructured.NestedSlice(p, "conditions")
+		parentStatus[parentKey(ref, route.GetNamespace())] = conditions
+	}
+	var parents []string
+	for _, ref := range maps(route.Object, "spec", "parentRefs") {
+		key := parentKey(ref, route.GetNamespace())
+		conditions, ok := parentStatus[key]
+		if !ok {
+			parents = append(parents, key+" (no status yet)")
+			continue
+		}
+		parents = append(parents, fmt.Sprintf("%s (%s, %s)", key,
+			conditionStatus(conditions, "Accepted"), conditionStatus(conditions, "ResolvedRefs")))
+	}
+
+	var backends []string
+	for _, rule := range maps(route.Object, "spec", "rules") {
+		for _, b := range maps(rule, "backendRefs") {
+			name, _, _ := unstructured.NestedString(b, "name")
+			backend := name
+			if port, found, _ := unstructured.NestedInt64(b, "port"); found {
+				backend = fmt.Sprintf("%s:%d", name, port)
+			}
+			if !slices.Contains(backends, backend) {
+				backends = append(backends, backend)
+			}
+		}
+	}
+	return fmt.Sprintf("%s/%s hostnames %s: parents %s; backends %s",
+		route.GetNamespace(), route.GetName(), orNone(hostnames), orNone(parents), orNone(backends))
+}
+
+func orNone(items []string) string {
+	if len(items) == 0 {
+		return "<none>"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var namespace = flag.String("namespace", "", "namespace of the Gateways and HTTPRoutes (empty for all namespaces)")
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "55-gateway-api")
+	return config
+}
+
+// printHandler prints an object of the dynamic informer when it is added,
+// when its description changes, and when it is deleted. Updates that change
+// nothing described, such as a new resourceVersion after a status
+// heartbeat, are skipped.
+func printHandler(prefix string, describe func(*unstructured.Unstructured) string) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			fmt.Printf("[%s] %s\n", prefix, describe(obj.(*unstructured.Unstructured)))
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if current := describe(newObj.(*unstructured.Unstructured)); current != describe(oldObj.(*unstructured.Unstructured)) {
+				fmt.Printf("[%s] %s\n", prefix, current)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+				fmt.Printf("[%s] %s: deleted\n", prefix, key)
+			}
+		},
+	}
+}
+
+// run resolves the Gateway API resources through the RESTMapper and watches
+// them with dynamic informers until ctx is cancelled. The informers hand out
+// *unstructured.Unstructured; gateway.go reads the fields it needs by path.
+func run(ctx context.Context, client dynamic.Interface, mapper meta.RESTMapper, namespace string) error {
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, 0, namespace, nil)
+	for _, kind := range []struct {
+		gk       schema.GroupKind
+		describe func(*unstructured.Unstructured) string
+	}{
+		{gatewayKind, describeGateway},
+		{httpRouteKind, describeRoute},
+	} {
+		gvr, err := resolve(mapper, kind.gk)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s is served as %s\n", kind.gk.Kind, gvr.String())
+		if _, err := factory.ForResource(gvr).Informer().AddEventHandler(printHandler(kind.gk.Kind, kind.describe)); err != nil {
+			return err
+		}
+	}
+
+	defer factory.Shutdown()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+	<-ctx.Done()
+	return nil
+}
+
+func main() {
+	config := createConfig()
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create dynamic client: %v", err)
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create discovery client: %v", err)
+	}
+	// The mapper reads the API groups from discovery on first use, and keeps
+	// them in memory
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, client, mapper, *namespace); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+var (
+	gateways   = schema.GroupVersionResource{Group: gatewayGroup, Version: "v1", Resource: "gateways"}
+	httpRoutes = schema.GroupVersionResource{Group: gatewayGroup, Version: "v1", Resource: "httproutes"}
+)
+
+const gatewayYAML = `
+apiVersion: gateway.networking.k8s.io/v1
+kind: Gateway
+metadata:
+  name: public
+  namespace: shop
+spec:
+  gatewayClassName: istio
+  listeners:
+  - name: http
+    port: 80
+    protocol: HTTP
+  - name: https
+    port: 443
+    protocol: HTTPS
+    hostname: "*.example.com"
+`
+
+const gatewayStatusYAML = `
+addresses:
+- type: IPAddress
+  value: 203.0.113.10
+conditions:
+- type: Accepted
+  status: "True"
+- type: Programmed
+  status: "True"
+listeners:
+- name: http
+  attachedRoutes: 0
+- name: https
+  attachedRoutes: 1
+`
+
+const routeYAML = `
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: web
+  namespace: shop
+spec:
+  parentRefs:
+  - name: public
+    sectionName: https
+  hostnames:
+  - shop.example.com
+  rules:
+  - matches:
+    - path:
+        type: PathPrefix
+        value: /api
+    backendRefs:
+    - name: api
+      port: 8080
+  - backendRefs:
+    - name: web
+      port: 8080
+      weight: 90
+    - name: web-canary
+      port: 8080
+      weight: 10
+`
+
+const routeStatusYAML = `
+parents:
+- parentRef:
+    name: public
+    sectionName: https
+  controllerName: istio.io/gateway-controller
+  conditions:
+  - type: Accepted
+    status: "True"
+  - type: ResolvedRefs
+    status: "False"
+    reason: BackendNotFound
+`
+
+// object decodes a manifest, with status when it is not empty. It goes
+// through JSON like a response of the API server, so numbers are int64.
+func object(t *testing.T, manifest, status string) *unstructured.Unstructured {
+	t.Helper()
+	if status != "" {
+		manifest += "status:\n  " + strings.ReplaceAll(strings.TrimSpace(status), "\n", "\n  ") + "\n"
+	}
+	data, err := yaml.YAMLToJSON([]byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func gatewayMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gateways.GroupVersion()})
+	// Add would guess the plural "gatewaies"; discovery knows the real one
+	mapper.AddSpecific(gateways.GroupVersion().WithKind("Gateway"), gateways, gateways.GroupVersion().WithResource("gateway"), meta.RESTScopeNamespace)
+	mapper.Add(httpRoutes.GroupVersion().WithKind("HTTPRoute"), meta.RESTScopeNamespace)
+	return mapper
+}
+
+func TestResolve(t *testing.T) {
+	gvr, err := resolve(gatewayMapper(), httpRouteKind)
+	if err != nil || gvr != httpRoutes {
+		t.Errorf("resolve = %v, %v, want %v", gvr, err, httpRoutes)
+	}
+
+	_, err = resolve(meta.NewDefaultRESTMapper(nil), gatewayKind)
+	if !meta.IsNoMatchError(errors.Unwrap(err)) {
+		t.Errorf("resolve without the CRDs = %v, want a wrapped no match error", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      *unstructured.Unstructured
+		describe func(*unstructured.Unstructured) string
+		want     string
+	}{
+		{
+			name:     "gateway before its controller saw it",
+			obj:      object(t, gatewayYAML, ""),
+			describe: describeGateway,
+			want:     "shop/public class istio: listeners http 80/HTTP, https 443/HTTPS *.example.com; addresses <none>; Programmed=Unknown, 0 routes attached",
+		},
+		{
+			name:     "programmed gateway",
+			obj:      object(t, gatewayYAML, gatewayStatusYAML),
+			describe: describeGateway,
+			want:     "shop/public class istio: listeners http 80/HTTP, https 443/HTTPS *.example.com; addresses 203.0.113.10; Programmed=True, 1 routes attached",
+		},
+		{
+			name:     "route without status",
+			obj:      object(t, routeYAML, ""),
+			describe: describeRoute,
+			want:     "shop/web hostnames shop.example.com: parents shop/public/https (no status yet); backends api:8080, web:8080, web-canary:8080",
+		},
+		{
+			name:     "route with a missing backend",
+			obj:      object(t, routeYAML, routeStatusYAML),
+			describe: describeRoute,
+			want:     "shop/web hostnames shop.example.com: parents shop/public/https (Accepted=True, ResolvedRefs=False); backends api:8080, web:8080, web-canary:8080",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.describe(tt.obj); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunWatchesGatewayAndRoutes(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gateways: "GatewayList", httpRoutes: "HTTPRouteList"})
+	// Seeded objects would be stored under the guessed plural too, so they
+	// are created through their resources
+	ctx, cancel := context.WithCancel(context.Background())
+	for gvr, obj := range map[schema.GroupVersionResource]*unstructured.Unstructured{
+		gateways:   object(t, gatewayYAML, ""),
+		httpRoutes: object(t, routeYAML, ""),
+	} {
+		if _, err := client.Resource(gvr).Namespace("shop").Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Changes only reach watches that are open, so the test waits for both
+	// informers to watch before it changes anything
+	watching := make(chan schema.GroupVersionResource, 2)
+	client.PrependWatchReactor("*", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w, err := client.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		watching <- action.GetResource()
+		return true, w, err
+	})
+	output := testutil.CaptureOutput(t)
+
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, client, gatewayMapper(), "shop") }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("run: %v", err)
+		}
+	}()
+	testutil.WaitForOutput(t, output,
+		"Gateway is served as gateway.networking.k8s.io/v1, Resource=gateways\n",
+		"[Gateway] shop/public class istio: listeners http 80/HTTP, https 443/HTTPS *.example.com; addresses <none>; Programmed=Unknown, 0 routes attached\n",
+		"[HTTPRoute] shop/web hostnames shop.example.com: parents shop/public/https (no status yet)",
+	)
+	<-watching
+	<-watching
+
+	// The gateway controller programs the Gateway and accepts the route
+	if _, err := client.Resource(gateways).Namespace("shop").Update(ctx, object(t, gatewayYAML, gatewayStatusYAML), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Resource(httpRoutes).Namespace("shop").Update(ctx, object(t, routeYAML, routeStatusYAML), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForOutput(t, output,
+		"addresses 203.0.113.10; Programmed=True, 1 routes attached\n",
+		"parents shop/public/https (Accepted=True, ResolvedRefs=False)",
+	)
+
+	if err := client.Resource(httpRoutes).Namespace("shop").Delete(ctx, "web", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForOutput(t, output, "[HTTPRoute] shop/web: deleted\n")
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/featureflags"
+)
+
+// fieldManager is the writer of the labels and the default objects the
+// controller adds
+var fieldManager = clientid.FieldManager("56-namespace-lifecycle")
+
+// LifecycleController gives every new namespace default labels, a
+// NetworkPolicy and a ResourceQuota
+type LifecycleController struct {
+	clientset kubernetes.Interface
+	factory   informers.SharedInformerFactory
+	queue     workqueue.TypedRateLimitingInterface[string]
+	defaults  defaults
+	// flags toggles behavior at runtime; nil uses every default
+	flags *featureflags.Flags
+	now   func() time.Time
+}
+
+// Feature flags read on every reconcile
+const (
+	// verboseFlag prints why a namespace was skipped
+	verboseFlag = "verbose"
+	// networkPolicyFlag creates the default NetworkPolicy, on by default
+	networkPolicyFlag = "network-policy"
+)
+
+func NewLifecycleController(clientset kubernetes.Interface, d defaults, flags *featureflags.Flags) *LifecycleController {
+	c := &LifecycleController{
+		clientset: clientset,
+		factory:   informers.NewSharedInformerFactory(clientset, 0),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: controllerName},
+		),
+		defaults: d,
+		flags:    flags,
+		now:      time.Now,
+	}
+
+	// Only Add events matter: a namespace gets its defaults once, when it is
+	// created. The initial list adds every existing namespace too, so ones
+	// created while the controller was down are caught up; reconcile skips
+	// those that were initialized before.
+	c.factory.Core().V1().Namespaces().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.queue.Add(obj.(*corev1.Namespace).Name)
+		},
+	})
+	return c
+}
+
+// Run starts the informers and workers and blocks until ctx is cancelled
+func (c *LifecycleController) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+
+	c.factory.Start(ctx.Done())
+
+	fmt.Println("Waiting for cache sync...")
+	c.factory.WaitForCacheSync(ctx.Done())
+	fmt.Println("Cache sync completed!")
+
+	// The drain below still works through the queue after ctx is cancelled,
+	// so the workers' requests keep ctx's values but not its cancellation
+	work := context.WithoutCancel(ctx)
+	for i := 0; i < workers; i++ {
+		go wait.Until(func() { c.runWorker(work) }, time.Second, ctx.Done())
+	}
+	<-ctx.Done()
+
+	// Let the workers finish what is queued, then stop the informers
+	c.queue.ShutDownWithDrain()
+	c.factory.Shutdown()
+}
+
+func (c *LifecycleController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *LifecycleController) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.reconcile(ctx, key); err != nil {
+		fmt.Printf("[Lifecycle] Failed to initialize namespace %s, requeuing: %v\n", key, err)
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+// reconcile creates the default objects in a namespace, then sets the
+// missing default labels and the initialized annotation in one patch. The
+// annotation is written last, so a failure before it retries everything;
+// objects that already exist are left as they are.
+func (c *LifecycleController) reconcile(ctx context.Context, name string) error {
+	ns, err := c.factory.Core().V1().Namespaces().Lister().Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	initializedAt, initialized := ns.Annotations[initializedAnnotation]
+	skip := ""
+	switch {
+	case !ns.DeletionTimestamp.IsZero():
+		skip = "being deleted"
+	case c.defaults.excluded(name):
+		skip = "excluded"
+	case initialized:
+		skip = "initialized at " + initializedAt
+	}
+	if skip != "" {
+		if c.flags.Bool(verboseFlag, false) {
+			fmt.Printf("[Lifecycle] Skipped namespace %s: %s\n", name, skip)
+		}
+		return nil
+	}
+
+	var created []string
+	if c.flags.Bool(networkPolicyFlag, true) {
+		_, err = c.clientset.NetworkingV1().NetworkPolicies(name).Create(ctx, networkPolicy(name), metav1.CreateOptions{FieldManager: fieldManager})
+		if err == nil {
+			created = append(created, "NetworkPolicy "+networkPolicyName)
+		} else if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("create NetworkPolicy: %w", err)
+		}
+	}
+	if len(c.defaults.quota) > 0 {
+		_, err = c.clientset.CoreV1().ResourceQuotas(name).Create(ctx, resourceQuota(name, c.defaults.quota), metav1.CreateOptions{FieldManager: fieldManager})
+		if err == nil {
+			created = append(created, "ResourceQuota "+quotaName)
+		} else if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("create ResourceQuota: %w", err)
+		}
+	}
+
+	missing := c.defaults.missingLabels(ns)
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      missing,
+			"annotations": map[string]string{initializedAnnotation: c.now().UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.clientset.CoreV1().Namespaces().Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager}); err != nil {
+		return fmt.Errorf("patch namespace: %w", err)
+	}
+
+	var set []string
+	for _, k := range slices.Sorted(maps.Keys(missing)) {
+		set = append(set, k+"="+missing[k])
+	}
+	fmt.Printf("[Lifecycle] Initialized namespace %s: labels %s; created %s\n", name, orNone(set), orNone(created))
+	return nil
+}
+
+func orNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// initializedAnnotation records that a namespace got its defaults. The
+	// defaults are applied once; what a team changes afterwards is kept.
+	initializedAnnotation = "lifecycle.k8s-lab.io/initialized"
+	// managedByLabel marks the objects the controller creates
+	managedByLabel = "app.kubernetes.io/managed-by"
+	controllerName = "namespace-lifecycle"
+
+	networkPolicyName = "default-deny-ingress"
+	quotaName         = "default-quota"
+)
+
+// defaults is what every new namespace gets
+type defaults struct {
+	labels  map[string]string
+	quota   corev1.ResourceList
+	exclude []string
+}
+
+// parseDefaults parses the --labels, --quota and --exclude flags
+func parseDefaults(labelList, quotaList, excludeList string) (defaults, error) {
+	d := defaults{}
+	var err error
+	if d.labels, err = labels.ConvertSelectorToLabelsMap(labelList); err != nil {
+		return d, fmt.Errorf("--labels: %w", err)
+	}
+	if d.quota, err = parseResourceList(quotaList); err != nil {
+		return d, fmt.Errorf("--quota: %w", err)
+	}
+	for _, name := range strings.Split(excludeList, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			d.exclude = append(d.exclude, name)
+		}
+	}
+	return d, nil
+}
+
+// parseResourceList parses name=quantity pairs such as pods=50,requests.cpu=4
+func parseResourceList(list string) (corev1.ResourceList, error) {
+	resources := corev1.ResourceList{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=quantity", item)
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		resources[corev1.ResourceName(name)] = q
+	}
+	return resources, nil
+}
+
+// excluded is true for namespaces that never get defaults, such as
+// kube-system
+func (d defaults) excluded(name string) bool {
+	return slices.Contains(d.exclude, name)
+}
+
+// missingLabels returns the default labels the namespace does not have yet.
+// A label the namespace was created with wins over the default.
+func (d defaults) missingLabels(ns *corev1.Namespace) map[string]string {
+	missing := map[string]string{}
+	for k, v := range d.labels {
+		if _, ok := ns.Labels[k]; !ok {
+			missing[k] = v
+		}
+	}
+	return missing
+}
+
+// networkPolicy denies ingress from other namespaces and allows traffic
+// between the pods of the namespace
+func networkPolicy(namespace string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      networkPolicyName,
+			Namespace: namespace,
+			Labels:    map[string]string{managedByLabel: controllerName},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}},
+			}},
+		},
+	}
+}
+
+func resourceQuota(namespace string, hard corev1.ResourceList) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      quotaName,
+			Namespace: namespace,
+			Labels:    map[string]string{managedByLabel: controllerName},
+		},
+		Spec: corev1.ResourceQuotaSpec{Hard: hard},
+	}
+}
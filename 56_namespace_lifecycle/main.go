@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/featureflags"
+	"github.com/shamimice03/mastering-k8s-client-go/hotreload"
+)
+
+var (
+	defaultLabels = flag.String("labels", "pod-security.kubernetes.io/enforce=baseline", "labels every new namespace gets, unless it was created with them")
+	quota         = flag.String("quota", "requests.cpu=4,requests.memory=8Gi,pods=50", "hard limits of the default ResourceQuota (empty for no quota)")
+	exclude       = flag.String("exclude", "default,kube-system,kube-public,kube-node-lease", "namespaces that never get defaults")
+	workers       = flag.Int("workers", 2, "number of reconcile workers")
+	flagsMap      = flag.String("feature-flags", "", "namespace/name of a ConfigMap with feature flags (empty for the defaults)")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "56-namespace-lifecycle")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+func main() {
+	clientset := createClientSet()
+	d, err := parseDefaults(*defaultLabels, *quota, *exclude)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var flags *featureflags.Flags
+	if *flagsMap != "" {
+		namespace, name, err := cache.SplitMetaNamespaceKey(*flagsMap)
+		if err != nil || namespace == "" {
+			log.Fatalf("--feature-flags must be namespace/name, got %q", *flagsMap)
+		}
+		flags = featureflags.New(clientset, namespace, name)
+		flags.AddHandler(hotreload.HandlerFunc(func(data map[string][]byte, checksum string) error {
+			fmt.Printf("[Flags] %s: %v\n", *flagsMap, flags.All())
+			return nil
+		}))
+		go func() {
+			if err := flags.Run(ctx); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	controller := NewLifecycleController(clientset, d, flags)
+
+	controller.Run(ctx, *workers)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/featureflags"
+	"github.com/shamimice03/mastering-k8s-client-go/hotreload"
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func newNamespace(name string, nsLabels, annotations map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nsLabels, Annotations: annotations}}
+}
+
+func testDefaults(t *testing.T) defaults {
+	t.Helper()
+	d, err := parseDefaults("pod-security.kubernetes.io/enforce=baseline,team=unassigned", "pods=50,requests.cpu=4", "kube-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// startController syncs the controller's cache without starting workers, so
+// tests drive reconcile directly
+func startController(t *testing.T, clientset kubernetes.Interface) *LifecycleController {
+	t.Helper()
+	c := NewLifecycleController(clientset, testDefaults(t), nil)
+	c.now = func() time.Time { return time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC) }
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		c.queue.ShutDown()
+		c.factory.Shutdown()
+	})
+	c.factory.Start(stopCh)
+	c.factory.WaitForCacheSync(stopCh)
+	return c
+}
+
+func TestParseDefaults(t *testing.T) {
+	d := testDefaults(t)
+	if d.labels["team"] != "unassigned" || len(d.labels) != 2 {
+		t.Errorf("labels = %v", d.labels)
+	}
+	if q := d.quota[corev1.ResourceRequestsCPU]; q.Cmp(resource.MustParse("4")) != 0 || len(d.quota) != 2 {
+		t.Errorf("quota = %v", d.quota)
+	}
+	if !d.excluded("kube-system") || d.excluded("team-a") {
+		t.Errorf("exclude = %v", d.exclude)
+	}
+
+	for _, bad := range [][3]string{{"a b", "", ""}, {"", "pods", ""}, {"", "pods=lots", ""}} {
+		if _, err := parseDefaults(bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("parseDefaults(%q, %q, %q) did not fail", bad[0], bad[1], bad[2])
+		}
+	}
+}
+
+func TestReconcileInitializesNamespace(t *testing.T) {
+	output := testutil.CaptureOutput(t)
+	clientset := fake.NewSimpleClientset(
+		newNamespace("team-a", map[string]string{"pod-security.kubernetes.io/enforce": "restricted"}, nil),
+	)
+	c := startController(t, clientset)
+	ctx := context.TODO()
+
+	if err := c.reconcile(ctx, "team-a"); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, "team-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The label the namespace was created with wins over the default
+	if ns.Labels["pod-security.kubernetes.io/enforce"] != "restricted" || ns.Labels["team"] != "unassigned" {
+		t.Errorf("labels = %v", ns.Labels)
+	}
+	if ns.Annotations[initializedAnnotation] != "2026-10-15T09:00:00Z" {
+		t.Errorf("annotations = %v", ns.Annotations)
+	}
+	if _, err := clientset.NetworkingV1().NetworkPolicies("team-a").Get(ctx, networkPolicyName, metav1.GetOptions{}); err != nil {
+		t.Errorf("NetworkPolicy not created: %v", err)
+	}
+	quota, err := clientset.CoreV1().ResourceQuotas("team-a").Get(ctx, quotaName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("ResourceQuota not created: %v", err)
+	}
+	if quota.Labels[managedByLabel] != controllerName || len(quota.Spec.Hard) != 2 {
+		t.Errorf("quota = %+v", quota)
+	}
+	testutil.WaitForOutput(t, output,
+		"[Lifecycle] Initialized namespace team-a: labels team=unassigned; created NetworkPolicy default-deny-ingress, ResourceQuota default-quota\n")
+}
+
+func TestReconcileKeepsExistingQuota(t *testing.T) {
+	output := testutil.CaptureOutput(t)
+	existing := resourceQuota("team-a", corev1.ResourceList{corev1.ResourcePods: resource.MustParse("500")})
+	clientset := fake.NewSimpleClientset(newNamespace("team-a", nil, nil), existing)
+	c := startController(t, clientset)
+
+	if err := c.reconcile(context.TODO(), "team-a"); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	quota, _ := clientset.CoreV1().ResourceQuotas("team-a").Get(context.TODO(), quotaName, metav1.GetOptions{})
+	if q := quota.Spec.Hard[corev1.ResourcePods]; q.Cmp(resource.MustParse("500")) != 0 {
+		t.Errorf("existing quota was changed: %v", quota.Spec.Hard)
+	}
+	testutil.WaitForOutput(t, output, "created NetworkPolicy default-deny-ingress\n")
+}
+
+func TestReconcileSkips(t *testing.T) {
+	tests := []struct {
+		name string
+		ns   *corev1.Namespace
+	}{
+		{"excluded", newNamespace("kube-system", nil, nil)},
+		{"initialized before", newNamespace("team-b", nil, map[string]string{initializedAnnotation: "2026-01-01T00:00:00Z"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tt.ns)
+			c := startController(t, clientset)
+			clientset.ClearActions()
+
+			if err := c.reconcile(context.TODO(), tt.ns.Name); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			for _, action := range clientset.Actions() {
+				t.Errorf("unexpected %s %s", action.GetVerb(), action.GetResource().Resource)
+			}
+		})
+	}
+}
+
+func TestRunInitializesCreatedNamespace(t *testing.T) {
+	h := testutil.NewHarness(t)
+	output := testutil.CaptureOutput(t)
+	c := NewLifecycleController(h.Clientset, testDefaults(t), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		c.Run(ctx, 1)
+	}()
+	defer func() {
+		cancel()
+		<-returned
+	}()
+
+	h.WaitForWatchOf(&corev1.Namespace{}, 1)
+	h.Add(newNamespace("team-c", nil, nil))
+	testutil.WaitForOutput(t, output, "[Lifecycle] Initialized namespace team-c: labels pod-security.kubernetes.io/enforce=baseline, team=unassigned;")
+}
+
+func TestFeatureFlagsToggleBehavior(t *testing.T) {
+	h := testutil.NewHarness(t,
+		newNamespace("kube-system", nil, nil),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "feature-flags", Namespace: "kube-system"},
+			Data:       map[string]string{verboseFlag: "true", networkPolicyFlag: "false"},
+		},
+	)
+	output := testutil.CaptureOutput(t)
+	flags := featureflags.New(h.Clientset, "kube-system", "feature-flags")
+	flagsSet := make(chan struct{})
+	flags.AddHandler(hotreload.HandlerFunc(func(data map[string][]byte, checksum string) error {
+		close(flagsSet)
+		return nil
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	flagsDone := make(chan error, 1)
+	go func() { flagsDone <- flags.Run(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-flagsDone; err != nil {
+			t.Errorf("flags: %v", err)
+		}
+	}()
+	<-flagsSet
+
+	c := NewLifecycleController(h.Clientset, testDefaults(t), flags)
+	runCtx, stop := context.WithCancel(context.Background())
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		c.Run(runCtx, 1)
+	}()
+	defer func() {
+		stop()
+		<-returned
+	}()
+
+	h.WaitForWatchOf(&corev1.Namespace{}, 1)
+	h.Add(newNamespace("team-d", nil, nil))
+	testutil.WaitForOutput(t, output,
+		"[Lifecycle] Skipped namespace kube-system: excluded\n",
+		"[Lifecycle] Initialized namespace team-d: labels pod-security.kubernetes.io/enforce=baseline, team=unassigned; created ResourceQuota default-quota\n")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// limitRangerAnnotation is written by the LimitRanger admission plugin on
+// every pod it changed, for example
+//
+//	LimitRanger plugin set: cpu, memory request for container app; cpu limit for container app
+//
+// Defaults are applied before the pod is stored, so the pod spec alone does
+// not tell a defaulted value from one the author wrote. The annotation does.
+const limitRangerAnnotation = "kubernetes.io/limit-ranger"
+
+var limitRangerEntry = regexp.MustCompile(`^(.+) (request|limit) for (?:init )?container (.+)$`)
+
+// parseLimitRanger returns the values the plugin set, keyed by container
+// name, as "requests.cpu", "limits.memory" and so on
+func parseLimitRanger(annotation string) map[string]map[string]bool {
+	set := map[string]map[string]bool{}
+	annotation, ok := strings.CutPrefix(annotation, "LimitRanger plugin set: ")
+	if !ok {
+		return set
+	}
+	for _, entry := range strings.Split(annotation, "; ") {
+		m := limitRangerEntry.FindStringSubmatch(entry)
+		if m == nil {
+			continue
+		}
+		container := m[3]
+		if set[container] == nil {
+			set[container] = map[string]bool{}
+		}
+		for _, name := range strings.Split(m[1], ", ") {
+			set[container][m[2]+"s."+name] = true
+		}
+	}
+	return set
+}
+
+// columns are the values reported per container, in table order
+var columns = []struct {
+	key      string
+	resource corev1.ResourceName
+	limit    bool
+}{
+	{"requests.cpu", corev1.ResourceCPU, false},
+	{"requests.memory", corev1.ResourceMemory, false},
+	{"limits.cpu", corev1.ResourceCPU, true},
+	{"limits.memory", corev1.ResourceMemory, true},
+}
+
+// containerRow is a container with at least one value that was defaulted or
+// is missing. Values end in "*" when the LimitRange set them, and are "-"
+// when nothing did.
+type containerRow struct {
+	Pod       string
+	Container string
+	Values    []string
+}
+
+// namespaceReport is the LimitRange situation of one namespace
+type namespaceReport struct {
+	Namespace   string
+	LimitRanges []*corev1.LimitRange
+	Containers  int
+	Defaulted   int // containers with at least one value set by a LimitRange
+	Missing     int // containers with at least one value nobody set
+	Rows        []containerRow
+}
+
+// auditNamespace checks every container of the namespace's running pods
+func auditNamespace(namespace string, limitRanges []*corev1.LimitRange, pods []*corev1.Pod) namespaceReport {
+	r := namespaceReport{Namespace: namespace, LimitRanges: slices.Clone(limitRanges)}
+	slices.SortFunc(r.LimitRanges, func(a, b *corev1.LimitRange) int { return strings.Compare(a.Name, b.Name) })
+	pods = slices.Clone(pods)
+	slices.SortFunc(pods, func(a, b *corev1.Pod) int { return strings.Compare(a.Name, b.Name) })
+
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		set := parseLimitRanger(pod.Annotations[limitRangerAnnotation])
+		check := func(c corev1.Container, name string) {
+			r.Containers++
+			row := containerRow{Pod: pod.Name, Container: name}
+			defaulted, missing := false, false
+			for _, col := range columns {
+				list := c.Resources.Requests
+				if col.limit {
+					list = c.Resources.Limits
+				}
+				q, ok := list[col.resource]
+				switch {
+				case !ok:
+					missing = true
+					row.Values = append(row.Values, "-")
+				case set[c.Name][col.key]:
+					defaulted = true
+					row.Values = append(row.Values, q.String()+"*")
+				default:
+					row.Values = append(row.Values, q.String())
+				}
+			}
+			if defaulted {
+				r.Defaulted++
+			}
+			if missing {
+				r.Missing++
+			}
+			if defaulted || missing {
+				r.Rows = append(r.Rows, row)
+			}
+		}
+		for _, c := range pod.Spec.InitContainers {
+			check(c, c.Name+" (init)")
+		}
+		for _, c := range pod.Spec.Containers {
+			check(c, c.Name)
+		}
+	}
+	return r
+}
+
+// describeLimitRange renders the container defaults of a LimitRange
+func describeLimitRange(lr *corev1.LimitRange) string {
+	var parts []string
+	for _, item := range lr.Spec.Limits {
+		if item.Type != corev1.LimitTypeContainer {
+			continue
+		}
+		if len(item.DefaultRequest) > 0 {
+			parts = append(parts, "defaultRequest "+resourceList(item.DefaultRequest))
+		}
+		if len(item.Default) > 0 {
+			parts = append(parts, "default "+resourceList(item.Default))
+		}
+	}
+	if len(parts) == 0 {
+		return lr.Name + " (no container defaults)"
+	}
+	return fmt.Sprintf("%s (%s)", lr.Name, strings.Join(parts, ", "))
+}
+
+func resourceList(list corev1.ResourceList) string {
+	var names []string
+	for name := range list {
+		names = append(names, string(name))
+	}
+	slices.Sort(names)
+	for i, name := range names {
+		q := list[corev1.ResourceName(name)]
+		names[i] = name + "=" + q.String()
+	}
+	return strings.Join(names, " ")
+}
+
+// printReports writes a summary line per namespace and a row per container
+// that relies on defaulting or lacks a value. Namespaces without a
+// LimitRange whose containers lack values are marked with " !".
+func printReports(w io.Writer, reports []namespaceReport) {
+	for _, r := range reports {
+		limitRanges := "no LimitRange"
+		if len(r.LimitRanges) > 0 {
+			var described []string
+			for _, lr := range r.LimitRanges {
+				described = append(described, describeLimitRange(lr))
+			}
+			limitRanges = "LimitRange " + strings.Join(described, ", ")
+		}
+		flag := ""
+		if len(r.LimitRanges) == 0 && r.Missing > 0 {
+			flag = " !"
+		}
+		fmt.Fprintf(w, "Namespace %s: %s; %d containers, %d rely on defaulting, %d lack requests or limits%s\n",
+			r.Namespace, limitRanges, r.Containers, r.Defaulted, r.Missing, flag)
+		if len(r.Rows) == 0 {
+			continue
+		}
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "  POD\tCONTAINER\tCPU REQUEST\tMEMORY REQUEST\tCPU LIMIT\tMEMORY LIMIT")
+		for _, row := range r.Rows {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", row.Pod, row.Container, strings.Join(row.Values, "\t"))
+		}
+		tw.Flush()
+		if r.Defaulted > 0 {
+			fmt.Fprintln(w, "  * set by LimitRange defaulting")
+		}
+	}
+}
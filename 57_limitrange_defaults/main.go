@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace = flag.String("namespace", "", "check only this namespace (empty for all namespaces)")
+	watch     = flag.Bool("watch", false, "print the report again whenever a namespace, pod or LimitRange changes")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "57-limitrange-defaults")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// report checks every namespace from the namespace lister, so namespaces
+// without pods or LimitRanges are listed too. Pods and LimitRanges are read
+// per namespace from their listers.
+func report(factory informers.SharedInformerFactory, namespace string) (string, error) {
+	namespaces, err := factory.Core().V1().Namespaces().Lister().List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	slices.SortFunc(namespaces, func(a, b *corev1.Namespace) int { return strings.Compare(a.Name, b.Name) })
+
+	var reports []namespaceReport
+	for _, ns := range namespaces {
+		if namespace != "" && ns.Name != namespace {
+			continue
+		}
+		limitRanges, err := factory.Core().V1().LimitRanges().Lister().LimitRanges(ns.Name).List(labels.Everything())
+		if err != nil {
+			return "", err
+		}
+		pods, err := factory.Core().V1().Pods().Lister().Pods(ns.Name).List(labels.Everything())
+		if err != nil {
+			return "", err
+		}
+		reports = append(reports, auditNamespace(ns.Name, limitRanges, pods))
+	}
+	var out bytes.Buffer
+	printReports(&out, reports)
+	return out.String(), nil
+}
+
+// run prints the report once the caches synced. With watch it prints it
+// again whenever it changes, until ctx is cancelled.
+func run(ctx context.Context, clientset kubernetes.Interface, namespace string, watch bool) error {
+	// Namespaces are cluster-scoped and ignore the namespace option
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { notify() },
+		DeleteFunc: func(obj interface{}) { notify() },
+	}
+	for _, informer := range []cache.SharedIndexInformer{
+		factory.Core().V1().Namespaces().Informer(),
+		factory.Core().V1().Pods().Informer(),
+		factory.Core().V1().LimitRanges().Informer(),
+	} {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer factory.Shutdown()
+	defer cancel()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+	// The first report does not wait for an event
+	notify()
+
+	last := ""
+	for {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+		out, err := report(factory, namespace)
+		if err != nil {
+			return err
+		}
+		if out != last {
+			if last != "" {
+				fmt.Println("---")
+			}
+			fmt.Print(out)
+			last = out
+		}
+		if !watch {
+			return nil
+		}
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, clientset, *namespace, *watch); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func resources(pairs ...string) corev1.ResourceList {
+	list := corev1.ResourceList{}
+	for i := 0; i < len(pairs); i += 2 {
+		list[corev1.ResourceName(pairs[i])] = resource.MustParse(pairs[i+1])
+	}
+	return list
+}
+
+func container(name string, requests, limits corev1.ResourceList) corev1.Container {
+	return corev1.Container{Name: name, Resources: corev1.ResourceRequirements{Requests: requests, Limits: limits}}
+}
+
+func pod(namespace, name, limitRanger string, containers ...corev1.Container) *corev1.Pod {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.PodSpec{Containers: containers},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if limitRanger != "" {
+		p.Annotations = map[string]string{limitRangerAnnotation: limitRanger}
+	}
+	return p
+}
+
+func limitRange(namespace string) *corev1.LimitRange {
+	return &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: namespace},
+		Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+			Type:           corev1.LimitTypeContainer,
+			DefaultRequest: resources("cpu", "100m", "memory", "128Mi"),
+			Default:        resources("cpu", "500m", "memory", "512Mi"),
+		}}},
+	}
+}
+
+func TestParseLimitRanger(t *testing.T) {
+	got := parseLimitRanger("LimitRanger plugin set: cpu, memory request for container app; memory limit for container app; cpu request for init container migrate")
+	want := map[string]map[string]bool{
+		"app":     {"requests.cpu": true, "requests.memory": true, "limits.memory": true},
+		"migrate": {"requests.cpu": true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := parseLimitRanger("something else"); len(got) != 0 {
+		t.Errorf("unrelated annotation parsed as %v", got)
+	}
+}
+
+func TestAuditNamespaces(t *testing.T) {
+	migrate := pod("shop", "web-1", "LimitRanger plugin set: cpu, memory limit for container app; cpu request for init container migrate",
+		container("app", resources("cpu", "250m", "memory", "256Mi"), resources("cpu", "500m", "memory", "512Mi")))
+	migrate.Spec.InitContainers = []corev1.Container{container("migrate", resources("cpu", "100m"), nil)}
+	shop := auditNamespace("shop", []*corev1.LimitRange{limitRange("shop")}, []*corev1.Pod{
+		migrate,
+		pod("shop", "api-1", "", container("api", resources("cpu", "1", "memory", "1Gi"), resources("cpu", "2", "memory", "1Gi"))),
+	})
+	dev := auditNamespace("dev", nil, []*corev1.Pod{
+		pod("dev", "scratch", "", container("shell", nil, nil)),
+		pod("dev", "cache", "", container("redis", resources("memory", "64Mi"), resources("memory", "64Mi"))),
+	})
+	empty := auditNamespace("empty", nil, nil)
+
+	var out bytes.Buffer
+	printReports(&out, []namespaceReport{dev, empty, shop})
+	want := `Namespace dev: no LimitRange; 2 containers, 0 rely on defaulting, 2 lack requests or limits !
+POD CONTAINER CPU REQUEST MEMORY REQUEST CPU LIMIT MEMORY LIMIT
+cache redis - 64Mi - 64Mi
+scratch shell - - - -
+Namespace empty: no LimitRange; 0 containers, 0 rely on defaulting, 0 lack requests or limits
+Namespace shop: LimitRange defaults (defaultRequest cpu=100m memory=128Mi, default cpu=500m memory=512Mi); 3 containers, 2 rely on defaulting, 1 lack requests or limits
+POD CONTAINER CPU REQUEST MEMORY REQUEST CPU LIMIT MEMORY LIMIT
+web-1 migrate (init) 100m* - - -
+web-1 app 250m 256Mi 500m* 512Mi*
+* set by LimitRange defaulting
+`
+	if got := testutil.Collapse(out.String()); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRunWatchesLimitRanges(t *testing.T) {
+	h := testutil.NewHarness(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev"}},
+		pod("dev", "scratch", "", container("shell", nil, nil)),
+	)
+	output := testutil.CaptureOutput(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, h.Clientset, "", true) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("run: %v", err)
+		}
+	}()
+	testutil.WaitForOutput(t, output, "Namespace dev: no LimitRange; 1 containers, 0 rely on defaulting, 1 lack requests or limits !\n")
+
+	// The LimitRange only affects pods admitted after it; scratch keeps its
+	// missing values, but the namespace is no longer flagged
+	h.WaitForWatchOf(&corev1.LimitRange{}, 1)
+	h.Add(limitRange("dev"))
+	testutil.WaitForOutput(t, output, "---\n", "Namespace dev: LimitRange defaults (defaultRequest cpu=100m memory=128Mi, default cpu=500m memory=512Mi); 1 containers, 0 rely on defaulting, 1 lack requests or limits\n")
+}
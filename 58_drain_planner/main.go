@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var nodes = flag.String("nodes", "", "comma-separated nodes to drain, in order (required)")
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "58-drain-planner")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// run syncs pods, PDBs and nodes once and prints the plan for draining the
+// nodes. It only reads from the caches; nothing is cordoned or evicted.
+func run(ctx context.Context, clientset kubernetes.Interface, names []string) error {
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	podInformer := factory.Core().V1().Pods().Informer()
+	if err := podInformer.AddIndexers(cache.Indexers{nodeIndex: podNodeIndexFunc}); err != nil {
+		return err
+	}
+	pdbLister := factory.Policy().V1().PodDisruptionBudgets().Lister()
+	nodeLister := factory.Core().V1().Nodes().Lister()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer factory.Shutdown()
+	defer cancel()
+	factory.Start(ctx.Done())
+	for typ, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("cache for %v did not sync", typ)
+		}
+	}
+
+	podsByNode := map[string][]*corev1.Pod{}
+	for _, name := range names {
+		node, err := nodeLister.Get(name)
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("node %s not found", name)
+		}
+		if err != nil {
+			return err
+		}
+		objs, err := podInformer.GetIndexer().ByIndex(nodeIndex, name)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objs {
+			podsByNode[name] = append(podsByNode[name], obj.(*corev1.Pod))
+		}
+		if !node.Spec.Unschedulable {
+			fmt.Printf("Node %s is not cordoned; a drain cordons it first\n", name)
+		}
+	}
+	pdbs, err := pdbLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	plans, err := planDrain(names, podsByNode, pdbs)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Drain plan (simulated, nothing is evicted):")
+	printPlans(os.Stdout, plans)
+	return nil
+}
+
+func main() {
+	clientset := createClientSet()
+	if *nodes == "" {
+		log.Fatal("--nodes is required")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, clientset, strings.Split(*nodes, ",")); err != nil {
+		log.Fatal(err)
+	}
+}
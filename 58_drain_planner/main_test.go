@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func pod(name, node, owner string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{"app": strings.Split(name, "-")[0]}},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+	if owner != "" {
+		controller := true
+		p.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "owner", Controller: &controller}}
+	}
+	return p
+}
+
+func pdb(name, app string, allowed, healthy, desired int32) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: allowed, CurrentHealthy: healthy, DesiredHealthy: desired},
+	}
+}
+
+func TestPlanDrain(t *testing.T) {
+	static := pod("etcd-node-1", "node-1", "", true)
+	static.Annotations = map[string]string{mirrorPodAnnotation: "hash"}
+	done := pod("job-1", "node-1", "Job", false)
+	done.Status.Phase = corev1.PodSucceeded
+	scratch := pod("scratch", "node-2", "", true)
+	scratch.Spec.Volumes = []corev1.Volume{{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+	stale := pdb("cache", "cache", 1, 2, 1)
+	stale.Generation = 2
+	stale.Status.ObservedGeneration = 1
+
+	podsByNode := map[string][]*corev1.Pod{
+		"node-1": {
+			pod("web-1", "node-1", "ReplicaSet", true),
+			pod("log-agent-1", "node-1", "DaemonSet", true),
+			static,
+			done,
+			pod("db-1", "node-1", "StatefulSet", true),
+			pod("db-2", "node-1", "StatefulSet", false),
+		},
+		"node-2": {
+			pod("web-2", "node-2", "ReplicaSet", true),
+			pod("api-1", "node-2", "ReplicaSet", true),
+			pod("cache-1", "node-2", "ReplicaSet", true),
+			scratch,
+		},
+	}
+	pdbs := []*policyv1.PodDisruptionBudget{
+		pdb("web", "web", 1, 3, 2),
+		pdb("db", "db", 0, 2, 3),
+		pdb("api", "api", 1, 3, 2),
+		pdb("api-all", "api", 1, 3, 2),
+		stale,
+	}
+	plans, err := planDrain([]string{"node-1", "node-2", "node-3"}, podsByNode, pdbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	printPlans(&out, plans)
+	want := `Node node-1: 6 pods, 1 evict, 1 delete, 2 skip, 2 blocked
+POD RESULT REASON
+shop/db-1 blocked PDB db allows no disruptions now: 2 healthy, 3 required
+shop/db-2 blocked PDB db allows no disruptions now: 2 healthy, 3 required
+shop/etcd-node-1 skip static pod, managed by the kubelet
+shop/job-1 delete finished, deleted without eviction
+shop/log-agent-1 skip DaemonSet pod, ignored by drain
+shop/web-1 evict PDB web, disruption 1 of 1 allowed
+Node node-2: 4 pods, 1 evict, 0 delete, 0 skip, 3 blocked
+POD RESULT REASON
+shop/api-1 blocked covered by PDBs api, api-all; the eviction API refuses pods with more than one PDB
+shop/cache-1 blocked PDB cache status is not up to date with its spec yet
+shop/scratch evict no PDB; not managed by a controller, nothing recreates it (drain needs --force); emptyDir data is lost (drain needs --delete-emptydir-data)
+shop/web-2 blocked PDB web allows 1 disruptions, used by shop/web-1; waits until replacements are ready
+Node node-3: 0 pods, 0 evict, 0 delete, 0 skip, 0 blocked
+The drain would block on 5 pods
+`
+	if got := testutil.Collapse(out.String()); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnhealthyPodEviction(t *testing.T) {
+	alwaysAllow := pdb("db", "db", 0, 2, 3)
+	policy := policyv1.AlwaysAllow
+	alwaysAllow.Spec.UnhealthyPodEvictionPolicy = &policy
+	healthyBudget := pdb("db", "db", 0, 3, 3)
+
+	for _, tc := range []struct {
+		name   string
+		pdb    *policyv1.PodDisruptionBudget
+		result string
+	}{
+		{"IfHealthyBudget below desired", pdb("db", "db", 0, 2, 3), resultBlocked},
+		{"IfHealthyBudget at desired", healthyBudget, resultEvict},
+		{"AlwaysAllow", alwaysAllow, resultEvict},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := evict(pod("db-2", "node-1", "StatefulSet", false), "shop/db-2", &budget{pdb: tc.pdb})
+			if got.Result != tc.result {
+				t.Errorf("got %s (%s), want %s", got.Result, got.Reason, tc.result)
+			}
+		})
+	}
+}
+
+func TestRunReadsTheCaches(t *testing.T) {
+	h := testutil.NewHarness(t,
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+		pod("web-1", "node-1", "ReplicaSet", true),
+		pod("web-2", "node-2", "ReplicaSet", true),
+		pod("web-3", "node-3", "ReplicaSet", true),
+		pdb("web", "web", 1, 3, 2),
+	)
+	output := testutil.CaptureOutput(t)
+
+	if err := run(context.Background(), h.Clientset, []string{"node-1", "node-2"}); err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForOutput(t, output, "The drain would block on 1 pods\n")
+	want := `Node node-2 is not cordoned; a drain cordons it first
+Drain plan (simulated, nothing is evicted):
+Node node-1: 1 pods, 1 evict, 0 delete, 0 skip, 0 blocked
+POD RESULT REASON
+shop/web-1 evict PDB web, disruption 1 of 1 allowed
+Node node-2: 1 pods, 0 evict, 0 delete, 0 skip, 1 blocked
+POD RESULT REASON
+shop/web-2 blocked PDB web allows 1 disruptions, used by shop/web-1; waits until replacements are ready
+The drain would block on 1 pods
+`
+	if got := testutil.Collapse(output()); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := run(context.Background(), h.Clientset, []string{"node-9"}); err == nil || err.Error() != "node node-9 not found" {
+		t.Errorf("unknown node: got %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// nodeIndex indexes pods by spec.nodeName, as in 05_informer_index. Pods that
+// are not scheduled yet are indexed under "".
+const nodeIndex = "node"
+
+func podNodeIndexFunc(obj interface{}) ([]string, error) {
+	pod := obj.(*corev1.Pod)
+	return []string{pod.Spec.NodeName}, nil
+}
+
+// mirrorPodAnnotation marks the API copy of a static pod
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// What a drain would do with a pod
+const (
+	resultEvict   = "evict"
+	resultDelete  = "delete"
+	resultSkip    = "skip"
+	resultBlocked = "blocked"
+)
+
+// step is the simulated outcome for one pod
+type step struct {
+	Pod    string // namespace/name
+	Result string
+	Reason string
+}
+
+// nodePlan is the outcome for every pod of a node
+type nodePlan struct {
+	Node  string
+	Steps []step
+}
+
+// budget tracks the evictions a plan charges to one PDB
+type budget struct {
+	pdb  *policyv1.PodDisruptionBudget
+	used []string
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// matchingBudgets returns the PDBs whose selector matches the pod. A PDB
+// only covers pods of its own namespace; in policy/v1 an empty selector
+// matches every pod of it, and a missing one matches none.
+func matchingBudgets(pod *corev1.Pod, budgets []*budget) ([]*budget, error) {
+	var matching []*budget
+	for _, b := range budgets {
+		if b.pdb.Namespace != pod.Namespace || b.pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(b.pdb.Spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("PDB %s/%s: %w", b.pdb.Namespace, b.pdb.Name, err)
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			matching = append(matching, b)
+		}
+	}
+	return matching, nil
+}
+
+// evict decides whether the eviction API would accept the pod, given the
+// evictions the plan already charged to its PDB. It follows the checks of
+// the API server: a PDB whose status is behind its spec rejects every
+// eviction, an unhealthy pod may go without using the budget, and otherwise
+// status.disruptionsAllowed is what is left to spend.
+func evict(pod *corev1.Pod, key string, b *budget) step {
+	pdb := b.pdb
+	name := "PDB " + pdb.Name
+	if pdb.Status.ObservedGeneration < pdb.Generation {
+		return step{key, resultBlocked, name + " status is not up to date with its spec yet"}
+	}
+	if !podReady(pod) {
+		policy := policyv1.IfHealthyBudget
+		if pdb.Spec.UnhealthyPodEvictionPolicy != nil {
+			policy = *pdb.Spec.UnhealthyPodEvictionPolicy
+		}
+		if policy == policyv1.AlwaysAllow || pdb.Status.CurrentHealthy >= pdb.Status.DesiredHealthy {
+			return step{key, resultEvict, fmt.Sprintf("not ready, %s does not count it (unhealthyPodEvictionPolicy %s)", name, policy)}
+		}
+	}
+	allowed := int(pdb.Status.DisruptionsAllowed)
+	if len(b.used) < allowed {
+		b.used = append(b.used, key)
+		return step{key, resultEvict, fmt.Sprintf("%s, disruption %d of %d allowed", name, len(b.used), allowed)}
+	}
+	if allowed == 0 {
+		return step{key, resultBlocked, fmt.Sprintf("%s allows no disruptions now: %d healthy, %d required",
+			name, pdb.Status.CurrentHealthy, pdb.Status.DesiredHealthy)}
+	}
+	return step{key, resultBlocked, fmt.Sprintf("%s allows %d disruptions, used by %s; waits until replacements are ready",
+		name, allowed, strings.Join(b.used, ", "))}
+}
+
+// caveats notes what a drain needs extra flags for
+func caveats(pod *corev1.Pod) string {
+	var notes []string
+	if metav1.GetControllerOf(pod) == nil {
+		notes = append(notes, "not managed by a controller, nothing recreates it (drain needs --force)")
+	}
+	for _, v := range pod.Spec.Volumes {
+		if v.EmptyDir != nil {
+			notes = append(notes, "emptyDir data is lost (drain needs --delete-emptydir-data)")
+			break
+		}
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return "; " + strings.Join(notes, "; ")
+}
+
+// planDrain simulates draining the nodes in order. Evictions are charged to
+// the PDBs across all nodes: the plan assumes no evicted pod is replaced
+// before the last node is drained, the worst case for a maintenance window.
+// Nothing is written to the cluster.
+func planDrain(nodes []string, podsByNode map[string][]*corev1.Pod, pdbs []*policyv1.PodDisruptionBudget) ([]nodePlan, error) {
+	budgets := make([]*budget, 0, len(pdbs))
+	for _, pdb := range pdbs {
+		budgets = append(budgets, &budget{pdb: pdb})
+	}
+	slices.SortFunc(budgets, func(a, b *budget) int { return strings.Compare(a.pdb.Name, b.pdb.Name) })
+
+	var plans []nodePlan
+	for _, node := range nodes {
+		plan := nodePlan{Node: node}
+		pods := slices.Clone(podsByNode[node])
+		slices.SortFunc(pods, func(a, b *corev1.Pod) int {
+			return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+		})
+		for _, pod := range pods {
+			key := pod.Namespace + "/" + pod.Name
+			if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+				plan.Steps = append(plan.Steps, step{key, resultSkip, "static pod, managed by the kubelet"})
+				continue
+			}
+			if ref := metav1.GetControllerOf(pod); ref != nil && ref.Kind == "DaemonSet" {
+				plan.Steps = append(plan.Steps, step{key, resultSkip, "DaemonSet pod, ignored by drain"})
+				continue
+			}
+			if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				plan.Steps = append(plan.Steps, step{key, resultDelete, "finished, deleted without eviction"})
+				continue
+			}
+
+			matching, err := matchingBudgets(pod, budgets)
+			if err != nil {
+				return nil, err
+			}
+			var s step
+			switch len(matching) {
+			case 0:
+				s = step{key, resultEvict, "no PDB"}
+			case 1:
+				s = evict(pod, key, matching[0])
+			default:
+				var names []string
+				for _, b := range matching {
+					names = append(names, b.pdb.Name)
+				}
+				s = step{key, resultBlocked, fmt.Sprintf("covered by PDBs %s; the eviction API refuses pods with more than one PDB",
+					strings.Join(names, ", "))}
+			}
+			if s.Result == resultEvict {
+				s.Reason += caveats(pod)
+			}
+			plan.Steps = append(plan.Steps, s)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// printPlans writes a table per node and a summary of the blocked pods
+func printPlans(w io.Writer, plans []nodePlan) {
+	blocked := 0
+	for _, plan := range plans {
+		counts := map[string]int{}
+		for _, s := range plan.Steps {
+			counts[s.Result]++
+		}
+		blocked += counts[resultBlocked]
+		fmt.Fprintf(w, "Node %s: %d pods, %d evict, %d delete, %d skip, %d blocked\n", plan.Node, len(plan.Steps),
+			counts[resultEvict], counts[resultDelete], counts[resultSkip], counts[resultBlocked])
+		if len(plan.Steps) == 0 {
+			continue
+		}
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "  POD\tRESULT\tREASON")
+		for _, s := range plan.Steps {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", s.Pod, s.Result, s.Reason)
+		}
+		tw.Flush()
+	}
+	if blocked == 0 {
+		fmt.Fprintln(w, "The drain would not block")
+	} else {
+		fmt.Fprintf(w, "The drain would block on %d pods\n", blocked)
+	}
+}
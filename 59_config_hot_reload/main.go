@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/hotreload"
+)
+
+var (
+	namespace = flag.String("namespace", "default", "namespace of the ConfigMap or Secret")
+	name      = flag.String("name", "app-config", "name of the ConfigMap or Secret")
+	secret    = flag.Bool("secret", false, "read the configuration from a Secret instead of a ConfigMap")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "59-config-hot-reload")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// appConfig is the configuration the application reloads
+type appConfig struct {
+	Greeting string
+	Interval time.Duration
+	LogLevel string
+}
+
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// parseConfig reads the keys greeting, interval and log-level. Missing keys
+// keep their defaults; invalid values reject the whole configuration.
+func parseConfig(data map[string][]byte) (*appConfig, error) {
+	cfg := &appConfig{Greeting: "hello", Interval: 5 * time.Second, LogLevel: "info"}
+	if v, ok := data["greeting"]; ok {
+		cfg.Greeting = string(v)
+	}
+	if v, ok := data["interval"]; ok {
+		d, err := time.ParseDuration(string(v))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("interval %q is not a positive duration", v)
+		}
+		cfg.Interval = d
+	}
+	if v, ok := data["log-level"]; ok {
+		if !slices.Contains(logLevels, string(v)) {
+			return nil, fmt.Errorf("log-level %q is not one of %v", v, logLevels)
+		}
+		cfg.LogLevel = string(v)
+	}
+	return cfg, nil
+}
+
+// run greets on every interval with whatever configuration is current, until
+// ctx is cancelled. The watcher swaps the configuration in the background.
+func run(ctx context.Context, clientset kubernetes.Interface, kind hotreload.Kind, namespace, name string) error {
+	config := hotreload.NewConfig(parseConfig)
+	watcher := hotreload.NewWatcher(clientset, kind, namespace, name)
+	// The config is added first: when it rejects the data, the print below
+	// does not run
+	watcher.AddHandler(config)
+	watcher.AddHandler(hotreload.HandlerFunc(func(data map[string][]byte, checksum string) error {
+		cfg := config.Get()
+		fmt.Printf("[Reload] %s %s/%s checksum %s: greeting=%q interval=%s log-level=%s\n",
+			kind, namespace, name, checksum[:12], cfg.Greeting, cfg.Interval, cfg.LogLevel)
+		return nil
+	}))
+
+	done := make(chan error, 1)
+	go func() { done <- watcher.Run(ctx) }()
+
+	fmt.Printf("Waiting for %s %s/%s...\n", kind, namespace, name)
+	for {
+		// Get is read on every tick, so a new interval applies from the next one
+		wait := time.Second
+		if cfg := config.Get(); cfg != nil {
+			fmt.Printf("[App] %s (log level %s)\n", cfg.Greeting, cfg.LogLevel)
+			wait = cfg.Interval
+		}
+		select {
+		case <-time.After(wait):
+		case err := <-done:
+			return err
+		}
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	kind := hotreload.ConfigMap
+	if *secret {
+		kind = hotreload.Secret
+	}
+	if err := run(ctx, clientset, kind, *namespace, *name); err != nil {
+		log.Fatal(err)
+	}
+}
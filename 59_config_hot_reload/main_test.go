@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/hotreload"
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func configMap(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"},
+		Data:       data,
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := parseConfig(nil)
+	if err != nil || *cfg != (appConfig{Greeting: "hello", Interval: 5 * time.Second, LogLevel: "info"}) {
+		t.Errorf("defaults: got %+v, %v", cfg, err)
+	}
+	cfg, err = parseConfig(map[string][]byte{"greeting": []byte("hi"), "interval": []byte("1m"), "log-level": []byte("debug")})
+	if err != nil || *cfg != (appConfig{Greeting: "hi", Interval: time.Minute, LogLevel: "debug"}) {
+		t.Errorf("got %+v, %v", cfg, err)
+	}
+	for _, data := range []map[string][]byte{
+		{"interval": []byte("soon")},
+		{"interval": []byte("0s")},
+		{"log-level": []byte("loud")},
+	} {
+		if _, err := parseConfig(data); err == nil {
+			t.Errorf("%q was accepted", data)
+		}
+	}
+}
+
+func TestRunReloadsTheConfigMap(t *testing.T) {
+	h := testutil.NewHarness(t)
+	output := testutil.CaptureOutput(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, h.Clientset, hotreload.ConfigMap, "default", "app-config") }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("run: %v", err)
+		}
+	}()
+	testutil.WaitForOutput(t, output, "Waiting for ConfigMap default/app-config...\n")
+
+	h.WaitForWatchOf(&corev1.ConfigMap{}, 1)
+	first := map[string]string{"greeting": "hello", "interval": "20ms"}
+	h.Add(configMap(first))
+	testutil.WaitForOutput(t, output,
+		`[Reload] ConfigMap default/app-config checksum `+checksum(first)+`: greeting="hello" interval=20ms log-level=info`+"\n",
+		"[App] hello (log level info)\n")
+
+	// A rejected value keeps the greeting; the fix is picked up
+	h.Update(configMap(map[string]string{"greeting": "bonjour", "interval": "20ms", "log-level": "loud"}))
+	fixed := map[string]string{"greeting": "bonjour", "interval": "20ms", "log-level": "debug"}
+	h.Update(configMap(fixed))
+	testutil.WaitForOutput(t, output,
+		`[Reload] ConfigMap default/app-config checksum `+checksum(fixed)+`: greeting="bonjour" interval=20ms log-level=debug`+"\n",
+		"[App] bonjour (log level debug)\n")
+}
+
+func checksum(data map[string]string) string {
+	b := map[string][]byte{}
+	for k, v := range data {
+		b[k] = []byte(v)
+	}
+	return hotreload.Checksum(b)[:12]
+}
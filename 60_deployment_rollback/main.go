@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace  = flag.String("namespace", "default", "namespace of the Deployment")
+	name       = flag.String("name", "web", "name of the Deployment")
+	undo       = flag.Bool("undo", false, "roll the Deployment back instead of only listing its revisions")
+	toRevision = flag.Int64("to-revision", 0, "revision --undo rolls back to (0 for the previous one)")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "60-deployment-rollback")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// run prints the revision history of the Deployment and, with undo, rolls it
+// back to toRevision. The Deployment and its ReplicaSets are read once from
+// the API, as kubectl does, since the rollback must start from their
+// current state.
+func run(ctx context.Context, clientset kubernetes.Interface, namespace, name string, undo bool, toRevision int64) error {
+	deploy, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	replicaSets, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	entries, err := history(deploy, replicaSets.Items)
+	if err != nil {
+		return err
+	}
+	printHistory(os.Stdout, deploy, entries)
+	if !undo {
+		return nil
+	}
+
+	target, err := findRevision(entries, toRevision)
+	if err != nil {
+		return fmt.Errorf("cannot roll back deployment %s: %w", name, err)
+	}
+	changed, err := rollback(ctx, clientset, deploy, target)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Printf("Skipped rollback: deployment %s already runs the template of revision %d\n", name, target.Revision)
+		return nil
+	}
+	fmt.Printf("Rolled back deployment %s to revision %d (ReplicaSet %s, images %s)\n",
+		name, target.Revision, target.ReplicaSet.Name, images(target.ReplicaSet.Spec.Template))
+	fmt.Println("The deployment controller scales that ReplicaSet up again and gives it the next revision number")
+	return nil
+}
+
+func main() {
+	clientset := createClientSet()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, clientset, *namespace, *name, *undo, *toRevision); err != nil {
+		log.Fatal(err)
+	}
+}
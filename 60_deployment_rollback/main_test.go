@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func template(image string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: image}}},
+	}
+}
+
+func deployment(revision, image, changeCause string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", UID: types.UID("web-uid"),
+			Annotations: map[string]string{revisionAnnotation: revision, changeCauseAnnotation: changeCause},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: template(image),
+		},
+	}
+}
+
+func replicaSet(hash, revision, image, changeCause string, owner types.UID) *appsv1.ReplicaSet {
+	controller := true
+	t := template(image)
+	t.Labels[appsv1.DefaultDeploymentUniqueLabelKey] = hash
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "web-" + hash, Namespace: "default",
+			Labels:          t.Labels,
+			Annotations:     map[string]string{revisionAnnotation: revision},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", UID: owner, Controller: &controller}},
+		},
+		Spec: appsv1.ReplicaSetSpec{Template: t},
+	}
+	if changeCause != "" {
+		rs.Annotations[changeCauseAnnotation] = changeCause
+	}
+	return rs
+}
+
+func clusterObjects() []*appsv1.ReplicaSet {
+	current := replicaSet("7d9c", "3", "nginx:1.27", "image 1.27", "web-uid")
+	current.Status.Replicas = 3
+	return []*appsv1.ReplicaSet{
+		replicaSet("5b8f", "1", "nginx:1.25", "", "web-uid"),
+		current,
+		replicaSet("6c4d", "2", "nginx:1.26", "image 1.26", "web-uid"),
+		// Matches the selector, but another Deployment owns it
+		replicaSet("9a1e", "4", "nginx:latest", "", "other-uid"),
+	}
+}
+
+func startRun(t *testing.T, undo bool, toRevision int64) (*fake.Clientset, func() string, error) {
+	t.Helper()
+	clientset := fake.NewSimpleClientset(deployment("3", "nginx:1.27", "image 1.27"))
+	for _, rs := range clusterObjects() {
+		clientset.Tracker().Add(rs)
+	}
+	output := testutil.CaptureOutput(t)
+	err := run(context.Background(), clientset, "default", "web", undo, toRevision)
+	return clientset, output, err
+}
+
+func TestHistory(t *testing.T) {
+	_, output, err := startRun(t, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Deployment default/web: revision 3, 3 revisions kept (revisionHistoryLimit 10)
+REVISION REPLICASET REPLICAS IMAGES CHANGE-CAUSE
+1 web-5b8f 0 nginx:1.25 <none>
+2 web-6c4d 0 nginx:1.26 image 1.26
+3 (current) web-7d9c 3 nginx:1.27 image 1.27
+`
+	testutil.WaitForOutput(t, output, "3 (current)")
+	if got := testutil.Collapse(output()); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUndoRestoresThePreviousTemplate(t *testing.T) {
+	clientset, output, err := startRun(t, true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForOutput(t, output, "Rolled back deployment web to revision 2 (ReplicaSet web-6c4d, images nginx:1.26)\n")
+
+	deploy, err := clientset.AppsV1().Deployments("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := deploy.Spec.Template.Spec.Containers[0].Image; got != "nginx:1.26" {
+		t.Errorf("image = %s, want nginx:1.26", got)
+	}
+	if _, ok := deploy.Spec.Template.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok {
+		t.Error("pod-template-hash label was copied into the Deployment")
+	}
+	// The revision stays for the controller to bump; the change-cause comes
+	// back with the template
+	if deploy.Annotations[revisionAnnotation] != "3" || deploy.Annotations[changeCauseAnnotation] != "image 1.26" {
+		t.Errorf("annotations = %v", deploy.Annotations)
+	}
+}
+
+func TestUndoToRevision(t *testing.T) {
+	clientset, _, err := startRun(t, true, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deploy, _ := clientset.AppsV1().Deployments("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	if got := deploy.Spec.Template.Spec.Containers[0].Image; got != "nginx:1.25" {
+		t.Errorf("image = %s, want nginx:1.25", got)
+	}
+	if _, ok := deploy.Annotations[changeCauseAnnotation]; ok {
+		t.Errorf("revision 1 had no change-cause, got %q", deploy.Annotations[changeCauseAnnotation])
+	}
+}
+
+func TestUndoRefusals(t *testing.T) {
+	if _, _, err := startRun(t, true, 4); err == nil || !strings.Contains(err.Error(), "revision 4 not found") {
+		t.Errorf("revision of another Deployment: got %v", err)
+	}
+
+	clientset, output, err := startRun(t, true, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForOutput(t, output, "Skipped rollback: deployment web already runs the template of revision 3\n")
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "patch" {
+			t.Error("rollback to the current template sent a patch")
+		}
+	}
+
+	paused := deployment("3", "nginx:1.27", "")
+	paused.Spec.Paused = true
+	target := revisionEntry{Revision: 2, ReplicaSet: replicaSet("6c4d", "2", "nginx:1.26", "", "web-uid")}
+	if _, err := rollback(context.TODO(), fake.NewSimpleClientset(paused), paused, target); err == nil {
+		t.Error("paused Deployment was rolled back")
+	}
+}
+
+func TestFindPreviousRevision(t *testing.T) {
+	only := []revisionEntry{{Revision: 1, Current: true}}
+	if _, err := findRevision(only, 0); err == nil {
+		t.Error("found a revision before the only one")
+	}
+}
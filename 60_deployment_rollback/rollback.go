@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+// fieldManager takes over the pod template fields a rollback restores
+var fieldManager = clientid.FieldManager("60-deployment-rollback")
+
+const (
+	// revisionAnnotation is set by the deployment controller on the
+	// Deployment and on each of its ReplicaSets. A rollback does not restore
+	// an old number: the old ReplicaSet becomes the newest revision.
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+	// changeCauseAnnotation is copied from the Deployment to the ReplicaSet
+	// it creates, and is what kubectl rollout history shows
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// annotationsSkippedOnRollback are the ReplicaSet annotations that describe
+// the ReplicaSet itself, not the template. Like kubectl rollout undo, a
+// rollback copies every other annotation of the ReplicaSet back onto the
+// Deployment, so a change-cause comes back with its template.
+var annotationsSkippedOnRollback = map[string]bool{
+	corev1.LastAppliedConfigAnnotation:          true,
+	revisionAnnotation:                          true,
+	"deployment.kubernetes.io/revision-history": true,
+	"deployment.kubernetes.io/desired-replicas": true,
+	"deployment.kubernetes.io/max-replicas":     true,
+	"deprecated.deployment.rollback.to":         true,
+}
+
+// revisionEntry is one ReplicaSet of a Deployment's history
+type revisionEntry struct {
+	Revision    int64
+	ReplicaSet  *appsv1.ReplicaSet
+	ChangeCause string
+	Current     bool
+}
+
+// revisionOf parses the revision annotation, 0 when it is missing
+func revisionOf(obj metav1.Object) (int64, error) {
+	v, ok := obj.GetAnnotations()[revisionAnnotation]
+	if !ok {
+		return 0, nil
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// history returns the ReplicaSets the Deployment controls, oldest revision
+// first. ReplicaSets matching the selector but owned by something else are
+// left out, as the deployment controller would not adopt them either.
+func history(deploy *appsv1.Deployment, replicaSets []appsv1.ReplicaSet) ([]revisionEntry, error) {
+	current, err := revisionOf(deploy)
+	if err != nil {
+		return nil, fmt.Errorf("deployment %s: %w", deploy.Name, err)
+	}
+	var entries []revisionEntry
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		if ref := metav1.GetControllerOf(rs); ref == nil || ref.UID != deploy.UID {
+			continue
+		}
+		revision, err := revisionOf(rs)
+		if err != nil {
+			return nil, fmt.Errorf("replicaset %s: %w", rs.Name, err)
+		}
+		entries = append(entries, revisionEntry{
+			Revision:    revision,
+			ReplicaSet:  rs,
+			ChangeCause: rs.Annotations[changeCauseAnnotation],
+			Current:     revision == current,
+		})
+	}
+	slices.SortFunc(entries, func(a, b revisionEntry) int { return int(a.Revision - b.Revision) })
+	return entries, nil
+}
+
+// images lists the images of a pod template's containers
+func images(template corev1.PodTemplateSpec) string {
+	var names []string
+	for _, c := range template.Spec.Containers {
+		names = append(names, c.Image)
+	}
+	return strings.Join(names, ",")
+}
+
+// printHistory writes a row per revision, like kubectl rollout history
+func printHistory(w io.Writer, deploy *appsv1.Deployment, entries []revisionEntry) {
+	fmt.Fprintf(w, "Deployment %s/%s: revision %s, %d revisions kept (revisionHistoryLimit %s)\n",
+		deploy.Namespace, deploy.Name, deploy.Annotations[revisionAnnotation], len(entries), historyLimit(deploy))
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "  REVISION\tREPLICASET\tREPLICAS\tIMAGES\tCHANGE-CAUSE")
+	for _, e := range entries {
+		revision := strconv.FormatInt(e.Revision, 10)
+		if e.Current {
+			revision += " (current)"
+		}
+		cause := e.ChangeCause
+		if cause == "" {
+			cause = "<none>"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%d\t%s\t%s\n", revision, e.ReplicaSet.Name, e.ReplicaSet.Status.Replicas,
+			images(e.ReplicaSet.Spec.Template), cause)
+	}
+	tw.Flush()
+}
+
+func historyLimit(deploy *appsv1.Deployment) string {
+	if deploy.Spec.RevisionHistoryLimit == nil {
+		return "10"
+	}
+	return strconv.Itoa(int(*deploy.Spec.RevisionHistoryLimit))
+}
+
+// findRevision returns the entry to roll back to. Revision 0 means the one
+// before the current revision, as for kubectl rollout undo.
+func findRevision(entries []revisionEntry, revision int64) (revisionEntry, error) {
+	if revision == 0 {
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].Current && i > 0 {
+				return entries[i-1], nil
+			}
+		}
+		return revisionEntry{}, fmt.Errorf("no revision before the current one")
+	}
+	for _, e := range entries {
+		if e.Revision == revision {
+			return e, nil
+		}
+	}
+	return revisionEntry{}, fmt.Errorf("revision %d not found", revision)
+}
+
+// rollbackPatch returns a JSON patch that replaces the Deployment's pod
+// template with the ReplicaSet's, without the pod-template-hash label the
+// deployment controller adds to it, and its annotations with the
+// Deployment's own plus the ReplicaSet's template annotations. "replace"
+// rather than a merge patch, so fields the old template did not have are
+// removed instead of kept.
+func rollbackPatch(deploy *appsv1.Deployment, rs *appsv1.ReplicaSet) ([]byte, error) {
+	template := rs.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+
+	annotations := map[string]string{}
+	for k, v := range deploy.Annotations {
+		if annotationsSkippedOnRollback[k] {
+			annotations[k] = v
+		}
+	}
+	for k, v := range rs.Annotations {
+		if !annotationsSkippedOnRollback[k] {
+			annotations[k] = v
+		}
+	}
+	return json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/spec/template", "value": template},
+		{"op": "replace", "path": "/metadata/annotations", "value": annotations},
+	})
+}
+
+// sameTemplate compares two pod templates, ignoring the pod-template-hash label
+func sameTemplate(a, b corev1.PodTemplateSpec) bool {
+	a, b = *a.DeepCopy(), *b.DeepCopy()
+	delete(a.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	delete(b.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	return equality.Semantic.DeepEqual(a, b)
+}
+
+// rollback patches the Deployment back to the template of a revision. It
+// returns false when the Deployment already runs that template. A paused
+// Deployment is refused: the patch would be stored but not rolled out, and
+// resuming later would start a rollout nobody expects.
+func rollback(ctx context.Context, clientset kubernetes.Interface, deploy *appsv1.Deployment, target revisionEntry) (bool, error) {
+	if deploy.Spec.Paused {
+		return false, fmt.Errorf("deployment %s is paused; resume it before rolling back", deploy.Name)
+	}
+	if sameTemplate(deploy.Spec.Template, target.ReplicaSet.Spec.Template) {
+		return false, nil
+	}
+	patch, err := rollbackPatch(deploy, target.ReplicaSet)
+	if err != nil {
+		return false, err
+	}
+	_, err = clientset.AppsV1().Deployments(deploy.Namespace).Patch(ctx, deploy.Name, types.JSONPatchType, patch, metav1.PatchOptions{FieldManager: fieldManager})
+	return err == nil, err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace = flag.String("namespace", "default", "namespace of the Deployment")
+	name      = flag.String("name", "web", "name of the Deployment")
+	noWait    = flag.Bool("no-wait", false, "return after the patch instead of waiting for the rollout")
+	timeout   = flag.Duration("timeout", 10*time.Minute, "give up waiting for the rollout after this long")
+)
+
+// createClientset creates and returns a Kubernetes clientset
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "61-rollout-restart")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// waitForRollout prints the rollout status whenever the Deployment changes,
+// and returns once it rolled out. It is the waiter of
+// 48_statefulset_rollout: the handler only signals a change and the status
+// is computed from the lister. A field selector limits the informer to the
+// one Deployment.
+func waitForRollout(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error {
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) { opts.FieldSelector = selector }),
+	)
+	lister := factory.Apps().V1().Deployments().Lister()
+
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	if _, err := factory.Apps().V1().Deployments().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { notify() },
+		DeleteFunc: func(obj interface{}) { notify() },
+	}); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer factory.Shutdown()
+	defer cancel()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+	notify()
+
+	last := ""
+	for {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+		deploy, err := lister.Deployments(namespace).Get(name)
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("deployment %s was deleted", name)
+		}
+		if err != nil {
+			return err
+		}
+		status, done, err := rolloutStatus(deploy)
+		if err != nil {
+			return err
+		}
+		if status != last {
+			fmt.Println(status)
+			last = status
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+func main() {
+	clientset := createClientSet()
+	// Ctrl-C or SIGTERM stops waiting; the rollout itself continues
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	deploy, err := restart(ctx, clientset, *namespace, *name, time.Now())
+	if err != nil {
+		log.Fatalf("Failed to restart deployment: %v", err)
+	}
+	fmt.Printf("deployment %s/%s restarted at %s (generation %d)\n",
+		deploy.Namespace, deploy.Name, deploy.Spec.Template.Annotations[restartedAtAnnotation], deploy.Generation)
+	if *noWait {
+		return
+	}
+
+	waitCtx, cancel := context.WithTimeoutCause(ctx, *timeout, fmt.Errorf("rollout not finished after %v", *timeout))
+	defer cancel()
+	if err := waitForRollout(waitCtx, clientset, *namespace, *name); err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+}
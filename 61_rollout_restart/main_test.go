@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+// deployment returns a Deployment of 3 replicas whose controller observed
+// generation observed, with the given replica counts in its status
+func deployment(generation, observed int64, replicas, updated, available int32) *appsv1.Deployment {
+	three := int32(3)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: generation},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &three,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"team": "shop"}},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: observed,
+			Replicas:           replicas,
+			UpdatedReplicas:    updated,
+			AvailableReplicas:  available,
+		},
+	}
+}
+
+func TestRestartAddsTheAnnotation(t *testing.T) {
+	clientset := fake.NewSimpleClientset(deployment(1, 1, 3, 3, 3))
+	at := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	deploy, err := restart(context.TODO(), clientset, "default", "web", at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	annotations := deploy.Spec.Template.Annotations
+	if annotations[restartedAtAnnotation] != "2026-10-15T09:30:00Z" || annotations["team"] != "shop" {
+		t.Errorf("template annotations = %v", annotations)
+	}
+
+	paused := deployment(1, 1, 3, 3, 3)
+	paused.Spec.Paused = true
+	if _, err := restart(context.TODO(), fake.NewSimpleClientset(paused), "default", "web", at); err == nil {
+		t.Error("paused Deployment was restarted")
+	}
+}
+
+func TestRolloutStatus(t *testing.T) {
+	deadline := deployment(2, 2, 4, 1, 3)
+	deadline.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded",
+	}}
+	if _, _, err := rolloutStatus(deadline); err == nil {
+		t.Error("progress deadline not reported")
+	}
+
+	for _, tt := range []struct {
+		deploy *appsv1.Deployment
+		status string
+		done   bool
+	}{
+		{deployment(2, 1, 3, 0, 3), "Waiting for deployment spec update to be observed...", false},
+		{deployment(2, 2, 4, 1, 3), `Waiting for deployment "web" rollout to finish: 1 out of 3 new replicas have been updated...`, false},
+		{deployment(2, 2, 4, 3, 3), `Waiting for deployment "web" rollout to finish: 1 old replicas are pending termination...`, false},
+		{deployment(2, 2, 3, 3, 2), `Waiting for deployment "web" rollout to finish: 2 of 3 updated replicas are available...`, false},
+		{deployment(2, 2, 3, 3, 3), `deployment "web" successfully rolled out`, true},
+	} {
+		status, done, err := rolloutStatus(tt.deploy)
+		if err != nil || status != tt.status || done != tt.done {
+			t.Errorf("got %q, %t, %v; want %q, %t", status, done, err, tt.status, tt.done)
+		}
+	}
+}
+
+func TestWaitForRolloutFollowsTheStatus(t *testing.T) {
+	// The restart patch bumped the generation; the controller has not seen it
+	h := testutil.NewHarness(t, deployment(2, 1, 3, 0, 3))
+	output := testutil.CaptureOutput(t)
+
+	done := make(chan error, 1)
+	go func() { done <- waitForRollout(context.Background(), h.Clientset, "default", "web") }()
+	testutil.WaitForOutput(t, output, "Waiting for deployment spec update to be observed...\n")
+
+	h.WaitForWatchOf(&appsv1.Deployment{}, 1)
+	h.Update(deployment(2, 2, 4, 1, 3))
+	testutil.WaitForOutput(t, output, `Waiting for deployment "web" rollout to finish: 1 out of 3 new replicas have been updated...`+"\n")
+	h.Update(deployment(2, 2, 3, 3, 2))
+	testutil.WaitForOutput(t, output, `Waiting for deployment "web" rollout to finish: 2 of 3 updated replicas are available...`+"\n")
+	h.Update(deployment(2, 2, 3, 3, 3))
+	testutil.WaitForOutput(t, output, `deployment "web" successfully rolled out`+"\n")
+	if err := <-done; err != nil {
+		t.Errorf("waitForRollout: %v", err)
+	}
+}
+
+func TestWaitForRolloutTimesOut(t *testing.T) {
+	h := testutil.NewHarness(t, deployment(2, 1, 3, 0, 3))
+	testutil.CaptureOutput(t)
+	ctx, cancel := context.WithTimeoutCause(context.Background(), 100*time.Millisecond, context.DeadlineExceeded)
+	defer cancel()
+	if err := waitForRollout(ctx, h.Clientset, "default", "web"); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want the timeout cause", err)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+// fieldManager owns the restartedAt annotation after a restart
+var fieldManager = clientid.FieldManager("61-rollout-restart")
+
+// restartedAtAnnotation is the pod template annotation kubectl rollout
+// restart sets. Any change to the template starts a rollout; a timestamp is
+// a change that means nothing else, and shows when the restart was asked for.
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// restartPatch returns a strategic merge patch that sets the annotation on
+// the pod template and leaves every other annotation as it is
+func restartPatch(at time.Time) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{restartedAtAnnotation: at.Format(time.RFC3339)},
+				},
+			},
+		},
+	})
+}
+
+// restart patches the Deployment's pod template, which makes the deployment
+// controller replace every pod following the Deployment's strategy. A paused
+// Deployment is refused, as kubectl does: the patch would only roll out once
+// someone resumes it.
+func restart(ctx context.Context, clientset kubernetes.Interface, namespace, name string, at time.Time) (*appsv1.Deployment, error) {
+	deployments := clientset.AppsV1().Deployments(namespace)
+	deploy, err := deployments.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if deploy.Spec.Paused {
+		return nil, fmt.Errorf("deployment %s is paused; resume it before restarting", name)
+	}
+	patch, err := restartPatch(at)
+	if err != nil {
+		return nil, err
+	}
+	return deployments.Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager})
+}
+
+// rolloutStatus describes the rollout the way kubectl rollout status does,
+// and reports whether it finished. Its checks run in the order the
+// deployment controller makes progress: it observes the new generation,
+// creates updated replicas, removes the old ones, and the updated ones
+// become available.
+func rolloutStatus(deploy *appsv1.Deployment) (string, bool, error) {
+	if deploy.Status.ObservedGeneration < deploy.Generation {
+		return "Waiting for deployment spec update to be observed...", false, nil
+	}
+	for _, c := range deploy.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Status == corev1.ConditionFalse && c.Reason == "ProgressDeadlineExceeded" {
+			return "", false, fmt.Errorf("deployment %q exceeded its progress deadline", deploy.Name)
+		}
+	}
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+	status := deploy.Status
+	switch {
+	case status.UpdatedReplicas < replicas:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated...",
+			deploy.Name, status.UpdatedReplicas, replicas), false, nil
+	case status.Replicas > status.UpdatedReplicas:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d old replicas are pending termination...",
+			deploy.Name, status.Replicas-status.UpdatedReplicas), false, nil
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d of %d updated replicas are available...",
+			deploy.Name, status.AvailableReplicas, status.UpdatedReplicas), false, nil
+	}
+	return fmt.Sprintf("deployment %q successfully rolled out", deploy.Name), true, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ignoredPaths are set by the API server on every write, so they would
+// differ between the live object and any dry-run result. status belongs to
+// the controllers, not to the submitted object.
+var ignoredPaths = []string{
+	"metadata.creationTimestamp",
+	"metadata.generation",
+	"metadata.managedFields",
+	"metadata.resourceVersion",
+	"metadata.uid",
+	"status",
+}
+
+// change is one field that differs. Old is empty for an added field and New
+// for a removed one.
+type change struct {
+	Path string
+	Old  string
+	New  string
+}
+
+func ignored(path string) bool {
+	for _, p := range ignoredPaths {
+		if path == p || strings.HasPrefix(path, p+".") || strings.HasPrefix(path, p+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// flatten maps every leaf of a JSON object to its path, such as
+// spec.template.spec.containers[name=nginx].image. List elements that all
+// have a name are keyed by it, so inserting a container shows as one added
+// container rather than every later one changing. Empty maps and lists are
+// leaves too, so that adding "{}" is not invisible.
+func flatten(prefix string, value interface{}, out map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && prefix != "" {
+			out[prefix] = "{}"
+			return
+		}
+		for k, child := range v {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			flatten(path, child, out)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			out[prefix] = "[]"
+			return
+		}
+		names := listNames(v)
+		for i, child := range v {
+			key := fmt.Sprint(i)
+			if names != nil {
+				key = "name=" + names[i]
+			}
+			flatten(fmt.Sprintf("%s[%s]", prefix, key), child, out)
+		}
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			b = []byte(fmt.Sprint(v))
+		}
+		out[prefix] = string(b)
+	}
+}
+
+// listNames returns the name of every element, or nil unless every element
+// is an object with a distinct string name
+func listNames(list []interface{}) []string {
+	names := make([]string, 0, len(list))
+	seen := map[string]bool{}
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		name, ok := m["name"].(string)
+		if !ok || seen[name] {
+			return nil
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// diffObjects returns the changes from live to result, sorted by path. A nil
+// live object means the object would be created.
+func diffObjects(live, result map[string]interface{}) []change {
+	before, after := map[string]string{}, map[string]string{}
+	if live != nil {
+		flatten("", live, before)
+	}
+	flatten("", result, after)
+
+	var changes []change
+	for _, path := range slices.Sorted(maps.Keys(after)) {
+		if ignored(path) {
+			continue
+		}
+		if old, ok := before[path]; !ok || old != after[path] {
+			changes = append(changes, change{Path: path, Old: old, New: after[path]})
+		}
+	}
+	for path, old := range before {
+		if _, ok := after[path]; !ok && !ignored(path) {
+			changes = append(changes, change{Path: path, Old: old})
+		}
+	}
+	slices.SortFunc(changes, func(a, b change) int { return strings.Compare(a.Path, b.Path) })
+	return changes
+}
+
+// printChanges writes a line per change: "+" for an added field, "-" for a
+// removed one and "~" for a changed value
+func printChanges(w io.Writer, changes []change) {
+	for _, c := range changes {
+		switch {
+		case c.Old == "":
+			fmt.Fprintf(w, "  + %s: %s\n", c.Path, c.New)
+		case c.New == "":
+			fmt.Fprintf(w, "  - %s: %s\n", c.Path, c.Old)
+		default:
+			fmt.Fprintf(w, "  ~ %s: %s -> %s\n", c.Path, c.Old, c.New)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+// How an object is submitted
+const (
+	// modeApply sends it as a server-side apply, like the SSA controller in
+	// 14_namespace_onboarding_controller: only the fields in the file are
+	// owned, everything else on the live object stays
+	modeApply = "apply"
+	// modeUpdate creates it, or replaces the live object with it, like 02's
+	// create. Fields the file does not set are removed.
+	modeUpdate = "update"
+)
+
+// readObjects decodes every document of a YAML or JSON stream. Each is
+// decoded through JSON into an Unstructured, so numbers become int64 as
+// they would from the API server.
+func readObjects(r io.Reader) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	var objects []*unstructured.Unstructured
+	for {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); errors.Is(err, io.EOF) {
+			return objects, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(raw.Raw)) == 0 || string(raw.Raw) == "null" {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw.Raw); err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+}
+
+// dryRun submits the object with DryRun=All and returns the live object, nil
+// when it does not exist, and what the server would have stored. The server
+// runs defaulting, admission and validation as for a real write, but skips
+// persisting it, so the result is exact where a client-side diff guesses.
+func dryRun(ctx context.Context, client dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured, opts options) (*unstructured.Unstructured, *unstructured.Unstructured, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resource dynamic.ResourceInterface = client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(opts.namespace)
+		}
+		resource = client.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+	}
+
+	live, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		live = nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	dryRunAll := []string{metav1.DryRunAll}
+	var result *unstructured.Unstructured
+	switch {
+	case opts.mode == modeApply:
+		result, err = resource.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{DryRun: dryRunAll, FieldManager: opts.fieldManager, Force: opts.force})
+	case opts.mode == modeUpdate && live == nil:
+		result, err = resource.Create(ctx, obj, metav1.CreateOptions{DryRun: dryRunAll, FieldManager: opts.fieldManager})
+	case opts.mode == modeUpdate:
+		// An update must name the version it replaces
+		obj = obj.DeepCopy()
+		obj.SetResourceVersion(live.GetResourceVersion())
+		result, err = resource.Update(ctx, obj, metav1.UpdateOptions{DryRun: dryRunAll, FieldManager: opts.fieldManager})
+	default:
+		return nil, nil, fmt.Errorf("unknown mode %q, want %s or %s", opts.mode, modeApply, modeUpdate)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return live, result, nil
+}
+
+// describe names an object as "Deployment default/web"
+func describe(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetKind() + " " + obj.GetName()
+	}
+	return fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	file         = flag.String("file", "", "YAML or JSON file with the objects to preview, - for stdin (required)")
+	namespace    = flag.String("namespace", "default", "namespace of namespaced objects that do not set one")
+	mode         = flag.String("mode", modeApply, "how the objects would be written: apply (server-side apply) or update (create or replace)")
+	fieldManager = flag.String("field-manager", clientid.FieldManager("62-dry-run-diff"), "field manager of the dry-run writes")
+	force        = flag.Bool("force-conflicts", false, "with apply, take over fields other managers own instead of failing")
+)
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "62-dry-run-diff")
+	return config
+}
+
+// options are the flags that say how objects are submitted
+type options struct {
+	namespace    string
+	mode         string
+	fieldManager string
+	force        bool
+}
+
+// run previews every object of r and prints what the server would change.
+// An object the server rejects is reported and the others still run.
+func run(ctx context.Context, client dynamic.Interface, mapper meta.RESTMapper, r io.Reader, opts options) error {
+	objects, err := readObjects(r)
+	if err != nil {
+		return err
+	}
+	created, changed, unchanged, failed := 0, 0, 0, 0
+	for _, obj := range objects {
+		live, result, err := dryRun(ctx, client, mapper, obj, opts)
+		if err != nil {
+			fmt.Printf("%s: %v\n", describe(obj), err)
+			failed++
+			continue
+		}
+		var liveContent map[string]interface{}
+		if live != nil {
+			liveContent = live.Object
+		}
+		changes := diffObjects(liveContent, result.Object)
+		switch {
+		case live == nil:
+			fmt.Printf("%s: would be created\n", describe(obj))
+			created++
+		case len(changes) == 0:
+			fmt.Printf("%s: unchanged\n", describe(obj))
+			unchanged++
+		default:
+			fmt.Printf("%s: %d fields would change\n", describe(obj), len(changes))
+			changed++
+		}
+		printChanges(os.Stdout, changes)
+	}
+	fmt.Printf("%d objects: %d created, %d changed, %d unchanged, %d failed (dry run, nothing was written)\n",
+		len(objects), created, changed, unchanged, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d objects failed", failed)
+	}
+	return nil
+}
+
+func main() {
+	config := createConfig()
+	if *file == "" {
+		log.Fatal("--file is required")
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create dynamic client: %v", err)
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create discovery client: %v", err)
+	}
+	// The mapper reads the API groups from discovery on first use, and keeps
+	// them in memory
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+	in := os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	opts := options{namespace: *namespace, mode: *mode, fieldManager: *fieldManager, force: *force}
+	if err := run(ctx, client, mapper, in, opts); err != nil {
+		log.Fatal(err)
+	}
+}
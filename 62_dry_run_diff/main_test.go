@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+var (
+	deployments = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	configMaps  = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+)
+
+const manifest = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 5
+  template:
+    spec:
+      containers:
+        - name: nginx
+          image: nginx:1.27
+---
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: shop
+data:
+  mode: safe
+`
+
+func testMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(deployments.GroupVersion().WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(configMaps.GroupVersion().WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	return mapper
+}
+
+func liveDeployment() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON([]byte(`{
+		"apiVersion": "apps/v1", "kind": "Deployment",
+		"metadata": {"name": "web", "namespace": "default", "resourceVersion": "7", "labels": {"team": "shop"}},
+		"spec": {"replicas": 3, "template": {"spec": {"containers": [
+			{"name": "nginx", "image": "nginx:1.25"},
+			{"name": "sidecar", "image": "envoy:1.30"}
+		]}}},
+		"status": {"replicas": 3}
+	}`)); err != nil {
+		panic(err)
+	}
+	return obj
+}
+
+// merge overlays patch onto obj the way an apply merges it: maps are merged
+// recursively and lists of named elements by name, like containers. Other
+// lists are replaced.
+func merge(obj, patch map[string]interface{}) {
+	for k, v := range patch {
+		switch pv := v.(type) {
+		case map[string]interface{}:
+			if om, ok := obj[k].(map[string]interface{}); ok {
+				merge(om, pv)
+				continue
+			}
+		case []interface{}:
+			if ol, ok := obj[k].([]interface{}); ok && listNames(ol) != nil && listNames(pv) != nil {
+				obj[k] = mergeNamed(ol, pv)
+				continue
+			}
+		}
+		obj[k] = v
+	}
+}
+
+func mergeNamed(live, patch []interface{}) []interface{} {
+	merged := append([]interface{}(nil), live...)
+	for _, p := range patch {
+		pm := p.(map[string]interface{})
+		found := false
+		for _, l := range merged {
+			if lm := l.(map[string]interface{}); lm["name"] == pm["name"] {
+				merge(lm, pm)
+				found = true
+			}
+		}
+		if !found {
+			merged = append(merged, pm)
+		}
+	}
+	return merged
+}
+
+// newDryRunClient returns a fake dynamic client whose writes behave like
+// dry-run writes: they return what would be stored and store nothing. The
+// fake drops the DryRun option, so the reactors act as the server would for
+// it. Creates get a server-side default, to show it in the diff.
+func newDryRunClient(t *testing.T, objects ...runtime.Object) (*dynamicfake.FakeDynamicClient, *int) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+	writes := 0
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		writes++
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			t.Errorf("patch type %s, want apply", patch.GetPatchType())
+		}
+		var applied map[string]interface{}
+		if err := json.Unmarshal(patch.GetPatch(), &applied); err != nil {
+			return true, nil, err
+		}
+		// Through JSON, so the numbers of the patch become int64
+		b, _ := json.Marshal(applied)
+		result := &unstructured.Unstructured{}
+		if err := result.UnmarshalJSON(b); err != nil {
+			return true, nil, err
+		}
+		// Apply creates an object that does not exist yet
+		live, err := client.Tracker().Get(patch.GetResource(), patch.GetNamespace(), patch.GetName())
+		if err == nil {
+			merged := live.DeepCopyObject().(*unstructured.Unstructured)
+			merge(merged.Object, result.Object)
+			result = merged
+		}
+		return true, result, nil
+	})
+	client.PrependReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		writes++
+		obj := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured).DeepCopy()
+		obj.SetUID("0d2f")
+		unstructured.SetNestedField(obj.Object, map[string]interface{}{}, "immutable-defaulted")
+		return true, obj, nil
+	})
+	client.PrependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		writes++
+		return true, action.(k8stesting.UpdateAction).GetObject(), nil
+	})
+	return client, &writes
+}
+
+func TestDiffObjects(t *testing.T) {
+	live := liveDeployment().Object
+	result := liveDeployment()
+	unstructured.SetNestedField(result.Object, int64(5), "spec", "replicas")
+	unstructured.SetNestedField(result.Object, "9", "metadata", "resourceVersion")
+	unstructured.SetNestedField(result.Object, int64(5), "status", "replicas")
+	unstructured.RemoveNestedField(result.Object, "metadata", "labels")
+	unstructured.SetNestedSlice(result.Object, []interface{}{
+		map[string]interface{}{"name": "init", "image": "busybox"},
+		map[string]interface{}{"name": "nginx", "image": "nginx:1.27"},
+		map[string]interface{}{"name": "sidecar", "image": "envoy:1.30"},
+	}, "spec", "template", "spec", "containers")
+	unstructured.SetNestedStringSlice(result.Object, []string{"a", "b"}, "spec", "template", "spec", "args")
+
+	got := diffObjects(live, result.Object)
+	want := []change{
+		{Path: "metadata.labels.team", Old: `"shop"`},
+		{Path: "spec.replicas", Old: "3", New: "5"},
+		{Path: "spec.template.spec.args[0]", New: `"a"`},
+		{Path: "spec.template.spec.args[1]", New: `"b"`},
+		{Path: "spec.template.spec.containers[name=init].image", New: `"busybox"`},
+		{Path: "spec.template.spec.containers[name=init].name", New: `"init"`},
+		{Path: "spec.template.spec.containers[name=nginx].image", Old: `"nginx:1.25"`, New: `"nginx:1.27"`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if changes := diffObjects(live, liveDeployment().Object); len(changes) != 0 {
+		t.Errorf("identical objects differ: %+v", changes)
+	}
+}
+
+func TestReadObjects(t *testing.T) {
+	objects, err := readObjects(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("got %d objects, want 2", len(objects))
+	}
+	if replicas, _, _ := unstructured.NestedInt64(objects[0].Object, "spec", "replicas"); replicas != 5 {
+		t.Errorf("replicas = %d, want 5 as int64", replicas)
+	}
+}
+
+func TestRunApply(t *testing.T) {
+	client, writes := newDryRunClient(t, liveDeployment())
+	output := testutil.CaptureOutput(t)
+	opts := options{namespace: "default", mode: modeApply, fieldManager: "dry-run-diff"}
+	if err := run(context.Background(), client, testMapper(), strings.NewReader(manifest), opts); err != nil {
+		t.Fatal(err)
+	}
+	// Apply only sets the fields of the manifest: the sidecar and the label
+	// stay
+	testutil.WaitForOutput(t, output,
+		"Deployment default/web: 2 fields would change\n",
+		"  ~ spec.replicas: 3 -> 5\n",
+		`  ~ spec.template.spec.containers[name=nginx].image: "nginx:1.25" -> "nginx:1.27"`+"\n",
+		"ConfigMap shop/settings: would be created\n",
+		`  + data.mode: "safe"`+"\n",
+		"2 objects: 1 created, 1 changed, 0 unchanged, 0 failed (dry run, nothing was written)\n",
+	)
+	if *writes != 2 {
+		t.Errorf("got %d writes, want 2", *writes)
+	}
+	live, err := client.Resource(deployments).Namespace("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(live.Object, "spec", "replicas"); replicas != 3 {
+		t.Errorf("live replicas = %d, the dry run wrote", replicas)
+	}
+}
+
+func TestRunUpdate(t *testing.T) {
+	client, _ := newDryRunClient(t, liveDeployment())
+	output := testutil.CaptureOutput(t)
+	opts := options{namespace: "default", mode: modeUpdate, fieldManager: "dry-run-diff"}
+	if err := run(context.Background(), client, testMapper(), strings.NewReader(manifest), opts); err != nil {
+		t.Fatal(err)
+	}
+	// An update replaces the object, so everything the manifest leaves out
+	// would be removed
+	testutil.WaitForOutput(t, output,
+		"Deployment default/web: 5 fields would change\n",
+		`  - metadata.labels.team: "shop"`+"\n",
+		`  - spec.template.spec.containers[name=sidecar].image: "envoy:1.30"`+"\n",
+		"ConfigMap shop/settings: would be created\n",
+		`  + data.mode: "safe"`+"\n",
+		"  + immutable-defaulted: {}\n",
+		"2 objects: 1 created, 1 changed, 0 unchanged, 0 failed (dry run, nothing was written)\n",
+	)
+}
+
+func TestRunReportsUnknownKinds(t *testing.T) {
+	client, _ := newDryRunClient(t)
+	output := testutil.CaptureOutput(t)
+	opts := options{namespace: "default", mode: modeApply}
+	err := run(context.Background(), client, testMapper(), strings.NewReader("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: w\n"), opts)
+	if err == nil {
+		t.Error("unknown kind did not fail")
+	}
+	testutil.WaitForOutput(t, output, "Widget w: no matches for kind \"Widget\" in version \"example.com/v1\"\n")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace = flag.String("namespace", "default", "namespace whose objects are cached")
+	resource  = flag.String("resource", "configmaps", "what is cached: configmaps, secrets or pods")
+	mode      = flag.String("mode", modeBoth, "how the cache is filled: list (paginated LIST, then WATCH), watch-list (one streaming WATCH) or both")
+	pageSize  = flag.Int64("page-size", 500, "objects per LIST page")
+	seed      = flag.Int("seed", 0, "first create this many ConfigMaps in the namespace, to make it large")
+	seedBytes = flag.Int("seed-bytes", 4096, "bytes of data in each seeded ConfigMap")
+	cleanup   = flag.Bool("cleanup", false, "delete the seeded ConfigMaps before exiting")
+)
+
+var fieldManager = clientid.FieldManager("63-watch-list")
+
+// createConfig builds a rest.Config from kubeconfig
+func createConfig() *rest.Config {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "63-watch-list")
+	return config
+}
+
+// modesToRun returns whether to run the paginated and the streaming sync
+func modesToRun(mode string) (list, watchList bool, err error) {
+	switch mode {
+	case modeList:
+		return true, false, nil
+	case modeWatchList:
+		return false, true, nil
+	case modeBoth:
+		return true, true, nil
+	}
+	return false, false, fmt.Errorf("unknown mode %q: use list, watch-list or both", mode)
+}
+
+func main() {
+	config := createConfig()
+	runList, runWatchList, err := modesToRun(*mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *seed > 0 {
+		fmt.Printf("Seeding %d ConfigMaps of %d bytes in %s...\n", *seed, *seedBytes, *namespace)
+		created, err := seedConfigMaps(ctx, clientset, *namespace, *seed, *seedBytes)
+		if err != nil {
+			log.Fatalf("Failed to seed: %v", err)
+		}
+		fmt.Printf("Created %d ConfigMaps (%d already existed)\n", created, *seed-created)
+	}
+	if *cleanup {
+		defer func() {
+			// The signal context may be done already; the deletes still have to run
+			deleted, err := cleanupConfigMaps(context.Background(), clientset, *namespace)
+			if err != nil {
+				log.Printf("Cleanup failed: %v", err)
+				return
+			}
+			fmt.Printf("Deleted %d seeded ConfigMaps\n", deleted)
+		}()
+	}
+
+	var results []result
+	for _, watchList := range []bool{false, true} {
+		if (watchList && !runWatchList) || (!watchList && !runList) {
+			continue
+		}
+		r, err := syncOnce(ctx, clientset, *resource, *namespace, watchList, *pageSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Sync failed: %v", err)
+			return
+		}
+		fmt.Printf("[%s] %d %s synced in %s\n", r.mode, r.objects, *resource, formatDuration(r.took))
+		results = append(results, r)
+	}
+	printComparison(os.Stdout, *resource, *namespace, results...)
+}
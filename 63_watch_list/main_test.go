@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	clientfeatures "k8s.io/client-go/features"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func configMaps(n int) []runtime.Object {
+	objects := make([]runtime.Object, n)
+	for i := range objects {
+		objects[i] = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("cm-%d", i), Namespace: "default", ResourceVersion: "10",
+		}}
+	}
+	return objects
+}
+
+// streamInitialEvents makes the fake answer every watch as a server with
+// the WatchList feature does: ADDED for each object, then the bookmark that
+// ends the initial events
+func streamInitialEvents(clientset *fake.Clientset, objects []runtime.Object) {
+	clientset.PrependWatchReactor("configmaps", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w := watch.NewFakeWithChanSize(len(objects)+1, false)
+		for _, obj := range objects {
+			w.Add(obj)
+		}
+		w.Action(watch.Bookmark, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			ResourceVersion: "10",
+			Annotations:     map[string]string{metav1.InitialEventsAnnotationKey: "true"},
+		}})
+		return true, w, nil
+	})
+}
+
+// resetGate turns WatchListClient off again when the test ends
+func resetGate(t *testing.T) {
+	t.Cleanup(func() {
+		if err := setWatchListClient(false); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestSetWatchListClient(t *testing.T) {
+	resetGate(t)
+	for _, enabled := range []bool{true, false} {
+		if err := setWatchListClient(enabled); err != nil {
+			t.Fatal(err)
+		}
+		if got := clientfeatures.FeatureGates().Enabled(clientfeatures.WatchListClient); got != enabled {
+			t.Errorf("WatchListClient = %v after setting it to %v", got, enabled)
+		}
+	}
+}
+
+func TestListSyncPagesThenWatches(t *testing.T) {
+	resetGate(t)
+	clientset := fake.NewClientset(configMaps(3)...)
+	var limits []int64
+	clientset.PrependReactor("list", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		limits = append(limits, action.(k8stesting.ListActionImpl).ListOptions.Limit)
+		return false, nil, nil
+	})
+
+	r, err := syncOnce(context.Background(), clientset, "configmaps", "default", false, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.mode != modeList || r.objects != 3 || r.listPages != 1 || r.watchLists != 0 {
+		t.Errorf("got %+v", r)
+	}
+	if len(limits) != 1 || limits[0] != 2 {
+		t.Errorf("list limits = %v, want [2]", limits)
+	}
+	if r.fellBack() {
+		t.Error("a list sync cannot fall back")
+	}
+}
+
+func TestWatchListSyncStreams(t *testing.T) {
+	resetGate(t)
+	objects := configMaps(3)
+	clientset := fake.NewClientset(objects...)
+	streamInitialEvents(clientset, objects)
+
+	r, err := syncOnce(context.Background(), clientset, "configmaps", "default", true, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.mode != modeWatchList || r.objects != 3 || r.listPages != 0 || r.watchLists != 1 {
+		t.Errorf("got %+v", r)
+	}
+	if r.fellBack() {
+		t.Error("reported a fallback although the objects were streamed")
+	}
+}
+
+func TestWatchListSyncFallsBackToList(t *testing.T) {
+	resetGate(t)
+	clientset := fake.NewClientset(configMaps(2)...)
+	// A server without the WatchList feature rejects sendInitialEvents
+	var refused atomic.Bool
+	clientset.PrependWatchReactor("configmaps", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		if refused.CompareAndSwap(false, true) {
+			return true, nil, errors.New("sendInitialEvents is forbidden")
+		}
+		return false, nil, nil
+	})
+
+	r, err := syncOnce(context.Background(), clientset, "configmaps", "default", true, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.objects != 2 || r.watchLists != 1 || r.listPages != 1 || !r.fellBack() {
+		t.Errorf("got %+v", r)
+	}
+	var out bytes.Buffer
+	printComparison(&out, "configmaps", "default", r)
+	if !strings.Contains(out.String(), "fell back to LIST") {
+		t.Errorf("the fallback is not reported:\n%s", out.String())
+	}
+}
+
+func TestUnknownResourceAndMode(t *testing.T) {
+	resetGate(t)
+	if _, err := syncOnce(context.Background(), fake.NewClientset(), "deployments", "default", false, 500); err == nil {
+		t.Error("unknown resource accepted")
+	}
+	if _, _, err := modesToRun("stream"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func TestSeedAndCleanup(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewClientset(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"}})
+
+	created, err := seedConfigMaps(ctx, clientset, "default", 40, 16)
+	if err != nil || created != 40 {
+		t.Fatalf("created %d: %v", created, err)
+	}
+	// A second run keeps what is there
+	if created, err := seedConfigMaps(ctx, clientset, "default", 50, 16); err != nil || created != 10 {
+		t.Fatalf("second run created %d: %v", created, err)
+	}
+	cm, err := clientset.CoreV1().ConfigMaps("default").Get(ctx, "watch-list-seed-00007", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cm.Data["payload"]) != 16 || cm.Labels[seedLabel] != "seed" {
+		t.Errorf("seeded %+v", cm)
+	}
+
+	deleted, err := cleanupConfigMaps(ctx, clientset, "default")
+	if err != nil || deleted != 50 {
+		t.Fatalf("deleted %d: %v", deleted, err)
+	}
+	left, _ := clientset.CoreV1().ConfigMaps("default").List(ctx, metav1.ListOptions{})
+	if len(left.Items) != 1 || left.Items[0].Name != "app-config" {
+		t.Errorf("left %v", left.Items)
+	}
+}
+
+func TestPrintComparison(t *testing.T) {
+	var out bytes.Buffer
+	printComparison(&out, "configmaps", "load",
+		result{mode: modeList, objects: 5000, took: 2412e6, peakHeap: 180 << 20, listPages: 10, watches: 1},
+		result{mode: modeWatchList, objects: 5000, took: 1108e6, peakHeap: 60 << 20, watchLists: 1},
+	)
+	for _, want := range []string{
+		"=== Initial sync of configmaps in load ===",
+		"METRIC            list        watch-list",
+		"Time to sync      2.41s       1.11s",
+		"Peak heap         180.0 MiB   60.0 MiB",
+		"LIST pages        10          0",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"time"
+)
+
+// heapInUse returns the bytes of live heap objects after a full collection
+func heapInUse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// totalAllocated returns the bytes allocated on the heap since the start
+func totalAllocated() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.TotalAlloc
+}
+
+// heapSampler records the highest heap in use until it is stopped. It reads
+// runtime/metrics, which does not stop the world as ReadMemStats does.
+type heapSampler struct {
+	done chan struct{}
+	peak chan uint64
+}
+
+func sampleHeap(interval time.Duration) *heapSampler {
+	s := &heapSampler{done: make(chan struct{}), peak: make(chan uint64, 1)}
+	go func() {
+		sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var peak uint64
+		for {
+			metrics.Read(sample)
+			peak = max(peak, sample[0].Value.Uint64())
+			select {
+			case <-s.done:
+				s.peak <- peak
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return s
+}
+
+// stop ends the sampling and returns the peak
+func (s *heapSampler) stop() uint64 {
+	close(s.done)
+	return <-s.peak
+}
+
+// above returns how far b exceeds base, 0 if it does not
+func above(b, base uint64) uint64 {
+	if b < base {
+		return 0
+	}
+	return b - base
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// printComparison prints the initial syncs side by side
+func printComparison(w io.Writer, resource, namespace string, results ...result) {
+	fmt.Fprintf(w, "\n=== Initial sync of %s in %s ===\n", resource, namespace)
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	row := func(metric string, cell func(r result) string) {
+		fmt.Fprint(tw, metric)
+		for _, r := range results {
+			fmt.Fprintf(tw, "\t%s", cell(r))
+		}
+		fmt.Fprintln(tw)
+	}
+	row("METRIC", func(r result) string { return r.mode })
+	row("Objects", func(r result) string { return fmt.Sprint(r.objects) })
+	row("Time to sync", func(r result) string { return formatDuration(r.took) })
+	row("Peak heap", func(r result) string { return mib(r.peakHeap) })
+	row("Allocated", func(r result) string { return mib(r.allocated) })
+	row("Heap after sync", func(r result) string { return mib(r.retained) })
+	row("LIST pages", func(r result) string { return fmt.Sprint(r.listPages) })
+	row("WATCH requests", func(r result) string { return fmt.Sprint(r.watches) })
+	row("Streaming lists", func(r result) string { return fmt.Sprint(r.watchLists) })
+	tw.Flush()
+
+	for _, r := range results {
+		if r.fellBack() {
+			fmt.Fprintf(w, "\nNote: the server did not stream the %s, so the watch-list sync fell back to LIST.\n", resource)
+			fmt.Fprintln(w, "Streaming lists need the WatchList feature gate of the API server (beta since Kubernetes 1.32).")
+		}
+	}
+}
+
+// formatDuration rounds a duration to a readable precision
+func formatDuration(d time.Duration) string {
+	if d >= time.Second {
+		return d.Round(10 * time.Millisecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// mib formats a byte count in MiB
+func mib(b uint64) string {
+	return fmt.Sprintf("%.1f MiB", float64(b)/(1<<20))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// seedLabel marks the ConfigMaps --seed created, so --cleanup finds them
+const seedLabel = "k8s-lab/watch-list"
+
+// seedWorkers is how many ConfigMaps are created at the same time
+const seedWorkers = 16
+
+// seedConfigMaps fills the namespace with count ConfigMaps of size bytes of
+// data each. ConfigMaps left by an earlier run are kept, so it can be run
+// again with a larger count. It returns how many it created.
+func seedConfigMaps(ctx context.Context, clientset kubernetes.Interface, namespace string, count, size int) (int, error) {
+	payload := strings.Repeat("x", size)
+	names := make(chan string)
+	var created atomic.Int64
+	var wg sync.WaitGroup
+	errs := make(chan error, seedWorkers)
+	for range seedWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				cm := &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{seedLabel: "seed"}},
+					Data:       map[string]string{"payload": payload},
+				}
+				_, err := clientset.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{FieldManager: fieldManager})
+				switch {
+				case apierrors.IsAlreadyExists(err):
+				case err != nil:
+					errs <- fmt.Errorf("failed to create configmap %s: %w", name, err)
+					return
+				default:
+					created.Add(1)
+				}
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for i := range count {
+		select {
+		case names <- fmt.Sprintf("watch-list-seed-%05d", i):
+		case err = <-errs:
+			break feed
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+	close(names)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	return int(created.Load()), err
+}
+
+// cleanupConfigMaps deletes the ConfigMaps seedConfigMaps created and
+// returns how many there were
+func cleanupConfigMaps(ctx context.Context, clientset kubernetes.Interface, namespace string) (int, error) {
+	list, err := clientset.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{LabelSelector: seedLabel + "=seed"})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list seeded configmaps: %w", err)
+	}
+	for _, cm := range list.Items {
+		err := clientset.CoreV1().ConfigMaps(namespace).Delete(ctx, cm.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return 0, fmt.Errorf("failed to delete configmap %s: %w", cm.Name, err)
+		}
+	}
+	return len(list.Items), nil
+}
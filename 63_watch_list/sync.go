@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	clientfeatures "k8s.io/client-go/features"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// How the informer fills its cache before it starts watching
+const (
+	modeList      = "list"
+	modeWatchList = "watch-list"
+	modeBoth      = "both"
+)
+
+// setWatchListClient turns the WatchListClient gate of client-go on or off,
+// as KUBE_FEATURE_WatchListClient=true does for a whole process. Set wins
+// over the environment variable. A reflector reads the gate when it is
+// created, so it decides for every informer that is started afterwards.
+func setWatchListClient(enabled bool) error {
+	gates, ok := clientfeatures.FeatureGates().(interface {
+		Set(clientfeatures.Feature, bool) error
+	})
+	if !ok {
+		return fmt.Errorf("the client-go feature gates of this process cannot be set")
+	}
+	return gates.Set(clientfeatures.WatchListClient, enabled)
+}
+
+// listWatchFor returns a ListerWatcher for the resource in the namespace and
+// the type of its objects
+func listWatchFor(clientset kubernetes.Interface, resource, namespace string) (*cache.ListWatch, k8sruntime.Object, error) {
+	switch resource {
+	case "configmaps":
+		c := clientset.CoreV1().ConfigMaps(namespace)
+		return &cache.ListWatch{
+			ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (k8sruntime.Object, error) {
+				return c.List(ctx, options)
+			},
+			WatchFuncWithContext: c.Watch,
+		}, &corev1.ConfigMap{}, nil
+	case "secrets":
+		c := clientset.CoreV1().Secrets(namespace)
+		return &cache.ListWatch{
+			ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (k8sruntime.Object, error) {
+				return c.List(ctx, options)
+			},
+			WatchFuncWithContext: c.Watch,
+		}, &corev1.Secret{}, nil
+	case "pods":
+		c := clientset.CoreV1().Pods(namespace)
+		return &cache.ListWatch{
+			ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (k8sruntime.Object, error) {
+				return c.List(ctx, options)
+			},
+			WatchFuncWithContext: c.Watch,
+		}, &corev1.Pod{}, nil
+	}
+	return nil, nil, fmt.Errorf("unknown resource %q: use configmaps, secrets or pods", resource)
+}
+
+// requests counts what a reflector asked the API server for
+type requests struct {
+	listPages  atomic.Int64
+	watches    atomic.Int64
+	watchLists atomic.Int64 // watches with sendInitialEvents=true
+}
+
+// wrap counts the calls to lw. Lists are sent in pages of pageSize objects:
+// the reflector lists at resourceVersion 0, which the watch cache answers in
+// one piece whatever the limit, so the list is moved to the latest version,
+// where the server honours the pages as it does for a client that pages
+// through a LIST itself.
+func (r *requests) wrap(lw *cache.ListWatch, pageSize int64) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (k8sruntime.Object, error) {
+			r.listPages.Add(1)
+			if options.ResourceVersion == "0" {
+				options.ResourceVersion = ""
+			}
+			options.Limit = pageSize
+			return lw.ListWithContext(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			if options.SendInitialEvents != nil && *options.SendInitialEvents {
+				r.watchLists.Add(1)
+			} else {
+				r.watches.Add(1)
+			}
+			return lw.WatchWithContext(ctx, options)
+		},
+	}
+}
+
+// result is what one initial sync cost
+type result struct {
+	mode    string
+	objects int
+	took    time.Duration
+	// peakHeap is the highest live heap above the baseline while syncing,
+	// where a LIST holds its pages and the decoded objects at once
+	peakHeap uint64
+	// allocated counts every byte allocated while syncing, garbage included
+	allocated uint64
+	// retained is the live heap above the baseline once synced: the cache
+	retained   uint64
+	listPages  int64
+	watches    int64
+	watchLists int64
+}
+
+// fellBack reports whether a watch-list sync had to list after all, as the
+// reflector does when the server cannot stream the initial state
+func (r result) fellBack() bool {
+	return r.mode == modeWatchList && r.listPages > 0
+}
+
+// syncOnce starts an informer for the resource with the WatchListClient gate
+// on or off, waits until its cache is filled and measures what that took
+func syncOnce(ctx context.Context, clientset kubernetes.Interface, resource, namespace string, watchList bool, pageSize int64) (result, error) {
+	r := result{mode: modeList}
+	if watchList {
+		r.mode = modeWatchList
+	}
+	if err := setWatchListClient(watchList); err != nil {
+		return r, err
+	}
+	lw, objType, err := listWatchFor(clientset, resource, namespace)
+	if err != nil {
+		return r, err
+	}
+	counts := &requests{}
+	informer := cache.NewSharedIndexInformer(counts.wrap(lw, pageSize), objType, 0, cache.Indexers{})
+
+	// The informer stops when this sync has been measured, so the next one
+	// starts from the same baseline
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	baseline := heapInUse()
+	allocatedBefore := totalAllocated()
+	sampler := sampleHeap(time.Millisecond)
+	start := time.Now()
+	go informer.RunWithContext(ctx)
+	synced := cache.WaitForCacheSync(ctx.Done(), informer.HasSynced)
+	r.took = time.Since(start)
+	peak := sampler.stop()
+	if !synced {
+		return r, fmt.Errorf("%s: the cache did not sync", r.mode)
+	}
+	r.allocated = totalAllocated() - allocatedBefore
+	r.peakHeap = above(peak, baseline)
+	r.objects = len(informer.GetStore().ListKeys())
+	r.retained = above(heapInUse(), baseline)
+	r.listPages, r.watches, r.watchLists = counts.listPages.Load(), counts.watches.Load(), counts.watchLists.Load()
+	// The cache must stay reachable until it was measured
+	runtime.KeepAlive(informer)
+	return r, nil
+}
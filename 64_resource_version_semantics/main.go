@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var namespace = flag.String("namespace", "default", "namespace whose ConfigMaps are listed")
+
+var fieldManager = clientid.FieldManager("64-resource-version-semantics")
+
+// createClientSet creates a Kubernetes clientset from kubeconfig
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "64-resource-version-semantics")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// writeProbe creates a ConfigMap and returns it, with the resourceVersion
+// of the write
+func writeProbe(ctx context.Context, clientset kubernetes.Interface, namespace string) (*corev1.ConfigMap, error) {
+	probe := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:   fmt.Sprintf("rv-probe-%d", time.Now().UnixNano()),
+		Labels: map[string]string{"app.kubernetes.io/managed-by": "k8s-lab"},
+	}}
+	return clientset.CoreV1().ConfigMaps(namespace).Create(ctx, probe, metav1.CreateOptions{FieldManager: fieldManager})
+}
+
+func main() {
+	clientset := createClientSet()
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	probe, err := writeProbe(ctx, clientset, *namespace)
+	if err != nil {
+		log.Fatalf("Failed to create the probe configmap: %v", err)
+	}
+	defer func() {
+		err := clientset.CoreV1().ConfigMaps(*namespace).Delete(context.Background(), probe.Name, metav1.DeleteOptions{})
+		if err != nil {
+			log.Printf("Failed to delete the probe configmap %s: %v", probe.Name, err)
+		}
+	}()
+	fmt.Printf("Wrote configmap %s/%s at resourceVersion %s\n\n", *namespace, probe.Name, probe.ResourceVersion)
+
+	reads, err := readsAfter(probe.ResourceVersion)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	outcomes := runReads(ctx, clientset, *namespace, probe.Name, reads)
+	if ctx.Err() != nil {
+		return
+	}
+	printOutcomes(os.Stdout, outcomes)
+	fmt.Println(informerNote)
+}
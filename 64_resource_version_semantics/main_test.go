@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestReadsAfter(t *testing.T) {
+	reads, err := readsAfter("4711")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []metav1.ListOptions{
+		{},
+		{ResourceVersion: "0"},
+		{ResourceVersion: "4711", ResourceVersionMatch: metav1.ResourceVersionMatchNotOlderThan},
+		{ResourceVersion: "4711", ResourceVersionMatch: metav1.ResourceVersionMatchExact},
+		{ResourceVersion: "1", ResourceVersionMatch: metav1.ResourceVersionMatchExact},
+		{ResourceVersion: "1004711", ResourceVersionMatch: metav1.ResourceVersionMatchNotOlderThan},
+	}
+	if len(reads) != len(want) {
+		t.Fatalf("got %d reads, want %d", len(reads), len(want))
+	}
+	for i, r := range reads {
+		if r.options != want[i] {
+			t.Errorf("read %q sends %+v, want %+v", r.name, r.options, want[i])
+		}
+	}
+	if _, err := readsAfter("abc"); err == nil {
+		t.Error("non-numeric resourceVersion accepted")
+	}
+}
+
+func TestRunReads(t *testing.T) {
+	clientset := fake.NewClientset()
+	ctx := context.Background()
+	probe, err := writeProbe(ctx, clientset, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The watch cache has not seen the write yet, and the oldest version is compacted
+	clientset.PrependReactor("list", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		switch action.(k8stesting.ListActionImpl).ListOptions.ResourceVersion {
+		case "0":
+			return true, &corev1.ConfigMapList{ListMeta: metav1.ListMeta{ResourceVersion: "41"}}, nil
+		case "1":
+			return true, nil, apierrors.NewResourceExpired("too old resource version: 1 (41)")
+		}
+		return false, nil, nil
+	})
+	reads := []read{
+		{name: "most recent", options: metav1.ListOptions{}},
+		{name: "any", options: metav1.ListOptions{ResourceVersion: "0"}},
+		{name: "compacted", options: metav1.ListOptions{ResourceVersion: "1", ResourceVersionMatch: metav1.ResourceVersionMatchExact}},
+	}
+
+	outcomes := runReads(ctx, clientset, "default", probe.Name, reads)
+	if o := outcomes[0]; o.err != nil || o.items != 1 || !o.sawProbe {
+		t.Errorf("most recent: %+v", o)
+	}
+	if o := outcomes[1]; o.err != nil || o.sawProbe || o.listRV != "41" {
+		t.Errorf("any: %+v", o)
+	}
+	if o := outcomes[2]; describeError(o.err) != "410 Expired" {
+		t.Errorf("compacted: %v", o.err)
+	}
+
+	var out bytes.Buffer
+	printOutcomes(&out, outcomes)
+	for _, want := range []string{
+		"READ          SEMANTICS   LIST RV   ITEMS   PROBE     TIME   RESULT",
+		"any                       41        0       missing",
+		"compacted                 -         -       -",
+		"Where each read is served from:",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDescribeError(t *testing.T) {
+	tooLarge := &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   504,
+		Reason: metav1.StatusReasonTimeout,
+		Details: &metav1.StatusDetails{Causes: []metav1.StatusCause{
+			{Type: metav1.CauseTypeResourceVersionTooLarge, Message: "Too large resource version"},
+		}},
+	}}
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{nil, "ok"},
+		{tooLarge, "504 ResourceVersionTooLarge"},
+		{apierrors.NewResourceExpired("too old"), "410 Expired"},
+		{errors.New("connection refused"), "connection refused"},
+	} {
+		if got := describeError(tt.err); got != tt.want {
+			t.Errorf("describeError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
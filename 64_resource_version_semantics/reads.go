@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// read is one way to LIST, and where the API server answers it from
+type read struct {
+	name      string
+	semantics string
+	options   metav1.ListOptions
+	servedBy  string
+}
+
+// readsAfter returns the reads to compare, given the resourceVersion of the
+// probe write. Clients must treat resource versions as opaque strings; the
+// future version is computed here only to show what the server does with one.
+func readsAfter(writeRV string) ([]read, error) {
+	n, err := strconv.ParseUint(writeRV, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("resourceVersion %q of the probe is not a number: %w", writeRV, err)
+	}
+	future := strconv.FormatUint(n+1_000_000, 10)
+	return []read{
+		{
+			name:      `resourceVersion=""`,
+			semantics: "Most recent",
+			options:   metav1.ListOptions{},
+			servedBy:  "a consistent read: a quorum read from etcd, or the watch cache once it has caught up with etcd (1.31+)",
+		},
+		{
+			name:      `resourceVersion="0"`,
+			semantics: "Any",
+			options:   metav1.ListOptions{ResourceVersion: "0"},
+			servedBy:  "the watch cache as it is, without waiting: cheap, but it may be behind the write",
+		},
+		{
+			name:      "NotOlderThan " + writeRV,
+			semantics: "Not older than",
+			options:   metav1.ListOptions{ResourceVersion: writeRV, ResourceVersionMatch: metav1.ResourceVersionMatchNotOlderThan},
+			servedBy:  "the watch cache, once it has seen the write; it waits up to 3s for that",
+		},
+		{
+			name:      "Exact " + writeRV,
+			semantics: "Exact",
+			options:   metav1.ListOptions{ResourceVersion: writeRV, ResourceVersionMatch: metav1.ResourceVersionMatchExact},
+			servedBy:  "the state at exactly that version, from etcd or a snapshot of the watch cache",
+		},
+		{
+			name:      "Exact 1",
+			semantics: "Exact",
+			options:   metav1.ListOptions{ResourceVersion: "1", ResourceVersionMatch: metav1.ResourceVersionMatchExact},
+			servedBy:  "etcd keeps old versions until it compacts them, every 5 minutes by default; then 410 Expired",
+		},
+		{
+			name:      "NotOlderThan " + future,
+			semantics: "Not older than",
+			options:   metav1.ListOptions{ResourceVersion: future, ResourceVersionMatch: metav1.ResourceVersionMatchNotOlderThan},
+			servedBy:  "a version the cache has not reached: it waits, then answers 504 ResourceVersionTooLarge",
+		},
+	}, nil
+}
+
+// outcome is what one read returned
+type outcome struct {
+	read
+	listRV   string
+	items    int
+	sawProbe bool
+	took     time.Duration
+	err      error
+}
+
+// runReads lists the ConfigMaps of the namespace in every way of reads and
+// checks whether each list contains the probe
+func runReads(ctx context.Context, clientset kubernetes.Interface, namespace, probe string, reads []read) []outcome {
+	outcomes := make([]outcome, 0, len(reads))
+	for _, r := range reads {
+		o := outcome{read: r}
+		start := time.Now()
+		list, err := clientset.CoreV1().ConfigMaps(namespace).List(ctx, r.options)
+		o.took, o.err = time.Since(start), err
+		if err == nil {
+			o.listRV, o.items = list.ResourceVersion, len(list.Items)
+			o.sawProbe = containsName(list.Items, probe)
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes
+}
+
+func containsName(items []corev1.ConfigMap, name string) bool {
+	for _, item := range items {
+		if item.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// describeError names the errors resource versions lead to
+func describeError(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case apierrors.HasStatusCause(err, metav1.CauseTypeResourceVersionTooLarge):
+		return "504 ResourceVersionTooLarge"
+	case apierrors.IsResourceExpired(err), apierrors.IsGone(err):
+		return "410 Expired"
+	}
+	return err.Error()
+}
+
+// printOutcomes prints the reads side by side and explains each
+func printOutcomes(w io.Writer, outcomes []outcome) {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "READ\tSEMANTICS\tLIST RV\tITEMS\tPROBE\tTIME\tRESULT")
+	for _, o := range outcomes {
+		rv, items, probe := "-", "-", "-"
+		if o.err == nil {
+			rv, items = o.listRV, fmt.Sprint(o.items)
+			probe = "missing"
+			if o.sawProbe {
+				probe = "seen"
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", o.name, o.semantics, rv, items, probe, o.took.Round(time.Millisecond), describeError(o.err))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nWhere each read is served from:")
+	for _, o := range outcomes {
+		fmt.Fprintf(w, "  %-28s %s\n", o.name, o.servedBy)
+	}
+}
+
+// informerNote explains which of the reads informers rely on
+const informerNote = `
+Why informers can read from the cache:
+  A reflector lists with resourceVersion="0", the cheapest read, even if the
+  watch cache is a little behind. It then watches from the resourceVersion of
+  that list, and the watch delivers every change after it. A stale start is
+  caught up by the watch; nothing is missed. When a watch expires with 410,
+  the reflector lists again from the last version it saw (NotOlderThan), so
+  its cache never goes back in time.
+  A one-off read that must see a write it just made needs the most recent
+  read (resourceVersion unset), or NotOlderThan the version of the write.`
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace       = flag.String("namespace", "default", "namespace whose pods are watched")
+	disconnectEvery = flag.Duration("disconnect-every", 90*time.Second, "drop the watch after this long and resume it")
+	noBookmarks     = flag.Bool("no-bookmarks", false, "do not ask for BOOKMARK events, to see what resuming is like without them")
+)
+
+// createClientSet creates a Kubernetes clientset from kubeconfig
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "65-watch-bookmarks")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+func main() {
+	clientset := createClientSet()
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	w := &bookmarkWatcher{
+		clientset:      clientset,
+		namespace:      *namespace,
+		out:            os.Stdout,
+		allowBookmarks: !*noBookmarks,
+	}
+	err := w.run(ctx, *disconnectEvery)
+	w.printSummary()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func pod(name, rv string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: rv},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func bookmark(rv string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: rv}}
+}
+
+// scriptedWatches answers each watch with the next list of events and then
+// closes it, and records the options of every watch
+type scriptedWatches struct {
+	scripts [][]watch.Event
+	options []metav1.ListOptions
+}
+
+func (s *scriptedWatches) install(clientset *fake.Clientset) {
+	clientset.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		s.options = append(s.options, action.(k8stesting.WatchActionImpl).ListOptions)
+		var events []watch.Event
+		if len(s.scripts) > 0 {
+			events, s.scripts = s.scripts[0], s.scripts[1:]
+		}
+		w := watch.NewFakeWithChanSize(len(events), false)
+		for _, e := range events {
+			w.Action(e.Type, e.Object)
+		}
+		w.Stop()
+		return true, w, nil
+	})
+}
+
+func newWatcher(clientset *fake.Clientset) (*bookmarkWatcher, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &bookmarkWatcher{clientset: clientset, namespace: "default", out: out, allowBookmarks: true}, out
+}
+
+func TestWatchKeepsBookmarkResourceVersion(t *testing.T) {
+	clientset := fake.NewClientset()
+	script := &scriptedWatches{scripts: [][]watch.Event{{
+		{Type: watch.Added, Object: pod("web-0", "11")},
+		{Type: watch.Modified, Object: pod("web-0", "12")},
+		{Type: watch.Bookmark, Object: bookmark("40")},
+	}}}
+	script.install(clientset)
+	w, out := newWatcher(clientset)
+	w.lastRV, w.lastFrom = "10", fromList
+
+	if err := w.watchFor(context.Background(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if w.lastRV != "40" || w.lastFrom != fromBookmark || w.events != 2 || w.bookmarks != 1 {
+		t.Errorf("got lastRV %s from the %s, %d events, %d bookmarks", w.lastRV, w.lastFrom, w.events, w.bookmarks)
+	}
+	if got := script.options[0]; got.ResourceVersion != "10" || !got.AllowWatchBookmarks {
+		t.Errorf("watch options = %+v", got)
+	}
+	for _, want := range []string{
+		"Watching from resourceVersion 10 (from the list)",
+		"(+) ADDED default/web-0 Running at resourceVersion 11",
+		"(*) MODIFIED default/web-0 Running at resourceVersion 12",
+		"(b) BOOKMARK at resourceVersion 40",
+		"--- The server closed the watch ---",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunResumesFromBookmarkAndRelistsWhenExpired(t *testing.T) {
+	clientset := fake.NewClientset(pod("web-0", "5"))
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &corev1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "10"}}, nil
+	})
+	expired := apierrors.NewResourceExpired("too old resource version: 40 (90)")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	script := &scriptedWatches{scripts: [][]watch.Event{
+		{{Type: watch.Bookmark, Object: bookmark("40")}},
+		{{Type: watch.Error, Object: &expired.ErrStatus}},
+	}}
+	script.install(clientset)
+	// Stop after the watch that follows the relist
+	clientset.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		if len(script.scripts) == 0 && len(script.options) == 2 {
+			cancel()
+		}
+		return false, nil, nil
+	})
+	w, out := newWatcher(clientset)
+
+	if err := w.run(ctx, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	var resumedFrom []string
+	for _, o := range script.options {
+		resumedFrom = append(resumedFrom, o.ResourceVersion)
+	}
+	if strings.Join(resumedFrom, ",") != "10,40,10" {
+		t.Errorf("watches started at %v, want [10 40 10]", resumedFrom)
+	}
+	if w.relists != 1 || w.lastRV != "10" || w.lastFrom != fromList {
+		t.Errorf("relists %d, lastRV %s from the %s", w.relists, w.lastRV, w.lastFrom)
+	}
+	if !strings.Contains(out.String(), "resourceVersion 40 (from the bookmark) is too old") {
+		t.Errorf("expiry not reported:\n%s", out.String())
+	}
+}
+
+func TestSimulatedDisconnect(t *testing.T) {
+	clientset := fake.NewClientset()
+	// A watch that stays open until it is dropped
+	clientset.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, watch.NewFake(), nil
+	})
+	w, out := newWatcher(clientset)
+	w.lastRV, w.lastFrom = "10", fromList
+
+	if err := w.watchFor(context.Background(), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "--- Simulated disconnect after 20ms ---") {
+		t.Errorf("got:\n%s", out.String())
+	}
+}
+
+func TestNoBookmarks(t *testing.T) {
+	clientset := fake.NewClientset()
+	script := &scriptedWatches{}
+	script.install(clientset)
+	w, _ := newWatcher(clientset)
+	w.allowBookmarks = false
+
+	if err := w.watchFor(context.Background(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if script.options[0].AllowWatchBookmarks {
+		t.Error("bookmarks requested with --no-bookmarks")
+	}
+}
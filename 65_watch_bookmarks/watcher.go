@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Where the resourceVersion a watch resumes from came from
+const (
+	fromList     = "list"
+	fromEvent    = "event"
+	fromBookmark = "bookmark"
+)
+
+// bookmarkWatcher watches pods the way a reflector does underneath an
+// informer: list once, then watch from the resourceVersion it got, keep the
+// version of every event and bookmark, and resume from it after a disconnect
+type bookmarkWatcher struct {
+	clientset kubernetes.Interface
+	namespace string
+	out       io.Writer
+	// allowBookmarks asks the server for BOOKMARK events
+	allowBookmarks bool
+
+	// lastRV is the resourceVersion to resume from, and lastFrom what it
+	// was taken from
+	lastRV   string
+	lastFrom string
+
+	events    int
+	bookmarks int
+	relists   int
+}
+
+// list fetches the pods and keeps the resourceVersion of the list, from
+// which the first watch starts
+func (w *bookmarkWatcher) list(ctx context.Context) error {
+	pods, err := w.clientset.CoreV1().Pods(w.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	w.lastRV, w.lastFrom = pods.ResourceVersion, fromList
+	fmt.Fprintf(w.out, "Listed %d pods at resourceVersion %s\n", len(pods.Items), w.lastRV)
+	return nil
+}
+
+// watchFor opens a watch at lastRV and handles its events until d has
+// passed, which simulates a dropped connection, until the server closes the
+// watch or until ctx is cancelled. An ERROR event, such as 410 Expired when
+// lastRV is too old, is returned as an error.
+func (w *bookmarkWatcher) watchFor(ctx context.Context, d time.Duration) error {
+	watcher, err := w.clientset.CoreV1().Pods(w.namespace).Watch(ctx, metav1.ListOptions{
+		ResourceVersion: w.lastRV,
+		// With bookmarks the server sends about once a minute, and before it
+		// ends the watch, a BOOKMARK event with its current resourceVersion
+		AllowWatchBookmarks: w.allowBookmarks,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch pods: %w", err)
+	}
+	defer watcher.Stop()
+	fmt.Fprintf(w.out, "Watching from resourceVersion %s (from the %s)\n", w.lastRV, w.lastFrom)
+
+	disconnect := time.After(d)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-disconnect:
+			fmt.Fprintf(w.out, "--- Simulated disconnect after %s ---\n", d)
+			return nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				fmt.Fprintln(w.out, "--- The server closed the watch ---")
+				return nil
+			}
+			if err := w.handle(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handle prints an event and keeps its resourceVersion
+func (w *bookmarkWatcher) handle(event watch.Event) error {
+	if event.Type == watch.Error {
+		return apierrors.FromObject(event.Object)
+	}
+	obj, err := meta.Accessor(event.Object)
+	if err != nil {
+		return fmt.Errorf("unexpected %s object %T: %w", event.Type, event.Object, err)
+	}
+	switch event.Type {
+	case watch.Bookmark:
+		// A bookmark has no object, only a resourceVersion: nothing changed
+		// up to it in what this watch selects
+		w.bookmarks++
+		w.lastRV, w.lastFrom = obj.GetResourceVersion(), fromBookmark
+		fmt.Fprintf(w.out, "(b) BOOKMARK at resourceVersion %s\n", w.lastRV)
+		return nil
+	case watch.Added, watch.Modified, watch.Deleted:
+		w.events++
+		w.lastRV, w.lastFrom = obj.GetResourceVersion(), fromEvent
+		phase := ""
+		if pod, ok := event.Object.(*corev1.Pod); ok && event.Type != watch.Deleted {
+			phase = " " + string(pod.Status.Phase)
+		}
+		fmt.Fprintf(w.out, "(%s) %s %s/%s%s at resourceVersion %s\n", marker(event.Type), event.Type, obj.GetNamespace(), obj.GetName(), phase, w.lastRV)
+	}
+	return nil
+}
+
+func marker(t watch.EventType) string {
+	switch t {
+	case watch.Added:
+		return "+"
+	case watch.Deleted:
+		return "-"
+	}
+	return "*"
+}
+
+// run lists and then watches until ctx is cancelled, disconnecting every d.
+// A watch that cannot resume because its resourceVersion was compacted
+// starts over with a new list, as a reflector does.
+func (w *bookmarkWatcher) run(ctx context.Context, d time.Duration) error {
+	if err := w.list(ctx); err != nil {
+		return err
+	}
+	for ctx.Err() == nil {
+		err := w.watchFor(ctx, d)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case apierrors.IsResourceExpired(err) || apierrors.IsGone(err):
+			fmt.Fprintf(w.out, "resourceVersion %s (from the %s) is too old: %v\n", w.lastRV, w.lastFrom, err)
+			w.relists++
+			if err := w.list(ctx); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		}
+	}
+	return nil
+}
+
+// printSummary reports what the watches received
+func (w *bookmarkWatcher) printSummary() {
+	fmt.Fprintln(w.out, "\n=== Summary ===")
+	fmt.Fprintf(w.out, "Events: %d, bookmarks: %d, relists after 410 Expired: %d\n", w.events, w.bookmarks, w.relists)
+	fmt.Fprintf(w.out, "Last resourceVersion: %s (from the %s)\n", w.lastRV, w.lastFrom)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func pod(name, rv string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: rv},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func newTestWatcher(clientset *fake.Clientset) (*rawWatcher, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	w := newRawWatcher(clientset, "default", out, time.Minute)
+	w.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 3, Cap: 5 * time.Millisecond}
+	return w, out
+}
+
+// closedWatch returns a watch that delivers events and then closes
+func closedWatch(events ...watch.Event) watch.Interface {
+	w := watch.NewFakeWithChanSize(len(events), false)
+	for _, e := range events {
+		w.Action(e.Type, e.Object)
+	}
+	w.Stop()
+	return w
+}
+
+func TestWatchOnceAppliesEvents(t *testing.T) {
+	clientset := fake.NewClientset()
+	var options metav1.ListOptions
+	clientset.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		options = action.(k8stesting.WatchActionImpl).ListOptions
+		return true, closedWatch(
+			watch.Event{Type: watch.Added, Object: pod("web-0", "11", corev1.PodPending)},
+			watch.Event{Type: watch.Modified, Object: pod("web-0", "12", corev1.PodRunning)},
+			watch.Event{Type: watch.Added, Object: pod("web-1", "13", corev1.PodRunning)},
+			watch.Event{Type: watch.Deleted, Object: pod("web-1", "14", corev1.PodRunning)},
+			watch.Event{Type: watch.Bookmark, Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "20"}}},
+		), nil
+	})
+	w, out := newTestWatcher(clientset)
+	w.lastRV = "10"
+
+	if _, err := w.watchOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if options.ResourceVersion != "10" || !options.AllowWatchBookmarks || options.TimeoutSeconds == nil {
+		t.Errorf("watch options = %+v", options)
+	}
+	if s := *options.TimeoutSeconds; s < 60 || s > 120 {
+		t.Errorf("timeout = %ds, want between 60 and 120", s)
+	}
+	if w.lastRV != "20" || len(w.pods) != 1 || w.pods["web-0"] != corev1.PodRunning {
+		t.Errorf("lastRV %s, pods %v", w.lastRV, w.pods)
+	}
+	want := "(+) default/web-0 Pending\n(*) default/web-0 Pending -> Running\n(+) default/web-1 Running\n(-) default/web-1\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestListReportsWhatTheWatchMissed(t *testing.T) {
+	clientset := fake.NewClientset(pod("web-0", "30", corev1.PodFailed), pod("web-2", "31", corev1.PodPending))
+	w, out := newTestWatcher(clientset)
+	w.pods = map[string]corev1.PodPhase{"web-0": corev1.PodRunning, "web-1": corev1.PodRunning}
+
+	if err := w.list(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"(*) default/web-0 Running -> Failed (while the watch was down)",
+		"(+) default/web-2 Pending",
+		"(-) default/web-1 (deleted while the watch was down)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+	if len(w.pods) != 2 {
+		t.Errorf("pods = %v", w.pods)
+	}
+}
+
+func TestRunHandlesEveryWayAWatchEnds(t *testing.T) {
+	clientset := fake.NewClientset(pod("web-0", "5", corev1.PodRunning))
+	lists := 0
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		lists++
+		return false, nil, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	expired := apierrors.NewResourceExpired("too old resource version")
+	watches := 0
+	clientset.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		watches++
+		switch watches {
+		case 1:
+			return true, closedWatch(watch.Event{Type: watch.Added, Object: pod("web-1", "6", corev1.PodPending)}), nil
+		case 2:
+			return true, nil, errors.New("connection refused")
+		case 3:
+			return true, closedWatch(watch.Event{Type: watch.Error, Object: &expired.ErrStatus}), nil
+		}
+		cancel()
+		return true, closedWatch(), nil
+	})
+	w, out := newTestWatcher(clientset)
+	// Every watch of the fake ends at once; count it as a normal end
+	w.shortWatch = 0
+
+	w.run(ctx)
+	if watches != 4 || lists != 2 || w.reconnects != 1 || w.failures != 1 || w.relists != 1 {
+		t.Errorf("%d watches, %d lists, %d reconnects, %d failures, %d relists", watches, lists, w.reconnects, w.failures, w.relists)
+	}
+	for _, want := range []string{
+		"Listed 1 pods at resourceVersion",
+		"--- Watch closed after 0s, resuming from resourceVersion 6 ---",
+		"--- failed to watch pods: connection refused; retrying in 1ms ---",
+		"--- resourceVersion 6 expired, listing again ---",
+		"(-) default/web-1 (deleted while the watch was down)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestShortWatchesBackOff(t *testing.T) {
+	clientset := fake.NewClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watches := 0
+	clientset.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		if watches++; watches == 3 {
+			cancel()
+		}
+		return true, closedWatch(), nil
+	})
+	w, out := newTestWatcher(clientset)
+
+	w.run(ctx)
+	if w.failures != 2 || w.reconnects != 0 {
+		t.Errorf("%d failures, %d reconnects", w.failures, w.reconnects)
+	}
+	if !strings.Contains(out.String(), "--- the watch closed after 0s; retrying in 2ms ---") {
+		t.Errorf("got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// rawWatcher keeps the phases of the pods in a namespace up to date with a
+// LIST and then a WATCH, without an informer. It does by hand what the
+// reflector inside every informer does.
+type rawWatcher struct {
+	clientset kubernetes.Interface
+	namespace string
+	out       io.Writer
+	// timeout asks the server to end each watch after about this long, as a
+	// reflector does with a random timeout between 5 and 10 minutes
+	timeout time.Duration
+	backoff wait.Backoff
+	// shortWatch is how long a watch has to last to count as working. A
+	// watch the server closes sooner is retried with backoff, so a broken
+	// server is not hammered.
+	shortWatch time.Duration
+
+	// pods is what the watcher knows: the phase of every pod by name
+	pods   map[string]corev1.PodPhase
+	lastRV string
+
+	reconnects int
+	relists    int
+	failures   int
+}
+
+func newRawWatcher(clientset kubernetes.Interface, namespace string, out io.Writer, timeout time.Duration) *rawWatcher {
+	return &rawWatcher{
+		clientset:  clientset,
+		namespace:  namespace,
+		out:        out,
+		timeout:    timeout,
+		backoff:    wait.Backoff{Duration: 500 * time.Millisecond, Factor: 2, Jitter: 0.1, Steps: 10, Cap: 30 * time.Second},
+		shortWatch: time.Second,
+		pods:       map[string]corev1.PodPhase{},
+	}
+}
+
+// list replaces what the watcher knows with a fresh LIST. Changes since the
+// last one are printed, including deletions the watcher missed while its
+// watch was down: a watch that resumes cannot report those.
+func (w *rawWatcher) list(ctx context.Context) error {
+	pods, err := w.clientset.CoreV1().Pods(w.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	seen := make(map[string]corev1.PodPhase, len(pods.Items))
+	for _, pod := range pods.Items {
+		seen[pod.Name] = pod.Status.Phase
+		old, known := w.pods[pod.Name]
+		switch {
+		case !known:
+			fmt.Fprintf(w.out, "(+) %s/%s %s\n", w.namespace, pod.Name, pod.Status.Phase)
+		case old != pod.Status.Phase:
+			fmt.Fprintf(w.out, "(*) %s/%s %s -> %s (while the watch was down)\n", w.namespace, pod.Name, old, pod.Status.Phase)
+		}
+	}
+	for _, name := range sortedNames(w.pods) {
+		if _, ok := seen[name]; !ok {
+			fmt.Fprintf(w.out, "(-) %s/%s (deleted while the watch was down)\n", w.namespace, name)
+		}
+	}
+	w.pods, w.lastRV = seen, pods.ResourceVersion
+	fmt.Fprintf(w.out, "Listed %d pods at resourceVersion %s\n", len(pods.Items), w.lastRV)
+	return nil
+}
+
+// watchOnce watches from lastRV until the result channel closes, which is
+// how a watch ends normally: the server's timeout, a restarted API server or
+// a dropped connection all look the same. It returns how long the watch
+// lasted. An ERROR event, such as 410 Expired, is returned as an error.
+func (w *rawWatcher) watchOnce(ctx context.Context) (time.Duration, error) {
+	// Spread the timeouts, so many clients do not reconnect at once
+	timeoutSeconds := int64(w.timeout.Seconds() * (1 + rand.Float64()))
+	start := time.Now()
+	watcher, err := w.clientset.CoreV1().Pods(w.namespace).Watch(ctx, metav1.ListOptions{
+		ResourceVersion: w.lastRV,
+		TimeoutSeconds:  &timeoutSeconds,
+		// Bookmarks keep lastRV recent in a quiet namespace (see 65_watch_bookmarks)
+		AllowWatchBookmarks: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to watch pods: %w", err)
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		if err := w.handle(event); err != nil {
+			return time.Since(start), err
+		}
+	}
+	return time.Since(start), nil
+}
+
+// handle applies one event and keeps its resourceVersion
+func (w *rawWatcher) handle(event watch.Event) error {
+	if event.Type == watch.Error {
+		return apierrors.FromObject(event.Object)
+	}
+	obj, err := meta.Accessor(event.Object)
+	if err != nil {
+		return fmt.Errorf("unexpected %s object %T: %w", event.Type, event.Object, err)
+	}
+	w.lastRV = obj.GetResourceVersion()
+	pod, ok := event.Object.(*corev1.Pod)
+	if !ok {
+		return fmt.Errorf("unexpected %s object %T", event.Type, event.Object)
+	}
+	switch event.Type {
+	case watch.Added:
+		w.pods[pod.Name] = pod.Status.Phase
+		fmt.Fprintf(w.out, "(+) %s/%s %s\n", pod.Namespace, pod.Name, pod.Status.Phase)
+	case watch.Modified:
+		if old := w.pods[pod.Name]; old != pod.Status.Phase {
+			fmt.Fprintf(w.out, "(*) %s/%s %s -> %s\n", pod.Namespace, pod.Name, old, pod.Status.Phase)
+		}
+		w.pods[pod.Name] = pod.Status.Phase
+	case watch.Deleted:
+		delete(w.pods, pod.Name)
+		fmt.Fprintf(w.out, "(-) %s/%s\n", pod.Namespace, pod.Name)
+	}
+	return nil
+}
+
+// run lists, then watches until ctx is cancelled:
+//   - the result channel closed: watch again from the last resourceVersion
+//   - 410 Expired: that version is compacted, so list again
+//   - any other failure: retry with exponential backoff
+func (w *rawWatcher) run(ctx context.Context) {
+	backoff := w.backoff
+	needList := true
+	for ctx.Err() == nil {
+		if needList {
+			if err := w.list(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				w.retryAfter(ctx, &backoff, err)
+				continue
+			}
+			needList = false
+		}
+
+		lasted, err := w.watchOnce(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case apierrors.IsResourceExpired(err) || apierrors.IsGone(err):
+			w.relists++
+			fmt.Fprintf(w.out, "--- resourceVersion %s expired, listing again ---\n", w.lastRV)
+			needList = true
+		case err != nil:
+			w.retryAfter(ctx, &backoff, err)
+		case lasted < w.shortWatch:
+			w.retryAfter(ctx, &backoff, fmt.Errorf("the watch closed after %s", lasted.Round(time.Millisecond)))
+		default:
+			w.reconnects++
+			backoff = w.backoff
+			fmt.Fprintf(w.out, "--- Watch closed after %s, resuming from resourceVersion %s ---\n", lasted.Round(time.Second), w.lastRV)
+		}
+	}
+}
+
+// retryAfter counts a failure and waits for the next backoff step
+func (w *rawWatcher) retryAfter(ctx context.Context, backoff *wait.Backoff, err error) {
+	w.failures++
+	delay := backoff.Step()
+	fmt.Fprintf(w.out, "--- %v; retrying in %s ---\n", err, delay.Round(time.Millisecond))
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+}
+
+// printSummary reports how the watches ended
+func (w *rawWatcher) printSummary() {
+	fmt.Fprintln(w.out, "\n=== Summary ===")
+	fmt.Fprintf(w.out, "Pods known: %d, at resourceVersion %s\n", len(w.pods), w.lastRV)
+	fmt.Fprintf(w.out, "Watches resumed: %d, relists after 410 Expired: %d, failures: %d\n", w.reconnects, w.relists, w.failures)
+}
+
+func sortedNames(pods map[string]corev1.PodPhase) []string {
+	names := make([]string, 0, len(pods))
+	for name := range pods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
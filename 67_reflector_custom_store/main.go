@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace   = flag.String("namespace", "", "only watch the pods of this namespace")
+	resync      = flag.Duration("resync", 0, "call Resync on the store this often, 0 for never")
+	reportEvery = flag.Duration("report-every", 30*time.Second, "print the pod count per phase this often")
+	quiet       = flag.Bool("quiet", false, "do not print Add, Update and Delete calls")
+)
+
+// createClientSet creates a Kubernetes clientset from kubeconfig
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "67-reflector-custom-store")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// newPodReflector returns a reflector that keeps store in step with the
+// pods of namespace. This is the bottom half of every informer: a
+// SharedIndexInformer passes a DeltaFIFO as the store, and pops it into its
+// indexer and the event handlers.
+func newPodReflector(clientset kubernetes.Interface, namespace string, store cache.ReflectorStore, resync time.Duration) *cache.Reflector {
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().Pods(namespace).List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().Pods(namespace).Watch(ctx, options)
+		},
+	}
+	return cache.NewReflectorWithOptions(lw, &corev1.Pod{}, store, cache.ReflectorOptions{
+		Name:         "pod-phases",
+		ResyncPeriod: resync,
+	})
+}
+
+// printReport prints the pod count per phase and how far the reflector got
+func printReport(w io.Writer, store *phaseStore, r *cache.Reflector) {
+	counts := store.countByPhase()
+	phases := make([]string, 0, len(counts))
+	for phase, n := range counts {
+		phases = append(phases, fmt.Sprintf("%s=%d", phase, n))
+	}
+	sort.Strings(phases)
+	fmt.Fprintf(w, "[Report] %d pods (%s), last synced resourceVersion %s\n",
+		len(store.ListKeys()), strings.Join(phases, " "), r.LastSyncResourceVersion())
+}
+
+func main() {
+	clientset := createClientSet()
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	store := newPhaseStore(os.Stdout, *quiet)
+	reflector := newPodReflector(clientset, *namespace, store, *resync)
+	go reflector.RunWithContext(ctx)
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		// Nothing was listed yet before the first Replace
+		if reflector.LastSyncResourceVersion() != "" {
+			printReport(os.Stdout, store, reflector)
+		}
+	}, *reportEvery)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func pod(name string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestPhaseStore(t *testing.T) {
+	var out bytes.Buffer
+	s := newPhaseStore(&out, false)
+
+	if err := s.Replace([]interface{}{pod("web-0", corev1.PodRunning), pod("web-1", corev1.PodPending)}, "10"); err != nil {
+		t.Fatal(err)
+	}
+	s.Update(pod("web-1", corev1.PodRunning))
+	s.Update(pod("web-1", corev1.PodRunning))
+	s.Add(pod("web-2", corev1.PodPending))
+	s.Delete(pod("web-0", corev1.PodRunning))
+	s.Delete(cache.DeletedFinalStateUnknown{Key: "default/web-2"})
+	s.Replace([]interface{}{pod("web-3", corev1.PodSucceeded)}, "20")
+	s.Resync()
+
+	want := `[Store] Replace with 2 pods at resourceVersion 10 (0 gone)
+[Store] Update default/web-1: Pending -> Running
+[Store] Update default/web-1: still Running
+[Store] Add default/web-2: Pending
+[Store] Delete default/web-0
+[Store] Delete default/web-2
+[Store] Replace with 1 pods at resourceVersion 20 (1 gone)
+[Store] Resync
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+	phase, ok, _ := s.GetByKey("default/web-3")
+	if !ok || phase != corev1.PodSucceeded {
+		t.Errorf("web-3 = %v, %v", phase, ok)
+	}
+	if _, ok, _ := s.Get(pod("web-1", "")); ok {
+		t.Error("web-1 survived the Replace")
+	}
+	if err := s.Add(&corev1.Node{}); err == nil {
+		t.Error("a node was accepted")
+	}
+}
+
+func TestReflectorFillsTheStore(t *testing.T) {
+	clientset := fake.NewClientset(pod("web-0", corev1.PodRunning), pod("web-1", corev1.PodPending))
+	out := &testutil.SyncBuffer{}
+	store := newPhaseStore(out, false)
+	r := newPodReflector(clientset, "default", store, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.RunWithContext(ctx)
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+			return cond(), nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", what, err, out.String())
+		}
+	}
+	waitFor("initial list", func() bool { return len(store.ListKeys()) == 2 })
+	// The watch starts after the Replace; wait until it is open
+	waitFor("watch", func() bool {
+		for _, a := range clientset.Actions() {
+			if a.GetVerb() == "watch" {
+				return true
+			}
+		}
+		return false
+	})
+
+	if _, err := clientset.CoreV1().Pods("default").UpdateStatus(ctx, pod("web-1", corev1.PodRunning), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := clientset.CoreV1().Pods("default").Delete(ctx, "web-0", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("watch events", func() bool { return strings.Contains(out.String(), "[Store] Delete default/web-0") })
+
+	if got := store.countByPhase(); len(got) != 1 || got[corev1.PodRunning] != 1 {
+		t.Errorf("phases = %v", got)
+	}
+	if !strings.Contains(out.String(), "[Store] Update default/web-1: Pending -> Running") {
+		t.Errorf("got:\n%s", out.String())
+	}
+	var report bytes.Buffer
+	printReport(&report, store, r)
+	if !strings.HasPrefix(report.String(), "[Report] 1 pods (Running=1), last synced resourceVersion") {
+		t.Errorf("report = %q", report.String())
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// phaseStore is a cache.Store that keeps only the key and the phase of each
+// pod, instead of the whole object a SharedIndexInformer would keep. It
+// prints every call, so it shows what a reflector asks of its store: Replace
+// after each LIST, Add, Update and Delete for watch events, and Resync on
+// the resync period.
+type phaseStore struct {
+	mu     sync.RWMutex
+	phases map[string]corev1.PodPhase
+	out    io.Writer
+	// quiet stops Add, Update and Delete from printing, on busy namespaces
+	quiet bool
+}
+
+var _ cache.Store = &phaseStore{}
+
+func newPhaseStore(out io.Writer, quiet bool) *phaseStore {
+	return &phaseStore{phases: map[string]corev1.PodPhase{}, out: out, quiet: quiet}
+}
+
+// podKeyAndPhase returns the namespace/name key of a pod and its phase
+func podKeyAndPhase(obj interface{}) (string, corev1.PodPhase, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return "", "", fmt.Errorf("expected *v1.Pod, got %T", obj)
+	}
+	key, err := cache.MetaNamespaceKeyFunc(pod)
+	return key, pod.Status.Phase, err
+}
+
+func (s *phaseStore) Add(obj interface{}) error {
+	return s.set("Add", obj)
+}
+
+func (s *phaseStore) Update(obj interface{}) error {
+	return s.set("Update", obj)
+}
+
+func (s *phaseStore) set(call string, obj interface{}) error {
+	key, phase, err := podKeyAndPhase(obj)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, known := s.phases[key]
+	s.phases[key] = phase
+	if s.quiet {
+		return nil
+	}
+	switch {
+	case !known:
+		fmt.Fprintf(s.out, "[Store] %s %s: %s\n", call, key, phase)
+	case old != phase:
+		fmt.Fprintf(s.out, "[Store] %s %s: %s -> %s\n", call, key, old, phase)
+	default:
+		// The pod changed, but not in anything this store keeps
+		fmt.Fprintf(s.out, "[Store] %s %s: still %s\n", call, key, phase)
+	}
+	return nil
+}
+
+func (s *phaseStore) Delete(obj interface{}) error {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.phases, key)
+	if !s.quiet {
+		fmt.Fprintf(s.out, "[Store] Delete %s\n", key)
+	}
+	return nil
+}
+
+// Replace swaps the whole content for the result of a LIST. The reflector
+// calls it after the first LIST and after every relist, e.g. when a watch
+// expired; pods missing from list were deleted in between.
+func (s *phaseStore) Replace(list []interface{}, resourceVersion string) error {
+	phases := make(map[string]corev1.PodPhase, len(list))
+	for _, obj := range list {
+		key, phase, err := podKeyAndPhase(obj)
+		if err != nil {
+			return err
+		}
+		phases[key] = phase
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gone := 0
+	for key := range s.phases {
+		if _, ok := phases[key]; !ok {
+			gone++
+		}
+	}
+	s.phases = phases
+	fmt.Fprintf(s.out, "[Store] Replace with %d pods at resourceVersion %s (%d gone)\n", len(phases), resourceVersion, gone)
+	return nil
+}
+
+// Resync has nothing to do here. In a DeltaFIFO it queues every object
+// again, which is how an informer's resync reaches UpdateFunc.
+func (s *phaseStore) Resync() error {
+	fmt.Fprintln(s.out, "[Store] Resync")
+	return nil
+}
+
+// List returns the phases. What a Store hands out is up to the store: this
+// one never had the pods.
+func (s *phaseStore) List() []interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]interface{}, 0, len(s.phases))
+	for _, phase := range s.phases {
+		list = append(list, phase)
+	}
+	return list
+}
+
+func (s *phaseStore) ListKeys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.phases))
+	for key := range s.phases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *phaseStore) Get(obj interface{}) (interface{}, bool, error) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return nil, false, err
+	}
+	return s.GetByKey(key)
+}
+
+func (s *phaseStore) GetByKey(key string) (interface{}, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	phase, ok := s.phases[key]
+	return phase, ok, nil
+}
+
+// countByPhase returns how many pods are in each phase
+func (s *phaseStore) countByPhase() map[corev1.PodPhase]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := map[corev1.PodPhase]int{}
+	for _, phase := range s.phases {
+		counts[phase]++
+	}
+	return counts
+}
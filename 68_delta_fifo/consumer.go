@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// newQueue returns an indexer and a DeltaFIFO that knows its keys. With
+// KnownObjects the queue can tell, on a relist, which objects the LIST no
+// longer has, and queue Deleted deltas for them. EmitDeltaTypeReplaced
+// marks the objects of a LIST as Replaced instead of Sync. The indexer's
+// key function must accept the DeletedFinalStateUnknown of such deletes.
+func newQueue() (cache.Indexer, *cache.DeltaFIFO) {
+	indexer := cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})
+	fifo := cache.NewDeltaFIFOWithOptions(cache.DeltaFIFOOptions{
+		KeyFunction:           cache.MetaNamespaceKeyFunc,
+		KnownObjects:          indexer,
+		EmitDeltaTypeReplaced: true,
+	})
+	return indexer, fifo
+}
+
+// consumer pops the DeltaFIFO and applies every delta to the indexer, as
+// the processDeltas function of an informer does before it calls the
+// event handlers
+type consumer struct {
+	fifo    *cache.DeltaFIFO
+	indexer cache.Indexer
+	out     io.Writer
+	// delay slows down every pop, so deltas pile up in the queue
+	delay time.Duration
+
+	counts map[cache.DeltaType]int
+}
+
+func newConsumer(fifo *cache.DeltaFIFO, indexer cache.Indexer, out io.Writer, delay time.Duration) *consumer {
+	return &consumer{fifo: fifo, indexer: indexer, out: out, delay: delay, counts: map[cache.DeltaType]int{}}
+}
+
+// run pops until ctx is cancelled, which closes the queue
+func (c *consumer) run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		c.fifo.Close()
+	}()
+	for {
+		if _, err := c.fifo.Pop(c.process); err != nil {
+			if errors.Is(err, cache.ErrFIFOClosed) {
+				return
+			}
+			fmt.Fprintf(c.out, "Failed to process deltas: %v\n", err)
+		}
+		if c.delay > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.delay):
+			}
+		}
+	}
+}
+
+// process handles all deltas queued for one object, oldest first. The
+// DeltaFIFO keeps one entry per key, so an object that changed several times
+// since its last pop comes out once, with all its deltas.
+func (c *consumer) process(obj interface{}, isInInitialList bool) error {
+	deltas, ok := obj.(cache.Deltas)
+	if !ok {
+		return fmt.Errorf("expected cache.Deltas, got %T", obj)
+	}
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(deltas.Newest().Object)
+	if err != nil {
+		return err
+	}
+	initial := ""
+	if isInInitialList {
+		initial = " (initial list)"
+	}
+	fmt.Fprintf(c.out, "[Pop] %s: %d delta(s)%s\n", key, len(deltas), initial)
+
+	for _, d := range deltas {
+		c.counts[d.Type]++
+		fmt.Fprintf(c.out, "  %-8s %s\n", d.Type, describe(d.Object))
+		switch d.Type {
+		case cache.Added, cache.Updated, cache.Replaced, cache.Sync:
+			// Whether it is an add or an update depends on the indexer, not
+			// on the delta type: a Replaced or Sync can be either
+			if _, exists, err := c.indexer.Get(d.Object); err == nil && exists {
+				if err := c.indexer.Update(d.Object); err != nil {
+					return err
+				}
+			} else if err := c.indexer.Add(d.Object); err != nil {
+				return err
+			}
+		case cache.Deleted:
+			if err := c.indexer.Delete(d.Object); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// describe shows what a delta carries: the pod's phase, or that the queue
+// only knows the key of a pod deleted while the watch was down
+func describe(obj interface{}) string {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return fmt.Sprintf("phase=%s resourceVersion=%s", o.Status.Phase, o.ResourceVersion)
+	case cache.DeletedFinalStateUnknown:
+		return "final state unknown: deleted while the watch was down"
+	}
+	return fmt.Sprintf("%T", obj)
+}
+
+// printSummary prints how many deltas of each type were popped
+func (c *consumer) printSummary() {
+	types := make([]string, 0, len(c.counts))
+	for t, n := range c.counts {
+		types = append(types, fmt.Sprintf("%s=%d", t, n))
+	}
+	sort.Strings(types)
+	fmt.Fprintf(c.out, "\n=== Summary ===\nDeltas popped: %v\nPods in the indexer: %d\n", types, len(c.indexer.ListKeys()))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace = flag.String("namespace", "default", "namespace whose pods are watched")
+	resync    = flag.Duration("resync", time.Minute, "queue a Sync delta for every pod this often, 0 for never")
+	popDelay  = flag.Duration("pop-delay", 0, "wait this long after each pop, so deltas pile up per pod")
+)
+
+// createClientSet creates a Kubernetes clientset from kubeconfig
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "68-delta-fifo")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// newPodReflector returns a reflector that lists and watches the pods of
+// namespace into fifo, and asks it to resync every resync
+func newPodReflector(clientset kubernetes.Interface, namespace string, fifo *cache.DeltaFIFO, resync time.Duration) *cache.Reflector {
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().Pods(namespace).List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().Pods(namespace).Watch(ctx, options)
+		},
+	}
+	return cache.NewReflectorWithOptions(lw, &corev1.Pod{}, fifo, cache.ReflectorOptions{
+		Name:         "pod-deltas",
+		ResyncPeriod: resync,
+	})
+}
+
+func main() {
+	clientset := createClientSet()
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	indexer, fifo := newQueue()
+	go newPodReflector(clientset, *namespace, fifo, *resync).RunWithContext(ctx)
+
+	c := newConsumer(fifo, indexer, os.Stdout, *popDelay)
+	c.run(ctx)
+	c.printSummary()
+}
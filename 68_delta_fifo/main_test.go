@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func pod(name, rv string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: rv},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+// pop pops n objects; the queue must hold that many
+func pop(t *testing.T, c *consumer, n int) {
+	t.Helper()
+	for range n {
+		if _, err := c.fifo.Pop(c.process); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEveryDeltaType(t *testing.T) {
+	indexer, fifo := newQueue()
+	var out bytes.Buffer
+	c := newConsumer(fifo, indexer, &out, 0)
+
+	// The first LIST
+	fifo.Replace([]interface{}{pod("web-0", "1", corev1.PodPending), pod("web-1", "2", corev1.PodRunning)}, "2")
+	pop(t, c, 2)
+	// Two watch events for web-0 before the next pop come out together
+	fifo.Update(pod("web-0", "3", corev1.PodRunning))
+	fifo.Update(pod("web-0", "4", corev1.PodSucceeded))
+	fifo.Add(pod("web-2", "5", corev1.PodPending))
+	pop(t, c, 2)
+	// A resync queues every pod the indexer knows
+	fifo.Resync()
+	pop(t, c, 3)
+	// A relist after the watch was down: web-1 and web-2 are gone, web-3 is new
+	fifo.Replace([]interface{}{pod("web-0", "4", corev1.PodSucceeded), pod("web-3", "9", corev1.PodPending)}, "9")
+	pop(t, c, 4)
+
+	if got := indexer.ListKeys(); len(got) != 2 {
+		t.Errorf("indexer holds %v, want web-0 and web-3", got)
+	}
+	obj, _, _ := indexer.GetByKey("default/web-0")
+	if obj.(*corev1.Pod).ResourceVersion != "4" {
+		t.Errorf("web-0 = %+v", obj)
+	}
+	want := map[cache.DeltaType]int{cache.Replaced: 4, cache.Updated: 2, cache.Added: 1, cache.Sync: 3, cache.Deleted: 2}
+	for typ, n := range want {
+		if c.counts[typ] != n {
+			t.Errorf("%s deltas = %d, want %d (all: %v)", typ, c.counts[typ], n, c.counts)
+		}
+	}
+	for _, line := range []string{
+		"[Pop] default/web-0: 1 delta(s) (initial list)\n  Replaced phase=Pending resourceVersion=1\n",
+		"[Pop] default/web-0: 2 delta(s)\n  Updated  phase=Running resourceVersion=3\n  Updated  phase=Succeeded resourceVersion=4\n",
+		"[Pop] default/web-2: 1 delta(s)\n  Added    phase=Pending resourceVersion=5\n",
+		"  Sync     phase=Running resourceVersion=2\n",
+		"[Pop] default/web-1: 1 delta(s)\n  Deleted  final state unknown: deleted while the watch was down\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output lacks %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestReflectorFeedsTheQueue(t *testing.T) {
+	clientset := fake.NewClientset(pod("web-0", "", corev1.PodRunning))
+	indexer, fifo := newQueue()
+	out := &testutil.SyncBuffer{}
+	c := newConsumer(fifo, indexer, out, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	go newPodReflector(clientset, "default", fifo, 0).RunWithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		c.run(ctx)
+		close(done)
+	}()
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+			return cond(), nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", what, err, out.String())
+		}
+	}
+	waitFor("initial list", func() bool { return len(indexer.ListKeys()) == 1 })
+	waitFor("watch", func() bool {
+		for _, a := range clientset.Actions() {
+			if a.GetVerb() == "watch" {
+				return true
+			}
+		}
+		return false
+	})
+	if err := clientset.CoreV1().Pods("default").Delete(ctx, "web-0", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("delete", func() bool { return len(indexer.ListKeys()) == 0 })
+
+	// Cancelling closes the queue and ends the consumer
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the consumer did not stop")
+	}
+	if !strings.Contains(out.String(), "  Deleted  phase=Running") {
+		t.Errorf("got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// nodeIndex indexes pods by the node they run on
+const nodeIndex = "byNode"
+
+// requests counts the LIST and WATCH calls of one ListerWatcher
+type requests struct {
+	lists   atomic.Int64
+	watches atomic.Int64
+}
+
+// listWatch returns a ListerWatcher for the pods of namespace that counts
+// its calls
+func (r *requests) listWatch(clientset kubernetes.Interface, namespace string) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			r.lists.Add(1)
+			return clientset.CoreV1().Pods(namespace).List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			r.watches.Add(1)
+			return clientset.CoreV1().Pods(namespace).Watch(ctx, options)
+		},
+	}
+}
+
+// events counts what one handler was called with
+type events struct {
+	adds        atomic.Int64
+	initialAdds atomic.Int64
+	updates     atomic.Int64
+	deletes     atomic.Int64
+}
+
+func (e *events) handler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			e.adds.Add(1)
+			if isInInitialList {
+				e.initialAdds.Add(1)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) { e.updates.Add(1) },
+		DeleteFunc: func(obj interface{}) { e.deletes.Add(1) },
+	}
+}
+
+// legacyInformer is the controller cache.NewInformer returns, built with
+// NewInformerWithOptions, its current spelling: one ListerWatcher, one
+// store and exactly one handler, fixed when it is created
+type legacyInformer struct {
+	store      cache.Store
+	controller cache.Controller
+	requests   *requests
+	events     *events
+}
+
+func newLegacyInformer(clientset kubernetes.Interface, namespace string) *legacyInformer {
+	l := &legacyInformer{requests: &requests{}, events: &events{}}
+	l.store, l.controller = cache.NewInformerWithOptions(cache.InformerOptions{
+		ListerWatcher: l.requests.listWatch(clientset, namespace),
+		ObjectType:    &corev1.Pod{},
+		Handler:       l.events.handler(),
+	})
+	return l
+}
+
+// sharedInformer is one SharedIndexInformer with a handler added before it
+// starts and one added once it has synced
+type sharedInformer struct {
+	informer cache.SharedIndexInformer
+	requests *requests
+	first    *events
+	late     *events
+}
+
+func newSharedInformer(clientset kubernetes.Interface, namespace string) (*sharedInformer, error) {
+	s := &sharedInformer{requests: &requests{}, first: &events{}, late: &events{}}
+	s.informer = cache.NewSharedIndexInformer(s.requests.listWatch(clientset, namespace), &corev1.Pod{}, 0, cache.Indexers{
+		nodeIndex: func(obj interface{}) ([]string, error) {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok {
+				return nil, fmt.Errorf("expected *v1.Pod, got %T", obj)
+			}
+			if pod.Spec.NodeName == "" {
+				return nil, nil
+			}
+			return []string{pod.Spec.NodeName}, nil
+		},
+	})
+	_, err := s.informer.AddEventHandler(s.first.handler())
+	return s, err
+}
+
+// addLateHandler adds the second handler to the running informer. It gets
+// an Add for every pod already in the cache, so it starts from the same
+// state as the first one.
+func (s *sharedInformer) addLateHandler() (cache.ResourceEventHandlerRegistration, error) {
+	return s.informer.AddEventHandler(s.late.handler())
+}
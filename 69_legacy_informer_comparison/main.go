@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace = flag.String("namespace", "default", "namespace whose pods are cached")
+	observe   = flag.Duration("observe", 10*time.Second, "how long to watch for changes before printing the comparison")
+)
+
+// createClientSet creates a Kubernetes clientset from kubeconfig
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "69-legacy-informer-comparison")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// start runs two legacy informers and one shared informer for the pods of
+// namespace, waits for all of them to sync and then adds the shared
+// informer's second handler
+func start(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]*legacyInformer, *sharedInformer, error) {
+	legacy := []*legacyInformer{newLegacyInformer(clientset, namespace), newLegacyInformer(clientset, namespace)}
+	shared, err := newSharedInformer(clientset, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	synced := []cache.InformerSynced{shared.informer.HasSynced}
+	for _, l := range legacy {
+		go l.controller.RunWithContext(ctx)
+		synced = append(synced, l.controller.HasSynced)
+	}
+	go shared.informer.RunWithContext(ctx)
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return nil, nil, fmt.Errorf("caches did not sync")
+	}
+
+	// A legacy informer has no way to add a handler now; the shared one
+	// replays its cache to the new handler
+	reg, err := shared.addLateHandler()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), reg.HasSynced) {
+		return nil, nil, fmt.Errorf("late handler did not sync")
+	}
+	return legacy, shared, nil
+}
+
+func main() {
+	clientset := createClientSet()
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	legacy, shared, err := start(ctx, clientset, *namespace)
+	if err != nil {
+		log.Fatalf("Failed to start informers: %v", err)
+	}
+	fmt.Printf("All caches synced, watching pods in %q for %s\n", *namespace, *observe)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(*observe):
+	}
+	printComparison(os.Stdout, legacy, shared)
+}
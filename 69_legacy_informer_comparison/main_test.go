@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func pod(name, node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: node},
+	}
+}
+
+func TestLegacyAndSharedInformers(t *testing.T) {
+	clientset := fake.NewClientset(
+		pod("web-0", "node-a"),
+		pod("web-1", "node-b"),
+		pod("web-2", "node-b"),
+		pod("pending", ""),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	legacy, shared, err := start(ctx, clientset, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, l := range legacy {
+		if got := l.requests.lists.Load(); got != 1 {
+			t.Errorf("legacy informer %d listed %d times, want 1", i, got)
+		}
+		if got := len(l.store.ListKeys()); got != 4 {
+			t.Errorf("legacy informer %d caches %d pods, want 4", i, got)
+		}
+	}
+	if got := shared.requests.lists.Load(); got != 1 {
+		t.Errorf("shared informer listed %d times, want 1", got)
+	}
+	if got := shared.first.adds.Load(); got != 4 {
+		t.Errorf("first handler got %d adds, want 4", got)
+	}
+	// The late handler is caught up from the cache, without another LIST
+	if got := shared.late.adds.Load(); got != 4 {
+		t.Errorf("late handler got %d adds, want 4", got)
+	}
+
+	// A new pod reaches every handler of both kinds of informer
+	if _, err := clientset.CoreV1().Pods("default").Create(ctx, pod("web-3", "node-a"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		return legacy[0].events.adds.Load() == 5 && legacy[1].events.adds.Load() == 5 &&
+			shared.first.adds.Load() == 5 && shared.late.adds.Load() == 5, nil
+	})
+	if err != nil {
+		t.Fatalf("web-3 did not reach every handler: %v", err)
+	}
+
+	if got := podsPerNode(shared.informer.GetIndexer()); got != "node-a=2 node-b=2" {
+		t.Errorf("pods per node = %q", got)
+	}
+
+	var out bytes.Buffer
+	printComparison(&out, legacy, shared)
+	for _, line := range []string{
+		"LIST requests         2                 1\n",
+		"Pods in memory        10                5\n",
+		"Adds delivered        10                5 + 5 (late handler)\n",
+		"Handler added later   not possible      replayed 5 pods\n",
+		"Pods per node         no index          node-a=2 node-b=2\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output lacks %q:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/tombstone"
+)
+
+var (
+	dir  = flag.String("dir", ".", "directory whose files are served as ConfigMaps")
+	poll = flag.Duration("poll", time.Second, "how often the directory is scanned for changes")
+)
+
+// extensionIndex indexes the ConfigMaps by their file's extension
+const extensionIndex = "byExtension"
+
+// newFileInformer returns a shared informer over src, with an index by
+// extension, exactly as if src were the API server
+func newFileInformer(src cache.ListerWatcher) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(src, &corev1.ConfigMap{}, 0, cache.Indexers{
+		extensionIndex: func(obj interface{}) ([]string, error) {
+			cm, ok := obj.(*corev1.ConfigMap)
+			if !ok {
+				return nil, fmt.Errorf("expected *v1.ConfigMap, got %T", obj)
+			}
+			return []string{cm.Labels[extensionLabel]}, nil
+		},
+	})
+}
+
+// size is the number of bytes a ConfigMap holds
+func size(cm *corev1.ConfigMap) int {
+	n := 0
+	for _, v := range cm.Data {
+		n += len(v)
+	}
+	for _, v := range cm.BinaryData {
+		n += len(v)
+	}
+	return n
+}
+
+// printHandler prints every change the informer delivers
+func printHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			cm := obj.(*corev1.ConfigMap)
+			fmt.Printf("[Added] %s (%d bytes, rv=%s)\n", cm.Name, size(cm), cm.ResourceVersion)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldCM, newCM := oldObj.(*corev1.ConfigMap), newObj.(*corev1.ConfigMap)
+			fmt.Printf("[Updated] %s (%d -> %d bytes, rv=%s)\n", newCM.Name, size(oldCM), size(newCM), newCM.ResourceVersion)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if cm, ok := tombstone.Unwrap[*corev1.ConfigMap](obj); ok {
+				fmt.Printf("[Deleted] %s\n", cm.Name)
+			}
+		},
+	}
+}
+
+// printByExtension prints the files of every extension from the index
+func printByExtension(indexer cache.Indexer) {
+	extensions := indexer.ListIndexFuncValues(extensionIndex)
+	sort.Strings(extensions)
+	fmt.Printf("\n=== %d files by extension ===\n", len(indexer.ListKeys()))
+	for _, ext := range extensions {
+		objs, err := indexer.ByIndex(extensionIndex, ext)
+		if err != nil {
+			fmt.Printf("%s: %v\n", ext, err)
+			continue
+		}
+		names := make([]string, 0, len(objs))
+		for _, obj := range objs {
+			names = append(names, obj.(*corev1.ConfigMap).Name)
+		}
+		sort.Strings(names)
+		fmt.Printf("%-6s %v\n", ext, names)
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	src := newDirSource(*dir)
+	if err := src.scan(); err != nil {
+		log.Fatalf("Failed to read %s: %v", *dir, err)
+	}
+	go src.run(ctx, *poll)
+
+	informer := newFileInformer(src)
+	if _, err := informer.AddEventHandler(printHandler()); err != nil {
+		log.Fatalf("Failed to add event handler: %v", err)
+	}
+	go informer.RunWithContext(ctx)
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		log.Fatal("Failed to sync cache")
+	}
+	fmt.Printf("Watching %s, press Ctrl-C to stop\n", *dir)
+
+	<-ctx.Done()
+	printByExtension(informer.GetIndexer())
+}
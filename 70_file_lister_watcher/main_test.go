@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func scan(t *testing.T, s *dirSource) {
+	t.Helper()
+	if err := s.scan(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestObjectName(t *testing.T) {
+	for file, want := range map[string]string{
+		"notes.txt":     "notes.txt",
+		"My Notes.TXT":  "my-notes.txt",
+		"_draft_.md":    "draft.md",
+		"日本.yaml":       "yaml",
+		"config.v2.ini": "config.v2.ini",
+	} {
+		if got := objectName(file); got != want {
+			t.Errorf("objectName(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestScanEmitsChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", "one")
+	writeFile(t, dir, ".hidden", "skipped")
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s := newDirSource(dir)
+	scan(t, s)
+
+	w, err := s.Watch(metav1.ListOptions{ResourceVersion: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	writeFile(t, dir, "a.txt", "two")
+	writeFile(t, dir, "b.bin", "\xff\xfe")
+	scan(t, s)
+	// A scan without changes emits nothing
+	scan(t, s)
+	if err := os.Remove(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	scan(t, s)
+
+	var got []string
+	for range 3 {
+		select {
+		case e := <-w.ResultChan():
+			cm := e.Object.(*corev1.ConfigMap)
+			got = append(got, string(e.Type)+" "+cm.Name+" "+cm.ResourceVersion)
+		case <-time.After(time.Second):
+			t.Fatalf("got %v", got)
+		}
+	}
+	// b.bin and the update of a.txt come from one scan, in map order
+	if got[2] != "DELETED a.txt 4" || len(got) != 3 {
+		t.Errorf("events = %v", got)
+	}
+
+	list, err := s.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cms := list.(*corev1.ConfigMapList)
+	if cms.ResourceVersion != "4" || len(cms.Items) != 1 {
+		t.Fatalf("list = %+v", cms)
+	}
+	if b := cms.Items[0]; b.Name != "b.bin" || string(b.BinaryData["b.bin"]) != "\xff\xfe" || b.Labels[extensionLabel] != "bin" {
+		t.Errorf("b.bin = %+v", b)
+	}
+}
+
+func TestWatchReplaysAndExpires(t *testing.T) {
+	dir := t.TempDir()
+	s := newDirSource(dir)
+	for i := range historySize + 5 {
+		writeFile(t, dir, "counter", string(rune('a'+i%26)))
+		scan(t, s)
+	}
+
+	// Resuming from a recent resourceVersion replays what came after it
+	w, err := s.Watch(metav1.ListOptions{ResourceVersion: "100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	for _, want := range []string{"101", "102", "103", "104", "105"} {
+		e := <-w.ResultChan()
+		if rv := e.Object.(*corev1.ConfigMap).ResourceVersion; rv != want || e.Type != watch.Modified {
+			t.Errorf("replayed %s %s, want MODIFIED %s", e.Type, rv, want)
+		}
+	}
+
+	// The first events have left the history
+	if _, err := s.Watch(metav1.ListOptions{ResourceVersion: "2"}); !apierrors.IsResourceExpired(err) {
+		t.Errorf("watch from 2: %v, want Expired", err)
+	}
+	if _, err := s.Watch(metav1.ListOptions{ResourceVersion: "x"}); !apierrors.IsBadRequest(err) {
+		t.Errorf("watch from x: %v, want BadRequest", err)
+	}
+}
+
+func TestSlowWatcherIsDropped(t *testing.T) {
+	dir := t.TempDir()
+	s := newDirSource(dir)
+	w, err := s.Watch(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	for i := range watchBuffer + 1 {
+		writeFile(t, dir, "counter", string(rune('a'+i%26))+"x")
+		scan(t, s)
+	}
+	n := 0
+	for range w.ResultChan() {
+		n++
+	}
+	if n != watchBuffer {
+		t.Errorf("got %d events before the channel closed, want %d", n, watchBuffer)
+	}
+}
+
+func TestInformerOverDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.txt", "one")
+	writeFile(t, dir, "b.yaml", "k: v")
+	s := newDirSource(dir)
+	scan(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	informer := newFileInformer(s)
+	go informer.RunWithContext(ctx)
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+
+	writeFile(t, dir, "c.txt", "three")
+	if err := os.Remove(filepath.Join(dir, "b.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	scan(t, s)
+
+	indexer := informer.GetIndexer()
+	err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		txt, _ := indexer.ByIndex(extensionIndex, "txt")
+		yaml, _ := indexer.ByIndex(extensionIndex, "yaml")
+		return len(txt) == 2 && len(yaml) == 0, nil
+	})
+	if err != nil {
+		t.Fatalf("indexer holds %v", indexer.ListKeys())
+	}
+	obj, exists, err := indexer.GetByKey("files/c.txt")
+	if err != nil || !exists || obj.(*corev1.ConfigMap).Data["c.txt"] != "three" {
+		t.Errorf("files/c.txt = %v, %v, %v", obj, exists, err)
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// namespace is the namespace every file's ConfigMap is put in
+	namespace = "files"
+	// extensionLabel holds a file's extension without the dot, or "none"
+	extensionLabel = "k8s-lab/extension"
+	// pathAnnotation holds the path a ConfigMap was read from
+	pathAnnotation = "k8s-lab/path"
+	// historySize is how many events a watch can be resumed from, like the
+	// watch cache of the API server
+	historySize = 100
+	// watchBuffer is how many events a watcher may fall behind before it is
+	// dropped
+	watchBuffer = 100
+	// maxFileSize is the most a ConfigMap can hold
+	maxFileSize = 1 << 20
+)
+
+// dirSource serves the regular files of a directory as ConfigMaps, one per
+// file, through the ListerWatcher interface an informer expects. Every
+// change it sees bumps a resourceVersion counter, as etcd's revision does.
+type dirSource struct {
+	dir string
+
+	mu       sync.Mutex
+	rv       uint64
+	objects  map[string]*corev1.ConfigMap
+	history  []watch.Event
+	watchers map[chan watch.Event]struct{}
+}
+
+var (
+	_ cache.ListerWatcher            = &dirSource{}
+	_ cache.ListerWatcherWithContext = &dirSource{}
+)
+
+func newDirSource(dir string) *dirSource {
+	return &dirSource{
+		dir:      dir,
+		objects:  map[string]*corev1.ConfigMap{},
+		watchers: map[chan watch.Event]struct{}{},
+	}
+}
+
+// run scans the directory every interval until ctx is cancelled
+func (s *dirSource) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.scan(); err != nil {
+				fmt.Printf("Failed to scan %s: %v\n", s.dir, err)
+			}
+		}
+	}
+}
+
+// scan reads the directory and turns what changed since the last scan into
+// watch events
+func (s *dirSource) scan() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	current := map[string]*corev1.ConfigMap{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(s.dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil || info.Size() > maxFileSize {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			// Removed between ReadDir and ReadFile: the next scan sees it gone
+			continue
+		}
+		cm := fileConfigMap(path, content)
+		current[cm.Name] = cm
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, cm := range current {
+		old, exists := s.objects[name]
+		switch {
+		case !exists:
+			cm.CreationTimestamp = metav1.Now()
+			s.emit(watch.Added, cm)
+		case !sameContent(old, cm):
+			cm.CreationTimestamp = old.CreationTimestamp
+			s.emit(watch.Modified, cm)
+		}
+	}
+	for name, old := range s.objects {
+		if _, exists := current[name]; !exists {
+			deleted := old.DeepCopy()
+			s.emit(watch.Deleted, deleted)
+		}
+	}
+	return nil
+}
+
+// emit gives obj the next resourceVersion, records the change and sends it
+// to every watcher. A watcher whose buffer is full is dropped: its channel
+// is closed and the reflector behind it watches again from its last
+// resourceVersion. The caller holds s.mu.
+func (s *dirSource) emit(eventType watch.EventType, obj *corev1.ConfigMap) {
+	s.rv++
+	obj.ResourceVersion = strconv.FormatUint(s.rv, 10)
+	if eventType == watch.Deleted {
+		delete(s.objects, obj.Name)
+	} else {
+		s.objects[obj.Name] = obj
+	}
+
+	event := watch.Event{Type: eventType, Object: obj}
+	s.history = append(s.history, event)
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+	for ch := range s.watchers {
+		select {
+		case ch <- event:
+		default:
+			delete(s.watchers, ch)
+			close(ch)
+		}
+	}
+}
+
+// List returns every ConfigMap and the resourceVersion they are current at
+func (s *dirSource) List(options metav1.ListOptions) (runtime.Object, error) {
+	return s.ListWithContext(context.Background(), options)
+}
+
+func (s *dirSource) ListWithContext(_ context.Context, _ metav1.ListOptions) (runtime.Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := &corev1.ConfigMapList{ListMeta: metav1.ListMeta{ResourceVersion: strconv.FormatUint(s.rv, 10)}}
+	for _, cm := range s.objects {
+		list.Items = append(list.Items, *cm.DeepCopy())
+	}
+	return list, nil
+}
+
+// Watch streams the changes after options.ResourceVersion. The events since
+// then are replayed from the history; if they are no longer all there, it
+// answers 410 Expired, and the reflector lists again.
+func (s *dirSource) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	return s.WatchWithContext(context.Background(), options)
+}
+
+func (s *dirSource) WatchWithContext(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from := s.rv
+	if options.ResourceVersion != "" && options.ResourceVersion != "0" {
+		rv, err := strconv.ParseUint(options.ResourceVersion, 10, 64)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid resourceVersion %q", options.ResourceVersion))
+		}
+		if rv > s.rv {
+			return nil, apierrors.NewTimeoutError(fmt.Sprintf("resourceVersion %d is newer than %d", rv, s.rv), 1)
+		}
+		from = rv
+	}
+	var replay []watch.Event
+	if from < s.rv {
+		if len(s.history) == 0 || eventRV(s.history[0]) > from+1 {
+			return nil, apierrors.NewResourceExpired(fmt.Sprintf("too old resource version: %d", from))
+		}
+		for _, event := range s.history {
+			if eventRV(event) > from {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	ch := make(chan watch.Event, watchBuffer+len(replay))
+	for _, event := range replay {
+		ch <- event
+	}
+	s.watchers[ch] = struct{}{}
+	w := watch.NewProxyWatcher(ch)
+	go func() {
+		select {
+		case <-w.StopChan():
+		case <-ctx.Done():
+			w.Stop()
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.watchers[ch]; ok {
+			delete(s.watchers, ch)
+			close(ch)
+		}
+	}()
+	return w, nil
+}
+
+// eventRV is the resourceVersion an event was recorded at
+func eventRV(event watch.Event) uint64 {
+	rv, _ := strconv.ParseUint(event.Object.(*corev1.ConfigMap).ResourceVersion, 10, 64)
+	return rv
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// objectName turns a file name into a ConfigMap name, a DNS subdomain:
+// "My Notes.TXT" becomes "my-notes.txt". Two files that map to the same name collide, and
+// the last one in directory order wins.
+func objectName(file string) string {
+	var labels []string
+	for _, label := range strings.Split(invalidNameChars.ReplaceAllString(strings.ToLower(file), "-"), ".") {
+		if label = strings.Trim(label, "-"); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	name := strings.Join(labels, ".")
+	if name == "" {
+		name = "file"
+	}
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return name
+}
+
+// fileConfigMap builds the ConfigMap of one file. Text goes into Data,
+// anything that is not UTF-8 into BinaryData, both under the file's name.
+func fileConfigMap(path string, content []byte) *corev1.ConfigMap {
+	file := filepath.Base(path)
+	ext := strings.TrimPrefix(filepath.Ext(file), ".")
+	if ext == "" {
+		ext = "none"
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        objectName(file),
+			Namespace:   namespace,
+			Labels:      map[string]string{extensionLabel: strings.ToLower(ext)},
+			Annotations: map[string]string{pathAnnotation: path},
+		},
+	}
+	if utf8.Valid(content) {
+		cm.Data = map[string]string{file: string(content)}
+	} else {
+		cm.BinaryData = map[string][]byte{file: content}
+	}
+	return cm
+}
+
+// sameContent reports whether two ConfigMaps of one file hold the same data
+func sameContent(a, b *corev1.ConfigMap) bool {
+	if len(a.Data) != len(b.Data) || len(a.BinaryData) != len(b.BinaryData) {
+		return false
+	}
+	for k, v := range a.Data {
+		if b.Data[k] != v {
+			return false
+		}
+	}
+	for k, v := range a.BinaryData {
+		if string(b.BinaryData[k]) != string(v) {
+			return false
+		}
+	}
+	return a.Annotations[pathAnnotation] == b.Annotations[pathAnnotation]
+}
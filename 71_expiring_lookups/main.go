@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespace   = flag.String("namespace", "", "only watch the pods of this namespace")
+	cacheKind   = flag.String("cache", "ttl", "how zones are memoized: ttl (cache.NewTTLStore), expiring (util/cache.Expiring) or none")
+	ttl         = flag.Duration("ttl", time.Minute, "how long a node's zone is remembered")
+	refresh     = flag.Duration("refresh", 10*time.Second, "look up expired zones again this often, ttl cache only, 0 for never")
+	resync      = flag.Duration("resync", 30*time.Second, "resync period of the pod informer, so every pod is handled again")
+	reportEvery = flag.Duration("report-every", 30*time.Second, "print the lookup counters this often")
+)
+
+// createClientSet creates a Kubernetes clientset from kubeconfig
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "71-expiring-lookups")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// uncachedZones looks every zone up, for comparison
+type uncachedZones struct {
+	lookup zoneLookup
+	s      stats
+}
+
+func (u *uncachedZones) stats() *stats { return &u.s }
+
+func (u *uncachedZones) zone(ctx context.Context, node string) (string, error) {
+	u.s.misses.Add(1)
+	zone, err := u.lookup(ctx, node)
+	if err != nil {
+		u.s.errors.Add(1)
+	}
+	return zone, err
+}
+
+// newResolver returns the resolver --cache asks for. It starts the refresh
+// loop of the ttl cache.
+func newResolver(ctx context.Context, kind string, lookup zoneLookup, ttl, refresh time.Duration) (zoneResolver, error) {
+	switch kind {
+	case "ttl":
+		t := newTTLZones(cache.NewTTLStore(nodeZoneKey, ttl), lookup)
+		if refresh > 0 {
+			go t.runRefresh(ctx, refresh)
+		}
+		return t, nil
+	case "expiring":
+		// Nodes that do not exist are remembered for a tenth of the TTL
+		return newExpiringZones(utilcache.NewExpiring(), lookup, ttl, ttl/10), nil
+	case "none":
+		return &uncachedZones{lookup: lookup}, nil
+	}
+	return nil, fmt.Errorf("unknown cache %q, want ttl, expiring or none", kind)
+}
+
+// zoneHandler resolves the zone of every scheduled pod it is given, and
+// prints it when a pod is first seen on its node. Resyncs and status
+// updates resolve it again, silently: these repeated lookups are what the
+// cache saves.
+func zoneHandler(ctx context.Context, resolver zoneResolver, out io.Writer) cache.ResourceEventHandler {
+	handle := func(pod *corev1.Pod, print bool) {
+		if pod.Spec.NodeName == "" {
+			return
+		}
+		zone, err := resolver.zone(ctx, pod.Spec.NodeName)
+		if err != nil {
+			fmt.Fprintf(out, "Failed to resolve the zone of %s: %v\n", pod.Spec.NodeName, err)
+			return
+		}
+		if print {
+			fmt.Fprintf(out, "[Scheduled] %s/%s on %s (zone %s)\n", pod.Namespace, pod.Name, pod.Spec.NodeName, zone)
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			handle(obj.(*corev1.Pod), true)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, newPod := oldObj.(*corev1.Pod), newObj.(*corev1.Pod)
+			handle(newPod, oldPod.Spec.NodeName != newPod.Spec.NodeName)
+		},
+	}
+}
+
+// printReport prints how often the API server was asked and how the cache
+// answered
+func printReport(out io.Writer, kind string, calls *atomic.Int64, resolver zoneResolver) {
+	fmt.Fprintf(out, "[Report] cache=%s node GETs=%d %s\n", kind, calls.Load(), resolver.stats())
+}
+
+func main() {
+	clientset := createClientSet()
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var calls atomic.Int64
+	resolver, err := newResolver(ctx, *cacheKind, apiZoneLookup(clientset, &calls), *ttl, *refresh)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, *resync, informers.WithNamespace(*namespace))
+	podInformer := factory.Core().V1().Pods().Informer()
+	if _, err := podInformer.AddEventHandler(zoneHandler(ctx, resolver, os.Stdout)); err != nil {
+		log.Fatalf("Failed to add event handler: %v", err)
+	}
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	if !cache.WaitForCacheSync(ctx.Done(), podInformer.HasSynced) {
+		log.Fatal("Failed to sync pod cache")
+	}
+	printReport(os.Stdout, *cacheKind, &calls, resolver)
+
+	ticker := time.NewTicker(*reportEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			printReport(os.Stdout, *cacheKind, &calls, resolver)
+			return
+		case <-ticker.C:
+			printReport(os.Stdout, *cacheKind, &calls, resolver)
+		}
+	}
+}
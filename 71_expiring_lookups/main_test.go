@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func node(name, zone string) *corev1.Node {
+	n := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if zone != "" {
+		n.Labels = map[string]string{corev1.LabelTopologyZone: zone}
+	}
+	return n
+}
+
+func resolve(t *testing.T, r zoneResolver, name, want string) {
+	t.Helper()
+	got, err := r.zone(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("zone(%s) = %q, want %q", name, got, want)
+	}
+}
+
+func TestTTLZones(t *testing.T) {
+	clientset := fake.NewClientset(node("node-a", "zone-1"), node("node-b", ""))
+	var calls atomic.Int64
+	clock := clocktesting.NewFakeClock(time.Now())
+	// The store made by NewTTLStore with a fake clock; it reports the keys
+	// it drops on expired
+	expired := make(chan string, 10)
+	store := cache.NewFakeExpirationStore(nodeZoneKey, expired, &cache.TTLPolicy{TTL: time.Minute, Clock: clock}, clock)
+	z := newTTLZones(store, apiZoneLookup(clientset, &calls))
+
+	resolve(t, z, "node-a", "zone-1")
+	resolve(t, z, "node-a", "zone-1")
+	resolve(t, z, "node-b", unknownZone)
+	resolve(t, z, "node-gone", unknownZone)
+	if calls.Load() != 3 || z.s.hits.Load() != 1 || z.s.misses.Load() != 3 {
+		t.Fatalf("GETs=%d %s", calls.Load(), z.stats())
+	}
+
+	// The zone of node-a changes while the old one is cached
+	n := node("node-a", "zone-2")
+	if _, err := clientset.CoreV1().Nodes().Update(context.Background(), n, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	resolve(t, z, "node-a", "zone-1")
+
+	// Nothing has expired yet, so nothing is refreshed
+	z.refreshExpired(context.Background())
+	if z.s.refreshes.Load() != 0 {
+		t.Errorf("refreshed %d before the TTL", z.s.refreshes.Load())
+	}
+
+	// Once the TTL has passed, the refresh looks up every node again, and
+	// the handlers find fresh entries
+	clock.Step(time.Minute + time.Second)
+	z.refreshExpired(context.Background())
+	if z.s.refreshes.Load() != 3 || calls.Load() != 6 || len(expired) != 3 {
+		t.Errorf("GETs=%d %s, %d expired, want 3 refreshes", calls.Load(), z.stats(), len(expired))
+	}
+	hits := z.s.hits.Load()
+	resolve(t, z, "node-a", "zone-2")
+	if z.s.hits.Load() != hits+1 {
+		t.Errorf("refreshed entry was not a hit: %s", z.stats())
+	}
+}
+
+func TestExpiringZones(t *testing.T) {
+	clientset := fake.NewClientset(node("node-a", "zone-1"))
+	var calls atomic.Int64
+	clock := clocktesting.NewFakeClock(time.Now())
+	z := newExpiringZones(utilcache.NewExpiringWithClock(clock), apiZoneLookup(clientset, &calls), time.Minute, 10*time.Second)
+
+	resolve(t, z, "node-a", "zone-1")
+	resolve(t, z, "node-gone", unknownZone)
+	resolve(t, z, "node-a", "zone-1")
+	resolve(t, z, "node-gone", unknownZone)
+	if calls.Load() != 2 {
+		t.Fatalf("GETs=%d %s", calls.Load(), z.stats())
+	}
+
+	// The missing node is forgotten after the negative TTL, node-a is not
+	clock.Step(15 * time.Second)
+	resolve(t, z, "node-a", "zone-1")
+	resolve(t, z, "node-gone", unknownZone)
+	if calls.Load() != 3 {
+		t.Errorf("GETs=%d after the negative TTL, want 3", calls.Load())
+	}
+
+	// After the TTL node-a is looked up again on the next read
+	clock.Step(time.Minute)
+	resolve(t, z, "node-a", "zone-1")
+	if calls.Load() != 4 || z.s.misses.Load() != 4 || z.s.hits.Load() != 3 {
+		t.Errorf("GETs=%d %s", calls.Load(), z.stats())
+	}
+}
+
+func TestZoneHandler(t *testing.T) {
+	clientset := fake.NewClientset(node("node-a", "zone-1"))
+	var calls atomic.Int64
+	ctx := context.Background()
+	r, err := newResolver(ctx, "expiring", apiZoneLookup(clientset, &calls), time.Minute, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	h := zoneHandler(ctx, r, &out)
+
+	pending := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	scheduled := pending.DeepCopy()
+	scheduled.Spec.NodeName = "node-a"
+	h.OnAdd(pending, true)
+	h.OnUpdate(pending, scheduled)
+	// A resync delivers the same pod again
+	h.OnUpdate(scheduled, scheduled)
+
+	if got := out.String(); got != "[Scheduled] default/web on node-a (zone zone-1)\n" {
+		t.Errorf("output = %q", got)
+	}
+	if calls.Load() != 1 || r.stats().hits.Load() != 1 {
+		t.Errorf("GETs=%d %s", calls.Load(), r.stats())
+	}
+
+	if _, err := newResolver(ctx, "lru", nil, time.Minute, 0); err == nil || !strings.Contains(err.Error(), "unknown cache") {
+		t.Errorf("newResolver(lru) = %v", err)
+	}
+}
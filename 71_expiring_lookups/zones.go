@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// unknownZone is the zone of a node without the zone label, or of a node
+// that does not exist
+const unknownZone = "<unknown>"
+
+// zoneLookup resolves the zone of a node the expensive way
+type zoneLookup func(ctx context.Context, node string) (string, error)
+
+// apiZoneLookup gets the node from the API server on every call and reads
+// its topology.kubernetes.io/zone label. It counts the GETs in calls.
+func apiZoneLookup(clientset kubernetes.Interface, calls *atomic.Int64) zoneLookup {
+	return func(ctx context.Context, node string) (string, error) {
+		calls.Add(1)
+		n, err := clientset.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		if zone, ok := n.Labels[corev1.LabelTopologyZone]; ok {
+			return zone, nil
+		}
+		return unknownZone, nil
+	}
+}
+
+// zoneResolver is a memoized zoneLookup
+type zoneResolver interface {
+	zone(ctx context.Context, node string) (string, error)
+	stats() *stats
+}
+
+// stats counts how a resolver answered
+type stats struct {
+	hits      atomic.Int64
+	misses    atomic.Int64
+	refreshes atomic.Int64
+	errors    atomic.Int64
+}
+
+func (s *stats) String() string {
+	return fmt.Sprintf("hits=%d misses=%d refreshed=%d errors=%d",
+		s.hits.Load(), s.misses.Load(), s.refreshes.Load(), s.errors.Load())
+}
+
+// nodeZone is what the TTL store holds: it needs a key, so the zone is
+// stored together with its node
+type nodeZone struct {
+	node string
+	zone string
+}
+
+func nodeZoneKey(obj interface{}) (string, error) {
+	nz, ok := obj.(*nodeZone)
+	if !ok {
+		return "", fmt.Errorf("expected *nodeZone, got %T", obj)
+	}
+	return nz.node, nil
+}
+
+// ttlZones memoizes zones in a TTL store from client-go's cache package.
+// Every entry lives for the same TTL. An expired entry is only removed when
+// it is read, so its key stays in ListKeys until then, which is what
+// refreshExpired uses to look it up again ahead of the handlers.
+type ttlZones struct {
+	store  cache.Store
+	lookup zoneLookup
+	s      stats
+}
+
+// newTTLZones wraps a store made by cache.NewTTLStore(nodeZoneKey, ttl)
+func newTTLZones(store cache.Store, lookup zoneLookup) *ttlZones {
+	return &ttlZones{store: store, lookup: lookup}
+}
+
+func (t *ttlZones) stats() *stats { return &t.s }
+
+func (t *ttlZones) zone(ctx context.Context, node string) (string, error) {
+	if obj, exists, _ := t.store.GetByKey(node); exists {
+		t.s.hits.Add(1)
+		return obj.(*nodeZone).zone, nil
+	}
+	t.s.misses.Add(1)
+	return t.resolve(ctx, node)
+}
+
+func (t *ttlZones) resolve(ctx context.Context, node string) (string, error) {
+	zone, err := t.lookup(ctx, node)
+	if apierrors.IsNotFound(err) {
+		zone, err = unknownZone, nil
+	}
+	if err != nil {
+		t.s.errors.Add(1)
+		return "", err
+	}
+	if err := t.store.Add(&nodeZone{node: node, zone: zone}); err != nil {
+		return "", err
+	}
+	return zone, nil
+}
+
+// refreshExpired looks up again every node whose entry has expired. Reading
+// an expired key removes it from the store, so each one is refreshed once.
+func (t *ttlZones) refreshExpired(ctx context.Context) {
+	for _, node := range t.store.ListKeys() {
+		if _, exists, _ := t.store.GetByKey(node); exists {
+			continue
+		}
+		if _, err := t.resolve(ctx, node); err == nil {
+			t.s.refreshes.Add(1)
+		}
+	}
+}
+
+// runRefresh calls refreshExpired every interval until ctx is cancelled
+func (t *ttlZones) runRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.refreshExpired(ctx)
+		}
+	}
+}
+
+// expiringZones memoizes zones in the Expiring cache of apimachinery's
+// util/cache. It takes a TTL per entry, so nodes that do not exist are
+// remembered for a shorter time than real zones, and it removes expired
+// entries by itself. There is no way to list them, so an entry is only
+// looked up again when a handler misses it.
+type expiringZones struct {
+	cache       *utilcache.Expiring
+	lookup      zoneLookup
+	ttl         time.Duration
+	negativeTTL time.Duration
+	s           stats
+}
+
+func newExpiringZones(c *utilcache.Expiring, lookup zoneLookup, ttl, negativeTTL time.Duration) *expiringZones {
+	return &expiringZones{cache: c, lookup: lookup, ttl: ttl, negativeTTL: negativeTTL}
+}
+
+func (e *expiringZones) stats() *stats { return &e.s }
+
+func (e *expiringZones) zone(ctx context.Context, node string) (string, error) {
+	if zone, ok := e.cache.Get(node); ok {
+		e.s.hits.Add(1)
+		return zone.(string), nil
+	}
+	e.s.misses.Add(1)
+	zone, err := e.lookup(ctx, node)
+	switch {
+	case apierrors.IsNotFound(err):
+		e.cache.Set(node, unknownZone, e.negativeTTL)
+		return unknownZone, nil
+	case err != nil:
+		e.s.errors.Add(1)
+		return "", err
+	}
+	e.cache.Set(node, zone, e.ttl)
+	return zone, nil
+}
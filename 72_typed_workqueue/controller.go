@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// failAnnotation makes every reconcile of an object fail, to show the rate
+// limited retries
+const failAnnotation = "k8s-lab/fail"
+
+// objectRef names one object of one kind. A struct of strings is
+// comparable, so the queue deduplicates it like a string key, and a worker
+// reads its fields instead of splitting "namespace/name".
+type objectRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (r objectRef) String() string {
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+// Controller queues every pod and ConfigMap change twice: the object itself
+// on a queue of objectRefs, and its namespace on a queue of strings, whose
+// worker summarizes the namespace
+type Controller struct {
+	factory    informers.SharedInformerFactory
+	pods       listerscorev1.PodLister
+	configMaps listerscorev1.ConfigMapLister
+	objects    workqueue.TypedRateLimitingInterface[objectRef]
+	namespaces workqueue.TypedRateLimitingInterface[string]
+	// maxRetries is how often a failing key is requeued before it is
+	// handed to deadLetters
+	maxRetries  int
+	deadLetters *deadLetters
+	out         io.Writer
+
+	events     atomic.Int64
+	reconciles atomic.Int64
+	summaries  atomic.Int64
+	failures   atomic.Int64
+}
+
+func NewController(clientset kubernetes.Interface, namespace string, maxRetries int, recorder record.EventRecorder, out io.Writer) *Controller {
+	c := &Controller{
+		factory: informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace)),
+		objects: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[objectRef](),
+			workqueue.TypedRateLimitingQueueConfig[objectRef]{Name: "typed-workqueue-objects"},
+		),
+		namespaces: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "typed-workqueue-namespaces"},
+		),
+		maxRetries:  maxRetries,
+		deadLetters: newDeadLetters(recorder, out),
+		out:         out,
+	}
+	c.pods = c.factory.Core().V1().Pods().Lister()
+	c.configMaps = c.factory.Core().V1().ConfigMaps().Lister()
+
+	c.factory.Core().V1().Pods().Informer().AddEventHandler(c.handler("Pod"))
+	c.factory.Core().V1().ConfigMaps().Informer().AddEventHandler(c.handler("ConfigMap"))
+	return c
+}
+
+// handler queues the objectRef and the namespace of every object of kind
+func (c *Controller) handler(kind string) cache.ResourceEventHandler {
+	enqueue := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			utilruntime.HandleError(err)
+			return
+		}
+		c.events.Add(1)
+		c.objects.Add(objectRef{Kind: kind, Namespace: m.GetNamespace(), Name: m.GetName()})
+		c.namespaces.Add(m.GetNamespace())
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, newObj interface{}) { enqueue(newObj) },
+		DeleteFunc: enqueue,
+	}
+}
+
+// Run starts the informers and workers and blocks until ctx is cancelled
+func (c *Controller) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+
+	c.factory.Start(ctx.Done())
+
+	fmt.Fprintln(c.out, "Waiting for cache sync...")
+	c.factory.WaitForCacheSync(ctx.Done())
+	fmt.Fprintln(c.out, "Cache sync completed!")
+
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runObjectWorker, time.Second)
+	}
+	go wait.UntilWithContext(ctx, c.runNamespaceWorker, time.Second)
+	<-ctx.Done()
+
+	fmt.Fprintln(c.out, "Shutting down...")
+	c.objects.ShutDown()
+	c.namespaces.ShutDown()
+	c.factory.Shutdown()
+	c.printSummary()
+}
+
+func (c *Controller) runObjectWorker(ctx context.Context) {
+	for c.processNextObject(ctx) {
+	}
+}
+
+// processNextObject reconciles one objectRef. Get returns an objectRef, not
+// an interface{}: there is no type assertion that could fail.
+func (c *Controller) processNextObject(ctx context.Context) bool {
+	ref, quit := c.objects.Get()
+	if quit {
+		return false
+	}
+	defer c.objects.Done(ref)
+
+	if err := c.reconcile(ctx, ref); err != nil {
+		c.failures.Add(1)
+		// NumRequeues counts the AddRateLimited calls since the last Forget
+		requeues := c.objects.NumRequeues(ref)
+		if requeues >= c.maxRetries {
+			// Forget resets the backoff, so the next change to the object
+			// starts over with a fresh budget
+			c.objects.Forget(ref)
+			obj, _ := c.get(ref)
+			c.deadLetters.send(ref, obj, requeues+1, err)
+			return true
+		}
+		fmt.Fprintf(c.out, "[Retry] %s: %v (requeue #%d of %d)\n", ref, err, requeues+1, c.maxRetries)
+		c.deadLetters.retried(ref.Kind)
+		c.objects.AddRateLimited(ref)
+		return true
+	}
+	c.objects.Forget(ref)
+	return true
+}
+
+// get reads the object ref names from the lister of its Kind
+func (c *Controller) get(ref objectRef) (runtime.Object, error) {
+	switch ref.Kind {
+	case "Pod":
+		pod, err := c.pods.Pods(ref.Namespace).Get(ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		return pod, nil
+	case "ConfigMap":
+		cm, err := c.configMaps.ConfigMaps(ref.Namespace).Get(ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		return cm, nil
+	}
+	return nil, fmt.Errorf("unknown kind %q", ref.Kind)
+}
+
+// reconcile reads the object from the lister its Kind names
+func (c *Controller) reconcile(_ context.Context, ref objectRef) error {
+	c.reconciles.Add(1)
+	obj, err := c.get(ref)
+	if apierrors.IsNotFound(err) {
+		fmt.Fprintf(c.out, "[Reconcile] %s: deleted\n", ref)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var detail string
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		detail = fmt.Sprintf("phase=%s", o.Status.Phase)
+	case *corev1.ConfigMap:
+		detail = fmt.Sprintf("keys=%d", len(o.Data)+len(o.BinaryData))
+	}
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if m.GetAnnotations()[failAnnotation] == "true" {
+		return fmt.Errorf("annotated %s=true", failAnnotation)
+	}
+	fmt.Fprintf(c.out, "[Reconcile] %s: %s\n", ref, detail)
+	return nil
+}
+
+func (c *Controller) runNamespaceWorker(_ context.Context) {
+	for c.processNextNamespace() {
+	}
+}
+
+// processNextNamespace summarizes one namespace. A burst of changes in a
+// namespace queues it many times, but while it waits it is in the queue
+// once, so it is summarized once.
+func (c *Controller) processNextNamespace() bool {
+	namespace, quit := c.namespaces.Get()
+	if quit {
+		return false
+	}
+	defer c.namespaces.Done(namespace)
+
+	c.summaries.Add(1)
+	pods, err := c.pods.Pods(namespace).List(labels.Everything())
+	if err != nil {
+		c.namespaces.AddRateLimited(namespace)
+		return true
+	}
+	configMaps, err := c.configMaps.ConfigMaps(namespace).List(labels.Everything())
+	if err != nil {
+		c.namespaces.AddRateLimited(namespace)
+		return true
+	}
+	c.namespaces.Forget(namespace)
+	fmt.Fprintf(c.out, "[Namespace] %s: %d pods, %d configmaps\n", namespace, len(pods), len(configMaps))
+	return true
+}
+
+// printSummary shows how many events the queues collapsed
+func (c *Controller) printSummary() {
+	fmt.Fprintf(c.out, "\n=== Summary ===\nEvents:              %d\nObject reconciles:   %d (%d failed)\nDead letters:        %d\nNamespace summaries: %d\n",
+		c.events.Load(), c.reconciles.Load(), c.failures.Load(), c.deadLetters.sent(), c.summaries.Load())
+}
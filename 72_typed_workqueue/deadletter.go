@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// deadLetters is where a key goes once it has used up its retries. Every
+// key is logged, recorded as a Warning event on its object, and counted per
+// kind for /metrics.
+type deadLetters struct {
+	recorder record.EventRecorder
+	out      io.Writer
+
+	mu      sync.Mutex
+	total   map[string]int64
+	retries map[string]int64
+}
+
+func newDeadLetters(recorder record.EventRecorder, out io.Writer) *deadLetters {
+	return &deadLetters{recorder: recorder, out: out, total: map[string]int64{}, retries: map[string]int64{}}
+}
+
+// retried counts one requeue of a failed key of kind
+func (d *deadLetters) retried(kind string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.retries[kind]++
+}
+
+// send gives up on ref after attempts failed reconciles. obj is the object
+// it failed on, or nil if it could not be read.
+func (d *deadLetters) send(ref objectRef, obj runtime.Object, attempts int, err error) {
+	d.mu.Lock()
+	d.total[ref.Kind]++
+	d.mu.Unlock()
+
+	fmt.Fprintf(d.out, "[DeadLetter] %s: giving up after %d attempts: %v\n", ref, attempts, err)
+	if obj != nil {
+		d.recorder.Eventf(obj, corev1.EventTypeWarning, "ReconcileAbandoned",
+			"Gave up after %d failed reconciles: %v; the next change to this object retries it", attempts, err)
+	}
+}
+
+// sent is the number of keys given up on, of all kinds
+func (d *deadLetters) sent() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var n int64
+	for _, count := range d.total {
+		n += count
+	}
+	return n
+}
+
+// ServeHTTP writes the counters in the Prometheus text format
+func (d *deadLetters) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeCounter(w, "typed_workqueue_retries_total", "Failed reconciles that were requeued, per kind.", d.retries)
+	writeCounter(w, "typed_workqueue_dead_letters_total", "Keys given up on after their last retry, per kind.", d.total)
+}
+
+func writeCounter(w io.Writer, name, help string, values map[string]int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	kinds := make([]string, 0, len(values))
+	for kind := range values {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "%s{kind=%q} %d\n", name, kind, values[kind])
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func TestQueuesDeduplicateKeys(t *testing.T) {
+	c := NewController(fake.NewClientset(), "", 5, record.NewFakeRecorder(10), &bytes.Buffer{})
+	h := c.handler("Pod")
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}}
+	h.OnAdd(pod, false)
+	h.OnUpdate(pod, pod)
+	h.OnAdd(other, false)
+	h.OnDelete(cache.DeletedFinalStateUnknown{Key: "default/web", Obj: pod})
+
+	// Equal structs are one key
+	if got := c.objects.Len(); got != 2 {
+		t.Errorf("objects queue holds %d refs, want 2", got)
+	}
+	if got := c.namespaces.Len(); got != 1 {
+		t.Errorf("namespaces queue holds %d, want 1", got)
+	}
+	ref, _ := c.objects.Get()
+	if ref != (objectRef{Kind: "Pod", Namespace: "default", Name: "web"}) {
+		t.Errorf("first ref = %v", ref)
+	}
+	if ns, _ := c.namespaces.Get(); ns != "default" {
+		t.Errorf("namespace = %q", ns)
+	}
+}
+
+func TestControllerReconcilesAndRetries(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"}, Data: map[string]string{"a": "1", "b": "2"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: "broken", Namespace: "default", Annotations: map[string]string{failAnnotation: "true"},
+		}},
+	)
+	out := &testutil.SyncBuffer{}
+	c := NewController(clientset, "default", 5, record.NewFakeRecorder(10), out)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx, 2)
+		close(done)
+	}()
+
+	err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		s := out.String()
+		return strings.Contains(s, "[Reconcile] Pod default/web: phase=Running\n") &&
+			strings.Contains(s, "[Reconcile] ConfigMap default/settings: keys=2\n") &&
+			strings.Contains(s, "[Namespace] default: 1 pods, 2 configmaps\n") &&
+			strings.Contains(s, "[Retry] ConfigMap default/broken: annotated k8s-lab/fail=true (requeue #3 of 5)\n"), nil
+	})
+	if err != nil {
+		t.Fatalf("got:\n%s", out.String())
+	}
+
+	if err := clientset.CoreV1().Pods("default").Delete(ctx, "web", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err = wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		return strings.Contains(out.String(), "[Reconcile] Pod default/web: deleted\n"), nil
+	})
+	if err != nil {
+		t.Fatalf("got:\n%s", out.String())
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the controller did not stop")
+	}
+	if !strings.Contains(out.String(), "=== Summary ===\nEvents:              4\n") {
+		t.Errorf("got:\n%s", out.String())
+	}
+}
+
+func TestFailingKeyIsDeadLettered(t *testing.T) {
+	broken := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name: "broken", Namespace: "default", Annotations: map[string]string{failAnnotation: "true"},
+	}}
+	clientset := fake.NewClientset(broken)
+	recorder := record.NewFakeRecorder(10)
+	out := &testutil.SyncBuffer{}
+	c := NewController(clientset, "default", 2, recorder, out)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 1)
+
+	waitFor := func(line string) {
+		t.Helper()
+		err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+			return strings.Contains(out.String(), line), nil
+		})
+		if err != nil {
+			t.Fatalf("waiting for %q:\n%s", line, out.String())
+		}
+	}
+	waitFor("[Retry] ConfigMap default/broken: annotated k8s-lab/fail=true (requeue #2 of 2)\n")
+	waitFor("[DeadLetter] ConfigMap default/broken: giving up after 3 attempts: annotated k8s-lab/fail=true\n")
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning ReconcileAbandoned Gave up after 3 failed reconciles") {
+			t.Errorf("event = %q", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event recorded")
+	}
+	// Given up on: no further retries
+	time.Sleep(100 * time.Millisecond)
+	if n := strings.Count(out.String(), "[Retry]"); n != 2 {
+		t.Errorf("%d retries, want 2:\n%s", n, out.String())
+	}
+
+	rec := httptest.NewRecorder()
+	c.deadLetters.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, line := range []string{
+		"typed_workqueue_retries_total{kind=\"ConfigMap\"} 2\n",
+		"typed_workqueue_dead_letters_total{kind=\"ConfigMap\"} 1\n",
+	} {
+		if !strings.Contains(rec.Body.String(), line) {
+			t.Errorf("metrics lack %q:\n%s", line, rec.Body.String())
+		}
+	}
+
+	// The next change to the object starts over with a fresh budget
+	fixed := broken.DeepCopy()
+	fixed.Annotations = nil
+	if _, err := clientset.CoreV1().ConfigMaps("default").Update(ctx, fixed, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("[Reconcile] ConfigMap default/broken: keys=0\n")
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+)
+
+// maxAgeAnnotation holds how long a pod may live, as a Go duration
+const maxAgeAnnotation = "k8s-lab/max-age"
+
+// Controller reports, and with delete set deletes, pods that outlive the
+// max-age annotation. It does not poll: when a pod is not due yet, its key
+// goes back on a delaying queue with AddAfter, for exactly the time left.
+type Controller struct {
+	clientset kubernetes.Interface
+	factory   informers.SharedInformerFactory
+	pods      listerscorev1.PodLister
+	// queue is a plain delaying queue. Scheduled re-checks and retries
+	// after a failure both wait in it, but their delays come from different
+	// places: the pod's deadline, and backoff.
+	queue   workqueue.TypedDelayingInterface[string]
+	backoff workqueue.TypedRateLimiter[string]
+	clock   clock.WithTicker
+	delete  bool
+	out     io.Writer
+}
+
+func NewController(clientset kubernetes.Interface, namespace string, deleteExpired bool, clk clock.WithTicker, out io.Writer) *Controller {
+	c := &Controller{
+		clientset: clientset,
+		factory:   informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace)),
+		queue: workqueue.NewTypedDelayingQueueWithConfig(workqueue.TypedDelayingQueueConfig[string]{
+			Name:  "scheduled-rechecks",
+			Clock: clk,
+		}),
+		backoff: workqueue.NewTypedItemExponentialFailureRateLimiter[string](time.Second, 5*time.Minute),
+		clock:   clk,
+		delete:  deleteExpired,
+		out:     out,
+	}
+	c.pods = c.factory.Core().V1().Pods().Lister()
+
+	c.factory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if _, ok := obj.(*corev1.Pod).Annotations[maxAgeAnnotation]; ok {
+				c.enqueue(obj)
+			}
+		},
+		// Status updates do not move the deadline; only a changed
+		// annotation does
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if oldObj.(*corev1.Pod).Annotations[maxAgeAnnotation] != newObj.(*corev1.Pod).Annotations[maxAgeAnnotation] {
+				c.enqueue(newObj)
+			}
+		},
+	})
+	return c
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// Run starts the informers and workers and blocks until ctx is cancelled
+func (c *Controller) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+
+	c.factory.Start(ctx.Done())
+
+	fmt.Fprintln(c.out, "Waiting for cache sync...")
+	c.factory.WaitForCacheSync(ctx.Done())
+	fmt.Fprintln(c.out, "Cache sync completed!")
+
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+	<-ctx.Done()
+
+	fmt.Fprintln(c.out, "Shutting down...")
+	c.queue.ShutDown()
+	c.factory.Shutdown()
+}
+
+func (c *Controller) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	recheckIn, err := c.reconcile(ctx, key)
+	if err != nil {
+		// Failure backoff: the delay grows with every failure of this key
+		delay := c.backoff.When(key)
+		fmt.Fprintf(c.out, "[Backoff] %s: %v, retrying in %s\n", key, err, delay)
+		c.queue.AddAfter(key, delay)
+		return true
+	}
+	c.backoff.Forget(key)
+	if recheckIn > 0 {
+		// Scheduled re-check: the delay is when the pod becomes due. If the
+		// key is already waiting, the earlier of the two times wins; the
+		// re-check then works out the deadline again.
+		fmt.Fprintf(c.out, "[Scheduled] %s: re-check in %s\n", key, recheckIn.Round(time.Second))
+		c.queue.AddAfter(key, recheckIn)
+	}
+	return true
+}
+
+// reconcile acts on a pod past its max age. For a pod that is not, it
+// returns how long until it is.
+func (c *Controller) reconcile(ctx context.Context, key string) (time.Duration, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return 0, nil
+	}
+	pod, err := c.pods.Pods(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		// Deleted before its deadline; the scheduled re-check ends here
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	value, ok := pod.Annotations[maxAgeAnnotation]
+	if !ok || pod.DeletionTimestamp != nil {
+		return 0, nil
+	}
+	maxAge, err := time.ParseDuration(value)
+	if err != nil || maxAge <= 0 {
+		// Retrying does not fix a bad annotation; the next edit queues it
+		fmt.Fprintf(c.out, "[Invalid] %s: %s=%q is not a positive duration\n", key, maxAgeAnnotation, value)
+		return 0, nil
+	}
+
+	age := c.clock.Since(pod.CreationTimestamp.Time)
+	if age < maxAge {
+		return maxAge - age, nil
+	}
+	fmt.Fprintf(c.out, "[Expired] %s: age %s exceeds %s=%s\n", key, age.Round(time.Second), maxAgeAnnotation, value)
+	if !c.delete {
+		return 0, nil
+	}
+	err = c.clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &pod.UID},
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return 0, err
+	}
+	fmt.Fprintf(c.out, "[Deleted] %s\n", key)
+	return 0, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func pod(name string, created time.Time, maxAge string) *corev1.Pod {
+	p := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: name, Namespace: "default", UID: types.UID("uid-" + name),
+		CreationTimestamp: metav1.NewTime(created),
+	}}
+	if maxAge != "" {
+		p.Annotations = map[string]string{maxAgeAnnotation: maxAge}
+	}
+	return p
+}
+
+// run starts the controller and returns a function that waits for a line
+// of output
+func run(t *testing.T, c *Controller, out *testutil.SyncBuffer) func(line string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx, 1)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return func(line string) {
+		t.Helper()
+		err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+			return strings.Contains(out.String(), line), nil
+		})
+		if err != nil {
+			t.Fatalf("waiting for %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestPodIsRecheckedWhenDue(t *testing.T) {
+	clk := clocktesting.NewFakeClock(time.Now())
+	clientset := fake.NewClientset(
+		pod("batch", clk.Now().Add(-4*time.Minute), "10m"),
+		pod("old", clk.Now().Add(-time.Hour), "30m"),
+		pod("web", clk.Now(), ""),
+		pod("typo", clk.Now(), "ten minutes"),
+	)
+	out := &testutil.SyncBuffer{}
+	waitFor := run(t, NewController(clientset, "default", false, clk, out), out)
+
+	waitFor("[Scheduled] default/batch: re-check in 6m0s\n")
+	waitFor("[Expired] default/old: age 1h0m0s exceeds k8s-lab/max-age=30m\n")
+	waitFor(`[Invalid] default/typo: k8s-lab/max-age="ten minutes" is not a positive duration`)
+
+	// Nothing happens until the deadline
+	clk.Step(5 * time.Minute)
+	time.Sleep(50 * time.Millisecond)
+	if strings.Contains(out.String(), "default/batch: age") {
+		t.Fatalf("re-checked too early:\n%s", out.String())
+	}
+	clk.Step(time.Minute)
+	waitFor("[Expired] default/batch: age 10m0s exceeds k8s-lab/max-age=10m\n")
+	if strings.Contains(out.String(), "default/web") || strings.Contains(out.String(), "[Deleted]") {
+		t.Errorf("got:\n%s", out.String())
+	}
+}
+
+func TestFailedDeleteBacksOff(t *testing.T) {
+	clk := clocktesting.NewFakeClock(time.Now())
+	clientset := fake.NewClientset(pod("old", clk.Now().Add(-time.Hour), "30m"))
+	failures := 2
+	clientset.PrependReactor("delete", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			failures--
+			return true, nil, errors.New("etcd unavailable")
+		}
+		return false, nil, nil
+	})
+	out := &testutil.SyncBuffer{}
+	waitFor := run(t, NewController(clientset, "default", true, clk, out), out)
+
+	waitFor("[Backoff] default/old: etcd unavailable, retrying in 1s\n")
+	clk.Step(time.Second)
+	waitFor("[Backoff] default/old: etcd unavailable, retrying in 2s\n")
+	clk.Step(2 * time.Second)
+	waitFor("[Deleted] default/old\n")
+
+	if _, err := clientset.CoreV1().Pods("default").Get(context.Background(), "old", metav1.GetOptions{}); err == nil {
+		t.Error("pod old still exists")
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+	"github.com/shamimice03/mastering-k8s-client-go/tombstone"
+)
+
+const (
+	// podSetLabel marks a ConfigMap as a pod set: its data holds
+	// "replicas", and optionally "image"
+	podSetLabel = "k8s-lab/pod-set"
+	// ownerLabel is set on every pod of a set to the set's name
+	ownerLabel   = "k8s-lab/pod-set-owner"
+	defaultImage = "busybox:1.36"
+)
+
+var fieldManager = clientid.FieldManager("74-controller-expectations")
+
+// Controller keeps "replicas" pods running for every pod set, the way the
+// ReplicaSet controller does for ReplicaSets. With expectations it skips a
+// set whose own creates and deletes the pod cache has not caught up with.
+type Controller struct {
+	clientset    kubernetes.Interface
+	factory      informers.SharedInformerFactory
+	sets         listerscorev1.ConfigMapLister
+	podInformer  cache.SharedIndexInformer
+	pods         listerscorev1.PodLister
+	queue        workqueue.TypedRateLimitingInterface[string]
+	expectations *expectations // nil when disabled
+	out          io.Writer
+}
+
+// NewController watches the pod sets of namespace. podLW feeds the pod
+// informer, so it can be made to lag.
+func NewController(clientset kubernetes.Interface, namespace string, podLW cache.ListerWatcher, useExpectations bool, clk clock.Clock, out io.Writer) *Controller {
+	c := &Controller{
+		clientset: clientset,
+		factory: informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+			informers.WithNamespace(namespace),
+			informers.WithTweakListOptions(func(o *metav1.ListOptions) { o.LabelSelector = podSetLabel })),
+		podInformer: cache.NewSharedIndexInformer(podLW, &corev1.Pod{}, 0, cache.Indexers{}),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "pod-sets"},
+		),
+		out: out,
+	}
+	if useExpectations {
+		c.expectations = newExpectations(clk)
+	}
+	c.sets = c.factory.Core().V1().ConfigMaps().Lister()
+	c.pods = listerscorev1.NewPodLister(c.podInformer.GetIndexer())
+
+	c.factory.Core().V1().ConfigMaps().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueSet,
+		UpdateFunc: func(_, newObj interface{}) { c.enqueueSet(newObj) },
+		DeleteFunc: func(obj interface{}) {
+			if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil && c.expectations != nil {
+				c.expectations.forget(key)
+			}
+		},
+	})
+	c.podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if key, ok := ownerKey(obj); ok {
+				if c.expectations != nil {
+					c.expectations.creationObserved(key)
+				}
+				c.queue.Add(key)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if key, ok := ownerKey(newObj); ok {
+				c.queue.Add(key)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if key, ok := ownerKey(obj); ok {
+				if c.expectations != nil {
+					c.expectations.deletionObserved(key)
+				}
+				c.queue.Add(key)
+			}
+		},
+	})
+	return c
+}
+
+func (c *Controller) enqueueSet(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// ownerKey is the key of the pod set that controls a pod
+func ownerKey(obj interface{}) (string, bool) {
+	pod, ok := tombstone.Unwrap[*corev1.Pod](obj)
+	if !ok {
+		return "", false
+	}
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil || ref.Kind != "ConfigMap" {
+		return "", false
+	}
+	return pod.Namespace + "/" + ref.Name, true
+}
+
+// Run starts the informers and workers and blocks until ctx is cancelled
+func (c *Controller) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+
+	c.factory.Start(ctx.Done())
+	go c.podInformer.RunWithContext(ctx)
+
+	fmt.Fprintln(c.out, "Waiting for cache sync...")
+	c.factory.WaitForCacheSync(ctx.Done())
+	cache.WaitForCacheSync(ctx.Done(), c.podInformer.HasSynced)
+	fmt.Fprintln(c.out, "Cache sync completed!")
+
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+	<-ctx.Done()
+
+	fmt.Fprintln(c.out, "Shutting down...")
+	c.queue.ShutDown()
+	c.factory.Shutdown()
+}
+
+func (c *Controller) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.sync(ctx, key); err != nil {
+		fmt.Fprintf(c.out, "[Error] %s: %v, requeuing\n", key, err)
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+// sync creates or deletes pods until the set has as many as it asks for
+func (c *Controller) sync(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	set, err := c.sets.ConfigMaps(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		// The garbage collector deletes its pods through their ownerReferences
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	replicas, err := strconv.Atoi(set.Data["replicas"])
+	if err != nil || replicas < 0 {
+		fmt.Fprintf(c.out, "[Invalid] %s: replicas=%q is not a count\n", key, set.Data["replicas"])
+		return nil
+	}
+
+	// The pods the cache knows; after our own writes it may be behind
+	if c.expectations != nil && !c.expectations.satisfied(key) {
+		fmt.Fprintf(c.out, "[Skip] %s: waiting to observe %s\n", key, c.expectations.pending(key))
+		return nil
+	}
+	all, err := c.pods.Pods(namespace).List(labels.SelectorFromSet(labels.Set{ownerLabel: name}))
+	if err != nil {
+		return err
+	}
+	var active []*corev1.Pod
+	for _, pod := range all {
+		if pod.DeletionTimestamp == nil && metav1.IsControlledBy(pod, set) {
+			active = append(active, pod)
+		}
+	}
+
+	diff := replicas - len(active)
+	switch {
+	case diff > 0:
+		fmt.Fprintf(c.out, "[Sync] %s: %d of %d pods, creating %d\n", key, len(active), replicas, diff)
+		if c.expectations != nil {
+			c.expectations.expect(key, diff, 0)
+		}
+		for i := 0; i < diff; i++ {
+			if _, err := c.clientset.CoreV1().Pods(namespace).Create(ctx, newPod(set), metav1.CreateOptions{FieldManager: fieldManager}); err != nil {
+				// Neither this pod nor the ones not created after it will be
+				// observed; do not wait for them
+				if c.expectations != nil {
+					c.expectations.lower(key, diff-i, 0)
+				}
+				return err
+			}
+		}
+	case diff < 0:
+		fmt.Fprintf(c.out, "[Sync] %s: %d of %d pods, deleting %d\n", key, len(active), replicas, -diff)
+		if c.expectations != nil {
+			c.expectations.expect(key, 0, -diff)
+		}
+		for i, pod := range active[:-diff] {
+			err := c.clientset.CoreV1().Pods(namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				if c.expectations != nil {
+					c.expectations.lower(key, 0, -diff-i)
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newPod returns a pod for set, controlled by it
+func newPod(set *corev1.ConfigMap) *corev1.Pod {
+	image := set.Data["image"]
+	if image == "" {
+		image = defaultImage
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName:    set.Name + "-",
+			Namespace:       set.Namespace,
+			Labels:          map[string]string{ownerLabel: set.Name},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(set, corev1.SchemeGroupVersion.WithKind("ConfigMap"))},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:    "main",
+				Image:   image,
+				Command: []string{"sleep", "infinity"},
+			}},
+		},
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// expectationsTimeout is how long expectations are trusted. If the events
+// never come, for example because a watch dropped them, the controller
+// syncs anyway after this long, as the ReplicaSet controller does.
+const expectationsTimeout = 5 * time.Minute
+
+// expectation counts the creates and deletes a controller issued for one
+// parent that its informer has not shown it yet
+type expectation struct {
+	adds      int
+	deletes   int
+	timestamp time.Time
+}
+
+func (e *expectation) fulfilled() bool {
+	return e.adds <= 0 && e.deletes <= 0
+}
+
+// expectations is a small version of ControllerExpectations from
+// k8s.io/kubernetes/pkg/controller. Before a sync creates or deletes
+// children it records how many; the pod event handlers count them down as
+// the informer observes them. Until they reach zero, the cache is known to
+// be behind the controller's own writes, and a sync would act on stale data.
+type expectations struct {
+	clock clock.Clock
+
+	mu sync.Mutex
+	m  map[string]*expectation
+}
+
+func newExpectations(clk clock.Clock) *expectations {
+	return &expectations{clock: clk, m: map[string]*expectation{}}
+}
+
+// expect records adds creates and deletes deletes about to be issued for key
+func (e *expectations) expect(key string, adds, deletes int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.m[key] = &expectation{adds: adds, deletes: deletes, timestamp: e.clock.Now()}
+}
+
+// creationObserved counts down one create for key. A create that failed is
+// also counted down, since its event will never come.
+func (e *expectations) creationObserved(key string) {
+	e.lower(key, 1, 0)
+}
+
+// deletionObserved counts down one delete for key
+func (e *expectations) deletionObserved(key string) {
+	e.lower(key, 0, 1)
+}
+
+func (e *expectations) lower(key string, adds, deletes int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if exp, ok := e.m[key]; ok {
+		exp.adds -= adds
+		exp.deletes -= deletes
+	}
+}
+
+// satisfied reports whether key can be synced: nothing was expected, all
+// of it was observed, or the expectations timed out
+func (e *expectations) satisfied(key string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	exp, ok := e.m[key]
+	if !ok || exp.fulfilled() {
+		return true
+	}
+	return e.clock.Since(exp.timestamp) > expectationsTimeout
+}
+
+// forget drops the expectations of a parent that was deleted
+func (e *expectations) forget(key string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.m, key)
+}
+
+// pending describes what key still waits for, e.g. "2 creates, 0 deletes"
+func (e *expectations) pending(key string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	exp, ok := e.m[key]
+	if !ok {
+		return "nothing"
+	}
+	return fmt.Sprintf("%d creates, %d deletes", max(exp.adds, 0), max(exp.deletes, 0))
+}
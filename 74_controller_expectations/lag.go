@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// laggingPodListWatch lists and watches the pods of pod sets in namespace,
+// all namespaces if empty, and hands on every watch event delay after it
+// arrived, in order. It stretches the gap between a write and the informer
+// seeing it, normally a few milliseconds, until it can be watched.
+func laggingPodListWatch(clientset kubernetes.Interface, namespace string, delay time.Duration) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = ownerLabel
+			return clientset.CoreV1().Pods(namespace).List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = ownerLabel
+			w, err := clientset.CoreV1().Pods(namespace).Watch(ctx, options)
+			if err != nil || delay <= 0 {
+				return w, err
+			}
+			return delayWatch(w, delay), nil
+		},
+	}
+}
+
+// delayWatch forwards the events of w, each one delay after it arrived
+func delayWatch(w watch.Interface, delay time.Duration) watch.Interface {
+	type pending struct {
+		event watch.Event
+		due   time.Time
+	}
+	// The buffer lets events keep arriving while earlier ones wait
+	queued := make(chan pending, 1000)
+	out := make(chan watch.Event)
+	proxy := watch.NewProxyWatcher(out)
+	go func() {
+		defer close(queued)
+		for {
+			select {
+			case <-proxy.StopChan():
+				w.Stop()
+				return
+			case event, ok := <-w.ResultChan():
+				if !ok {
+					return
+				}
+				select {
+				case queued <- pending{event: event, due: time.Now().Add(delay)}:
+				case <-proxy.StopChan():
+					w.Stop()
+					return
+				}
+			}
+		}
+	}()
+	go func() {
+		defer close(out)
+		for p := range queued {
+			select {
+			case <-proxy.StopChan():
+				return
+			case <-time.After(time.Until(p.due)):
+			}
+			select {
+			case <-proxy.StopChan():
+				return
+			case out <- p.event:
+			}
+		}
+	}()
+	return proxy
+}
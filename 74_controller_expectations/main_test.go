@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func podSet(replicas string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "sleepers", Namespace: "default", UID: "set-uid", Labels: map[string]string{podSetLabel: "true"}},
+		Data:       map[string]string{"replicas": replicas},
+	}
+}
+
+// newClientset returns a fake clientset that names pods from GenerateName,
+// as the API server does, and counts the creates
+func newClientset(objects ...runtime.Object) (*fake.Clientset, *atomic.Int64) {
+	clientset := fake.NewClientset(objects...)
+	var creates atomic.Int64
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		if pod.Name == "" {
+			pod.Name = fmt.Sprintf("%s%d", pod.GenerateName, creates.Add(1))
+		}
+		return false, nil, nil
+	})
+	return clientset, &creates
+}
+
+func TestExpectations(t *testing.T) {
+	clk := clocktesting.NewFakeClock(time.Now())
+	e := newExpectations(clk)
+	if !e.satisfied("default/a") {
+		t.Error("nothing expected, but not satisfied")
+	}
+	e.expect("default/a", 2, 1)
+	e.creationObserved("default/a")
+	e.deletionObserved("default/a")
+	if e.satisfied("default/a") || e.pending("default/a") != "1 creates, 0 deletes" {
+		t.Errorf("pending %s", e.pending("default/a"))
+	}
+	e.creationObserved("default/a")
+	if !e.satisfied("default/a") {
+		t.Error("all observed, but not satisfied")
+	}
+
+	// Events that never come stop blocking after the timeout
+	e.expect("default/b", 1, 0)
+	clk.Step(expectationsTimeout + time.Second)
+	if !e.satisfied("default/b") {
+		t.Error("expired expectations still block")
+	}
+}
+
+// TestLaggingCache syncs twice before the pod cache has seen any of the
+// pods the first sync created
+func TestLaggingCache(t *testing.T) {
+	for _, tc := range []struct {
+		useExpectations bool
+		wantCreates     int64
+	}{
+		{useExpectations: true, wantCreates: 3},
+		{useExpectations: false, wantCreates: 6},
+	} {
+		t.Run(fmt.Sprintf("expectations=%v", tc.useExpectations), func(t *testing.T) {
+			set := podSet("3")
+			clientset, creates := newClientset(set)
+			var out bytes.Buffer
+			c := NewController(clientset, "default", laggingPodListWatch(clientset, "default", 0), tc.useExpectations, clocktesting.NewFakeClock(time.Now()), &out)
+			if err := c.factory.Core().V1().ConfigMaps().Informer().GetIndexer().Add(set); err != nil {
+				t.Fatal(err)
+			}
+
+			for range 2 {
+				if err := c.sync(context.Background(), "default/sleepers"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if creates.Load() != tc.wantCreates {
+				t.Errorf("%d creates, want %d:\n%s", creates.Load(), tc.wantCreates, out.String())
+			}
+			if tc.useExpectations && !strings.Contains(out.String(), "[Skip] default/sleepers: waiting to observe 3 creates, 0 deletes\n") {
+				t.Errorf("got:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestControllerWithLaggingWatch(t *testing.T) {
+	clientset, creates := newClientset(podSet("3"))
+	out := &testutil.SyncBuffer{}
+	c := NewController(clientset, "default", laggingPodListWatch(clientset, "default", 200*time.Millisecond), true, clocktesting.NewFakeClock(time.Now()), out)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 2)
+
+	err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		pods, _ := c.pods.Pods("default").List(labels.Everything())
+		return len(pods) == 3 && c.expectations.satisfied("default/sleepers"), nil
+	})
+	if err != nil {
+		t.Fatalf("pods did not show up:\n%s", out.String())
+	}
+
+	// Scale down: the pod events lag again, and no second delete goes out
+	set := podSet("1")
+	if _, err := clientset.CoreV1().ConfigMaps("default").Update(ctx, set, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		pods, _ := c.pods.Pods("default").List(labels.Everything())
+		return len(pods) == 1, nil
+	})
+	if err != nil {
+		t.Fatalf("pods were not deleted:\n%s", out.String())
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	deletes := 0
+	for _, a := range clientset.Actions() {
+		if a.GetVerb() == "delete" && a.GetResource().Resource == "pods" {
+			deletes++
+		}
+	}
+	if creates.Load() != 3 || deletes != 2 {
+		t.Errorf("%d creates and %d deletes, want 3 and 2:\n%s", creates.Load(), deletes, out.String())
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listersappsv1 "k8s.io/client-go/listers/apps/v1"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Controller reconciles Deployments, and is woken by their pods and
+// ReplicaSets too: every event is mapped to the key of the Deployment at
+// the top of the ownerReference chain before it is queued
+type Controller struct {
+	factory     informers.SharedInformerFactory
+	owners      *ownerResolver
+	deployments listersappsv1.DeploymentLister
+	replicaSets listersappsv1.ReplicaSetLister
+	pods        listerscorev1.PodLister
+	queue       workqueue.TypedRateLimitingInterface[string]
+	trace       bool
+	out         io.Writer
+
+	mu   sync.Mutex
+	last map[string]string // the last summary printed per Deployment
+}
+
+func NewController(clientset kubernetes.Interface, namespace string, trace bool, out io.Writer) *Controller {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	c := &Controller{
+		factory:     factory,
+		deployments: factory.Apps().V1().Deployments().Lister(),
+		replicaSets: factory.Apps().V1().ReplicaSets().Lister(),
+		pods:        factory.Core().V1().Pods().Lister(),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "pod-owner-mapping"},
+		),
+		trace: trace,
+		out:   out,
+		last:  map[string]string{},
+	}
+	c.owners = &ownerResolver{replicaSets: c.replicaSets, deployments: c.deployments}
+
+	factory.Apps().V1().Deployments().Informer().AddEventHandler(handlerFor(c.enqueueDeployment))
+	factory.Apps().V1().ReplicaSets().Informer().AddEventHandler(handlerFor(c.enqueueReplicaSet))
+	factory.Core().V1().Pods().Informer().AddEventHandler(handlerFor(c.enqueuePod))
+	return c
+}
+
+// handlerFor calls enqueue with the object of every add, update and delete,
+// tombstones unwrapped
+func handlerFor(enqueue func(obj interface{})) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, newObj interface{}) { enqueue(newObj) },
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			enqueue(obj)
+		},
+	}
+}
+
+func (c *Controller) enqueueDeployment(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *Controller) enqueueReplicaSet(obj interface{}) {
+	rs, ok := obj.(*appsv1.ReplicaSet)
+	if !ok {
+		return
+	}
+	key, err := c.owners.deploymentKeyForReplicaSet(rs)
+	c.enqueueMapped(fmt.Sprintf("ReplicaSet %s/%s", rs.Namespace, rs.Name), key, err)
+}
+
+func (c *Controller) enqueuePod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	key, err := c.owners.deploymentKeyForPod(pod)
+	c.enqueueMapped(fmt.Sprintf("Pod %s/%s", pod.Namespace, pod.Name), key, err)
+}
+
+// enqueueMapped queues the Deployment an event was mapped to. An owner
+// missing from the cache is not an error: the informers are not synced
+// with each other, so a pod can arrive before its ReplicaSet. The
+// ReplicaSet's own event queues the Deployment once it arrives.
+func (c *Controller) enqueueMapped(from, key string, err error) {
+	switch {
+	case apierrors.IsNotFound(err):
+		if c.trace {
+			fmt.Fprintf(c.out, "[Map] %s: owner not in the cache yet\n", from)
+		}
+	case err != nil:
+		utilruntime.HandleError(fmt.Errorf("mapping %s: %w", from, err))
+	case key == "":
+		// Not run by a Deployment
+	default:
+		if c.trace {
+			fmt.Fprintf(c.out, "[Map] %s -> Deployment %s\n", from, key)
+		}
+		c.queue.Add(key)
+	}
+}
+
+// Run starts the informers and workers and blocks until ctx is cancelled
+func (c *Controller) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+
+	c.factory.Start(ctx.Done())
+
+	fmt.Fprintln(c.out, "Waiting for cache sync...")
+	c.factory.WaitForCacheSync(ctx.Done())
+	fmt.Fprintln(c.out, "Cache sync completed!")
+
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+	<-ctx.Done()
+
+	fmt.Fprintln(c.out, "Shutting down...")
+	c.queue.ShutDown()
+	c.factory.Shutdown()
+}
+
+func (c *Controller) runWorker(_ context.Context) {
+	for c.processNextItem() {
+	}
+}
+
+func (c *Controller) processNextItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.sync(key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("syncing %s: %w", key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+// sync summarizes a Deployment from the pods of the ReplicaSets it controls,
+// and prints the summary when it changed
+func (c *Controller) sync(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	d, err := c.deployments.Deployments(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		c.print(key, "deleted")
+		c.mu.Lock()
+		delete(c.last, key)
+		c.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	summary, err := c.summarize(d)
+	if err != nil {
+		return err
+	}
+	c.print(key, summary)
+	return nil
+}
+
+// summarize walks the ownerReferences down: the ReplicaSets d controls, and
+// the pods they control
+func (c *Controller) summarize(d *appsv1.Deployment) (string, error) {
+	replicaSets, err := c.replicaSets.ReplicaSets(d.Namespace).List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	owned := map[types.UID]bool{}
+	for _, rs := range replicaSets {
+		if metav1.IsControlledBy(rs, d) {
+			owned[rs.UID] = true
+		}
+	}
+	pods, err := c.pods.Pods(d.Namespace).List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	var count, ready int
+	var restarts int32
+	for _, pod := range pods {
+		ref := metav1.GetControllerOf(pod)
+		if ref == nil || !owned[ref.UID] {
+			continue
+		}
+		count++
+		if podReady(pod) {
+			ready++
+		}
+		for _, s := range pod.Status.ContainerStatuses {
+			restarts += s.RestartCount
+		}
+	}
+	return fmt.Sprintf("%d replicasets, %d pods, %d ready, %d restarts", len(owned), count, ready, restarts), nil
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// print prints a Deployment's summary unless it is the one printed last
+func (c *Controller) print(key, summary string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last[key] == summary {
+		return
+	}
+	c.last[key] = summary
+	fmt.Fprintf(c.out, "[Deployment] %s: %s\n", key, summary)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	listersappsv1 "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func deployment(name string, uid types.UID) *appsv1.Deployment {
+	return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: uid}}
+}
+
+func replicaSet(name string, uid types.UID, owner *appsv1.Deployment) *appsv1.ReplicaSet {
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: uid}}
+	if owner != nil {
+		rs.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, appsv1.SchemeGroupVersion.WithKind("Deployment"))}
+	}
+	return rs
+}
+
+func pod(name string, owner metav1.Object, kind string, ready bool, restarts int32) *corev1.Pod {
+	p := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	if owner != nil {
+		p.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, appsv1.SchemeGroupVersion.WithKind(kind))}
+	}
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	p.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}
+	p.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "main", RestartCount: restarts}}
+	return p
+}
+
+func TestOwnerResolver(t *testing.T) {
+	web := deployment("web", "d-1")
+	current := replicaSet("web-abc", "rs-1", web)
+	bare := replicaSet("bare", "rs-2", nil)
+	orphaned := replicaSet("api-xyz", "rs-3", deployment("api", "d-2"))
+
+	rsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	dIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, rs := range []*appsv1.ReplicaSet{current, bare, orphaned} {
+		rsIndexer.Add(rs)
+	}
+	dIndexer.Add(web)
+	r := &ownerResolver{
+		replicaSets: listersappsv1.NewReplicaSetLister(rsIndexer),
+		deployments: listersappsv1.NewDeploymentLister(dIndexer),
+	}
+
+	// A ReplicaSet of the same name, created again after the pod's owner
+	// was deleted
+	recreated := replicaSet("web-abc", "rs-old", web)
+
+	for _, tc := range []struct {
+		name     string
+		pod      *corev1.Pod
+		want     string
+		notFound bool
+		err      bool
+	}{
+		{name: "deployment pod", pod: pod("web-abc-1", current, "ReplicaSet", true, 0), want: "default/web"},
+		{name: "bare replicaset", pod: pod("bare-1", bare, "ReplicaSet", true, 0)},
+		{name: "statefulset pod", pod: pod("db-0", &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", UID: "s-1"}}, "StatefulSet", true, 0)},
+		{name: "no owner", pod: pod("debug", nil, "", true, 0)},
+		{name: "replicaset not cached", pod: pod("new-1", replicaSet("new", "rs-4", web), "ReplicaSet", true, 0), notFound: true},
+		{name: "deployment not cached", pod: pod("api-xyz-1", orphaned, "ReplicaSet", true, 0), notFound: true},
+		{name: "stale owner UID", pod: pod("web-abc-old", recreated, "ReplicaSet", true, 0), err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := r.deploymentKeyForPod(tc.pod)
+			switch {
+			case tc.notFound:
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("err = %v, want NotFound", err)
+				}
+			case tc.err:
+				if err == nil || apierrors.IsNotFound(err) {
+					t.Errorf("err = %v, want a UID mismatch", err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case got != tc.want:
+				t.Errorf("key = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPodEventsWakeTheirDeployment(t *testing.T) {
+	web := deployment("web", "d-1")
+	old := replicaSet("web-old", "rs-1", web)
+	current := replicaSet("web-new", "rs-2", web)
+	clientset := fake.NewClientset(
+		web, old, current,
+		pod("web-old-1", old, "ReplicaSet", true, 2),
+		pod("web-new-1", current, "ReplicaSet", false, 1),
+		pod("unrelated", nil, "", true, 9),
+	)
+	out := &testutil.SyncBuffer{}
+	c := NewController(clientset, "default", true, out)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 1)
+
+	waitFor := func(line string) {
+		t.Helper()
+		err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+			return strings.Contains(out.String(), line), nil
+		})
+		if err != nil {
+			t.Fatalf("waiting for %q:\n%s", line, out.String())
+		}
+	}
+	waitFor("[Deployment] default/web: 2 replicasets, 2 pods, 1 ready, 3 restarts\n")
+
+	// A pod becoming ready is a pod event, handled as a Deployment key
+	ready := pod("web-new-1", current, "ReplicaSet", true, 1)
+	if _, err := clientset.CoreV1().Pods("default").UpdateStatus(ctx, ready, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("[Map] Pod default/web-new-1 -> Deployment default/web\n")
+	waitFor("[Deployment] default/web: 2 replicasets, 2 pods, 2 ready, 3 restarts\n")
+
+	// So is the old ReplicaSet going away
+	if err := clientset.AppsV1().ReplicaSets("default").Delete(ctx, "web-old", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := clientset.CoreV1().Pods("default").Delete(ctx, "web-old-1", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("[Deployment] default/web: 1 replicasets, 1 pods, 1 ready, 1 restarts\n")
+	if strings.Contains(out.String(), "unrelated") {
+		t.Errorf("a pod without owner was mapped:\n%s", out.String())
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	listersappsv1 "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ownerResolver walks controller ownerReferences up to a Deployment, using
+// listers only: mapping an event never costs an API request
+type ownerResolver struct {
+	replicaSets listersappsv1.ReplicaSetLister
+	deployments listersappsv1.DeploymentLister
+}
+
+// controllerOf returns the controller reference of obj if it is a kind of
+// the apps group
+func controllerOf(obj metav1.Object, kind string) *metav1.OwnerReference {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil || ref.Kind != kind || ref.APIVersion != appsv1.SchemeGroupVersion.String() {
+		return nil
+	}
+	return ref
+}
+
+// replicaSetFor returns the ReplicaSet that controls pod. It checks the UID:
+// a ReplicaSet deleted and created again under the same name is not the
+// owner of the old one's pods.
+func (r *ownerResolver) replicaSetFor(pod *corev1.Pod) (*appsv1.ReplicaSet, error) {
+	ref := controllerOf(pod, "ReplicaSet")
+	if ref == nil {
+		return nil, nil
+	}
+	rs, err := r.replicaSets.ReplicaSets(pod.Namespace).Get(ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	if rs.UID != ref.UID {
+		return nil, fmt.Errorf("replicaset %s/%s has UID %s, the pod's owner was %s", rs.Namespace, rs.Name, rs.UID, ref.UID)
+	}
+	return rs, nil
+}
+
+// deploymentFor returns the Deployment that controls rs
+func (r *ownerResolver) deploymentFor(rs *appsv1.ReplicaSet) (*appsv1.Deployment, error) {
+	ref := controllerOf(rs, "Deployment")
+	if ref == nil {
+		return nil, nil
+	}
+	d, err := r.deployments.Deployments(rs.Namespace).Get(ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	if d.UID != ref.UID {
+		return nil, fmt.Errorf("deployment %s/%s has UID %s, the replicaset's owner was %s", d.Namespace, d.Name, d.UID, ref.UID)
+	}
+	return d, nil
+}
+
+// deploymentKeyForPod maps a pod to the key of its Deployment: pod →
+// ReplicaSet → Deployment. It returns "" for pods not run by a Deployment,
+// such as those of a StatefulSet, a Job or a bare ReplicaSet.
+func (r *ownerResolver) deploymentKeyForPod(pod *corev1.Pod) (string, error) {
+	rs, err := r.replicaSetFor(pod)
+	if err != nil || rs == nil {
+		return "", err
+	}
+	return r.deploymentKeyForReplicaSet(rs)
+}
+
+// deploymentKeyForReplicaSet maps a ReplicaSet to the key of its Deployment
+func (r *ownerResolver) deploymentKeyForReplicaSet(rs *appsv1.ReplicaSet) (string, error) {
+	d, err := r.deploymentFor(rs)
+	if err != nil || d == nil {
+		return "", err
+	}
+	return cache.MetaNamespaceKeyFunc(d)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceFactories holds one SharedInformerFactory per tenant namespace.
+// Every factory lists and watches only its own namespace, so the process
+// needs list and watch in those namespaces and nothing cluster-wide.
+type namespaceFactories struct {
+	// namespaces is sorted, so queries fan out in a stable order
+	namespaces []string
+	factories  map[string]informers.SharedInformerFactory
+}
+
+// parseNamespaces splits a comma-separated list, dropping blanks and duplicates
+func parseNamespaces(list string) ([]string, error) {
+	seen := map[string]bool{}
+	var namespaces []string
+	for _, ns := range strings.Split(list, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		namespaces = append(namespaces, ns)
+	}
+	if len(namespaces) == 0 {
+		return nil, fmt.Errorf("no namespaces in %q", list)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// newNamespaceFactories builds a factory scoped to each namespace and
+// registers its pod informer, so Start has something to run
+func newNamespaceFactories(clientset kubernetes.Interface, namespaces []string, resync time.Duration) *namespaceFactories {
+	f := &namespaceFactories{
+		namespaces: namespaces,
+		factories:  make(map[string]informers.SharedInformerFactory, len(namespaces)),
+	}
+	for _, ns := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(clientset, resync, informers.WithNamespace(ns))
+		factory.Core().V1().Pods().Informer()
+		f.factories[ns] = factory
+	}
+	return f
+}
+
+// Start starts every factory's informers
+func (f *namespaceFactories) Start(stopCh <-chan struct{}) {
+	for _, ns := range f.namespaces {
+		f.factories[ns].Start(stopCh)
+	}
+}
+
+// WaitForCacheSync waits for every factory and returns the namespaces whose
+// caches did not sync, which only happens when stopCh is closed first
+func (f *namespaceFactories) WaitForCacheSync(stopCh <-chan struct{}) []string {
+	var unsynced []string
+	for _, ns := range f.namespaces {
+		for _, synced := range f.factories[ns].WaitForCacheSync(stopCh) {
+			if !synced {
+				unsynced = append(unsynced, ns)
+				break
+			}
+		}
+	}
+	return unsynced
+}
+
+// Shutdown stops every factory and waits for its informers to exit
+func (f *namespaceFactories) Shutdown() {
+	for _, ns := range f.namespaces {
+		f.factories[ns].Shutdown()
+	}
+}
+
+// podInformers returns the pod informer of every factory, in namespace order
+func (f *namespaceFactories) podInformers() []cache.SharedIndexInformer {
+	informers := make([]cache.SharedIndexInformer, 0, len(f.namespaces))
+	for _, ns := range f.namespaces {
+		informers = append(informers, f.factories[ns].Core().V1().Pods().Informer())
+	}
+	return informers
+}
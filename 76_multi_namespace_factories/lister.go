@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// multiNamespacePodLister answers lister calls from the per-namespace
+// factories. It implements corelisters.PodLister, so code written against a
+// cluster-wide lister works unchanged: List fans out over every namespace,
+// Pods(ns) goes to the one factory that caches ns.
+type multiNamespacePodLister struct {
+	namespaces []string
+	listers    map[string]corelisters.PodLister
+}
+
+var _ corelisters.PodLister = &multiNamespacePodLister{}
+
+func newMultiNamespacePodLister(f *namespaceFactories) *multiNamespacePodLister {
+	l := &multiNamespacePodLister{
+		namespaces: f.namespaces,
+		listers:    make(map[string]corelisters.PodLister, len(f.namespaces)),
+	}
+	for _, ns := range f.namespaces {
+		l.listers[ns] = f.factories[ns].Core().V1().Pods().Lister()
+	}
+	return l
+}
+
+// List returns the matching pods of every watched namespace, namespace by
+// namespace
+func (l *multiNamespacePodLister) List(selector labels.Selector) ([]*corev1.Pod, error) {
+	var pods []*corev1.Pod
+	for _, ns := range l.namespaces {
+		found, err := l.listers[ns].Pods(ns).List(selector)
+		if err != nil {
+			return nil, fmt.Errorf("listing pods in %s: %w", ns, err)
+		}
+		pods = append(pods, found...)
+	}
+	return pods, nil
+}
+
+// Pods returns a lister for one namespace. "" covers every watched namespace,
+// like a cluster-wide lister. A namespace without a factory is an error rather
+// than an empty result, so a typo does not look like an empty namespace.
+func (l *multiNamespacePodLister) Pods(namespace string) corelisters.PodNamespaceLister {
+	if namespace == "" {
+		return allNamespaces{l}
+	}
+	lister, ok := l.listers[namespace]
+	if !ok {
+		return unwatchedNamespace(namespace)
+	}
+	return lister.Pods(namespace)
+}
+
+// countByNamespace returns how many pods match selector in each namespace
+func (l *multiNamespacePodLister) countByNamespace(selector labels.Selector) (map[string]int, error) {
+	counts := make(map[string]int, len(l.namespaces))
+	for _, ns := range l.namespaces {
+		pods, err := l.listers[ns].Pods(ns).List(selector)
+		if err != nil {
+			return nil, err
+		}
+		counts[ns] = len(pods)
+	}
+	return counts, nil
+}
+
+// allNamespaces is Pods("")
+type allNamespaces struct {
+	l *multiNamespacePodLister
+}
+
+func (a allNamespaces) List(selector labels.Selector) ([]*corev1.Pod, error) {
+	return a.l.List(selector)
+}
+
+func (a allNamespaces) Get(name string) (*corev1.Pod, error) {
+	return nil, errors.New("getting a pod by name needs a namespace")
+}
+
+// unwatchedNamespace is Pods(ns) for a namespace no factory covers
+type unwatchedNamespace string
+
+func (ns unwatchedNamespace) List(selector labels.Selector) ([]*corev1.Pod, error) {
+	return nil, fmt.Errorf("namespace %s is not watched", string(ns))
+}
+
+func (ns unwatchedNamespace) Get(name string) (*corev1.Pod, error) {
+	return nil, fmt.Errorf("namespace %s is not watched", string(ns))
+}
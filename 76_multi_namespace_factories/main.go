@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	namespaceList = flag.String("namespaces", "default", "comma-separated tenant namespaces, each cached by its own factory")
+	selector      = flag.String("selector", "", "label selector for the pods queried across the namespaces")
+	compare       = flag.Bool("compare", false, "also run one cluster-wide factory and compare the two")
+)
+
+// createClientSet creates a Kubernetes clientset from kubeconfig
+func createClientSet() *kubernetes.Clientset {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	// Build config from kubeconfig file
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Fatalf("Failed to build config: %v", err)
+	}
+	clientid.Configure(config, "76-multi-namespace-factories")
+	// Create clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset
+}
+
+// startTenants checks RBAC in every namespace, then starts a factory per
+// namespace and waits for the caches. It returns the running factories and
+// how many namespaces RBAC allows.
+func startTenants(ctx context.Context, clientset kubernetes.Interface, namespaces []string) (*namespaceFactories, int, error) {
+	allowed := 0
+	var denied []string
+	for _, ns := range namespaces {
+		ok, err := canListAndWatchPods(ctx, clientset, ns)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			denied = append(denied, ns)
+			continue
+		}
+		allowed++
+	}
+	if len(denied) > 0 {
+		return nil, allowed, fmt.Errorf("not allowed to list and watch pods in %s", strings.Join(denied, ", "))
+	}
+
+	tenants := newNamespaceFactories(clientset, namespaces, 10*time.Minute)
+	tenants.Start(ctx.Done())
+	if unsynced := tenants.WaitForCacheSync(ctx.Done()); len(unsynced) > 0 {
+		tenants.Shutdown()
+		return nil, allowed, fmt.Errorf("caches did not sync for %s", strings.Join(unsynced, ", "))
+	}
+	return tenants, allowed, nil
+}
+
+// printQueries answers a few questions through the unified lister, without
+// the caller knowing there is one cache per namespace
+func printQueries(w io.Writer, lister *multiNamespacePodLister, selector labels.Selector) error {
+	counts, err := lister.countByNamespace(labels.Everything())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "=== Pods per namespace ===")
+	total := 0
+	for _, ns := range lister.namespaces {
+		fmt.Fprintf(w, "  %-30s %d\n", ns, counts[ns])
+		total += counts[ns]
+	}
+	fmt.Fprintf(w, "  %-30s %d\n", "total", total)
+
+	matching, err := lister.List(selector)
+	if err != nil {
+		return err
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		if matching[i].Namespace != matching[j].Namespace {
+			return matching[i].Namespace < matching[j].Namespace
+		}
+		return matching[i].Name < matching[j].Name
+	})
+	fmt.Fprintf(w, "\n=== Pods matching %q across %d namespaces: %d ===\n", selector.String(), len(lister.namespaces), len(matching))
+	for _, pod := range matching {
+		fmt.Fprintf(w, "  %s/%s\n", pod.Namespace, pod.Name)
+	}
+	return nil
+}
+
+// clusterWideStats runs one cluster-wide pod informer long enough to measure
+// it. It returns stats with allowed 0 when RBAC does not grant the cluster.
+func clusterWideStats(ctx context.Context, clientset kubernetes.Interface) (*scopeStats, error) {
+	ok, err := canListAndWatchPods(ctx, clientset, "")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &scopeStats{scopes: 1}, nil
+	}
+	factory := informers.NewSharedInformerFactory(clientset, 10*time.Minute)
+	defer factory.Shutdown()
+	informer := factory.Core().V1().Pods().Informer()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return nil, fmt.Errorf("cluster-wide pod cache did not sync")
+	}
+	stats := measure([]cache.SharedIndexInformer{informer})
+	stats.allowed, stats.scopes = 1, 1
+	return &stats, nil
+}
+
+func main() {
+	clientset := createClientSet()
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	namespaces, err := parseNamespaces(*namespaceList)
+	if err != nil {
+		log.Fatalf("Invalid --namespaces: %v", err)
+	}
+	podSelector, err := labels.Parse(*selector)
+	if err != nil {
+		log.Fatalf("Invalid --selector: %v", err)
+	}
+
+	fmt.Printf("Starting %d namespace-scoped factories: %s\n", len(namespaces), strings.Join(namespaces, ", "))
+	tenants, allowed, err := startTenants(ctx, clientset, namespaces)
+	if err != nil {
+		log.Fatalf("Failed to start factories: %v", err)
+	}
+	defer tenants.Shutdown()
+	fmt.Println("Cache sync completed!")
+
+	lister := newMultiNamespacePodLister(tenants)
+	if err := printQueries(os.Stdout, lister, podSelector); err != nil {
+		log.Fatalf("Failed to query pods: %v", err)
+	}
+
+	if !*compare {
+		return
+	}
+	tenant := measure(tenants.podInformers())
+	tenant.allowed, tenant.scopes = allowed, len(namespaces)
+	cluster, err := clusterWideStats(ctx, clientset)
+	if err != nil {
+		log.Fatalf("Failed to measure the cluster-wide factory: %v", err)
+	}
+	printComparison(os.Stdout, tenant, cluster)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newPod(namespace, name string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: podLabels}}
+}
+
+// seededClientset holds pods in two tenant namespaces and kube-system, and
+// grants list and watch everywhere except in the namespaces in denied ("" is
+// cluster-wide)
+func seededClientset(denied ...string) *fake.Clientset {
+	clientset := fake.NewClientset(
+		newPod("team-a", "web", map[string]string{"app": "web"}),
+		newPod("team-a", "db", map[string]string{"app": "db"}),
+		newPod("team-b", "web", map[string]string{"app": "web"}),
+		newPod("kube-system", "coredns", nil),
+	)
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = !slices.Contains(denied, review.Spec.ResourceAttributes.Namespace)
+		return true, review, nil
+	})
+	return clientset
+}
+
+func startTestTenants(t *testing.T, clientset *fake.Clientset, namespaces ...string) *namespaceFactories {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	tenants, _, err := startTenants(ctx, clientset, namespaces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		tenants.Shutdown()
+	})
+	return tenants
+}
+
+func TestParseNamespaces(t *testing.T) {
+	got, err := parseNamespaces(" team-b,team-a,,team-b ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"team-a", "team-b"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := parseNamespaces(" , "); err == nil {
+		t.Error("an empty list was accepted")
+	}
+}
+
+func TestEveryFactoryListsOnlyItsNamespace(t *testing.T) {
+	clientset := seededClientset()
+	tenants := startTestTenants(t, clientset, "team-a", "team-b")
+
+	var listed []string
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "pods" {
+			listed = append(listed, action.GetNamespace())
+		}
+	}
+	slices.Sort(listed)
+	if want := []string{"team-a", "team-b"}; !slices.Equal(listed, want) {
+		t.Errorf("pods listed in %q, want one LIST in each of %q", listed, want)
+	}
+	// kube-system is in the cluster, but in no factory's cache
+	if stats := measure(tenants.podInformers()); stats.pods != 3 || stats.watches != 2 {
+		t.Errorf("cached %d pods over %d informers, want 3 over 2", stats.pods, stats.watches)
+	}
+}
+
+func TestListerFansOutAndRoutes(t *testing.T) {
+	lister := newMultiNamespacePodLister(startTestTenants(t, seededClientset(), "team-a", "team-b"))
+
+	web, _ := labels.Parse("app=web")
+	pods, err := lister.List(web)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, pod := range pods {
+		keys = append(keys, pod.Namespace+"/"+pod.Name)
+	}
+	if want := []string{"team-a/web", "team-b/web"}; !slices.Equal(keys, want) {
+		t.Errorf("List(app=web) = %v, want %v", keys, want)
+	}
+
+	if pod, err := lister.Pods("team-a").Get("db"); err != nil || pod.Name != "db" {
+		t.Errorf("Pods(team-a).Get(db) = %v, %v", pod, err)
+	}
+	if _, err := lister.Pods("team-b").Get("db"); !apierrors.IsNotFound(err) {
+		t.Errorf("Pods(team-b).Get(db) error = %v, want NotFound", err)
+	}
+	if all, err := lister.Pods("").List(labels.Everything()); err != nil || len(all) != 3 {
+		t.Errorf("Pods(\"\").List = %d pods, %v; want 3", len(all), err)
+	}
+	// kube-system exists, but the layer does not cache it
+	if _, err := lister.Pods("kube-system").List(labels.Everything()); err == nil || !strings.Contains(err.Error(), "not watched") {
+		t.Errorf("unwatched namespace error = %v", err)
+	}
+}
+
+func TestStartTenantsRefusesDeniedNamespaces(t *testing.T) {
+	clientset := seededClientset("team-b")
+	_, allowed, err := startTenants(context.Background(), clientset, []string{"team-a", "team-b"})
+	if err == nil || !strings.Contains(err.Error(), "team-b") {
+		t.Fatalf("err = %v, want team-b denied", err)
+	}
+	if allowed != 1 {
+		t.Errorf("allowed = %d, want 1", allowed)
+	}
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "list" {
+			t.Errorf("listed %s before RBAC allowed every namespace", action.GetResource().Resource)
+		}
+	}
+}
+
+func TestPrintQueries(t *testing.T) {
+	lister := newMultiNamespacePodLister(startTestTenants(t, seededClientset(), "team-a", "team-b"))
+	web, _ := labels.Parse("app=web")
+	var out bytes.Buffer
+	if err := printQueries(&out, lister, web); err != nil {
+		t.Fatal(err)
+	}
+	want := `=== Pods per namespace ===
+  team-a                         2
+  team-b                         1
+  total                          3
+
+=== Pods matching "app=web" across 2 namespaces: 2 ===
+  team-a/web
+  team-b/web
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestComparisonAgainstClusterWideFactory(t *testing.T) {
+	clientset := seededClientset()
+	tenants := startTestTenants(t, clientset, "team-a", "team-b")
+	tenant := measure(tenants.podInformers())
+	tenant.allowed, tenant.scopes = 2, 2
+	cluster, err := clusterWideStats(context.Background(), clientset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cluster.pods != 4 || cluster.watches != 1 {
+		t.Errorf("cluster-wide cached %d pods over %d informers, want 4 over 1", cluster.pods, cluster.watches)
+	}
+
+	var out bytes.Buffer
+	printComparison(&out, tenant, cluster)
+	for _, line := range []string{
+		"METRIC               2 x WithNamespace        cluster-wide\n",
+		"LIST+WATCH streams   2                        1\n",
+		"Pods cached          3                        4\n",
+		"RBAC needed          Role in each namespace   ClusterRole\n",
+		"RBAC granted         2/2 namespaces           yes\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("missing %q in:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestClusterWideStatsWithoutClusterRole(t *testing.T) {
+	clientset := seededClientset("")
+	cluster, err := clusterWideStats(context.Background(), clientset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	printComparison(&out, scopeStats{watches: 1, pods: 2, allowed: 1, scopes: 1}, cluster)
+	for _, line := range []string{
+		"Pods cached          2                        -\n",
+		"RBAC granted         1/1 namespaces           no\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("missing %q in:\n%s", line, out.String())
+		}
+	}
+}
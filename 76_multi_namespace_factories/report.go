@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// scopeStats describes what one way of caching pods costs
+type scopeStats struct {
+	// watches is the number of LIST+WATCH streams, one per informer
+	watches int
+	pods    int
+	// cacheBytes is the JSON size of the cached pods, a stand-in for memory
+	cacheBytes int
+	// allowed is how many of the namespaces (or the cluster) RBAC grants
+	allowed, scopes int
+}
+
+// measure counts the pods cached by informers and their JSON size
+func measure(informers []cache.SharedIndexInformer) scopeStats {
+	stats := scopeStats{watches: len(informers)}
+	for _, informer := range informers {
+		for _, obj := range informer.GetStore().List() {
+			data, err := json.Marshal(obj)
+			if err != nil {
+				continue
+			}
+			stats.pods++
+			stats.cacheBytes += len(data)
+		}
+	}
+	return stats
+}
+
+// canListAndWatchPods asks the API server whether the current identity may
+// list and watch pods in ns, or in every namespace when ns is empty
+func canListAndWatchPods(ctx context.Context, clientset kubernetes.Interface, ns string) (bool, error) {
+	for _, verb := range []string{"list", "watch"} {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: ns,
+					Verb:      verb,
+					Resource:  "pods",
+				},
+			},
+		}
+		result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return false, fmt.Errorf("access review for %s pods: %w", verb, err)
+		}
+		if !result.Status.Allowed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// printComparison prints the per-namespace factories next to one cluster-wide
+// factory. cluster is nil when the cluster-wide factory could not run.
+func printComparison(w io.Writer, tenant scopeStats, cluster *scopeStats) {
+	clusterValue := func(value func(s *scopeStats) string) string {
+		if cluster == nil || cluster.allowed == 0 {
+			return "-"
+		}
+		return value(cluster)
+	}
+	clusterAllowed := "no"
+	if cluster != nil && cluster.allowed > 0 {
+		clusterAllowed = "yes"
+	}
+
+	fmt.Fprintf(w, "\n=== %d namespace-scoped factories vs one cluster-wide factory ===\n", tenant.scopes)
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(tw, "METRIC\t%d x WithNamespace\tcluster-wide\n", tenant.scopes)
+	fmt.Fprintf(tw, "LIST+WATCH streams\t%d\t%s\n", tenant.watches, clusterValue(func(s *scopeStats) string { return fmt.Sprint(s.watches) }))
+	fmt.Fprintf(tw, "Pods cached\t%d\t%s\n", tenant.pods, clusterValue(func(s *scopeStats) string { return fmt.Sprint(s.pods) }))
+	fmt.Fprintf(tw, "Cache size (JSON)\t%s\t%s\n", kib(tenant.cacheBytes), clusterValue(func(s *scopeStats) string { return kib(s.cacheBytes) }))
+	fmt.Fprintf(tw, "RBAC needed\tRole in each namespace\tClusterRole\n")
+	fmt.Fprintf(tw, "RBAC granted\t%d/%d namespaces\t%s\n", tenant.allowed, tenant.scopes, clusterAllowed)
+	tw.Flush()
+	fmt.Fprint(w, implications)
+}
+
+func kib(b int) string {
+	return fmt.Sprintf("%.1f KiB", float64(b)/1024)
+}
+
+// implications explains the table
+const implications = `
+Implications:
+  Memory   Namespace factories cache only the tenants' pods. A cluster-wide
+           factory caches every pod, and filtering in the handlers or the
+           query layer does not shrink the cache.
+  Streams  Each namespace factory runs its own LIST and WATCH per resource,
+           so the API server serves N watches instead of one. Past a few
+           dozen namespaces, one cluster-wide watch is usually cheaper.
+  RBAC     Namespace factories work with a Role and RoleBinding per tenant
+           namespace. A cluster-wide informer needs a ClusterRole, even when
+           the program only ever reads a few namespaces.
+  Changes  A new tenant needs a new factory (and a restart here). A
+           cluster-wide factory sees new namespaces as soon as they exist.
+`
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// configKey is the ConfigMap key holding the cluster list
+const configKey = "clusters.yaml"
+
+// clusterList is the content of --file or of the ConfigMap's clusters.yaml:
+//
+//	clusters:
+//	- name: kind-a
+//	  kubeconfig: /etc/clusters/kind-a.yaml
+//	- name: staging
+//	  context: staging-admin
+type clusterList struct {
+	Clusters []clusterSpec `json:"clusters"`
+}
+
+// clusterSpec says how to reach one cluster. Two specs with the same fields
+// describe the same connection, so a changed field restarts the cluster.
+type clusterSpec struct {
+	// Name identifies the cluster in the output and must be unique
+	Name string `json:"name"`
+	// Kubeconfig is the path of the kubeconfig file; empty uses the default
+	// loading rules ($KUBECONFIG, then ~/.kube/config)
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// Context picks a context of the kubeconfig; empty uses its current one
+	Context string `json:"context,omitempty"`
+}
+
+// parseClusters reads and validates a cluster list. The whole list is
+// rejected on any error, so a typo never removes a running cluster.
+func parseClusters(data []byte) ([]clusterSpec, error) {
+	var list clusterList
+	if err := yaml.UnmarshalStrict(data, &list); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for i, spec := range list.Clusters {
+		if errs := validation.IsDNS1123Label(spec.Name); len(errs) > 0 {
+			return nil, fmt.Errorf("clusters[%d]: name %q: %s", i, spec.Name, errs[0])
+		}
+		if seen[spec.Name] {
+			return nil, fmt.Errorf("clusters[%d]: cluster %s is listed twice", i, spec.Name)
+		}
+		seen[spec.Name] = true
+	}
+	return list.Clusters, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	file        = flag.String("file", "", "read the cluster list from this file instead of a ConfigMap")
+	poll        = flag.Duration("poll", 5*time.Second, "how often --file is checked for changes")
+	namespace   = flag.String("namespace", "default", "namespace of the ConfigMap holding the cluster list")
+	name        = flag.String("name", "clusters", "name of the ConfigMap holding the cluster list")
+	reportEvery = flag.Duration("report-every", 30*time.Second, "how often the pods cached per cluster are printed")
+)
+
+// parseKubeconfig parses the flags and returns the kubeconfig path. That
+// kubeconfig reaches the cluster holding the ConfigMap, and every listed
+// cluster without a kubeconfig of its own.
+func parseKubeconfig() string {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	return *kubeconfig
+}
+
+// connector returns a clientsetFunc loading a spec's kubeconfig and context,
+// with defaultKubeconfig for specs that name no file
+func connector(defaultKubeconfig string) clientsetFunc {
+	return func(spec clusterSpec) (kubernetes.Interface, error) {
+		rules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: spec.Kubeconfig}
+		if spec.Kubeconfig == "" {
+			rules.ExplicitPath = defaultKubeconfig
+		}
+		overrides := &clientcmd.ConfigOverrides{CurrentContext: spec.Context}
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build config: %w", err)
+		}
+		clientid.Configure(config, "77-cluster-registry")
+		return kubernetes.NewForConfig(config)
+	}
+}
+
+func main() {
+	kubeconfig := parseKubeconfig()
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	registry := newRegistry(connector(kubeconfig), 10*time.Minute, os.Stdout)
+	done := make(chan error, 1)
+	if *file != "" {
+		fmt.Printf("Watching %s for clusters, every %s\n", *file, *poll)
+		go func() {
+			watchFile(ctx, *file, *poll, registry.reload, os.Stdout)
+			done <- nil
+		}()
+	} else {
+		config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+		if err != nil {
+			log.Fatalf("Failed to build config: %v", err)
+		}
+		clientid.Configure(config, "77-cluster-registry")
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			log.Fatalf("Failed to create clientset: %v", err)
+		}
+		fmt.Printf("Watching ConfigMap %s/%s key %s for clusters\n", *namespace, *name, configKey)
+		go func() { done <- watchConfigMap(ctx, clientset, *namespace, *name, registry.reload, os.Stdout) }()
+	}
+
+	ticker := time.NewTicker(*reportEvery)
+	defer ticker.Stop()
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			registry.printSummary(os.Stdout)
+		case err := <-done:
+			if err != nil {
+				log.Printf("Cluster list watch failed: %v", err)
+			}
+			break loop
+		case <-ctx.Done():
+			<-done
+			break loop
+		}
+	}
+
+	// factory.Shutdown waits for each cluster's informers to exit
+	fmt.Println("Shutting down, stopping every cluster's informers...")
+	registry.shutdown()
+	fmt.Println("Exited cleanly")
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func waitForOutput(t *testing.T, out *testutil.SyncBuffer, lines ...string) {
+	t.Helper()
+	err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		for _, line := range lines {
+			if !strings.Contains(out.String(), line) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("expected output %q, got:\n%s", lines, out.String())
+	}
+}
+
+// fakeClusters hands out one fake clientset per cluster name, seeded with
+// pods named after the cluster, and counts the connections made
+type fakeClusters struct {
+	mu          sync.Mutex
+	connections map[string]int
+}
+
+func (f *fakeClusters) connect(spec clusterSpec) (kubernetes.Interface, error) {
+	if spec.Kubeconfig == "/missing" {
+		return nil, errors.New("stat /missing: no such file or directory")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.connections == nil {
+		f.connections = map[string]int{}
+	}
+	f.connections[spec.Name]++
+	return fake.NewClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: spec.Name + "-1"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: spec.Name + "-2"}},
+	), nil
+}
+
+func (f *fakeClusters) count(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.connections[name]
+}
+
+func newTestRegistry(t *testing.T) (*registry, *fakeClusters, *testutil.SyncBuffer) {
+	t.Helper()
+	clusters := &fakeClusters{}
+	out := &testutil.SyncBuffer{}
+	r := newRegistry(clusters.connect, 0, out)
+	t.Cleanup(r.shutdown)
+	return r, clusters, out
+}
+
+func TestParseClusters(t *testing.T) {
+	specs, err := parseClusters([]byte("clusters:\n- name: kind-a\n  kubeconfig: /etc/a.yaml\n- name: staging\n  context: admin\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []clusterSpec{{Name: "kind-a", Kubeconfig: "/etc/a.yaml"}, {Name: "staging", Context: "admin"}}
+	if len(specs) != 2 || specs[0] != want[0] || specs[1] != want[1] {
+		t.Errorf("got %+v, want %+v", specs, want)
+	}
+
+	for _, tc := range []struct {
+		name, data, err string
+	}{
+		{"duplicate", "clusters:\n- name: a\n- name: a\n", "listed twice"},
+		{"invalid name", "clusters:\n- name: Kind_A\n", "name \"Kind_A\""},
+		{"unknown field", "clusters:\n- name: a\n  kubeconfg: /etc/a.yaml\n", "unknown field"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseClusters([]byte(tc.data)); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("err = %v, want it to mention %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestApplyAddsRestartsAndRemovesClusters(t *testing.T) {
+	r, clusters, out := newTestRegistry(t)
+
+	r.apply([]clusterSpec{{Name: "a"}, {Name: "b", Context: "admin"}})
+	waitForOutput(t, out,
+		"[Registry] Added cluster a (--kubeconfig)\n",
+		"[Registry] Added cluster b (--kubeconfig, context admin)\n",
+		"[Registry] Cluster a synced: 2 pods\n",
+		"[Registry] Cluster b synced: 2 pods\n",
+	)
+	first := r.clusters["a"]
+
+	// An unchanged list touches nothing
+	r.apply([]clusterSpec{{Name: "a"}, {Name: "b", Context: "admin"}})
+	if clusters.count("a") != 1 || clusters.count("b") != 1 {
+		t.Errorf("connections after an unchanged list: a=%d b=%d, want 1 each", clusters.count("a"), clusters.count("b"))
+	}
+
+	// A changed spec restarts the cluster, a missing one is stopped
+	r.apply([]clusterSpec{{Name: "a", Context: "other"}})
+	waitForOutput(t, out,
+		"[Registry] Cluster a changed, restarting its informers\n",
+		"[Registry] Removed cluster b, informers stopped\n",
+		"[Registry] Added cluster a (--kubeconfig, context other)\n",
+	)
+	select {
+	case <-first.stopCh:
+	default:
+		t.Error("the replaced factory of a was not stopped")
+	}
+	if clusters.count("a") != 2 {
+		t.Errorf("a connected %d times, want 2", clusters.count("a"))
+	}
+	if names := r.sortedNames(); len(names) != 1 || names[0] != "a" {
+		t.Errorf("running clusters = %v, want [a]", names)
+	}
+}
+
+func TestClusterThatCannotConnectIsRetriedWithTheNextList(t *testing.T) {
+	r, _, out := newTestRegistry(t)
+
+	r.apply([]clusterSpec{{Name: "a", Kubeconfig: "/missing"}})
+	waitForOutput(t, out, "[Registry] Failed to add cluster a: stat /missing: no such file or directory\n")
+	if r.len() != 0 {
+		t.Errorf("%d clusters running, want 0", r.len())
+	}
+	r.apply([]clusterSpec{{Name: "a"}})
+	waitForOutput(t, out, "[Registry] Added cluster a (--kubeconfig)\n")
+}
+
+func TestRejectedListKeepsRunningClusters(t *testing.T) {
+	r, _, out := newTestRegistry(t)
+	r.reload([]byte("clusters:\n- name: a\n"))
+	r.reload([]byte("clusters:\n- name: a\n- name: a\n"))
+
+	waitForOutput(t, out, "[Registry] Rejected cluster list, keeping 1 clusters: clusters[1]: cluster a is listed twice\n")
+	if r.len() != 1 {
+		t.Errorf("%d clusters running, want 1", r.len())
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	r, _, out := newTestRegistry(t)
+	var summary bytes.Buffer
+	r.printSummary(&summary)
+	if summary.String() != "[Summary] no clusters\n" {
+		t.Errorf("empty summary = %q", summary.String())
+	}
+
+	r.apply([]clusterSpec{{Name: "b"}, {Name: "a"}})
+	waitForOutput(t, out, "[Registry] Cluster a synced", "[Registry] Cluster b synced")
+	summary.Reset()
+	r.printSummary(&summary)
+	if want := "[Summary] a: 2 pods, b: 2 pods\n"; summary.String() != want {
+		t.Errorf("summary = %q, want %q", summary.String(), want)
+	}
+}
+
+func TestWatchFileAppliesChanges(t *testing.T) {
+	r, clusters, out := newTestRegistry(t)
+	path := filepath.Join(t.TempDir(), "clusters.yaml")
+	if err := os.WriteFile(path, []byte("clusters:\n- name: a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watchFile(ctx, path, 10*time.Millisecond, r.reload, out)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitForOutput(t, out, "[Registry] Added cluster a (--kubeconfig)\n")
+	if err := os.WriteFile(path, []byte("clusters:\n- name: b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, out, "[Registry] Removed cluster a, informers stopped\n", "[Registry] Added cluster b (--kubeconfig)\n")
+
+	// A missing file keeps b and is reported once, however often it is polled
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, out, "[Registry] Cannot read "+path)
+	time.Sleep(50 * time.Millisecond)
+	if n := strings.Count(out.String(), "Cannot read"); n != 1 {
+		t.Errorf("missing file reported %d times, want once", n)
+	}
+	if r.len() != 1 || clusters.count("b") != 1 {
+		t.Errorf("running clusters = %v after the file went away", r.sortedNames())
+	}
+}
+
+func TestWatchConfigMapAppliesChanges(t *testing.T) {
+	r, _, out := newTestRegistry(t)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "clusters"},
+		Data:       map[string]string{configKey: "clusters:\n- name: a\n"},
+	}
+	home := fake.NewClientset(cm)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- watchConfigMap(ctx, home, "default", "clusters", r.reload, out) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("watchConfigMap: %v", err)
+		}
+	}()
+
+	waitForOutput(t, out, "[Registry] Added cluster a (--kubeconfig)\n")
+	updated := cm.DeepCopy()
+	updated.Data = map[string]string{configKey: "clusters:\n- name: a\n- name: b\n"}
+	if _, err := home.CoreV1().ConfigMaps("default").Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, out, "[Registry] Added cluster b (--kubeconfig)\n")
+
+	updated = updated.DeepCopy()
+	updated.Data = map[string]string{"other": "x"}
+	if _, err := home.CoreV1().ConfigMaps("default").Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, out, "[Registry] ConfigMap default/clusters has no clusters.yaml key, keeping the running clusters\n")
+	if r.len() != 2 {
+		t.Errorf("%d clusters running, want 2", r.len())
+	}
+}
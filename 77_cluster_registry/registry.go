@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// clientsetFunc connects to the cluster a spec describes
+type clientsetFunc func(spec clusterSpec) (kubernetes.Interface, error)
+
+// runningCluster is one cluster's factory and the channel that stops it
+type runningCluster struct {
+	spec    clusterSpec
+	factory informers.SharedInformerFactory
+	stopCh  chan struct{}
+	synced  atomic.Bool
+}
+
+// registry runs one SharedInformerFactory per configured cluster. apply is
+// called with every new cluster list and starts, stops or restarts factories
+// until the running set matches it.
+type registry struct {
+	connect clientsetFunc
+	resync  time.Duration
+	out     io.Writer
+
+	mu       sync.Mutex
+	clusters map[string]*runningCluster
+	// waiters tracks the goroutines waiting for a cluster's first sync
+	waiters sync.WaitGroup
+}
+
+func newRegistry(connect clientsetFunc, resync time.Duration, out io.Writer) *registry {
+	return &registry{
+		connect:  connect,
+		resync:   resync,
+		out:      out,
+		clusters: make(map[string]*runningCluster),
+	}
+}
+
+// reload parses a cluster list and applies it. A list that does not parse is
+// rejected as a whole and the running clusters are left alone.
+func (r *registry) reload(data []byte) {
+	specs, err := parseClusters(data)
+	if err != nil {
+		fmt.Fprintf(r.out, "[Registry] Rejected cluster list, keeping %d clusters: %v\n", r.len(), err)
+		return
+	}
+	r.apply(specs)
+}
+
+// apply stops the clusters no longer listed, restarts the ones whose spec
+// changed and starts the new ones. A cluster that cannot be added is
+// reported and skipped; it is tried again with the next list.
+func (r *registry) apply(specs []clusterSpec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := make(map[string]clusterSpec, len(specs))
+	for _, spec := range specs {
+		wanted[spec.Name] = spec
+	}
+	for _, name := range r.sortedNames() {
+		c := r.clusters[name]
+		spec, ok := wanted[name]
+		switch {
+		case !ok:
+			r.stop(c)
+			fmt.Fprintf(r.out, "[Registry] Removed cluster %s, informers stopped\n", name)
+		case spec != c.spec:
+			r.stop(c)
+			fmt.Fprintf(r.out, "[Registry] Cluster %s changed, restarting its informers\n", name)
+		}
+	}
+	for _, spec := range specs {
+		if _, running := r.clusters[spec.Name]; running {
+			continue
+		}
+		if err := r.start(spec); err != nil {
+			fmt.Fprintf(r.out, "[Registry] Failed to add cluster %s: %v\n", spec.Name, err)
+			continue
+		}
+		fmt.Fprintf(r.out, "[Registry] Added cluster %s (%s)\n", spec.Name, describe(spec))
+	}
+}
+
+// start connects to a cluster and starts its factory. The first sync is
+// awaited in the background, so an unreachable cluster does not hold up the
+// others or the next reload.
+func (r *registry) start(spec clusterSpec) error {
+	clientset, err := r.connect(spec)
+	if err != nil {
+		return err
+	}
+	c := &runningCluster{
+		spec:    spec,
+		factory: informers.NewSharedInformerFactory(clientset, r.resync),
+		stopCh:  make(chan struct{}),
+	}
+	podInformer := c.factory.Core().V1().Pods().Informer()
+	c.factory.Start(c.stopCh)
+	r.clusters[spec.Name] = c
+
+	r.waiters.Add(1)
+	go func() {
+		defer r.waiters.Done()
+		// The wait fails when the cluster is removed before it synced
+		if cache.WaitForCacheSync(c.stopCh, podInformer.HasSynced) {
+			c.synced.Store(true)
+			fmt.Fprintf(r.out, "[Registry] Cluster %s synced: %d pods\n", spec.Name, len(podInformer.GetStore().ListKeys()))
+		}
+	}()
+	return nil
+}
+
+// stop closes a cluster's stop channel and waits in factory.Shutdown until
+// every informer goroutine of that cluster has exited
+func (r *registry) stop(c *runningCluster) {
+	close(c.stopCh)
+	c.factory.Shutdown()
+	delete(r.clusters, c.spec.Name)
+}
+
+// shutdown stops every cluster
+func (r *registry) shutdown() {
+	r.mu.Lock()
+	for _, name := range r.sortedNames() {
+		r.stop(r.clusters[name])
+	}
+	r.mu.Unlock()
+	r.waiters.Wait()
+}
+
+func (r *registry) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.clusters)
+}
+
+// sortedNames returns the running clusters' names; r.mu must be held
+func (r *registry) sortedNames() []string {
+	names := make([]string, 0, len(r.clusters))
+	for name := range r.clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printSummary prints the pods cached per cluster, e.g.
+// "[Summary] kind-a: 12 pods, staging: not synced"
+func (r *registry) printSummary(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.clusters) == 0 {
+		fmt.Fprintln(w, "[Summary] no clusters")
+		return
+	}
+	parts := make([]string, 0, len(r.clusters))
+	for _, name := range r.sortedNames() {
+		c := r.clusters[name]
+		if !c.synced.Load() {
+			parts = append(parts, name+": not synced")
+			continue
+		}
+		pods := len(c.factory.Core().V1().Pods().Informer().GetStore().ListKeys())
+		parts = append(parts, fmt.Sprintf("%s: %d pods", name, pods))
+	}
+	fmt.Fprintf(w, "[Summary] %s\n", strings.Join(parts, ", "))
+}
+
+// describe names the kubeconfig and context of a spec
+func describe(spec clusterSpec) string {
+	kubeconfig := spec.Kubeconfig
+	if kubeconfig == "" {
+		kubeconfig = "--kubeconfig"
+	}
+	if spec.Context == "" {
+		return kubeconfig
+	}
+	return kubeconfig + ", context " + spec.Context
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/shamimice03/mastering-k8s-client-go/hotreload"
+)
+
+// watchFile reads path every interval and hands its content to reload when
+// it changed. A file that cannot be read keeps the running clusters; the
+// error is printed once until it changes.
+func watchFile(ctx context.Context, path string, interval time.Duration, reload func([]byte), out io.Writer) {
+	var checksum, lastErr string
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		data, err := os.ReadFile(path)
+		switch {
+		case err != nil:
+			if err.Error() != lastErr {
+				fmt.Fprintf(out, "[Registry] Cannot read %s, keeping the running clusters: %v\n", path, err)
+			}
+			lastErr = err.Error()
+		default:
+			lastErr = ""
+			// The same checksum the ConfigMap source uses, so both skip
+			// content that did not change
+			if sum := hotreload.Checksum(map[string][]byte{configKey: data}); sum != checksum {
+				checksum = sum
+				reload(data)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchConfigMap hands the clusters.yaml key of a ConfigMap to reload every
+// time the ConfigMap's data changes, until ctx is cancelled
+func watchConfigMap(ctx context.Context, clientset kubernetes.Interface, namespace, name string, reload func([]byte), out io.Writer) error {
+	watcher := hotreload.NewWatcher(clientset, hotreload.ConfigMap, namespace, name)
+	watcher.AddHandler(hotreload.HandlerFunc(func(data map[string][]byte, checksum string) error {
+		list, ok := data[configKey]
+		if !ok {
+			fmt.Fprintf(out, "[Registry] ConfigMap %s/%s has no %s key, keeping the running clusters\n", namespace, name, configKey)
+			return nil
+		}
+		reload(list)
+		return nil
+	}))
+	return watcher.Run(ctx)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+)
+
+// target is one cluster the informers can run against
+type target struct {
+	name      string
+	clientset kubernetes.Interface
+	// probe reports whether the cluster's API server answers
+	probe func(ctx context.Context) error
+}
+
+// Switch tells consumers that the informers now run against another cluster.
+// The caches were rebuilt from a full list of the new cluster before it is
+// sent, so everything read from them earlier should be considered stale.
+type Switch struct {
+	From, To string
+	// Unreachable is how long From had been failing its probe
+	Unreachable time.Duration
+	// Err is the last probe error of From, nil for the first start
+	Err  error
+	Pods int
+}
+
+// failover runs a SharedInformerFactory against one of two clusters. When
+// the active cluster fails its probe for unreachableAfter, a factory is
+// started against the other one; once its caches synced it replaces the old
+// factory, which is then shut down.
+type failover struct {
+	targets          [2]target
+	namespace        string
+	unreachableAfter time.Duration
+	// syncTimeout bounds the wait for a new factory's caches
+	syncTimeout time.Duration
+	clock       clock.Clock
+	out         io.Writer
+	handlers    []func(Switch)
+
+	mu      sync.RWMutex
+	active  int
+	factory informers.SharedInformerFactory
+	stopCh  chan struct{}
+
+	// failingSince and lastErr are only used by the probing goroutine
+	failingSince time.Time
+	lastErr      error
+}
+
+func newFailover(primary, standby target, namespace string, unreachableAfter time.Duration, clk clock.Clock, out io.Writer) *failover {
+	return &failover{
+		targets:          [2]target{primary, standby},
+		namespace:        namespace,
+		unreachableAfter: unreachableAfter,
+		syncTimeout:      unreachableAfter,
+		clock:            clk,
+		out:              out,
+	}
+}
+
+// AddSwitchHandler registers h to be called, on the probing goroutine, every
+// time the informers start against a cluster. Handlers must be added before
+// start.
+func (f *failover) AddSwitchHandler(h func(Switch)) {
+	f.handlers = append(f.handlers, h)
+}
+
+// start runs the informers against the primary, or against the standby when
+// the primary's caches do not sync within syncTimeout
+func (f *failover) start(ctx context.Context) error {
+	errPrimary := f.switchTo(ctx, 0, Switch{To: f.targets[0].name})
+	if errPrimary == nil {
+		return nil
+	}
+	fmt.Fprintf(f.out, "[Failover] Cannot start on %s, trying %s: %v\n", f.targets[0].name, f.targets[1].name, errPrimary)
+	if err := f.switchTo(ctx, 1, Switch{From: f.targets[0].name, To: f.targets[1].name, Err: errPrimary}); err != nil {
+		return fmt.Errorf("neither cluster synced: %s: %v, %s: %w", f.targets[0].name, errPrimary, f.targets[1].name, err)
+	}
+	return nil
+}
+
+// check probes the active cluster once, and fails over to the other one once
+// the active cluster has been unreachable for unreachableAfter
+func (f *failover) check(ctx context.Context) {
+	active := f.targets[f.Active()]
+	err := active.probe(ctx)
+	if err == nil {
+		if !f.failingSince.IsZero() {
+			fmt.Fprintf(f.out, "[Failover] %s reachable again after %s\n", active.name, f.clock.Since(f.failingSince).Round(time.Second))
+		}
+		f.failingSince, f.lastErr = time.Time{}, nil
+		return
+	}
+
+	f.lastErr = err
+	if f.failingSince.IsZero() {
+		f.failingSince = f.clock.Now()
+		fmt.Fprintf(f.out, "[Failover] %s unreachable, failing over in %s: %v\n", active.name, f.unreachableAfter, err)
+		return
+	}
+	down := f.clock.Since(f.failingSince)
+	if down < f.unreachableAfter {
+		return
+	}
+
+	next := 1 - f.Active()
+	other := f.targets[next]
+	// A standby that is down too is not worth a factory; the active cluster
+	// keeps its stale caches until one of them answers
+	if err := other.probe(ctx); err != nil {
+		fmt.Fprintf(f.out, "[Failover] %s unreachable for %s, but %s is unreachable too: %v\n", active.name, down.Round(time.Second), other.name, err)
+		return
+	}
+	sw := Switch{From: active.name, To: other.name, Unreachable: down, Err: f.lastErr}
+	if err := f.switchTo(ctx, next, sw); err != nil {
+		fmt.Fprintf(f.out, "[Failover] Staying on %s: %v\n", active.name, err)
+		return
+	}
+	f.failingSince, f.lastErr = time.Time{}, nil
+}
+
+// switchTo starts a factory against targets[i] and waits for its caches. Only
+// then is the old factory replaced and shut down, so consumers always read
+// from a synced cache: the old one, stale, until the new one is complete.
+func (f *failover) switchTo(ctx context.Context, i int, sw Switch) error {
+	t := f.targets[i]
+	factory := informers.NewSharedInformerFactoryWithOptions(t.clientset, 0, informers.WithNamespace(f.namespace))
+	podInformer := factory.Core().V1().Pods().Informer()
+	stopCh := make(chan struct{})
+	factory.Start(stopCh)
+
+	syncCtx, cancel := context.WithTimeout(ctx, f.syncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), podInformer.HasSynced) {
+		close(stopCh)
+		factory.Shutdown()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("caches of %s did not sync within %s", t.name, f.syncTimeout)
+	}
+
+	f.mu.Lock()
+	oldFactory, oldStopCh := f.factory, f.stopCh
+	f.active, f.factory, f.stopCh = i, factory, stopCh
+	f.mu.Unlock()
+	if oldFactory != nil {
+		close(oldStopCh)
+		oldFactory.Shutdown()
+	}
+
+	sw.Pods = len(podInformer.GetStore().ListKeys())
+	if sw.From == "" {
+		fmt.Fprintf(f.out, "[Failover] Informers running against %s: %d pods\n", t.name, sw.Pods)
+	} else {
+		fmt.Fprintf(f.out, "[Failover] Switched from %s to %s: %d pods resynced\n", sw.From, sw.To, sw.Pods)
+	}
+	for _, h := range f.handlers {
+		h(sw)
+	}
+	return nil
+}
+
+// Run starts the informers and probes the active cluster every interval
+// until ctx is cancelled, then shuts the factory down
+func (f *failover) Run(ctx context.Context, interval time.Duration) error {
+	if err := f.start(ctx); err != nil {
+		return err
+	}
+	defer f.shutdown()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			f.check(ctx)
+		}
+	}
+}
+
+// shutdown stops the active factory and waits for its informers to exit
+func (f *failover) shutdown() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.factory == nil {
+		return
+	}
+	close(f.stopCh)
+	f.factory.Shutdown()
+	f.factory = nil
+}
+
+// Active returns the index of the cluster the informers run against
+func (f *failover) Active() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.active
+}
+
+// ActiveName returns the name of the cluster the informers run against
+func (f *failover) ActiveName() string {
+	return f.targets[f.Active()].name
+}
+
+// Pods returns the pod lister of the active factory. Consumers should call
+// it for every read rather than keep the lister, which goes stale on a switch.
+func (f *failover) Pods() (corelisters.PodLister, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.factory == nil {
+		return nil, errNotStarted
+	}
+	return f.factory.Core().V1().Pods().Lister(), nil
+}
+
+var errNotStarted = errors.New("informers are not running")
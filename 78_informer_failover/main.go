@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/clock"
+
+	"github.com/shamimice03/mastering-k8s-client-go/clientid"
+)
+
+var (
+	standbyKubeconfig = flag.String("standby-kubeconfig", "", "kubeconfig of the standby cluster (required)")
+	primaryContext    = flag.String("primary-context", "", "context of --kubeconfig to use, default its current context")
+	standbyContext    = flag.String("standby-context", "", "context of --standby-kubeconfig to use, default its current context")
+	namespace         = flag.String("namespace", "default", "namespace whose pods are cached")
+	unreachableAfter  = flag.Duration("unreachable-after", 30*time.Second, "how long the active cluster may fail its probe before the informers switch")
+	probeEvery        = flag.Duration("probe-every", 5*time.Second, "how often the active cluster is probed")
+	probeTimeout      = flag.Duration("probe-timeout", 3*time.Second, "timeout of a single probe")
+	reportEvery       = flag.Duration("report-every", 30*time.Second, "how often the pods cached are printed")
+)
+
+// parseKubeconfig parses the flags and returns the kubeconfig path of the
+// primary cluster
+func parseKubeconfig() string {
+	// Get home directory for kubeconfig path
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("Failed to get home directory: %v", err)
+	}
+	// Parse kubeconfig flag
+	kubeconfig := flag.String("kubeconfig", filepath.Join(home, "/.kube/config"), "location of kubeconfig file")
+	flag.Parse()
+	return *kubeconfig
+}
+
+// connect builds a target from a kubeconfig and context. Its probe asks the
+// API server for its version through a client of its own with a timeout;
+// the timeout cannot go on the informers' client, where it would cut every
+// watch short.
+func connect(name, kubeconfig, kubeContext string) (target, error) {
+	rules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return target{}, fmt.Errorf("failed to build config: %w", err)
+	}
+	clientid.Configure(config, "78-informer-failover")
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return target{}, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	probeConfig := rest.CopyConfig(config)
+	probeConfig.Timeout = *probeTimeout
+	probeClient, err := discovery.NewDiscoveryClientForConfig(probeConfig)
+	if err != nil {
+		return target{}, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	probe := func(context.Context) error {
+		_, err := probeClient.ServerVersion()
+		return err
+	}
+	return target{name: name, clientset: clientset, probe: probe}, nil
+}
+
+func main() {
+	kubeconfig := parseKubeconfig()
+	if *standbyKubeconfig == "" {
+		log.Fatalf("Failed to start: --standby-kubeconfig is required")
+	}
+
+	primary, err := connect("primary", kubeconfig, *primaryContext)
+	if err != nil {
+		log.Fatalf("Failed to connect to the primary cluster: %v", err)
+	}
+	standby, err := connect("standby", *standbyKubeconfig, *standbyContext)
+	if err != nil {
+		log.Fatalf("Failed to connect to the standby cluster: %v", err)
+	}
+
+	// Stop on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	f := newFailover(primary, standby, *namespace, *unreachableAfter, clock.RealClock{}, os.Stdout)
+	// A consumer holding state derived from the caches would rebuild it here
+	f.AddSwitchHandler(func(sw Switch) {
+		fmt.Printf("[Consumer] Reading pods from %s, rebuilt from %d pods\n", sw.To, sw.Pods)
+	})
+
+	fmt.Printf("Caching pods in %s, failing over after %s unreachable\n", *namespace, *unreachableAfter)
+	done := make(chan error, 1)
+	go func() { done <- f.Run(ctx, *probeEvery) }()
+
+	ticker := time.NewTicker(*reportEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			lister, err := f.Pods()
+			if err != nil {
+				fmt.Printf("[Pods] %v\n", err)
+				continue
+			}
+			pods, err := lister.Pods(*namespace).List(labels.Everything())
+			if err != nil {
+				fmt.Printf("[Pods] Failed to list pods: %v\n", err)
+				continue
+			}
+			fmt.Printf("[Pods] %d pods cached from %s\n", len(pods), f.ActiveName())
+		case err := <-done:
+			// Ctrl-C during the first sync is not a failure
+			if err != nil && ctx.Err() == nil {
+				log.Fatalf("Failed to run informers: %v", err)
+			}
+			fmt.Println("Exited cleanly")
+			return
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+// fakeCluster is a target whose probe fails while down is set
+type fakeCluster struct {
+	target
+	down atomic.Bool
+}
+
+func newFakeCluster(name string, pods int) *fakeCluster {
+	var objects []runtime.Object
+	for i := range pods {
+		objects = append(objects, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name + "-" + string(rune('a'+i)),
+		}})
+	}
+	c := &fakeCluster{}
+	c.target = target{
+		name:      name,
+		clientset: fake.NewClientset(objects...),
+		probe: func(context.Context) error {
+			if c.down.Load() {
+				return errors.New("dial tcp: connection refused")
+			}
+			return nil
+		},
+	}
+	return c
+}
+
+func newTestFailover(t *testing.T, primary, standby *fakeCluster) (*failover, *clocktesting.FakeClock, *testutil.SyncBuffer, *[]Switch) {
+	t.Helper()
+	clk := clocktesting.NewFakeClock(time.Now())
+	out := &testutil.SyncBuffer{}
+	f := newFailover(primary.target, standby.target, "default", 30*time.Second, clk, out)
+	f.syncTimeout = time.Second
+	var switches []Switch
+	f.AddSwitchHandler(func(sw Switch) { switches = append(switches, sw) })
+	t.Cleanup(f.shutdown)
+	return f, clk, out, &switches
+}
+
+func cachedPods(t *testing.T, f *failover) []string {
+	t.Helper()
+	lister, err := f.Pods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pods, err := lister.List(labels.Everything())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func TestStartsOnPrimary(t *testing.T) {
+	primary, standby := newFakeCluster("primary", 2), newFakeCluster("standby", 3)
+	f, _, out, switches := newTestFailover(t, primary, standby)
+	if _, err := f.Pods(); err != errNotStarted {
+		t.Errorf("Pods before start: err = %v, want %v", err, errNotStarted)
+	}
+
+	if err := f.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if f.ActiveName() != "primary" || len(cachedPods(t, f)) != 2 {
+		t.Errorf("active = %s with %v cached, want primary with 2 pods", f.ActiveName(), cachedPods(t, f))
+	}
+	if !strings.Contains(out.String(), "[Failover] Informers running against primary: 2 pods\n") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+	if len(*switches) != 1 || (*switches)[0].From != "" || (*switches)[0].To != "primary" {
+		t.Errorf("switches = %+v, want one start on primary", *switches)
+	}
+}
+
+func TestFailsOverAfterUnreachableFor(t *testing.T) {
+	primary, standby := newFakeCluster("primary", 2), newFakeCluster("standby", 3)
+	f, clk, out, switches := newTestFailover(t, primary, standby)
+	ctx := context.Background()
+	if err := f.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.RLock()
+	primaryStop := f.stopCh
+	f.mu.RUnlock()
+
+	primary.down.Store(true)
+	f.check(ctx)
+	clk.Step(29 * time.Second)
+	f.check(ctx)
+	if f.ActiveName() != "primary" {
+		t.Fatalf("switched to %s after 29s, want to wait for 30s", f.ActiveName())
+	}
+
+	clk.Step(time.Second)
+	f.check(ctx)
+	if f.ActiveName() != "standby" {
+		t.Fatalf("active = %s after 30s unreachable, want standby", f.ActiveName())
+	}
+	if got := cachedPods(t, f); len(got) != 3 || !strings.HasPrefix(got[0], "standby-") {
+		t.Errorf("cached pods after the switch = %v, want the standby's 3", got)
+	}
+	select {
+	case <-primaryStop:
+	default:
+		t.Error("the primary's factory was not stopped")
+	}
+
+	for _, want := range []string{
+		"[Failover] primary unreachable, failing over in 30s: dial tcp: connection refused\n",
+		"[Failover] Switched from primary to standby: 3 pods resynced\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+	if len(*switches) != 2 {
+		t.Fatalf("switches = %+v, want the start and one failover", *switches)
+	}
+	sw := (*switches)[1]
+	if sw.From != "primary" || sw.To != "standby" || sw.Unreachable != 30*time.Second || sw.Pods != 3 || sw.Err == nil {
+		t.Errorf("failover switch = %+v", sw)
+	}
+}
+
+func TestRecoveryResetsTheTimer(t *testing.T) {
+	primary, standby := newFakeCluster("primary", 1), newFakeCluster("standby", 1)
+	f, clk, out, _ := newTestFailover(t, primary, standby)
+	ctx := context.Background()
+	if err := f.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	primary.down.Store(true)
+	f.check(ctx)
+	clk.Step(20 * time.Second)
+	primary.down.Store(false)
+	f.check(ctx)
+
+	// A second outage starts counting from zero
+	primary.down.Store(true)
+	f.check(ctx)
+	clk.Step(20 * time.Second)
+	f.check(ctx)
+	if f.ActiveName() != "primary" {
+		t.Errorf("active = %s after two 20s outages, want primary", f.ActiveName())
+	}
+	if !strings.Contains(out.String(), "[Failover] primary reachable again after 20s\n") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestStaysWhenStandbyIsDownToo(t *testing.T) {
+	primary, standby := newFakeCluster("primary", 1), newFakeCluster("standby", 1)
+	f, clk, out, switches := newTestFailover(t, primary, standby)
+	ctx := context.Background()
+	if err := f.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	primary.down.Store(true)
+	standby.down.Store(true)
+	f.check(ctx)
+	clk.Step(30 * time.Second)
+	f.check(ctx)
+	if f.ActiveName() != "primary" || len(*switches) != 1 {
+		t.Errorf("active = %s after %d switches, want to stay on primary", f.ActiveName(), len(*switches))
+	}
+	if !strings.Contains(out.String(), "but standby is unreachable too") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+	// The stale caches stay readable
+	if len(cachedPods(t, f)) != 1 {
+		t.Errorf("cached pods = %v, want the primary's", cachedPods(t, f))
+	}
+
+	// Once the standby answers, the next check fails over
+	standby.down.Store(false)
+	f.check(ctx)
+	if f.ActiveName() != "standby" {
+		t.Errorf("active = %s, want standby once it answers", f.ActiveName())
+	}
+}
+
+func TestStartFallsBackToStandby(t *testing.T) {
+	primary, standby := newFakeCluster("primary", 1), newFakeCluster("standby", 2)
+	// A primary whose list fails never syncs
+	primary.clientset.(*fake.Clientset).PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	f, _, out, switches := newTestFailover(t, primary, standby)
+	f.syncTimeout = 500 * time.Millisecond
+
+	if err := f.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if f.ActiveName() != "standby" {
+		t.Errorf("active = %s, want standby", f.ActiveName())
+	}
+	if !strings.Contains(out.String(), "[Failover] Cannot start on primary, trying standby: caches of primary did not sync within 500ms\n") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+	if len(*switches) != 1 || (*switches)[0].From != "primary" || (*switches)[0].To != "standby" {
+		t.Errorf("switches = %+v, want one from primary to standby", *switches)
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	primary, standby := newFakeCluster("primary", 1), newFakeCluster("standby", 1)
+	f, _, out, _ := newTestFailover(t, primary, standby)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- f.Run(ctx, 10*time.Millisecond) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "Informers running against primary") {
+		if time.Now().After(deadline) {
+			t.Fatalf("informers did not start:\n%s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run: %v", err)
+	}
+	if _, err := f.Pods(); err != errNotStarted {
+		t.Errorf("Pods after Run returned: err = %v, want %v", err, errNotStarted)
+	}
+}
@@ -0,0 +1,110 @@
+package cachetransform
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// appliedPod returns a pod as kubectl apply leaves it
+func appliedPod(name string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: "default",
+		Annotations: map[string]string{
+			corev1.LastAppliedConfigAnnotation: `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"` + name + `"}}`,
+			"team":                             "web",
+		},
+		ManagedFields: []metav1.ManagedFieldsEntry{{
+			Manager:    "kubectl-client-side-apply",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{".":{}}}}`)},
+		}},
+	}}
+}
+
+func TestTransformStripsManagedFieldsAndLastApplied(t *testing.T) {
+	stripper := &Stripper{}
+	obj, err := stripper.Transform(appliedPod("web"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod := obj.(*corev1.Pod)
+	if pod.ManagedFields != nil {
+		t.Errorf("managedFields kept: %v", pod.ManagedFields)
+	}
+	if _, ok := pod.Annotations[corev1.LastAppliedConfigAnnotation]; ok {
+		t.Error("last-applied annotation kept")
+	}
+	if pod.Annotations["team"] != "web" {
+		t.Errorf("other annotations changed: %v", pod.Annotations)
+	}
+	if stripper.managedFieldsBytes.Load() == 0 || stripper.lastAppliedBytes.Load() == 0 {
+		t.Errorf("removed bytes not counted: managedFields %d, last-applied %d",
+			stripper.managedFieldsBytes.Load(), stripper.lastAppliedBytes.Load())
+	}
+
+	// Running it again on the stripped pod changes nothing
+	managed, lastApplied := stripper.managedFieldsBytes.Load(), stripper.lastAppliedBytes.Load()
+	if _, err := stripper.Transform(pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stripper.managedFieldsBytes.Load() != managed || stripper.lastAppliedBytes.Load() != lastApplied {
+		t.Error("second transform counted removed bytes again")
+	}
+	if pod.Annotations["team"] != "web" {
+		t.Errorf("second transform changed annotations: %v", pod.Annotations)
+	}
+}
+
+func TestTransformPassesTombstonesThrough(t *testing.T) {
+	stripper := &Stripper{}
+	tombstone := cache.DeletedFinalStateUnknown{Key: "default/web", Obj: appliedPod("web")}
+	obj, err := stripper.Transform(tombstone)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obj != tombstone {
+		t.Errorf("got %v, want the tombstone unchanged", obj)
+	}
+	if stripper.objects.Load() != 0 {
+		t.Errorf("counted %d objects, want 0", stripper.objects.Load())
+	}
+}
+
+func TestCachedPodsAreStripped(t *testing.T) {
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(appliedPod("web"), appliedPod("api")), 0)
+	podInformer := factory.Core().V1().Pods().Informer()
+	stripper := &Stripper{}
+	if err := podInformer.SetTransform(stripper.Transform); err != nil {
+		t.Fatalf("SetTransform: %v", err)
+	}
+	stopCh := make(chan struct{})
+	defer func() {
+		close(stopCh)
+		factory.Shutdown()
+	}()
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	for _, obj := range podInformer.GetStore().List() {
+		pod := obj.(*corev1.Pod)
+		if pod.ManagedFields != nil || pod.Annotations[corev1.LastAppliedConfigAnnotation] != "" {
+			t.Errorf("%s cached unstripped: %+v", pod.Name, pod.ObjectMeta)
+		}
+	}
+	if got := stripper.objects.Load(); got != 2 {
+		t.Errorf("transformed %d objects, want 2", got)
+	}
+
+	// A transform cannot be swapped once the informer runs
+	if err := podInformer.SetTransform(stripper.Transform); err == nil {
+		t.Error("SetTransform succeeded on a started informer")
+	}
+}
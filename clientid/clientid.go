@@ -0,0 +1,53 @@
+package clientid
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"k8s.io/client-go/rest"
+)
+
+// Product is the first part of every User-Agent and field manager, so the
+// requests of all examples can be found together in an audit log
+const Product = "k8s-lab"
+
+// Configure makes every client built from config, and from its copies,
+// identify itself as the example. Name the example after its directory,
+// with dashes for underscores: "05-informer-index" for 05_informer_index.
+func Configure(config *rest.Config, example string) {
+	config.UserAgent = UserAgent(example)
+}
+
+// UserAgent returns "k8s-lab/05-informer-index (linux/amd64) client-go/v0.33.2".
+// It follows client-go's default, "<binary>/<version> (<os>/<arch>)
+// kubernetes/<commit>", which for `go run` names a temporary binary and an
+// unknown version.
+func UserAgent(example string) string {
+	return fmt.Sprintf("%s/%s (%s/%s) client-go/%s", Product, example, runtime.GOOS, runtime.GOARCH, clientGoVersion())
+}
+
+// FieldManager returns "k8s-lab/47-cronjob-manager", the name to send with
+// every apply, patch and update of the example. Without one the API server
+// takes the User-Agent up to the first "/" as the manager, which is
+// "k8s-lab" for every example alike.
+func FieldManager(example string) string {
+	return Product + "/" + example
+}
+
+// clientGoVersion reads the client-go version the binary was built with
+func clientGoVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "k8s.io/client-go" {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
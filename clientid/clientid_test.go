@@ -0,0 +1,52 @@
+package clientid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestUserAgent(t *testing.T) {
+	got := UserAgent("05-informer-index")
+	if !regexp.MustCompile(`^k8s-lab/05-informer-index \(\w+/\w+\) client-go/(v\d+\.\d+\.\d+|unknown)$`).MatchString(got) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFieldManager(t *testing.T) {
+	if got := FieldManager("47-cronjob-manager"); got != "k8s-lab/47-cronjob-manager" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestConfigureSetsTheHeader(t *testing.T) {
+	agents := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents <- r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind":"Namespace","apiVersion":"v1","metadata":{"name":"default"}}`))
+	}))
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	Configure(config, "05-informer-index")
+	// Copies, such as the impersonating ones of 15_user_impersonation, keep it
+	for _, c := range []*rest.Config{config, rest.CopyConfig(config)} {
+		clientset, err := kubernetes.NewForConfig(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := clientset.CoreV1().Namespaces().Get(context.TODO(), "default", metav1.GetOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := <-agents, UserAgent("05-informer-index"); got != want {
+			t.Errorf("User-Agent = %q, want %q", got, want)
+		}
+	}
+}
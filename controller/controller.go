@@ -0,0 +1,223 @@
+// Package controller runs the loop most examples in this repository write out
+// by hand: an informer's events become keys in a rate-limited workqueue, and
+// workers hand each key to a reconcile function until it succeeds.
+//
+// A Controller only carries keys. The reconcile function reads the current
+// state from the informer's cache, so several events for one object collapse
+// into a single reconcile, and a failed reconcile is retried with backoff.
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// KeyFunc returns the key to reconcile for an object the informer delivered,
+// which may be a cache.DeletedFinalStateUnknown. An empty key drops the event,
+// so a KeyFunc can also filter events or map an object to its owner.
+type KeyFunc func(obj interface{}) (string, error)
+
+// ReconcileFunc brings the object named by key to its desired state. An error
+// requeues the key with backoff.
+type ReconcileFunc func(ctx context.Context, key string) error
+
+// Options tune a Controller. Only Name is required.
+type Options struct {
+	// Name labels the workqueue, the log lines and the metrics
+	Name string
+	// Workers is the number of keys reconciled in parallel, 1 when unset
+	Workers int
+	// MaxRetries drops a key once it failed this many times in a row after
+	// its first attempt. 0 retries until the reconcile succeeds.
+	MaxRetries int
+	// RateLimiter spaces the retries of a failing key,
+	// workqueue.DefaultTypedControllerRateLimiter when unset
+	RateLimiter workqueue.TypedRateLimiter[string]
+	// Instrument wraps the event handler the controller registers, e.g. with
+	// informermetrics' CountEvents
+	Instrument func(cache.ResourceEventHandler) cache.ResourceEventHandler
+	// Out receives the retry and drop messages, os.Stdout when unset
+	Out io.Writer
+}
+
+// Controller feeds the keys of an informer's objects to a ReconcileFunc
+type Controller struct {
+	name         string
+	keyFunc      KeyFunc
+	reconcile    ReconcileFunc
+	workers      int
+	maxRetries   int
+	out          io.Writer
+	informer     cache.SharedIndexInformer
+	registration cache.ResourceEventHandlerRegistration
+	queue        workqueue.TypedRateLimitingInterface[string]
+
+	succeeded, failed, dropped atomic.Int64
+	// busy counts the workers inside reconcile, reconcileNanos the time they
+	// spent there
+	busy, reconcileNanos atomic.Int64
+}
+
+// New registers an event handler on informer that queues keyFunc's key for
+// every add, update and delete. keyFunc defaults to
+// cache.DeletionHandlingMetaNamespaceKeyFunc. Events are queued from now on;
+// Run reconciles them.
+func New(informer cache.SharedIndexInformer, keyFunc KeyFunc, reconcile ReconcileFunc, opts Options) (*Controller, error) {
+	if opts.Name == "" {
+		return nil, errors.New("controller name is required")
+	}
+	if reconcile == nil {
+		return nil, fmt.Errorf("controller %s: reconcile function is required", opts.Name)
+	}
+	if keyFunc == nil {
+		keyFunc = cache.DeletionHandlingMetaNamespaceKeyFunc
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.RateLimiter == nil {
+		opts.RateLimiter = workqueue.DefaultTypedControllerRateLimiter[string]()
+	}
+
+	c := &Controller{
+		name:       opts.Name,
+		keyFunc:    keyFunc,
+		reconcile:  reconcile,
+		workers:    opts.Workers,
+		maxRetries: opts.MaxRetries,
+		out:        opts.Out,
+		informer:   informer,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(opts.RateLimiter,
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: opts.Name}),
+	}
+
+	var handler cache.ResourceEventHandler = cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueObject,
+		UpdateFunc: func(oldObj, newObj interface{}) { c.enqueueObject(newObj) },
+		DeleteFunc: c.enqueueObject,
+	}
+	if opts.Instrument != nil {
+		handler = opts.Instrument(handler)
+	}
+	registration, err := informer.AddEventHandler(handler)
+	if err != nil {
+		return nil, fmt.Errorf("controller %s: failed to add event handler: %w", opts.Name, err)
+	}
+	c.registration = registration
+	return c, nil
+}
+
+// Name returns the name the controller was created with
+func (c *Controller) Name() string {
+	return c.name
+}
+
+// Enqueue queues key, e.g. from the handler of another informer whose objects
+// map to this controller's
+func (c *Controller) Enqueue(key string) {
+	c.queue.Add(key)
+}
+
+// Len returns the number of keys waiting, so healthz.QueueDepth can watch it
+func (c *Controller) Len() int {
+	return c.queue.Len()
+}
+
+// HasSynced reports whether the initial list has been queued
+func (c *Controller) HasSynced() bool {
+	return c.registration.HasSynced()
+}
+
+// Run waits for the informer's initial list to be queued, then reconciles
+// with the configured number of workers until ctx is cancelled. On the way
+// out it removes its event handler, lets running reconciles finish and
+// returns once every worker has exited. The informer itself is left running.
+func (c *Controller) Run(ctx context.Context) error {
+	defer utilruntime.HandleCrash()
+
+	var workers sync.WaitGroup
+	defer func() {
+		if err := c.informer.RemoveEventHandler(c.registration); err != nil {
+			utilruntime.HandleError(fmt.Errorf("controller %s: failed to remove event handler: %w", c.name, err))
+		}
+		c.queue.ShutDownWithDrain()
+		workers.Wait()
+	}()
+
+	// The wait only fails when ctx is cancelled
+	if !cache.WaitForNamedCacheSyncWithContext(ctx, c.registration.HasSynced) {
+		return fmt.Errorf("controller %s: cache did not sync", c.name)
+	}
+	for i := 0; i < c.workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for c.processNextItem(ctx) {
+			}
+		}()
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// enqueueObject queues the key of an informer event
+func (c *Controller) enqueueObject(obj interface{}) {
+	key, err := c.keyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("controller %s: %w", c.name, err))
+		return
+	}
+	if key == "" {
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *Controller) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	c.busy.Add(1)
+	start := time.Now()
+	err := c.reconcile(ctx, key)
+	c.reconcileNanos.Add(int64(time.Since(start)))
+	c.busy.Add(-1)
+
+	if err == nil {
+		c.succeeded.Add(1)
+		c.queue.Forget(key)
+		return true
+	}
+	c.failed.Add(1)
+	requeues := c.queue.NumRequeues(key)
+	if c.maxRetries > 0 && requeues >= c.maxRetries {
+		c.dropped.Add(1)
+		c.queue.Forget(key)
+		c.printf("[%s] Dropping %s after %d retries: %v\n", c.name, key, requeues, err)
+		return true
+	}
+	c.printf("[%s] Failed to reconcile %s, requeuing: %v\n", c.name, key, err)
+	c.queue.AddRateLimited(key)
+	return true
+}
+
+func (c *Controller) printf(format string, args ...interface{}) {
+	out := c.out
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintf(out, format, args...)
+}
@@ -0,0 +1,259 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func pod(name string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+}
+
+// keyLog records the keys reconciled, in order
+type keyLog struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (l *keyLog) add(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.keys = append(l.keys, key)
+}
+
+func (l *keyLog) count(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, k := range l.keys {
+		if k == key {
+			n++
+		}
+	}
+	return n
+}
+
+func podInformer(t *testing.T, clientset kubernetes.Interface) (informers.SharedInformerFactory, cache.SharedIndexInformer) {
+	t.Helper()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	return factory, factory.Core().V1().Pods().Informer()
+}
+
+// run starts factory and c, and stops both when the test ends
+func run(t *testing.T, factory informers.SharedInformerFactory, c *Controller) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	factory.Start(ctx.Done())
+	go func() { done <- c.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+		factory.Shutdown()
+	})
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	err := wait.PollUntilContextTimeout(context.Background(), 5*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		return condition(), nil
+	})
+	if err != nil {
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestNewRequiresNameAndReconcile(t *testing.T) {
+	_, informer := podInformer(t, fake.NewClientset())
+	reconcile := func(context.Context, string) error { return nil }
+	if _, err := New(informer, nil, reconcile, Options{}); err == nil {
+		t.Error("New without a name succeeded")
+	}
+	if _, err := New(informer, nil, nil, Options{Name: "pods"}); err == nil {
+		t.Error("New without a reconcile function succeeded")
+	}
+}
+
+func TestReconcilesEveryEvent(t *testing.T) {
+	clientset := fake.NewClientset(pod("web"))
+	factory, informer := podInformer(t, clientset)
+	var log keyLog
+	c, err := New(informer, nil, func(ctx context.Context, key string) error {
+		log.add(key)
+		return nil
+	}, Options{Name: "pods", Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, factory, c)
+
+	// The initial list is queued before Run starts the workers
+	waitFor(t, "the existing pod", func() bool { return log.count("default/web") == 1 })
+	if _, err := clientset.CoreV1().Pods("default").Create(context.Background(), pod("db"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the created pod", func() bool { return log.count("default/db") == 1 })
+	if err := clientset.CoreV1().Pods("default").Delete(context.Background(), "db", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the deleted pod", func() bool { return log.count("default/db") == 2 })
+}
+
+func TestKeyFuncMapsAndFilters(t *testing.T) {
+	factory, informer := podInformer(t, fake.NewClientset(pod("web"), pod("skip-me")))
+	var log keyLog
+	keyFunc := func(obj interface{}) (string, error) {
+		p := obj.(*corev1.Pod)
+		if strings.HasPrefix(p.Name, "skip") {
+			return "", nil
+		}
+		return "owner-of/" + p.Name, nil
+	}
+	c, err := New(informer, keyFunc, func(ctx context.Context, key string) error {
+		log.add(key)
+		return nil
+	}, Options{Name: "pods"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, factory, c)
+
+	c.Enqueue("manual/key")
+	waitFor(t, "the mapped and the enqueued key", func() bool {
+		return log.count("owner-of/web") == 1 && log.count("manual/key") == 1
+	})
+	if log.count("owner-of/skip-me") != 0 {
+		t.Error("an empty key was reconciled")
+	}
+}
+
+func TestFailingKeyIsRetriedThenDropped(t *testing.T) {
+	factory, informer := podInformer(t, fake.NewClientset(pod("web")))
+	var log keyLog
+	out := &testutil.SyncBuffer{}
+	c, err := New(informer, nil, func(ctx context.Context, key string) error {
+		log.add(key)
+		return errors.New("boom")
+	}, Options{
+		Name:        "pods",
+		MaxRetries:  2,
+		RateLimiter: workqueue.NewTypedItemExponentialFailureRateLimiter[string](time.Millisecond, 10*time.Millisecond),
+		Out:         out,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, factory, c)
+
+	// The first attempt and two retries
+	waitFor(t, "the key to be dropped", func() bool { return c.dropped.Load() == 1 })
+	if n := log.count("default/web"); n != 3 {
+		t.Errorf("reconciled %d times, want 3", n)
+	}
+	want := "[pods] Failed to reconcile default/web, requeuing: boom\n" +
+		"[pods] Failed to reconcile default/web, requeuing: boom\n" +
+		"[pods] Dropping default/web after 2 retries: boom\n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestRunDrainsRunningReconciles(t *testing.T) {
+	factory, informer := podInformer(t, fake.NewClientset(pod("web")))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished bool
+	c, err := New(informer, nil, func(ctx context.Context, key string) error {
+		close(started)
+		<-release
+		finished = true
+		return nil
+	}, Options{Name: "pods"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer factory.Shutdown()
+	factory.Start(ctx.Done())
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	<-started
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("Run returned while a reconcile was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !finished {
+		t.Error("reconcile did not finish before Run returned")
+	}
+}
+
+func TestRunFailsWhenCancelledBeforeSync(t *testing.T) {
+	// The informer is never started, so it cannot sync
+	_, informer := podInformer(t, fake.NewClientset())
+	c, err := New(informer, nil, func(context.Context, string) error { return nil }, Options{Name: "pods"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Run(ctx); err == nil || !strings.Contains(err.Error(), "cache did not sync") {
+		t.Errorf("Run = %v, want a sync error", err)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	factory, informer := podInformer(t, fake.NewClientset(pod("web"), pod("db")))
+	c, err := New(informer, nil, func(ctx context.Context, key string) error {
+		if key == "default/db" {
+			return errors.New("boom")
+		}
+		return nil
+	}, Options{Name: "pods", MaxRetries: 1, RateLimiter: workqueue.NewTypedItemFastSlowRateLimiter[string](time.Millisecond, time.Millisecond, 0), Out: &testutil.SyncBuffer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, factory, c)
+	waitFor(t, "the failing key to be dropped", func() bool { return c.dropped.Load() == 1 })
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	got := rec.Body.String()
+	for _, line := range []string{
+		`controller_reconcile_total{controller="pods",result="success"} 1`,
+		`controller_reconcile_total{controller="pods",result="error"} 2`,
+		`controller_reconcile_dropped_total{controller="pods"} 1`,
+		`controller_reconcile_duration_seconds_count{controller="pods"} 3`,
+		`controller_queue_depth{controller="pods"} 0`,
+		`controller_active_workers{controller="pods"} 0`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WriteMetrics writes the reconcile statistics of controllers in the
+// Prometheus text format, one series per controller. Pass it to
+// informermetrics' AddCollector to serve them next to the cache statistics.
+func WriteMetrics(w io.Writer, controllers ...*Controller) {
+	fmt.Fprintln(w, "# HELP controller_reconcile_total Reconciles finished, by result.")
+	fmt.Fprintln(w, "# TYPE controller_reconcile_total counter")
+	for _, c := range controllers {
+		fmt.Fprintf(w, "controller_reconcile_total{controller=%q,result=\"success\"} %d\n", c.name, c.succeeded.Load())
+		fmt.Fprintf(w, "controller_reconcile_total{controller=%q,result=\"error\"} %d\n", c.name, c.failed.Load())
+	}
+
+	fmt.Fprintln(w, "# HELP controller_reconcile_dropped_total Keys dropped after MaxRetries failed retries.")
+	fmt.Fprintln(w, "# TYPE controller_reconcile_dropped_total counter")
+	for _, c := range controllers {
+		fmt.Fprintf(w, "controller_reconcile_dropped_total{controller=%q} %d\n", c.name, c.dropped.Load())
+	}
+
+	fmt.Fprintln(w, "# HELP controller_reconcile_duration_seconds Time spent in the reconcile function.")
+	fmt.Fprintln(w, "# TYPE controller_reconcile_duration_seconds summary")
+	for _, c := range controllers {
+		seconds := time.Duration(c.reconcileNanos.Load()).Seconds()
+		fmt.Fprintf(w, "controller_reconcile_duration_seconds_sum{controller=%q} %g\n", c.name, seconds)
+		fmt.Fprintf(w, "controller_reconcile_duration_seconds_count{controller=%q} %d\n", c.name, c.succeeded.Load()+c.failed.Load())
+	}
+
+	fmt.Fprintln(w, "# HELP controller_queue_depth Keys waiting in the workqueue.")
+	fmt.Fprintln(w, "# TYPE controller_queue_depth gauge")
+	for _, c := range controllers {
+		fmt.Fprintf(w, "controller_queue_depth{controller=%q} %d\n", c.name, c.queue.Len())
+	}
+
+	fmt.Fprintln(w, "# HELP controller_active_workers Workers reconciling a key right now.")
+	fmt.Fprintln(w, "# TYPE controller_active_workers gauge")
+	for _, c := range controllers {
+		fmt.Fprintf(w, "controller_active_workers{controller=%q} %d\n", c.name, c.busy.Load())
+	}
+}
+
+// ServeHTTP writes the controller's metrics, for an example that serves no
+// other /metrics
+func (c *Controller) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	WriteMetrics(w, c)
+}
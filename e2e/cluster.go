@@ -0,0 +1,113 @@
+// Package e2e runs the examples end-to-end against a kind cluster.
+//
+// Setup creates the cluster (or reuses one from E2E_KUBECONFIG), Deploy
+// creates the objects the examples expect to find, and Run builds an example
+// and runs it until its output shows what it is supposed to do. Every example
+// reads $HOME/.kube/config by default, so examples are pointed at the cluster
+// by running them with HOME set to a directory holding its kubeconfig.
+package e2e
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cmd"
+)
+
+// Environment variables that change how the harness manages the cluster
+const (
+	// kubeconfigEnv points at an existing cluster; no kind cluster is created
+	kubeconfigEnv = "E2E_KUBECONFIG"
+	// keepClusterEnv leaves the kind cluster running after the tests, for debugging
+	keepClusterEnv = "E2E_KEEP_CLUSTER"
+)
+
+// Cluster is a cluster the examples run against
+type Cluster struct {
+	// Name is the kind cluster name
+	Name string
+	// Home is used as $HOME for the examples; it holds .kube/config
+	Home      string
+	Clientset kubernetes.Interface
+
+	workDir  string
+	provider *cluster.Provider
+	// created is false when the cluster came from E2E_KUBECONFIG
+	created bool
+}
+
+// Kubeconfig returns the path of the cluster's kubeconfig file
+func (c *Cluster) Kubeconfig() string {
+	return filepath.Join(c.Home, ".kube", "config")
+}
+
+// Setup creates a kind cluster named name, or reuses the cluster in
+// E2E_KUBECONFIG, and waits until its nodes are ready
+func Setup(name string) (*Cluster, error) {
+	workDir, err := os.MkdirTemp("", "client-go-e2e-")
+	if err != nil {
+		return nil, err
+	}
+	c := &Cluster{Name: name, Home: filepath.Join(workDir, "home"), workDir: workDir}
+	if err := os.MkdirAll(filepath.Dir(c.Kubeconfig()), 0o700); err != nil {
+		return nil, err
+	}
+
+	if existing := os.Getenv(kubeconfigEnv); existing != "" {
+		data, err := os.ReadFile(existing)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", kubeconfigEnv, err)
+		}
+		if err := os.WriteFile(c.Kubeconfig(), data, 0o600); err != nil {
+			return nil, err
+		}
+	} else {
+		c.provider = cluster.NewProvider(cluster.ProviderWithLogger(cmd.NewLogger()))
+		fmt.Printf("Creating kind cluster %s...\n", name)
+		err := c.provider.Create(name,
+			cluster.CreateWithKubeconfigPath(c.Kubeconfig()),
+			cluster.CreateWithWaitForReady(3*time.Minute),
+			cluster.CreateWithDisplayUsage(false),
+			cluster.CreateWithDisplaySalutation(false),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create kind cluster: %w", err)
+		}
+		c.created = true
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", c.Kubeconfig())
+	if err != nil {
+		c.Teardown()
+		return nil, err
+	}
+	c.Clientset, err = kubernetes.NewForConfig(config)
+	if err != nil {
+		c.Teardown()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Teardown deletes the kind cluster unless E2E_KEEP_CLUSTER is set, and
+// removes the built examples. A reused cluster is never deleted.
+func (c *Cluster) Teardown() {
+	if c.created {
+		if os.Getenv(keepClusterEnv) != "" {
+			fmt.Printf("Keeping kind cluster %s: kind delete cluster --name %s\n", c.Name, c.Name)
+			// The kubeconfig lives in the work directory, so keep that too
+			fmt.Printf("Kubeconfig: %s\n", c.Kubeconfig())
+			return
+		}
+		fmt.Printf("Deleting kind cluster %s...\n", c.Name)
+		if err := c.provider.Delete(c.Name, c.Kubeconfig()); err != nil {
+			fmt.Printf("Failed to delete kind cluster %s: %v\n", c.Name, err)
+		}
+	}
+	os.RemoveAll(c.workDir)
+}
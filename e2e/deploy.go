@@ -0,0 +1,53 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// NginxDeployment is the prerequisite workload. Its pods carry app=nginx,
+// the label the lister and index examples query for.
+const NginxDeployment = "nginx"
+
+// Deploy creates the objects the examples expect and waits for their pods to run
+func (c *Cluster) Deploy(ctx context.Context) error {
+	replicas := int32(2)
+	podLabels := map[string]string{"app": "nginx"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: NginxDeployment},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "nginx", Image: "nginx:1.21"}},
+				},
+			},
+		},
+	}
+	_, err := c.Clientset.AppsV1().Deployments("default").Create(ctx, deployment, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create deployment %s: %w", NginxDeployment, err)
+	}
+
+	fmt.Printf("Waiting for deployment %s to become available...\n", NginxDeployment)
+	err = wait.PollUntilContextTimeout(ctx, 2*time.Second, 3*time.Minute, true, func(ctx context.Context) (bool, error) {
+		d, err := c.Clientset.AppsV1().Deployments("default").Get(ctx, NginxDeployment, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return d.Status.AvailableReplicas == replicas, nil
+	})
+	if err != nil {
+		return fmt.Errorf("deployment %s never became available: %w", NginxDeployment, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var testCluster *Cluster
+
+func TestMain(m *testing.M) {
+	c, err := Setup("client-go-e2e")
+	if err != nil {
+		fmt.Printf("Setup failed: %v\n", err)
+		os.Exit(1)
+	}
+	if err := c.Deploy(context.TODO()); err != nil {
+		fmt.Printf("Deploy failed: %v\n", err)
+		c.Teardown()
+		os.Exit(1)
+	}
+	testCluster = c
+	code := m.Run()
+	c.Teardown()
+	os.Exit(code)
+}
+
+func TestExamples(t *testing.T) {
+	// 02 creates this Deployment and fails if it exists, e.g. on a reused cluster
+	err := testCluster.Clientset.AppsV1().Deployments("default").Delete(context.TODO(), "nginx-deployment", metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		t.Fatalf("delete nginx-deployment: %v", err)
+	}
+
+	tests := []struct {
+		example  string
+		timeout  time.Duration
+		patterns []string
+	}{
+		{"01_starter/k8s-api-access", time.Minute, []string{`^Connected to external cluster: https://`, `^Pod Name: nginx-`}},
+		{"02_deployment_using_client_go", time.Minute, []string{`^Successfully created deployment: nginx-deployment$`}},
+		{"03_without_informer", time.Minute, []string{`^nginx-[a-z0-9]+-[a-z0-9]+: Running$`, `^---$`}},
+		{"04_informer_events", time.Minute, []string{`^\(\+\) Pod added: default/nginx-`, `^\[SECOND-CONTROLLER\] Also saw pod: default/nginx-`}},
+		{"05_informer_index", time.Minute, []string{`^Pods in default namespace: [1-9]`}},
+		// The resync period is 30s
+		{"06_Resync", 2 * time.Minute, []string{`^\(\+\) Pod added: default/nginx-`, `^Resync event \(same ResourceVersion\)$`}},
+		{"07_shared_informer_factory", time.Minute, []string{`^\[Monitor\] Pod added: nginx-`, `^\[Manager\] Deployment added: nginx$`}},
+		{"08_shared_informer_factory_lister", time.Minute, []string{`^Pods in default namespace: [1-9]`, `^Found pod: `}},
+		{"09_shared_informer_factory_custom_index", time.Minute, []string{`^Pods on node '.+': [1-9]`, `^Running pods: [1-9]`}},
+		{"10_shared_informer_factory_complete", time.Minute, []string{`^Nginx pods: [1-9]`}},
+		{"11_shared_informer_factory_with_options", time.Minute, []string{`^Created 6 informer factories$`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.example, func(t *testing.T) {
+			output, err := testCluster.Run(context.TODO(), tt.example, tt.timeout, nil, tt.patterns...)
+			if err != nil {
+				t.Fatalf("%v\noutput:\n%s", err, output)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package e2e
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// repoRoot is the directory holding the numbered examples, relative to this module
+const repoRoot = ".."
+
+// Build compiles an example directory into the cluster's work directory and
+// returns the binary. The examples are built with the caller's environment,
+// so the module and build caches under the real $HOME are used.
+func (c *Cluster) Build(ctx context.Context, example string) (string, error) {
+	binary := filepath.Join(c.workDir, "bin", filepath.Base(example))
+	build := exec.CommandContext(ctx, "go", "build", "-o", binary, ".")
+	build.Dir = filepath.Join(repoRoot, example)
+	if out, err := build.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("build %s: %w\n%s", example, err, out)
+	}
+	return binary, nil
+}
+
+// Run builds an example and runs it against the cluster until every pattern
+// has matched a line of its output, then stops it. Examples that exit on
+// their own must match before exiting. The combined output is returned either way.
+func (c *Cluster) Run(ctx context.Context, example string, timeout time.Duration, args []string, patterns ...string) (string, error) {
+	binary, err := c.Build(ctx, example)
+	if err != nil {
+		return "", err
+	}
+	expect := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		expect[i] = regexp.MustCompile(pattern)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	run := exec.CommandContext(ctx, binary, args...)
+	run.Dir = filepath.Join(repoRoot, example)
+	run.Env = append(os.Environ(), "HOME="+c.Home)
+	stdout, err := run.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	run.Stderr = run.Stdout
+	if err := run.Start(); err != nil {
+		return "", err
+	}
+
+	var mu sync.Mutex
+	var output strings.Builder
+	matched := make([]bool, len(expect))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanLines(stdout, func(line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			output.WriteString(line + "\n")
+			remaining := 0
+			for i, re := range expect {
+				if !matched[i] && re.MatchString(line) {
+					matched[i] = true
+				}
+				if !matched[i] {
+					remaining++
+				}
+			}
+			if remaining == 0 {
+				// Informer examples never exit; everything expected has been seen
+				cancel()
+			}
+		})
+	}()
+	<-done
+	waitErr := run.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	var missing []string
+	for i, ok := range matched {
+		if !ok {
+			missing = append(missing, patterns[i])
+		}
+	}
+	if len(missing) > 0 {
+		if waitErr != nil && ctx.Err() == nil {
+			return output.String(), fmt.Errorf("%s exited (%v) before printing %q", example, waitErr, missing)
+		}
+		return output.String(), fmt.Errorf("%s did not print %q within %v", example, missing, timeout)
+	}
+	return output.String(), nil
+}
+
+// scanLines calls fn for every line read from r until it is closed
+func scanLines(r io.Reader, fn func(string)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+}
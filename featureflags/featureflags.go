@@ -0,0 +1,113 @@
+// Package featureflags reads feature flags from a ConfigMap, so a controller
+// can turn behavior on and off while it runs:
+//
+//	kubectl create configmap feature-flags --from-literal=verbose=true
+//
+// It builds on hotreload: a Watcher keeps the ConfigMap's data current, and
+// the typed getters parse a value each time they are called. A flag that is
+// missing or does not parse returns the default the caller passed, so a
+// typo never breaks the controller.
+package featureflags
+
+import (
+	"context"
+	"maps"
+	"strconv"
+	"sync/atomic"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/shamimice03/mastering-k8s-client-go/hotreload"
+)
+
+// Flags holds the data of the flags ConfigMap. A nil *Flags returns every
+// default, so a controller can run without a flags ConfigMap.
+type Flags struct {
+	watcher *hotreload.Watcher
+	values  atomic.Pointer[map[string]string]
+}
+
+// New returns Flags backed by the ConfigMap namespace/name. Until Run has
+// seen the ConfigMap, every getter returns its default.
+func New(clientset kubernetes.Interface, namespace, name string) *Flags {
+	f := &Flags{watcher: hotreload.NewWatcher(clientset, hotreload.ConfigMap, namespace, name)}
+	f.watcher.AddHandler(hotreload.HandlerFunc(func(data map[string][]byte, checksum string) error {
+		values := make(map[string]string, len(data))
+		for k, v := range data {
+			values[k] = string(v)
+		}
+		f.values.Store(&values)
+		return nil
+	}))
+	return f
+}
+
+// AddHandler adds a handler that runs after the flags changed, for example
+// to print them. The getters already return the new values then.
+func (f *Flags) AddHandler(h hotreload.Handler) {
+	f.watcher.AddHandler(h)
+}
+
+// Run watches the ConfigMap until ctx is cancelled
+func (f *Flags) Run(ctx context.Context) error {
+	return f.watcher.Run(ctx)
+}
+
+// All returns a copy of every flag as it is set in the ConfigMap
+func (f *Flags) All() map[string]string {
+	if f == nil {
+		return map[string]string{}
+	}
+	values := f.values.Load()
+	if values == nil {
+		return map[string]string{}
+	}
+	return maps.Clone(*values)
+}
+
+func (f *Flags) lookup(name string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	values := f.values.Load()
+	if values == nil {
+		return "", false
+	}
+	v, ok := (*values)[name]
+	return v, ok
+}
+
+// Bool returns the flag parsed with strconv.ParseBool, or def
+func (f *Flags) Bool(name string, def bool) bool {
+	v, ok := f.lookup(name)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// String returns the flag, or def when it is not set
+func (f *Flags) String(name string, def string) string {
+	v, ok := f.lookup(name)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// Int returns the flag parsed with strconv.Atoi, or def
+func (f *Flags) Int(name string, def int) int {
+	v, ok := f.lookup(name)
+	if !ok {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+	return i
+}
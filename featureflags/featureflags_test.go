@@ -0,0 +1,105 @@
+package featureflags
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/shamimice03/mastering-k8s-client-go/hotreload"
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func flagsConfigMap(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "feature-flags", Namespace: "default"},
+		Data:       data,
+	}
+}
+
+func startFlags(t *testing.T, f *Flags) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- f.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, testutil.Timeout, true,
+		func(ctx context.Context) (bool, error) { return condition(), nil })
+	if err != nil {
+		t.Fatal("condition not met")
+	}
+}
+
+func TestNilFlagsReturnDefaults(t *testing.T) {
+	var f *Flags
+	if !f.Bool("verbose", true) || f.String("mode", "fast") != "fast" || f.Int("workers", 3) != 3 || len(f.All()) != 0 {
+		t.Error("nil Flags did not return the defaults")
+	}
+}
+
+func TestGetters(t *testing.T) {
+	h := testutil.NewHarness(t, flagsConfigMap(map[string]string{
+		"verbose": "true",
+		"mode":    "safe",
+		"workers": "4",
+		"dry-run": "maybe",
+		"retries": "many",
+	}))
+	f := New(h.Clientset, "default", "feature-flags")
+	startFlags(t, f)
+	waitFor(t, func() bool { return len(f.All()) == 5 })
+
+	if !f.Bool("verbose", false) {
+		t.Error("verbose = false, want true")
+	}
+	if got := f.String("mode", "fast"); got != "safe" {
+		t.Errorf("mode = %q, want safe", got)
+	}
+	if got := f.Int("workers", 1); got != 4 {
+		t.Errorf("workers = %d, want 4", got)
+	}
+	// Values that do not parse, and missing flags, return the default
+	if f.Bool("dry-run", true) != true || f.Int("retries", 2) != 2 || f.String("missing", "x") != "x" {
+		t.Error("invalid or missing flags did not return the default")
+	}
+}
+
+func TestFlagsChangeAtRuntime(t *testing.T) {
+	h := testutil.NewHarness(t)
+	f := New(h.Clientset, "default", "feature-flags")
+	changes := make(chan string, 10)
+	f.AddHandler(hotreload.HandlerFunc(func(data map[string][]byte, checksum string) error {
+		// Handlers run after the getters were updated
+		changes <- f.String("verbose", "")
+		return nil
+	}))
+	startFlags(t, f)
+
+	h.WaitForWatchOf(&corev1.ConfigMap{}, 1)
+	if f.Bool("verbose", false) {
+		t.Error("verbose set before the ConfigMap exists")
+	}
+	h.Add(flagsConfigMap(map[string]string{"verbose": "true"}))
+	if got := <-changes; got != "true" {
+		t.Errorf("handler saw verbose=%q, want true", got)
+	}
+	h.Update(flagsConfigMap(map[string]string{"verbose": "false"}))
+	if got := <-changes; got != "false" {
+		t.Errorf("handler saw verbose=%q, want false", got)
+	}
+	if f.Bool("verbose", true) {
+		t.Error("verbose still true after the update")
+	}
+}
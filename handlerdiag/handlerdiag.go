@@ -0,0 +1,238 @@
+// Package handlerdiag shows which event handlers of a shared informer fall
+// behind, and why.
+package handlerdiag
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// The shared informer gives every handler its own goroutine and an unbounded
+// buffer, so a slow handler never blocks the others, it just falls behind. That
+// buffer is internal to client-go and cannot be inspected. An instrumented
+// handler therefore takes over the buffering: it returns to the informer
+// immediately, queues the event itself and runs the real handler on its own
+// goroutine, where queue depth, queueing delay and processing time are visible.
+
+// notification is one event waiting for the wrapped handler
+type notification struct {
+	kind          string // "add", "update" or "delete"
+	oldObj, obj   interface{}
+	isInitialList bool
+	queuedAt      time.Time
+}
+
+// handlerStats summarizes one handler since the last report
+type handlerStats struct {
+	Events    int
+	TotalTime time.Duration
+	MaxTime   time.Duration
+	MaxDelay  time.Duration
+	Queued    int
+	PeakQueue int
+}
+
+// instrumentedHandler wraps a cache.ResourceEventHandler with its own queue
+type instrumentedHandler struct {
+	name    string
+	handler cache.ResourceEventHandler
+	diag    *Diagnostics
+
+	mu      sync.Mutex
+	pending []notification
+	// wake has room for one signal; the worker drains the whole queue per signal
+	wake  chan struct{}
+	stats handlerStats
+	// warnAt is the next queue depth that logs a warning; it doubles each time
+	// so a handler that stays behind does not flood the log
+	warnAt int
+}
+
+func (h *instrumentedHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	h.push(notification{kind: "add", obj: obj, isInitialList: isInInitialList})
+}
+
+func (h *instrumentedHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.push(notification{kind: "update", oldObj: oldObj, obj: newObj})
+}
+
+func (h *instrumentedHandler) OnDelete(obj interface{}) {
+	h.push(notification{kind: "delete", obj: obj})
+}
+
+func (h *instrumentedHandler) push(n notification) {
+	n.queuedAt = time.Now()
+	h.mu.Lock()
+	h.pending = append(h.pending, n)
+	depth := len(h.pending)
+	h.stats.PeakQueue = max(h.stats.PeakQueue, depth)
+	warn := depth >= h.warnAt
+	if warn {
+		h.warnAt *= 2
+	}
+	oldest := n.queuedAt.Sub(h.pending[0].queuedAt)
+	h.mu.Unlock()
+
+	if warn {
+		fmt.Printf("[Diagnostics] WARNING %s is falling behind: %d events queued, oldest waiting %v\n",
+			h.name, depth, oldest.Round(time.Millisecond))
+	}
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers queued events to the wrapped handler, in order, until stopCh is closed
+func (h *instrumentedHandler) run(stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-h.wake:
+		}
+		for {
+			h.mu.Lock()
+			if len(h.pending) == 0 {
+				// Caught up: re-arm the warning at the configured threshold
+				h.warnAt = h.diag.maxBacklog
+				h.mu.Unlock()
+				break
+			}
+			n := h.pending[0]
+			h.pending[0] = notification{}
+			h.pending = h.pending[1:]
+			h.mu.Unlock()
+
+			h.deliver(n)
+		}
+	}
+}
+
+// deliver calls the wrapped handler and records how long the event waited and ran
+func (h *instrumentedHandler) deliver(n notification) {
+	start := time.Now()
+	switch n.kind {
+	case "add":
+		h.handler.OnAdd(n.obj, n.isInitialList)
+	case "update":
+		h.handler.OnUpdate(n.oldObj, n.obj)
+	case "delete":
+		h.handler.OnDelete(n.obj)
+	}
+	elapsed := time.Since(start)
+	delay := start.Sub(n.queuedAt)
+
+	h.mu.Lock()
+	h.stats.Events++
+	h.stats.TotalTime += elapsed
+	h.stats.MaxTime = max(h.stats.MaxTime, elapsed)
+	h.stats.MaxDelay = max(h.stats.MaxDelay, delay)
+	h.mu.Unlock()
+
+	if elapsed >= h.diag.slowEvent {
+		key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(n.obj)
+		fmt.Printf("[Diagnostics] WARNING %s took %v for %s %s\n", h.name, elapsed.Round(time.Millisecond), n.kind, key)
+	}
+}
+
+// snapshot returns the stats since the last snapshot and starts a new period
+func (h *instrumentedHandler) snapshot() handlerStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := h.stats
+	stats.Queued = len(h.pending)
+	h.stats = handlerStats{PeakQueue: len(h.pending)}
+	return stats
+}
+
+// Diagnostics owns every instrumented handler of the program
+type Diagnostics struct {
+	// slowEvent is the processing time that logs a warning for a single event
+	slowEvent time.Duration
+	// maxBacklog is the queue depth that logs a falling-behind warning
+	maxBacklog int
+
+	mu       sync.Mutex
+	handlers []*instrumentedHandler
+	// stopCh is set by Run; handlers instrumented later start right away
+	stopCh <-chan struct{}
+}
+
+// New returns diagnostics that warn when one event takes slowEvent or longer,
+// and when maxBacklog events are queued for one handler
+func New(slowEvent time.Duration, maxBacklog int) *Diagnostics {
+	return &Diagnostics{slowEvent: slowEvent, maxBacklog: max(maxBacklog, 1)}
+}
+
+// Instrument wraps handler under a name used in warnings and reports
+func (d *Diagnostics) Instrument(name string, handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	h := &instrumentedHandler{
+		name:    name,
+		handler: handler,
+		diag:    d,
+		wake:    make(chan struct{}, 1),
+		warnAt:  d.maxBacklog,
+	}
+	d.mu.Lock()
+	d.handlers = append(d.handlers, h)
+	if d.stopCh != nil {
+		go h.run(d.stopCh)
+	}
+	d.mu.Unlock()
+	return h
+}
+
+// Run starts a worker per instrumented handler. Events arriving before Run are
+// queued, not lost. A report is printed every interval when it is positive.
+func (d *Diagnostics) Run(interval time.Duration, stopCh <-chan struct{}) {
+	d.mu.Lock()
+	d.stopCh = stopCh
+	for _, h := range d.handlers {
+		go h.run(stopCh)
+		// Deliver anything queued before the worker started
+		select {
+		case h.wake <- struct{}{}:
+		default:
+		}
+	}
+	d.mu.Unlock()
+
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				d.Report()
+			}
+		}
+	}()
+}
+
+// Report prints one line per handler for the period since the last report
+func (d *Diagnostics) Report() {
+	d.mu.Lock()
+	handlers := append([]*instrumentedHandler(nil), d.handlers...)
+	d.mu.Unlock()
+	sort.Slice(handlers, func(i, j int) bool { return handlers[i].name < handlers[j].name })
+
+	for _, h := range handlers {
+		s := h.snapshot()
+		var avg time.Duration
+		if s.Events > 0 {
+			avg = s.TotalTime / time.Duration(s.Events)
+		}
+		fmt.Printf("[Diagnostics] %s: %d events, avg %v, max %v, max queueing delay %v, queued %d (peak %d)\n",
+			h.name, s.Events, avg, s.MaxTime, s.MaxDelay.Round(time.Microsecond), s.Queued, s.PeakQueue)
+	}
+}
@@ -0,0 +1,106 @@
+package handlerdiag
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+func testPod(name string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+}
+
+func TestSlowEventIsReported(t *testing.T) {
+	output := testutil.CaptureOutput(t)
+	diag := New(10*time.Millisecond, 100)
+	handler := diag.Instrument("Sleepy", cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) { time.Sleep(20 * time.Millisecond) },
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	diag.Run(0, stopCh)
+
+	handler.OnAdd(testPod("fast"), false)
+	handler.OnUpdate(testPod("slow"), testPod("slow"))
+	testutil.WaitForOutput(t, output, "[Diagnostics] WARNING Sleepy took ", " for update default/slow\n")
+	if strings.Contains(output(), "default/fast") {
+		t.Errorf("a fast event was reported:\n%s", output())
+	}
+}
+
+func TestBacklogWarningsDoubleAndRearm(t *testing.T) {
+	output := testutil.CaptureOutput(t)
+	diag := New(time.Second, 10)
+	release := make(chan struct{})
+	stuck := &testutil.Recorder{Gate: release}
+	handler := diag.Instrument("Stuck", stuck)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	diag.Run(0, stopCh)
+
+	// The informer is never blocked: every call returns while the handler hangs
+	var want []string
+	for i := 0; i < 45; i++ {
+		pod := testPod(fmt.Sprintf("pod-%02d", i))
+		want = append(want, "add "+testutil.Key(pod))
+		handler.OnAdd(pod, false)
+	}
+
+	// The first event is with the handler, the rest are queued; warnings fire
+	// at 10, 20 and 40 queued events rather than on every event
+	for _, depth := range []int{10, 20, 40} {
+		testutil.WaitForOutput(t, output, fmt.Sprintf("WARNING Stuck is falling behind: %d events queued", depth))
+	}
+	if n := strings.Count(output(), "falling behind"); n != 3 {
+		t.Errorf("got %d backlog warnings, want 3:\n%s", n, output())
+	}
+
+	close(release)
+	stuck.Expect(t, want...)
+
+	// Caught up, the threshold is back at 10
+	for i := 0; i < 10; i++ {
+		handler.OnAdd(testPod(fmt.Sprintf("again-%02d", i)), false)
+	}
+	stuck.WaitForCount(t, 55)
+	if n := strings.Count(output(), "falling behind"); n > 4 {
+		t.Errorf("got %d backlog warnings after catching up, want at most 4", n)
+	}
+}
+
+func TestReportResetsEachPeriod(t *testing.T) {
+	output := testutil.CaptureOutput(t)
+	diag := New(time.Second, 100)
+	recorder := &testutil.Recorder{}
+	handler := diag.Instrument("PodMonitor", recorder)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	diag.Run(0, stopCh)
+
+	handler.OnAdd(testPod("a"), true)
+	handler.OnDelete(testPod("a"))
+	recorder.Expect(t, "add default/a", "delete default/a")
+
+	diag.Report()
+	testutil.WaitForOutput(t, output, "[Diagnostics] PodMonitor: 2 events,", "queued 0 (peak ")
+	diag.Report()
+	testutil.WaitForOutput(t, output, "[Diagnostics] PodMonitor: 0 events,")
+}
+
+func TestHandlerInstrumentedAfterRunStarts(t *testing.T) {
+	diag := New(time.Second, 100)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	diag.Run(0, stopCh)
+
+	recorder := &testutil.Recorder{}
+	diag.Instrument("Late", recorder).OnAdd(testPod("a"), false)
+	recorder.Expect(t, "add default/a")
+}
@@ -0,0 +1,154 @@
+// Package healthz serves the /healthz and /readyz endpoints a liveness and a
+// readiness probe need, so the examples can run as pods.
+//
+// /healthz answers as long as the process does. /readyz runs every registered
+// readiness check: the informers must have synced, and the workqueues must
+// not be backed up beyond a threshold.
+package healthz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// Check reports why a component is not ready, or nil when it is
+type Check func() error
+
+// Server holds the readiness checks and serves both endpoints
+type Server struct {
+	mu     sync.Mutex
+	checks []namedCheck
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// New returns a Server without readiness checks, which is always ready
+func New() *Server {
+	return &Server{}
+}
+
+// AddReadyCheck makes /readyz fail while check returns an error
+func (s *Server) AddReadyCheck(name string, check Check) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks = append(s.checks, namedCheck{name: name, check: check})
+}
+
+// Handler returns a handler serving /healthz and /readyz
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	mux.HandleFunc("/readyz", s.serveReadyz)
+	return mux
+}
+
+// serveReadyz lists every check the way the API server's /readyz?verbose does,
+// and answers 503 if any of them failed
+func (s *Server) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	checks := append([]namedCheck(nil), s.checks...)
+	s.mu.Unlock()
+
+	var body strings.Builder
+	failed := false
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			failed = true
+			fmt.Fprintf(&body, "[-]%s failed: %v\n", c.name, err)
+			continue
+		}
+		fmt.Fprintf(&body, "[+]%s ok\n", c.name)
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if failed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		body.WriteString("readyz check failed\n")
+	} else {
+		body.WriteString("readyz check passed\n")
+	}
+	w.Write([]byte(body.String()))
+}
+
+// Serve serves both endpoints on addr until ctx is cancelled
+func (s *Server) Serve(ctx context.Context, addr string) error {
+	server := &http.Server{Addr: addr, Handler: s.Handler()}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// InformersSynced fails until every informer has synced
+func InformersSynced(synced ...cache.InformerSynced) Check {
+	return func() error {
+		for _, hasSynced := range synced {
+			if !hasSynced() {
+				return errors.New("informer cache not synced")
+			}
+		}
+		return nil
+	}
+}
+
+// startedFactory is satisfied by both informers.SharedInformerFactory, keyed
+// by object type, and dynamicinformer.DynamicSharedInformerFactory, keyed by
+// GroupVersionResource
+type startedFactory[K comparable] interface {
+	WaitForCacheSync(stopCh <-chan struct{}) map[K]bool
+}
+
+// closed makes WaitForCacheSync check each informer once instead of waiting
+var closed = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// FactorySynced fails until every informer the factory has started has
+// synced, and while it has started none yet. Informers requested from the
+// factory after the check was added are covered as soon as they start.
+func FactorySynced[K comparable](factory startedFactory[K]) Check {
+	return func() error {
+		synced := factory.WaitForCacheSync(closed)
+		if len(synced) == 0 {
+			return errors.New("no informers started")
+		}
+		var pending []string
+		for informer, ok := range synced {
+			if !ok {
+				pending = append(pending, fmt.Sprint(informer))
+			}
+		}
+		if len(pending) > 0 {
+			sort.Strings(pending)
+			return fmt.Errorf("not synced: %s", strings.Join(pending, ", "))
+		}
+		return nil
+	}
+}
+
+// QueueDepth fails while more than max items wait in queue. A controller that
+// falls that far behind should not receive traffic, and during a rollout the
+// new pod should not replace the old one yet.
+func QueueDepth(queue interface{ Len() int }, max int) Check {
+	return func() error {
+		if depth := queue.Len(); depth > max {
+			return fmt.Errorf("%d items queued, more than %d", depth, max)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+package healthz
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func get(t *testing.T, s *Server, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestHealthzIgnoresReadiness(t *testing.T) {
+	s := New()
+	s.AddReadyCheck("never", func() error { return errors.New("not yet") })
+	if code, body := get(t, s, "/healthz"); code != http.StatusOK || body != "ok" {
+		t.Errorf("/healthz = %d %q, want 200 ok", code, body)
+	}
+}
+
+func TestReadyzReportsEveryCheck(t *testing.T) {
+	s := New()
+	ready := errors.New("cache not synced")
+	s.AddReadyCheck("informers", func() error { return ready })
+	s.AddReadyCheck("workqueue", func() error { return nil })
+
+	code, body := get(t, s, "/readyz")
+	want := "[-]informers failed: cache not synced\n[+]workqueue ok\nreadyz check failed\n"
+	if code != http.StatusServiceUnavailable || body != want {
+		t.Errorf("/readyz = %d %q, want 503 %q", code, body, want)
+	}
+
+	ready = nil
+	code, body = get(t, s, "/readyz")
+	want = "[+]informers ok\n[+]workqueue ok\nreadyz check passed\n"
+	if code != http.StatusOK || body != want {
+		t.Errorf("/readyz = %d %q, want 200 %q", code, body, want)
+	}
+}
+
+func TestFactorySynced(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	defer factory.Shutdown()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	check := FactorySynced(factory)
+
+	if err := check(); err == nil || err.Error() != "no informers started" {
+		t.Errorf("before Start: %v", err)
+	}
+	factory.Core().V1().Pods().Informer()
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	if err := check(); err != nil {
+		t.Errorf("after sync: %v", err)
+	}
+
+	// An informer whose LIST keeps failing never syncs
+	clientset.PrependReactor("list", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	factory.Core().V1().ConfigMaps().Informer()
+	factory.Start(stopCh)
+	if err := check(); err == nil || err.Error() != "not synced: *v1.ConfigMap" {
+		t.Errorf("with a failing informer: %v", err)
+	}
+}
+
+func TestQueueDepth(t *testing.T) {
+	queue := workqueue.NewTyped[string]()
+	defer queue.ShutDown()
+	check := QueueDepth(queue, 2)
+
+	queue.Add("a")
+	queue.Add("b")
+	if err := check(); err != nil {
+		t.Errorf("2 items: %v", err)
+	}
+	queue.Add("c")
+	if err := check(); err == nil || !strings.Contains(err.Error(), "3 items queued") {
+		t.Errorf("3 items: %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+// Package hotreload watches one ConfigMap or Secret and hands its data to
+// callbacks whenever the content changes, so an example can pick up new
+// configuration without a restart.
+//
+// The Watcher runs an informer restricted to the object by a field selector
+// and compares a checksum of the data, so resyncs and changes to labels or
+// annotations do not trigger a reload. Config parses the data into an
+// application struct and swaps it atomically; readers never see a half
+// applied configuration.
+package hotreload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Kind is the kind of object a Watcher watches
+type Kind string
+
+const (
+	ConfigMap Kind = "ConfigMap"
+	Secret    Kind = "Secret"
+)
+
+// Handler is called with the data of the watched object whenever its
+// checksum changes. An error keeps the handlers after it from running.
+type Handler interface {
+	OnReload(data map[string][]byte, checksum string) error
+}
+
+// HandlerFunc adapts a function to a Handler
+type HandlerFunc func(data map[string][]byte, checksum string) error
+
+func (f HandlerFunc) OnReload(data map[string][]byte, checksum string) error {
+	return f(data, checksum)
+}
+
+// Watcher calls its handlers with the data of one ConfigMap or Secret
+type Watcher struct {
+	clientset kubernetes.Interface
+	kind      Kind
+	namespace string
+	name      string
+
+	mu       sync.Mutex
+	handlers []Handler
+	checksum string
+}
+
+// NewWatcher returns a Watcher for the object of the given kind, namespace
+// and name. Handlers are added before Run.
+func NewWatcher(clientset kubernetes.Interface, kind Kind, namespace, name string) *Watcher {
+	return &Watcher{clientset: clientset, kind: kind, namespace: namespace, name: name}
+}
+
+// AddHandler appends a handler. Handlers run in the order they were added.
+func (w *Watcher) AddHandler(h Handler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, h)
+}
+
+// Checksum returns the checksum of the data last handed to the handlers, or
+// "" while the object has not been seen
+func (w *Watcher) Checksum() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.checksum
+}
+
+// Run watches the object until ctx is cancelled. The object does not have to
+// exist yet; the handlers run once it is created.
+func (w *Watcher) Run(ctx context.Context) error {
+	// The field selector makes the API server send this one object only,
+	// instead of every ConfigMap or Secret of the namespace
+	selector := fields.OneTermEqualSelector("metadata.name", w.name).String()
+	factory := informers.NewSharedInformerFactoryWithOptions(w.clientset, 0,
+		informers.WithNamespace(w.namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) { opts.FieldSelector = selector }),
+	)
+	var informer cache.SharedIndexInformer
+	switch w.kind {
+	case ConfigMap:
+		informer = factory.Core().V1().ConfigMaps().Informer()
+	case Secret:
+		informer = factory.Core().V1().Secrets().Informer()
+	default:
+		return fmt.Errorf("hotreload: unsupported kind %q", w.kind)
+	}
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { w.update(obj) },
+		UpdateFunc: func(oldObj, newObj interface{}) { w.update(newObj) },
+		DeleteFunc: func(obj interface{}) {
+			utilruntime.HandleError(fmt.Errorf("%s %s/%s was deleted, keeping the last configuration", w.kind, w.namespace, w.name))
+		},
+	}); err != nil {
+		return err
+	}
+
+	defer factory.Shutdown()
+	factory.Start(ctx.Done())
+	// WaitForCacheSync only fails once ctx is cancelled, which ends Run anyway
+	cache.WaitForCacheSync(ctx.Done(), informer.HasSynced)
+	<-ctx.Done()
+	return nil
+}
+
+// update runs the handlers if the object's data changed. Handlers run under
+// the lock, so two reloads never overlap.
+func (w *Watcher) update(obj interface{}) {
+	var data map[string][]byte
+	switch obj := obj.(type) {
+	case *corev1.ConfigMap:
+		if obj.Name != w.name {
+			return
+		}
+		data = configMapData(obj)
+	case *corev1.Secret:
+		if obj.Name != w.name {
+			return
+		}
+		data = obj.Data
+	default:
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sum := Checksum(data)
+	if sum == w.checksum {
+		return
+	}
+	// The checksum is recorded even when a handler fails: the same content
+	// would fail again, and the next edit changes the checksum anyway
+	w.checksum = sum
+	for _, h := range w.handlers {
+		if err := h.OnReload(data, sum); err != nil {
+			utilruntime.HandleError(fmt.Errorf("reloading %s %s/%s: %w", w.kind, w.namespace, w.name, err))
+			return
+		}
+	}
+}
+
+// configMapData merges data and binaryData, whose keys never overlap
+func configMapData(cm *corev1.ConfigMap) map[string][]byte {
+	data := make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+	for k, v := range cm.Data {
+		data[k] = []byte(v)
+	}
+	for k, v := range cm.BinaryData {
+		data[k] = v
+	}
+	return data
+}
+
+// Checksum returns a SHA-256 of the keys and values, independent of map order
+func Checksum(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		// Length prefixes keep {"a": "bc"} and {"ab": "c"} apart
+		fmt.Fprintf(h, "%d:%s%d:", len(k), k, len(data[k]))
+		h.Write(data[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Config holds the configuration parsed from the watched data. It is a
+// Handler: add it to a Watcher, and read the current value with Get.
+type Config[T any] struct {
+	parse   func(data map[string][]byte) (*T, error)
+	current atomic.Pointer[T]
+}
+
+// NewConfig returns a Config that parses the data with parse. Data parse
+// rejects leaves the previous value in place.
+func NewConfig[T any](parse func(data map[string][]byte) (*T, error)) *Config[T] {
+	return &Config[T]{parse: parse}
+}
+
+// Get returns the current value, or nil before the first successful load.
+// The value is replaced, never modified, so callers must not modify it.
+func (c *Config[T]) Get() *T {
+	return c.current.Load()
+}
+
+// OnReload parses the data and swaps in the new value
+func (c *Config[T]) OnReload(data map[string][]byte, checksum string) error {
+	v, err := c.parse(data)
+	if err != nil {
+		return err
+	}
+	c.current.Store(v)
+	return nil
+}
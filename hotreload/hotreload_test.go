@@ -0,0 +1,174 @@
+package hotreload
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+type settings struct {
+	Replicas int
+}
+
+func parseSettings(data map[string][]byte) (*settings, error) {
+	n, err := strconv.Atoi(string(data["replicas"]))
+	if err != nil {
+		return nil, errors.New("replicas is not a number")
+	}
+	return &settings{Replicas: n}, nil
+}
+
+func configMap(name, replicas string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Data:       map[string]string{"replicas": replicas},
+	}
+}
+
+// recorder is a Handler that records the checksums it was called with
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) OnReload(data map[string][]byte, checksum string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, checksum)
+	return nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+func (r *recorder) waitFor(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for r.count() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d reloads, want %d", r.count(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func startWatcher(t *testing.T, w *Watcher) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+}
+
+func TestChecksum(t *testing.T) {
+	a := Checksum(map[string][]byte{"a": []byte("bc"), "d": []byte("e")})
+	if b := Checksum(map[string][]byte{"d": []byte("e"), "a": []byte("bc")}); a != b {
+		t.Errorf("checksum depends on map order")
+	}
+	if b := Checksum(map[string][]byte{"ab": []byte("c"), "d": []byte("e")}); a == b {
+		t.Errorf("different keys and values have the same checksum")
+	}
+}
+
+func TestWatcherReloadsOnDataChanges(t *testing.T) {
+	h := testutil.NewHarness(t, configMap("app", "2"), configMap("other", "9"))
+	config := NewConfig(parseSettings)
+	calls := &recorder{}
+	w := NewWatcher(h.Clientset, ConfigMap, "default", "app")
+	w.AddHandler(config)
+	w.AddHandler(calls)
+	startWatcher(t, w)
+
+	calls.waitFor(t, 1)
+	if got := config.Get(); got == nil || got.Replicas != 2 {
+		t.Fatalf("initial config = %+v, want 2 replicas", got)
+	}
+	h.WaitForWatchOf(&corev1.ConfigMap{}, 1)
+
+	// A label change keeps the checksum and reloads nothing
+	labeled := configMap("app", "2")
+	labeled.Labels = map[string]string{"team": "a"}
+	h.Update(labeled)
+	h.Update(configMap("app", "3"))
+	calls.waitFor(t, 2)
+	if got := config.Get(); got.Replicas != 3 {
+		t.Errorf("config = %+v, want 3 replicas", got)
+	}
+	if calls.count() != 2 {
+		t.Errorf("got %d reloads, want 2", calls.count())
+	}
+	if w.Checksum() != Checksum(map[string][]byte{"replicas": []byte("3")}) {
+		t.Errorf("checksum %s is not the one of the last data", w.Checksum())
+	}
+}
+
+func TestRejectedDataKeepsTheConfig(t *testing.T) {
+	h := testutil.NewHarness(t, configMap("app", "2"))
+	config := NewConfig(parseSettings)
+	calls := &recorder{}
+	w := NewWatcher(h.Clientset, ConfigMap, "default", "app")
+	w.AddHandler(config)
+	w.AddHandler(calls)
+	startWatcher(t, w)
+	calls.waitFor(t, 1)
+	h.WaitForWatchOf(&corev1.ConfigMap{}, 1)
+
+	// The failing parse stops the chain, so calls only sees the fix
+	h.Update(configMap("app", "many"))
+	h.Update(configMap("app", "4"))
+	calls.waitFor(t, 2)
+	if got := config.Get(); got.Replicas != 4 {
+		t.Errorf("config = %+v, want 4 replicas", got)
+	}
+	want := Checksum(map[string][]byte{"replicas": []byte("4")})
+	if calls.calls[1] != want {
+		t.Errorf("second reload had checksum %s, want the fixed data's", calls.calls[1])
+	}
+}
+
+func TestWatcherWaitsForTheSecret(t *testing.T) {
+	h := testutil.NewHarness(t)
+	calls := &recorder{}
+	w := NewWatcher(h.Clientset, Secret, "default", "token")
+	w.AddHandler(HandlerFunc(func(data map[string][]byte, checksum string) error {
+		if string(data["token"]) != "s3cr3t" {
+			t.Errorf("data = %q", data)
+		}
+		return calls.OnReload(data, checksum)
+	}))
+	startWatcher(t, w)
+
+	h.WaitForWatchOf(&corev1.Secret{}, 1)
+	if w.Checksum() != "" {
+		t.Errorf("checksum %q before the Secret exists", w.Checksum())
+	}
+	h.Add(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	})
+	calls.waitFor(t, 1)
+}
+
+func TestUnsupportedKind(t *testing.T) {
+	h := testutil.NewHarness(t)
+	err := NewWatcher(h.Clientset, "Pod", "default", "app").Run(context.Background())
+	if err == nil {
+		t.Error("Run accepted kind Pod")
+	}
+}
@@ -0,0 +1,275 @@
+package httpdebug
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// Access is one kind of request the clients sent, in the terms RBAC
+// authorizes: a verb on a resource in a namespace, or on a non-resource URL
+type Access struct {
+	Verb        string `json:"verb"`
+	APIGroup    string `json:"apiGroup"`
+	Resource    string `json:"resource,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+	// Namespace is empty for a cluster-scoped resource and for a request
+	// across all namespaces
+	Namespace      string `json:"namespace,omitempty"`
+	NonResourceURL string `json:"nonResourceURL,omitempty"`
+	// GrantedByDefault is set for discovery and self reviews, which the
+	// system:discovery and system:basic-user roles allow every user
+	GrantedByDefault bool `json:"grantedByDefault,omitempty"`
+	Count            int  `json:"count"`
+	// Denied counts the 401 and 403 responses
+	Denied int `json:"denied,omitempty"`
+}
+
+// Report is what Recorder.WriteReport writes
+type Report struct {
+	Accesses []Access `json:"accesses"`
+	// ClusterScoped is set when a request went to a cluster-scoped resource,
+	// across all namespaces or to a non-resource URL. Only a ClusterRole can
+	// grant those; otherwise a Role in each of Namespaces is enough.
+	ClusterScoped bool     `json:"clusterScoped"`
+	Namespaces    []string `json:"namespaces"`
+	// Rules grant exactly the accesses that are not granted by default
+	Rules []rbacv1.PolicyRule `json:"rules"`
+}
+
+// selfReviews are the resources system:basic-user lets every user create
+var selfReviews = map[string]bool{
+	"selfsubjectaccessreviews": true,
+	"selfsubjectrulesreviews":  true,
+	"selfsubjectreviews":       true,
+}
+
+// namespaceSubresources follow namespaces/<name>, where any other segment
+// would be a resource inside the namespace
+var namespaceSubresources = map[string]bool{"status": true, "finalize": true}
+
+// Recorder records what the clients of a rest.Config touch. Install it
+// with config.Wrap(recorder.Wrap); it can sit next to the logging
+// transport of Configure.
+type Recorder struct {
+	mu sync.Mutex
+	// accesses is keyed by the access with both counts zero
+	accesses map[Access]*Access
+}
+
+// NewRecorder returns an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{accesses: map[Access]*Access{}}
+}
+
+// Wrap is a function for rest.Config.Wrap
+func (r *Recorder) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &recordingTransport{next: next, recorder: r}
+}
+
+type recordingTransport struct {
+	next     http.RoundTripper
+	recorder *Recorder
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	denied := err == nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden)
+	t.recorder.record(accessOf(req), denied)
+	return resp, err
+}
+
+func (r *Recorder) record(a Access, denied bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recorded, ok := r.accesses[a]
+	if !ok {
+		recorded = &a
+		r.accesses[a] = recorded
+	}
+	recorded.Count++
+	if denied {
+		recorded.Denied++
+	}
+}
+
+// Accesses returns every kind of request sent so far, sorted
+func (r *Recorder) Accesses() []Access {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	accesses := make([]Access, 0, len(r.accesses))
+	for _, a := range r.accesses {
+		accesses = append(accesses, *a)
+	}
+	slices.SortFunc(accesses, func(a, b Access) int {
+		return strings.Compare(sortKey(a), sortKey(b))
+	})
+	return accesses
+}
+
+func sortKey(a Access) string {
+	return strings.Join([]string{a.NonResourceURL, a.APIGroup, a.Resource, a.Subresource, a.Namespace, a.Verb}, "\x00")
+}
+
+// Report summarizes the accesses so far into RBAC rules
+func (r *Recorder) Report() Report {
+	report := Report{Accesses: r.Accesses(), Namespaces: []string{}, Rules: []rbacv1.PolicyRule{}}
+	namespaces := map[string]bool{}
+	// verbs per group and resource, and per non-resource URL
+	type resourceKey struct{ group, resource string }
+	resourceVerbs := map[resourceKey][]string{}
+	urlVerbs := map[string][]string{}
+	for _, a := range report.Accesses {
+		if a.GrantedByDefault {
+			continue
+		}
+		switch {
+		case a.NonResourceURL != "":
+			report.ClusterScoped = true
+			urlVerbs[a.NonResourceURL] = appendUnique(urlVerbs[a.NonResourceURL], a.Verb)
+			continue
+		case a.Namespace == "":
+			report.ClusterScoped = true
+		default:
+			namespaces[a.Namespace] = true
+		}
+		resource := a.Resource
+		if a.Subresource != "" {
+			resource += "/" + a.Subresource
+		}
+		key := resourceKey{a.APIGroup, resource}
+		resourceVerbs[key] = appendUnique(resourceVerbs[key], a.Verb)
+	}
+
+	// One rule per group and set of verbs, as a hand-written role would
+	// group them
+	byGroupAndVerbs := map[string]*rbacv1.PolicyRule{}
+	for key, verbs := range resourceVerbs {
+		slices.Sort(verbs)
+		id := key.group + "\x00" + strings.Join(verbs, ",")
+		rule, ok := byGroupAndVerbs[id]
+		if !ok {
+			rule = &rbacv1.PolicyRule{APIGroups: []string{key.group}, Verbs: verbs}
+			byGroupAndVerbs[id] = rule
+		}
+		rule.Resources = append(rule.Resources, key.resource)
+	}
+	for _, id := range sortedKeys(byGroupAndVerbs) {
+		rule := byGroupAndVerbs[id]
+		slices.Sort(rule.Resources)
+		report.Rules = append(report.Rules, *rule)
+	}
+	for _, url := range sortedKeys(urlVerbs) {
+		verbs := urlVerbs[url]
+		slices.Sort(verbs)
+		report.Rules = append(report.Rules, rbacv1.PolicyRule{NonResourceURLs: []string{url}, Verbs: verbs})
+	}
+	report.Namespaces = sortedKeys(namespaces)
+	return report
+}
+
+// WriteReport writes the report as indented JSON
+func (r *Recorder) WriteReport(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.Report())
+}
+
+// accessOf maps a request to the attributes the API server authorizes,
+// following the rules of its RequestInfoFactory:
+//
+//	/api/v1/namespaces/default/pods/web-0/log -> get pods/log in default
+//	/apis/apps/v1/deployments?watch=true      -> watch deployments.apps
+//	/version                                  -> get on a non-resource URL
+func accessOf(req *http.Request) Access {
+	method := strings.ToLower(req.Method)
+	if method == "head" {
+		method = "get"
+	}
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	var group string
+	switch {
+	case len(parts) >= 3 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) >= 4 && parts[0] == "apis":
+		group, parts = parts[1], parts[3:]
+	default:
+		// Discovery, /version, /healthz and the like
+		return Access{Verb: method, NonResourceURL: req.URL.Path, GrantedByDefault: method == "get" && discoveryURL(req.URL.Path)}
+	}
+
+	a := Access{APIGroup: group}
+	if parts[0] == "namespaces" && len(parts) > 1 {
+		a.Namespace = parts[1]
+		if len(parts) > 2 && !namespaceSubresources[parts[2]] {
+			parts = parts[2:]
+		}
+	}
+	a.Resource = parts[0]
+	name := ""
+	if len(parts) > 1 {
+		name = parts[1]
+	}
+	if len(parts) > 2 {
+		a.Subresource = parts[2]
+	}
+	// The namespace of a namespace is itself, but RBAC grants it cluster-wide
+	if a.Resource == "namespaces" && a.APIGroup == "" {
+		a.Namespace = ""
+	}
+
+	watch := req.URL.Query().Get("watch")
+	switch {
+	case method == "get" && (watch == "true" || watch == "1"):
+		a.Verb = "watch"
+	case method == "get" && name == "":
+		a.Verb = "list"
+	case method == "post":
+		a.Verb = "create"
+	case method == "put":
+		a.Verb = "update"
+	case method == "delete" && name == "":
+		a.Verb = "deletecollection"
+	default:
+		// get, patch and delete of one object
+		a.Verb = method
+	}
+	a.GrantedByDefault = a.Verb == "create" && selfReviews[a.Resource] &&
+		(a.APIGroup == "authorization.k8s.io" || a.APIGroup == "authentication.k8s.io")
+	return a
+}
+
+// discoveryURL reports whether system:discovery or system:public-info-viewer
+// grants a GET of the path to every user
+func discoveryURL(path string) bool {
+	switch {
+	case path == "/api" || path == "/apis" || path == "/version":
+		return true
+	case strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/apis/") || strings.HasPrefix(path, "/openapi/"):
+		return true
+	case path == "/healthz" || path == "/livez" || path == "/readyz":
+		return true
+	}
+	return false
+}
+
+func appendUnique(list []string, s string) []string {
+	if slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,132 @@
+package httpdebug
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestAccessOf(t *testing.T) {
+	for _, tt := range []struct {
+		method string
+		url    string
+		want   Access
+	}{
+		{"GET", "/api/v1/pods?limit=500&resourceVersion=0", Access{Verb: "list", Resource: "pods"}},
+		{"GET", "/api/v1/namespaces/default/pods?watch=true", Access{Verb: "watch", Resource: "pods", Namespace: "default"}},
+		{"GET", "/api/v1/namespaces/default/pods/web-0/log", Access{Verb: "get", Resource: "pods", Subresource: "log", Namespace: "default"}},
+		{"POST", "/apis/apps/v1/namespaces/shop/deployments", Access{Verb: "create", APIGroup: "apps", Resource: "deployments", Namespace: "shop"}},
+		{"PUT", "/apis/apps/v1/namespaces/shop/deployments/web/scale", Access{Verb: "update", APIGroup: "apps", Resource: "deployments", Subresource: "scale", Namespace: "shop"}},
+		{"PATCH", "/apis/apps/v1/namespaces/shop/deployments/web", Access{Verb: "patch", APIGroup: "apps", Resource: "deployments", Namespace: "shop"}},
+		{"DELETE", "/api/v1/namespaces/shop/pods", Access{Verb: "deletecollection", Resource: "pods", Namespace: "shop"}},
+		{"DELETE", "/api/v1/namespaces/shop/pods/web-0", Access{Verb: "delete", Resource: "pods", Namespace: "shop"}},
+		// A namespace is cluster-scoped, and so are its subresources
+		{"GET", "/api/v1/namespaces/shop", Access{Verb: "get", Resource: "namespaces"}},
+		{"PUT", "/api/v1/namespaces/shop/finalize", Access{Verb: "update", Resource: "namespaces", Subresource: "finalize"}},
+		{"GET", "/api/v1/nodes", Access{Verb: "list", Resource: "nodes"}},
+		{"POST", "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews", Access{Verb: "create", APIGroup: "authorization.k8s.io", Resource: "selfsubjectaccessreviews", GrantedByDefault: true}},
+		{"POST", "/apis/authorization.k8s.io/v1/subjectaccessreviews", Access{Verb: "create", APIGroup: "authorization.k8s.io", Resource: "subjectaccessreviews"}},
+		{"GET", "/apis/apps/v1", Access{Verb: "get", NonResourceURL: "/apis/apps/v1", GrantedByDefault: true}},
+		{"GET", "/version", Access{Verb: "get", NonResourceURL: "/version", GrantedByDefault: true}},
+		{"GET", "/metrics", Access{Verb: "get", NonResourceURL: "/metrics"}},
+	} {
+		req := httptest.NewRequest(tt.method, tt.url, nil)
+		if got := accessOf(req); got != tt.want {
+			t.Errorf("%s %s = %+v, want %+v", tt.method, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestRecorderReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/secrets":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403}`))
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"kind":"SelfSubjectAccessReview","apiVersion":"authorization.k8s.io/v1","status":{"allowed":true}}`))
+		default:
+			w.Write([]byte(`{"kind":"List","apiVersion":"v1","items":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	config := &rest.Config{Host: server.URL}
+	config.Wrap(recorder.Wrap)
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.TODO()
+	for range 2 {
+		clientset.CoreV1().Pods("shop").List(ctx, metav1.ListOptions{})
+	}
+	clientset.CoreV1().Pods("shop").Get(ctx, "web-0", metav1.GetOptions{})
+	clientset.AppsV1().Deployments("shop").List(ctx, metav1.ListOptions{})
+	clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	clientset.CoreV1().Secrets("").List(ctx, metav1.ListOptions{})
+	clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{}, metav1.CreateOptions{})
+
+	report := recorder.Report()
+	wantAccesses := []Access{
+		{Verb: "list", Resource: "namespaces", Count: 1},
+		{Verb: "get", Resource: "pods", Namespace: "shop", Count: 1},
+		{Verb: "list", Resource: "pods", Namespace: "shop", Count: 2},
+		{Verb: "list", Resource: "secrets", Count: 1, Denied: 1},
+		{Verb: "list", APIGroup: "apps", Resource: "deployments", Namespace: "shop", Count: 1},
+		{Verb: "create", APIGroup: "authorization.k8s.io", Resource: "selfsubjectaccessreviews", GrantedByDefault: true, Count: 1},
+	}
+	if !reflect.DeepEqual(report.Accesses, wantAccesses) {
+		t.Errorf("accesses = %+v\nwant %+v", report.Accesses, wantAccesses)
+	}
+	// Listing namespaces and secrets across namespaces needs a ClusterRole;
+	// the self review is left out
+	wantRules := []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+		{APIGroups: []string{""}, Resources: []string{"namespaces", "secrets"}, Verbs: []string{"list"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"list"}},
+	}
+	if !reflect.DeepEqual(report.Rules, wantRules) {
+		t.Errorf("rules = %+v\nwant %+v", report.Rules, wantRules)
+	}
+	if !report.ClusterScoped || !reflect.DeepEqual(report.Namespaces, []string{"shop"}) {
+		t.Errorf("clusterScoped = %v, namespaces = %v", report.ClusterScoped, report.Namespaces)
+	}
+
+	var buf bytes.Buffer
+	if err := recorder.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, report) {
+		t.Errorf("the written report does not read back:\n%s", buf.String())
+	}
+}
+
+func TestEmptyReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewRecorder().WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// Empty lists, not null, so consumers can range over them
+	want := "{\n  \"accesses\": [],\n  \"clusterScoped\": false,\n  \"namespaces\": [],\n  \"rules\": []\n}\n"
+	if buf.String() != want {
+		t.Errorf("got %s", buf.String())
+	}
+}
@@ -0,0 +1,263 @@
+package httpdebug
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+// redacted replaces the value of every field that may hold a credential
+const redacted = "<redacted>"
+
+// defaultMaxBodyBytes is how much of a body is logged when Options leaves
+// MaxBodyBytes unset
+const defaultMaxBodyBytes = 2048
+
+// Options say where and how much the transport logs
+type Options struct {
+	// Out receives the log lines, os.Stderr when nil, so that the output of
+	// the example on stdout stays as it is
+	Out io.Writer
+	// Bodies logs request and response bodies, and every event of a watch
+	Bodies bool
+	// MaxBodyBytes truncates each logged body, 2048 when zero
+	MaxBodyBytes int
+}
+
+// Configure installs the logging transport on config. With Bodies it also
+// makes the clients speak JSON: the typed clientset otherwise sends and may
+// receive protobuf, which would only be logged as its size.
+func Configure(config *rest.Config, opts Options) {
+	if opts.Bodies {
+		config.ContentType = "application/json"
+		config.AcceptContentTypes = "application/json"
+	}
+	config.Wrap(Wrapper(opts))
+}
+
+// Wrapper returns a function for rest.Config.Wrap that logs every request
+// the clients built from the config send:
+//
+//	config.Wrap(httpdebug.Wrapper(httpdebug.Options{Bodies: true}))
+//
+// client-go installs it below its authentication transports, so the requests
+// it sees already carry the Authorization header. Headers are never logged
+// for that reason.
+func Wrapper(opts Options) func(http.RoundTripper) http.RoundTripper {
+	if opts.Out == nil {
+		opts.Out = os.Stderr
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	// Shared by every transport of the wrapper, so that lines written by
+	// concurrent informers never interleave
+	out := &lineWriter{w: opts.Out}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &debugTransport{next: next, opts: opts, out: out}
+	}
+}
+
+// lineWriter writes one complete line at a time
+type lineWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lineWriter) printf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.w, "[HTTP] "+format+"\n", args...)
+}
+
+// debugTransport logs the method, URL, status and latency of each round
+// trip, and with Options.Bodies the bodies as well
+type debugTransport struct {
+	next http.RoundTripper
+	opts Options
+	out  *lineWriter
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if t.opts.Bodies && req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// The request is sent with a copy whose body can be read again
+		requestBody = body
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	// For a watch, this is the time until the stream opened
+	latency := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		t.out.printf("%s %s -> error: %v (%s)", req.Method, req.URL, err, latency)
+		return nil, err
+	}
+
+	watch := req.URL.Query().Get("watch") == "true" || req.URL.Query().Get("watch") == "1"
+	if watch {
+		t.out.printf("%s %s -> %s (%s, streaming)", req.Method, req.URL, resp.Status, latency)
+	} else {
+		t.out.printf("%s %s -> %s (%s)", req.Method, req.URL, resp.Status, latency)
+	}
+	if !t.opts.Bodies {
+		return resp, nil
+	}
+	if requestBody != nil {
+		t.out.printf("  request body: %s", t.format(req.Header.Get("Content-Type"), requestBody))
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if watch {
+		// A watch body never ends, so each event is logged as it is read
+		resp.Body = &watchBody{ReadCloser: resp.Body, transport: t, contentType: contentType}
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	t.out.printf("  response body: %s", t.format(contentType, body))
+	return resp, nil
+}
+
+// format renders a body for the log: JSON and YAML with credentials
+// redacted, other text as it is, and binary formats such as protobuf as their
+// size. A JSON or YAML body that does not parse is logged as its size too, as
+// it could not be redacted.
+func (t *debugTransport) format(contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case len(body) == 0:
+		return "<empty>"
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return fmt.Sprintf("<%d bytes of unparseable %s>", len(body), mediaType)
+		}
+		body = encodeRedacted(v)
+	case strings.HasSuffix(mediaType, "yaml"):
+		// Such as a server-side apply; logged as JSON so it stays on one line
+		var v interface{}
+		if err := yaml.Unmarshal(body, &v); err != nil {
+			return fmt.Sprintf("<%d bytes of unparseable %s>", len(body), mediaType)
+		}
+		body = encodeRedacted(v)
+	case mediaType == "" || strings.HasPrefix(mediaType, "text/"):
+	default:
+		return fmt.Sprintf("<%d bytes of %s>", len(body), mediaType)
+	}
+	if len(body) > t.opts.MaxBodyBytes {
+		return fmt.Sprintf("%s... (%d more bytes)", body[:t.opts.MaxBodyBytes], len(body)-t.opts.MaxBodyBytes)
+	}
+	return string(body)
+}
+
+// encodeRedacted encodes a decoded body as JSON with credentials redacted
+func encodeRedacted(v interface{}) []byte {
+	// Without HTML escaping, "<redacted>" stays readable
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(redact(v)); err != nil {
+		return []byte(redacted)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// watchBody logs every line of a JSON watch stream, each of which is an
+// event, as the watcher reads it
+type watchBody struct {
+	io.ReadCloser
+	transport   *debugTransport
+	contentType string
+	pending     []byte
+}
+
+func (b *watchBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.pending = append(b.pending, p[:n]...)
+	for {
+		i := bytes.IndexByte(b.pending, '\n')
+		if i < 0 {
+			break
+		}
+		b.logEvent(b.pending[:i])
+		b.pending = b.pending[i+1:]
+	}
+	if err != nil && len(b.pending) > 0 {
+		b.logEvent(b.pending)
+		b.pending = nil
+	}
+	return n, err
+}
+
+func (b *watchBody) logEvent(line []byte) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	b.transport.out.printf("  watch event: %s", b.transport.format(b.contentType, line))
+}
+
+// redact replaces the values of credential fields in a decoded JSON value:
+// data and stringData of a Secret, and any string field named token, as in
+// a TokenRequest or TokenReview. The items of a list carry no kind, so those
+// of a SecretList are redacted as Secrets.
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		switch v["kind"] {
+		case "Secret":
+			redactSecret(v)
+		case "SecretList":
+			if items, ok := v["items"].([]interface{}); ok {
+				for _, item := range items {
+					if secret, ok := item.(map[string]interface{}); ok {
+						redactSecret(secret)
+					}
+				}
+			}
+		}
+		for k, child := range v {
+			if _, ok := child.(string); ok && k == "token" {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redact(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redact(child)
+		}
+	}
+	return v
+}
+
+func redactSecret(secret map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		if values, ok := secret[field].(map[string]interface{}); ok {
+			for key := range values {
+				values[key] = redacted
+			}
+		}
+	}
+}
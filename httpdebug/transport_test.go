@@ -0,0 +1,243 @@
+package httpdebug
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/shamimice03/mastering-k8s-client-go/testutil"
+)
+
+const secretList = `{"kind":"SecretList","apiVersion":"v1","metadata":{"resourceVersion":"7"},
+"items":[{"metadata":{"name":"db","namespace":"default"},"data":{"password":"aHVudGVyMg=="}}]}`
+
+// newClientset returns a clientset for a server answering with handler,
+// logging through the debug transport into out
+func newClientset(t *testing.T, handler http.HandlerFunc, opts Options) (*kubernetes.Clientset, *testutil.SyncBuffer) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	out := &testutil.SyncBuffer{}
+	opts.Out = out
+	config := &rest.Config{Host: server.URL}
+	Configure(config, opts)
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientset, out
+}
+
+func serveJSON(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}
+}
+
+func TestLogsRequestsWithoutBodies(t *testing.T) {
+	clientset, out := newClientset(t, serveJSON(http.StatusOK, secretList), Options{})
+
+	if _, err := clientset.CoreV1().Secrets("default").List(context.TODO(), metav1.ListOptions{Limit: 500}); err != nil {
+		t.Fatal(err)
+	}
+	line := out.String()
+	if !strings.HasPrefix(line, "[HTTP] GET http://127.0.0.1:") ||
+		!strings.Contains(line, "/api/v1/namespaces/default/secrets?limit=500 -> 200 OK (") {
+		t.Errorf("got %q", line)
+	}
+	if strings.Count(line, "\n") != 1 {
+		t.Errorf("bodies are logged without Options.Bodies:\n%s", line)
+	}
+}
+
+func TestLogsBodiesWithSecretsRedacted(t *testing.T) {
+	var received []byte
+	clientset, out := newClientset(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			received, _ = io.ReadAll(r.Body)
+			serveJSON(http.StatusCreated, string(received))(w, r)
+			return
+		}
+		serveJSON(http.StatusOK, secretList)(w, r)
+	}, Options{Bodies: true})
+	ctx := context.TODO()
+
+	list, err := clientset.CoreV1().Secrets("default").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The caller still reads the body the transport logged
+	if got := string(list.Items[0].Data["password"]); got != "hunter2" {
+		t.Errorf("decoded password = %q, want hunter2", got)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+		StringData: map[string]string{"token": "s3cret"},
+	}
+	if _, err := clientset.CoreV1().Secrets("default").Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(received, []byte("s3cret")) {
+		t.Errorf("server received %s, want the unredacted body", received)
+	}
+
+	log := out.String()
+	for _, leak := range []string{"aHVudGVyMg==", "s3cret"} {
+		if strings.Contains(log, leak) {
+			t.Errorf("log leaks %q:\n%s", leak, log)
+		}
+	}
+	for _, want := range []string{
+		`  response body: {"apiVersion":"v1","items":[{"data":{"password":"<redacted>"}`,
+		"/api/v1/namespaces/default/secrets -> 201 Created (",
+		`  request body: {"apiVersion":"v1","kind":"Secret",`,
+		`"stringData":{"token":"<redacted>"}`,
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log lacks %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestLogsWatchEvents(t *testing.T) {
+	clientset, out := newClientset(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		for _, event := range []string{
+			`{"type":"ADDED","object":{"kind":"Secret","apiVersion":"v1","metadata":{"name":"db","resourceVersion":"8"},"data":{"password":"aHVudGVyMg=="}}}`,
+			`{"type":"BOOKMARK","object":{"kind":"Secret","apiVersion":"v1","metadata":{"resourceVersion":"9"}}}`,
+		} {
+			fmt.Fprintln(w, event)
+			w.(http.Flusher).Flush()
+		}
+	}, Options{Bodies: true})
+
+	watcher, err := clientset.CoreV1().Secrets("default").Watch(context.TODO(), metav1.ListOptions{AllowWatchBookmarks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Stop()
+	for range 2 {
+		select {
+		case <-watcher.ResultChan():
+		case <-time.After(5 * time.Second):
+			t.Fatal("no watch event")
+		}
+	}
+
+	log := out.String()
+	if !strings.Contains(log, "watch=true -> 200 OK (") || !strings.Contains(log, ", streaming)\n") {
+		t.Errorf("watch request not logged as streaming:\n%s", log)
+	}
+	for _, want := range []string{
+		`  watch event: {"object":{"apiVersion":"v1","data":{"password":"<redacted>"}`,
+		`  watch event: {"object":{"apiVersion":"v1","kind":"Secret","metadata":{"resourceVersion":"9"}},"type":"BOOKMARK"}`,
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log lacks %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestLogsFailedRequests(t *testing.T) {
+	out := &testutil.SyncBuffer{}
+	config := &rest.Config{Host: "http://127.0.0.1:1"}
+	config.Wrap(Wrapper(Options{Out: out}))
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := clientset.CoreV1().Namespaces().Get(context.TODO(), "default", metav1.GetOptions{}); err == nil {
+		t.Fatal("request to a closed port succeeded")
+	}
+	if log := out.String(); !strings.Contains(log, "[HTTP] GET http://127.0.0.1:1/api/v1/namespaces/default -> error: ") {
+		t.Errorf("got %q", log)
+	}
+}
+
+func TestLogsAppliedSecretYAMLRedacted(t *testing.T) {
+	var contentType string
+	clientset, out := newClientset(t, func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		serveJSON(http.StatusOK, `{"kind":"Secret","apiVersion":"v1","metadata":{"name":"api","namespace":"default"}}`)(w, r)
+	}, Options{Bodies: true})
+
+	manifest := `apiVersion: v1
+kind: Secret
+metadata:
+  name: api
+  namespace: default
+stringData:
+  password: s3cret
+data:
+  token: aHVudGVyMg==
+`
+	if _, err := clientset.CoreV1().Secrets("default").Patch(context.TODO(), "api", types.ApplyPatchType, []byte(manifest),
+		metav1.PatchOptions{FieldManager: "httpdebug-test"}); err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/apply-patch+yaml" {
+		t.Fatalf("server received %s, want a YAML apply patch", contentType)
+	}
+
+	log := out.String()
+	for _, leak := range []string{"s3cret", "aHVudGVyMg=="} {
+		if strings.Contains(log, leak) {
+			t.Errorf("log leaks %q:\n%s", leak, log)
+		}
+	}
+	if !strings.Contains(log, `"stringData":{"password":"<redacted>"}`) {
+		t.Errorf("applied Secret not logged:\n%s", log)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tr := &debugTransport{opts: Options{MaxBodyBytes: 10}}
+	for _, tt := range []struct {
+		contentType string
+		body        string
+		want        string
+	}{
+		{"application/json", `{"a":1}`, `{"a":1}`},
+		{"application/json", `{"kind":"TokenRequest","status":{"token":"x"}}`, `{"kind":"T... (45 more bytes)`},
+		{"application/vnd.kubernetes.protobuf", "k8s\x00\x0a", "<5 bytes of application/vnd.kubernetes.protobuf>"},
+		{"text/plain; charset=utf-8", "ok", "ok"},
+		{"application/json", "", "<empty>"},
+		// Bodies that cannot be redacted are never logged as they are
+		{"application/json", `{"token":"x"`, "<12 bytes of unparseable application/json>"},
+		{"application/apply-patch+yaml", "token: [x", "<9 bytes of unparseable application/apply-patch+yaml>"},
+	} {
+		if got := tr.format(tt.contentType, []byte(tt.body)); got != tt.want {
+			t.Errorf("format(%s, %q) = %q, want %q", tt.contentType, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tr := &debugTransport{opts: Options{MaxBodyBytes: defaultMaxBodyBytes}}
+	got := tr.format("application/json", []byte(`{"kind":"TokenReview","spec":{"token":"eyJh"},"status":{"user":{"username":"bob"}}}`))
+	want := `{"kind":"TokenReview","spec":{"token":"<redacted>"},"status":{"user":{"username":"bob"}}}`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	// A ConfigMap keeps its data: only Secrets hold credentials there
+	got = tr.format("application/json", []byte(`{"data":{"mode":"safe"},"kind":"ConfigMap"}`))
+	if want := `{"data":{"mode":"safe"},"kind":"ConfigMap"}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
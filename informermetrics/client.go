@@ -0,0 +1,126 @@
+package informermetrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/metrics"
+)
+
+// requestDurationBuckets are the upper bounds, in seconds, of the request
+// latency histogram. They match the ones Kubernetes components use.
+var requestDurationBuckets = []float64{0.005, 0.025, 0.1, 0.25, 0.5, 1, 2, 4, 8, 15, 30, 60}
+
+// RegisterClientMetrics makes client-go report the latency and the result of
+// every API request to r. client-go keeps one set of request metrics per
+// process and honors only the first registration, so call it once, early in
+// main.
+func (r *Registry) RegisterClientMetrics() {
+	m := newRequestMetrics()
+	r.mu.Lock()
+	r.requests = m
+	r.mu.Unlock()
+	metrics.Register(metrics.RegisterOpts{RequestLatency: m, RequestResult: m})
+}
+
+// requestMetrics implements client-go's metrics.LatencyMetric and
+// metrics.ResultMetric
+type requestMetrics struct {
+	mu      sync.Mutex
+	latency map[latencyKey]*histogram
+	results map[resultKey]int64
+}
+
+type latencyKey struct{ verb, host string }
+
+type resultKey struct{ code, method, host string }
+
+// histogram counts observations per bucket; write makes the counts cumulative
+type histogram struct {
+	buckets []int64
+	count   int64
+	sum     float64
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{latency: map[latencyKey]*histogram{}, results: map[resultKey]int64{}}
+}
+
+// Observe records the latency of one request, labelled by verb and API server host
+func (m *requestMetrics) Observe(ctx context.Context, verb string, u url.URL, latency time.Duration) {
+	seconds := latency.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := latencyKey{verb: verb, host: u.Host}
+	h, ok := m.latency[key]
+	if !ok {
+		h = &histogram{buckets: make([]int64, len(requestDurationBuckets))}
+		m.latency[key] = h
+	}
+	if i := sort.SearchFloat64s(requestDurationBuckets, seconds); i < len(h.buckets) {
+		h.buckets[i]++
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// Increment counts one request result. code is the HTTP status code, or
+// "<error>" when no response was received.
+func (m *requestMetrics) Increment(ctx context.Context, code, method, host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results[resultKey{code: code, method: method, host: host}]++
+}
+
+func (m *requestMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP rest_client_request_duration_seconds Latency of API server requests, by verb and host.")
+	fmt.Fprintln(w, "# TYPE rest_client_request_duration_seconds histogram")
+	latencyKeys := make([]latencyKey, 0, len(m.latency))
+	for k := range m.latency {
+		latencyKeys = append(latencyKeys, k)
+	}
+	sort.Slice(latencyKeys, func(i, j int) bool {
+		a, b := latencyKeys[i], latencyKeys[j]
+		return a.host < b.host || a.host == b.host && a.verb < b.verb
+	})
+	for _, k := range latencyKeys {
+		h := m.latency[k]
+		var cumulative int64
+		for i, le := range requestDurationBuckets {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(w, "rest_client_request_duration_seconds_bucket{verb=%q,host=%q,le=%q} %d\n", k.verb, k.host, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "rest_client_request_duration_seconds_bucket{verb=%q,host=%q,le=\"+Inf\"} %d\n", k.verb, k.host, h.count)
+		fmt.Fprintf(w, "rest_client_request_duration_seconds_sum{verb=%q,host=%q} %g\n", k.verb, k.host, h.sum)
+		fmt.Fprintf(w, "rest_client_request_duration_seconds_count{verb=%q,host=%q} %d\n", k.verb, k.host, h.count)
+	}
+
+	fmt.Fprintln(w, "# HELP rest_client_requests_total API server requests, by status code, method and host.")
+	fmt.Fprintln(w, "# TYPE rest_client_requests_total counter")
+	resultKeys := make([]resultKey, 0, len(m.results))
+	for k := range m.results {
+		resultKeys = append(resultKeys, k)
+	}
+	sort.Slice(resultKeys, func(i, j int) bool {
+		a, b := resultKeys[i], resultKeys[j]
+		if a.host != b.host {
+			return a.host < b.host
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.code < b.code
+	})
+	for _, k := range resultKeys {
+		fmt.Fprintf(w, "rest_client_requests_total{code=%q,method=%q,host=%q} %d\n", k.code, k.method, k.host, m.results[k])
+	}
+}
@@ -0,0 +1,78 @@
+package informermetrics
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestRequestLatencyHistogram(t *testing.T) {
+	m := newRequestMetrics()
+	u := url.URL{Host: "10.0.0.1:6443"}
+	m.Observe(context.TODO(), "GET", u, 3*time.Millisecond)
+	m.Observe(context.TODO(), "GET", u, 30*time.Millisecond)
+	m.Observe(context.TODO(), "GET", u, 90*time.Second)
+	m.Observe(context.TODO(), "POST", u, 100*time.Millisecond)
+	var out bytes.Buffer
+	m.write(&out)
+
+	for _, want := range []string{
+		"# TYPE rest_client_request_duration_seconds histogram",
+		`rest_client_request_duration_seconds_bucket{verb="GET",host="10.0.0.1:6443",le="0.005"} 1`,
+		`rest_client_request_duration_seconds_bucket{verb="GET",host="10.0.0.1:6443",le="0.025"} 1`,
+		`rest_client_request_duration_seconds_bucket{verb="GET",host="10.0.0.1:6443",le="0.1"} 2`,
+		`rest_client_request_duration_seconds_bucket{verb="GET",host="10.0.0.1:6443",le="60"} 2`,
+		`rest_client_request_duration_seconds_bucket{verb="GET",host="10.0.0.1:6443",le="+Inf"} 3`,
+		`rest_client_request_duration_seconds_count{verb="GET",host="10.0.0.1:6443"} 3`,
+		// 100ms lands in the le="0.1" bucket: bounds are inclusive
+		`rest_client_request_duration_seconds_bucket{verb="POST",host="10.0.0.1:6443",le="0.1"} 1`,
+	} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRegisterClientMetricsRecordsRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/pods") {
+			w.Write([]byte(`{"kind":"PodList","apiVersion":"v1","items":[]}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+	}))
+	defer server.Close()
+
+	r := New()
+	r.RegisterClientMetrics()
+	clientset := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL})
+	if _, err := clientset.CoreV1().Pods("default").List(context.TODO(), metav1.ListOptions{}); err != nil {
+		t.Fatalf("list pods: %v", err)
+	}
+	if _, err := clientset.CoreV1().Pods("default").Get(context.TODO(), "missing", metav1.GetOptions{}); err == nil {
+		t.Fatal("get of a missing pod succeeded")
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	got := scrape(t, r)
+	for _, want := range []string{
+		`rest_client_requests_total{code="200",method="GET",host="` + host + `"} 1`,
+		`rest_client_requests_total{code="404",method="GET",host="` + host + `"} 1`,
+		`rest_client_request_duration_seconds_count{verb="GET",host="` + host + `"} 2`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package informermetrics serves informer cache statistics in the Prometheus
+// text format: how many objects each cache holds, how many distinct values
+// each index has, how often every event handler ran, and whether the caches
+// have synced. Optionally it also reports client-go's request latency and
+// results.
+//
+// The values are read from the informers when /metrics is scraped, so an
+// example only registers its informers and wraps its handlers; nothing has to
+// be updated as events arrive.
+package informermetrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// Registry collects the informers and handlers whose statistics are served
+type Registry struct {
+	mu        sync.Mutex
+	informers []informerEntry
+	handlers  []*handlerCounts
+	// requests is set by RegisterClientMetrics
+	requests *requestMetrics
+	// collectors write metrics kept outside the registry
+	collectors []func(io.Writer)
+}
+
+type informerEntry struct {
+	resource string
+	informer cache.SharedIndexInformer
+}
+
+// handlerCounts counts the events delivered to one handler
+type handlerCounts struct {
+	resource, handler string
+	add, update, del  atomic.Int64
+}
+
+// New returns an empty Registry
+func New() *Registry {
+	return &Registry{}
+}
+
+// AddInformer reports the cache size, index cardinality and sync status of
+// informer under the resource label, e.g. "pods"
+func (r *Registry) AddInformer(resource string, informer cache.SharedIndexInformer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.informers = append(r.informers, informerEntry{resource: resource, informer: informer})
+}
+
+// CountEvents wraps handler so that every add, update and delete it receives
+// is counted under the resource and handler labels
+func (r *Registry) CountEvents(resource, handler string, next cache.ResourceEventHandler) cache.ResourceEventHandler {
+	counts := &handlerCounts{resource: resource, handler: handler}
+	r.mu.Lock()
+	r.handlers = append(r.handlers, counts)
+	r.mu.Unlock()
+	return &countingHandler{next: next, counts: counts}
+}
+
+// AddCollector appends the output of write to every scrape, so metrics kept
+// elsewhere, such as a controller's reconcile counts, share one /metrics
+func (r *Registry) AddCollector(write func(w io.Writer)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, write)
+}
+
+type countingHandler struct {
+	next   cache.ResourceEventHandler
+	counts *handlerCounts
+}
+
+func (h *countingHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	h.counts.add.Add(1)
+	h.next.OnAdd(obj, isInInitialList)
+}
+
+func (h *countingHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.counts.update.Add(1)
+	h.next.OnUpdate(oldObj, newObj)
+}
+
+func (h *countingHandler) OnDelete(obj interface{}) {
+	h.counts.del.Add(1)
+	h.next.OnDelete(obj)
+}
+
+// ServeHTTP writes every metric in the Prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.write(w)
+}
+
+func (r *Registry) write(w io.Writer) {
+	r.mu.Lock()
+	informers := append([]informerEntry(nil), r.informers...)
+	handlers := append([]*handlerCounts(nil), r.handlers...)
+	requests := r.requests
+	collectors := slices.Clone(r.collectors)
+	r.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP informer_cached_objects Number of objects in the informer's cache.")
+	fmt.Fprintln(w, "# TYPE informer_cached_objects gauge")
+	for _, e := range informers {
+		fmt.Fprintf(w, "informer_cached_objects{resource=%q} %d\n", e.resource, len(e.informer.GetStore().ListKeys()))
+	}
+
+	fmt.Fprintln(w, "# HELP informer_index_values Number of distinct values in an informer index.")
+	fmt.Fprintln(w, "# TYPE informer_index_values gauge")
+	for _, e := range informers {
+		indexer := e.informer.GetIndexer()
+		names := make([]string, 0, len(indexer.GetIndexers()))
+		for name := range indexer.GetIndexers() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "informer_index_values{resource=%q,index=%q} %d\n", e.resource, name, len(indexer.ListIndexFuncValues(name)))
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP informer_synced Whether the informer's cache has synced (1) or not (0).")
+	fmt.Fprintln(w, "# TYPE informer_synced gauge")
+	for _, e := range informers {
+		synced := 0
+		if e.informer.HasSynced() {
+			synced = 1
+		}
+		fmt.Fprintf(w, "informer_synced{resource=%q} %d\n", e.resource, synced)
+	}
+
+	fmt.Fprintln(w, "# HELP informer_handler_events_total Events delivered to an event handler.")
+	fmt.Fprintln(w, "# TYPE informer_handler_events_total counter")
+	for _, h := range handlers {
+		for _, c := range []struct {
+			event string
+			count *atomic.Int64
+		}{{"add", &h.add}, {"update", &h.update}, {"delete", &h.del}} {
+			fmt.Fprintf(w, "informer_handler_events_total{resource=%q,handler=%q,event=%q} %d\n", h.resource, h.handler, c.event, c.count.Load())
+		}
+	}
+
+	if requests != nil {
+		requests.write(w)
+	}
+	for _, write := range collectors {
+		write(w)
+	}
+}
+
+// Serve serves the metrics under /metrics on addr until ctx is cancelled
+func (r *Registry) Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r)
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package informermetrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func pod(name, node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: node},
+	}
+}
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func TestRegistryReportsCacheStatistics(t *testing.T) {
+	clientset := fake.NewSimpleClientset(pod("web-1", "node-a"), pod("web-2", "node-a"), pod("db", "node-b"))
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	podInformer := factory.Core().V1().Pods().Informer()
+	if err := podInformer.AddIndexers(cache.Indexers{"node": func(obj interface{}) ([]string, error) {
+		return []string{obj.(*corev1.Pod).Spec.NodeName}, nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New()
+	r.AddInformer("pods", podInformer)
+	if _, err := podInformer.AddEventHandler(r.CountEvents("pods", "printer", cache.ResourceEventHandlerFuncs{})); err != nil {
+		t.Fatal(err)
+	}
+	if got := scrape(t, r); !strings.Contains(got, `informer_synced{resource="pods"} 0`) {
+		t.Errorf("cache reported synced before the informer ran:\n%s", got)
+	}
+
+	stopCh := make(chan struct{})
+	defer factory.Shutdown()
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	want := []string{
+		"# TYPE informer_cached_objects gauge",
+		`informer_cached_objects{resource="pods"} 3`,
+		`informer_index_values{resource="pods",index="namespace"} 1`,
+		`informer_index_values{resource="pods",index="node"} 2`,
+		`informer_synced{resource="pods"} 1`,
+		"# TYPE informer_handler_events_total counter",
+		`informer_handler_events_total{resource="pods",handler="printer",event="add"} 3`,
+		`informer_handler_events_total{resource="pods",handler="printer",event="update"} 0`,
+		`informer_handler_events_total{resource="pods",handler="printer",event="delete"} 0`,
+	}
+	var got string
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		got = scrape(t, r)
+		for _, line := range want {
+			if !strings.Contains(got, line+"\n") {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("want lines %q, got:\n%s", want, got)
+	}
+}
+
+func TestCountEventsPassesEventsThrough(t *testing.T) {
+	var initial bool
+	var deleted interface{}
+	r := New()
+	h := r.CountEvents("pods", "printer", cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc:    func(obj interface{}, isInInitialList bool) { initial = isInInitialList },
+		DeleteFunc: func(obj interface{}) { deleted = obj },
+	})
+	tombstone := cache.DeletedFinalStateUnknown{Key: "default/web", Obj: pod("web", "")}
+	h.OnAdd(pod("web", ""), true)
+	h.OnDelete(tombstone)
+	if !initial {
+		t.Error("isInInitialList was not passed through")
+	}
+	if deleted != tombstone {
+		t.Errorf("delete received %v, want the tombstone", deleted)
+	}
+	got := scrape(t, r)
+	for _, line := range []string{
+		`informer_handler_events_total{resource="pods",handler="printer",event="add"} 1`,
+		`informer_handler_events_total{resource="pods",handler="printer",event="delete"} 1`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, got)
+		}
+	}
+}
+
+func TestCollectorsAreAppended(t *testing.T) {
+	r := New()
+	r.AddCollector(func(w io.Writer) {
+		fmt.Fprintln(w, `controller_queue_depth{controller="pods"} 3`)
+	})
+	got := scrape(t, r)
+	if !strings.HasSuffix(got, `controller_queue_depth{controller="pods"} 3`+"\n") {
+		t.Errorf("collector output missing at the end of:\n%s", got)
+	}
+}
+
+func TestServeStopsWithTheContext(t *testing.T) {
+	// Find a free port, then let Serve listen on it
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- New().Serve(ctx, addr) }()
+
+	err = wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		resp, err := http.Get("http://" + addr + "/metrics")
+		if err != nil {
+			return false, nil
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK, nil
+	})
+	if err != nil {
+		t.Fatalf("/metrics never answered: %v", err)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve returned %v, want nil after cancel", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve still running after cancel")
+	}
+}
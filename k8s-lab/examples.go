@@ -0,0 +1,98 @@
+package main
+
+// example is one module of the repository, run as a k8s-lab subcommand
+type example struct {
+	// name is the subcommand
+	name string
+	// dir is the module directory, relative to the repository root
+	dir   string
+	short string
+
+	// The shared flags the example understands. They are checked against
+	// the example's source in main_test.go.
+	kubeconfig bool
+	namespace  bool
+	selector   bool
+}
+
+// examples lists every example module in the order of the repository
+var examples = []example{
+	{name: "api-access", dir: "01_starter/k8s-api-access", short: "Connect with a kubeconfig and list pods"},
+	{name: "deployment", dir: "02_deployment_using_client_go", short: "Create a Deployment with the typed clientset", namespace: true},
+	{name: "polling", dir: "03_without_informer", short: "Poll pod status without an informer", kubeconfig: true, namespace: true},
+	{name: "informer-events", dir: "04_informer_events", short: "Print pod add, update and delete events from an informer", kubeconfig: true},
+	{name: "informer-index", dir: "05_informer_index", short: "Query an informer's indexer", kubeconfig: true},
+	{name: "resync", dir: "06_Resync", short: "Watch periodic resyncs arrive as updates", kubeconfig: true},
+	{name: "factory", dir: "07_shared_informer_factory", short: "Share informers through a SharedInformerFactory", kubeconfig: true, namespace: true},
+	{name: "factory-lister", dir: "08_shared_informer_factory_lister", short: "Read from the cache with listers", kubeconfig: true, namespace: true},
+	{name: "factory-index", dir: "09_shared_informer_factory_custom_index", short: "Add custom indexes and a cache transform", kubeconfig: true, namespace: true},
+	{name: "factory-complete", dir: "10_shared_informer_factory_complete", short: "Pod monitor combining handlers, listers and indexes", kubeconfig: true, namespace: true},
+	{name: "factory-options", dir: "11_shared_informer_factory_with_options", short: "Scope a factory with namespace and list options", kubeconfig: true},
+	{name: "rightsizing", dir: "12_workload_rightsizing", short: "Compare requests with metrics-server usage", kubeconfig: true, namespace: true},
+	{name: "cost-report", dir: "13_cluster_cost_report", short: "Estimate cluster cost per namespace", kubeconfig: true},
+	{name: "namespace-onboarding", dir: "14_namespace_onboarding_controller", short: "Controller applying a baseline to labeled namespaces", kubeconfig: true, selector: true},
+	{name: "impersonation", dir: "15_user_impersonation", short: "Act as another user or group", kubeconfig: true},
+	{name: "exec-credential", dir: "16_exec_credential_plugin", short: "Authenticate through an exec credential plugin", kubeconfig: true},
+	{name: "secret-syncer", dir: "17_secret_syncer", short: "Copy secrets across namespaces", kubeconfig: true},
+	{name: "cert-expiry", dir: "18_certificate_expiry_monitor", short: "Monitor TLS secrets for expiring certificates", kubeconfig: true},
+	{name: "token-request", dir: "19_service_account_token_request", short: "Request short-lived ServiceAccount tokens", kubeconfig: true, namespace: true},
+	{name: "csr", dir: "20_certificate_signing_request", short: "Run the CertificateSigningRequest workflow", kubeconfig: true},
+	{name: "node-leases", dir: "21_node_lease_monitor", short: "Watch node lease heartbeats", kubeconfig: true},
+	{name: "version-skew", dir: "22_kubelet_version_skew", short: "Check kubelet version skew before an upgrade", kubeconfig: true},
+	{name: "rbac-bootstrap", dir: "23_rbac_bootstrap", short: "Bootstrap RBAC objects from Go", kubeconfig: true, namespace: true},
+	{name: "resource-census", dir: "24_api_resource_census", short: "Count objects of every listable API resource", kubeconfig: true},
+	{name: "admission-webhook", dir: "25_admission_webhook", short: "Validating and mutating admission webhooks", kubeconfig: true},
+	{name: "replicaset-pruner", dir: "26_replicaset_pruner", short: "Prune old ReplicaSets", kubeconfig: true, namespace: true},
+	{name: "event-compactor", dir: "27_event_compactor", short: "Compact repeated events", kubeconfig: true},
+	{name: "crd-lifecycle", dir: "28_crd_lifecycle", short: "Create, use and remove a CRD", kubeconfig: true, namespace: true},
+	{name: "crd-conversion", dir: "29_crd_conversion_webhook", short: "Convert CRD versions with a webhook", kubeconfig: true, namespace: true},
+	{name: "website-operator", dir: "30_website_operator", short: "Operator for a Website custom resource", kubeconfig: true},
+	{name: "aggregated-api", dir: "31_aggregated_apiservice", short: "Register an aggregated APIService", kubeconfig: true, namespace: true},
+	{name: "cache-consistency", dir: "32_cache_consistency_check", short: "Compare informer caches with the API server", kubeconfig: true},
+	{name: "cache-shell", dir: "33_cache_shell", short: "Interactive shell over informer caches", kubeconfig: true},
+	{name: "polling-vs-informer", dir: "34_polling_vs_informer", short: "Benchmark polling against an informer", kubeconfig: true, namespace: true},
+	{name: "memory-profile", dir: "35_informer_memory_profile", short: "Compare informer cache memory footprints", kubeconfig: true, namespace: true},
+	{name: "dashboard", dir: "36_pod_dashboard", short: "Terminal pod dashboard backed by the cache", kubeconfig: true, namespace: true},
+	{name: "read-api", dir: "37_cached_read_api", short: "REST API served from informer indexes", kubeconfig: true},
+	{name: "sqlite-mirror", dir: "38_sqlite_mirror", short: "Mirror caches into a SQLite database", kubeconfig: true, namespace: true},
+	{name: "event-bridge", dir: "39_event_bridge", short: "Publish informer events to NATS JetStream", kubeconfig: true, namespace: true},
+	{name: "failure-alerts", dir: "40_pod_failure_alerts", short: "Alert a webhook on pod failures", kubeconfig: true, namespace: true},
+	{name: "composite-index", dir: "41_composite_index", short: "Answer two-field queries from composite index keys", kubeconfig: true},
+	{name: "node-pods", dir: "42_node_pod_join", short: "Join node and pod caches to show pods per node", kubeconfig: true},
+	{name: "top", dir: "43_resource_top", short: "Show pod and node usage from metrics.k8s.io", kubeconfig: true, namespace: true},
+	{name: "custom-metrics", dir: "44_custom_metrics_scaler", short: "Make HPA-style scaling decisions from custom and external metrics", kubeconfig: true, namespace: true},
+	{name: "hpa", dir: "45_hpa_monitor", short: "Create an HPA for the nginx deployment and follow its decisions", kubeconfig: true, namespace: true},
+	{name: "job", dir: "46_job_runner", short: "Run a Job, wait for it and print its logs on failure", kubeconfig: true, namespace: true},
+	{name: "cronjob", dir: "47_cronjob_manager", short: "Create, suspend, resume and trigger a CronJob and list its Jobs", kubeconfig: true, namespace: true},
+	{name: "statefulset", dir: "48_statefulset_rollout", short: "Follow a StatefulSet rolling update per ordinal, with partitions", kubeconfig: true, namespace: true},
+	{name: "daemonset", dir: "49_daemonset_tracker", short: "Track DaemonSet rollouts per node and flag missing or unready pods", kubeconfig: true, namespace: true},
+	{name: "pvc", dir: "50_pvc_binding", short: "Create a PVC and follow its binding, reporting claims stuck Pending", kubeconfig: true, namespace: true},
+	{name: "storage", dir: "51_storage_discovery", short: "List StorageClasses, CSI drivers and the drivers registered on each node", kubeconfig: true},
+	{name: "ingress-tls", dir: "52_ingress_tls", short: "Check the TLS certificates of Ingresses for upcoming expiry", kubeconfig: true, namespace: true},
+	{name: "endpoints", dir: "53_endpointslice_readiness", short: "Show which endpoints of a Service are ready, serving or terminating", kubeconfig: true, namespace: true},
+	{name: "netpol", dir: "54_networkpolicy_audit", short: "Report pods that no NetworkPolicy selects, per namespace", kubeconfig: true, namespace: true},
+	{name: "gateway", dir: "55_gateway_api", short: "Watch Gateway API Gateways and HTTPRoutes with the dynamic client", kubeconfig: true, namespace: true},
+	{name: "ns-lifecycle", dir: "56_namespace_lifecycle", short: "Give new namespaces default labels, a NetworkPolicy and a ResourceQuota", kubeconfig: true},
+	{name: "limitrange", dir: "57_limitrange_defaults", short: "Report containers that rely on LimitRange defaults or lack requests and limits", kubeconfig: true, namespace: true},
+	{name: "drain-plan", dir: "58_drain_planner", short: "Simulate draining nodes against PodDisruptionBudgets before evicting anything", kubeconfig: true},
+	{name: "hot-reload", dir: "59_config_hot_reload", short: "Reload application config from a ConfigMap or Secret without a restart", kubeconfig: true, namespace: true},
+	{name: "rollback", dir: "60_deployment_rollback", short: "List a Deployment's revisions and roll it back to one", kubeconfig: true, namespace: true},
+	{name: "restart", dir: "61_rollout_restart", short: "Restart a Deployment's pods with a rollout and wait until it finishes", kubeconfig: true, namespace: true},
+	{name: "dry-run-diff", dir: "62_dry_run_diff", short: "Preview the changes of a manifest with a server-side dry run", kubeconfig: true, namespace: true},
+	{name: "watch-list", dir: "63_watch_list", short: "Compare the initial sync of a streaming watch-list with a paginated LIST", kubeconfig: true, namespace: true},
+	{name: "rv-semantics", dir: "64_resource_version_semantics", short: "List with each resourceVersion semantics and compare what comes back", kubeconfig: true, namespace: true},
+	{name: "watch-bookmarks", dir: "65_watch_bookmarks", short: "Watch pods with bookmarks and resume from them after a disconnect", kubeconfig: true, namespace: true},
+	{name: "raw-watch", dir: "66_raw_watch", short: "Watch pods without an informer and reconnect by hand", kubeconfig: true, namespace: true},
+	{name: "reflector-store", dir: "67_reflector_custom_store", short: "Run a reflector into a store that keeps only pod phases", kubeconfig: true, namespace: true},
+	{name: "delta-fifo", dir: "68_delta_fifo", short: "Pop the deltas of a DeltaFIFO and apply them to an indexer by hand", kubeconfig: true, namespace: true},
+	{name: "legacy-informer", dir: "69_legacy_informer_comparison", short: "Run NewInformer next to a SharedIndexInformer and compare them", kubeconfig: true, namespace: true},
+	{name: "file-lister-watcher", dir: "70_file_lister_watcher", short: "Drive an informer from the files of a directory with a custom ListerWatcher"},
+	{name: "expiring-lookups", dir: "71_expiring_lookups", short: "Memoize node zone lookups in a TTL store and an expiring cache", kubeconfig: true, namespace: true},
+	{name: "typed-workqueue", dir: "72_typed_workqueue", short: "Reconcile pods and configmaps from workqueues keyed by a struct and a string", kubeconfig: true, namespace: true},
+	{name: "scheduled-rechecks", dir: "73_scheduled_rechecks", short: "Re-check pods when their max age is due with a delaying queue", kubeconfig: true, namespace: true},
+	{name: "controller-expectations", dir: "74_controller_expectations", short: "Skip syncs until a pod set controller has observed its own creates and deletes", kubeconfig: true, namespace: true},
+	{name: "pod-owner-mapping", dir: "75_pod_owner_mapping", short: "Map pod and replicaset events to the key of their Deployment through ownerReferences", kubeconfig: true, namespace: true},
+	{name: "multi-namespace-factories", dir: "76_multi_namespace_factories", short: "Cache tenant namespaces with one factory each behind a single pod lister", kubeconfig: true, selector: true},
+	{name: "cluster-registry", dir: "77_cluster_registry", short: "Start and stop one informer factory per cluster as a ConfigMap or file lists them", kubeconfig: true, namespace: true},
+	{name: "informer-failover", dir: "78_informer_failover", short: "Re-point pod informers to a standby cluster when the primary stops answering", kubeconfig: true, namespace: true},
+}
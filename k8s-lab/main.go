@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// sharedFlags are the persistent flags every subcommand accepts
+type sharedFlags struct {
+	kubeconfig string
+	namespace  string
+	selector   string
+	repo       string
+}
+
+func newRootCommand() *cobra.Command {
+	flags := &sharedFlags{}
+	root := &cobra.Command{
+		Use:   "k8s-lab",
+		Short: "Run the client-go examples of this repository",
+		Long: `k8s-lab runs every example of the repository as a subcommand.
+
+The shared flags are translated into the example's own flags. Flags that only
+one example knows go after "--", for example:
+
+  k8s-lab informer-events -- --slow-handler 200ms
+  k8s-lab factory-lister -n web -- --metrics-addr :9102`,
+		SilenceUsage: true,
+	}
+	root.PersistentFlags().StringVar(&flags.kubeconfig, "kubeconfig", "", "kubeconfig file (default: the example's default, ~/.kube/config)")
+	root.PersistentFlags().StringVarP(&flags.namespace, "namespace", "n", "", "namespace, for examples that can be scoped to one")
+	root.PersistentFlags().StringVarP(&flags.selector, "selector", "l", "", "label selector, for examples that take one")
+	root.PersistentFlags().StringVar(&flags.repo, "repo", "", "repository root (default: found from the working directory)")
+
+	root.AddCommand(newListCommand())
+	for _, ex := range examples {
+		root.AddCommand(newExampleCommand(ex, flags))
+	}
+	return root
+}
+
+func newListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the examples and the shared flags each one accepts",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printExamples(cmd.OutOrStdout())
+		},
+	}
+}
+
+func printExamples(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMMAND\tDIRECTORY\tSHARED FLAGS\tDESCRIPTION")
+	for _, ex := range examples {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", ex.name, ex.dir, strings.Join(ex.sharedFlags(), ","), ex.short)
+	}
+	w.Flush()
+}
+
+// sharedFlags names the shared flags ex accepts
+func (ex example) sharedFlags() []string {
+	var names []string
+	if ex.kubeconfig {
+		names = append(names, "kubeconfig")
+	}
+	if ex.namespace {
+		names = append(names, "namespace")
+	}
+	if ex.selector {
+		names = append(names, "selector")
+	}
+	if names == nil {
+		names = []string{"-"}
+	}
+	return names
+}
+
+func newExampleCommand(ex example, flags *sharedFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   ex.name + " [-- example flags]",
+		Short: ex.short,
+		Long:  fmt.Sprintf("%s.\n\nBuilds and runs the module in %s.", ex.short, ex.dir),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exampleArgs, err := ex.args(flags, args)
+			if err != nil {
+				return err
+			}
+			repo := flags.repo
+			if repo == "" {
+				if repo, err = findRepo(); err != nil {
+					return err
+				}
+			}
+			return run(cmd, ex, filepath.Join(repo, ex.dir), exampleArgs)
+		},
+	}
+}
+
+// args translates the shared flags into the example's flags, followed by the
+// arguments given after "--". A shared flag the example does not understand
+// is an error rather than silently ignored.
+func (ex example) args(flags *sharedFlags, extra []string) ([]string, error) {
+	var args []string
+	for _, f := range []struct {
+		name      string
+		value     string
+		supported bool
+	}{
+		{"kubeconfig", flags.kubeconfig, ex.kubeconfig},
+		{"namespace", flags.namespace, ex.namespace},
+		{"selector", flags.selector, ex.selector},
+	} {
+		if f.value == "" {
+			continue
+		}
+		if !f.supported {
+			return nil, fmt.Errorf("%s does not support --%s", ex.name, f.name)
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", f.name, f.value))
+	}
+	return append(args, extra...), nil
+}
+
+// findRepo walks up from the working directory to the repository root, the
+// directory holding k8s-lab itself
+func findRepo() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "k8s-lab", "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("repository root not found above the working directory; set --repo")
+		}
+		dir = parent
+	}
+}
+
+// run builds the example into the user cache directory and runs the binary.
+// Building first, instead of go run, keeps the example's exit code, and lets
+// SIGTERM be forwarded to the example itself. Ctrl-C reaches the example
+// directly, as both are in the terminal's process group.
+func run(cmd *cobra.Command, ex example, dir string, args []string) error {
+	// The examples are compiled on demand, so k8s-lab needs the toolchain
+	// next to the checkout it runs from
+	if _, err := exec.LookPath("go"); err != nil {
+		return fmt.Errorf("k8s-lab builds %s with the Go toolchain, and go is not in PATH: %w", ex.dir, err)
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return err
+	}
+	binary := filepath.Join(cacheDir, "k8s-lab", ex.name)
+	build := exec.Command("go", "build", "-o", binary, ".")
+	build.Dir = dir
+	build.Stdout, build.Stderr = cmd.ErrOrStderr(), cmd.ErrOrStderr()
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("failed to build %s: %w", dir, err)
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "==> %s %s\n", ex.dir, strings.Join(args, " "))
+	child := exec.Command(binary, args...)
+	// Examples run in their own directory, as with go run .
+	child.Dir = dir
+	child.Stdin, child.Stdout, child.Stderr = os.Stdin, cmd.OutOrStdout(), cmd.ErrOrStderr()
+	if err := child.Start(); err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGTERM {
+				child.Process.Signal(sig)
+			}
+		}
+	}()
+
+	err = child.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	return err
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
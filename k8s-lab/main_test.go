@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// readSource returns the non-test Go source of an example module
+func readSource(t *testing.T, dir string) string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("..", dir, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var src strings.Builder
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src.Write(data)
+	}
+	return src.String()
+}
+
+func TestExamplesCoverEveryModule(t *testing.T) {
+	listed := map[string]bool{}
+	names := map[string]bool{}
+	for _, ex := range examples {
+		if _, err := os.Stat(filepath.Join("..", ex.dir, "go.mod")); err != nil {
+			t.Errorf("%s: no module in %s", ex.name, ex.dir)
+		}
+		if names[ex.name] {
+			t.Errorf("command %s is listed twice", ex.name)
+		}
+		names[ex.name] = true
+		listed[strings.Split(ex.dir, "/")[0]] = true
+	}
+	dirs, err := filepath.Glob(filepath.Join("..", "[0-9][0-9]_*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		if !listed[filepath.Base(dir)] {
+			t.Errorf("%s has no k8s-lab command; add it to examples", filepath.Base(dir))
+		}
+	}
+}
+
+func TestSharedFlagsMatchTheSource(t *testing.T) {
+	for _, ex := range examples {
+		src := readSource(t, ex.dir)
+		for flag, declared := range map[string]bool{
+			"kubeconfig": ex.kubeconfig,
+			"namespace":  ex.namespace,
+			"selector":   ex.selector,
+		} {
+			defined := regexp.MustCompile(`flag\.\w+\("` + flag + `"`).MatchString(src)
+			// The scope module defines --namespace for the examples using it
+			if flag == "namespace" && strings.Contains(src, "scope.NamespaceFlag()") {
+				defined = true
+			}
+			if defined != declared {
+				t.Errorf("%s: example defines --%s: %v, examples says %v", ex.name, flag, defined, declared)
+			}
+		}
+	}
+}
+
+func TestArgsTranslatesSharedFlags(t *testing.T) {
+	ex := example{name: "test", kubeconfig: true, namespace: true}
+	args, err := ex.args(&sharedFlags{kubeconfig: "/tmp/config", namespace: "web"}, []string{"--interval", "5s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(args, " "); got != "--kubeconfig=/tmp/config --namespace=web --interval 5s" {
+		t.Errorf("args = %s", got)
+	}
+	if _, err := ex.args(&sharedFlags{selector: "team=a"}, nil); err == nil || !strings.Contains(err.Error(), "does not support --selector") {
+		t.Errorf("err = %v, want an unsupported flag error", err)
+	}
+}
+
+func execute(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	root := newRootCommand()
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs(args)
+	err := root.Execute()
+	return out.String(), err
+}
+
+func TestListCommand(t *testing.T) {
+	out, err := execute(t, "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`informer-events +04_informer_events +kubeconfig +`).MatchString(out) {
+		t.Errorf("list output:\n%s", out)
+	}
+}
+
+func TestUnsupportedSharedFlagFailsBeforeBuilding(t *testing.T) {
+	_, err := execute(t, "informer-events", "--namespace", "web", "--repo", t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "informer-events does not support --namespace") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRunBuildsAndRunsTheExample(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module echo\n\ngo 1.24.1\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	wd, _ := os.Getwd()
+	fmt.Printf("%s: %s\n", wd, strings.Join(os.Args[1:], " "))
+}
+`), 0o644)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	if err := run(cmd, example{name: "echo", dir: "echo"}, dir, []string{"--namespace=web", "report"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := dir + ": --namespace=web report\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestRunNeedsTheGoToolchain(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	cmd := &cobra.Command{}
+	err := run(cmd, example{name: "echo", dir: "echo"}, t.TempDir(), nil)
+	if err == nil || !strings.Contains(err.Error(), "go is not in PATH") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package reportsink
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2026, 10, 14, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 14, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 14, 10, 30, 0, 0, time.UTC)},
+		{"0 8 * * *", time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)},
+		{"30 9-17/4 * * *", time.Date(2026, 10, 14, 13, 30, 0, 0, time.UTC)},
+		{"0 8 * * mon-fri", time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)},
+		{"0 8 * * sat,sun", time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)},
+		// Sunday may be written as 7
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches (the 1st, or a Monday)
+		{"0 0 1 * mon", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2026, 10, 14, 10, 25, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule("CRON_TZ=UTC " + tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: Next = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleTimeZone(t *testing.T) {
+	s, err := ParseSchedule("CRON_TZ=Asia/Tokyo 0 9 * * *")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	// 09:00 in Tokyo is 00:00 UTC
+	got := s.Next(time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %s, want %s", got.UTC(), want)
+	}
+}
+
+func TestScheduleNeverFires(t *testing.T) {
+	s, err := ParseSchedule("0 0 30 feb *")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+	if got := s.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Next = %s, want zero time", got)
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * foo *",
+		"CRON_TZ=Nowhere/Special * * * * *",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/shamimice03/mastering-k8s-client-go/reportsink
+
+go 1.24.1
@@ -0,0 +1,92 @@
+package reportsink
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// GenerateFunc renders one report. now is the scheduled time of the run.
+type GenerateFunc func(ctx context.Context, now time.Time) (Report, error)
+
+// job is one report on one schedule
+type job struct {
+	name     string
+	spec     string
+	schedule *Schedule
+	generate GenerateFunc
+}
+
+// Runner runs reports on cron schedules inside a long-running process and
+// delivers them to a sink. Each job runs in its own goroutine. A run that
+// overruns skips the slots it missed instead of queueing them.
+type Runner struct {
+	sink ReportSink
+	jobs []job
+
+	// now and after are replaced in tests
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+}
+
+func NewRunner(sink ReportSink) *Runner {
+	return &Runner{sink: sink, now: time.Now, after: time.After}
+}
+
+// Add schedules generate under a cron expression; name identifies it in logs
+func (r *Runner) Add(name, spec string, generate GenerateFunc) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	r.jobs = append(r.jobs, job{name: name, spec: spec, schedule: schedule, generate: generate})
+	return nil
+}
+
+// Run blocks until ctx is cancelled and every job has stopped
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, j := range r.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.loop(ctx, j)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Runner) loop(ctx context.Context, j job) {
+	for {
+		next := j.schedule.Next(r.now())
+		if next.IsZero() {
+			log.Printf("[Scheduler] %s: %q never fires, not scheduling it", j.name, j.spec)
+			return
+		}
+		log.Printf("[Scheduler] %s: next run at %s", j.name, next.Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.after(next.Sub(r.now())):
+		}
+		if err := r.runOnce(ctx, j, next); err != nil {
+			// A failed run is retried at the next slot, not immediately
+			log.Printf("[Scheduler] %s: %v", j.name, err)
+		}
+	}
+}
+
+// runOnce generates one report and delivers it
+func (r *Runner) runOnce(ctx context.Context, j job, now time.Time) error {
+	report, err := j.generate(ctx, now)
+	if err != nil {
+		return fmt.Errorf("generate: %w", err)
+	}
+	if err := r.sink.Write(ctx, report); err != nil {
+		return fmt.Errorf("deliver %s: %w", report.Key(), err)
+	}
+	log.Printf("[Scheduler] %s: delivered %s (%d bytes)", j.name, report.Key(), len(report.Body))
+	return nil
+}
@@ -0,0 +1,112 @@
+package reportsink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink keeps every delivered report
+type recordingSink struct {
+	mu      sync.Mutex
+	reports []Report
+	fail    error
+}
+
+func (s *recordingSink) Write(ctx context.Context, report Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return s.fail
+	}
+	s.reports = append(s.reports, report)
+	return nil
+}
+
+// fakeClock moves time forward to whatever the runner waits for
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waits   []time.Duration
+	cancel  context.CancelFunc
+	maxRuns int
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = append(c.waits, d)
+	if len(c.waits) > c.maxRuns {
+		c.cancel()
+		return nil
+	}
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestRunnerDeliversOnSchedule(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := &fakeClock{now: time.Date(2026, 10, 15, 7, 59, 30, 0, time.UTC), cancel: cancel, maxRuns: 2}
+	sink := &recordingSink{}
+	runner := NewRunner(sink)
+	runner.now, runner.after = clock.Now, clock.After
+
+	err := runner.Add("security", "CRON_TZ=UTC 0 8 * * *", func(ctx context.Context, now time.Time) (Report, error) {
+		return Report{Name: "security", GeneratedAt: now, Format: "txt", Body: []byte("ok\n")}, nil
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	runner.Run(ctx)
+
+	if len(sink.reports) != 2 {
+		t.Fatalf("delivered %d reports, want 2", len(sink.reports))
+	}
+	if got := sink.reports[0].Key(); got != "security/security-20261015T080000Z.txt" {
+		t.Errorf("first report %s", got)
+	}
+	if got := sink.reports[1].Key(); got != "security/security-20261016T080000Z.txt" {
+		t.Errorf("second report %s", got)
+	}
+	if clock.waits[0] != 30*time.Second || clock.waits[1] != 24*time.Hour {
+		t.Errorf("waited %v", clock.waits)
+	}
+}
+
+func TestRunnerKeepsGoingAfterFailures(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := &fakeClock{now: time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC), cancel: cancel, maxRuns: 3}
+	sink := &recordingSink{}
+	runner := NewRunner(sink)
+	runner.now, runner.after = clock.Now, clock.After
+
+	runs := 0
+	runner.Add("cost", "* * * * *", func(ctx context.Context, now time.Time) (Report, error) {
+		runs++
+		if runs == 1 {
+			return Report{}, errors.New("lister not synced")
+		}
+		return Report{Name: "cost", GeneratedAt: now, Format: "json"}, nil
+	})
+	runner.Run(ctx)
+
+	if runs != 3 || len(sink.reports) != 2 {
+		t.Errorf("%d runs, %d reports delivered; want 3 and 2", runs, len(sink.reports))
+	}
+}
+
+func TestRunnerRejectsBadSchedule(t *testing.T) {
+	if err := NewRunner(Stdout{}).Add("cost", "every hour", nil); err == nil {
+		t.Error("Add accepted an invalid cron expression")
+	}
+}
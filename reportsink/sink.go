@@ -0,0 +1,120 @@
+// Package reportsink delivers the reports generated by the examples (capacity,
+// security, cost, drift) to stdout, a directory or S3-compatible object storage.
+//
+// Examples render a report into memory and hand it to a ReportSink chosen with
+// a --sink flag, so the same report can go to the terminal during development
+// and to durable storage when it runs on a schedule.
+package reportsink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Report is one rendered report
+type Report struct {
+	// Name is the kind of report, e.g. "cost"; it groups stored reports
+	Name string
+	// GeneratedAt is part of the stored object's name
+	GeneratedAt time.Time
+	// Format is the file extension of Body: "txt", "json", "csv"...
+	Format string
+	Body   []byte
+}
+
+// Key is where the report is stored, relative to a directory or bucket prefix:
+// <name>/<name>-<UTC timestamp>.<format>
+func (r Report) Key() string {
+	ts := r.GeneratedAt.UTC().Format("20060102T150405Z")
+	return fmt.Sprintf("%s/%s-%s.%s", r.Name, r.Name, ts, r.Format)
+}
+
+// contentType is the MIME type stored alongside the report
+func (r Report) contentType() string {
+	switch r.Format {
+	case "json":
+		return "application/json"
+	case "csv":
+		return "text/csv"
+	case "yaml":
+		return "application/yaml"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// ReportSink delivers reports
+type ReportSink interface {
+	Write(ctx context.Context, report Report) error
+}
+
+// Stdout writes the report body as is, the behaviour the examples had before sinks
+type Stdout struct {
+	// Out defaults to os.Stdout
+	Out io.Writer
+}
+
+func (s Stdout) Write(ctx context.Context, report Report) error {
+	out := s.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	_, err := out.Write(report.Body)
+	return err
+}
+
+// File stores every report as its own file under Dir, at report.Key()
+type File struct {
+	Dir string
+}
+
+func (f File) Write(ctx context.Context, report Report) error {
+	path := filepath.Join(f.Dir, filepath.FromSlash(report.Key()))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so readers never see half a report
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, report.Body, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Open returns the sink described by spec, the value of an example's --sink flag:
+//
+//	"" or "stdout"        print to stdout
+//	file:///var/reports   one file per report under /var/reports
+//	s3://bucket/prefix    S3-compatible object storage, configured from the
+//	                      AWS_* environment variables (see S3FromEnv)
+func Open(spec string) (ReportSink, error) {
+	if spec == "" || spec == "stdout" {
+		return Stdout{}, nil
+	}
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink %q: %w", spec, err)
+	}
+	switch u.Scheme {
+	case "file":
+		// file://reports is relative, file:///reports absolute
+		dir := u.Host + u.Path
+		if dir == "" {
+			return nil, fmt.Errorf("sink %q has no directory", spec)
+		}
+		return File{Dir: dir}, nil
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("sink %q has no bucket", spec)
+		}
+		return S3FromEnv(u.Host, strings.Trim(u.Path, "/"))
+	default:
+		return nil, fmt.Errorf("unknown sink %q: want stdout, file://<dir> or s3://<bucket>/<prefix>", spec)
+	}
+}
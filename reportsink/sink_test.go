@@ -0,0 +1,90 @@
+package reportsink
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testReport = Report{
+	Name:        "security",
+	GeneratedAt: time.Date(2026, 10, 15, 8, 30, 0, 0, time.FixedZone("CEST", 2*60*60)),
+	Format:      "txt",
+	Body:        []byte("all certificates valid\n"),
+}
+
+func TestKeyUsesUTC(t *testing.T) {
+	if got := testReport.Key(); got != "security/security-20261015T063000Z.txt" {
+		t.Errorf("Key() = %s", got)
+	}
+}
+
+func TestStdout(t *testing.T) {
+	var out bytes.Buffer
+	if err := (Stdout{Out: &out}).Write(context.TODO(), testReport); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if out.String() != "all certificates valid\n" {
+		t.Errorf("wrote %q", out.String())
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := (File{Dir: dir}).Write(context.TODO(), testReport); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "security", "security-20261015T063000Z.txt"))
+	if err != nil {
+		t.Fatalf("report not stored: %v", err)
+	}
+	if string(data) != "all certificates valid\n" {
+		t.Errorf("stored %q", data)
+	}
+	// Nothing but the report is left behind
+	entries, _ := os.ReadDir(filepath.Join(dir, "security"))
+	if len(entries) != 1 {
+		t.Errorf("got %d files, want 1", len(entries))
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "minio")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "minio123")
+	t.Setenv("AWS_ENDPOINT_URL", "http://minio:9000")
+	t.Setenv("AWS_REGION", "")
+
+	for spec, want := range map[string]ReportSink{
+		"":                    Stdout{},
+		"stdout":              Stdout{},
+		"file:///var/reports": File{Dir: "/var/reports"},
+		"file://reports":      File{Dir: "reports"},
+	} {
+		got, err := Open(spec)
+		if err != nil || got != want {
+			t.Errorf("Open(%q) = %#v, %v; want %#v", spec, got, err, want)
+		}
+	}
+
+	sink, err := Open("s3://reports/cluster-a/")
+	if err != nil {
+		t.Fatalf("Open s3: %v", err)
+	}
+	s3 := sink.(*S3)
+	if s3.Bucket != "reports" || s3.Prefix != "cluster-a" || s3.Endpoint != "http://minio:9000" || s3.Region != "us-east-1" {
+		t.Errorf("got %+v", s3)
+	}
+
+	for _, spec := range []string{"ftp://host/dir", "file://", "s3:///prefix"} {
+		if _, err := Open(spec); err == nil {
+			t.Errorf("Open(%q) succeeded", spec)
+		}
+	}
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if _, err := Open("s3://reports"); err == nil {
+		t.Error("s3 sink opened without credentials")
+	}
+}
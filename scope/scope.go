@@ -0,0 +1,83 @@
+// Package scope runs an example in all namespaces or in only one of them. In
+// one namespace, a Role is enough instead of a ClusterRole; Preflight checks
+// up front that the current identity actually has the access it needs.
+package scope
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NamespaceFlag defines the --namespace flag that limits every informer and
+// query to one namespace. Left empty, the informers watch the whole cluster
+// and need a ClusterRole. When it is set, a Role in that namespace is enough
+// (23_rbac_bootstrap --namespaced creates one).
+func NamespaceFlag() *string {
+	return flag.String("namespace", "", "only watch and query this namespace, so a Role is enough instead of a ClusterRole")
+}
+
+// NewFactory returns a factory over all namespaces, or only over ns when it is set
+func NewFactory(clientset kubernetes.Interface, resync time.Duration, ns string) informers.SharedInformerFactory {
+	if ns == "" {
+		return informers.NewSharedInformerFactory(clientset, resync)
+	}
+	return informers.NewSharedInformerFactoryWithOptions(clientset, resync, informers.WithNamespace(ns))
+}
+
+// Describe names the namespace an informer or query covers
+func Describe(ns string) string {
+	if ns == "" {
+		return "all namespaces"
+	}
+	return "namespace " + ns
+}
+
+// Allowed asks the API server, with a SelfSubjectAccessReview, whether the
+// current identity may perform attrs
+func Allowed(ctx context.Context, clientset kubernetes.Interface, attrs authorizationv1.ResourceAttributes) (bool, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attrs},
+	}
+	result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return result.Status.Allowed, nil
+}
+
+// Preflight checks whether the current identity may list and watch every
+// resource in ns ("" means all namespaces). A missing permission then fails at
+// startup with a clear message, rather than showing up as reflector errors
+// that repeat forever.
+func Preflight(ctx context.Context, clientset kubernetes.Interface, ns string, resources ...schema.GroupResource) error {
+	var denied []string
+	for _, resource := range resources {
+		for _, verb := range []string{"list", "watch"} {
+			allowed, err := Allowed(ctx, clientset, authorizationv1.ResourceAttributes{
+				Namespace: ns,
+				Verb:      verb,
+				Group:     resource.Group,
+				Resource:  resource.Resource,
+			})
+			if err != nil {
+				return fmt.Errorf("access review for %s %s: %w", verb, resource, err)
+			}
+			if !allowed {
+				denied = append(denied, verb+" "+resource.String())
+			}
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("not allowed to %s in %s", strings.Join(denied, ", "), Describe(ns))
+	}
+	return nil
+}
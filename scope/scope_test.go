@@ -0,0 +1,81 @@
+package scope
+
+import (
+	"context"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNamespacedFactoryStaysInItsNamespace(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"}},
+	)
+	factory := NewFactory(clientset, 0, "default")
+	podInformer := factory.Core().V1().Pods().Informer()
+	stopCh := make(chan struct{})
+	defer func() {
+		close(stopCh)
+		factory.Shutdown()
+	}()
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	if keys := podInformer.GetStore().ListKeys(); len(keys) != 1 || keys[0] != "default/web" {
+		t.Errorf("cached %v, want [default/web]", keys)
+	}
+	// Every request a Role in default must allow: nothing cluster-wide
+	for _, action := range clientset.Actions() {
+		if action.GetNamespace() != "default" {
+			t.Errorf("%s %s sent to namespace %q, want default", action.GetVerb(), action.GetResource().Resource, action.GetNamespace())
+		}
+	}
+}
+
+// roleClientset answers access reviews as if a Role granted list and watch
+// on pods in the default namespace and nothing else
+func roleClientset() *fake.Clientset {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = attrs.Namespace == "default" && attrs.Group == "" && attrs.Resource == "pods"
+		return true, review, nil
+	})
+	return clientset
+}
+
+func TestPreflight(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+	if err := Preflight(context.TODO(), roleClientset(), "default", pods); err != nil {
+		t.Errorf("namespaced pods: %v", err)
+	}
+
+	err := Preflight(context.TODO(), roleClientset(), "", pods)
+	if err == nil || err.Error() != "not allowed to list pods, watch pods in all namespaces" {
+		t.Errorf("cluster-wide pods: got %v", err)
+	}
+
+	err = Preflight(context.TODO(), roleClientset(), "default", pods, deployments)
+	if err == nil || err.Error() != "not allowed to list deployments.apps, watch deployments.apps in namespace default" {
+		t.Errorf("namespaced deployments: got %v", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	if got := Describe(""); got != "all namespaces" {
+		t.Errorf("Describe(\"\") = %q", got)
+	}
+	if got := Describe("default"); got != "namespace default" {
+		t.Errorf("Describe(default) = %q", got)
+	}
+}
@@ -0,0 +1,229 @@
+// Package testutil drives informers deterministically in unit tests.
+//
+// The fake clientset only forwards changes to watches that are already open,
+// so a change made right after WaitForCacheSync can be lost if the informer has
+// not sent its WATCH yet. Harness replaces the fake watch with one the test
+// controls: WaitForWatch blocks until the informer is listening, and
+// Add/Update/Delete write the object tracker and emit the matching event.
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// Timeout bounds every wait in this package
+const Timeout = 5 * time.Second
+
+// openWatch is one WATCH request made by an informer
+type openWatch struct {
+	namespace string
+	selector  labels.Selector
+	watcher   *watch.RaceFreeFakeWatcher
+}
+
+// Harness is a fake clientset whose watch events are injected by the test
+type Harness struct {
+	Clientset *fake.Clientset
+
+	t               testing.TB
+	mu              sync.Mutex
+	watches         map[schema.GroupVersionResource][]*openWatch
+	resourceVersion int
+}
+
+// NewHarness returns a harness seeded with objects, which informers see in their initial LIST
+func NewHarness(t testing.TB, objects ...runtime.Object) *Harness {
+	h := &Harness{
+		Clientset: fake.NewSimpleClientset(objects...),
+		t:         t,
+		watches:   make(map[schema.GroupVersionResource][]*openWatch),
+	}
+	h.Clientset.PrependWatchReactor("*", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w := &openWatch{
+			namespace: action.GetNamespace(),
+			selector:  labels.Everything(),
+			watcher:   watch.NewRaceFreeFake(),
+		}
+		// Field selectors are not evaluated; the fake clientset ignores them too
+		if restrictions := action.(k8stesting.WatchAction).GetWatchRestrictions(); restrictions.Labels != nil {
+			w.selector = restrictions.Labels
+		}
+		h.mu.Lock()
+		h.watches[action.GetResource()] = append(h.watches[action.GetResource()], w)
+		h.mu.Unlock()
+		return true, w.watcher, nil
+	})
+	t.Cleanup(func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for _, watches := range h.watches {
+			for _, w := range watches {
+				w.watcher.Stop()
+			}
+		}
+	})
+	return h
+}
+
+// resourceFor maps a typed object to its resource through the client-go scheme
+func resourceFor(obj runtime.Object) (schema.GroupVersionResource, error) {
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(gvks[0])
+	return gvr, nil
+}
+
+// WaitForWatch blocks until count WATCH requests for the resource are open.
+// Events injected before that are not seen by the informer.
+func (h *Harness) WaitForWatch(gvr schema.GroupVersionResource, count int) {
+	h.t.Helper()
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, Timeout, true, func(ctx context.Context) (bool, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		open := 0
+		for _, w := range h.watches[gvr] {
+			if !w.watcher.IsStopped() {
+				open++
+			}
+		}
+		return open >= count, nil
+	})
+	if err != nil {
+		h.t.Fatalf("%d watch(es) on %s never opened", count, gvr.Resource)
+	}
+}
+
+// WaitForWatchOf is WaitForWatch for the resource of obj
+func (h *Harness) WaitForWatchOf(obj runtime.Object, count int) {
+	h.t.Helper()
+	gvr, err := resourceFor(obj)
+	if err != nil {
+		h.t.Fatalf("unknown type %T: %v", obj, err)
+	}
+	h.WaitForWatch(gvr, count)
+}
+
+// Add creates obj and sends an ADDED event
+func (h *Harness) Add(obj runtime.Object) {
+	h.t.Helper()
+	h.apply(watch.Added, obj)
+}
+
+// Update replaces obj and sends a MODIFIED event
+func (h *Harness) Update(obj runtime.Object) {
+	h.t.Helper()
+	h.apply(watch.Modified, obj)
+}
+
+// Delete removes obj and sends a DELETED event carrying its last state
+func (h *Harness) Delete(obj runtime.Object) {
+	h.t.Helper()
+	h.apply(watch.Deleted, obj)
+}
+
+// DeleteUnseen removes obj without telling any watch, then ends the watches on
+// its resource with 410 Gone. This is what an informer experiences when obj is
+// deleted while its watch is disconnected. The informer relists and finds obj
+// missing. Its delete handlers then receive a cache.DeletedFinalStateUnknown
+// tombstone that holds obj's last known state, not obj itself.
+func (h *Harness) DeleteUnseen(obj runtime.Object) {
+	h.t.Helper()
+	gvr, err := resourceFor(obj)
+	if err != nil {
+		h.t.Fatalf("unknown type %T: %v", obj, err)
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		h.t.Fatalf("%T has no object metadata: %v", obj, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.Clientset.Tracker().Delete(gvr, accessor.GetNamespace(), accessor.GetName()); err != nil {
+		h.t.Fatalf("delete %s %s: %v", gvr.Resource, Key(obj), err)
+	}
+	expired := apierrors.NewResourceExpired("too old resource version").Status()
+	for _, w := range h.watches[gvr] {
+		if !w.watcher.IsStopped() {
+			w.watcher.Error(&expired)
+		}
+	}
+}
+
+// apply writes the tracker, so later LISTs and GETs agree with the events,
+// then delivers the event to every matching watch
+func (h *Harness) apply(eventType watch.EventType, obj runtime.Object) {
+	h.t.Helper()
+	gvr, err := resourceFor(obj)
+	if err != nil {
+		h.t.Fatalf("unknown type %T: %v", obj, err)
+	}
+	obj = obj.DeepCopyObject()
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		h.t.Fatalf("%T has no object metadata: %v", obj, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Every write gets a new resourceVersion, like on a real API server, so an
+	// update is never mistaken for a resync
+	if eventType != watch.Deleted {
+		h.resourceVersion++
+		accessor.SetResourceVersion(strconv.Itoa(h.resourceVersion))
+	}
+
+	tracker := h.Clientset.Tracker()
+	switch eventType {
+	case watch.Added:
+		err = tracker.Create(gvr, obj, accessor.GetNamespace())
+	case watch.Modified:
+		err = tracker.Update(gvr, obj, accessor.GetNamespace())
+	case watch.Deleted:
+		err = tracker.Delete(gvr, accessor.GetNamespace(), accessor.GetName())
+	}
+	if err != nil {
+		h.t.Fatalf("%s %s %s: %v", eventType, gvr.Resource, Key(obj), err)
+	}
+
+	for _, w := range h.watches[gvr] {
+		if w.namespace != "" && w.namespace != accessor.GetNamespace() {
+			continue
+		}
+		if !w.selector.Matches(labels.Set(accessor.GetLabels())) {
+			continue
+		}
+		w.watcher.Action(eventType, obj)
+	}
+}
+
+// Key returns namespace/name of an object, or just the name when it is cluster scoped
+func Key(obj interface{}) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return fmt.Sprintf("<%T>", obj)
+	}
+	if accessor.GetNamespace() == "" {
+		return accessor.GetName()
+	}
+	return accessor.GetNamespace() + "/" + accessor.GetName()
+}
@@ -0,0 +1,123 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newPod(namespace, name string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+}
+
+// startPodInformer runs a pod informer from factory with a Recorder attached
+func startPodInformer(t *testing.T, h *Harness, factory informers.SharedInformerFactory) (cache.SharedIndexInformer, *Recorder) {
+	t.Helper()
+	recorder := &Recorder{}
+	informer := factory.Core().V1().Pods().Informer()
+	informer.AddEventHandler(recorder)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		factory.Shutdown()
+	})
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	return informer, recorder
+}
+
+func TestInjectedEventsReachHandlers(t *testing.T) {
+	h := NewHarness(t, newPod("default", "web"))
+	informer, recorder := startPodInformer(t, h, informers.NewSharedInformerFactory(h.Clientset, 0))
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+
+	api := newPod("default", "api")
+	h.Add(api)
+	api.Spec.NodeName = "node-a"
+	h.Update(api)
+	h.Delete(newPod("default", "web"))
+
+	recorder.Expect(t, "add default/web", "add default/api", "update default/api", "delete default/web")
+
+	obj, exists, _ := informer.GetStore().GetByKey("default/api")
+	if !exists || obj.(*corev1.Pod).Spec.NodeName != "node-a" {
+		t.Errorf("cache holds %v, want the updated default/api", obj)
+	}
+	// The tracker stays in step with the events
+	if _, err := h.Clientset.Tracker().Get(corev1.SchemeGroupVersion.WithResource("pods"), "default", "web"); err == nil {
+		t.Error("default/web is still in the tracker after Delete")
+	}
+}
+
+func TestEventsAreFilteredLikeTheWatch(t *testing.T) {
+	h := NewHarness(t)
+	factory := informers.NewSharedInformerFactoryWithOptions(h.Clientset, 0,
+		informers.WithNamespace("default"),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = "app=web"
+		}))
+	_, recorder := startPodInformer(t, h, factory)
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+
+	other := newPod("kube-system", "web")
+	other.Labels = map[string]string{"app": "web"}
+	h.Add(other)
+	h.Add(newPod("default", "unlabelled"))
+	web := newPod("default", "web")
+	web.Labels = map[string]string{"app": "web"}
+	h.Add(web)
+
+	recorder.Expect(t, "add default/web")
+}
+
+func TestEveryWriteBumpsResourceVersion(t *testing.T) {
+	h := NewHarness(t)
+	informer, recorder := startPodInformer(t, h, informers.NewSharedInformerFactory(h.Clientset, 0))
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+
+	pod := newPod("default", "web")
+	h.Add(pod)
+	h.Update(pod)
+	recorder.Expect(t, "add default/web", "update default/web")
+
+	obj, _, _ := informer.GetStore().GetByKey("default/web")
+	if rv := obj.(*corev1.Pod).ResourceVersion; rv != "2" {
+		t.Errorf("resourceVersion = %q, want 2", rv)
+	}
+}
+
+func TestDeleteUnseenDeliversTombstone(t *testing.T) {
+	h := NewHarness(t, newPod("default", "web"))
+	informer, recorder := startPodInformer(t, h, informers.NewSharedInformerFactory(h.Clientset, 0))
+	tombstones := make(chan cache.DeletedFinalStateUnknown, 1)
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				tombstones <- tombstone
+			}
+		},
+	})
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+
+	h.DeleteUnseen(newPod("default", "web"))
+	recorder.Expect(t, "add default/web", "delete default/web")
+	select {
+	case tombstone := <-tombstones:
+		if tombstone.Key != "default/web" || tombstone.Obj.(*corev1.Pod).Name != "web" {
+			t.Errorf("got tombstone %+v", tombstone)
+		}
+	case <-time.After(Timeout):
+		t.Fatal("default/web was not deleted through a tombstone")
+	}
+	if _, exists, _ := informer.GetStore().GetByKey("default/web"); exists {
+		t.Error("default/web is still cached after the relist")
+	}
+	// The informer watches again after relisting, so later events still arrive
+	h.WaitForWatchOf(&corev1.Pod{}, 1)
+	h.Add(newPod("default", "db"))
+	recorder.Expect(t, "add default/web", "delete default/web", "add default/db")
+}
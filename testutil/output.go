@@ -0,0 +1,98 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// CaptureOutput redirects stdout until the test ends and returns a function
+// reading everything printed so far. The examples report through fmt.Printf,
+// so this is how their handlers are observed.
+func CaptureOutput(t testing.TB) func() string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+
+	var mu sync.Mutex
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		chunk := make([]byte, 4096)
+		for {
+			n, err := r.Read(chunk)
+			mu.Lock()
+			buf.Write(chunk[:n])
+			mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		os.Stdout = stdout
+		w.Close()
+		<-done
+	})
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return buf.String()
+	}
+}
+
+// WaitForOutput fails the test unless every line shows up within Timeout
+func WaitForOutput(t testing.TB, output func() string, lines ...string) {
+	t.Helper()
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, Timeout, true, func(ctx context.Context) (bool, error) {
+		for _, line := range lines {
+			if !strings.Contains(output(), line) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("expected output %q, got:\n%s", lines, output())
+	}
+}
+
+// SyncBuffer is a bytes.Buffer that a program's goroutines can write while
+// the test reads it
+type SyncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *SyncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *SyncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Collapse replaces the padding of tabwriter tables with single spaces, so
+// tests compare rows without depending on column widths
+func Collapse(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	return strings.Join(lines, "\n")
+}
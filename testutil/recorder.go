@@ -0,0 +1,75 @@
+package testutil
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Recorder is a cache.ResourceEventHandler that records every invocation as
+// "add <key>", "update <key>" or "delete <key>", in the order they arrive
+type Recorder struct {
+	// Gate, when set, holds the first invocation until it is closed. This
+	// stands in for a slow handler.
+	Gate <-chan struct{}
+
+	once   sync.Once
+	mu     sync.Mutex
+	events []string
+}
+
+var _ cache.ResourceEventHandler = &Recorder{}
+
+func (r *Recorder) record(verb string, obj interface{}) {
+	if r.Gate != nil {
+		r.once.Do(func() { <-r.Gate })
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, verb+" "+Key(obj))
+}
+
+func (r *Recorder) OnAdd(obj interface{}, isInInitialList bool) { r.record("add", obj) }
+
+func (r *Recorder) OnUpdate(oldObj, newObj interface{}) { r.record("update", newObj) }
+
+func (r *Recorder) OnDelete(obj interface{}) { r.record("delete", obj) }
+
+// Events returns a copy of everything recorded so far
+func (r *Recorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+// Expect waits until as many events as want have been recorded and fails the
+// test unless they are exactly want, in order
+func (r *Recorder) Expect(t testing.TB, want ...string) {
+	t.Helper()
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, Timeout, true, func(ctx context.Context) (bool, error) {
+		return len(r.Events()) >= len(want), nil
+	})
+	if got := r.Events(); err != nil || !slices.Equal(got, want) {
+		t.Fatalf("handler invocations = %q, want %q", got, want)
+	}
+}
+
+// WaitForCount waits until at least n events have been recorded. Tests that
+// inject many events use it to pace themselves: a fake watch buffers only 100.
+func (r *Recorder) WaitForCount(t testing.TB, n int) {
+	t.Helper()
+	err := wait.PollUntilContextTimeout(context.TODO(), time.Millisecond, Timeout, true, func(ctx context.Context) (bool, error) {
+		return len(r.Events()) >= n, nil
+	})
+	if err != nil {
+		t.Fatalf("recorded %d events, want at least %d", len(r.Events()), n)
+	}
+}
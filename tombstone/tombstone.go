@@ -0,0 +1,35 @@
+// Package tombstone unwraps the cache.DeletedFinalStateUnknown tombstones that
+// delete handlers receive for deletes their informer's watch missed.
+package tombstone
+
+import (
+	"fmt"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Unwrap returns the object of a delete event as a T. When the informer
+// missed the DELETED event, because its watch was down while the object went
+// away, it only notices on the next relist and hands the delete handlers a
+// cache.DeletedFinalStateUnknown tombstone holding the last state it cached.
+// ok is false, and the error reported, when obj is neither a T nor a tombstone
+// of one.
+//
+//	DeleteFunc: func(obj interface{}) {
+//		pod, ok := tombstone.Unwrap[*corev1.Pod](obj)
+//		if !ok {
+//			return
+//		}
+//		...
+//	}
+func Unwrap[T any](obj interface{}) (T, bool) {
+	if tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown); isTombstone {
+		obj = tombstone.Obj
+	}
+	typed, ok := obj.(T)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("delete event for unexpected type %T", obj))
+	}
+	return typed, ok
+}
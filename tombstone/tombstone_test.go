@@ -0,0 +1,35 @@
+package tombstone
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestUnwrap(t *testing.T) {
+	web := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}}
+	for _, obj := range []interface{}{web, cache.DeletedFinalStateUnknown{Key: "default/web", Obj: web}} {
+		if pod, ok := Unwrap[*corev1.Pod](obj); !ok || pod != web {
+			t.Errorf("Unwrap(%T) = %v, %v", obj, pod, ok)
+		}
+		// Handlers for several types ask for an interface instead
+		if object, ok := Unwrap[runtime.Object](obj); !ok || object != web {
+			t.Errorf("Unwrap[runtime.Object](%T) = %v, %v", obj, object, ok)
+		}
+	}
+}
+
+func TestUnwrapReportsUnexpectedTypes(t *testing.T) {
+	for _, obj := range []interface{}{
+		cache.DeletedFinalStateUnknown{Key: "default/web"},
+		&corev1.ConfigMap{},
+		"default/web",
+	} {
+		if _, ok := Unwrap[*corev1.Pod](obj); ok {
+			t.Errorf("Unwrap[*corev1.Pod](%#v) accepted", obj)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package watcherrors makes the LIST and WATCH failures of informers visible.
+//
+// The reflector behind every informer retries a failed LIST or WATCH with
+// backoff, forever. By default only unexpected errors are logged, without any
+// classification, and expired resourceVersions are logged only at -v=4. So an
+// informer that is stuck on missing RBAC or an unreachable API server just
+// serves an ever older cache. Handle logs each failure with its reason and
+// counts it.
+package watcherrors
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io"
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// Reasons a watch error is counted under
+const (
+	// Expired means the resourceVersion is too old (410 Gone). The reflector
+	// relists, which is expected after a long disconnection.
+	Expired      = "Expired"
+	Forbidden    = "Forbidden"
+	Unauthorized = "Unauthorized"
+	// Connection covers refused, reset and timed out connections, as well as
+	// watches cut off mid-stream
+	Connection = "Connection"
+	Other      = "Other"
+)
+
+// errorsTotal counts watch errors by "<type>/<reason>", e.g.
+// "*v1.Pod/Forbidden". Being an expvar, it appears under /debug/vars on any HTTP
+// server that uses http.DefaultServeMux.
+var errorsTotal = expvar.NewMap("informer_watch_errors_total")
+
+// Classify maps an error returned by LIST or WATCH to a reason
+func Classify(err error) string {
+	var netErr net.Error
+	switch {
+	case apierrors.IsResourceExpired(err), apierrors.IsGone(err):
+		return Expired
+	case apierrors.IsForbidden(err):
+		return Forbidden
+	case apierrors.IsUnauthorized(err):
+		return Unauthorized
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &netErr):
+		return Connection
+	default:
+		return Other
+	}
+}
+
+// Handle is a cache.WatchErrorHandlerWithContext that logs structured errors
+// and counts them. It logs through the logger of ctx, which is the informer's
+// context, so a name or values stored there show up in every line.
+//
+//	informer.SetWatchErrorHandlerWithContext(watcherrors.Handle)
+func Handle(ctx context.Context, r *cache.Reflector, err error) {
+	// The server closed the watch normally; the reflector simply watches again
+	if errors.Is(err, io.EOF) {
+		return
+	}
+	reason := Classify(err)
+	errorsTotal.Add(r.TypeDescription()+"/"+reason, 1)
+
+	logger := klog.FromContext(ctx)
+	switch reason {
+	case Expired:
+		logger.Info("Watch expired, relisting", "type", r.TypeDescription(), "resourceVersion", r.LastSyncResourceVersion())
+	case Forbidden, Unauthorized:
+		logger.Error(err, "Watch not permitted, check RBAC (see 23_rbac_bootstrap)", "type", r.TypeDescription(), "reason", reason)
+	default:
+		logger.Error(err, "Watch failed, retrying", "type", r.TypeDescription(), "reason", reason)
+	}
+}
+
+// Count returns how many errors of reason informers of typ have seen
+func Count(typ, reason string) int64 {
+	if v, ok := errorsTotal.Get(typ + "/" + reason).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
@@ -0,0 +1,105 @@
+package watcherrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestClassify(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{apierrors.NewResourceExpired("too old resource version: 1 (42)"), Expired},
+		{apierrors.NewGone("gone"), Expired},
+		{apierrors.NewForbidden(pods, "", errors.New("RBAC: access denied")), Forbidden},
+		// The reflector wraps LIST errors; the reason must survive that
+		{fmt.Errorf("failed to list *v1.Pod: %w", apierrors.NewForbidden(pods, "", errors.New("denied"))), Forbidden},
+		{apierrors.NewUnauthorized("token expired"), Unauthorized},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, Connection},
+		{io.ErrUnexpectedEOF, Connection},
+		{apierrors.NewInternalError(errors.New("etcd unavailable")), Other},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestForbiddenListIsCounted(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", errors.New("RBAC: access denied"))
+	})
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	podInformer := factory.Core().V1().Pods().Informer()
+	if err := podInformer.SetWatchErrorHandlerWithContext(Handle); err != nil {
+		t.Fatalf("SetWatchErrorHandlerWithContext: %v", err)
+	}
+	before := Count("*v1.Pod", Forbidden)
+	stopCh := make(chan struct{})
+	defer func() {
+		close(stopCh)
+		factory.Shutdown()
+	}()
+	factory.Start(stopCh)
+
+	// The informer never syncs, but the failure no longer goes unnoticed
+	deadline := time.Now().Add(5 * time.Second)
+	for Count("*v1.Pod", Forbidden) == before {
+		if time.Now().After(deadline) {
+			t.Fatal("forbidden LIST was not counted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if podInformer.HasSynced() {
+		t.Error("informer synced although LIST is forbidden")
+	}
+}
+
+func TestNormalWatchCloseIsIgnored(t *testing.T) {
+	r := cache.NewReflector(&cache.ListWatch{}, &corev1.Pod{}, cache.NewStore(cache.MetaNamespaceKeyFunc), 0)
+	before := Count("*v1.Pod", Other)
+	Handle(context.TODO(), r, io.EOF)
+	if Count("*v1.Pod", Other) != before {
+		t.Error("io.EOF was counted as an error")
+	}
+
+	before = Count("*v1.Pod", Expired)
+	Handle(context.TODO(), r, apierrors.NewResourceExpired("too old resource version"))
+	if got := Count("*v1.Pod", Expired); got != before+1 {
+		t.Errorf("expired count = %d, want %d", got, before+1)
+	}
+}
+
+func TestWatchErrorsLogThroughTheContextLogger(t *testing.T) {
+	logger := ktesting.NewLogger(t, ktesting.NewConfig(ktesting.BufferLogs(true)))
+	ctx := klog.NewContext(context.Background(), klog.LoggerWithName(logger, "pod-informer"))
+	r := cache.NewReflector(&cache.ListWatch{}, &corev1.Pod{}, cache.NewStore(cache.MetaNamespaceKeyFunc), 0)
+
+	Handle(ctx, r, apierrors.NewUnauthorized("token expired"))
+	logged := logger.GetSink().(ktesting.Underlier).GetBuffer().String()
+	if !strings.Contains(logged, "pod-informer: Watch not permitted") || !strings.Contains(logged, `reason="Unauthorized"`) {
+		t.Errorf("logged:\n%s", logged)
+	}
+}